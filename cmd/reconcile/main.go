@@ -0,0 +1,53 @@
+// Команда reconcile сверяет журнал двойной записи с user_balances и escrow.
+// Завершается с кодом 1, если найдены расхождения.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ignatzorin/freelance-backend/internal/config"
+	"github.com/ignatzorin/freelance-backend/internal/db"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("reconcile: ошибка загрузки конфигурации: %v", err)
+	}
+
+	dbConn, err := db.NewPostgres(ctx, cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("reconcile: ошибка подключения к базе: %v", err)
+	}
+	defer dbConn.Close()
+
+	report, err := repository.NewLedgerRepository(dbConn).Reconcile(ctx)
+	if err != nil {
+		log.Fatalf("reconcile: ошибка сверки: %v", err)
+	}
+
+	for _, m := range report.Mismatches {
+		log.Printf("reconcile: пользователь %s, %s: available %s (журнал %s), frozen %s (журнал %s)",
+			m.UserID, m.Currency, m.Available, m.LedgerAvailable, m.Frozen, m.LedgerFrozen)
+	}
+	for _, m := range report.EscrowMismatches {
+		log.Printf("reconcile: escrow %s %s, счёт platform_escrow %s", m.Held, m.Currency, m.Ledger)
+	}
+	for _, id := range report.UnbalancedJournals {
+		log.Printf("reconcile: несбалансированный журнал %s", id)
+	}
+
+	if !report.OK() {
+		log.Printf("reconcile: найдены расхождения")
+		os.Exit(1)
+	}
+	log.Printf("reconcile: расхождений нет")
+}
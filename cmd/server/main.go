@@ -13,11 +13,13 @@ import (
 	"github.com/ignatzorin/freelance-backend/internal/ai"
 	"github.com/ignatzorin/freelance-backend/internal/config"
 	"github.com/ignatzorin/freelance-backend/internal/db"
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	httpHandlers "github.com/ignatzorin/freelance-backend/internal/http/handlers"
 	httpRouter "github.com/ignatzorin/freelance-backend/internal/http/router"
 	"github.com/ignatzorin/freelance-backend/internal/infrastructure/persistence"
 	newHandler "github.com/ignatzorin/freelance-backend/internal/interface/http/handler"
 	"github.com/ignatzorin/freelance-backend/internal/logger"
+	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/repository"
 	"github.com/ignatzorin/freelance-backend/internal/service"
 	"github.com/ignatzorin/freelance-backend/internal/storage"
@@ -62,6 +64,10 @@ func main() {
 	if err != nil {
 		log.Fatalf("main: не удалось подготовить файловое хранилище: %v", err)
 	}
+	privateStorage, err := storage.NewPhotoStorage(cfg.PrivateMediaStoragePath, cfg.MaxUploadSizeMB)
+	if err != nil {
+		log.Fatalf("main: не удалось подготовить приватное хранилище: %v", err)
+	}
 
 	// === СТАРЫЕ РЕПОЗИТОРИИ (для совместимости) ===
 	userRepo := repository.NewUserRepository(dbConn)
@@ -79,6 +85,15 @@ func main() {
 	disputeRepo := repository.NewDisputeRepository(dbConn)
 	verificationRepo := repository.NewVerificationRepository(dbConn)
 	proposalTemplateRepo := repository.NewProposalTemplateRepository(dbConn)
+	milestoneRepo := repository.NewMilestoneRepository(dbConn)
+	idempotencyRepo := repository.NewIdempotencyRepository(dbConn)
+	twoFactorRepo := repository.NewTwoFactorRepository(dbConn)
+	identityRepo := repository.NewIdentityRepository(dbConn)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(dbConn)
+	kycRepo := repository.NewKYCRepository(dbConn)
+	personalTokenRepo := repository.NewPersonalTokenRepository(dbConn)
+	privacyRepo := repository.NewPrivacyRepository(dbConn)
+	notificationPreferenceRepo := repository.NewNotificationPreferenceRepository(dbConn)
 
 	// === НОВЫЕ РЕПОЗИТОРИИ (Clean Architecture) ===
 	newOrderRepo := persistence.NewOrderRepositoryAdapter(dbConn)
@@ -117,8 +132,22 @@ func main() {
 	newProposalHandler := newHandler.NewProposalHandler(createProposalUC, updateProposalStatusUC, getProposalUC, listProposalsUC, listMyProposalsUC, getMyProposalForOrderUC)
 	newConvHandler := newHandler.NewConversationHandler(getOrCreateConvUC, listMyConvsUC, sendMessageUC, listMessagesUC, updateMessageUC, deleteMessageUC, addReactionUC, removeReactionUC)
 
+	// Почта: коды подтверждения и ссылки сброса пароля и смены email
+	var mailer service.Mailer
+	switch cfg.MailDriver {
+	case service.MailDriverSMTP:
+		mailer = service.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	case service.MailDriverFile:
+		if mailer, err = service.NewFileMailer(cfg.MailOutboxDir, cfg.MailFrom); err != nil {
+			log.Fatalf("main: ошибка инициализации почты: %v", err)
+		}
+	default:
+		mailer = service.LogMailer{}
+	}
+
 	// === СТАРЫЕ СЕРВИСЫ (для совместимости) ===
 	authService := service.NewAuthService(userRepo, tokenManager)
+	accountService := service.NewAccountService(userRepo, tokenManager, mailer, cfg.FrontendURL)
 	notificationService := service.NewNotificationService(notificationRepo)
 	portfolioService := service.NewPortfolioService(portfolioRepo)
 	seedService := service.NewSeedService(userRepo, orderRepo)
@@ -130,8 +159,26 @@ func main() {
 	favoriteService := service.NewFavoriteService(favoriteRepo)
 	reportService := service.NewReportService(reportRepo)
 	disputeService := service.NewDisputeService(disputeRepo, paymentRepo)
-	verificationService := service.NewVerificationService(verificationRepo)
+	verificationService := service.NewVerificationService(verificationRepo, mailer)
+	if cfg.SMSGatewayURL != "" {
+		verificationService.SetPhoneSender(models.PhoneChannelSMS, service.NewHTTPSMSSender(cfg.SMSGatewayURL, cfg.SMSGatewayAPIKey, cfg.SMSSender))
+	}
+	if cfg.TelegramBotToken != "" {
+		verificationService.SetPhoneSender(models.PhoneChannelTelegram, service.NewTelegramSMSSender(cfg.TelegramBotToken))
+	}
+	if len(verificationService.PhoneChannels()) == 0 && cfg.Env != "production" {
+		verificationService.SetPhoneSender(models.PhoneChannelSMS, service.NewFakeSMSSender())
+	}
+
+	// Уведомления дублируются письмом или в Telegram по настройкам пользователя
+	notificationService.SetPreferences(notificationPreferenceRepo)
+	var notificationTelegram service.SMSSender
+	if cfg.TelegramBotToken != "" {
+		notificationTelegram = service.NewTelegramSMSSender(cfg.TelegramBotToken)
+	}
+	notificationService.SetChannels(mailer, notificationTelegram, cfg.FrontendURL)
 	proposalTemplateService := service.NewProposalTemplateService(proposalTemplateRepo)
+	milestoneService := service.NewMilestoneService(milestoneRepo, paymentRepo, orderRepo)
 
 	var orderService *service.OrderService
 	if cfg.AIBaseURL != "" && cfg.AIModel != "" {
@@ -140,22 +187,103 @@ func main() {
 		orderService = service.NewOrderService(orderRepo, userRepo, portfolioRepo, userRepo, nil)
 	}
 	orderService.SetPaymentRepository(paymentRepo)
+	orderService.SetMilestoneRepository(milestoneRepo)
+
+	exchangeRates := service.DefaultExchangeRates()
+	if cfg.ExchangeRatesFile != "" {
+		if exchangeRates, err = service.LoadExchangeRatesFile(cfg.ExchangeRatesFile); err != nil {
+			log.Fatalf("main: ошибка загрузки курсов валют: %v", err)
+		}
+	}
+	orderService.SetExchangeRates(exchangeRates)
+
+	// Проверка личности: заявки с фотографиями документов и порог вывода без неё
+	kycService := service.NewKYCService(kycRepo, mediaRepo)
+	withdrawalService.SetIdentityRequirement(kycRepo, valueobject.AmountFromFloat(float64(cfg.KYCWithdrawalThreshold)), exchangeRates)
+	personalTokenService := service.NewPersonalTokenService(personalTokenRepo)
 
 	hub := ws.NewHub(ctx)
 	hub.SetNotificationSaver(ws.NewNotificationServiceAdapter(notificationService))
+	hub.SetConversationStore(orderRepo)
+	hub.SetOrderStore(orderRepo)
+	switch cfg.WSBackplane {
+	case "memory":
+		hub.SetBackplane(ws.NewMemoryBackplane())
+	default:
+		hub.SetBackplane(ws.NewPostgresBackplane(dbConn, cfg.DatabaseURL))
+	}
 	go hub.Run()
 
 	orderService.SetHub(hub)
+	milestoneService.SetHub(hub)
+	disputeService.SetHub(hub)
+	reportService.SetHub(hub)
+	kycService.SetHub(hub)
+	authService.SetHub(hub)
+
+	// Отзыв сессии и блокировка пользователя действуют на access токены и WebSocket сразу
+	sessionGuard := service.NewSessionGuard(userRepo, cacheService, cfg.SessionCheckCacheTTL)
+	sessionGuard.SetHub(hub)
+	authService.SetSessionRevoker(sessionGuard)
+	accountService.SetSessionRevoker(sessionGuard)
+	reportService.SetSessionRevoker(sessionGuard)
+
+	// Двухфакторная аутентификация: второй шаг входа и свежий код для вывода средств
+	twoFactorService, err := service.NewTwoFactorService(twoFactorRepo, userRepo, cacheService, cfg.TOTPEncryptionKey, cfg.TOTPIssuer)
+	if err != nil {
+		log.Fatalf("main: ошибка инициализации 2FA: %v", err)
+	}
+	authService.SetTwoFactor(twoFactorService)
+
+	// Задержки и блокировка входа при подборе пароля, предупреждения о входе с нового устройства
+	loginGuard := service.NewLoginGuard(loginAttemptRepo, mailer, cfg.FrontendURL)
+	loginGuard.SetHub(hub)
+	authService.SetLoginGuard(loginGuard)
+
+	// Вход через внешних провайдеров из OIDC_PROVIDERS
+	oidcProviders := make([]service.OIDCProviderConfig, 0, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		oidcProviders = append(oidcProviders, service.OIDCProviderConfig{
+			Name:         p.Name,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			Issuer:       p.Issuer,
+			AuthURL:      p.AuthURL,
+			TokenURL:     p.TokenURL,
+			UserInfoURL:  p.UserInfoURL,
+			EmailsURL:    p.EmailsURL,
+			Scopes:       p.Scopes,
+			TrustEmail:   p.TrustEmail,
+		})
+	}
+	oidcService := service.NewOIDCService(identityRepo, userRepo, authService, oidcProviders, cfg.OIDCRedirectURL)
+
+	withdrawalProcessor := service.NewWithdrawalProcessor(withdrawalRepo, service.NewFakePayoutProvider(), cfg.WithdrawalPollInterval)
+	withdrawalProcessor.SetHub(hub)
+	go withdrawalProcessor.Run(ctx)
+
+	privacyService := service.NewPrivacyService(privacyRepo, photoStorage, privateStorage, mailer, cfg.AccountDeletionGracePeriod, cfg.DataExportTTL)
+	privacyService.SetHub(hub)
+	privacyService.SetSessionRevoker(sessionGuard)
+	go privacyService.Run(ctx, cfg.PrivacyJobInterval)
+	go notificationService.RunDigests(ctx, cfg.NotificationDigestInterval)
 
 	// === СТАРЫЕ HANDLERS (для совместимости) ===
 	authHandler := httpHandlers.NewAuthHandler(authService)
+	accountHandler := httpHandlers.NewAccountHandler(accountService)
+	twoFactorHandler := httpHandlers.NewTwoFactorHandler(twoFactorService)
+	oidcHandler := httpHandlers.NewOIDCHandler(oidcService)
+	personalTokenHandler := httpHandlers.NewPersonalTokenHandler(personalTokenService)
 	profileHandler := httpHandlers.NewProfileHandler(userRepo, hub)
+	profileHandler.SetTokenManager(tokenManager)
+	profileHandler.SetSessionRevoker(sessionGuard)
 	orderHandler := httpHandlers.NewOrderHandler(orderService, userRepo, mediaRepo, hub, cacheService)
 	conversationHandler := httpHandlers.NewConversationHandler(orderService, userRepo, mediaRepo, hub)
 	proposalOperationsHandler := httpHandlers.NewProposalOperationsHandler(orderService, userRepo, mediaRepo, hub)
 	aiOrderHandler := httpHandlers.NewAIOrderHandler(orderService, userRepo, mediaRepo, hub)
 	mediaHandler := httpHandlers.NewMediaHandler(mediaRepo, photoStorage)
-	wsHandler := httpHandlers.NewWSHandler(hub, tokenManager)
+	mediaHandler.SetPrivateStorage(privateStorage)
+	wsHandler := httpHandlers.NewWSHandler(hub, tokenManager, sessionGuard)
 	statsHandler := httpHandlers.NewStatsHandler(orderRepo, userRepo)
 	dashboardHandler := httpHandlers.NewDashboardHandler(orderRepo, userRepo, notificationRepo, orderService, cacheService)
 	proposalHandler := httpHandlers.NewProposalHandler(orderRepo)
@@ -172,13 +300,21 @@ func main() {
 	reportHandler := httpHandlers.NewReportHandler(reportService)
 	disputeHandler := httpHandlers.NewDisputeHandler(disputeService)
 	verificationHandler := httpHandlers.NewVerificationHandler(verificationService)
+	verificationHandler.SetTelegramWebhookSecret(cfg.TelegramWebhookSecret)
+	kycHandler := httpHandlers.NewKYCHandler(kycService)
+	privacyHandler := httpHandlers.NewPrivacyHandler(privacyService)
 	proposalTemplateHandler := httpHandlers.NewProposalTemplateHandler(proposalTemplateService)
 	freelancerHandler := httpHandlers.NewFreelancerHandler(userRepo)
+	milestoneHandler := httpHandlers.NewMilestoneHandler(milestoneService)
 
 	// Роутер с новыми и старыми handlers
 	engine := httpRouter.SetupRouter(
 		cfg,
 		authHandler,
+		accountHandler,
+		twoFactorHandler,
+		oidcHandler,
+		personalTokenHandler,
 		profileHandler,
 		orderHandler,
 		conversationHandler,
@@ -194,6 +330,10 @@ func main() {
 		healthHandler,
 		seedHandler,
 		tokenManager,
+		sessionGuard,
+		personalTokenService,
+		twoFactorService,
+		idempotencyRepo,
 		// Новые handlers
 		newOrderHandler,
 		newProposalHandler,
@@ -209,8 +349,11 @@ func main() {
 		reportHandler,
 		disputeHandler,
 		verificationHandler,
+		kycHandler,
+		privacyHandler,
 		proposalTemplateHandler,
 		freelancerHandler,
+		milestoneHandler,
 	)
 
 	server := &http.Server{
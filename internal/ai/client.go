@@ -16,6 +16,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 )
 
@@ -428,7 +429,7 @@ func selectBestBySkills(proposals []*models.Proposal, freelancerProfiles map[uui
 	justification := fmt.Sprintf("Рекомендован на основе соответствия навыков требованиям заказа. Исполнитель имеет опыт работы с: %s. ", strings.Join(matchingSkills, ", "))
 	justification += fmt.Sprintf("Уровень опыта: %s. ", bestProfile.ExperienceLevel)
 	if bestProposal.ProposedAmount != nil {
-		justification += fmt.Sprintf("Предложенная цена: $%.2f. ", *bestProposal.ProposedAmount)
+		justification += fmt.Sprintf("Предложенная цена: $%s. ", *bestProposal.ProposedAmount)
 	}
 
 	return &bestProposal.ID, justification
@@ -525,7 +526,7 @@ func fallbackBestProposalJustification(orderTitle string, proposal *models.Propo
 	}
 
 	if proposal.ProposedAmount != nil {
-		justification += fmt.Sprintf("Предложенная цена: $%.2f. ", *proposal.ProposedAmount)
+		justification += fmt.Sprintf("Предложенная цена: $%s. ", *proposal.ProposedAmount)
 	}
 
 	justification += "Этот исполнитель хорошо подходит для данного проекта."
@@ -706,7 +707,7 @@ func fallbackFeedback(orderTitle, coverLetter string) string {
 }
 
 // fallbackClientAnalysis формирует простой анализ для заказчика.
-func fallbackClientAnalysis(orderTitle, coverLetter string, skills []string, proposedAmount *float64) string {
+func fallbackClientAnalysis(orderTitle, coverLetter string, skills []string, proposedAmount *valueobject.Amount) string {
 	analysis := fmt.Sprintf("Анализ отклика на заказ \"%s\": ", orderTitle)
 
 	if len(skills) > 0 {
@@ -714,7 +715,7 @@ func fallbackClientAnalysis(orderTitle, coverLetter string, skills []string, pro
 	}
 
 	if proposedAmount != nil {
-		analysis += fmt.Sprintf("Предложенная цена: $%.2f. ", *proposedAmount)
+		analysis += fmt.Sprintf("Предложенная цена: $%s. ", *proposedAmount)
 	}
 
 	analysis += "Предложение выглядит профессионально и заслуживает внимания."
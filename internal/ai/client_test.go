@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 )
 
@@ -23,8 +24,8 @@ var (
 )
 
 func createTestOrder() *models.Order {
-	budgetMin := 50000.0
-	budgetMax := 100000.0
+	budgetMin := valueobject.AmountFromFloat(50000)
+	budgetMax := valueobject.AmountFromFloat(100000)
 	return &models.Order{
 		ID:          uuid.New(),
 		ClientID:    uuid.New(),
@@ -340,7 +341,7 @@ func TestRecommendPriceAndTimeline(t *testing.T) {
 	profile := createTestProfile()
 
 	fmt.Println("\n========== TEST: RecommendPriceAndTimeline ==========")
-	fmt.Printf("INPUT:\n  Order: %s\n  Budget: %.0f-%.0f\n  Freelancer: %v\n",
+	fmt.Printf("INPUT:\n  Order: %s\n  Budget: %s-%s\n  Freelancer: %v\n",
 		order.Title, *order.BudgetMin, *order.BudgetMax, profile.Skills)
 
 	result, err := client.RecommendPriceAndTimeline(ctx, order, requirements, profile, nil)
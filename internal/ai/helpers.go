@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 )
 
@@ -120,19 +121,19 @@ func formatPortfolioStr(items []PortfolioItem, prefix string) string {
 }
 
 // formatBudgetStr формирует строку с бюджетом заказа.
-func formatBudgetStr(budgetMin, budgetMax *float64) string {
+func formatBudgetStr(budgetMin, budgetMax *valueobject.Amount) string {
 	if budgetMin == nil || budgetMax == nil {
 		return ""
 	}
-	return fmt.Sprintf("\nБюджет заказа: $%.2f - $%.2f", *budgetMin, *budgetMax)
+	return fmt.Sprintf("\nБюджет заказа: $%s - $%s", *budgetMin, *budgetMax)
 }
 
 // formatBudgetStrSimple формирует строку с бюджетом заказа (простой формат).
-func formatBudgetStrSimple(budgetMin, budgetMax *float64) string {
+func formatBudgetStrSimple(budgetMin, budgetMax *valueobject.Amount) string {
 	if budgetMin == nil || budgetMax == nil {
 		return ""
 	}
-	return fmt.Sprintf("\nБюджет: $%.2f - $%.2f", *budgetMin, *budgetMax)
+	return fmt.Sprintf("\nБюджет: $%s - $%s", *budgetMin, *budgetMax)
 }
 
 // formatProfileInfo формирует строку с информацией о профиле.
@@ -149,13 +150,13 @@ func formatProfileInfo(profile *models.Profile) string {
 }
 
 // formatPriceInfo формирует строку с информацией о цене.
-func formatPriceInfo(proposedAmount *float64, budgetMin, budgetMax *float64) string {
+func formatPriceInfo(proposedAmount *valueobject.Amount, budgetMin, budgetMax *valueobject.Amount) string {
 	var b strings.Builder
 	if proposedAmount != nil {
-		fmt.Fprintf(&b, "\nПредложенная цена: $%.2f", *proposedAmount)
+		fmt.Fprintf(&b, "\nПредложенная цена: $%s", *proposedAmount)
 	}
 	if budgetMin != nil && budgetMax != nil {
-		fmt.Fprintf(&b, "\nБюджет заказа: $%.2f - $%.2f", *budgetMin, *budgetMax)
+		fmt.Fprintf(&b, "\nБюджет заказа: $%s - $%s", *budgetMin, *budgetMax)
 	}
 	return b.String()
 }
@@ -168,7 +169,7 @@ func formatOtherPricesStr(otherProposals []*models.Proposal) string {
 	prices := make([]string, 0)
 	for _, p := range otherProposals {
 		if p.ProposedAmount != nil {
-			prices = append(prices, fmt.Sprintf("$%.2f", *p.ProposedAmount))
+			prices = append(prices, fmt.Sprintf("$%s", *p.ProposedAmount))
 		}
 	}
 	if len(prices) == 0 {
@@ -186,7 +187,7 @@ func formatComparisonInfo(otherProposals []*models.Proposal) string {
 	otherPrices := make([]string, 0)
 	for _, other := range otherProposals {
 		if other.ProposedAmount != nil {
-			otherPrices = append(otherPrices, fmt.Sprintf("$%.2f", *other.ProposedAmount))
+			otherPrices = append(otherPrices, fmt.Sprintf("$%s", *other.ProposedAmount))
 		}
 	}
 	if len(otherPrices) > 0 {
@@ -244,7 +245,7 @@ func formatOrdersInfo(orders []models.Order) string {
 		fmt.Fprintf(&b, "Заголовок: %s\n", order.Title)
 		fmt.Fprintf(&b, "Описание: %s\n", order.Description)
 		if order.BudgetMin != nil && order.BudgetMax != nil {
-			fmt.Fprintf(&b, "Бюджет: $%.2f - $%.2f\n", *order.BudgetMin, *order.BudgetMax)
+			fmt.Fprintf(&b, "Бюджет: $%s - $%s\n", *order.BudgetMin, *order.BudgetMax)
 		}
 		if order.AISummary != nil {
 			fmt.Fprintf(&b, "Краткое резюме: %s\n", *order.AISummary)
@@ -326,7 +327,7 @@ func formatProposalsInfo(
 		}
 
 		if proposal.ProposedAmount != nil {
-			fmt.Fprintf(&b, "Предложенная цена: $%.2f\n", *proposal.ProposedAmount)
+			fmt.Fprintf(&b, "Предложенная цена: $%s\n", *proposal.ProposedAmount)
 		}
 
 		fmt.Fprintf(&b, "Сопроводительное письмо: %s\n", proposal.CoverLetter)
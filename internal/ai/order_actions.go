@@ -324,7 +324,7 @@ JSON: {"recommended_amount":1000,"min_amount":800,"max_amount":1200,"recommended
 	if err := json.Unmarshal([]byte(response), &recommendation); err != nil {
 		recommendedAmount := 0.0
 		if order.BudgetMin != nil && order.BudgetMax != nil {
-			recommendedAmount = (*order.BudgetMin + *order.BudgetMax) / 2
+			recommendedAmount = ((*order.BudgetMin + *order.BudgetMax) / 2).Float64()
 		}
 		return &models.PriceTimelineRecommendation{
 			RecommendedAmount: &recommendedAmount,
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +30,92 @@ type Config struct {
 	AllowedOrigins   []string
 	RateLimitLimit   int64
 	RateLimitPeriod  time.Duration
+	// Каталог приватных файлов (фотографии документов); не должен лежать внутри MediaStoragePath,
+	// который раздаётся по /media без авторизации
+	PrivateMediaStoragePath string
+	// Период обработки очереди выводов средств
+	WithdrawalPollInterval time.Duration
+	// Выводы за 30 дней свыше этой суммы в валюте по умолчанию требуют проверки личности; 0 — без ограничения
+	KYCWithdrawalThreshold int64
+	// JSON-файл с курсами валют; если не задан, используются встроенные курсы
+	ExchangeRatesFile string
+	// Срок кэширования проверки отзыва сессии и блокировки пользователя
+	SessionCheckCacheTTL time.Duration
+	// Адрес фронтенда, на страницы которого ведут ссылки из писем
+	FrontendURL string
+	// Отправка писем: smtp, file (каталог MailOutboxDir) или log
+	MailDriver    string
+	MailFrom      string
+	MailOutboxDir string
+	SMTPHost      string
+	SMTPPort      int
+	SMTPUsername  string
+	SMTPPassword  string
+	// Доставка кодов подтверждения телефона: SMS шлюз и/или Telegram бот.
+	// Без них вне production коды пишутся в лог
+	SMSGatewayURL    string
+	SMSGatewayAPIKey string
+	SMSSender        string
+	TelegramBotToken string
+	// Секрет вебхука бота (secret_token в setWebhook): через вебхук пользователь делится
+	// с ботом номером, без этого коды в Telegram не отправляются
+	TelegramWebhookSecret string
+	// Ключ шифрования TOTP секретов и название сервиса в приложении-аутентификаторе.
+	// После смены ключа коды из приложений не принимаются, остаются только коды восстановления
+	TOTPEncryptionKey string
+	TOTPIssuer        string
+	// Вход через внешних провайдеров (OIDC_PROVIDERS) и страница фронтенда, куда провайдер возвращает пользователя
+	OIDCProviders   []OIDCProvider
+	OIDCRedirectURL string
+	// Сколько дней после запроса удаление аккаунта ещё можно отменить
+	AccountDeletionGracePeriod time.Duration
+	// Сколько хранится готовый архив с данными пользователя
+	DataExportTTL time.Duration
+	// Как часто собираются архивы и выполняются наступившие удаления аккаунтов
+	PrivacyJobInterval time.Duration
+	// Как часто отправляются уведомления, отложенные в тихие часы, и проверяется, кому пора отправить сводку
+	NotificationDigestInterval time.Duration
+	// Как события WebSocket доходят до соединений на других экземплярах: postgres или memory
+	WSBackplane string
+}
+
+// OIDCProvider описывает внешнего провайдера входа. Провайдер с Issuer работает по OpenID Connect
+// (адреса из discovery), остальным адреса задаются явно.
+type OIDCProvider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	Issuer       string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	EmailsURL    string
+	Scopes       []string
+	TrustEmail   bool
+}
+
+// oidcPresets содержит адреса известных провайдеров; переменные OIDC_<NAME>_* их переопределяют.
+var oidcPresets = map[string]OIDCProvider{
+	"google": {
+		Issuer: "https://accounts.google.com",
+		Scopes: []string{"openid", "email", "profile"},
+	},
+	// GitHub не поддерживает OpenID Connect: подтверждённость email берётся из списка адресов
+	"github": {
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		EmailsURL:   "https://api.github.com/user/emails",
+		Scopes:      []string{"read:user", "user:email"},
+	},
+	// Yandex ID отдаёт в default_email только подтверждённый адрес
+	"yandex": {
+		AuthURL:     "https://oauth.yandex.ru/authorize",
+		TokenURL:    "https://oauth.yandex.ru/token",
+		UserInfoURL: "https://login.yandex.ru/info?format=json",
+		Scopes:      []string{"login:info", "login:email"},
+		TrustEmail:  true,
+	},
 }
 
 // Load читает переменные окружения и возвращает готовую конфигурацию.
@@ -79,6 +166,19 @@ func Load() (*Config, error) {
 	cfg.JWTSecret = jwtSecret
 	cfg.RefreshSecret = refreshSecret
 
+	totpKey := getEnv("TOTP_ENCRYPTION_KEY", "")
+	if len(totpKey) < 32 {
+		if env == "production" {
+			return nil, fmt.Errorf("config: TOTP_ENCRYPTION_KEY обязателен и должен быть не менее 32 символов в production")
+		}
+		if totpKey == "" {
+			totpKey = "totp-encryption-key-development-only-change-in-production"
+			log.Printf("config: WARNING - используется дефолтный TOTP_ENCRYPTION_KEY, измените в production!")
+		}
+	}
+	cfg.TOTPEncryptionKey = totpKey
+	cfg.TOTPIssuer = getEnv("TOTP_ISSUER", "Freelance")
+
 	// CORS allowed origins
 	originsStr := getEnv("CORS_ALLOWED_ORIGINS", "")
 	if originsStr == "" {
@@ -99,14 +199,120 @@ func Load() (*Config, error) {
 	cfg.RefreshTokenTTL = mustParseDuration(getEnv("REFRESH_TOKEN_TTL", "720h"))
 	cfg.MaxUploadSizeMB = mustParseInt64(getEnv("MAX_UPLOAD_MB", "10"))
 
+	cfg.PrivateMediaStoragePath = getEnv("PRIVATE_MEDIA_STORAGE_PATH", "./storage/private")
+	if isSubPath(cfg.MediaStoragePath, cfg.PrivateMediaStoragePath) {
+		return nil, fmt.Errorf("config: PRIVATE_MEDIA_STORAGE_PATH не должен находиться внутри MEDIA_STORAGE_PATH")
+	}
+
 	// Rate limiting настройки
 	cfg.RateLimitLimit = mustParseInt64(getEnv("RATE_LIMIT_LIMIT", "10"))
 	rateLimitPeriodStr := getEnv("RATE_LIMIT_PERIOD", "1m")
 	cfg.RateLimitPeriod = mustParseDuration(rateLimitPeriodStr)
 
+	cfg.WithdrawalPollInterval = mustParseDuration(getEnv("WITHDRAWAL_POLL_INTERVAL", "30s"))
+	cfg.KYCWithdrawalThreshold = mustParseInt64(getEnv("KYC_WITHDRAWAL_THRESHOLD", "15000"))
+	cfg.ExchangeRatesFile = getEnv("EXCHANGE_RATES_FILE", "")
+	cfg.SessionCheckCacheTTL = mustParseDuration(getEnv("SESSION_CHECK_CACHE_TTL", "30s"))
+	cfg.AccountDeletionGracePeriod = mustParseDuration(getEnv("ACCOUNT_DELETION_GRACE_PERIOD", "336h"))
+	cfg.DataExportTTL = mustParseDuration(getEnv("DATA_EXPORT_TTL", "168h"))
+	cfg.PrivacyJobInterval = mustParseDuration(getEnv("PRIVACY_JOB_INTERVAL", "1m"))
+	cfg.NotificationDigestInterval = mustParseDuration(getEnv("NOTIFICATION_DIGEST_INTERVAL", "10m"))
+	cfg.WSBackplane = getEnv("WS_BACKPLANE", "postgres")
+
+	cfg.FrontendURL = getEnv("FRONTEND_URL", "http://localhost:3000")
+	cfg.MailDriver = getEnv("MAIL_DRIVER", "log")
+	cfg.MailFrom = getEnv("MAIL_FROM", "no-reply@localhost")
+	cfg.MailOutboxDir = getEnv("MAIL_OUTBOX_DIR", "./storage/mail")
+	cfg.SMTPHost = getEnv("SMTP_HOST", "")
+	cfg.SMTPPort = int(mustParseInt64(getEnv("SMTP_PORT", "587")))
+	cfg.SMTPUsername = getEnv("SMTP_USERNAME", "")
+	cfg.SMTPPassword = getEnv("SMTP_PASSWORD", "")
+
+	cfg.SMSGatewayURL = getEnv("SMS_GATEWAY_URL", "")
+	cfg.SMSGatewayAPIKey = getEnv("SMS_GATEWAY_API_KEY", "")
+	cfg.SMSSender = getEnv("SMS_SENDER", "")
+	cfg.TelegramBotToken = getEnv("TELEGRAM_BOT_TOKEN", "")
+	cfg.TelegramWebhookSecret = getEnv("TELEGRAM_WEBHOOK_SECRET", "")
+
+	providers, err := loadOIDCProviders(getEnv("OIDC_PROVIDERS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.OIDCProviders = providers
+	cfg.OIDCRedirectURL = getEnv("OIDC_REDIRECT_URL", strings.TrimRight(cfg.FrontendURL, "/")+"/auth/oidc/callback")
+
+	switch cfg.MailDriver {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("config: SMTP_HOST обязателен при MAIL_DRIVER=smtp")
+		}
+	case "file", "log":
+		// Письма со ссылками сброса пароля не должны оседать в логах и на диске боевого сервера
+		if env == "production" {
+			return nil, fmt.Errorf("config: MAIL_DRIVER=%s недопустим в production, используйте smtp", cfg.MailDriver)
+		}
+	default:
+		return nil, fmt.Errorf("config: неизвестный MAIL_DRIVER %q", cfg.MailDriver)
+	}
+
+	switch cfg.WSBackplane {
+	case "postgres", "memory":
+	default:
+		return nil, fmt.Errorf("config: неизвестный WS_BACKPLANE %q", cfg.WSBackplane)
+	}
+
 	return cfg, nil
 }
 
+// loadOIDCProviders читает провайдеров из списка names через запятую. Для каждого нужны
+// OIDC_<NAME>_CLIENT_ID и OIDC_<NAME>_CLIENT_SECRET; провайдеру не из oidcPresets — ещё
+// OIDC_<NAME>_ISSUER либо OIDC_<NAME>_AUTH_URL, _TOKEN_URL и _USERINFO_URL.
+func loadOIDCProviders(names string) ([]OIDCProvider, error) {
+	var providers []OIDCProvider
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("config: провайдер %s указан в OIDC_PROVIDERS дважды", name)
+		}
+		seen[name] = true
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := oidcPresets[name]
+		provider.Name = name
+		provider.ClientID = getEnv(prefix+"CLIENT_ID", "")
+		provider.ClientSecret = getEnv(prefix+"CLIENT_SECRET", "")
+		provider.Issuer = getEnv(prefix+"ISSUER", provider.Issuer)
+		provider.AuthURL = getEnv(prefix+"AUTH_URL", provider.AuthURL)
+		provider.TokenURL = getEnv(prefix+"TOKEN_URL", provider.TokenURL)
+		provider.UserInfoURL = getEnv(prefix+"USERINFO_URL", provider.UserInfoURL)
+		provider.EmailsURL = getEnv(prefix+"EMAILS_URL", provider.EmailsURL)
+		if scopes := getEnv(prefix+"SCOPES", ""); scopes != "" {
+			provider.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+		}
+		if trust := getEnv(prefix+"TRUST_EMAIL", ""); trust != "" {
+			provider.TrustEmail = trust == "true"
+		}
+
+		if provider.ClientID == "" || provider.ClientSecret == "" {
+			return nil, fmt.Errorf("config: для провайдера %s нужны %sCLIENT_ID и %sCLIENT_SECRET", name, prefix, prefix)
+		}
+		if provider.Issuer == "" && (provider.AuthURL == "" || provider.TokenURL == "" || provider.UserInfoURL == "") {
+			return nil, fmt.Errorf("config: для провайдера %s нужен %sISSUER или %sAUTH_URL, %sTOKEN_URL и %sUSERINFO_URL", name, prefix, prefix, prefix, prefix)
+		}
+		if provider.Issuer != "" && len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
 // getEnv возвращает значение переменной окружения или дефолт.
 func getEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
@@ -169,6 +375,20 @@ func mustParseDuration(v string) time.Duration {
 	return dur
 }
 
+// isSubPath сообщает, что path совпадает с root или лежит внутри него.
+func isSubPath(root, path string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // mustParseInt64 безопасно парсит строку в int64.
 func mustParseInt64(v string) int64 {
 	num, err := strconv.ParseInt(v, 10, 64)
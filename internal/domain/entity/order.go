@@ -42,7 +42,7 @@ type OrderAttachment struct {
 	CreatedAt time.Time
 }
 
-func NewOrder(clientID uuid.UUID, title, description string, budgetMin, budgetMax float64, deadline *time.Time) (*Order, error) {
+func NewOrder(clientID uuid.UUID, title, description string, budgetMin, budgetMax valueobject.Amount, deadline *time.Time) (*Order, error) {
 	if title == "" {
 		return nil, apperror.New(apperror.ErrCodeValidation, "название заказа обязательно")
 	}
@@ -109,7 +109,7 @@ func (o *Order) Cancel() error {
 	return nil
 }
 
-func (o *Order) Update(title, description string, budgetMin, budgetMax float64, deadline *time.Time) error {
+func (o *Order) Update(title, description string, budgetMin, budgetMax valueobject.Amount, deadline *time.Time) error {
 	if title != "" {
 		o.Title = title
 	}
@@ -118,7 +118,7 @@ func (o *Order) Update(title, description string, budgetMin, budgetMax float64,
 	}
 	
 	if budgetMin > 0 || budgetMax > 0 {
-		budget, err := valueobject.NewBudget(budgetMin, budgetMax)
+		budget, err := valueobject.NewBudgetInCurrency(budgetMin, budgetMax, o.Budget.Min.Currency)
 		if err != nil {
 			return err
 		}
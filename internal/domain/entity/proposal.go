@@ -13,7 +13,7 @@ type Proposal struct {
 	OrderID                 uuid.UUID
 	FreelancerID            uuid.UUID
 	CoverLetter             string
-	ProposedBudget          float64
+	ProposedBudget          valueobject.Amount
 	ProposedDeadline        *time.Time
 	Status                  valueobject.ProposalStatus
 	AIFeedback              *string
@@ -24,7 +24,7 @@ type Proposal struct {
 	UpdatedAt               time.Time
 }
 
-func NewProposal(orderID, freelancerID uuid.UUID, coverLetter string, proposedBudget float64, proposedDeadline *time.Time) (*Proposal, error) {
+func NewProposal(orderID, freelancerID uuid.UUID, coverLetter string, proposedBudget valueobject.Amount, proposedDeadline *time.Time) (*Proposal, error) {
 	if coverLetter == "" {
 		return nil, apperror.New(apperror.ErrCodeValidation, "сопроводительное письмо обязательно")
 	}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/ignatzorin/freelance-backend/internal/domain/entity"
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 )
 
 type OrderRepository interface {
@@ -28,8 +29,8 @@ type OrderRepository interface {
 type OrderFilter struct {
 	Status     string
 	Skills     []string
-	BudgetMin  *float64
-	BudgetMax  *float64
+	BudgetMin  *valueobject.Amount
+	BudgetMax  *valueobject.Amount
 	Search     string
 	SortBy     string
 	SortOrder  string
@@ -1,49 +1,197 @@
 package valueobject
 
 import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/ignatzorin/freelance-backend/internal/pkg/apperror"
 )
 
+// DefaultCurrency валюта сумм, для которых валюта не указана.
+const DefaultCurrency = "RUB"
+
+// amountScale количество минимальных единиц (копеек, центов) в единице валюты.
+const amountScale = 100
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// Amount денежная сумма с фиксированной точкой в минимальных единицах валюты.
+// В JSON и в базе (NUMERIC(12,2)) представляется десятичным числом с двумя знаками
+// после запятой, поэтому суммы не накапливают ошибку округления float64.
+type Amount int64
+
+// AmountFromFloat переводит сумму из float64 с округлением до копейки.
+func AmountFromFloat(v float64) Amount {
+	return Amount(math.Round(v * amountScale))
+}
+
+// ParseAmount разбирает десятичную запись суммы ("1234.5", "-10.00").
+// Значащие цифры дальше второго знака после запятой считаются ошибкой.
+func ParseAmount(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if len(frac) > 2 {
+		if strings.Trim(frac[2:], "0") != "" {
+			return 0, fmt.Errorf("%w: %q has more than two decimal places", ErrInvalidAmount, s)
+		}
+		frac = frac[:2]
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	if whole == "" {
+		whole = "0"
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || strings.ContainsAny(whole, "+-") {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	cents, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil || strings.ContainsAny(frac, "+-") {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if units > (math.MaxInt64-cents)/amountScale {
+		return 0, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, s)
+	}
+
+	amount := Amount(units*amountScale + cents)
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// Float64 возвращает сумму в единицах валюты; только для отображения и расчётов,
+// результат которых снова округляется до копейки.
+func (a Amount) Float64() float64 {
+	return float64(a) / amountScale
+}
+
+// Percent возвращает p процентов суммы, округлённые до копейки.
+func (a Amount) Percent(p float64) Amount {
+	return Amount(math.Round(float64(a) * p / 100))
+}
+
+// Mul умножает сумму на коэффициент (например, курс валюты) с округлением до копейки.
+func (a Amount) Mul(factor float64) Amount {
+	return Amount(math.Round(float64(a) * factor))
+}
+
+func (a Amount) String() string {
+	sign := ""
+	v := int64(a)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/amountScale, v%amountScale)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON принимает сумму числом или строкой: 1500, 1500.5, "1500.50".
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	parsed, err := ParseAmount(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Scan читает NUMERIC из базы без промежуточного float64.
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		parsed, err := ParseAmount(string(v))
+		if err != nil {
+			return err
+		}
+		*a = parsed
+	case string:
+		parsed, err := ParseAmount(v)
+		if err != nil {
+			return err
+		}
+		*a = parsed
+	case int64:
+		*a = Amount(v * amountScale)
+	case float64:
+		*a = AmountFromFloat(v)
+	case nil:
+		return fmt.Errorf("%w: NULL", ErrInvalidAmount)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidAmount, src)
+	}
+	return nil
+}
+
+// Value передаёт сумму в базу десятичной строкой.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
 type Money struct {
-	Amount   float64
+	Amount   Amount
 	Currency string
 }
 
-func NewMoney(amount float64, currency string) (Money, error) {
+func NewMoney(amount Amount, currency string) (Money, error) {
 	if amount < 0 {
 		return Money{}, apperror.New(apperror.ErrCodeValidation, "сумма не может быть отрицательной")
 	}
 	if currency == "" {
-		currency = "USD"
+		currency = DefaultCurrency
 	}
 	return Money{Amount: amount, Currency: currency}, nil
 }
 
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Amount, m.Currency)
+}
+
 type Budget struct {
 	Min Money
 	Max Money
 }
 
-func NewBudget(min, max float64) (Budget, error) {
+func NewBudget(min, max Amount) (Budget, error) {
+	return NewBudgetInCurrency(min, max, DefaultCurrency)
+}
+
+func NewBudgetInCurrency(min, max Amount, currency string) (Budget, error) {
 	if min < 0 || max < 0 {
 		return Budget{}, apperror.New(apperror.ErrCodeValidation, "бюджет не может быть отрицательным")
 	}
 	if min > max {
 		return Budget{}, apperror.New(apperror.ErrCodeValidation, "минимальный бюджет не может превышать максимальный")
 	}
-	
-	minMoney, _ := NewMoney(min, "USD")
-	maxMoney, _ := NewMoney(max, "USD")
-	
+
+	minMoney, _ := NewMoney(min, currency)
+	maxMoney, _ := NewMoney(max, currency)
+
 	return Budget{Min: minMoney, Max: maxMoney}, nil
 }
 
-func (b Budget) IsInRange(amount float64) bool {
+func (b Budget) IsInRange(amount Amount) bool {
 	return amount >= b.Min.Amount && amount <= b.Max.Amount
 }
 
 func (b Budget) String() string {
-	return fmt.Sprintf("%s %.2f - %.2f", b.Min.Currency, b.Min.Amount, b.Max.Amount)
+	return fmt.Sprintf("%s %s - %s", b.Min.Currency, b.Min.Amount, b.Max.Amount)
 }
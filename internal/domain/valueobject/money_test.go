@@ -0,0 +1,103 @@
+package valueobject
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	cases := map[string]Amount{
+		"0":         0,
+		"10":        1000,
+		"10.5":      1050,
+		"10.05":     1005,
+		"-3.10":     -310,
+		".99":       99,
+		"1234.500":  123450,
+		"99999.99 ": 9999999,
+	}
+	for in, want := range cases {
+		got, err := ParseAmount(in)
+		if err != nil {
+			t.Errorf("ParseAmount(%q): unexpected error %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseAmount(%q) = %d, want %d", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "1.005", "1.2.3", "--1", "1e3"} {
+		if _, err := ParseAmount(in); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("ParseAmount(%q): expected ErrInvalidAmount, got %v", in, err)
+		}
+	}
+}
+
+func TestAmount_NoDrift(t *testing.T) {
+	// 0.1 + 0.2 во float64 даёт 0.30000000000000004; копейки складываются точно
+	var total Amount
+	for i := 0; i < 1000; i++ {
+		total += AmountFromFloat(0.1)
+	}
+	if total.String() != "100.00" {
+		t.Errorf("expected 100.00, got %s", total)
+	}
+	if got := AmountFromFloat(0.1) + AmountFromFloat(0.2); got != AmountFromFloat(0.3) {
+		t.Errorf("expected 0.30, got %s", got)
+	}
+}
+
+func TestAmount_PercentRoundsToCents(t *testing.T) {
+	if got := AmountFromFloat(1234.56).Percent(10); got != AmountFromFloat(123.46) {
+		t.Errorf("expected 123.46, got %s", got)
+	}
+	if got := AmountFromFloat(100.01).Percent(50); got != AmountFromFloat(50.01) {
+		t.Errorf("expected 50.01, got %s", got)
+	}
+}
+
+func TestAmount_JSON(t *testing.T) {
+	var req struct {
+		Amount   Amount  `json:"amount"`
+		Optional *Amount `json:"optional"`
+		Quoted   Amount  `json:"quoted"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount": 1500.5, "optional": null, "quoted": "42.10"}`), &req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Amount != 150050 || req.Optional != nil || req.Quoted != 4210 {
+		t.Errorf("unexpected values: %d %v %d", req.Amount, req.Optional, req.Quoted)
+	}
+
+	data, err := json.Marshal(map[string]Amount{"amount": -150050})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != `{"amount":-1500.50}` {
+		t.Errorf("unexpected JSON %s", data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"amount": 0.001}`), &req); err == nil {
+		t.Error("expected error for sub-cent amount")
+	}
+}
+
+func TestAmount_Scan(t *testing.T) {
+	var a Amount
+	if err := a.Scan([]byte("12345.67")); err != nil || a != 1234567 {
+		t.Errorf("Scan([]byte) = %d, %v", a, err)
+	}
+	if err := a.Scan(int64(5)); err != nil || a != 500 {
+		t.Errorf("Scan(int64) = %d, %v", a, err)
+	}
+	if err := a.Scan(nil); err == nil {
+		t.Error("expected error for NULL")
+	}
+
+	v, err := Amount(-5).Value()
+	if err != nil || v != "-0.05" {
+		t.Errorf("Value() = %v, %v", v, err)
+	}
+}
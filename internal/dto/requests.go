@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 )
 
 // CreateOrderRequest represents the request to create an order
@@ -11,8 +13,9 @@ type CreateOrderRequest struct {
 	Title        string                    `json:"title" binding:"required"`
 	Description  string                    `json:"description" binding:"required"`
 	CategoryID   *string                   `json:"category_id"`
-	BudgetMin    *float64                  `json:"budget_min"`
-	BudgetMax    *float64                  `json:"budget_max"`
+	BudgetMin    *valueobject.Amount       `json:"budget_min"`
+	BudgetMax    *valueobject.Amount       `json:"budget_max"`
+	Currency     string                    `json:"currency"`
 	DeadlineAt   *string                   `json:"deadline_at"`
 	Requirements []OrderRequirementRequest `json:"requirements"`
 	Attachments  []string                  `json:"attachment_ids"`
@@ -29,8 +32,9 @@ type UpdateOrderRequest struct {
 	Title        string                    `json:"title" binding:"required"`
 	Description  string                    `json:"description" binding:"required"`
 	CategoryID   *string                   `json:"category_id"`
-	BudgetMin    *float64                  `json:"budget_min"`
-	BudgetMax    *float64                  `json:"budget_max"`
+	BudgetMin    *valueobject.Amount       `json:"budget_min"`
+	BudgetMax    *valueobject.Amount       `json:"budget_max"`
+	Currency     string                    `json:"currency"`
 	DeadlineAt   *string                   `json:"deadline_at"`
 	Status       string                    `json:"status"`
 	Requirements []OrderRequirementRequest `json:"requirements"`
@@ -39,8 +43,9 @@ type UpdateOrderRequest struct {
 
 // CreateProposalRequest represents the request to create a proposal
 type CreateProposalRequest struct {
-	CoverLetter string   `json:"cover_letter" binding:"required"`
-	Amount      *float64 `json:"amount"`
+	CoverLetter string              `json:"cover_letter" binding:"required"`
+	Amount      *valueobject.Amount `json:"amount"`
+	Currency    string              `json:"currency"`
 }
 
 // UpdateProposalStatusRequest represents the request to update proposal status
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
+	"github.com/ignatzorin/freelance-backend/internal/service"
+	"github.com/ignatzorin/freelance-backend/internal/validation"
+)
+
+// AccountHandler предоставляет HTTP слой для сброса и смены пароля и смены email.
+type AccountHandler struct {
+	account *service.AccountService
+}
+
+// NewAccountHandler создаёт хэндлер.
+func NewAccountHandler(account *service.AccountService) *AccountHandler {
+	return &AccountHandler{account: account}
+}
+
+// ForgotPassword обрабатывает POST /auth/password/forgot.
+// Ответ одинаков для зарегистрированных и неизвестных адресов.
+func (h *AccountHandler) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validation.ValidateEmail(req.Email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.account.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось отправить письмо, попробуйте позже"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "если адрес зарегистрирован, на него отправлена ссылка для сброса пароля"})
+}
+
+// ResetPassword обрабатывает POST /auth/password/reset.
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validation.ValidatePassword(req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.account.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "пароль изменён, войдите с новым паролем"})
+}
+
+// ChangePassword обрабатывает POST /auth/password/change. Текущая сессия сохраняется.
+func (h *AccountHandler) ChangePassword(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validation.ValidatePassword(req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.account.ChangePassword(c.Request.Context(), userID, common.CurrentSessionID(c), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "пароль изменён, остальные сессии завершены"})
+}
+
+// RequestEmailChange обрабатывает POST /auth/email/change.
+func (h *AccountHandler) RequestEmailChange(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		NewEmail string `json:"new_email" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validation.ValidateEmail(req.NewEmail); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.account.RequestEmailChange(c.Request.Context(), userID, req.NewEmail, req.Password); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "ссылка для подтверждения отправлена на новый адрес"})
+}
+
+// ConfirmEmailChange обрабатывает POST /auth/email/confirm.
+func (h *AccountHandler) ConfirmEmailChange(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.account.ConfirmEmailChange(c.Request.Context(), req.Token); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email изменён"})
+}
+
+func respondAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidActionLink),
+		errors.Is(err, service.ErrWrongPassword),
+		errors.Is(err, service.ErrSameEmail):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAccountSuspended):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
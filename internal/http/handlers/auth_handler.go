@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
+	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/service"
 	"github.com/ignatzorin/freelance-backend/internal/validation"
 )
@@ -65,9 +69,9 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		}
 	}
 
-	// Валидация роли
-	if req.Role != "" && req.Role != "client" && req.Role != "freelancer" && req.Role != "admin" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "роль должна быть client, freelancer или admin"})
+	// Валидация роли: администраторов и модераторов назначает только администратор
+	if _, ok := models.SelfAssignableRoles[req.Role]; req.Role != "" && !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "роль должна быть client или freelancer"})
 		return
 	}
 
@@ -129,10 +133,69 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		Password: req.Password,
 	}, meta)
 	if err != nil {
+		var throttled *service.LoginThrottledError
+		if errors.As(err, &throttled) {
+			retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       err.Error(),
+				"retry_after": retryAfter,
+				"locked":      throttled.Locked,
+			})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Пароль верный, но нужен код из приложения-аутентификатора: токены выдаст /auth/login/2fa
+	if result.TwoFactorChallenge != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     result.TwoFactorChallenge,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":    result.User,
+		"profile": result.Profile,
+		"tokens":  result.TokenPair,
+	})
+}
+
+// LoginTwoFactor обрабатывает POST /auth/login/2fa - второй шаг входа.
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meta := map[string]string{
+		"user_agent": c.GetHeader("User-Agent"),
+		"ip":         c.ClientIP(),
+	}
+
+	result, err := h.auth.LoginTwoFactor(c.Request.Context(), req.ChallengeToken, req.Code, meta)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTwoFactorLocked):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAccountSuspended):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrTwoFactorInvalidCode), errors.Is(err, service.ErrTwoFactorChallengeInvalid):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"user":    result.User,
 		"profile": result.Profile,
@@ -53,6 +53,14 @@ func CurrentUserRole(c *gin.Context) (string, error) {
 	return role, nil
 }
 
+// CurrentSessionID extracts the session ID of the access token from Gin context.
+// Returns uuid.Nil for tokens issued without a session claim.
+func CurrentSessionID(c *gin.Context) uuid.UUID {
+	raw, _ := c.Get(middleware.ContextSessionIDKey)
+	sessionID, _ := raw.(uuid.UUID)
+	return sessionID
+}
+
 // ParseUUIDParam parses UUID from URL parameter
 // Consolidates UUID parsing logic across handlers
 func ParseUUIDParam(c *gin.Context, paramName string) (uuid.UUID, error) {
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -597,7 +598,8 @@ func (h *ConversationHandler) AddMessageReaction(c *gin.Context) {
 			"message_id":      messageID,
 			"reaction":        reaction,
 		}
-		// Уведомляем обоих участников чата
+		// Оба участника получают событие с номером, в том числе после переподключения.
+		// В тему чата оно не публикуется: подписчик получил бы его дважды
 		_ = h.hub.BroadcastToUser(conversation.ClientID, "message.reaction.added", payload)
 		if conversation.FreelancerID != conversation.ClientID {
 			_ = h.hub.BroadcastToUser(conversation.FreelancerID, "message.reaction.added", payload)
@@ -676,23 +678,33 @@ func (h *ConversationHandler) ListMyConversations(c *gin.Context) {
 		return
 	}
 
+	unread, err := h.orders.CountUnreadMessages(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Обогащаем данные информацией о заказах и собеседниках
 	type ConversationResponse struct {
 		models.Conversation
 		OrderTitle *string `json:"order_title,omitempty"`
 		OtherUser  *struct {
-			ID          uuid.UUID `json:"id"`
-			DisplayName string    `json:"display_name"`
-			PhotoID     *string   `json:"photo_id,omitempty"`
-			PhotoURL    *string   `json:"photo_url,omitempty"`
+			ID          uuid.UUID  `json:"id"`
+			DisplayName string     `json:"display_name"`
+			PhotoID     *string    `json:"photo_id,omitempty"`
+			PhotoURL    *string    `json:"photo_url,omitempty"`
+			Online      bool       `json:"online"`
+			LastSeenAt  *time.Time `json:"last_seen_at,omitempty"`
 		} `json:"other_user,omitempty"`
 		LastMessage *models.Message `json:"last_message,omitempty"`
+		UnreadCount int             `json:"unread_count"`
 	}
 
 	response := make([]ConversationResponse, 0, len(conversations))
 	for _, conv := range conversations {
 		resp := ConversationResponse{
 			Conversation: conv,
+			UnreadCount:  unread[conv.ID],
 		}
 
 		// Получаем информацию о заказе
@@ -729,16 +741,21 @@ func (h *ConversationHandler) ListMyConversations(c *gin.Context) {
 					}
 				}
 				resp.OtherUser = &struct {
-					ID          uuid.UUID `json:"id"`
-					DisplayName string    `json:"display_name"`
-					PhotoID     *string   `json:"photo_id,omitempty"`
-					PhotoURL    *string   `json:"photo_url,omitempty"`
+					ID          uuid.UUID  `json:"id"`
+					DisplayName string     `json:"display_name"`
+					PhotoID     *string    `json:"photo_id,omitempty"`
+					PhotoURL    *string    `json:"photo_url,omitempty"`
+					Online      bool       `json:"online"`
+					LastSeenAt  *time.Time `json:"last_seen_at,omitempty"`
 				}{
 					ID:          otherUserID,
 					DisplayName: profile.DisplayName,
 					PhotoID:     photoIDStr,
 					PhotoURL:    photoURL,
 				}
+				if h.hub != nil {
+					resp.OtherUser.Online, resp.OtherUser.LastSeenAt = h.hub.Presence(otherUserID)
+				}
 			}
 		}
 
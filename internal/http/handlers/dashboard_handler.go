@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/repository"
@@ -52,13 +53,13 @@ type DashboardData struct {
 
 // StatsData contains user statistics.
 type StatsData struct {
-	Orders            OrderStats    `json:"orders"`
-	Proposals         ProposalStats `json:"proposals"`
-	Balance           float64       `json:"balance"`
-	AverageRating     float64       `json:"average_rating"`
-	TotalReviews      int           `json:"total_reviews"`
-	CompletionRate    float64       `json:"completion_rate"`
-	ResponseTimeHours float64       `json:"response_time_hours"`
+	Orders            OrderStats         `json:"orders"`
+	Proposals         ProposalStats      `json:"proposals"`
+	Balance           valueobject.Amount `json:"balance"`
+	AverageRating     float64            `json:"average_rating"`
+	TotalReviews      int                `json:"total_reviews"`
+	CompletionRate    float64            `json:"completion_rate"`
+	ResponseTimeHours float64            `json:"response_time_hours"`
 }
 
 // OrderStats contains order statistics.
@@ -260,7 +261,7 @@ func (h *DashboardHandler) GetDashboardData(c *gin.Context) {
 			Accepted: statsRes.proposalStats["accepted"],
 			Rejected: statsRes.proposalStats["rejected"],
 		},
-		Balance:           statsRes.userStats.TotalEarnings,
+		Balance:           statsRes.userStats.NetEarnings,
 		AverageRating:     statsRes.userStats.AverageRating,
 		TotalReviews:      statsRes.userStats.TotalReviews,
 		CompletionRate:    completionRate,
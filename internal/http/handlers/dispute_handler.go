@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
 	"github.com/ignatzorin/freelance-backend/internal/service"
 )
 
@@ -48,6 +50,41 @@ func (h *DisputeHandler) CreateDispute(c *gin.Context) {
 	c.JSON(http.StatusCreated, dispute)
 }
 
+// CreateMilestoneDispute POST /orders/:id/milestones/:milestoneId/dispute
+func (h *DisputeHandler) CreateMilestoneDispute(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		common.RespondUnauthorized(c, err.Error())
+		return
+	}
+
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "invalid order_id")
+		return
+	}
+	milestoneID, err := uuid.Parse(c.Param("milestoneId"))
+	if err != nil {
+		common.RespondBadRequest(c, "invalid milestone_id")
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	dispute, err := h.svc.CreateMilestoneDispute(c.Request.Context(), orderID, milestoneID, userID, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, dispute)
+}
+
 // GetDispute GET /orders/:id/dispute
 func (h *DisputeHandler) GetDispute(c *gin.Context) {
 	orderID, err := uuid.Parse(c.Param("id"))
@@ -80,3 +117,156 @@ func (h *DisputeHandler) ListMyDisputes(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, disputes)
 }
+
+// AddEvidence POST /disputes/:id/evidence
+func (h *DisputeHandler) AddEvidence(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		common.RespondUnauthorized(c, err.Error())
+		return
+	}
+	role, _ := common.CurrentUserRole(c)
+
+	disputeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "invalid dispute_id")
+		return
+	}
+
+	var req struct {
+		Content   *string    `json:"content"`
+		MessageID *uuid.UUID `json:"message_id"`
+		MediaID   *uuid.UUID `json:"media_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	evidence, err := h.svc.AddEvidence(c.Request.Context(), disputeID, userID, role, service.EvidenceInput{
+		Content:   req.Content,
+		MessageID: req.MessageID,
+		MediaID:   req.MediaID,
+	})
+	if err != nil {
+		respondDisputeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, evidence)
+}
+
+// ListEvidence GET /disputes/:id/evidence
+func (h *DisputeHandler) ListEvidence(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		common.RespondUnauthorized(c, err.Error())
+		return
+	}
+	role, _ := common.CurrentUserRole(c)
+
+	disputeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "invalid dispute_id")
+		return
+	}
+
+	evidence, err := h.svc.ListEvidence(c.Request.Context(), disputeID, userID, role)
+	if err != nil {
+		respondDisputeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"evidence": evidence})
+}
+
+// AdminListDisputes GET /admin/disputes
+func (h *DisputeHandler) AdminListDisputes(c *gin.Context) {
+	limit, offset := common.GetPagination(c)
+	disputes, err := h.svc.ListDisputes(c.Request.Context(), c.Query("status"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, disputes)
+}
+
+// AdminGetDispute GET /admin/disputes/:id
+func (h *DisputeHandler) AdminGetDispute(c *gin.Context) {
+	disputeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "invalid dispute_id")
+		return
+	}
+
+	disputeCase, err := h.svc.GetDisputeCase(c.Request.Context(), disputeID)
+	if err != nil {
+		respondDisputeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, disputeCase)
+}
+
+// AdminAssignDispute POST /admin/disputes/:id/assign — спор назначается на текущего администратора
+func (h *DisputeHandler) AdminAssignDispute(c *gin.Context) {
+	adminID, err := common.CurrentUserID(c)
+	if err != nil {
+		common.RespondUnauthorized(c, err.Error())
+		return
+	}
+
+	disputeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "invalid dispute_id")
+		return
+	}
+
+	dispute, err := h.svc.AssignDispute(c.Request.Context(), disputeID, adminID)
+	if err != nil {
+		respondDisputeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dispute)
+}
+
+// AdminResolveDispute POST /admin/disputes/:id/resolve
+func (h *DisputeHandler) AdminResolveDispute(c *gin.Context) {
+	adminID, err := common.CurrentUserID(c)
+	if err != nil {
+		common.RespondUnauthorized(c, err.Error())
+		return
+	}
+
+	disputeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "invalid dispute_id")
+		return
+	}
+
+	var req struct {
+		ClientPercent *float64 `json:"client_percent" binding:"required"`
+		Resolution    string   `json:"resolution" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	dispute, err := h.svc.ResolveDispute(c.Request.Context(), disputeID, adminID, *req.ClientPercent, req.Resolution)
+	if err != nil {
+		respondDisputeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dispute)
+}
+
+func respondDisputeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrDisputeNotFound), errors.Is(err, repository.ErrEscrowNotFound):
+		common.RespondNotFound(c, err.Error())
+	case errors.Is(err, service.ErrNotParticipant):
+		common.RespondForbidden(c, err.Error())
+	case errors.Is(err, repository.ErrDisputeClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/http/middleware"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+	"github.com/ignatzorin/freelance-backend/internal/service"
+)
+
+func TestDisputeHandler_AddEvidence_Unauthorized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	handler := &DisputeHandler{svc: nil}
+	r.POST("/disputes/:id/evidence", handler.AddEvidence)
+
+	req, _ := http.NewRequest("POST", "/disputes/"+uuid.New().String()+"/evidence", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestDisputeHandler_AdminResolveDispute_MissingPercent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	handler := &DisputeHandler{svc: nil}
+	r.POST("/admin/disputes/:id/resolve", func(c *gin.Context) {
+		c.Set(middleware.ContextUserIDKey, uuid.New())
+		c.Set(middleware.ContextRoleKey, "admin")
+	}, handler.AdminResolveDispute)
+
+	body := strings.NewReader(`{"resolution":"Работа выполнена частично"}`)
+	req, _ := http.NewRequest("POST", "/admin/disputes/"+uuid.New().String()+"/resolve", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRequirePermission_ForbidsClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/admin/disputes", func(c *gin.Context) {
+		c.Set(middleware.ContextRoleKey, "client")
+	}, middleware.RequirePermission(models.PermDisputesManage), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req, _ := http.NewRequest("GET", "/admin/disputes", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRequirePermission_DisputesAdminOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for role, want := range map[string]int{
+		"admin":      http.StatusOK,
+		"moderator":  http.StatusForbidden,
+		"freelancer": http.StatusForbidden,
+		"":           http.StatusForbidden,
+	} {
+		r := gin.New()
+		r.POST("/admin/disputes/:id/resolve", func(c *gin.Context) {
+			if role != "" {
+				c.Set(middleware.ContextRoleKey, role)
+			}
+		}, middleware.RequirePermission(models.PermDisputesManage), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		req, _ := http.NewRequest("POST", "/admin/disputes/"+uuid.New().String()+"/resolve", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, want, w.Code, role)
+	}
+}
+
+func TestRespondDisputeError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{repository.ErrDisputeNotFound, http.StatusNotFound},
+		{repository.ErrEscrowNotFound, http.StatusNotFound},
+		{service.ErrNotParticipant, http.StatusForbidden},
+		{repository.ErrDisputeClosed, http.StatusConflict},
+		{service.ErrDisputeNotAssigned, http.StatusBadRequest},
+		{service.ErrEvidenceEmpty, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		respondDisputeError(c, tt.err)
+		assert.Equal(t, tt.want, w.Code, tt.err.Error())
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+	"github.com/ignatzorin/freelance-backend/internal/service"
+)
+
+// KYCHandler предоставляет HTTP слой для проверки личности.
+type KYCHandler struct {
+	kyc *service.KYCService
+}
+
+// NewKYCHandler создаёт хэндлер.
+func NewKYCHandler(kyc *service.KYCService) *KYCHandler {
+	return &KYCHandler{kyc: kyc}
+}
+
+// Submit обрабатывает POST /verification/identity - подача заявки с фотографиями документа.
+func (h *KYCHandler) Submit(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		DocumentType string `json:"document_type" binding:"required"`
+		Documents    []struct {
+			Kind    string    `json:"kind" binding:"required"`
+			MediaID uuid.UUID `json:"media_id" binding:"required"`
+		} `json:"documents" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	documents := make([]models.KYCDocument, 0, len(req.Documents))
+	for _, doc := range req.Documents {
+		documents = append(documents, models.KYCDocument{Kind: doc.Kind, MediaID: doc.MediaID})
+	}
+
+	submission, err := h.kyc.Submit(c.Request.Context(), userID, req.DocumentType, documents)
+	if err != nil {
+		respondKYCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, submission)
+}
+
+// GetStatus обрабатывает GET /verification/identity - статус проверки и последняя заявка.
+func (h *KYCHandler) GetStatus(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.kyc.GetStatus(c.Request.Context(), userID)
+	if err != nil {
+		respondKYCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// AdminListSubmissions обрабатывает GET /admin/identity-verifications - очередь заявок,
+// по умолчанию только ожидающие решения.
+func (h *KYCHandler) AdminListSubmissions(c *gin.Context) {
+	limit, offset := common.GetPagination(c)
+	submissions, err := h.kyc.ListSubmissions(c.Request.Context(), c.DefaultQuery("status", models.KYCStatusPending), limit, offset)
+	if err != nil {
+		respondKYCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submissions": submissions})
+}
+
+// AdminGetSubmission обрабатывает GET /admin/identity-verifications/:id.
+// Фотографии открываются через GET /media/:id/file.
+func (h *KYCHandler) AdminGetSubmission(c *gin.Context) {
+	submissionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "некорректный идентификатор заявки")
+		return
+	}
+
+	submission, err := h.kyc.GetSubmission(c.Request.Context(), submissionID)
+	if err != nil {
+		respondKYCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, submission)
+}
+
+// AdminApprove обрабатывает POST /admin/identity-verifications/:id/approve.
+func (h *KYCHandler) AdminApprove(c *gin.Context) {
+	reviewerID, err := common.CurrentUserID(c)
+	if err != nil {
+		common.RespondUnauthorized(c, err.Error())
+		return
+	}
+
+	submissionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "некорректный идентификатор заявки")
+		return
+	}
+
+	submission, err := h.kyc.Approve(c.Request.Context(), submissionID, reviewerID)
+	if err != nil {
+		respondKYCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, submission)
+}
+
+// AdminReject обрабатывает POST /admin/identity-verifications/:id/reject.
+func (h *KYCHandler) AdminReject(c *gin.Context) {
+	reviewerID, err := common.CurrentUserID(c)
+	if err != nil {
+		common.RespondUnauthorized(c, err.Error())
+		return
+	}
+
+	submissionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "некорректный идентификатор заявки")
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	submission, err := h.kyc.Reject(c.Request.Context(), submissionID, reviewerID, req.Reason)
+	if err != nil {
+		respondKYCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, submission)
+}
+
+func respondKYCError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrKYCSubmissionNotFound):
+		common.RespondNotFound(c, "заявка не найдена")
+	case errors.Is(err, repository.ErrKYCSubmissionPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "заявка уже на рассмотрении"})
+	case errors.Is(err, repository.ErrKYCSubmissionReviewed):
+		c.JSON(http.StatusConflict, gin.H{"error": "решение по заявке уже принято"})
+	case errors.Is(err, service.ErrKYCAlreadyVerified):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrKYCSelfReview):
+		common.RespondForbidden(c, err.Error())
+	case errors.Is(err, service.ErrKYCDocumentType),
+		errors.Is(err, service.ErrKYCDocumentsIncomplete),
+		errors.Is(err, service.ErrKYCDocumentInvalid),
+		errors.Is(err, service.ErrKYCRejectReason),
+		errors.Is(err, service.ErrKYCStatusInvalid):
+		common.RespondBadRequest(c, err.Error())
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
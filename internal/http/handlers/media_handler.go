@@ -38,10 +38,19 @@ var allowedExtensions = map[string]bool{
 	".svg":  true,
 }
 
+// Фотографии документов для проверки личности: только растровые изображения
+var documentMimeTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
 // MediaHandler управляет загрузкой и удалением медиа-файлов.
 type MediaHandler struct {
 	repo    *repository.MediaRepository
 	storage *storage.PhotoStorage
+	// Хранилище приватных файлов; его каталог не раздаётся по /media
+	privateStorage *storage.PhotoStorage
 }
 
 // NewMediaHandler создаёт новый хэндлер.
@@ -49,8 +58,28 @@ func NewMediaHandler(repo *repository.MediaRepository, storage *storage.PhotoSto
 	return &MediaHandler{repo: repo, storage: storage}
 }
 
+// SetPrivateStorage подключает хранилище приватных файлов (фотографий документов).
+func (h *MediaHandler) SetPrivateStorage(storage *storage.PhotoStorage) {
+	h.privateStorage = storage
+}
+
 // UploadPhoto обрабатывает POST /media/photos.
 func (h *MediaHandler) UploadPhoto(c *gin.Context) {
+	h.upload(c, h.storage, true, allowedMimeTypes)
+}
+
+// UploadDocument обрабатывает POST /media/documents - загрузка фотографии документа
+// в приватное хранилище. Файл доступен только владельцу и модераторам через GET /media/:id/file.
+func (h *MediaHandler) UploadDocument(c *gin.Context) {
+	if h.privateStorage == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "приватное хранилище не настроено"})
+		return
+	}
+	h.upload(c, h.privateStorage, false, documentMimeTypes)
+}
+
+// upload проверяет и сохраняет файл из поля file; mimeTypes — допустимые реальные типы файла.
+func (h *MediaHandler) upload(c *gin.Context, store *storage.PhotoStorage, isPublic bool, mimeTypes map[string]bool) {
 	userID, err := common.CurrentUserID(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
@@ -105,9 +134,9 @@ func (h *MediaHandler) UploadPhoto(c *gin.Context) {
 
 	// Проверяем, что это изображение
 	contentType := kind.MIME.Value
-	if !allowedMimeTypes[contentType] {
+	if !mimeTypes[contentType] {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("неподдерживаемый тип файла (%s). Разрешены изображения: %s", contentType, strings.Join(getAllowedMimeTypes(), ", ")),
+			"error": fmt.Sprintf("неподдерживаемый тип файла (%s). Разрешены изображения: %s", contentType, strings.Join(getAllowedMimeTypes(mimeTypes), ", ")),
 		})
 		return
 	}
@@ -130,7 +159,7 @@ func (h *MediaHandler) UploadPhoto(c *gin.Context) {
 		}
 	}
 
-	relativePath, size, err := h.storage.Save(c.Request.Context(), userID, file.Filename, src)
+	relativePath, size, err := store.Save(c.Request.Context(), userID, file.Filename, src)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -141,7 +170,7 @@ func (h *MediaHandler) UploadPhoto(c *gin.Context) {
 		FilePath: filepath.ToSlash(relativePath),
 		FileType: contentType,
 		FileSize: size,
-		IsPublic: true,
+		IsPublic: isPublic,
 	}
 
 	if err := h.repo.Create(c.Request.Context(), media); err != nil {
@@ -183,16 +212,85 @@ func (h *MediaHandler) DeleteMedia(c *gin.Context) {
 	}
 
 	if err := h.repo.Delete(c.Request.Context(), mediaID); err != nil {
+		if errors.Is(err, repository.ErrMediaInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "файл приложен к заявке на проверку личности"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.storage.Delete(c.Request.Context(), media.FilePath); err != nil {
+	if store := h.storageFor(media); store != nil {
+		if err := store.Delete(c.Request.Context(), media.FilePath); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetFile обрабатывает GET /media/:id/file. Приватные файлы отдаются только владельцу
+// и модераторам, проверяющим личность пользователей.
+func (h *MediaHandler) GetFile(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	mediaID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный идентификатор"})
+		return
+	}
+
+	media, err := h.repo.GetByID(c.Request.Context(), mediaID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMediaNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "файл не найден"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.Status(http.StatusNoContent)
+	if !media.IsPublic {
+		role, _ := common.CurrentUserRole(c)
+		owner := media.UserID != nil && *media.UserID == userID
+		if !owner && !models.HasPermission(role, models.PermIdentityReview) {
+			// Не раскрываем существование чужих приватных файлов
+			c.JSON(http.StatusNotFound, gin.H{"error": "файл не найден"})
+			return
+		}
+	}
+
+	store := h.storageFor(media)
+	if store == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "файл не найден"})
+		return
+	}
+	f, err := store.Open(c.Request.Context(), media.FilePath)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "файл не найден"})
+		return
+	}
+	defer f.Close()
+
+	if !media.IsPublic {
+		c.Header("Cache-Control", "private, no-store")
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Type", media.FileType)
+	http.ServeContent(c.Writer, c.Request, "", media.CreatedAt, f)
+}
+
+// storageFor возвращает хранилище, в котором лежит файл.
+func (h *MediaHandler) storageFor(media *models.MediaFile) *storage.PhotoStorage {
+	if media.IsPublic {
+		return h.storage
+	}
+	return h.privateStorage
 }
 
 // getAllowedExtensions возвращает список разрешённых расширений.
@@ -205,9 +303,9 @@ func getAllowedExtensions() []string {
 }
 
 // getAllowedMimeTypes возвращает список разрешённых MIME типов.
-func getAllowedMimeTypes() []string {
-	types := make([]string, 0, len(allowedMimeTypes))
-	for mimeType := range allowedMimeTypes {
+func getAllowedMimeTypes(mimeTypes map[string]bool) []string {
+	types := make([]string, 0, len(mimeTypes))
+	for mimeType := range mimeTypes {
 		types = append(types, mimeType)
 	}
 	return types
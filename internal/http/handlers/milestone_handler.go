@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+	"github.com/ignatzorin/freelance-backend/internal/service"
+)
+
+// MilestoneHandler обслуживает этапы заказа и их поэтапную оплату.
+type MilestoneHandler struct {
+	milestones *service.MilestoneService
+}
+
+// NewMilestoneHandler создаёт обработчик этапов.
+func NewMilestoneHandler(milestones *service.MilestoneService) *MilestoneHandler {
+	return &MilestoneHandler{milestones: milestones}
+}
+
+type milestoneRequest struct {
+	Title       string             `json:"title" binding:"required"`
+	Description *string            `json:"description"`
+	Amount      valueobject.Amount `json:"amount" binding:"required,gt=0"`
+	DueAt       *time.Time         `json:"due_at"`
+}
+
+func (r milestoneRequest) toInput() service.MilestoneInput {
+	return service.MilestoneInput{
+		Title:       r.Title,
+		Description: r.Description,
+		Amount:      r.Amount,
+		DueAt:       r.DueAt,
+	}
+}
+
+// ListMilestones GET /orders/:id/milestones
+func (h *MilestoneHandler) ListMilestones(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		common.RespondUnauthorized(c, err.Error())
+		return
+	}
+
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "неверный order_id")
+		return
+	}
+
+	milestones, err := h.milestones.ListMilestones(c.Request.Context(), orderID, userID)
+	if err != nil {
+		respondMilestoneError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"milestones": milestones})
+}
+
+// CreateMilestone POST /orders/:id/milestones
+func (h *MilestoneHandler) CreateMilestone(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		common.RespondUnauthorized(c, err.Error())
+		return
+	}
+
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "неверный order_id")
+		return
+	}
+
+	var req milestoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	milestone, err := h.milestones.CreateMilestone(c.Request.Context(), orderID, userID, req.toInput())
+	if err != nil {
+		respondMilestoneError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, milestone)
+}
+
+// UpdateMilestone PUT /orders/:id/milestones/:milestoneId
+func (h *MilestoneHandler) UpdateMilestone(c *gin.Context) {
+	userID, orderID, milestoneID, ok := milestoneParams(c)
+	if !ok {
+		return
+	}
+
+	var req milestoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	milestone, err := h.milestones.UpdateMilestone(c.Request.Context(), orderID, milestoneID, userID, req.toInput())
+	if err != nil {
+		respondMilestoneError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, milestone)
+}
+
+// DeleteMilestone DELETE /orders/:id/milestones/:milestoneId
+func (h *MilestoneHandler) DeleteMilestone(c *gin.Context) {
+	userID, orderID, milestoneID, ok := milestoneParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.milestones.DeleteMilestone(c.Request.Context(), orderID, milestoneID, userID); err != nil {
+		respondMilestoneError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "этап удалён"})
+}
+
+// FundMilestone POST /orders/:id/milestones/:milestoneId/fund
+func (h *MilestoneHandler) FundMilestone(c *gin.Context) {
+	h.transition(c, h.milestones.FundMilestone)
+}
+
+// SubmitMilestone POST /orders/:id/milestones/:milestoneId/submit
+func (h *MilestoneHandler) SubmitMilestone(c *gin.Context) {
+	h.transition(c, h.milestones.SubmitMilestone)
+}
+
+// AcceptMilestone POST /orders/:id/milestones/:milestoneId/accept
+func (h *MilestoneHandler) AcceptMilestone(c *gin.Context) {
+	h.transition(c, h.milestones.AcceptMilestone)
+}
+
+// RefundMilestone POST /orders/:id/milestones/:milestoneId/refund
+func (h *MilestoneHandler) RefundMilestone(c *gin.Context) {
+	h.transition(c, h.milestones.RefundMilestone)
+}
+
+type milestoneTransition func(ctx context.Context, orderID, milestoneID, userID uuid.UUID) (*models.Milestone, error)
+
+// transition выполняет смену статуса этапа без тела запроса.
+func (h *MilestoneHandler) transition(c *gin.Context, fn milestoneTransition) {
+	userID, orderID, milestoneID, ok := milestoneParams(c)
+	if !ok {
+		return
+	}
+
+	milestone, err := fn(c.Request.Context(), orderID, milestoneID, userID)
+	if err != nil {
+		respondMilestoneError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, milestone)
+}
+
+func milestoneParams(c *gin.Context) (userID, orderID, milestoneID uuid.UUID, ok bool) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		common.RespondUnauthorized(c, err.Error())
+		return
+	}
+
+	orderID, err = uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "неверный order_id")
+		return
+	}
+
+	milestoneID, err = uuid.Parse(c.Param("milestoneId"))
+	if err != nil {
+		common.RespondBadRequest(c, "неверный milestone_id")
+		return
+	}
+
+	return userID, orderID, milestoneID, true
+}
+
+func respondMilestoneError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrMilestoneForbidden):
+		common.RespondForbidden(c, err.Error())
+	case errors.Is(err, repository.ErrOrderEscrowExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrOrderNotFound),
+		errors.Is(err, repository.ErrMilestoneNotFound),
+		errors.Is(err, service.ErrMilestoneWrongOrder):
+		common.RespondNotFound(c, err.Error())
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
+	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/repository"
 	"github.com/ignatzorin/freelance-backend/internal/service"
 )
@@ -160,3 +161,70 @@ func (h *NotificationHandler) CountUnread(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"count": count})
 }
 
+// GetPreferences обрабатывает GET /notifications/preferences.
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.notifications.GetSettings(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdatePreferences обрабатывает PUT /notifications/preferences - заменяет настройки целиком.
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Preferences     []models.NotificationPreference `json:"preferences"`
+		QuietHoursStart *string                         `json:"quiet_hours_start"`
+		QuietHoursEnd   *string                         `json:"quiet_hours_end"`
+		Timezone        string                          `json:"timezone"`
+		DigestEnabled   bool                            `json:"digest_enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	input := service.NotificationSettingsInput{
+		Preferences:   req.Preferences,
+		Timezone:      req.Timezone,
+		DigestEnabled: req.DigestEnabled,
+	}
+	if req.QuietHoursStart != nil {
+		input.QuietHoursStart = *req.QuietHoursStart
+	}
+	if req.QuietHoursEnd != nil {
+		input.QuietHoursEnd = *req.QuietHoursEnd
+	}
+
+	settings, err := h.notifications.UpdateSettings(c.Request.Context(), userID, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotificationChannel),
+			errors.Is(err, service.ErrNotificationEventType),
+			errors.Is(err, service.ErrNotificationMandatory),
+			errors.Is(err, service.ErrNotificationQuietHours),
+			errors.Is(err, service.ErrNotificationPreferencesLimit),
+			errors.Is(err, service.ErrNotificationTelegramNotLinked):
+			common.RespondBadRequest(c, err.Error())
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
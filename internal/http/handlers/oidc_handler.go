@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
+	"github.com/ignatzorin/freelance-backend/internal/service"
+)
+
+// OIDCHandler предоставляет HTTP слой для входа через внешних провайдеров.
+type OIDCHandler struct {
+	oidc *service.OIDCService
+}
+
+// NewOIDCHandler создаёт хэндлер.
+func NewOIDCHandler(oidc *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidc: oidc}
+}
+
+// Providers обрабатывает GET /auth/oidc/providers.
+func (h *OIDCHandler) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.oidc.Providers()})
+}
+
+// Start обрабатывает POST /auth/oidc/:provider/start - возвращает адрес страницы входа провайдера.
+func (h *OIDCHandler) Start(c *gin.Context) {
+	start, err := h.oidc.Start(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, start)
+}
+
+// Callback обрабатывает POST /auth/oidc/callback - завершает вход кодом, с которым вернул провайдер.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var req struct {
+		State string `json:"state" binding:"required"`
+		Code  string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meta := map[string]string{
+		"user_agent": c.GetHeader("User-Agent"),
+		"ip":         c.ClientIP(),
+	}
+
+	result, err := h.oidc.Callback(c.Request.Context(), req.State, req.Code, meta)
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+
+	// Токены выдаст /auth/login/2fa после ввода кода из приложения-аутентификатора
+	if result.TwoFactorChallenge != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     result.TwoFactorChallenge,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":    result.User,
+		"profile": result.Profile,
+		"tokens":  result.TokenPair,
+	})
+}
+
+// Identities обрабатывает GET /auth/oidc/identities - привязанные учётные записи провайдеров.
+func (h *OIDCHandler) Identities(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	identities, err := h.oidc.ListIdentities(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+func respondOIDCError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrOIDCProviderUnknown):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOIDCStateInvalid),
+		errors.Is(err, service.ErrOIDCEmailUnverified):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOIDCLoginFailed):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAccountSuspended):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOIDCAccountUnverified):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
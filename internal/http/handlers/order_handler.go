@@ -4,12 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/dto"
 	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
 	"github.com/ignatzorin/freelance-backend/internal/models"
@@ -40,16 +40,6 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	user, err := h.users.GetByID(c.Request.Context(), userID)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "пользователь не найден"})
-		return
-	}
-	if user.Role != "client" && user.Role != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "только заказчики могут создавать заказы"})
-		return
-	}
-
 	var req dto.CreateOrderRequest
 	if err := common.BindAndValidate(c, &req); err != nil {
 		common.RespondBadRequest(c, err.Error())
@@ -112,6 +102,7 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		Description:   req.Description,
 		BudgetMin:     req.BudgetMin,
 		BudgetMax:     req.BudgetMax,
+		Currency:      req.Currency,
 		DeadlineAt:    deadline,
 		Requirements:  requirements,
 		AttachmentIDs: attachmentIDs,
@@ -144,6 +135,7 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 			"order":   order,
 			"message": "Заказ успешно создан",
 		})
+		_ = h.hub.PublishToTopic(ws.DashboardTopic(userID), "dashboard.updated", gin.H{"reason": "orders.new"})
 	}
 
 	// Invalidate cache
@@ -179,12 +171,12 @@ func (h *OrderHandler) ListOrders(c *gin.Context) {
 	}
 
 	if budgetMinStr := c.Query("budget_min"); budgetMinStr != "" {
-		if budgetMin, err := strconv.ParseFloat(budgetMinStr, 64); err == nil {
+		if budgetMin, err := valueobject.ParseAmount(budgetMinStr); err == nil {
 			params.BudgetMin = &budgetMin
 		}
 	}
 	if budgetMaxStr := c.Query("budget_max"); budgetMaxStr != "" {
-		if budgetMax, err := strconv.ParseFloat(budgetMaxStr, 64); err == nil {
+		if budgetMax, err := valueobject.ParseAmount(budgetMaxStr); err == nil {
 			params.BudgetMax = &budgetMax
 		}
 	}
@@ -328,6 +320,7 @@ func (h *OrderHandler) UpdateOrder(c *gin.Context) {
 		Description:   req.Description,
 		BudgetMin:     req.BudgetMin,
 		BudgetMax:     req.BudgetMax,
+		Currency:      req.Currency,
 		Status:        status,
 		DeadlineAt:    deadline,
 		Requirements:  requirements,
@@ -338,6 +331,10 @@ func (h *OrderHandler) UpdateOrder(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "заказ не найден"})
 			return
 		}
+		if errors.Is(err, service.ErrOrderMilestonesOpen) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -379,6 +376,7 @@ func (h *OrderHandler) UpdateOrder(c *gin.Context) {
 				_ = h.hub.BroadcastToUser(proposal.FreelancerID, "orders.updated", wsPayload)
 			}
 		}
+		_ = h.hub.PublishToTopic(ws.DashboardTopic(userID), "dashboard.updated", gin.H{"reason": "orders.updated"})
 	}
 
 	updated.Attachments = attachments
@@ -435,6 +433,10 @@ func (h *OrderHandler) DeleteOrder(c *gin.Context) {
 		return
 	}
 
+	if h.hub != nil {
+		_ = h.hub.PublishToTopic(ws.DashboardTopic(userID), "dashboard.updated", gin.H{"reason": "orders.deleted"})
+	}
+
 	// Invalidate cache
 	if h.cache != nil {
 		h.cache.InvalidateUserCache(userID)
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
 	"github.com/ignatzorin/freelance-backend/internal/service"
 )
@@ -18,7 +20,7 @@ func NewPaymentHandler(payments *service.PaymentService) *PaymentHandler {
 	return &PaymentHandler{payments: payments}
 }
 
-// GetBalance GET /payments/balance
+// GetBalance GET /payments/balance?currency=RUB
 func (h *PaymentHandler) GetBalance(c *gin.Context) {
 	userID, err := common.CurrentUserID(c)
 	if err != nil {
@@ -26,8 +28,12 @@ func (h *PaymentHandler) GetBalance(c *gin.Context) {
 		return
 	}
 
-	balance, err := h.payments.GetBalance(c.Request.Context(), userID)
+	balance, err := h.payments.GetBalance(c.Request.Context(), userID, c.Query("currency"))
 	if err != nil {
+		if errors.Is(err, service.ErrUnsupportedCurrency) {
+			common.RespondBadRequest(c, "неподдерживаемая валюта")
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -35,6 +41,23 @@ func (h *PaymentHandler) GetBalance(c *gin.Context) {
 	c.JSON(http.StatusOK, balance)
 }
 
+// ListBalances GET /payments/balances
+func (h *PaymentHandler) ListBalances(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		common.RespondUnauthorized(c, err.Error())
+		return
+	}
+
+	balances, err := h.payments.ListBalances(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"balances": balances})
+}
+
 // Deposit POST /payments/deposit
 func (h *PaymentHandler) Deposit(c *gin.Context) {
 	userID, err := common.CurrentUserID(c)
@@ -44,14 +67,15 @@ func (h *PaymentHandler) Deposit(c *gin.Context) {
 	}
 
 	var req struct {
-		Amount float64 `json:"amount" binding:"required,gt=0"`
+		Amount   valueobject.Amount `json:"amount" binding:"required,gt=0"`
+		Currency string             `json:"currency"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		common.RespondBadRequest(c, "сумма должна быть положительной")
 		return
 	}
 
-	transaction, err := h.payments.Deposit(c.Request.Context(), userID, req.Amount)
+	transaction, err := h.payments.Deposit(c.Request.Context(), userID, req.Amount, req.Currency)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -69,9 +93,10 @@ func (h *PaymentHandler) CreateEscrow(c *gin.Context) {
 	}
 
 	var req struct {
-		OrderID      string  `json:"order_id" binding:"required"`
-		FreelancerID string  `json:"freelancer_id" binding:"required"`
-		Amount       float64 `json:"amount" binding:"required,gt=0"`
+		OrderID      string             `json:"order_id" binding:"required"`
+		FreelancerID string             `json:"freelancer_id" binding:"required"`
+		Amount       valueobject.Amount `json:"amount" binding:"required,gt=0"`
+		Currency     string             `json:"currency"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		common.RespondBadRequest(c, err.Error())
@@ -89,7 +114,7 @@ func (h *PaymentHandler) CreateEscrow(c *gin.Context) {
 		return
 	}
 
-	escrow, err := h.payments.CreateEscrow(c.Request.Context(), orderID, userID, freelancerID, req.Amount)
+	escrow, err := h.payments.CreateEscrow(c.Request.Context(), orderID, userID, freelancerID, req.Amount, req.Currency)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
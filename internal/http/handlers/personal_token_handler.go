@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+	"github.com/ignatzorin/freelance-backend/internal/service"
+)
+
+// PersonalTokenHandler предоставляет HTTP слой для персональных токенов доступа.
+type PersonalTokenHandler struct {
+	tokens *service.PersonalTokenService
+}
+
+// NewPersonalTokenHandler создаёт хэндлер.
+func NewPersonalTokenHandler(tokens *service.PersonalTokenService) *PersonalTokenHandler {
+	return &PersonalTokenHandler{tokens: tokens}
+}
+
+// List обрабатывает GET /auth/tokens - действующие токены без их значений.
+func (h *PersonalTokenHandler) List(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.tokens.List(c.Request.Context(), userID)
+	if err != nil {
+		respondPersonalTokenError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+// Create обрабатывает POST /auth/tokens - выпускает токен; значение возвращается только в этом ответе.
+func (h *PersonalTokenHandler) Create(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Name          string   `json:"name" binding:"required"`
+		Scopes        []string `json:"scopes" binding:"required"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, err := h.tokens.Create(c.Request.Context(), userID, req.Name, req.Scopes, ttl)
+	if err != nil {
+		respondPersonalTokenError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// Revoke обрабатывает DELETE /auth/tokens/:id.
+func (h *PersonalTokenHandler) Revoke(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokenID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "некорректный идентификатор токена")
+		return
+	}
+
+	if err := h.tokens.Revoke(c.Request.Context(), userID, tokenID); err != nil {
+		respondPersonalTokenError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondPersonalTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrPersonalTokenNotFound):
+		common.RespondNotFound(c, "токен не найден")
+	case errors.Is(err, service.ErrPersonalTokenLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPersonalTokenName),
+		errors.Is(err, service.ErrPersonalTokenScopes),
+		errors.Is(err, service.ErrPersonalTokenTTL):
+		common.RespondBadRequest(c, err.Error())
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+	"github.com/ignatzorin/freelance-backend/internal/service"
+)
+
+// PrivacyHandler предоставляет HTTP слой для выгрузки данных и удаления аккаунта.
+type PrivacyHandler struct {
+	privacy *service.PrivacyService
+}
+
+// NewPrivacyHandler создаёт хэндлер.
+func NewPrivacyHandler(privacy *service.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{privacy: privacy}
+}
+
+// RequestExport обрабатывает POST /account/exports - ставит в очередь сборку архива.
+func (h *PrivacyHandler) RequestExport(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	export, err := h.privacy.RequestExport(c.Request.Context(), userID)
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, export)
+}
+
+// ListExports обрабатывает GET /account/exports.
+func (h *PrivacyHandler) ListExports(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	exports, err := h.privacy.ListExports(c.Request.Context(), userID)
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"exports": exports})
+}
+
+// DownloadExport обрабатывает GET /account/exports/:id/download - отдаёт готовый ZIP-архив.
+func (h *PrivacyHandler) DownloadExport(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	exportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "некорректный идентификатор выгрузки")
+		return
+	}
+
+	export, f, err := h.privacy.OpenExport(c.Request.Context(), userID, exportID)
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+	defer f.Close()
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="freelance-data-%s.zip"`, export.CreatedAt.Format("2006-01-02")))
+	http.ServeContent(c.Writer, c.Request, "", *export.CompletedAt, f)
+}
+
+// GetDeletion обрабатывает GET /account/deletion - дата удаления и что ему мешает.
+func (h *PrivacyHandler) GetDeletion(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.privacy.DeletionStatus(c.Request.Context(), userID)
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// RequestDeletion обрабатывает POST /account/deletion - планирует удаление аккаунта.
+func (h *PrivacyHandler) RequestDeletion(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.privacy.RequestDeletion(c.Request.Context(), userID)
+	if err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, status)
+}
+
+// CancelDeletion обрабатывает DELETE /account/deletion.
+func (h *PrivacyHandler) CancelDeletion(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.privacy.CancelDeletion(c.Request.Context(), userID); err != nil {
+		respondPrivacyError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondPrivacyError(c *gin.Context, err error) {
+	var blocked *service.AccountDeletionBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "blockers": blocked.Blockers})
+	case errors.Is(err, repository.ErrDataExportNotFound):
+		common.RespondNotFound(c, "выгрузка не найдена")
+	case errors.Is(err, repository.ErrDataExportInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": "архив уже собирается"})
+	case errors.Is(err, service.ErrDataExportNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrAccountDeletionNotScheduled):
+		common.RespondNotFound(c, "удаление аккаунта не запланировано")
+	case errors.Is(err, repository.ErrUserNotFound):
+		common.RespondNotFound(c, "пользователь не найден")
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -10,14 +10,17 @@ import (
 	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/repository"
+	"github.com/ignatzorin/freelance-backend/internal/service"
 	"github.com/ignatzorin/freelance-backend/internal/validation"
 	"github.com/ignatzorin/freelance-backend/internal/ws"
 )
 
 // ProfileHandler отвечает за работу с профилем.
 type ProfileHandler struct {
-	users *repository.UserRepository
-	hub   *ws.Hub
+	users   *repository.UserRepository
+	hub     *ws.Hub
+	tokens  *service.TokenManager
+	revoker service.SessionRevoker
 }
 
 // NewProfileHandler создаёт экземпляр.
@@ -25,6 +28,24 @@ func NewProfileHandler(users *repository.UserRepository, hub *ws.Hub) *ProfileHa
 	return &ProfileHandler{users: users, hub: hub}
 }
 
+// SetTokenManager позволяет выдавать новый access токен после смены роли,
+// так как права проверяются по роли из токена.
+func (h *ProfileHandler) SetTokenManager(tokens *service.TokenManager) {
+	h.tokens = tokens
+}
+
+// SetSessionRevoker устанавливает получателя уведомлений о сессиях, завершённых
+// при смене роли администратором.
+func (h *ProfileHandler) SetSessionRevoker(revoker service.SessionRevoker) {
+	h.revoker = revoker
+}
+
+// roleChangeResponse — пользователь после смены роли и, если доступен, новый access токен.
+type roleChangeResponse struct {
+	*models.User
+	AccessToken string `json:"access_token,omitempty"`
+}
+
 // GetMe возвращает профиль текущего пользователя.
 func (h *ProfileHandler) GetMe(c *gin.Context) {
 	userID, err := common.CurrentUserID(c)
@@ -229,10 +250,11 @@ func (h *ProfileHandler) GetUserProfile(c *gin.Context) {
 	// Возвращаем публичную информацию (без email и других приватных данных)
 	c.JSON(http.StatusOK, gin.H{
 		"user": gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"role":       user.Role,
-			"created_at": user.CreatedAt,
+			"id":                user.ID,
+			"username":          user.Username,
+			"role":              user.Role,
+			"identity_verified": user.IdentityVerified,
+			"created_at":        user.CreatedAt,
 		},
 		"profile":          profile,
 		"stats":            stats,
@@ -258,6 +280,17 @@ func (h *ProfileHandler) UpdateRole(c *gin.Context) {
 		return
 	}
 
+	// Администратор и модератор не могут сами сменить роль — это делает администратор
+	current, err := h.users.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "пользователь не найден"})
+		return
+	}
+	if models.IsPrivilegedRole(current.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "роль администратора или модератора меняет только администратор"})
+		return
+	}
+
 	// Обновляем роль
 	if err := h.users.UpdateRole(c.Request.Context(), userID, req.Role); err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
@@ -275,5 +308,86 @@ func (h *ProfileHandler) UpdateRole(c *gin.Context) {
 		return
 	}
 
+	resp := roleChangeResponse{User: user}
+	if h.tokens != nil {
+		if token, err := h.tokens.GenerateAccess(user, common.CurrentSessionID(c)); err == nil {
+			resp.AccessToken = token
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// AdminUpdateRole обрабатывает PUT /admin/users/:id/role - назначение роли администратором.
+func (h *ProfileHandler) AdminUpdateRole(c *gin.Context) {
+	adminID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "неверный user_id"})
+		return
+	}
+	if userID == adminID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "нельзя изменить собственную роль"})
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required,oneof=client freelancer moderator admin"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	previous, err := h.users.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "пользователь не найден"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.users.UpdateRole(c.Request.Context(), userID, req.Role); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "пользователь не найден"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.users.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось получить обновленного пользователя"})
+		return
+	}
+	if previous.Role == user.Role {
+		c.JSON(http.StatusOK, user)
+		return
+	}
+
+	if h.hub != nil {
+		_ = h.hub.BroadcastToUser(userID, "role_changed", gin.H{"role": user.Role})
+	}
+
+	// Права проверяются по роли из токена, поэтому все сессии пользователя завершаются:
+	// прежняя роль не должна действовать до истечения выданных токенов
+	revoked, err := h.users.DeleteSessionsExcept(c.Request.Context(), userID, uuid.Nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "роль изменена, но не удалось завершить сессии пользователя"})
+		return
+	}
+	if h.revoker != nil {
+		h.revoker.SessionsRevoked(userID, revoked...)
+		h.revoker.UserSuspended(userID)
+	}
+
 	c.JSON(http.StatusOK, user)
 }
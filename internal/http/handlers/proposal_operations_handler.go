@@ -37,17 +37,6 @@ func (h *ProposalOperationsHandler) CreateProposal(c *gin.Context) {
 		return
 	}
 
-	// Проверяем роль пользователя из базы данных (не из токена, так как токен может быть устаревшим)
-	user, err := h.users.GetByID(c.Request.Context(), userID)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "пользователь не найден"})
-		return
-	}
-	if user.Role != "freelancer" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "только исполнители могут создавать предложения к заказам"})
-		return
-	}
-
 	orderID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "неверный идентификатор заказа"})
@@ -73,7 +62,7 @@ func (h *ProposalOperationsHandler) CreateProposal(c *gin.Context) {
 			return
 		}
 		if *req.Amount > validation.MaxBudget {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("сумма предложения не может превышать %.0f", validation.MaxBudget)})
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("сумма предложения не может превышать %.0f", validation.MaxBudget.Float64())})
 			return
 		}
 	}
@@ -83,6 +72,7 @@ func (h *ProposalOperationsHandler) CreateProposal(c *gin.Context) {
 		FreelancerID: userID,
 		CoverLetter:  req.CoverLetter,
 		Amount:       req.Amount,
+		Currency:     req.Currency,
 	})
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -111,6 +101,14 @@ func (h *ProposalOperationsHandler) CreateProposal(c *gin.Context) {
 				"proposal": proposal,
 				"message":  "Предложение успешно отправлено",
 			})
+			// Счётчик откликов на странице заказа и дашборд заказчика
+			if count, err := h.orders.CountProposals(c.Request.Context(), orderID); err == nil {
+				_ = h.hub.PublishToTopic(ws.OrderTopic(orderID), "order.proposals_count", gin.H{
+					"order_id":        orderID,
+					"proposals_count": count,
+				})
+			}
+			_ = h.hub.PublishToTopic(ws.DashboardTopic(order.ClientID), "dashboard.updated", gin.H{"reason": "proposals.new"})
 		}
 	}
 
@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
 	"github.com/ignatzorin/freelance-backend/internal/service"
 )
 
@@ -62,3 +64,77 @@ func (h *ReportHandler) ListMyReports(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, reports)
 }
+
+// AdminListReports GET /admin/reports
+func (h *ReportHandler) AdminListReports(c *gin.Context) {
+	limit, offset := common.GetPagination(c)
+	targets, err := h.svc.ModerationQueue(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, targets)
+}
+
+// AdminGetReportTarget GET /admin/reports/:type/:id
+func (h *ReportHandler) AdminGetReportTarget(c *gin.Context) {
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "invalid target_id")
+		return
+	}
+
+	targetCase, err := h.svc.GetTargetCase(c.Request.Context(), c.Param("type"), targetID)
+	if err != nil {
+		respondReportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, targetCase)
+}
+
+// AdminResolveReportTarget POST /admin/reports/:type/:id/resolve
+func (h *ReportHandler) AdminResolveReportTarget(c *gin.Context) {
+	moderatorID, err := common.CurrentUserID(c)
+	if err != nil {
+		common.RespondUnauthorized(c, err.Error())
+		return
+	}
+
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		common.RespondBadRequest(c, "invalid target_id")
+		return
+	}
+
+	var req struct {
+		Status string  `json:"status" binding:"required"`
+		Action *string `json:"action"`
+		Note   *string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	reports, err := h.svc.ResolveTarget(c.Request.Context(), moderatorID, c.Param("type"), targetID, service.ReportDecision{
+		Status: req.Status,
+		Action: req.Action,
+		Note:   req.Note,
+	})
+	if err != nil {
+		respondReportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+func respondReportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNoPendingReports), errors.Is(err, repository.ErrReportTargetNotFound):
+		common.RespondNotFound(c, err.Error())
+	case errors.Is(err, repository.ErrReportActionUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
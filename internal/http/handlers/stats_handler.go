@@ -87,7 +87,14 @@ func (h *StatsHandler) GetMyStats(c *gin.Context) {
 			"accepted": proposalStats["accepted"],
 			"rejected": proposalStats["rejected"],
 		},
-		"balance":          userStats.TotalEarnings,
+		"balance":          userStats.NetEarnings,
+		"earnings": gin.H{
+			"gross":         userStats.TotalEarnings,
+			"platform_fees": userStats.PlatformFees,
+			"net":           userStats.NetEarnings,
+			"currency":      models.DefaultCurrency,
+			"by_currency":   earningsByCurrency(userStats.Earnings),
+		},
 		"average_rating":   userStats.AverageRating,
 		"total_reviews":    userStats.TotalReviews,
 		"completion_rate":  completionRate,
@@ -97,3 +104,17 @@ func (h *StatsHandler) GetMyStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+
+// earningsByCurrency раскладывает заработок по валютам выплат вместе с комиссией платформы.
+func earningsByCurrency(earnings []models.CurrencyEarnings) []gin.H {
+	result := make([]gin.H, 0, len(earnings))
+	for _, e := range earnings {
+		result = append(result, gin.H{
+			"currency":      e.Currency,
+			"gross":         e.TotalEarnings,
+			"platform_fees": e.PlatformFees,
+			"net":           e.NetEarnings,
+		})
+	}
+	return result
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
+	"github.com/ignatzorin/freelance-backend/internal/service"
+)
+
+// TwoFactorHandler предоставляет HTTP слой для подключения и отключения TOTP.
+type TwoFactorHandler struct {
+	twoFactor *service.TwoFactorService
+}
+
+// NewTwoFactorHandler создаёт хэндлер.
+func NewTwoFactorHandler(twoFactor *service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactor: twoFactor}
+}
+
+// Status обрабатывает GET /auth/2fa.
+func (h *TwoFactorHandler) Status(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.twoFactor.Status(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// Setup обрабатывает POST /auth/2fa/setup - выдаёт секрет и otpauth URI для QR кода.
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	setup, err := h.twoFactor.Setup(c.Request.Context(), userID)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// Enable обрабатывает POST /auth/2fa/enable - подтверждает подключение первым кодом.
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.twoFactor.Enable(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// Disable обрабатывает POST /auth/2fa/disable.
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.twoFactor.Disable(c.Request.Context(), userID, req.Password, req.Code); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "двухфакторная аутентификация отключена"})
+}
+
+// RegenerateRecoveryCodes обрабатывает POST /auth/2fa/recovery-codes.
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, err := common.CurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTwoFactorInvalidCode),
+		errors.Is(err, service.ErrWrongPassword),
+		errors.Is(err, service.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTwoFactorLocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
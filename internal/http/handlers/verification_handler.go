@@ -1,6 +1,10 @@
 package handlers
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -10,13 +14,20 @@ import (
 )
 
 type VerificationHandler struct {
-	svc *service.VerificationService
+	svc            *service.VerificationService
+	telegramSecret string
 }
 
 func NewVerificationHandler(s *service.VerificationService) *VerificationHandler {
 	return &VerificationHandler{svc: s}
 }
 
+// SetTelegramWebhookSecret включает приём обновлений Telegram бота; secret передаётся
+// в setWebhook как secret_token и приходит в заголовке каждого запроса.
+func (h *VerificationHandler) SetTelegramWebhookSecret(secret string) {
+	h.telegramSecret = secret
+}
+
 // SendEmailCode POST /verification/email/send
 func (h *VerificationHandler) SendEmailCode(c *gin.Context) {
 	userID, err := common.CurrentUserID(c)
@@ -25,13 +36,11 @@ func (h *VerificationHandler) SendEmailCode(c *gin.Context) {
 		return
 	}
 
-	code, err := h.svc.SendEmailCode(c.Request.Context(), userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.svc.SendEmailCode(c.Request.Context(), userID); err != nil {
+		respondVerificationError(c, err)
 		return
 	}
-	// В продакшене код не возвращаем, только отправляем на email
-	c.JSON(http.StatusOK, gin.H{"message": "code sent", "code": code})
+	c.JSON(http.StatusOK, gin.H{"message": "code sent"})
 }
 
 // SendPhoneCode POST /verification/phone/send
@@ -42,12 +51,22 @@ func (h *VerificationHandler) SendPhoneCode(c *gin.Context) {
 		return
 	}
 
-	code, err := h.svc.SendPhoneCode(c.Request.Context(), userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Тело необязательно: без него код уходит по первому настроенному каналу
+	var req struct {
+		Channel string `json:"channel" binding:"omitempty,oneof=sms telegram"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			common.RespondBadRequest(c, err.Error())
+			return
+		}
+	}
+
+	if err := h.svc.SendPhoneCode(c.Request.Context(), userID, req.Channel); err != nil {
+		respondVerificationError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "code sent", "code": code})
+	c.JSON(http.StatusOK, gin.H{"message": "code sent"})
 }
 
 // VerifyCode POST /verification/verify
@@ -69,7 +88,7 @@ func (h *VerificationHandler) VerifyCode(c *gin.Context) {
 
 	ok, err := h.svc.VerifyCode(c.Request.Context(), userID, req.Type, req.Code)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondVerificationError(c, err)
 		return
 	}
 	if !ok {
@@ -94,3 +113,46 @@ func (h *VerificationHandler) GetStatus(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, status)
 }
+
+// TelegramWebhook POST /telegram/webhook — обновления бота, через которые пользователь
+// делится номером телефона.
+func (h *VerificationHandler) TelegramWebhook(c *gin.Context) {
+	if h.telegramSecret == "" {
+		common.RespondNotFound(c, "not found")
+		return
+	}
+	header := c.GetHeader("X-Telegram-Bot-Api-Secret-Token")
+	if subtle.ConstantTimeCompare([]byte(header), []byte(h.telegramSecret)) != 1 {
+		common.RespondForbidden(c, "invalid secret token")
+		return
+	}
+
+	var update service.TelegramUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		common.RespondBadRequest(c, err.Error())
+		return
+	}
+	if err := h.svc.HandleTelegramUpdate(c.Request.Context(), update); err != nil {
+		respondVerificationError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func respondVerificationError(c *gin.Context, err error) {
+	var throttled *service.VerificationThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrVerificationLocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPhoneNotSet),
+		errors.Is(err, service.ErrTelegramNotLinked),
+		errors.Is(err, service.ErrTelegramPhoneNotShared),
+		errors.Is(err, service.ErrPhoneChannelUnavailable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
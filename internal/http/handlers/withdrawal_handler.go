@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/http/handlers/common"
 	"github.com/ignatzorin/freelance-backend/internal/service"
 )
@@ -26,16 +28,21 @@ func (h *WithdrawalHandler) CreateWithdrawal(c *gin.Context) {
 	}
 
 	var req struct {
-		Amount    float64 `json:"amount" binding:"required,gt=0"`
-		CardLast4 string  `json:"card_last4" binding:"required,len=4"`
-		BankName  string  `json:"bank_name" binding:"required"`
+		Amount    valueobject.Amount `json:"amount" binding:"required,gt=0"`
+		Currency  string             `json:"currency"`
+		CardLast4 string             `json:"card_last4" binding:"required,len=4"`
+		BankName  string             `json:"bank_name" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		common.RespondBadRequest(c, err.Error())
 		return
 	}
 
-	w, err := h.svc.CreateWithdrawal(c.Request.Context(), userID, req.Amount, req.CardLast4, req.BankName)
+	w, err := h.svc.CreateWithdrawal(c.Request.Context(), userID, req.Amount, req.Currency, req.CardLast4, req.BankName)
+	if errors.Is(err, service.ErrIdentityVerificationRequired) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "identity_verification_required": true})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 
+	"github.com/ignatzorin/freelance-backend/internal/http/middleware"
 	"github.com/ignatzorin/freelance-backend/internal/service"
 	"github.com/ignatzorin/freelance-backend/internal/ws"
 )
@@ -15,14 +17,16 @@ import (
 type WSHandler struct {
 	hub          *ws.Hub
 	tokenManager *service.TokenManager
+	sessions     middleware.SessionChecker
 	upgrader     websocket.Upgrader
 }
 
-// NewWSHandler создаёт новый хэндлер.
-func NewWSHandler(hub *ws.Hub, tokens *service.TokenManager) *WSHandler {
+// NewWSHandler создаёт новый хэндлер; sessions проверяет сессию токена так же, как AuthMiddleware.
+func NewWSHandler(hub *ws.Hub, tokens *service.TokenManager, sessions middleware.SessionChecker) *WSHandler {
 	return &WSHandler{
 		hub:          hub,
 		tokenManager: tokens,
+		sessions:     sessions,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool { return true },
 		},
@@ -34,7 +38,9 @@ func (h *WSHandler) TokenManager() *service.TokenManager {
 	return h.tokenManager
 }
 
-// Handle обслуживает GET /api/ws?token=...
+// Handle обслуживает GET /api/ws?token=...&since=...
+// since — номер последнего полученного события; пропущенные после него события досылаются
+// до новых.
 func (h *WSHandler) Handle(c *gin.Context) {
 	rawToken := c.Query("token")
 	if rawToken == "" {
@@ -42,19 +48,40 @@ func (h *WSHandler) Handle(c *gin.Context) {
 		return
 	}
 
-	userID, _, err := h.tokenManager.ParseAccess(rawToken)
-	if err != nil || userID == uuid.Nil {
+	since := int64(-1)
+	if raw := c.Query("since"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since должен быть неотрицательным числом"})
+			return
+		}
+		since = parsed
+	}
+
+	claims, err := h.tokenManager.ParseAccess(rawToken)
+	if err != nil || claims.UserID == uuid.Nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "невалидный access токен"})
 		return
 	}
 
+	if h.sessions != nil {
+		if err := h.sessions.Check(c.Request.Context(), claims); err != nil {
+			status, message := middleware.SessionCheckError(c, err)
+			c.JSON(status, gin.H{"error": message})
+			return
+		}
+	}
+
 	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	client := ws.NewClient(conn, h.hub, userID)
+	client := ws.NewClient(conn, h.hub, claims.UserID, claims.SessionID)
+	if since >= 0 {
+		client.ReplayFrom(since)
+	}
 	h.hub.Register(client)
 
 	client.Run(c.Request.Context())
@@ -1,23 +1,36 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 
+	"github.com/ignatzorin/freelance-backend/internal/logger"
+	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/service"
 )
 
 // Context ключи для gin.Context.
 const (
-	ContextUserIDKey = "userID"
-	ContextRoleKey   = "role"
+	ContextUserIDKey    = "userID"
+	ContextRoleKey      = "role"
+	ContextSessionIDKey = "sessionID"
 )
 
-// AuthMiddleware проверяет JWT access токен.
-func AuthMiddleware(tokens *service.TokenManager) gin.HandlerFunc {
+// SessionChecker проверяет, что сессия access токена не отозвана и пользователь не заблокирован.
+type SessionChecker interface {
+	Check(ctx context.Context, claims *service.AccessClaims) error
+}
+
+// AuthMiddleware проверяет JWT access токен, а если задан sessions — ещё и то,
+// что его сессия не отозвана и аккаунт не заблокирован. Если задан personal, вместо JWT
+// принимается и персональный токен доступа, но только на разрешённых для него маршрутах.
+func AuthMiddleware(tokens *service.TokenManager, sessions SessionChecker, personal *PersonalTokenAuth) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		auth := c.GetHeader("Authorization")
 		if auth == "" || !strings.HasPrefix(auth, "Bearer ") {
@@ -26,14 +39,61 @@ func AuthMiddleware(tokens *service.TokenManager) gin.HandlerFunc {
 		}
 
 		raw := strings.TrimPrefix(auth, "Bearer ")
-		userID, role, err := tokens.ParseAccess(raw)
-		if err != nil || userID == uuid.Nil {
+		if personal != nil && strings.HasPrefix(raw, service.PersonalTokenPrefix) {
+			personal.authenticate(c, raw, sessions)
+			return
+		}
+
+		claims, err := tokens.ParseAccess(raw)
+		if err != nil || claims.UserID == uuid.Nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "токен невалиден"})
 			return
 		}
 
-		c.Set(ContextUserIDKey, userID)
-		c.Set(ContextRoleKey, role)
+		if sessions != nil {
+			if err := sessions.Check(c.Request.Context(), claims); err != nil {
+				status, message := SessionCheckError(c, err)
+				c.AbortWithStatusJSON(status, gin.H{"error": message})
+				return
+			}
+		}
+
+		c.Set(ContextUserIDKey, claims.UserID)
+		c.Set(ContextRoleKey, claims.Role)
+		c.Set(ContextSessionIDKey, claims.SessionID)
+		c.Next()
+	}
+}
+
+// SessionCheckError переводит ошибку SessionChecker в HTTP статус и сообщение для клиента.
+func SessionCheckError(c *gin.Context, err error) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrAccountSuspended):
+		return http.StatusForbidden, "аккаунт заблокирован"
+	case errors.Is(err, service.ErrSessionRevoked):
+		return http.StatusUnauthorized, "сессия завершена, войдите заново"
+	}
+	if logger.Log != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":  err.Error(),
+			"path":   c.Request.URL.Path,
+			"method": c.Request.Method,
+		}).Error("auth: не удалось проверить сессию")
+	}
+	return http.StatusInternalServerError, "внутренняя ошибка сервера"
+}
+
+// RequirePermission пропускает запрос, только если у роли пользователя есть все
+// указанные права. Должен подключаться после AuthMiddleware.
+func RequirePermission(perms ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString(ContextRoleKey)
+		for _, perm := range perms {
+			if !models.HasPermission(role, perm) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "недостаточно прав"})
+				return
+			}
+		}
 		c.Next()
 	}
 }
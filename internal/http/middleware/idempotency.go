@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ignatzorin/freelance-backend/internal/logger"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	idempotencySaveTimeout    = 5 * time.Second
+	idempotencyMaxRequestBody = 1 << 20
+)
+
+// IdempotencyStore хранит ключи идемпотентности и сохранённые ответы.
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, userID uuid.UUID, key, requestHash string) (*models.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, userID uuid.UUID, key string) error
+}
+
+// Idempotency выполняет запрос с заголовком Idempotency-Key не больше одного раза:
+// повтор с тем же телом получает сохранённый ответ, другое тело под тем же ключом — 409.
+// Запросы без заголовка проходят как обычно. Должен подключаться после AuthMiddleware.
+func Idempotency(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		uid, ok := c.Get(ContextUserIDKey)
+		userID, isUUID := uid.(uuid.UUID)
+		if key == "" || !ok || !isUUID {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key не может быть длиннее 255 символов"})
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, idempotencyMaxRequestBody+1))
+		if err != nil || len(body) > idempotencyMaxRequestBody {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "не удалось прочитать тело запроса"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := requestHash(c.Request, body)
+		record, created, err := store.Reserve(c.Request.Context(), userID, key, hash)
+		if err != nil {
+			logIdempotencyError(c, err, "idempotency: не удалось занять ключ")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "внутренняя ошибка сервера"})
+			return
+		}
+		if !created {
+			replayIdempotent(c, record, hash)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Ответ сохраняется даже если клиент уже отключился: иначе повтор выполнит операцию ещё раз
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), idempotencySaveTimeout)
+		defer cancel()
+		if recorder.Status() >= http.StatusInternalServerError {
+			if err := store.Release(ctx, userID, key); err != nil {
+				logIdempotencyError(c, err, "idempotency: не удалось освободить ключ")
+			}
+			return
+		}
+		if err := store.Complete(ctx, userID, key, recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			logIdempotencyError(c, err, "idempotency: не удалось сохранить ответ")
+		}
+	}
+}
+
+// replayIdempotent отвечает на повтор запроса с уже занятым ключом.
+func replayIdempotent(c *gin.Context, record *models.IdempotencyKey, hash string) {
+	if record.RequestHash != hash {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Idempotency-Key уже использован для другого запроса"})
+		return
+	}
+	if !record.Completed() {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "запрос с этим Idempotency-Key ещё выполняется"})
+		return
+	}
+
+	contentType := "application/json; charset=utf-8"
+	if record.ContentType != nil && *record.ContentType != "" {
+		contentType = *record.ContentType
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(*record.StatusCode, contentType, record.ResponseBody)
+	c.Abort()
+}
+
+// requestHash отличает запросы под одним ключом: метод, путь и тело.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func logIdempotencyError(c *gin.Context, err error, message string) {
+	if logger.Log != nil {
+		logger.Log.WithFields(logrus.Fields{
+			"error":  err.Error(),
+			"path":   c.Request.URL.Path,
+			"method": c.Request.Method,
+		}).Error(message)
+	}
+}
+
+// responseRecorder копирует тело ответа, чтобы сохранить его для повторов.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*models.IdempotencyKey
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyKey)}
+}
+
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, userID uuid.UUID, key, requestHash string) (*models.IdempotencyKey, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := userID.String() + "/" + key
+	if record, ok := s.records[id]; ok {
+		copied := *record
+		return &copied, false, nil
+	}
+	record := &models.IdempotencyKey{UserID: userID, Key: key, RequestHash: requestHash}
+	s.records[id] = record
+	copied := *record
+	return &copied, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(_ context.Context, userID uuid.UUID, key string, statusCode int, contentType string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.records[userID.String()+"/"+key]
+	record.StatusCode = &statusCode
+	record.ContentType = &contentType
+	record.ResponseBody = body
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, userID uuid.UUID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, userID.String()+"/"+key)
+	return nil
+}
+
+func setupIdempotencyRouter(store IdempotencyStore, userID uuid.UUID, status *int, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/payments/deposit", func(c *gin.Context) {
+		c.Set(ContextUserIDKey, userID)
+		c.Next()
+	}, Idempotency(store), func(c *gin.Context) {
+		*calls++
+		c.JSON(*status, gin.H{"call": *calls})
+	})
+	return r
+}
+
+func doIdempotentRequest(r *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/payments/deposit", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysStoredResponse(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	r := setupIdempotencyRouter(newMemoryIdempotencyStore(), uuid.New(), &status, &calls)
+
+	first := doIdempotentRequest(r, "key-1", `{"amount": 100}`)
+	second := doIdempotentRequest(r, "key-1", `{"amount": 100}`)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+}
+
+func TestIdempotency_DifferentBodyConflicts(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	r := setupIdempotencyRouter(newMemoryIdempotencyStore(), uuid.New(), &status, &calls)
+
+	doIdempotentRequest(r, "key-1", `{"amount": 100}`)
+	w := doIdempotentRequest(r, "key-1", `{"amount": 200}`)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_ServerErrorReleasesKey(t *testing.T) {
+	status, calls := http.StatusInternalServerError, 0
+	r := setupIdempotencyRouter(newMemoryIdempotencyStore(), uuid.New(), &status, &calls)
+
+	doIdempotentRequest(r, "key-1", `{"amount": 100}`)
+	status = http.StatusCreated
+	w := doIdempotentRequest(r, "key-1", `{"amount": 100}`)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	r := setupIdempotencyRouter(newMemoryIdempotencyStore(), uuid.New(), &status, &calls)
+
+	doIdempotentRequest(r, "", `{"amount": 100}`)
+	doIdempotentRequest(r, "", `{"amount": 100}`)
+
+	assert.Equal(t, 2, calls)
+}
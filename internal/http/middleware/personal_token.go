@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ignatzorin/freelance-backend/internal/logger"
+	"github.com/ignatzorin/freelance-backend/internal/service"
+)
+
+// PersonalTokenAuthenticator проверяет персональный токен доступа и запоминает его использование.
+type PersonalTokenAuthenticator interface {
+	Authenticate(ctx context.Context, raw, ip string) (*service.AccessClaims, []string, error)
+}
+
+// PersonalTokenAuth пускает персональные токены только на явно разрешённые маршруты:
+// каждому маршруту ("METHOD /api/полный/путь") сопоставлено право, которое должно быть у токена.
+// Всё остальное, включая управление сессиями и самими токенами, требует обычного входа.
+type PersonalTokenAuth struct {
+	authenticator PersonalTokenAuthenticator
+	routes        map[string]string
+}
+
+// NewPersonalTokenAuth создаёт проверку персональных токенов. Без authenticator возвращает nil,
+// и AuthMiddleware принимает только JWT.
+func NewPersonalTokenAuth(authenticator PersonalTokenAuthenticator) *PersonalTokenAuth {
+	if authenticator == nil {
+		return nil
+	}
+	return &PersonalTokenAuth{authenticator: authenticator, routes: make(map[string]string)}
+}
+
+// Allow разрешает персональным токенам с правом scope вызывать маршруты routes.
+func (p *PersonalTokenAuth) Allow(scope string, routes ...string) {
+	if p == nil {
+		return
+	}
+	for _, route := range routes {
+		p.routes[route] = scope
+	}
+}
+
+// Validate проверяет, что каждый разрешённый маршрут зарегистрирован в роутере,
+// чтобы опечатка в пути не превратилась в молча недоступный для интеграций маршрут.
+func (p *PersonalTokenAuth) Validate(registered gin.RoutesInfo) error {
+	if p == nil {
+		return nil
+	}
+	known := make(map[string]bool, len(registered))
+	for _, route := range registered {
+		known[route.Method+" "+route.Path] = true
+	}
+	for route := range p.routes {
+		if !known[route] {
+			return fmt.Errorf("middleware: маршрут %q для персональных токенов не зарегистрирован", route)
+		}
+	}
+	return nil
+}
+
+// authenticate обрабатывает запрос с персональным токеном вместо JWT.
+func (p *PersonalTokenAuth) authenticate(c *gin.Context, raw string, sessions SessionChecker) {
+	claims, scopes, err := p.authenticator.Authenticate(c.Request.Context(), raw, c.ClientIP())
+	if errors.Is(err, service.ErrPersonalTokenInvalid) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "токен невалиден"})
+		return
+	}
+	if err != nil {
+		if logger.Log != nil {
+			logger.Log.WithFields(logrus.Fields{
+				"error":  err.Error(),
+				"path":   c.Request.URL.Path,
+				"method": c.Request.Method,
+			}).Error("auth: не удалось проверить персональный токен")
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "внутренняя ошибка сервера"})
+		return
+	}
+
+	// У персонального токена нет сессии, но блокировка аккаунта действует и на него
+	if sessions != nil {
+		if err := sessions.Check(c.Request.Context(), claims); err != nil {
+			status, message := SessionCheckError(c, err)
+			c.AbortWithStatusJSON(status, gin.H{"error": message})
+			return
+		}
+	}
+
+	scope, ok := p.routes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "маршрут недоступен для персональных токенов"})
+		return
+	}
+	if !hasScope(scopes, scope) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "у токена нет права " + scope, "required_scope": scope})
+		return
+	}
+
+	c.Set(ContextUserIDKey, claims.UserID)
+	c.Set(ContextRoleKey, claims.Role)
+	c.Set(ContextSessionIDKey, claims.SessionID)
+	c.Next()
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/service"
+)
+
+type staticPersonalTokens struct {
+	userID uuid.UUID
+	scopes []string
+}
+
+func (s staticPersonalTokens) Authenticate(_ context.Context, raw, _ string) (*service.AccessClaims, []string, error) {
+	if raw != service.PersonalTokenPrefix+"valid" {
+		return nil, nil, service.ErrPersonalTokenInvalid
+	}
+	return &service.AccessClaims{UserID: s.userID, Role: models.RoleClient}, s.scopes, nil
+}
+
+func TestAuthMiddleware_PersonalTokenScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	personal := NewPersonalTokenAuth(staticPersonalTokens{userID: userID, scopes: []string{models.ScopeOrdersRead}})
+
+	r := gin.New()
+	api := r.Group("/api", AuthMiddleware(nil, nil, personal))
+	handler := func(c *gin.Context) {
+		assert.Equal(t, userID, c.MustGet(ContextUserIDKey))
+		c.Status(http.StatusOK)
+	}
+	api.GET("/orders/my", handler)
+	api.POST("/orders", handler)
+	api.GET("/profile", handler)
+	personal.Allow(models.ScopeOrdersRead, "GET /api/orders/my")
+	personal.Allow(models.ScopeOrdersWrite, "POST /api/orders")
+	assert.NoError(t, personal.Validate(r.Routes()))
+
+	do := func(method, path, token string) int {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do("GET", "/api/orders/my", service.PersonalTokenPrefix+"valid"))
+	// Нет права orders:write
+	assert.Equal(t, http.StatusForbidden, do("POST", "/api/orders", service.PersonalTokenPrefix+"valid"))
+	// Маршрут не разрешён для персональных токенов вовсе
+	assert.Equal(t, http.StatusForbidden, do("GET", "/api/profile", service.PersonalTokenPrefix+"valid"))
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/api/orders/my", service.PersonalTokenPrefix+"revoked"))
+
+	personal.Allow(models.ScopeOrdersRead, "GET /api/orders/:id")
+	assert.Error(t, personal.Validate(r.Routes()))
+}
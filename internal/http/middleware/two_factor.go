@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/service"
+)
+
+// TOTPCodeHeader — заголовок с кодом из приложения-аутентификатора для чувствительных действий.
+const TOTPCodeHeader = "X-TOTP-Code"
+
+// FreshTOTPChecker проверяет свежий код второго фактора перед чувствительным действием.
+type FreshTOTPChecker interface {
+	RequireFresh(ctx context.Context, userID uuid.UUID, code string) error
+}
+
+// RequireFreshTOTP требует код из заголовка X-TOTP-Code у пользователей с подключённой
+// двухфакторной аутентификацией. Без checker пропускает запрос.
+func RequireFreshTOTP(checker FreshTOTPChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if checker == nil {
+			c.Next()
+			return
+		}
+
+		userID, ok := c.Get(ContextUserIDKey)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "требуется авторизация"})
+			return
+		}
+
+		err := checker.RequireFresh(c.Request.Context(), userID.(uuid.UUID), c.GetHeader(TOTPCodeHeader))
+		switch {
+		case err == nil:
+			c.Next()
+		case errors.Is(err, service.ErrTwoFactorRequired), errors.Is(err, service.ErrTwoFactorInvalidCode):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error(), "two_factor_required": true})
+		case errors.Is(err, service.ErrTwoFactorLocked):
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "не удалось проверить код двухфакторной аутентификации"})
+		}
+	}
+}
@@ -9,12 +9,17 @@ import (
 	"github.com/ignatzorin/freelance-backend/internal/http/handlers"
 	"github.com/ignatzorin/freelance-backend/internal/http/middleware"
 	newHandler "github.com/ignatzorin/freelance-backend/internal/interface/http/handler"
+	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/service"
 )
 
 func SetupRouter(
 	cfg *config.Config,
 	authHandler *handlers.AuthHandler,
+	accountHandler *handlers.AccountHandler,
+	twoFactorHandler *handlers.TwoFactorHandler,
+	oidcHandler *handlers.OIDCHandler,
+	personalTokenHandler *handlers.PersonalTokenHandler,
 	profileHandler *handlers.ProfileHandler,
 	orderHandler *handlers.OrderHandler,
 	conversationHandler *handlers.ConversationHandler,
@@ -30,6 +35,10 @@ func SetupRouter(
 	healthHandler *handlers.HealthHandler,
 	seedHandler *handlers.SeedHandler,
 	tokenManager *service.TokenManager,
+	sessionGuard middleware.SessionChecker,
+	personalTokenAuth middleware.PersonalTokenAuthenticator,
+	totpChecker middleware.FreshTOTPChecker,
+	idempotencyStore middleware.IdempotencyStore,
 	// Новые handlers (Clean Architecture)
 	newOrderHandler *newHandler.OrderHandler,
 	newProposalHandler *newHandler.ProposalHandler,
@@ -45,8 +54,11 @@ func SetupRouter(
 	reportHandler *handlers.ReportHandler,
 	disputeHandler *handlers.DisputeHandler,
 	verificationHandler *handlers.VerificationHandler,
+	kycHandler *handlers.KYCHandler,
+	privacyHandler *handlers.PrivacyHandler,
 	proposalTemplateHandler *handlers.ProposalTemplateHandler,
 	freelancerHandler *handlers.FreelancerHandler,
+	milestoneHandler *handlers.MilestoneHandler,
 ) *gin.Engine {
 	if cfg.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -56,6 +68,9 @@ func SetupRouter(
 	r.Use(middleware.ErrorHandler())
 	r.Use(middleware.CORSMiddleware(cfg.AllowedOrigins))
 
+	// Персональные токены принимаются только на маршрутах, разрешённых ниже через Allow
+	personalTokens := middleware.NewPersonalTokenAuth(personalTokenAuth)
+
 	r.GET("/health", healthHandler.Health)
 	r.StaticFS("/media", http.Dir(cfg.MediaStoragePath))
 
@@ -68,6 +83,10 @@ func SetupRouter(
 		api.GET("/seed/realistic", seedHandler.SeedRealistic)
 	}
 
+	if verificationHandler != nil {
+		api.POST("/telegram/webhook", verificationHandler.TelegramWebhook)
+	}
+
 	authGroup := api.Group("/auth")
 	authRateLimit := middleware.RateLimitMiddleware(5, cfg.RateLimitPeriod)
 	authGroup.Use(authRateLimit)
@@ -75,14 +94,45 @@ func SetupRouter(
 		authGroup.POST("/register", authHandler.Register)
 		authGroup.POST("/login", authHandler.Login)
 		authGroup.POST("/refresh", authHandler.Refresh)
+		authGroup.POST("/login/2fa", authHandler.LoginTwoFactor)
+		if accountHandler != nil {
+			authGroup.POST("/password/forgot", accountHandler.ForgotPassword)
+			authGroup.POST("/password/reset", accountHandler.ResetPassword)
+			authGroup.POST("/email/confirm", accountHandler.ConfirmEmailChange)
+		}
+		if oidcHandler != nil {
+			authGroup.GET("/oidc/providers", oidcHandler.Providers)
+			authGroup.POST("/oidc/:provider/start", oidcHandler.Start)
+			authGroup.POST("/oidc/callback", oidcHandler.Callback)
+		}
 	}
 
 	protectedAuth := api.Group("/auth")
-	protectedAuth.Use(middleware.AuthMiddleware(tokenManager))
+	protectedAuth.Use(middleware.AuthMiddleware(tokenManager, sessionGuard, personalTokens))
 	{
 		protectedAuth.GET("/sessions", authHandler.ListSessions)
 		protectedAuth.DELETE("/sessions/:id", authHandler.DeleteSession)
 		protectedAuth.DELETE("/sessions", authHandler.DeleteAllSessionsExcept)
+		if accountHandler != nil {
+			// Обе операции проверяют текущий пароль, поэтому ограничены как логин
+			protectedAuth.POST("/password/change", authRateLimit, accountHandler.ChangePassword)
+			protectedAuth.POST("/email/change", authRateLimit, accountHandler.RequestEmailChange)
+		}
+		if twoFactorHandler != nil {
+			protectedAuth.GET("/2fa", twoFactorHandler.Status)
+			protectedAuth.POST("/2fa/setup", twoFactorHandler.Setup)
+			protectedAuth.POST("/2fa/enable", authRateLimit, twoFactorHandler.Enable)
+			protectedAuth.POST("/2fa/disable", authRateLimit, twoFactorHandler.Disable)
+			protectedAuth.POST("/2fa/recovery-codes", authRateLimit, twoFactorHandler.RegenerateRecoveryCodes)
+		}
+		if oidcHandler != nil {
+			protectedAuth.GET("/oidc/identities", oidcHandler.Identities)
+		}
+		if personalTokenHandler != nil {
+			protectedAuth.GET("/tokens", personalTokenHandler.List)
+			protectedAuth.POST("/tokens", middleware.RequireFreshTOTP(totpChecker), personalTokenHandler.Create)
+			protectedAuth.DELETE("/tokens/:id", personalTokenHandler.Revoke)
+		}
 	}
 
 	// Публичные маршруты
@@ -109,7 +159,12 @@ func SetupRouter(
 
 	// Защищённые маршруты
 	protected := api.Group("/")
-	protected.Use(middleware.AuthMiddleware(tokenManager))
+	protected.Use(middleware.AuthMiddleware(tokenManager, sessionGuard, personalTokens))
+	// Повтор запроса с тем же Idempotency-Key не выполняет операцию второй раз
+	idempotent := middleware.Idempotency(idempotencyStore)
+	// Вывод денег с подключённой 2FA требует свежего кода; проверка идёт до идемпотентности,
+	// чтобы отклонённый запрос не сохранился под Idempotency-Key
+	freshTOTP := middleware.RequireFreshTOTP(totpChecker)
 	{
 		protected.GET("/profile", profileHandler.GetMe)
 		protected.PUT("/profile", profileHandler.UpdateMe)
@@ -122,12 +177,14 @@ func SetupRouter(
 
 		protected.GET("/notifications", notificationHandler.ListNotifications)
 		protected.GET("/notifications/unread/count", notificationHandler.CountUnread)
+		protected.GET("/notifications/preferences", notificationHandler.GetPreferences)
+		protected.PUT("/notifications/preferences", notificationHandler.UpdatePreferences)
 		protected.GET("/notifications/:id", notificationHandler.GetNotification)
 		protected.PUT("/notifications/:id/read", notificationHandler.MarkAsRead)
 		protected.PUT("/notifications/read-all", notificationHandler.MarkAllAsRead)
 		protected.DELETE("/notifications/:id", notificationHandler.DeleteNotification)
 
-		protected.POST("/orders", orderHandler.CreateOrder)
+		protected.POST("/orders", middleware.RequirePermission(models.PermOrdersCreate), idempotent, orderHandler.CreateOrder)
 		protected.GET("/orders/my", orderHandler.ListMyOrders)
 		protected.GET("/orders/:id/my-proposal", middleware.UUIDValidator("id"), proposalOperationsHandler.GetMyProposal)
 		protected.GET("/orders/:id/chat", middleware.UUIDValidator("id"), conversationHandler.GetOrderChat)
@@ -135,9 +192,9 @@ func SetupRouter(
 		protected.GET("/orders/:id/conversations/:participantId", middleware.UUIDValidator("id"), middleware.UUIDValidator("participantId"), conversationHandler.GetConversation)
 		protected.PUT("/orders/:id", middleware.UUIDValidator("id"), orderHandler.UpdateOrder)
 		protected.DELETE("/orders/:id", middleware.UUIDValidator("id"), orderHandler.DeleteOrder)
-		protected.POST("/orders/:id/proposals", middleware.UUIDValidator("id"), proposalOperationsHandler.CreateProposal)
+		protected.POST("/orders/:id/proposals", middleware.RequirePermission(models.PermProposalsCreate), middleware.UUIDValidator("id"), proposalOperationsHandler.CreateProposal)
 		protected.GET("/orders/:id/proposals", middleware.UUIDValidator("id"), proposalOperationsHandler.ListProposals)
-		protected.PUT("/orders/:id/proposals/:proposalId/status", middleware.UUIDValidator("id"), middleware.UUIDValidator("proposalId"), proposalOperationsHandler.UpdateProposalStatus)
+		protected.PUT("/orders/:id/proposals/:proposalId/status", middleware.RequirePermission(models.PermOrdersHire), middleware.UUIDValidator("id"), middleware.UUIDValidator("proposalId"), proposalOperationsHandler.UpdateProposalStatus)
 		protected.GET("/conversations/my", conversationHandler.ListMyConversations)
 		protected.GET("/conversations/:conversationId/messages", middleware.UUIDValidator("conversationId"), conversationHandler.ListMessages)
 		protected.POST("/conversations/:conversationId/messages", middleware.UUIDValidator("conversationId"), conversationHandler.SendMessage)
@@ -147,34 +204,34 @@ func SetupRouter(
 		protected.DELETE("/conversations/:conversationId/messages/:messageId/reactions", middleware.UUIDValidator("conversationId"), middleware.UUIDValidator("messageId"), conversationHandler.RemoveMessageReaction)
 
 		// AI endpoints
-		protected.POST("/ai/orders/description", aiOrderHandler.GenerateOrderDescription)
-		protected.POST("/ai/orders/description/stream", aiOrderHandler.StreamGenerateOrderDescription)
-		protected.POST("/ai/orders/suggestions", aiOrderHandler.GenerateOrderSuggestions)
-		protected.POST("/ai/orders/suggestions/stream", aiOrderHandler.StreamGenerateOrderSuggestions)
-		protected.POST("/ai/orders/skills", aiOrderHandler.GenerateOrderSkills)
-		protected.POST("/ai/orders/skills/stream", aiOrderHandler.StreamGenerateOrderSkills)
-		protected.POST("/ai/orders/budget", aiOrderHandler.GenerateOrderBudget)
-		protected.POST("/ai/orders/budget/stream", aiOrderHandler.StreamGenerateOrderBudget)
+		protected.POST("/ai/orders/description", middleware.RequirePermission(models.PermOrdersCreate), aiOrderHandler.GenerateOrderDescription)
+		protected.POST("/ai/orders/description/stream", middleware.RequirePermission(models.PermOrdersCreate), aiOrderHandler.StreamGenerateOrderDescription)
+		protected.POST("/ai/orders/suggestions", middleware.RequirePermission(models.PermOrdersCreate), aiOrderHandler.GenerateOrderSuggestions)
+		protected.POST("/ai/orders/suggestions/stream", middleware.RequirePermission(models.PermOrdersCreate), aiOrderHandler.StreamGenerateOrderSuggestions)
+		protected.POST("/ai/orders/skills", middleware.RequirePermission(models.PermOrdersCreate), aiOrderHandler.GenerateOrderSkills)
+		protected.POST("/ai/orders/skills/stream", middleware.RequirePermission(models.PermOrdersCreate), aiOrderHandler.StreamGenerateOrderSkills)
+		protected.POST("/ai/orders/budget", middleware.RequirePermission(models.PermOrdersCreate), aiOrderHandler.GenerateOrderBudget)
+		protected.POST("/ai/orders/budget/stream", middleware.RequirePermission(models.PermOrdersCreate), aiOrderHandler.StreamGenerateOrderBudget)
 		protected.POST("/ai/welcome-message", aiOrderHandler.GenerateWelcomeMessage)
 		protected.POST("/ai/welcome-message/stream", aiOrderHandler.StreamGenerateWelcomeMessage)
-		protected.POST("/ai/orders/:id/proposal", middleware.UUIDValidator("id"), aiOrderHandler.GenerateProposal)
-		protected.POST("/ai/orders/:id/proposal/stream", middleware.UUIDValidator("id"), aiOrderHandler.StreamGenerateProposal)
-		protected.GET("/ai/orders/:id/proposals/feedback", middleware.UUIDValidator("id"), aiOrderHandler.GetProposalFeedback)
-		protected.GET("/ai/orders/:id/proposals/feedback/stream", middleware.UUIDValidator("id"), aiOrderHandler.StreamProposalFeedback)
-		protected.POST("/ai/orders/improve", aiOrderHandler.ImproveOrderDescription)
-		protected.POST("/ai/orders/improve/stream", aiOrderHandler.StreamImproveOrderDescription)
+		protected.POST("/ai/orders/:id/proposal", middleware.RequirePermission(models.PermProposalsCreate), middleware.UUIDValidator("id"), aiOrderHandler.GenerateProposal)
+		protected.POST("/ai/orders/:id/proposal/stream", middleware.RequirePermission(models.PermProposalsCreate), middleware.UUIDValidator("id"), aiOrderHandler.StreamGenerateProposal)
+		protected.GET("/ai/orders/:id/proposals/feedback", middleware.RequirePermission(models.PermProposalsCreate), middleware.UUIDValidator("id"), aiOrderHandler.GetProposalFeedback)
+		protected.GET("/ai/orders/:id/proposals/feedback/stream", middleware.RequirePermission(models.PermProposalsCreate), middleware.UUIDValidator("id"), aiOrderHandler.StreamProposalFeedback)
+		protected.POST("/ai/orders/improve", middleware.RequirePermission(models.PermOrdersCreate), aiOrderHandler.ImproveOrderDescription)
+		protected.POST("/ai/orders/improve/stream", middleware.RequirePermission(models.PermOrdersCreate), aiOrderHandler.StreamImproveOrderDescription)
 		protected.POST("/ai/orders/:id/regenerate-summary", middleware.UUIDValidator("id"), aiOrderHandler.RegenerateOrderSummary)
 		protected.POST("/ai/orders/:id/regenerate-summary/stream", middleware.UUIDValidator("id"), aiOrderHandler.StreamRegenerateOrderSummary)
 		protected.GET("/ai/conversations/:conversationId/summary", middleware.UUIDValidator("conversationId"), conversationHandler.SummarizeConversation)
 		protected.GET("/ai/conversations/:conversationId/summary/stream", middleware.UUIDValidator("conversationId"), conversationHandler.StreamSummarizeConversation)
-		protected.GET("/ai/orders/recommended", aiOrderHandler.RecommendRelevantOrders)
-		protected.GET("/ai/orders/recommended/stream", aiOrderHandler.StreamRecommendRelevantOrders)
-		protected.GET("/ai/orders/:id/price-timeline", middleware.UUIDValidator("id"), aiOrderHandler.RecommendPriceAndTimeline)
-		protected.GET("/ai/orders/:id/price-timeline/stream", middleware.UUIDValidator("id"), aiOrderHandler.StreamRecommendPriceAndTimeline)
+		protected.GET("/ai/orders/recommended", middleware.RequirePermission(models.PermOrdersFindWork), aiOrderHandler.RecommendRelevantOrders)
+		protected.GET("/ai/orders/recommended/stream", middleware.RequirePermission(models.PermOrdersFindWork), aiOrderHandler.StreamRecommendRelevantOrders)
+		protected.GET("/ai/orders/:id/price-timeline", middleware.RequirePermission(models.PermOrdersFindWork), middleware.UUIDValidator("id"), aiOrderHandler.RecommendPriceAndTimeline)
+		protected.GET("/ai/orders/:id/price-timeline/stream", middleware.RequirePermission(models.PermOrdersFindWork), middleware.UUIDValidator("id"), aiOrderHandler.StreamRecommendPriceAndTimeline)
 		protected.GET("/ai/orders/:id/quality", middleware.UUIDValidator("id"), aiOrderHandler.EvaluateOrderQuality)
 		protected.GET("/ai/orders/:id/quality/stream", middleware.UUIDValidator("id"), aiOrderHandler.StreamEvaluateOrderQuality)
-		protected.GET("/ai/orders/:id/suitable-freelancers", middleware.UUIDValidator("id"), aiOrderHandler.FindSuitableFreelancers)
-		protected.GET("/ai/orders/:id/suitable-freelancers/stream", middleware.UUIDValidator("id"), aiOrderHandler.StreamFindSuitableFreelancers)
+		protected.GET("/ai/orders/:id/suitable-freelancers", middleware.RequirePermission(models.PermOrdersHire), middleware.UUIDValidator("id"), aiOrderHandler.FindSuitableFreelancers)
+		protected.GET("/ai/orders/:id/suitable-freelancers/stream", middleware.RequirePermission(models.PermOrdersHire), middleware.UUIDValidator("id"), aiOrderHandler.StreamFindSuitableFreelancers)
 		protected.POST("/ai/assistant", aiOrderHandler.AIChatAssistant)
 		protected.POST("/ai/assistant/stream", aiOrderHandler.StreamAIChatAssistant)
 		protected.POST("/ai/profile/improve", aiOrderHandler.ImproveProfile)
@@ -189,20 +246,23 @@ func SetupRouter(
 		protected.DELETE("/portfolio/:id", middleware.UUIDValidator("id"), portfolioHandler.DeletePortfolioItem)
 
 		protected.POST("/media/photos", mediaHandler.UploadPhoto)
+		protected.POST("/media/documents", mediaHandler.UploadDocument)
+		protected.GET("/media/:id/file", middleware.UUIDValidator("id"), mediaHandler.GetFile)
 		protected.DELETE("/media/:id", middleware.UUIDValidator("id"), mediaHandler.DeleteMedia)
 
 		// Платежи и escrow
 		if paymentHandler != nil {
 			protected.GET("/payments/balance", paymentHandler.GetBalance)
-			protected.POST("/payments/deposit", paymentHandler.Deposit)
-			protected.POST("/payments/escrow", paymentHandler.CreateEscrow)
+			protected.GET("/payments/balances", paymentHandler.ListBalances)
+			protected.POST("/payments/deposit", idempotent, paymentHandler.Deposit)
+			protected.POST("/payments/escrow", idempotent, paymentHandler.CreateEscrow)
 			protected.GET("/payments/escrow/:orderId", middleware.UUIDValidator("orderId"), paymentHandler.GetEscrow)
 			protected.GET("/payments/transactions", paymentHandler.ListTransactions)
 		}
 
 		// Вывод средств
 		if withdrawalHandler != nil {
-			protected.POST("/withdrawals", withdrawalHandler.CreateWithdrawal)
+			protected.POST("/withdrawals", freshTOTP, idempotent, withdrawalHandler.CreateWithdrawal)
 			protected.GET("/withdrawals", withdrawalHandler.ListWithdrawals)
 		}
 
@@ -218,6 +278,21 @@ func SetupRouter(
 			protected.POST("/orders/:id/dispute", middleware.UUIDValidator("id"), disputeHandler.CreateDispute)
 			protected.GET("/orders/:id/dispute", middleware.UUIDValidator("id"), disputeHandler.GetDispute)
 			protected.GET("/disputes", disputeHandler.ListMyDisputes)
+			protected.POST("/orders/:id/milestones/:milestoneId/dispute", middleware.UUIDValidator("id"), middleware.UUIDValidator("milestoneId"), disputeHandler.CreateMilestoneDispute)
+			protected.POST("/disputes/:id/evidence", middleware.UUIDValidator("id"), disputeHandler.AddEvidence)
+			protected.GET("/disputes/:id/evidence", middleware.UUIDValidator("id"), disputeHandler.ListEvidence)
+		}
+
+		// Этапы заказа
+		if milestoneHandler != nil {
+			protected.GET("/orders/:id/milestones", middleware.UUIDValidator("id"), milestoneHandler.ListMilestones)
+			protected.POST("/orders/:id/milestones", middleware.UUIDValidator("id"), milestoneHandler.CreateMilestone)
+			protected.PUT("/orders/:id/milestones/:milestoneId", middleware.UUIDValidator("id"), middleware.UUIDValidator("milestoneId"), milestoneHandler.UpdateMilestone)
+			protected.DELETE("/orders/:id/milestones/:milestoneId", middleware.UUIDValidator("id"), middleware.UUIDValidator("milestoneId"), milestoneHandler.DeleteMilestone)
+			protected.POST("/orders/:id/milestones/:milestoneId/fund", middleware.UUIDValidator("id"), middleware.UUIDValidator("milestoneId"), milestoneHandler.FundMilestone)
+			protected.POST("/orders/:id/milestones/:milestoneId/submit", middleware.UUIDValidator("id"), middleware.UUIDValidator("milestoneId"), milestoneHandler.SubmitMilestone)
+			protected.POST("/orders/:id/milestones/:milestoneId/accept", middleware.UUIDValidator("id"), middleware.UUIDValidator("milestoneId"), milestoneHandler.AcceptMilestone)
+			protected.POST("/orders/:id/milestones/:milestoneId/refund", middleware.UUIDValidator("id"), middleware.UUIDValidator("milestoneId"), milestoneHandler.RefundMilestone)
 		}
 
 		// Избранное
@@ -241,6 +316,20 @@ func SetupRouter(
 			protected.POST("/verification/verify", verificationHandler.VerifyCode)
 			protected.GET("/verification/status", verificationHandler.GetStatus)
 		}
+		if kycHandler != nil {
+			protected.POST("/verification/identity", kycHandler.Submit)
+			protected.GET("/verification/identity", kycHandler.GetStatus)
+		}
+
+		// Выгрузка данных и удаление аккаунта
+		if privacyHandler != nil {
+			protected.POST("/account/exports", privacyHandler.RequestExport)
+			protected.GET("/account/exports", privacyHandler.ListExports)
+			protected.GET("/account/exports/:id/download", middleware.UUIDValidator("id"), privacyHandler.DownloadExport)
+			protected.GET("/account/deletion", privacyHandler.GetDeletion)
+			protected.POST("/account/deletion", freshTOTP, privacyHandler.RequestDeletion)
+			protected.DELETE("/account/deletion", privacyHandler.CancelDeletion)
+		}
 
 		// Шаблоны откликов
 		if proposalTemplateHandler != nil {
@@ -251,23 +340,53 @@ func SetupRouter(
 		}
 	}
 
+	// Администрирование
+	admin := api.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(tokenManager, sessionGuard, personalTokens))
+	{
+		admin.PUT("/users/:id/role", middleware.RequirePermission(models.PermUsersManageRoles), middleware.UUIDValidator("id"), profileHandler.AdminUpdateRole)
+
+		if reportHandler != nil {
+			reports := admin.Group("/reports", middleware.RequirePermission(models.PermReportsModerate))
+			reports.GET("", reportHandler.AdminListReports)
+			reports.GET("/:type/:id", middleware.UUIDValidator("id"), reportHandler.AdminGetReportTarget)
+			reports.POST("/:type/:id/resolve", middleware.UUIDValidator("id"), reportHandler.AdminResolveReportTarget)
+		}
+
+		if disputeHandler != nil {
+			disputes := admin.Group("/disputes", middleware.RequirePermission(models.PermDisputesManage))
+			disputes.GET("", disputeHandler.AdminListDisputes)
+			disputes.GET("/:id", middleware.UUIDValidator("id"), disputeHandler.AdminGetDispute)
+			disputes.POST("/:id/assign", middleware.UUIDValidator("id"), disputeHandler.AdminAssignDispute)
+			disputes.POST("/:id/resolve", middleware.UUIDValidator("id"), disputeHandler.AdminResolveDispute)
+		}
+
+		if kycHandler != nil {
+			kyc := admin.Group("/identity-verifications", middleware.RequirePermission(models.PermIdentityReview))
+			kyc.GET("", kycHandler.AdminListSubmissions)
+			kyc.GET("/:id", middleware.UUIDValidator("id"), kycHandler.AdminGetSubmission)
+			kyc.POST("/:id/approve", middleware.UUIDValidator("id"), kycHandler.AdminApprove)
+			kyc.POST("/:id/reject", middleware.UUIDValidator("id"), kycHandler.AdminReject)
+		}
+	}
+
 	// === НОВЫЕ ENDPOINTS (Clean Architecture) ===
 	v2 := api.Group("/v2")
-	v2.Use(middleware.AuthMiddleware(tokenManager))
+	v2.Use(middleware.AuthMiddleware(tokenManager, sessionGuard, personalTokens))
 	{
 		// Orders
-		v2.POST("/orders", newOrderHandler.CreateOrder)
+		v2.POST("/orders", middleware.RequirePermission(models.PermOrdersCreate), newOrderHandler.CreateOrder)
 		v2.GET("/orders", newOrderHandler.ListOrders)
 		v2.GET("/orders/:id", middleware.UUIDValidator("id"), newOrderHandler.GetOrder)
 		v2.PUT("/orders/:id", middleware.UUIDValidator("id"), newOrderHandler.UpdateOrder)
 		v2.DELETE("/orders/:id", middleware.UUIDValidator("id"), newOrderHandler.DeleteOrder)
 
 		// Proposals
-		v2.POST("/orders/:id/proposals", middleware.UUIDValidator("id"), newProposalHandler.CreateProposal)
+		v2.POST("/orders/:id/proposals", middleware.RequirePermission(models.PermProposalsCreate), middleware.UUIDValidator("id"), newProposalHandler.CreateProposal)
 		v2.GET("/orders/:id/proposals", middleware.UUIDValidator("id"), newProposalHandler.ListProposals)
 		v2.GET("/orders/:id/my-proposal", middleware.UUIDValidator("id"), newProposalHandler.GetMyProposalForOrder)
 		v2.GET("/proposals/:proposalId", middleware.UUIDValidator("proposalId"), newProposalHandler.GetProposal)
-		v2.PUT("/proposals/:proposalId/status", middleware.UUIDValidator("proposalId"), newProposalHandler.UpdateProposalStatus)
+		v2.PUT("/proposals/:proposalId/status", middleware.RequirePermission(models.PermOrdersHire), middleware.UUIDValidator("proposalId"), newProposalHandler.UpdateProposalStatus)
 		v2.GET("/proposals/my", newProposalHandler.ListMyProposals)
 
 		// Conversations
@@ -281,5 +400,38 @@ func SetupRouter(
 		v2.DELETE("/conversations/:conversationId/messages/:messageId/reactions", middleware.UUIDValidator("conversationId"), middleware.UUIDValidator("messageId"), newConvHandler.RemoveReaction)
 	}
 
+	// Маршруты для интеграций с персональными токенами
+	personalTokens.Allow(models.ScopeOrdersRead,
+		"GET /api/orders/my",
+		"GET /api/v2/orders",
+		"GET /api/v2/orders/:id",
+	)
+	personalTokens.Allow(models.ScopeOrdersWrite,
+		"POST /api/orders",
+		"PUT /api/orders/:id",
+		"DELETE /api/orders/:id",
+		"POST /api/v2/orders",
+		"PUT /api/v2/orders/:id",
+		"DELETE /api/v2/orders/:id",
+	)
+	personalTokens.Allow(models.ScopeProposalsRead,
+		"GET /api/proposals/my",
+		"GET /api/orders/:id/my-proposal",
+		"GET /api/orders/:id/proposals",
+		"GET /api/v2/proposals/my",
+		"GET /api/v2/proposals/:proposalId",
+		"GET /api/v2/orders/:id/proposals",
+		"GET /api/v2/orders/:id/my-proposal",
+	)
+	personalTokens.Allow(models.ScopeProposalsWrite,
+		"POST /api/orders/:id/proposals",
+		"PUT /api/orders/:id/proposals/:proposalId/status",
+		"POST /api/v2/orders/:id/proposals",
+		"PUT /api/v2/proposals/:proposalId/status",
+	)
+	if err := personalTokens.Validate(r.Routes()); err != nil {
+		panic(err)
+	}
+
 	return r
 }
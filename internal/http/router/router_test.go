@@ -0,0 +1,48 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/config"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/service"
+)
+
+func TestRouter_V2RequiresPermissions(t *testing.T) {
+	tokens := service.NewTokenManager("access-secret", "refresh-secret", time.Minute, time.Hour)
+	engine := SetupRouter(&config.Config{Env: "test"},
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+		tokens, nil, nil, nil, nil,
+		nil, nil, nil,
+		nil, nil, nil,
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+	)
+
+	freelancer, err := tokens.GenerateAccess(&models.User{ID: uuid.New(), Role: models.RoleFreelancer}, uuid.New())
+	assert.NoError(t, err)
+	client, err := tokens.GenerateAccess(&models.User{ID: uuid.New(), Role: models.RoleClient}, uuid.New())
+	assert.NoError(t, err)
+
+	// Разрешения проверяются до обработчика, поэтому запрос без прав до него не доходит
+	cases := []struct {
+		method, path, token string
+	}{
+		{http.MethodPost, "/api/v2/orders", freelancer},
+		{http.MethodPost, "/api/v2/orders/" + uuid.NewString() + "/proposals", client},
+		{http.MethodPut, "/api/v2/proposals/" + uuid.NewString() + "/status", freelancer},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{}`))
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code, tc.path)
+	}
+}
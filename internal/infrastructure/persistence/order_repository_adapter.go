@@ -28,8 +28,8 @@ func NewOrderRepositoryAdapter(db *sqlx.DB) *OrderRepositoryAdapter {
 
 func (r *OrderRepositoryAdapter) Create(ctx context.Context, order *entity.Order) error {
 	query := `
-		INSERT INTO orders (id, client_id, title, description, budget_min, budget_max, status, deadline_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO orders (id, client_id, title, description, budget_min, budget_max, currency, status, deadline_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 	
 	_, err := r.db.ExecContext(ctx, query,
@@ -39,6 +39,7 @@ func (r *OrderRepositoryAdapter) Create(ctx context.Context, order *entity.Order
 		order.Description,
 		order.Budget.Min.Amount,
 		order.Budget.Max.Amount,
+		order.Budget.Min.Currency,
 		string(order.Status),
 		order.DeadlineAt,
 		order.CreatedAt,
@@ -69,7 +70,7 @@ func (r *OrderRepositoryAdapter) Update(ctx context.Context, order *entity.Order
 		SET title = $2, description = $3, budget_min = $4, budget_max = $5, 
 		    status = $6, deadline_at = $7, ai_summary = $8, 
 		    best_recommendation_proposal_id = $9, best_recommendation_justification = $10,
-		    ai_analysis_updated_at = $11, freelancer_id = $12, updated_at = $13
+		    ai_analysis_updated_at = $11, freelancer_id = $12, updated_at = $13, currency = $14
 		WHERE id = $1
 	`
 	
@@ -87,6 +88,7 @@ func (r *OrderRepositoryAdapter) Update(ctx context.Context, order *entity.Order
 		order.AIAnalysisUpdatedAt,
 		order.FreelancerID,
 		order.UpdatedAt,
+		order.Budget.Min.Currency,
 	)
 	if err != nil {
 		return apperror.Wrap(err, apperror.ErrCodeDatabaseError, "не удалось обновить заказ")
@@ -116,11 +118,11 @@ func (r *OrderRepositoryAdapter) Delete(ctx context.Context, id uuid.UUID) error
 
 func (r *OrderRepositoryAdapter) FindByID(ctx context.Context, id uuid.UUID) (*entity.Order, error) {
 	var order entity.Order
-	var budgetMin, budgetMax float64
-	var status string
+	var budgetMin, budgetMax valueobject.Amount
+	var currency, status string
 	
 	query := `
-		SELECT id, client_id, freelancer_id, title, description, budget_min, budget_max, status, deadline_at, 
+		SELECT id, client_id, freelancer_id, title, description, budget_min, budget_max, currency, status, deadline_at, 
 		       ai_summary, best_recommendation_proposal_id, best_recommendation_justification, 
 		       ai_analysis_updated_at, created_at, updated_at
 		FROM orders
@@ -135,6 +137,7 @@ func (r *OrderRepositoryAdapter) FindByID(ctx context.Context, id uuid.UUID) (*e
 		&order.Description,
 		&budgetMin,
 		&budgetMax,
+		&currency,
 		&status,
 		&order.DeadlineAt,
 		&order.AISummary,
@@ -152,7 +155,7 @@ func (r *OrderRepositoryAdapter) FindByID(ctx context.Context, id uuid.UUID) (*e
 		return nil, apperror.Wrap(err, apperror.ErrCodeDatabaseError, "не удалось получить заказ")
 	}
 	
-	budget, _ := valueobject.NewBudget(budgetMin, budgetMax)
+	budget, _ := valueobject.NewBudgetInCurrency(budgetMin, budgetMax, currency)
 	order.Budget = budget
 	
 	orderStatus, _ := valueobject.NewOrderStatus(status)
@@ -184,7 +187,7 @@ func (r *OrderRepositoryAdapter) FindByIDWithDetails(ctx context.Context, id uui
 
 func (r *OrderRepositoryAdapter) FindByClientID(ctx context.Context, clientID uuid.UUID) ([]*entity.Order, error) {
 	query := `
-		SELECT id, client_id, freelancer_id, title, description, budget_min, budget_max, status, deadline_at, 
+		SELECT id, client_id, freelancer_id, title, description, budget_min, budget_max, currency, status, deadline_at, 
 		       ai_summary, best_recommendation_proposal_id, best_recommendation_justification, 
 		       ai_analysis_updated_at, created_at, updated_at
 		FROM orders
@@ -201,8 +204,8 @@ func (r *OrderRepositoryAdapter) FindByClientID(ctx context.Context, clientID uu
 	var orders []*entity.Order
 	for rows.Next() {
 		var order entity.Order
-		var budgetMin, budgetMax float64
-		var status string
+		var budgetMin, budgetMax valueobject.Amount
+		var currency, status string
 		
 		err := rows.Scan(
 			&order.ID,
@@ -212,6 +215,7 @@ func (r *OrderRepositoryAdapter) FindByClientID(ctx context.Context, clientID uu
 			&order.Description,
 			&budgetMin,
 			&budgetMax,
+			&currency,
 			&status,
 			&order.DeadlineAt,
 			&order.AISummary,
@@ -225,7 +229,7 @@ func (r *OrderRepositoryAdapter) FindByClientID(ctx context.Context, clientID uu
 			return nil, apperror.Wrap(err, apperror.ErrCodeDatabaseError, "не удалось прочитать заказ")
 		}
 		
-		budget, _ := valueobject.NewBudget(budgetMin, budgetMax)
+		budget, _ := valueobject.NewBudgetInCurrency(budgetMin, budgetMax, currency)
 		order.Budget = budget
 		
 		orderStatus, _ := valueobject.NewOrderStatus(status)
@@ -281,7 +285,7 @@ func (r *OrderRepositoryAdapter) List(ctx context.Context, filter repository.Ord
 		sortOrder = "ASC"
 	}
 
-	selectQuery := fmt.Sprintf(`SELECT id, client_id, freelancer_id, title, description, budget_min, budget_max, status, deadline_at, 
+	selectQuery := fmt.Sprintf(`SELECT id, client_id, freelancer_id, title, description, budget_min, budget_max, currency, status, deadline_at, 
 		ai_summary, best_recommendation_proposal_id, best_recommendation_justification, 
 		ai_analysis_updated_at, created_at, updated_at %s ORDER BY %s %s LIMIT $%d OFFSET $%d`,
 		baseQuery, sortBy, sortOrder, argNum, argNum+1)
@@ -296,12 +300,12 @@ func (r *OrderRepositoryAdapter) List(ctx context.Context, filter repository.Ord
 	var orders []*entity.Order
 	for rows.Next() {
 		var order entity.Order
-		var budgetMin, budgetMax float64
-		var status string
+		var budgetMin, budgetMax valueobject.Amount
+		var currency, status string
 
 		err := rows.Scan(
 			&order.ID, &order.ClientID, &order.FreelancerID, &order.Title, &order.Description,
-			&budgetMin, &budgetMax, &status, &order.DeadlineAt,
+			&budgetMin, &budgetMax, &currency, &status, &order.DeadlineAt,
 			&order.AISummary, &order.BestRecommendationProposalID, &order.BestRecommendationJustification,
 			&order.AIAnalysisUpdatedAt, &order.CreatedAt, &order.UpdatedAt,
 		)
@@ -309,7 +313,7 @@ func (r *OrderRepositoryAdapter) List(ctx context.Context, filter repository.Ord
 			return nil, 0, apperror.Wrap(err, apperror.ErrCodeDatabaseError, "не удалось прочитать заказ")
 		}
 
-		budget, _ := valueobject.NewBudget(budgetMin, budgetMax)
+		budget, _ := valueobject.NewBudgetInCurrency(budgetMin, budgetMax, currency)
 		order.Budget = budget
 		orderStatus, _ := valueobject.NewOrderStatus(status)
 		order.Status = orderStatus
@@ -126,19 +126,19 @@ func (r *ProposalRepositoryAdapter) GetLastUpdateTime(ctx context.Context, order
 }
 
 type proposalRow struct {
-	ID                      uuid.UUID  `db:"id"`
-	OrderID                 uuid.UUID  `db:"order_id"`
-	FreelancerID            uuid.UUID  `db:"freelancer_id"`
-	CoverLetter             string     `db:"cover_letter"`
-	ProposedBudget          float64    `db:"proposed_budget"`
-	ProposedDeadline        *time.Time `db:"proposed_deadline"`
-	Status                  string     `db:"status"`
-	AIFeedback              *string    `db:"ai_feedback"`
-	AIAnalysisForClient     *string    `db:"ai_analysis_for_client"`
-	AIAnalysisForClientAt   *time.Time `db:"ai_analysis_for_client_at"`
-	CompletedByFreelancerAt *time.Time `db:"completed_by_freelancer_at"`
-	CreatedAt               time.Time  `db:"created_at"`
-	UpdatedAt               time.Time  `db:"updated_at"`
+	ID                      uuid.UUID          `db:"id"`
+	OrderID                 uuid.UUID          `db:"order_id"`
+	FreelancerID            uuid.UUID          `db:"freelancer_id"`
+	CoverLetter             string             `db:"cover_letter"`
+	ProposedBudget          valueobject.Amount `db:"proposed_budget"`
+	ProposedDeadline        *time.Time         `db:"proposed_deadline"`
+	Status                  string             `db:"status"`
+	AIFeedback              *string            `db:"ai_feedback"`
+	AIAnalysisForClient     *string            `db:"ai_analysis_for_client"`
+	AIAnalysisForClientAt   *time.Time         `db:"ai_analysis_for_client_at"`
+	CompletedByFreelancerAt *time.Time         `db:"completed_by_freelancer_at"`
+	CreatedAt               time.Time          `db:"created_at"`
+	UpdatedAt               time.Time          `db:"updated_at"`
 }
 
 func (p *proposalRow) toEntity() *entity.Proposal {
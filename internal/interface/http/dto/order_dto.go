@@ -5,13 +5,14 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/ignatzorin/freelance-backend/internal/domain/entity"
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 )
 
 type CreateOrderRequest struct {
 	Title         string              `json:"title" binding:"required"`
 	Description   string              `json:"description" binding:"required"`
-	BudgetMin     float64             `json:"budget_min" binding:"required,gt=0"`
-	BudgetMax     float64             `json:"budget_max" binding:"required,gt=0"`
+	BudgetMin     valueobject.Amount  `json:"budget_min" binding:"required,gt=0"`
+	BudgetMax     valueobject.Amount  `json:"budget_max" binding:"required,gt=0"`
 	DeadlineAt    *string             `json:"deadline_at"`
 	Requirements  []RequirementDTO    `json:"requirements"`
 	AttachmentIDs []string            `json:"attachment_ids"`
@@ -20,8 +21,8 @@ type CreateOrderRequest struct {
 type UpdateOrderRequest struct {
 	Title         string              `json:"title"`
 	Description   string              `json:"description"`
-	BudgetMin     float64             `json:"budget_min"`
-	BudgetMax     float64             `json:"budget_max"`
+	BudgetMin     valueobject.Amount  `json:"budget_min"`
+	BudgetMax     valueobject.Amount  `json:"budget_max"`
 	DeadlineAt    *string             `json:"deadline_at"`
 	Requirements  []RequirementDTO    `json:"requirements"`
 	AttachmentIDs []string            `json:"attachment_ids"`
@@ -37,8 +38,8 @@ type OrderResponse struct {
 	ClientID                        uuid.UUID           `json:"client_id"`
 	Title                           string              `json:"title"`
 	Description                     string              `json:"description"`
-	BudgetMin                       float64             `json:"budget_min"`
-	BudgetMax                       float64             `json:"budget_max"`
+	BudgetMin                       valueobject.Amount  `json:"budget_min"`
+	BudgetMax                       valueobject.Amount  `json:"budget_max"`
 	Status                          string              `json:"status"`
 	DeadlineAt                      *time.Time          `json:"deadline_at"`
 	AISummary                       *string             `json:"ai_summary"`
@@ -5,12 +5,13 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/ignatzorin/freelance-backend/internal/domain/entity"
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 )
 
 type CreateProposalRequest struct {
-	CoverLetter      string  `json:"cover_letter" binding:"required"`
-	ProposedBudget   float64 `json:"proposed_budget" binding:"required,gt=0"`
-	ProposedDeadline *string `json:"proposed_deadline"`
+	CoverLetter      string             `json:"cover_letter" binding:"required"`
+	ProposedBudget   valueobject.Amount `json:"proposed_budget" binding:"required,gt=0"`
+	ProposedDeadline *string            `json:"proposed_deadline"`
 }
 
 type UpdateProposalStatusRequest struct {
@@ -18,19 +19,19 @@ type UpdateProposalStatusRequest struct {
 }
 
 type ProposalResponse struct {
-	ID                      uuid.UUID  `json:"id"`
-	OrderID                 uuid.UUID  `json:"order_id"`
-	FreelancerID            uuid.UUID  `json:"freelancer_id"`
-	CoverLetter             string     `json:"cover_letter"`
-	ProposedBudget          float64    `json:"proposed_budget"`
-	ProposedDeadline        *time.Time `json:"proposed_deadline"`
-	Status                  string     `json:"status"`
-	AIFeedback              *string    `json:"ai_feedback"`
-	AIAnalysisForClient     *string    `json:"ai_analysis_for_client"`
-	AIAnalysisForClientAt   *time.Time `json:"ai_analysis_for_client_at"`
-	CompletedByFreelancerAt *time.Time `json:"completed_by_freelancer_at"`
-	CreatedAt               time.Time  `json:"created_at"`
-	UpdatedAt               time.Time  `json:"updated_at"`
+	ID                      uuid.UUID          `json:"id"`
+	OrderID                 uuid.UUID          `json:"order_id"`
+	FreelancerID            uuid.UUID          `json:"freelancer_id"`
+	CoverLetter             string             `json:"cover_letter"`
+	ProposedBudget          valueobject.Amount `json:"proposed_budget"`
+	ProposedDeadline        *time.Time         `json:"proposed_deadline"`
+	Status                  string             `json:"status"`
+	AIFeedback              *string            `json:"ai_feedback"`
+	AIAnalysisForClient     *string            `json:"ai_analysis_for_client"`
+	AIAnalysisForClientAt   *time.Time         `json:"ai_analysis_for_client_at"`
+	CompletedByFreelancerAt *time.Time         `json:"completed_by_freelancer_at"`
+	CreatedAt               time.Time          `json:"created_at"`
+	UpdatedAt               time.Time          `json:"updated_at"`
 }
 
 func ToProposalResponse(proposal *entity.Proposal) ProposalResponse {
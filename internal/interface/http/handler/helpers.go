@@ -6,6 +6,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 )
 
 func getUserID(c *gin.Context) (uuid.UUID, error) {
@@ -36,13 +38,13 @@ func parseIntQuery(c *gin.Context, key string, defaultValue int) int {
 	return value
 }
 
-func parseFloatQuery(c *gin.Context, key string) *float64 {
+func parseAmountQuery(c *gin.Context, key string) *valueobject.Amount {
 	valueStr := c.Query(key)
 	if valueStr == "" {
 		return nil
 	}
 	
-	value, err := strconv.ParseFloat(valueStr, 64)
+	value, err := valueobject.ParseAmount(valueStr)
 	if err != nil {
 		return nil
 	}
@@ -139,10 +139,10 @@ func (h *OrderHandler) ListOrders(c *gin.Context) {
 		Offset:    parseIntQuery(c, "offset", 0),
 	}
 
-	if budgetMin := parseFloatQuery(c, "budget_min"); budgetMin != nil {
+	if budgetMin := parseAmountQuery(c, "budget_min"); budgetMin != nil {
 		filter.BudgetMin = budgetMin
 	}
-	if budgetMax := parseFloatQuery(c, "budget_max"); budgetMax != nil {
+	if budgetMax := parseAmountQuery(c, "budget_max"); budgetMax != nil {
 		filter.BudgetMax = budgetMax
 	}
 
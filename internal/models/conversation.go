@@ -27,6 +27,10 @@ type Message struct {
 	AIMetadata     json.RawMessage `db:"ai_metadata" json:"ai_metadata,omitempty"`
 	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt      *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+	HiddenAt       *time.Time `db:"hidden_at" json:"hidden_at,omitempty"`
+	HiddenBy       *uuid.UUID `db:"hidden_by" json:"-"`
+	// ReadAt — когда сообщение прочитал собеседник автора; nil, пока не прочитано
+	ReadAt         *time.Time `db:"-" json:"read_at,omitempty"`
 	// Связанные данные (загружаются отдельно)
 	Attachments    []MessageAttachment `json:"attachments,omitempty"`
 	Reactions       []MessageReaction    `json:"reactions,omitempty"`
@@ -51,12 +55,21 @@ type MessageReaction struct {
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 }
 
+// ReadReceipt описывает сообщения, которые участник чата отметил прочитанными за одну команду.
+type ReadReceipt struct {
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	ReaderID       uuid.UUID   `json:"reader_id"`
+	MessageIDs     []uuid.UUID `json:"message_ids"`
+	ReadAt         time.Time   `json:"read_at"`
+}
+
 // Notification описывает событие, отправленное пользователю.
 type Notification struct {
 	ID        uuid.UUID       `db:"id" json:"id"`
 	UserID    uuid.UUID       `db:"user_id" json:"user_id"`
 	Payload   json.RawMessage `db:"payload" json:"payload"`
 	IsRead    bool            `db:"is_read" json:"is_read"`
+	Seq       *int64          `db:"seq" json:"seq,omitempty"`
 	CreatedAt time.Time       `db:"created_at" json:"created_at"`
 }
 
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+)
+
+// Валюты платформы (коды ISO 4217)
+const (
+	CurrencyRUB = "RUB"
+	CurrencyUSD = "USD"
+	CurrencyEUR = "EUR"
+)
+
+// DefaultCurrency валюта заказов и балансов, если другая не указана.
+const DefaultCurrency = CurrencyRUB
+
+// SupportedCurrencies валюты, в которых можно публиковать заказы, откликаться и держать баланс.
+var SupportedCurrencies = map[string]struct{}{
+	CurrencyRUB: {},
+	CurrencyUSD: {},
+	CurrencyEUR: {},
+}
+
+// MinWithdrawalAmounts минимальная сумма вывода в каждой валюте.
+var MinWithdrawalAmounts = map[string]valueobject.Amount{
+	CurrencyRUB: valueobject.AmountFromFloat(100),
+	CurrencyUSD: valueobject.AmountFromFloat(1),
+	CurrencyEUR: valueobject.AmountFromFloat(1),
+}
+
+// NormalizeCurrency приводит код валюты к верхнему регистру и проверяет, что она поддерживается.
+// Пустой код означает валюту по умолчанию.
+func NormalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return DefaultCurrency, true
+	}
+	_, ok := SupportedCurrencies[code]
+	return code, ok
+}
+
+// ConvertedAmount — сумма, пересчитанная в другую валюту по курсу на момент запроса.
+type ConvertedAmount struct {
+	Amount   valueobject.Amount `json:"amount"`
+	Currency string             `json:"currency"`
+	Rate     float64            `json:"rate"`
+}
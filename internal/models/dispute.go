@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 )
 
 const (
@@ -11,18 +13,44 @@ const (
 	DisputeStatusUnderReview      = "under_review"
 	DisputeStatusResolvedClient   = "resolved_client"
 	DisputeStatusResolvedFreelancer = "resolved_freelancer"
+	DisputeStatusResolvedSplit      = "resolved_split"
 	DisputeStatusCancelled        = "cancelled"
 )
 
+// Виды доказательств по спору
+const (
+	DisputeEvidenceNote       = "note"
+	DisputeEvidenceMessage    = "message"
+	DisputeEvidenceAttachment = "attachment"
+)
+
 type Dispute struct {
-	ID          uuid.UUID  `db:"id" json:"id"`
-	EscrowID    uuid.UUID  `db:"escrow_id" json:"escrow_id"`
-	OrderID     uuid.UUID  `db:"order_id" json:"order_id"`
-	InitiatorID uuid.UUID  `db:"initiator_id" json:"initiator_id"`
-	Reason      string     `db:"reason" json:"reason"`
-	Status      string     `db:"status" json:"status"`
-	Resolution  *string    `db:"resolution" json:"resolution,omitempty"`
-	ResolvedBy  *uuid.UUID `db:"resolved_by" json:"resolved_by,omitempty"`
-	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
-	ResolvedAt  *time.Time `db:"resolved_at" json:"resolved_at,omitempty"`
+	ID               uuid.UUID           `db:"id" json:"id"`
+	EscrowID         uuid.UUID           `db:"escrow_id" json:"escrow_id"`
+	OrderID          uuid.UUID           `db:"order_id" json:"order_id"`
+	MilestoneID      *uuid.UUID          `db:"milestone_id" json:"milestone_id,omitempty"`
+	InitiatorID      uuid.UUID           `db:"initiator_id" json:"initiator_id"`
+	Reason           string              `db:"reason" json:"reason"`
+	Status           string              `db:"status" json:"status"`
+	Resolution       *string             `db:"resolution" json:"resolution,omitempty"`
+	ResolvedBy       *uuid.UUID          `db:"resolved_by" json:"resolved_by,omitempty"`
+	AssignedTo       *uuid.UUID          `db:"assigned_to" json:"assigned_to,omitempty"`
+	AssignedAt       *time.Time          `db:"assigned_at" json:"assigned_at,omitempty"`
+	ClientPercent    *float64            `db:"client_percent" json:"client_percent,omitempty"`
+	ClientAmount     *valueobject.Amount `db:"client_amount" json:"client_amount,omitempty"`
+	FreelancerAmount *valueobject.Amount `db:"freelancer_amount" json:"freelancer_amount,omitempty"`
+	CreatedAt        time.Time           `db:"created_at" json:"created_at"`
+	ResolvedAt       *time.Time          `db:"resolved_at" json:"resolved_at,omitempty"`
+}
+
+// DisputeEvidence — доказательство по спору: заметка, ссылка на сообщение чата или файл.
+type DisputeEvidence struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	DisputeID uuid.UUID  `db:"dispute_id" json:"dispute_id"`
+	AuthorID  uuid.UUID  `db:"author_id" json:"author_id"`
+	Kind      string     `db:"kind" json:"kind"`
+	Content   *string    `db:"content" json:"content,omitempty"`
+	MessageID *uuid.UUID `db:"message_id" json:"message_id,omitempty"`
+	MediaID   *uuid.UUID `db:"media_id" json:"media_id,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
 }
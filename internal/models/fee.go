@@ -0,0 +1,59 @@
+package models
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+)
+
+// FeeTier — ставка комиссии для фрилансеров с заработком не меньше MinEarnings.
+type FeeTier struct {
+	MinEarnings valueobject.Amount `db:"min_earnings" json:"min_earnings"`
+	Percent     float64            `db:"percent" json:"percent"`
+}
+
+// FeePolicy — правила расчёта комиссии платформы с выплаты фрилансеру.
+type FeePolicy struct {
+	DefaultPercent   float64
+	MinFee           valueobject.Amount
+	CategoryPercents map[uuid.UUID]float64
+	Tiers            []FeeTier
+}
+
+// Percent возвращает ставку: ставка категории (или базовая), но не выше ставки
+// самого старшего уровня, которого достиг фрилансер.
+func (p *FeePolicy) Percent(categoryID *uuid.UUID, lifetimeEarnings valueobject.Amount) float64 {
+	percent := p.DefaultPercent
+	if categoryID != nil {
+		if categoryPercent, ok := p.CategoryPercents[*categoryID]; ok {
+			percent = categoryPercent
+		}
+	}
+
+	var tier *FeeTier
+	for i := range p.Tiers {
+		if lifetimeEarnings >= p.Tiers[i].MinEarnings && (tier == nil || p.Tiers[i].MinEarnings > tier.MinEarnings) {
+			tier = &p.Tiers[i]
+		}
+	}
+	if tier != nil && tier.Percent < percent {
+		percent = tier.Percent
+	}
+	return percent
+}
+
+// Fee рассчитывает комиссию с суммы выплаты с точностью до копейки.
+// Комиссия не меньше MinFee и не больше самой выплаты.
+func (p *FeePolicy) Fee(amount valueobject.Amount, categoryID *uuid.UUID, lifetimeEarnings valueobject.Amount) valueobject.Amount {
+	if amount <= 0 {
+		return 0
+	}
+	fee := amount.Percent(p.Percent(categoryID, lifetimeEarnings))
+	if fee < p.MinFee {
+		fee = p.MinFee
+	}
+	if fee > amount {
+		fee = amount
+	}
+	return fee
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+)
+
+func TestFeePolicy_Fee(t *testing.T) {
+	rub := valueobject.AmountFromFloat
+	design := uuid.New()
+	policy := &FeePolicy{
+		DefaultPercent:   10,
+		MinFee:           rub(50),
+		CategoryPercents: map[uuid.UUID]float64{design: 15},
+		Tiers: []FeeTier{
+			{MinEarnings: rub(100000), Percent: 8},
+			{MinEarnings: rub(500000), Percent: 5},
+		},
+	}
+
+	// Базовая ставка
+	assert.Equal(t, rub(1000), policy.Fee(rub(10000), nil, 0))
+	// Ставка категории
+	assert.Equal(t, rub(1500), policy.Fee(rub(10000), &design, 0))
+	// Уровень по заработку снижает ставку категории
+	assert.Equal(t, rub(800), policy.Fee(rub(10000), &design, rub(150000)))
+	assert.Equal(t, rub(500), policy.Fee(rub(10000), nil, rub(500000)))
+	// Минимальная комиссия
+	assert.Equal(t, rub(50), policy.Fee(rub(100), nil, 0))
+	// Комиссия не больше выплаты
+	assert.Equal(t, rub(30), policy.Fee(rub(30), nil, 0))
+	// Округление до копейки
+	assert.Equal(t, rub(123.46), policy.Fee(rub(1234.56), nil, 0))
+}
+
+func TestFeePolicy_TierDoesNotRaiseLowerCategoryRate(t *testing.T) {
+	promo := uuid.New()
+	policy := &FeePolicy{
+		DefaultPercent:   10,
+		CategoryPercents: map[uuid.UUID]float64{promo: 3},
+		Tiers:            []FeeTier{{MinEarnings: 0, Percent: 8}},
+	}
+
+	assert.Equal(t, 3.0, policy.Percent(&promo, valueobject.AmountFromFloat(1000)))
+	assert.Equal(t, 8.0, policy.Percent(nil, valueobject.AmountFromFloat(1000)))
+}
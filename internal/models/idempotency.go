@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey — запрос, выполненный с заголовком Idempotency-Key, и его сохранённый ответ.
+type IdempotencyKey struct {
+	UserID       uuid.UUID  `db:"user_id"`
+	Key          string     `db:"key"`
+	RequestHash  string     `db:"request_hash"`
+	StatusCode   *int       `db:"status_code"`
+	ContentType  *string    `db:"content_type"`
+	ResponseBody []byte     `db:"response_body"`
+	CreatedAt    time.Time  `db:"created_at"`
+	CompletedAt  *time.Time `db:"completed_at"`
+}
+
+// Completed сообщает, что ответ на запрос уже сохранён.
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != nil
+}
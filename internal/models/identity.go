@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity — учётная запись внешнего провайдера входа, привязанная к пользователю.
+type UserIdentity struct {
+	ID          uuid.UUID  `db:"id" json:"id"`
+	UserID      uuid.UUID  `db:"user_id" json:"-"`
+	Provider    string     `db:"provider" json:"provider"`
+	Subject     string     `db:"subject" json:"-"`
+	Email       *string    `db:"email" json:"email,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	LastLoginAt *time.Time `db:"last_login_at" json:"last_login_at,omitempty"`
+}
+
+// OIDCLoginState — начатый вход через внешнего провайдера, ожидающий возврата пользователя.
+type OIDCLoginState struct {
+	StateHash    string    `db:"state_hash"`
+	Provider     string    `db:"provider"`
+	CodeVerifier string    `db:"code_verifier"`
+	Nonce        string    `db:"nonce"`
+	ExpiresAt    time.Time `db:"expires_at"`
+	CreatedAt    time.Time `db:"created_at"`
+}
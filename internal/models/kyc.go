@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Статусы заявки на проверку личности
+const (
+	KYCStatusPending  = "pending"
+	KYCStatusApproved = "approved"
+	KYCStatusRejected = "rejected"
+)
+
+// Типы документов, удостоверяющих личность
+const (
+	KYCDocumentPassport      = "passport"
+	KYCDocumentIDCard        = "id_card"
+	KYCDocumentDriverLicense = "driver_license"
+)
+
+// Фотографии в заявке: лицевая сторона, оборот и фото с документом в руках
+const (
+	KYCDocumentFront  = "front"
+	KYCDocumentBack   = "back"
+	KYCDocumentSelfie = "selfie"
+)
+
+// KYCDocumentTypes допустимые типы документов.
+var KYCDocumentTypes = map[string]struct{}{
+	KYCDocumentPassport:      {},
+	KYCDocumentIDCard:        {},
+	KYCDocumentDriverLicense: {},
+}
+
+// KYCDocumentKinds допустимые фотографии в заявке.
+var KYCDocumentKinds = map[string]struct{}{
+	KYCDocumentFront:  {},
+	KYCDocumentBack:   {},
+	KYCDocumentSelfie: {},
+}
+
+// KYCSubmission — заявка пользователя на проверку личности.
+type KYCSubmission struct {
+	ID              uuid.UUID     `db:"id" json:"id"`
+	UserID          uuid.UUID     `db:"user_id" json:"user_id"`
+	DocumentType    string        `db:"document_type" json:"document_type"`
+	Status          string        `db:"status" json:"status"`
+	RejectionReason *string       `db:"rejection_reason" json:"rejection_reason,omitempty"`
+	ReviewedBy      *uuid.UUID    `db:"reviewed_by" json:"-"`
+	ReviewedAt      *time.Time    `db:"reviewed_at" json:"reviewed_at,omitempty"`
+	CreatedAt       time.Time     `db:"created_at" json:"created_at"`
+	Documents       []KYCDocument `db:"-" json:"documents"`
+}
+
+// KYCDocument — фотография документа, приложенная к заявке.
+type KYCDocument struct {
+	Kind    string    `db:"kind" json:"kind"`
+	MediaID uuid.UUID `db:"media_id" json:"media_id"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+)
+
+// Счета двойной записи
+const (
+	LedgerAccountUserAvailable  = "user_available"
+	LedgerAccountUserFrozen     = "user_frozen"
+	LedgerAccountPlatformFees   = "platform_fees"
+	LedgerAccountPlatformEscrow = "platform_escrow"
+	LedgerAccountExternalCash   = "external_cash"
+)
+
+// Виды журналов (хозяйственных операций)
+const (
+	LedgerJournalDeposit          = "deposit"
+	LedgerJournalEscrowHold       = "escrow_hold"
+	LedgerJournalEscrowRelease    = "escrow_release"
+	LedgerJournalEscrowRefund     = "escrow_refund"
+	LedgerJournalEscrowSplit      = "escrow_split"
+	LedgerJournalWithdrawalHold   = "withdrawal_hold"
+	LedgerJournalWithdrawalPayout = "withdrawal_payout"
+	LedgerJournalWithdrawalReject = "withdrawal_reject"
+)
+
+// LedgerMismatch — расхождение баланса пользователя в валюте с балансом, выведенным из журнала.
+type LedgerMismatch struct {
+	UserID          uuid.UUID          `db:"user_id" json:"user_id"`
+	Currency        string             `db:"currency" json:"currency"`
+	Available       valueobject.Amount `db:"available" json:"available"`
+	Frozen          valueobject.Amount `db:"frozen" json:"frozen"`
+	LedgerAvailable valueobject.Amount `db:"ledger_available" json:"ledger_available"`
+	LedgerFrozen    valueobject.Amount `db:"ledger_frozen" json:"ledger_frozen"`
+}
+
+// LedgerEscrowMismatch — расхождение суммы незакрытых escrow в валюте со счётом platform_escrow.
+type LedgerEscrowMismatch struct {
+	Currency string             `db:"currency" json:"currency"`
+	Held     valueobject.Amount `db:"held" json:"held"`
+	Ledger   valueobject.Amount `db:"ledger" json:"ledger"`
+}
+
+// LedgerReconciliation — результат сверки журнала с user_balances и escrow.
+type LedgerReconciliation struct {
+	Mismatches         []LedgerMismatch       `json:"mismatches"`
+	EscrowMismatches   []LedgerEscrowMismatch `json:"escrow_mismatches"`
+	UnbalancedJournals []uuid.UUID            `json:"unbalanced_journals"`
+}
+
+// OK сообщает, что расхождений не найдено.
+func (r *LedgerReconciliation) OK() bool {
+	return len(r.Mismatches) == 0 && len(r.EscrowMismatches) == 0 && len(r.UnbalancedJournals) == 0
+}
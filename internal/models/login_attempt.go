@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginAttempt — попытка входа по паролю.
+type LoginAttempt struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	Email     string     `db:"email" json:"email"`
+	UserID    *uuid.UUID `db:"user_id" json:"user_id,omitempty"`
+	IPAddress *string    `db:"ip_address" json:"ip_address,omitempty"`
+	UserAgent *string    `db:"user_agent" json:"user_agent,omitempty"`
+	Success   bool       `db:"success" json:"success"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
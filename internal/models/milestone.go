@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+)
+
+// Статусы этапов заказа
+const (
+	MilestoneStatusPending   = "pending"
+	MilestoneStatusFunded    = "funded"
+	MilestoneStatusSubmitted = "submitted"
+	MilestoneStatusReleased  = "released"
+	MilestoneStatusRefunded  = "refunded"
+	MilestoneStatusDisputed  = "disputed"
+)
+
+// Milestone описывает этап заказа с собственной суммой, сроком и приёмкой. Currency не хранится
+// в этапе: это валюта его escrow, а до оплаты — валюта, в которой этап будет оплачен.
+type Milestone struct {
+	ID          uuid.UUID          `db:"id" json:"id"`
+	OrderID     uuid.UUID          `db:"order_id" json:"order_id"`
+	Title       string             `db:"title" json:"title"`
+	Description *string            `db:"description" json:"description,omitempty"`
+	Amount      valueobject.Amount `db:"amount" json:"amount"`
+	Currency    string             `db:"currency" json:"currency"`
+	Position    int                `db:"position" json:"position"`
+	Status      string             `db:"status" json:"status"`
+	DueAt       *time.Time         `db:"due_at" json:"due_at,omitempty"`
+	FundedAt    *time.Time         `db:"funded_at" json:"funded_at,omitempty"`
+	SubmittedAt *time.Time         `db:"submitted_at" json:"submitted_at,omitempty"`
+	ReleasedAt  *time.Time         `db:"released_at" json:"released_at,omitempty"`
+	CreatedAt   time.Time          `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `db:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Каналы доставки уведомлений.
+const (
+	// NotificationChannelInApp — только список уведомлений и WebSocket; значение по умолчанию.
+	NotificationChannelInApp = "in_app"
+	// NotificationChannelEmail и NotificationChannelTelegram дублируют уведомление письмом
+	// или сообщением от бота.
+	NotificationChannelEmail    = "email"
+	NotificationChannelTelegram = "telegram"
+	// NotificationChannelNone — уведомление не сохраняется и никуда не отправляется.
+	NotificationChannelNone = "none"
+)
+
+// NotificationEventDefault — тип события в настройках, задающий канал для всех остальных типов.
+const NotificationEventDefault = "*"
+
+// NotificationPreference — канал доставки для одного типа события.
+type NotificationPreference struct {
+	EventType string `db:"event_type" json:"event_type"`
+	Channel   string `db:"channel" json:"channel"`
+}
+
+// NotificationSettings — общие настройки уведомлений пользователя. Тихие часы заданы минутами
+// от полуночи в часовом поясе Timezone; начало может быть больше конца, если интервал
+// переходит через полночь.
+type NotificationSettings struct {
+	UserID          uuid.UUID  `db:"user_id" json:"-"`
+	QuietHoursStart *int       `db:"quiet_hours_start" json:"-"`
+	QuietHoursEnd   *int       `db:"quiet_hours_end" json:"-"`
+	Timezone        string     `db:"timezone" json:"timezone"`
+	DigestEnabled   bool       `db:"digest_enabled" json:"digest_enabled"`
+	LastDigestAt    *time.Time `db:"last_digest_at" json:"last_digest_at,omitempty"`
+}
+
+// InQuietHours сообщает, попадает ли момент t в тихие часы.
+func (s *NotificationSettings) InQuietHours(t time.Time) bool {
+	if s.QuietHoursStart == nil || s.QuietHoursEnd == nil || *s.QuietHoursStart == *s.QuietHoursEnd {
+		return false
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	start, end := *s.QuietHoursStart, *s.QuietHoursEnd
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// NotificationDelivery — как доставить событие пользователю: выбранный канал, настройки
+// и контакты для внешних каналов.
+type NotificationDelivery struct {
+	Channel  string
+	Settings NotificationSettings
+	Email    string
+	Telegram *string
+	// Телефон профиля, его подтверждение и номер, которым чат из поля Telegram поделился с ботом:
+	// по ним проверяется, что чат принадлежит пользователю
+	Phone         *string
+	PhoneVerified bool
+	TelegramPhone *string
+}
+
+// DeferredNotification — письмо или сообщение в Telegram, отложенное до конца тихих часов.
+type DeferredNotification struct {
+	ID        int64     `db:"id"`
+	EventType string    `db:"event_type"`
+	Channel   string    `db:"channel"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// DigestRecipient — пользователь, которому пора отправить сводку.
+type DigestRecipient struct {
+	NotificationSettings
+	Email string `db:"email"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationSettings_InQuietHours(t *testing.T) {
+	minutes := func(v int) *int { return &v }
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 10, hour, minute, 0, 0, time.UTC)
+	}
+
+	// Не заданы — тихих часов нет
+	assert.False(t, (&NotificationSettings{Timezone: "UTC"}).InQuietHours(at(3, 0)))
+
+	day := &NotificationSettings{QuietHoursStart: minutes(13 * 60), QuietHoursEnd: minutes(14 * 60), Timezone: "UTC"}
+	assert.True(t, day.InQuietHours(at(13, 0)))
+	assert.False(t, day.InQuietHours(at(14, 0)))
+
+	// Интервал через полночь
+	night := &NotificationSettings{QuietHoursStart: minutes(23 * 60), QuietHoursEnd: minutes(7 * 60), Timezone: "UTC"}
+	assert.True(t, night.InQuietHours(at(23, 30)))
+	assert.True(t, night.InQuietHours(at(6, 59)))
+	assert.False(t, night.InQuietHours(at(12, 0)))
+
+	// Время считается в часовом поясе пользователя: 20:30 UTC — 23:30 в Москве, 05:00 UTC — 08:00
+	night.Timezone = "Europe/Moscow"
+	assert.True(t, night.InQuietHours(at(20, 30)))
+	assert.False(t, night.InQuietHours(at(5, 0)))
+}
@@ -4,30 +4,33 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 )
 
 // Order описывает заказ на разработку или услугу.
 type Order struct {
-	ID                              uuid.UUID  `db:"id" json:"id"`
-	ClientID                        uuid.UUID  `db:"client_id" json:"client_id"`
-	FreelancerID                    *uuid.UUID `db:"freelancer_id" json:"freelancer_id,omitempty"`
-	CategoryID                      *uuid.UUID `db:"category_id" json:"category_id,omitempty"`
-	Title                           string     `db:"title" json:"title"`
-	Description                     string     `db:"description" json:"description"`
-	BudgetMin                       *float64   `db:"budget_min" json:"budget_min,omitempty"`
-	BudgetMax                       *float64   `db:"budget_max" json:"budget_max,omitempty"`
-	FinalAmount                     *float64   `db:"final_amount" json:"final_amount,omitempty"`
-	Status                          string     `db:"status" json:"status"`
-	DeadlineAt                      *time.Time `db:"deadline_at" json:"deadline_at,omitempty"`
-	AISummary                       *string    `db:"ai_summary" json:"ai_summary,omitempty"`
-	BestRecommendationProposalID    *uuid.UUID `db:"best_recommendation_proposal_id" json:"best_recommendation_proposal_id,omitempty"`
-	BestRecommendationJustification *string    `db:"best_recommendation_justification" json:"best_recommendation_justification,omitempty"`
-	AIAnalysisUpdatedAt             *time.Time `db:"ai_analysis_updated_at" json:"ai_analysis_updated_at,omitempty"`
-	CreatedAt                       time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt                       time.Time  `db:"updated_at" json:"updated_at"`
-	Attachments                     []OrderAttachment `json:"attachments,omitempty"`
-	ProposalsCount                  *int       `db:"proposals_count" json:"proposals_count,omitempty"`
-	Category                        *Category  `json:"category,omitempty"`
+	ID                              uuid.UUID           `db:"id" json:"id"`
+	ClientID                        uuid.UUID           `db:"client_id" json:"client_id"`
+	FreelancerID                    *uuid.UUID          `db:"freelancer_id" json:"freelancer_id,omitempty"`
+	CategoryID                      *uuid.UUID          `db:"category_id" json:"category_id,omitempty"`
+	Title                           string              `db:"title" json:"title"`
+	Description                     string              `db:"description" json:"description"`
+	BudgetMin                       *valueobject.Amount `db:"budget_min" json:"budget_min,omitempty"`
+	BudgetMax                       *valueobject.Amount `db:"budget_max" json:"budget_max,omitempty"`
+	Currency                        string              `db:"currency" json:"currency"`
+	FinalAmount                     *valueobject.Amount `db:"final_amount" json:"final_amount,omitempty"`
+	Status                          string              `db:"status" json:"status"`
+	DeadlineAt                      *time.Time          `db:"deadline_at" json:"deadline_at,omitempty"`
+	AISummary                       *string             `db:"ai_summary" json:"ai_summary,omitempty"`
+	BestRecommendationProposalID    *uuid.UUID          `db:"best_recommendation_proposal_id" json:"best_recommendation_proposal_id,omitempty"`
+	BestRecommendationJustification *string             `db:"best_recommendation_justification" json:"best_recommendation_justification,omitempty"`
+	AIAnalysisUpdatedAt             *time.Time          `db:"ai_analysis_updated_at" json:"ai_analysis_updated_at,omitempty"`
+	CreatedAt                       time.Time           `db:"created_at" json:"created_at"`
+	UpdatedAt                       time.Time           `db:"updated_at" json:"updated_at"`
+	Attachments                     []OrderAttachment   `json:"attachments,omitempty"`
+	ProposalsCount                  *int                `db:"proposals_count" json:"proposals_count,omitempty"`
+	Category                        *Category           `json:"category,omitempty"`
 }
 
 // OrderRequirement хранит информацию о требуемых навыках.
@@ -49,13 +52,17 @@ type OrderAttachment struct {
 
 // Proposal представляет отклик фрилансера на заказ.
 type Proposal struct {
-	ID             uuid.UUID `db:"id" json:"id"`
-	OrderID        uuid.UUID `db:"order_id" json:"order_id"`
-	FreelancerID   uuid.UUID `db:"freelancer_id" json:"freelancer_id"`
-	CoverLetter    string    `db:"cover_letter" json:"cover_letter"`
-	ProposedAmount *float64  `db:"proposed_amount" json:"proposed_amount,omitempty"`
-	Status         string    `db:"status" json:"status"`
-	AIFeedback     *string   `db:"ai_feedback" json:"ai_feedback,omitempty"`
-	CreatedAt      time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+	ID             uuid.UUID           `db:"id" json:"id"`
+	OrderID        uuid.UUID           `db:"order_id" json:"order_id"`
+	FreelancerID   uuid.UUID           `db:"freelancer_id" json:"freelancer_id"`
+	CoverLetter    string              `db:"cover_letter" json:"cover_letter"`
+	ProposedAmount *valueobject.Amount `db:"proposed_amount" json:"proposed_amount,omitempty"`
+	Currency       string              `db:"currency" json:"currency"`
+	Status         string              `db:"status" json:"status"`
+	AIFeedback     *string             `db:"ai_feedback" json:"ai_feedback,omitempty"`
+	CreatedAt      time.Time           `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time           `db:"updated_at" json:"updated_at"`
+
+	// ConvertedAmount — предложенная сумма в валюте заказа, если отклик в другой валюте
+	ConvertedAmount *ConvertedAmount `db:"-" json:"converted_amount,omitempty"`
 }
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 )
 
 // Статусы escrow
@@ -21,6 +23,7 @@ const (
 	TransactionTypeEscrowHold    = "escrow_hold"
 	TransactionTypeEscrowRelease = "escrow_release"
 	TransactionTypeEscrowRefund  = "escrow_refund"
+	TransactionTypePlatformFee   = "platform_fee"
 )
 
 // Статусы транзакций
@@ -31,35 +34,41 @@ const (
 	TransactionStatusCancelled = "cancelled"
 )
 
-// UserBalance представляет баланс пользователя.
+// UserBalance представляет баланс пользователя в одной валюте.
 type UserBalance struct {
-	UserID    uuid.UUID `db:"user_id" json:"user_id"`
-	Available float64   `db:"available" json:"available"`
-	Frozen    float64   `db:"frozen" json:"frozen"`
-	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	UserID    uuid.UUID          `db:"user_id" json:"user_id"`
+	Currency  string             `db:"currency" json:"currency"`
+	Available valueobject.Amount `db:"available" json:"available"`
+	Frozen    valueobject.Amount `db:"frozen" json:"frozen"`
+	UpdatedAt time.Time          `db:"updated_at" json:"updated_at"`
 }
 
 // Transaction представляет финансовую транзакцию.
 type Transaction struct {
-	ID          uuid.UUID  `db:"id" json:"id"`
-	UserID      uuid.UUID  `db:"user_id" json:"user_id"`
-	OrderID     *uuid.UUID `db:"order_id" json:"order_id,omitempty"`
-	Type        string     `db:"type" json:"type"`
-	Amount      float64    `db:"amount" json:"amount"`
-	Status      string     `db:"status" json:"status"`
-	Description *string    `db:"description" json:"description,omitempty"`
-	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
-	CompletedAt *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+	ID          uuid.UUID          `db:"id" json:"id"`
+	UserID      uuid.UUID          `db:"user_id" json:"user_id"`
+	OrderID     *uuid.UUID         `db:"order_id" json:"order_id,omitempty"`
+	MilestoneID *uuid.UUID         `db:"milestone_id" json:"milestone_id,omitempty"`
+	Type        string             `db:"type" json:"type"`
+	Amount      valueobject.Amount `db:"amount" json:"amount"`
+	Currency    string             `db:"currency" json:"currency"`
+	Status      string             `db:"status" json:"status"`
+	Description *string            `db:"description" json:"description,omitempty"`
+	CreatedAt   time.Time          `db:"created_at" json:"created_at"`
+	CompletedAt *time.Time         `db:"completed_at" json:"completed_at,omitempty"`
 }
 
 // Escrow представляет защищённую сделку.
 type Escrow struct {
-	ID           uuid.UUID  `db:"id" json:"id"`
-	OrderID      uuid.UUID  `db:"order_id" json:"order_id"`
-	ClientID     uuid.UUID  `db:"client_id" json:"client_id"`
-	FreelancerID uuid.UUID  `db:"freelancer_id" json:"freelancer_id"`
-	Amount       float64    `db:"amount" json:"amount"`
-	Status       string     `db:"status" json:"status"`
-	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
-	ReleasedAt   *time.Time `db:"released_at" json:"released_at,omitempty"`
+	ID           uuid.UUID          `db:"id" json:"id"`
+	OrderID      uuid.UUID          `db:"order_id" json:"order_id"`
+	MilestoneID  *uuid.UUID         `db:"milestone_id" json:"milestone_id,omitempty"`
+	ClientID     uuid.UUID          `db:"client_id" json:"client_id"`
+	FreelancerID uuid.UUID          `db:"freelancer_id" json:"freelancer_id"`
+	Amount       valueobject.Amount `db:"amount" json:"amount"`
+	FeeAmount    valueobject.Amount `db:"fee_amount" json:"fee_amount"`
+	Currency     string             `db:"currency" json:"currency"`
+	Status       string             `db:"status" json:"status"`
+	CreatedAt    time.Time          `db:"created_at" json:"created_at"`
+	ReleasedAt   *time.Time         `db:"released_at" json:"released_at,omitempty"`
 }
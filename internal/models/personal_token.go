@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Права персональных токенов доступа. Токен работает только на маршрутах,
+// которым нужно одно из его прав; остальные API доступны лишь после входа.
+const (
+	ScopeOrdersRead     = "orders:read"
+	ScopeOrdersWrite    = "orders:write"
+	ScopeProposalsRead  = "proposals:read"
+	ScopeProposalsWrite = "proposals:write"
+)
+
+// TokenScopes допустимые права персональных токенов.
+var TokenScopes = map[string]struct{}{
+	ScopeOrdersRead:     {},
+	ScopeOrdersWrite:    {},
+	ScopeProposalsRead:  {},
+	ScopeProposalsWrite: {},
+}
+
+// PersonalAccessToken — долгоживущий токен пользователя для скриптов и интеграций.
+type PersonalAccessToken struct {
+	ID          uuid.UUID      `db:"id" json:"id"`
+	UserID      uuid.UUID      `db:"user_id" json:"-"`
+	Name        string         `db:"name" json:"name"`
+	TokenHash   string         `db:"token_hash" json:"-"`
+	TokenPrefix string         `db:"token_prefix" json:"token_prefix"`
+	Scopes      pq.StringArray `db:"scopes" json:"scopes"`
+	ExpiresAt   *time.Time     `db:"expires_at" json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time     `db:"last_used_at" json:"last_used_at,omitempty"`
+	LastUsedIP  *string        `db:"last_used_ip" json:"last_used_ip,omitempty"`
+	RevokedAt   *time.Time     `db:"revoked_at" json:"-"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Статусы выгрузки данных пользователя.
+const (
+	DataExportPending    = "pending"
+	DataExportProcessing = "processing"
+	DataExportReady      = "ready"
+	DataExportFailed     = "failed"
+	DataExportExpired    = "expired"
+)
+
+// Причины, по которым аккаунт пока нельзя удалить.
+const (
+	DeletionBlockerEscrowHeld        = "escrow_held"
+	DeletionBlockerDisputeOpen       = "dispute_open"
+	DeletionBlockerWithdrawalPending = "withdrawal_pending"
+	DeletionBlockerBalance           = "balance_not_empty"
+)
+
+// DataExport — ZIP-архив с данными пользователя, который собирается в фоне.
+type DataExport struct {
+	ID                  uuid.UUID  `db:"id" json:"id"`
+	UserID              uuid.UUID  `db:"user_id" json:"-"`
+	Status              string     `db:"status" json:"status"`
+	FilePath            *string    `db:"file_path" json:"-"`
+	FileSize            *int64     `db:"file_size" json:"file_size,omitempty"`
+	ProcessingStartedAt *time.Time `db:"processing_started_at" json:"-"`
+	CompletedAt         *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+	ExpiresAt           *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	CreatedAt           time.Time  `db:"created_at" json:"created_at"`
+}
@@ -1,9 +1,11 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const (
@@ -18,15 +20,44 @@ const (
 	ReportTargetReview  = "review"
 )
 
+// Меры, которые модератор применяет к объекту жалобы
+const (
+	ReportActionHideMessage    = "hide_message"
+	ReportActionUnpublishOrder = "unpublish_order"
+	ReportActionRemoveReview   = "remove_review"
+	ReportActionSuspendUser    = "suspend_user"
+)
+
+// ReportActionTargets тип объекта, к которому применима каждая мера.
+var ReportActionTargets = map[string]string{
+	ReportActionHideMessage:    ReportTargetMessage,
+	ReportActionUnpublishOrder: ReportTargetOrder,
+	ReportActionRemoveReview:   ReportTargetReview,
+	ReportActionSuspendUser:    ReportTargetUser,
+}
+
 type Report struct {
-	ID          uuid.UUID  `db:"id" json:"id"`
-	ReporterID  uuid.UUID  `db:"reporter_id" json:"reporter_id"`
-	TargetType  string     `db:"target_type" json:"target_type"`
-	TargetID    uuid.UUID  `db:"target_id" json:"target_id"`
-	Reason      string     `db:"reason" json:"reason"`
-	Description *string    `db:"description" json:"description,omitempty"`
-	Status      string     `db:"status" json:"status"`
-	ReviewedBy  *uuid.UUID `db:"reviewed_by" json:"reviewed_by,omitempty"`
-	ReviewedAt  *time.Time `db:"reviewed_at" json:"reviewed_at,omitempty"`
-	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	ID            uuid.UUID  `db:"id" json:"id"`
+	ReporterID    uuid.UUID  `db:"reporter_id" json:"reporter_id"`
+	TargetType    string     `db:"target_type" json:"target_type"`
+	TargetID      uuid.UUID  `db:"target_id" json:"target_id"`
+	Reason        string     `db:"reason" json:"reason"`
+	Description   *string    `db:"description" json:"description,omitempty"`
+	Status        string     `db:"status" json:"status"`
+	Action        *string    `db:"action" json:"action,omitempty"`
+	ModeratorNote *string    `db:"moderator_note" json:"moderator_note,omitempty"`
+	ReviewedBy    *uuid.UUID `db:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time `db:"reviewed_at" json:"reviewed_at,omitempty"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+}
+
+// ReportTarget — объект, на который поступили жалобы, в очереди модерации.
+type ReportTarget struct {
+	TargetType      string          `db:"target_type" json:"target_type"`
+	TargetID        uuid.UUID       `db:"target_id" json:"target_id"`
+	ReportCount     int             `db:"report_count" json:"report_count"`
+	Reasons         pq.StringArray  `db:"reasons" json:"reasons"`
+	FirstReportedAt time.Time       `db:"first_reported_at" json:"first_reported_at"`
+	LastReportedAt  time.Time       `db:"last_reported_at" json:"last_reported_at"`
+	Preview         json.RawMessage `db:"-" json:"preview"`
 }
@@ -0,0 +1,69 @@
+package models
+
+// Роли пользователей
+const (
+	RoleClient     = "client"
+	RoleFreelancer = "freelancer"
+	RoleModerator  = "moderator"
+	RoleAdmin      = "admin"
+)
+
+// Permission — право на выполнение группы действий.
+type Permission string
+
+// Права доступа
+const (
+	PermOrdersCreate     Permission = "orders.create"
+	PermOrdersHire       Permission = "orders.hire"
+	PermOrdersFindWork   Permission = "orders.find_work"
+	PermProposalsCreate  Permission = "proposals.create"
+	PermReportsModerate  Permission = "reports.moderate"
+	PermDisputesManage   Permission = "disputes.manage"
+	PermUsersManageRoles Permission = "users.manage_roles"
+	PermIdentityReview   Permission = "identity.review"
+)
+
+// SelfAssignableRoles роли, которые пользователь может выбрать сам
+// при регистрации или смене роли.
+var SelfAssignableRoles = map[string]struct{}{
+	RoleClient:     {},
+	RoleFreelancer: {},
+}
+
+// ValidRoles список всех ролей платформы.
+var ValidRoles = map[string]struct{}{
+	RoleClient:     {},
+	RoleFreelancer: {},
+	RoleModerator:  {},
+	RoleAdmin:      {},
+}
+
+// RolePermissions права каждой роли. Администратору доступно всё.
+var RolePermissions = map[string]map[Permission]struct{}{
+	RoleClient: {
+		PermOrdersCreate: {},
+		PermOrdersHire:   {},
+	},
+	RoleFreelancer: {
+		PermOrdersFindWork:  {},
+		PermProposalsCreate: {},
+	},
+	RoleModerator: {
+		PermReportsModerate: {},
+		PermIdentityReview:  {},
+	},
+}
+
+// HasPermission проверяет, есть ли у роли указанное право.
+func HasPermission(role string, perm Permission) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	_, ok := RolePermissions[role][perm]
+	return ok
+}
+
+// IsPrivilegedRole сообщает, что роль выдаётся только администратором.
+func IsPrivilegedRole(role string) bool {
+	return role == RoleAdmin || role == RoleModerator
+}
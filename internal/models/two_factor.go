@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserTOTP — TOTP секрет пользователя. Второй фактор действует после EnabledAt.
+type UserTOTP struct {
+	UserID       uuid.UUID  `db:"user_id" json:"-"`
+	Secret       string     `db:"secret" json:"-"`
+	EnabledAt    *time.Time `db:"enabled_at" json:"enabled_at,omitempty"`
+	LastUsedStep int64      `db:"last_used_step" json:"-"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+}
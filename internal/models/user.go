@@ -4,19 +4,22 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 )
 
 // User описывает сущность пользователя платформы.
 type User struct {
-	ID           uuid.UUID  `db:"id" json:"id"`
-	Email        string     `db:"email" json:"email"`
-	Username     string     `db:"username" json:"username"`
-	PasswordHash string     `db:"password_hash" json:"-"`
-	Role         string     `db:"role" json:"role"`
-	IsActive     bool       `db:"is_active" json:"is_active"`
-	LastLoginAt  *time.Time `db:"last_login_at" json:"last_login_at,omitempty"`
-	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
+	ID               uuid.UUID  `db:"id" json:"id"`
+	Email            string     `db:"email" json:"email"`
+	Username         string     `db:"username" json:"username"`
+	PasswordHash     string     `db:"password_hash" json:"-"`
+	Role             string     `db:"role" json:"role"`
+	IsActive         bool       `db:"is_active" json:"is_active"`
+	IdentityVerified bool       `db:"identity_verified" json:"identity_verified"`
+	LastLoginAt      *time.Time `db:"last_login_at" json:"last_login_at,omitempty"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time  `db:"updated_at" json:"updated_at"`
 }
 
 // Profile описывает публичный профиль пользователя.
@@ -38,15 +41,42 @@ type Profile struct {
 	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
 }
 
-// Session представляет сохранённую сессию пользователя.
+// Session представляет сохранённую сессию пользователя — семейство refresh токенов,
+// выпущенных друг за другом начиная с одного входа.
 type Session struct {
-	ID           uuid.UUID `db:"id" json:"id"`
-	UserID       uuid.UUID `db:"user_id" json:"user_id"`
-	RefreshToken string    `db:"refresh_token" json:"refresh_token"`
-	UserAgent    *string   `db:"user_agent" json:"user_agent,omitempty"`
-	IPAddress    *string   `db:"ip_address" json:"ip_address,omitempty"`
-	ExpiresAt    time.Time `db:"expires_at" json:"expires_at"`
-	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	ID        uuid.UUID `db:"id" json:"id"`
+	UserID    uuid.UUID `db:"user_id" json:"user_id"`
+	UserAgent *string   `db:"user_agent" json:"user_agent,omitempty"`
+	IPAddress *string   `db:"ip_address" json:"ip_address,omitempty"`
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// RefreshToken — хеш refresh токена, выпущенного в рамках сессии.
+type RefreshToken struct {
+	TokenHash string     `db:"token_hash"`
+	SessionID uuid.UUID  `db:"session_id"`
+	UserID    uuid.UUID  `db:"user_id"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	RotatedAt *time.Time `db:"rotated_at"`
+}
+
+// Назначения одноразовых ссылок из писем.
+const (
+	ActionPasswordReset = "password_reset"
+	ActionEmailChange   = "email_change"
+)
+
+// UserActionToken — одноразовая ссылка из письма (сброс пароля, подтверждение нового email).
+type UserActionToken struct {
+	ID        uuid.UUID  `db:"id"`
+	UserID    uuid.UUID  `db:"user_id"`
+	Purpose   string     `db:"purpose"`
+	NewEmail  *string    `db:"new_email"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
 }
 
 // Review описывает отзыв пользователя о другом пользователе после завершения заказа.
@@ -63,26 +93,43 @@ type Review struct {
 
 // PublicProfileStats содержит статистику для публичного профиля.
 type PublicProfileStats struct {
-	TotalOrders      int     `json:"total_orders"`
-	CompletedOrders  int     `json:"completed_orders"`
-	AverageRating    float64 `json:"average_rating"`
-	TotalReviews     int     `json:"total_reviews"`
-	TotalEarnings    float64 `json:"total_earnings,omitempty"`
+	TotalOrders     int     `json:"total_orders"`
+	CompletedOrders int     `json:"completed_orders"`
+	AverageRating   float64 `json:"average_rating"`
+	TotalReviews    int     `json:"total_reviews"`
+	// Заработок в валюте EarningsCurrency (валюта по умолчанию)
+	TotalEarnings    valueobject.Amount `json:"total_earnings,omitempty"`
+	NetEarnings      valueobject.Amount `json:"net_earnings,omitempty"`
+	EarningsCurrency string             `json:"earnings_currency,omitempty"`
+	// Комиссия платформы с выплат; в публичный профиль не попадает
+	PlatformFees valueobject.Amount `json:"-"`
+	// Заработок по каждой валюте выплат
+	Earnings []CurrencyEarnings `json:"earnings,omitempty"`
+}
+
+// CurrencyEarnings заработок пользователя в одной валюте.
+type CurrencyEarnings struct {
+	Currency      string             `db:"currency" json:"currency"`
+	TotalEarnings valueobject.Amount `db:"gross" json:"total_earnings"`
+	NetEarnings   valueobject.Amount `db:"-" json:"net_earnings"`
+	// Комиссия платформы с выплат; в публичный профиль не попадает
+	PlatformFees valueobject.Amount `db:"fees" json:"-"`
 }
 
 
 // FreelancerSearchResult результат поиска фрилансера.
 type FreelancerSearchResult struct {
-	ID              uuid.UUID  `json:"id"`
-	Username        string     `json:"username"`
-	DisplayName     *string    `json:"display_name,omitempty"`
-	Bio             *string    `json:"bio,omitempty"`
-	HourlyRate      *float64   `json:"hourly_rate,omitempty"`
-	ExperienceLevel *string    `json:"experience_level,omitempty"`
-	Skills          []string   `json:"skills,omitempty"`
-	Location        *string    `json:"location,omitempty"`
-	PhotoID         *uuid.UUID `json:"photo_id,omitempty"`
-	AvgRating       float64    `json:"avg_rating"`
-	ReviewCount     int        `json:"review_count"`
-	CreatedAt       time.Time  `json:"created_at"`
+	ID               uuid.UUID  `json:"id"`
+	Username         string     `json:"username"`
+	DisplayName      *string    `json:"display_name,omitempty"`
+	Bio              *string    `json:"bio,omitempty"`
+	HourlyRate       *float64   `json:"hourly_rate,omitempty"`
+	ExperienceLevel  *string    `json:"experience_level,omitempty"`
+	Skills           []string   `json:"skills,omitempty"`
+	Location         *string    `json:"location,omitempty"`
+	PhotoID          *uuid.UUID `json:"photo_id,omitempty"`
+	AvgRating        float64    `json:"avg_rating"`
+	ReviewCount      int        `json:"review_count"`
+	IdentityVerified bool       `json:"identity_verified"`
+	CreatedAt        time.Time  `json:"created_at"`
 }
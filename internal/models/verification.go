@@ -11,12 +11,21 @@ const (
 	VerificationTypePhone = "phone"
 )
 
+// Каналы доставки кода подтверждения телефона.
+const (
+	PhoneChannelSMS      = "sms"
+	PhoneChannelTelegram = "telegram"
+)
+
 type VerificationCode struct {
-	ID        uuid.UUID `db:"id" json:"id"`
-	UserID    uuid.UUID `db:"user_id" json:"user_id"`
-	Type      string    `db:"type" json:"type"`
-	Code      string    `db:"code" json:"-"`
-	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
-	Used      bool      `db:"used" json:"used"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	ID          uuid.UUID `db:"id" json:"id"`
+	UserID      uuid.UUID `db:"user_id" json:"user_id"`
+	Type        string    `db:"type" json:"type"`
+	Code        string    `db:"code" json:"-"`
+	Destination *string   `db:"destination" json:"-"`
+	Channel     *string   `db:"channel" json:"channel,omitempty"`
+	Attempts    int       `db:"attempts" json:"-"`
+	ExpiresAt   time.Time `db:"expires_at" json:"expires_at"`
+	Used        bool      `db:"used" json:"used"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
 }
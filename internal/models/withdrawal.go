@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 )
 
 const (
@@ -14,13 +16,17 @@ const (
 )
 
 type Withdrawal struct {
-	ID              uuid.UUID  `db:"id" json:"id"`
-	UserID          uuid.UUID  `db:"user_id" json:"user_id"`
-	Amount          float64    `db:"amount" json:"amount"`
-	Status          string     `db:"status" json:"status"`
-	CardLast4       *string    `db:"card_last4" json:"card_last4,omitempty"`
-	BankName        *string    `db:"bank_name" json:"bank_name,omitempty"`
-	RejectionReason *string    `db:"rejection_reason" json:"rejection_reason,omitempty"`
-	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
-	ProcessedAt     *time.Time `db:"processed_at" json:"processed_at,omitempty"`
+	ID              uuid.UUID          `db:"id" json:"id"`
+	UserID          uuid.UUID          `db:"user_id" json:"user_id"`
+	Amount          valueobject.Amount `db:"amount" json:"amount"`
+	Currency        string             `db:"currency" json:"currency"`
+	Status          string             `db:"status" json:"status"`
+	CardLast4       *string            `db:"card_last4" json:"card_last4,omitempty"`
+	BankName        *string            `db:"bank_name" json:"bank_name,omitempty"`
+	RejectionReason *string            `db:"rejection_reason" json:"rejection_reason,omitempty"`
+	TransactionID   *uuid.UUID         `db:"transaction_id" json:"transaction_id,omitempty"`
+	PayoutReference *string            `db:"payout_reference" json:"payout_reference,omitempty"`
+	CreatedAt       time.Time          `db:"created_at" json:"created_at"`
+	ProcessingAt    *time.Time         `db:"processing_started_at" json:"processing_started_at,omitempty"`
+	ProcessedAt     *time.Time         `db:"processed_at" json:"processed_at,omitempty"`
 }
@@ -9,10 +9,14 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 )
 
-var ErrDisputeNotFound = errors.New("dispute not found")
+var (
+	ErrDisputeNotFound = errors.New("dispute not found")
+	ErrDisputeClosed   = errors.New("dispute is already resolved or cancelled")
+)
 
 type DisputeRepository struct {
 	db *sqlx.DB
@@ -24,14 +28,55 @@ func NewDisputeRepository(db *sqlx.DB) *DisputeRepository {
 
 func (r *DisputeRepository) Create(ctx context.Context, d *models.Dispute) error {
 	query := `
-		INSERT INTO disputes (escrow_id, order_id, initiator_id, reason, status)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO disputes (escrow_id, order_id, milestone_id, initiator_id, reason, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at
 	`
-	return r.db.QueryRowContext(ctx, query, d.EscrowID, d.OrderID, d.InitiatorID, d.Reason, d.Status).
+	return r.db.QueryRowContext(ctx, query, d.EscrowID, d.OrderID, d.MilestoneID, d.InitiatorID, d.Reason, d.Status).
 		Scan(&d.ID, &d.CreatedAt)
 }
 
+// CreateForMilestone открывает спор по этапу и в той же транзакции замораживает
+// escrow и сам этап. Остальные этапы заказа продолжают работать как обычно.
+func (r *DisputeRepository) CreateForMilestone(ctx context.Context, d *models.Dispute) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE escrow SET status = 'disputed' WHERE id = $1 AND milestone_id = $2 AND status = 'held'
+	`, d.EscrowID, d.MilestoneID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrEscrowNotFound
+	}
+
+	result, err = tx.ExecContext(ctx, `
+		UPDATE order_milestones SET status = 'disputed' WHERE id = $1 AND status IN ('funded', 'submitted')
+	`, d.MilestoneID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrMilestoneInvalidState
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO disputes (escrow_id, order_id, milestone_id, initiator_id, reason, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, d.EscrowID, d.OrderID, d.MilestoneID, d.InitiatorID, d.Reason, d.Status).Scan(&d.ID, &d.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (r *DisputeRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Dispute, error) {
 	var d models.Dispute
 	err := r.db.GetContext(ctx, &d, `SELECT * FROM disputes WHERE id = $1`, id)
@@ -43,7 +88,17 @@ func (r *DisputeRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 
 func (r *DisputeRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) (*models.Dispute, error) {
 	var d models.Dispute
-	err := r.db.GetContext(ctx, &d, `SELECT * FROM disputes WHERE order_id = $1`, orderID)
+	err := r.db.GetContext(ctx, &d, `SELECT * FROM disputes WHERE order_id = $1 AND milestone_id IS NULL`, orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDisputeNotFound
+	}
+	return &d, err
+}
+
+// GetByMilestoneID возвращает спор по этапу заказа.
+func (r *DisputeRepository) GetByMilestoneID(ctx context.Context, milestoneID uuid.UUID) (*models.Dispute, error) {
+	var d models.Dispute
+	err := r.db.GetContext(ctx, &d, `SELECT * FROM disputes WHERE milestone_id = $1`, milestoneID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrDisputeNotFound
 	}
@@ -68,3 +123,100 @@ func (r *DisputeRepository) ListByUser(ctx context.Context, userID uuid.UUID, li
 	`, userID, limit, offset)
 	return disputes, err
 }
+
+// ListAll возвращает споры для администратора, при необходимости фильтруя по статусу.
+func (r *DisputeRepository) ListAll(ctx context.Context, status string, limit, offset int) ([]models.Dispute, error) {
+	disputes := []models.Dispute{}
+	err := r.db.SelectContext(ctx, &disputes, `
+		SELECT * FROM disputes
+		WHERE ($1 = '' OR status::text = $1)
+		ORDER BY created_at ASC LIMIT $2 OFFSET $3
+	`, status, limit, offset)
+	return disputes, err
+}
+
+// Assign назначает администратора на спор и переводит его на рассмотрение.
+func (r *DisputeRepository) Assign(ctx context.Context, id, adminID uuid.UUID) (*models.Dispute, error) {
+	var d models.Dispute
+	err := r.db.GetContext(ctx, &d, `
+		UPDATE disputes SET status = 'under_review', assigned_to = $2, assigned_at = NOW()
+		WHERE id = $1 AND status IN ('open', 'under_review')
+		RETURNING *
+	`, id, adminID)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, getErr := r.GetByID(ctx, id); getErr != nil {
+			return nil, getErr
+		}
+		return nil, ErrDisputeClosed
+	}
+	return &d, err
+}
+
+// MarkResolved возвращает шаг закрытия спора для выполнения в транзакции выплат.
+func (r *DisputeRepository) MarkResolved(id uuid.UUID, status, resolution string, resolvedBy uuid.UUID, clientPercent float64, clientAmount, freelancerAmount valueobject.Amount) func(ctx context.Context, tx *sqlx.Tx) error {
+	return func(ctx context.Context, tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE disputes SET status = $2, resolution = $3, resolved_by = $4, resolved_at = NOW(),
+				client_percent = $5, client_amount = $6, freelancer_amount = $7
+			WHERE id = $1 AND status IN ('open', 'under_review')
+		`, id, status, resolution, resolvedBy, clientPercent, clientAmount, freelancerAmount)
+		if err != nil {
+			return err
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return ErrDisputeClosed
+		}
+		return nil
+	}
+}
+
+// AddEvidence сохраняет доказательство по спору.
+func (r *DisputeRepository) AddEvidence(ctx context.Context, e *models.DisputeEvidence) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO dispute_evidence (dispute_id, author_id, kind, content, message_id, media_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, e.DisputeID, e.AuthorID, e.Kind, e.Content, e.MessageID, e.MediaID).Scan(&e.ID, &e.CreatedAt)
+}
+
+// ListEvidence возвращает доказательства по спору в хронологическом порядке.
+func (r *DisputeRepository) ListEvidence(ctx context.Context, disputeID uuid.UUID) ([]models.DisputeEvidence, error) {
+	evidence := []models.DisputeEvidence{}
+	err := r.db.SelectContext(ctx, &evidence, `
+		SELECT * FROM dispute_evidence WHERE dispute_id = $1 ORDER BY created_at ASC
+	`, disputeID)
+	return evidence, err
+}
+
+// ListOrderMessages возвращает переписку заказчика и фрилансера по заказу.
+func (r *DisputeRepository) ListOrderMessages(ctx context.Context, orderID, clientID, freelancerID uuid.UUID) ([]models.Message, error) {
+	messages := []models.Message{}
+	err := r.db.SelectContext(ctx, &messages, `
+		SELECT m.* FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE c.order_id = $1 AND c.client_id = $2 AND c.freelancer_id = $3
+		ORDER BY m.created_at ASC
+	`, orderID, clientID, freelancerID)
+	return messages, err
+}
+
+// MessageInOrderChat проверяет, что сообщение относится к чату по заказу.
+func (r *DisputeRepository) MessageInOrderChat(ctx context.Context, messageID, orderID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, `
+		SELECT EXISTS (
+			SELECT 1 FROM messages m JOIN conversations c ON c.id = m.conversation_id
+			WHERE m.id = $1 AND c.order_id = $2
+		)
+	`, messageID, orderID)
+	return exists, err
+}
+
+// MediaOwnedBy проверяет, что файл загружен указанным пользователем.
+func (r *DisputeRepository) MediaOwnedBy(ctx context.Context, mediaID, userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, `
+		SELECT EXISTS (SELECT 1 FROM media_files WHERE id = $1 AND user_id = $2)
+	`, mediaID, userID)
+	return exists, err
+}
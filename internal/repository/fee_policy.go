@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+// loadFeePolicy читает настройки комиссии платформы для выплаты в валюте.
+// Минимальная комиссия и пороги заработка берутся только для этой валюты.
+func loadFeePolicy(ctx context.Context, tx *sqlx.Tx, currency string) (*models.FeePolicy, error) {
+	policy := &models.FeePolicy{CategoryPercents: map[uuid.UUID]float64{}}
+
+	err := tx.GetContext(ctx, &policy.DefaultPercent, `SELECT default_percent FROM platform_fee_settings`)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("fee policy: settings %w", err)
+	}
+
+	err = tx.GetContext(ctx, &policy.MinFee, `SELECT min_fee FROM platform_fee_minimums WHERE currency = $1`, currency)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("fee policy: minimum %w", err)
+	}
+
+	var categories []struct {
+		CategoryID uuid.UUID `db:"category_id"`
+		Percent    float64   `db:"percent"`
+	}
+	if err := tx.SelectContext(ctx, &categories, `SELECT category_id, percent FROM platform_fee_categories`); err != nil {
+		return nil, fmt.Errorf("fee policy: categories %w", err)
+	}
+	for _, c := range categories {
+		policy.CategoryPercents[c.CategoryID] = c.Percent
+	}
+
+	if err := tx.SelectContext(ctx, &policy.Tiers, `SELECT min_earnings, percent FROM platform_fee_tiers WHERE currency = $1`, currency); err != nil {
+		return nil, fmt.Errorf("fee policy: tiers %w", err)
+	}
+	return policy, nil
+}
+
+// freelancerFee рассчитывает комиссию с выплаты фрилансеру по escrow с учётом
+// категории заказа и заработка фрилансера в валюте escrow до этой выплаты.
+func freelancerFee(ctx context.Context, tx *sqlx.Tx, escrow *models.Escrow, amount valueobject.Amount) (valueobject.Amount, error) {
+	policy, err := loadFeePolicy(ctx, tx, escrow.Currency)
+	if err != nil {
+		return 0, err
+	}
+
+	var categoryID *uuid.UUID
+	if err := tx.GetContext(ctx, &categoryID, `SELECT category_id FROM orders WHERE id = $1`, escrow.OrderID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("fee policy: order category %w", err)
+	}
+
+	var earnings valueobject.Amount
+	err = tx.GetContext(ctx, &earnings, `
+		SELECT COALESCE(SUM(amount), 0) FROM transactions
+		WHERE user_id = $1 AND currency = $2 AND type = 'escrow_release' AND status = 'completed'
+	`, escrow.FreelancerID, escrow.Currency)
+	if err != nil {
+		return 0, fmt.Errorf("fee policy: lifetime earnings %w", err)
+	}
+
+	return policy.Fee(amount, categoryID, earnings), nil
+}
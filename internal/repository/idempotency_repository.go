@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+const (
+	// idempotencyKeyTTL срок, в течение которого повтор с тем же ключом возвращает сохранённый ответ.
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyStaleAfter срок, после которого незавершённый запрос (например, после падения
+	// сервера) перестаёт блокировать ключ.
+	idempotencyStaleAfter = 5 * time.Minute
+)
+
+type IdempotencyRepository struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve занимает ключ под запрос с хешем requestHash. Если ключ уже занят, возвращает
+// существующую запись и created = false; просроченные записи перезаписываются.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, userID uuid.UUID, key, requestHash string) (*models.IdempotencyKey, bool, error) {
+	// Вторая попытка нужна, если занявший ключ запрос успел освободить его между INSERT и SELECT
+	for attempt := 0; attempt < 2; attempt++ {
+		var record models.IdempotencyKey
+		err := r.db.GetContext(ctx, &record, `
+			INSERT INTO idempotency_keys (user_id, key, request_hash)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, key) DO UPDATE SET
+				request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = NULL,
+				response_body = NULL, created_at = NOW(), completed_at = NULL
+			WHERE idempotency_keys.created_at < NOW() - make_interval(secs => $4)
+				OR (idempotency_keys.completed_at IS NULL AND idempotency_keys.created_at < NOW() - make_interval(secs => $5))
+			RETURNING *
+		`, userID, key, requestHash, idempotencyKeyTTL.Seconds(), idempotencyStaleAfter.Seconds())
+		if err == nil {
+			return &record, true, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, false, fmt.Errorf("idempotency repository: reserve %w", err)
+		}
+
+		err = r.db.GetContext(ctx, &record, `SELECT * FROM idempotency_keys WHERE user_id = $1 AND key = $2`, userID, key)
+		if err == nil {
+			return &record, false, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, false, fmt.Errorf("idempotency repository: get %w", err)
+		}
+	}
+	return nil, false, fmt.Errorf("idempotency repository: reserve %s: key is contended", key)
+}
+
+// Complete сохраняет ответ на запрос, занявший ключ.
+func (r *IdempotencyRepository) Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, body []byte) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, response_body = $5, completed_at = NOW()
+		WHERE user_id = $1 AND key = $2 AND completed_at IS NULL
+	`, userID, key, statusCode, contentType, body)
+	if err != nil {
+		return fmt.Errorf("idempotency repository: complete %w", err)
+	}
+	return nil
+}
+
+// Release освобождает ключ незавершённого запроса, чтобы клиент мог его повторить.
+func (r *IdempotencyRepository) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND completed_at IS NULL
+	`, userID, key)
+	if err != nil {
+		return fmt.Errorf("idempotency repository: release %w", err)
+	}
+	return nil
+}
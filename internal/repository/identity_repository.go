@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+var (
+	// ErrIdentityNotFound возвращается, если учётная запись провайдера не привязана ни к одному пользователю.
+	ErrIdentityNotFound = errors.New("identity not found")
+	// ErrIdentityLinked возвращается, если учётная запись провайдера уже привязана.
+	ErrIdentityLinked = errors.New("identity already linked")
+	// ErrLoginStateNotFound возвращается для неизвестного, истёкшего или уже использованного state.
+	ErrLoginStateNotFound = errors.New("login state not found")
+)
+
+// IdentityRepository отвечает за таблицы user_identities и oidc_login_states.
+type IdentityRepository struct {
+	db *sqlx.DB
+}
+
+// NewIdentityRepository создаёт экземпляр репозитория.
+func NewIdentityRepository(db *sqlx.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// CreateLoginState сохраняет начатый вход и попутно удаляет истёкшие.
+func (r *IdentityRepository) CreateLoginState(ctx context.Context, state *models.OIDCLoginState) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at < NOW()`); err != nil {
+		return fmt.Errorf("identity repository: cleanup login states %w", err)
+	}
+
+	if err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO oidc_login_states (state_hash, provider, code_verifier, nonce, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`, state.StateHash, state.Provider, state.CodeVerifier, state.Nonce, state.ExpiresAt).Scan(&state.CreatedAt); err != nil {
+		return fmt.Errorf("identity repository: create login state %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeLoginState удаляет начатый вход и возвращает его, если он ещё не истёк.
+// Повторный возврат с тем же state получает ErrLoginStateNotFound.
+func (r *IdentityRepository) ConsumeLoginState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	var state models.OIDCLoginState
+	err := r.db.GetContext(ctx, &state, `
+		DELETE FROM oidc_login_states
+		WHERE state_hash = $1 AND expires_at > NOW()
+		RETURNING state_hash, provider, code_verifier, nonce, expires_at, created_at
+	`, stateHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLoginStateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("identity repository: consume login state %w", err)
+	}
+
+	return &state, nil
+}
+
+// GetIdentity возвращает привязку учётной записи провайдера.
+func (r *IdentityRepository) GetIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.GetContext(ctx, &identity, `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`, provider, subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("identity repository: get identity %w", err)
+	}
+
+	return &identity, nil
+}
+
+// LinkIdentity привязывает учётную запись провайдера к пользователю.
+func (r *IdentityRepository) LinkIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	if err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO user_identities (user_id, provider, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id, created_at, last_login_at
+	`, identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(
+		&identity.ID, &identity.CreatedAt, &identity.LastLoginAt,
+	); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrIdentityLinked
+		}
+		return fmt.Errorf("identity repository: link identity %w", err)
+	}
+
+	return nil
+}
+
+// TouchIdentity запоминает время входа и email, который сообщил провайдер.
+func (r *IdentityRepository) TouchIdentity(ctx context.Context, id uuid.UUID, email *string) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE user_identities SET last_login_at = NOW(), email = COALESCE($2, email) WHERE id = $1
+	`, id, email); err != nil {
+		return fmt.Errorf("identity repository: touch identity %w", err)
+	}
+
+	return nil
+}
+
+// ListIdentities возвращает привязанные к пользователю учётные записи провайдеров.
+func (r *IdentityRepository) ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	identities := []models.UserIdentity{}
+	if err := r.db.SelectContext(ctx, &identities, `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID); err != nil {
+		return nil, fmt.Errorf("identity repository: list identities %w", err)
+	}
+
+	return identities, nil
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+var (
+	// ErrKYCSubmissionNotFound возвращается для неизвестной заявки или пользователя без заявок.
+	ErrKYCSubmissionNotFound = errors.New("kyc submission not found")
+	// ErrKYCSubmissionPending возвращается, если у пользователя уже есть заявка на рассмотрении.
+	ErrKYCSubmissionPending = errors.New("kyc submission already pending")
+	// ErrKYCSubmissionReviewed возвращается при повторном решении по заявке.
+	ErrKYCSubmissionReviewed = errors.New("kyc submission already reviewed")
+)
+
+const kycSubmissionColumns = `id, user_id, document_type, status, rejection_reason, reviewed_by, reviewed_at, created_at`
+
+// KYCRepository отвечает за таблицы kyc_submissions и kyc_documents.
+type KYCRepository struct {
+	db *sqlx.DB
+}
+
+// NewKYCRepository создаёт экземпляр репозитория.
+func NewKYCRepository(db *sqlx.DB) *KYCRepository {
+	return &KYCRepository{db: db}
+}
+
+// CreateSubmission сохраняет заявку вместе с фотографиями документа.
+func (r *KYCRepository) CreateSubmission(ctx context.Context, submission *models.KYCSubmission) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("kyc repository: begin %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowxContext(ctx, `
+		INSERT INTO kyc_submissions (user_id, document_type)
+		VALUES ($1, $2)
+		RETURNING `+kycSubmissionColumns,
+		submission.UserID, submission.DocumentType,
+	).StructScan(submission); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrKYCSubmissionPending
+		}
+		return fmt.Errorf("kyc repository: create submission %w", err)
+	}
+
+	for _, doc := range submission.Documents {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO kyc_documents (submission_id, kind, media_id) VALUES ($1, $2, $3)
+		`, submission.ID, doc.Kind, doc.MediaID); err != nil {
+			return fmt.Errorf("kyc repository: attach document %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("kyc repository: commit %w", err)
+	}
+	return nil
+}
+
+// GetSubmission возвращает заявку с фотографиями документа.
+func (r *KYCRepository) GetSubmission(ctx context.Context, id uuid.UUID) (*models.KYCSubmission, error) {
+	var submission models.KYCSubmission
+	err := r.db.GetContext(ctx, &submission, `SELECT `+kycSubmissionColumns+` FROM kyc_submissions WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrKYCSubmissionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kyc repository: get submission %w", err)
+	}
+
+	if err := r.loadDocuments(ctx, &submission); err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// GetLatestSubmission возвращает последнюю заявку пользователя.
+func (r *KYCRepository) GetLatestSubmission(ctx context.Context, userID uuid.UUID) (*models.KYCSubmission, error) {
+	var submission models.KYCSubmission
+	err := r.db.GetContext(ctx, &submission, `
+		SELECT `+kycSubmissionColumns+` FROM kyc_submissions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrKYCSubmissionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kyc repository: get latest submission %w", err)
+	}
+
+	if err := r.loadDocuments(ctx, &submission); err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// ListSubmissions возвращает заявки для модераторов, старые первыми; пустой status — все заявки.
+func (r *KYCRepository) ListSubmissions(ctx context.Context, status string, limit, offset int) ([]models.KYCSubmission, error) {
+	submissions := []models.KYCSubmission{}
+	if err := r.db.SelectContext(ctx, &submissions, `
+		SELECT `+kycSubmissionColumns+` FROM kyc_submissions
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at ASC
+		LIMIT $2 OFFSET $3
+	`, status, limit, offset); err != nil {
+		return nil, fmt.Errorf("kyc repository: list submissions %w", err)
+	}
+
+	for i := range submissions {
+		if err := r.loadDocuments(ctx, &submissions[i]); err != nil {
+			return nil, err
+		}
+	}
+	return submissions, nil
+}
+
+// ReviewSubmission записывает решение модератора по заявке на рассмотрении. При одобрении
+// в той же транзакции пользователь отмечается как прошедший проверку личности.
+func (r *KYCRepository) ReviewSubmission(ctx context.Context, id, reviewerID uuid.UUID, status string, reason *string) (*models.KYCSubmission, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("kyc repository: begin %w", err)
+	}
+	defer tx.Rollback()
+
+	var submission models.KYCSubmission
+	err = tx.GetContext(ctx, &submission, `
+		UPDATE kyc_submissions
+		SET status = $2, rejection_reason = $3, reviewed_by = $4, reviewed_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING `+kycSubmissionColumns,
+		id, status, reason, reviewerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, getErr := r.GetSubmission(ctx, id); getErr != nil {
+			return nil, getErr
+		}
+		return nil, ErrKYCSubmissionReviewed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kyc repository: review submission %w", err)
+	}
+
+	if status == models.KYCStatusApproved {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE users SET identity_verified = TRUE, updated_at = NOW() WHERE id = $1
+		`, submission.UserID); err != nil {
+			return nil, fmt.Errorf("kyc repository: mark identity verified %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("kyc repository: commit %w", err)
+	}
+
+	if err := r.loadDocuments(ctx, &submission); err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// IsIdentityVerified сообщает, прошёл ли пользователь проверку личности.
+func (r *KYCRepository) IsIdentityVerified(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var verified bool
+	if err := r.db.GetContext(ctx, &verified, `SELECT identity_verified FROM users WHERE id = $1`, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrUserNotFound
+		}
+		return false, fmt.Errorf("kyc repository: is identity verified %w", err)
+	}
+	return verified, nil
+}
+
+func (r *KYCRepository) loadDocuments(ctx context.Context, submission *models.KYCSubmission) error {
+	submission.Documents = []models.KYCDocument{}
+	if err := r.db.SelectContext(ctx, &submission.Documents, `
+		SELECT kind, media_id FROM kyc_documents WHERE submission_id = $1 ORDER BY kind
+	`, submission.ID); err != nil {
+		return fmt.Errorf("kyc repository: load documents %w", err)
+	}
+	return nil
+}
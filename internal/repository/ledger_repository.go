@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+var ErrLedgerUnbalanced = errors.New("ledger journal is unbalanced")
+
+// ledgerJournal описывает хозяйственную операцию, к которой относятся проводки.
+// Все проводки журнала ведутся в его валюте.
+type ledgerJournal struct {
+	Kind         string
+	Currency     string
+	OrderID      *uuid.UUID
+	EscrowID     *uuid.UUID
+	WithdrawalID *uuid.UUID
+	Description  string
+}
+
+// ledgerLeg — одна проводка: изменение баланса счёта.
+// Для счетов пользователя задан userID, для счетов платформы он nil.
+type ledgerLeg struct {
+	Account string
+	UserID  *uuid.UUID
+	Amount  valueobject.Amount
+}
+
+func userLeg(account string, userID uuid.UUID, amount valueobject.Amount) ledgerLeg {
+	return ledgerLeg{Account: account, UserID: &userID, Amount: amount}
+}
+
+func platformLeg(account string, amount valueobject.Amount) ledgerLeg {
+	return ledgerLeg{Account: account, Amount: amount}
+}
+
+// postLedger записывает журнал и его проводки в рамках транзакции.
+// Сумма проводок должна быть нулевой.
+func postLedger(ctx context.Context, tx *sqlx.Tx, journal ledgerJournal, legs ...ledgerLeg) error {
+	var total valueobject.Amount
+	for _, leg := range legs {
+		total += leg.Amount
+	}
+	if total != 0 {
+		return fmt.Errorf("%w: %s off by %s", ErrLedgerUnbalanced, journal.Kind, total)
+	}
+
+	var journalID uuid.UUID
+	err := tx.GetContext(ctx, &journalID, `
+		INSERT INTO ledger_journals (kind, order_id, escrow_id, withdrawal_id, description)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, journal.Kind, journal.OrderID, journal.EscrowID, journal.WithdrawalID, journal.Description)
+	if err != nil {
+		return fmt.Errorf("ledger: insert journal %w", err)
+	}
+
+	for _, leg := range legs {
+		if leg.Amount == 0 {
+			continue
+		}
+		accountID, err := ledgerAccountID(ctx, tx, leg.Account, leg.UserID, journal.Currency)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO ledger_entries (journal_id, account_id, amount) VALUES ($1, $2, $3)
+		`, journalID, accountID, leg.Amount)
+		if err != nil {
+			return fmt.Errorf("ledger: insert entry %w", err)
+		}
+	}
+	return nil
+}
+
+// ledgerAccountID возвращает счёт платформы или пользователя в валюте, создавая его при первой проводке.
+func ledgerAccountID(ctx context.Context, tx *sqlx.Tx, account string, userID *uuid.UUID, currency string) (uuid.UUID, error) {
+	var id uuid.UUID
+	var err error
+	if userID == nil {
+		err = tx.GetContext(ctx, &id, `
+			INSERT INTO ledger_accounts (kind, currency) VALUES ($1, $2)
+			ON CONFLICT (kind, currency) WHERE user_id IS NULL DO UPDATE SET kind = EXCLUDED.kind
+			RETURNING id
+		`, account, currency)
+	} else {
+		err = tx.GetContext(ctx, &id, `
+			INSERT INTO ledger_accounts (user_id, kind, currency) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, kind, currency) WHERE user_id IS NOT NULL DO UPDATE SET kind = EXCLUDED.kind
+			RETURNING id
+		`, *userID, account, currency)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("ledger: account %s %s %w", account, currency, err)
+	}
+	return id, nil
+}
+
+// LedgerRepository сверяет журнал двойной записи с балансами пользователей.
+type LedgerRepository struct {
+	db *sqlx.DB
+}
+
+func NewLedgerRepository(db *sqlx.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// Reconcile выводит балансы из журнала и сравнивает их с user_balances и escrow в каждой валюте.
+// Замороженные средства пользователя = счёт user_frozen + его незакрытые escrow (счёт platform_escrow).
+func (r *LedgerRepository) Reconcile(ctx context.Context) (*models.LedgerReconciliation, error) {
+	result := &models.LedgerReconciliation{
+		Mismatches:         []models.LedgerMismatch{},
+		EscrowMismatches:   []models.LedgerEscrowMismatch{},
+		UnbalancedJournals: []uuid.UUID{},
+	}
+
+	err := r.db.SelectContext(ctx, &result.Mismatches, `
+		WITH ledger AS (
+			SELECT a.user_id, a.currency,
+				COALESCE(SUM(e.amount) FILTER (WHERE a.kind = 'user_available'), 0) AS available,
+				COALESCE(SUM(e.amount) FILTER (WHERE a.kind = 'user_frozen'), 0) AS frozen
+			FROM ledger_accounts a JOIN ledger_entries e ON e.account_id = a.id
+			WHERE a.user_id IS NOT NULL
+			GROUP BY a.user_id, a.currency
+		), held AS (
+			SELECT client_id AS user_id, currency, SUM(amount) AS amount
+			FROM escrow WHERE status IN ('held', 'disputed')
+			GROUP BY client_id, currency
+		), derived AS (
+			SELECT COALESCE(b.user_id, l.user_id) AS user_id,
+				COALESCE(b.currency, l.currency) AS currency,
+				COALESCE(b.available, 0) AS available,
+				COALESCE(b.frozen, 0) AS frozen,
+				COALESCE(l.available, 0) AS ledger_available,
+				COALESCE(l.frozen, 0) + COALESCE(h.amount, 0) AS ledger_frozen
+			FROM user_balances b
+			FULL JOIN ledger l ON l.user_id = b.user_id AND l.currency = b.currency
+			LEFT JOIN held h ON h.user_id = COALESCE(b.user_id, l.user_id) AND h.currency = COALESCE(b.currency, l.currency)
+		)
+		SELECT * FROM derived
+		WHERE available <> ledger_available OR frozen <> ledger_frozen
+		ORDER BY user_id, currency
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("ledger repository: reconcile balances %w", err)
+	}
+
+	err = r.db.SelectContext(ctx, &result.EscrowMismatches, `
+		WITH held AS (
+			SELECT currency, SUM(amount) AS amount FROM escrow
+			WHERE status IN ('held', 'disputed')
+			GROUP BY currency
+		), ledger AS (
+			SELECT a.currency, SUM(e.amount) AS amount
+			FROM ledger_entries e JOIN ledger_accounts a ON a.id = e.account_id
+			WHERE a.kind = 'platform_escrow' AND a.user_id IS NULL
+			GROUP BY a.currency
+		)
+		SELECT COALESCE(h.currency, l.currency) AS currency,
+			COALESCE(h.amount, 0) AS held,
+			COALESCE(l.amount, 0) AS ledger
+		FROM held h FULL JOIN ledger l ON l.currency = h.currency
+		WHERE COALESCE(h.amount, 0) <> COALESCE(l.amount, 0)
+		ORDER BY currency
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("ledger repository: reconcile escrow %w", err)
+	}
+
+	err = r.db.SelectContext(ctx, &result.UnbalancedJournals, `
+		SELECT DISTINCT e.journal_id
+		FROM ledger_entries e JOIN ledger_accounts a ON a.id = e.account_id
+		GROUP BY e.journal_id, a.currency
+		HAVING SUM(e.amount) <> 0
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("ledger repository: reconcile journals %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+// loginAttemptRetention — сколько хранится журнал попыток входа.
+const loginAttemptRetention = 30 * 24 * time.Hour
+
+// LoginAttemptRepository отвечает за журнал попыток входа login_attempts.
+type LoginAttemptRepository struct {
+	db *sqlx.DB
+}
+
+// NewLoginAttemptRepository создаёт экземпляр репозитория.
+func NewLoginAttemptRepository(db *sqlx.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// RecordLoginAttempt сохраняет попытку входа и удаляет записи старше срока хранения.
+func (r *LoginAttemptRepository) RecordLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	if err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO login_attempts (email, user_id, ip_address, user_agent, success)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, attempt.Email, attempt.UserID, attempt.IPAddress, attempt.UserAgent, attempt.Success).Scan(&attempt.ID, &attempt.CreatedAt); err != nil {
+		return fmt.Errorf("login attempt repository: record %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM login_attempts WHERE created_at < $1
+	`, time.Now().Add(-loginAttemptRetention)); err != nil {
+		return fmt.Errorf("login attempt repository: cleanup %w", err)
+	}
+
+	return nil
+}
+
+// CountAccountFailures возвращает число неудачных попыток входа в email после since и после
+// последнего успешного входа, а также время последней из них.
+func (r *LoginAttemptRepository) CountAccountFailures(ctx context.Context, email string, since time.Time) (int, *time.Time, error) {
+	var stats struct {
+		Count int        `db:"count"`
+		Last  *time.Time `db:"last"`
+	}
+	if err := r.db.GetContext(ctx, &stats, `
+		SELECT COUNT(*) AS count, MAX(created_at) AS last
+		FROM login_attempts
+		WHERE email = $1 AND NOT success AND created_at > $2
+		  AND created_at > COALESCE(
+		      (SELECT MAX(created_at) FROM login_attempts WHERE email = $1 AND success), '-infinity'
+		  )
+	`, email, since); err != nil {
+		return 0, nil, fmt.Errorf("login attempt repository: count account failures %w", err)
+	}
+
+	return stats.Count, stats.Last, nil
+}
+
+// CountIPFailures возвращает число неудачных попыток входа с IP после since и время последней из них.
+func (r *LoginAttemptRepository) CountIPFailures(ctx context.Context, ip string, since time.Time) (int, *time.Time, error) {
+	var stats struct {
+		Count int        `db:"count"`
+		Last  *time.Time `db:"last"`
+	}
+	if err := r.db.GetContext(ctx, &stats, `
+		SELECT COUNT(*) AS count, MAX(created_at) AS last
+		FROM login_attempts
+		WHERE ip_address = $1::inet AND NOT success AND created_at > $2
+	`, ip, since); err != nil {
+		return 0, nil, fmt.Errorf("login attempt repository: count ip failures %w", err)
+	}
+
+	return stats.Count, stats.Last, nil
+}
+
+// HasSessionFrom сообщает, есть ли у пользователя сессия с тем же User-Agent и IP.
+func (r *LoginAttemptRepository) HasSessionFrom(ctx context.Context, userID uuid.UUID, userAgent, ip string) (bool, error) {
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, `
+		SELECT EXISTS (
+			SELECT 1 FROM user_sessions
+			WHERE user_id = $1 AND user_agent = $2 AND ip_address = $3::inet
+		)
+	`, userID, userAgent, ip); err != nil {
+		return false, fmt.Errorf("login attempt repository: has session from %w", err)
+	}
+
+	return exists, nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
 	"github.com/google/uuid"
 
@@ -23,8 +24,12 @@ func NewMediaRepository(db *sqlx.DB) *MediaRepository {
 	return &MediaRepository{db: db}
 }
 
-// ErrMediaNotFound сигнализирует об отсутствии файла.
-var ErrMediaNotFound = errors.New("media not found")
+var (
+	// ErrMediaNotFound сигнализирует об отсутствии файла.
+	ErrMediaNotFound = errors.New("media not found")
+	// ErrMediaInUse возвращается при удалении файла, приложенного к заявке на проверку личности.
+	ErrMediaInUse = errors.New("media is in use")
+)
 
 // Create сохраняет запись о файле.
 func (r *MediaRepository) Create(ctx context.Context, media *models.MediaFile) error {
@@ -64,6 +69,10 @@ func (r *MediaRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Me
 // Delete удаляет запись о файле.
 func (r *MediaRepository) Delete(ctx context.Context, mediaID uuid.UUID) error {
 	if _, err := r.db.ExecContext(ctx, `DELETE FROM media_files WHERE id = $1`, mediaID); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return ErrMediaInUse
+		}
 		return fmt.Errorf("media repository: delete %w", err)
 	}
 	return nil
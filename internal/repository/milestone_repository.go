@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+// milestoneCurrency — валюта этапа m: валюта его escrow, а до оплаты — валюта принятого
+// отклика исполнителя или заказа, как в PaymentRepository.FundMilestone.
+const milestoneCurrency = `COALESCE(
+	(SELECT e.currency FROM escrow e WHERE e.milestone_id = m.id ORDER BY e.created_at DESC LIMIT 1),
+	(SELECT p.currency FROM proposals p JOIN orders o ON o.id = p.order_id
+	 WHERE p.order_id = m.order_id AND p.freelancer_id = o.freelancer_id AND p.status = 'accepted' AND p.proposed_amount > 0
+	 LIMIT 1),
+	(SELECT o.currency FROM orders o WHERE o.id = m.order_id)
+) AS currency`
+
+// MilestoneRepository отвечает за работу с таблицей order_milestones.
+// Движение денег по этапам выполняет PaymentRepository.
+type MilestoneRepository struct {
+	db *sqlx.DB
+}
+
+// NewMilestoneRepository создаёт экземпляр репозитория.
+func NewMilestoneRepository(db *sqlx.DB) *MilestoneRepository {
+	return &MilestoneRepository{db: db}
+}
+
+// Create добавляет этап в конец списка этапов заказа.
+func (r *MilestoneRepository) Create(ctx context.Context, m *models.Milestone) error {
+	query := `
+		INSERT INTO order_milestones AS m (order_id, title, description, amount, due_at, position)
+		VALUES ($1, $2, $3, $4, $5, (SELECT COALESCE(MAX(position), -1) + 1 FROM order_milestones WHERE order_id = $1))
+		RETURNING id, position, status, created_at, updated_at, ` + milestoneCurrency + `
+	`
+	if err := r.db.QueryRowxContext(ctx, query, m.OrderID, m.Title, m.Description, m.Amount, m.DueAt).
+		Scan(&m.ID, &m.Position, &m.Status, &m.CreatedAt, &m.UpdatedAt, &m.Currency); err != nil {
+		return fmt.Errorf("milestone repository: create %w", err)
+	}
+	return nil
+}
+
+// GetByID возвращает этап по идентификатору.
+func (r *MilestoneRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Milestone, error) {
+	var m models.Milestone
+	if err := r.db.GetContext(ctx, &m, `SELECT m.*, `+milestoneCurrency+` FROM order_milestones m WHERE m.id = $1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMilestoneNotFound
+		}
+		return nil, fmt.Errorf("milestone repository: get by id %w", err)
+	}
+	return &m, nil
+}
+
+// ListByOrder возвращает этапы заказа в порядке выполнения.
+func (r *MilestoneRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.Milestone, error) {
+	milestones := []models.Milestone{}
+	if err := r.db.SelectContext(ctx, &milestones, `
+		SELECT m.*, `+milestoneCurrency+` FROM order_milestones m WHERE m.order_id = $1 ORDER BY m.position ASC, m.created_at ASC
+	`, orderID); err != nil {
+		return nil, fmt.Errorf("milestone repository: list by order %w", err)
+	}
+	return milestones, nil
+}
+
+// CountByOrder возвращает количество этапов заказа.
+func (r *MilestoneRepository) CountByOrder(ctx context.Context, orderID uuid.UUID) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM order_milestones WHERE order_id = $1`, orderID); err != nil {
+		return 0, fmt.Errorf("milestone repository: count by order %w", err)
+	}
+	return count, nil
+}
+
+// Update изменяет описание, сумму и срок этапа, пока он не оплачен.
+func (r *MilestoneRepository) Update(ctx context.Context, m *models.Milestone) error {
+	err := r.db.QueryRowxContext(ctx, `
+		UPDATE order_milestones SET title = $2, description = $3, amount = $4, due_at = $5
+		WHERE id = $1 AND status = 'pending'
+		RETURNING updated_at
+	`, m.ID, m.Title, m.Description, m.Amount, m.DueAt).Scan(&m.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMilestoneInvalidState
+		}
+		return fmt.Errorf("milestone repository: update %w", err)
+	}
+	return nil
+}
+
+// MarkSubmitted отмечает, что фрилансер сдал работу по этапу.
+func (r *MilestoneRepository) MarkSubmitted(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE order_milestones SET status = 'submitted', submitted_at = NOW()
+		WHERE id = $1 AND status = 'funded'
+	`, id)
+	if err != nil {
+		return fmt.Errorf("milestone repository: mark submitted %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrMilestoneInvalidState
+	}
+	return nil
+}
+
+// Delete удаляет неоплаченный этап.
+func (r *MilestoneRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM order_milestones WHERE id = $1 AND status = 'pending'`, id)
+	if err != nil {
+		return fmt.Errorf("milestone repository: delete %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrMilestoneInvalidState
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+const notificationSettingsColumns = `user_id, quiet_hours_start, quiet_hours_end, timezone, digest_enabled, last_digest_at`
+
+// NotificationPreferenceRepository отвечает за таблицы notification_settings и notification_preferences.
+type NotificationPreferenceRepository struct {
+	db *sqlx.DB
+}
+
+// NewNotificationPreferenceRepository создаёт экземпляр репозитория.
+func NewNotificationPreferenceRepository(db *sqlx.DB) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{db: db}
+}
+
+// GetSettings возвращает настройки пользователя; если он их не менял — значения по умолчанию.
+func (r *NotificationPreferenceRepository) GetSettings(ctx context.Context, userID uuid.UUID) (*models.NotificationSettings, error) {
+	var settings models.NotificationSettings
+	err := r.db.GetContext(ctx, &settings, `
+		SELECT `+notificationSettingsColumns+` FROM notification_settings WHERE user_id = $1
+	`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &models.NotificationSettings{UserID: userID, Timezone: "UTC"}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("notification preference repository: get settings %w", err)
+	}
+	return &settings, nil
+}
+
+// ListPreferences возвращает каналы, заданные пользователем для типов событий.
+func (r *NotificationPreferenceRepository) ListPreferences(ctx context.Context, userID uuid.UUID) ([]models.NotificationPreference, error) {
+	preferences := []models.NotificationPreference{}
+	if err := r.db.SelectContext(ctx, &preferences, `
+		SELECT event_type, channel FROM notification_preferences
+		WHERE user_id = $1
+		ORDER BY event_type
+	`, userID); err != nil {
+		return nil, fmt.Errorf("notification preference repository: list %w", err)
+	}
+	return preferences, nil
+}
+
+// SaveSettings сохраняет настройки и заменяет все каналы по типам событий на preferences.
+func (r *NotificationPreferenceRepository) SaveSettings(ctx context.Context, settings *models.NotificationSettings, preferences []models.NotificationPreference) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("notification preference repository: begin tx %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO notification_settings (user_id, quiet_hours_start, quiet_hours_end, timezone, digest_enabled)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			quiet_hours_start = EXCLUDED.quiet_hours_start,
+			quiet_hours_end = EXCLUDED.quiet_hours_end,
+			timezone = EXCLUDED.timezone,
+			digest_enabled = EXCLUDED.digest_enabled,
+			updated_at = NOW()
+	`, settings.UserID, settings.QuietHoursStart, settings.QuietHoursEnd, settings.Timezone, settings.DigestEnabled); err != nil {
+		return fmt.Errorf("notification preference repository: save settings %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM notification_preferences WHERE user_id = $1`, settings.UserID); err != nil {
+		return fmt.Errorf("notification preference repository: clear preferences %w", err)
+	}
+	for _, preference := range preferences {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO notification_preferences (user_id, event_type, channel) VALUES ($1, $2, $3)
+		`, settings.UserID, preference.EventType, preference.Channel); err != nil {
+			return fmt.Errorf("notification preference repository: save preference %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("notification preference repository: commit %w", err)
+	}
+	return nil
+}
+
+// GetDelivery возвращает канал для события eventType (заданный для типа, иначе для '*', иначе
+// in_app), настройки пользователя и его контакты одним запросом: вызывается на каждое событие.
+func (r *NotificationPreferenceRepository) GetDelivery(ctx context.Context, userID uuid.UUID, eventType string) (*models.NotificationDelivery, error) {
+	var row struct {
+		models.NotificationSettings
+		Channel       string  `db:"channel"`
+		Email         string  `db:"email"`
+		Telegram      *string `db:"telegram"`
+		Phone         *string `db:"phone"`
+		PhoneVerified bool    `db:"phone_verified"`
+		TelegramPhone *string `db:"telegram_phone"`
+	}
+	err := r.db.GetContext(ctx, &row, `
+		SELECT u.id AS user_id, u.email, p.telegram, p.phone, u.phone_verified, tc.phone AS telegram_phone,
+		       COALESCE(
+		           (SELECT channel FROM notification_preferences WHERE user_id = u.id AND event_type = $2),
+		           (SELECT channel FROM notification_preferences WHERE user_id = u.id AND event_type = $3),
+		           $4
+		       ) AS channel,
+		       s.quiet_hours_start, s.quiet_hours_end,
+		       COALESCE(s.timezone, 'UTC') AS timezone,
+		       COALESCE(s.digest_enabled, FALSE) AS digest_enabled,
+		       s.last_digest_at
+		FROM users u
+		LEFT JOIN profiles p ON p.user_id = u.id
+		LEFT JOIN telegram_contacts tc ON tc.chat_id::text = TRIM(p.telegram)
+		LEFT JOIN notification_settings s ON s.user_id = u.id
+		WHERE u.id = $1
+	`, userID, eventType, models.NotificationEventDefault, models.NotificationChannelInApp)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("notification preference repository: get delivery %w", err)
+	}
+	return &models.NotificationDelivery{
+		Channel:       row.Channel,
+		Settings:      row.NotificationSettings,
+		Email:         row.Email,
+		Telegram:      row.Telegram,
+		Phone:         row.Phone,
+		PhoneVerified: row.PhoneVerified,
+		TelegramPhone: row.TelegramPhone,
+	}, nil
+}
+
+// ListDigestRecipients возвращает до limit пользователей со включённой сводкой, которым
+// больше period не отправляли сводку и у которых за это время есть непрочитанные уведомления.
+func (r *NotificationPreferenceRepository) ListDigestRecipients(ctx context.Context, period time.Duration, limit int) ([]models.DigestRecipient, error) {
+	recipients := []models.DigestRecipient{}
+	if err := r.db.SelectContext(ctx, &recipients, `
+		SELECT s.user_id, s.quiet_hours_start, s.quiet_hours_end, s.timezone, s.digest_enabled, s.last_digest_at, u.email
+		FROM notification_settings s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.digest_enabled AND u.deleted_at IS NULL
+		  AND (s.last_digest_at IS NULL OR s.last_digest_at < NOW() - $1 * INTERVAL '1 second')
+		  AND EXISTS (
+		      SELECT 1 FROM notifications n
+		      WHERE n.user_id = s.user_id AND NOT n.is_read
+		        AND n.created_at > COALESCE(s.last_digest_at, NOW() - $1 * INTERVAL '1 second')
+		  )
+		ORDER BY s.last_digest_at NULLS FIRST
+		LIMIT $2
+	`, period.Seconds(), limit); err != nil {
+		return nil, fmt.Errorf("notification preference repository: list digest recipients %w", err)
+	}
+	return recipients, nil
+}
+
+// ClaimDigest отмечает сводку отправленной, если с момента выборки её не отправил другой
+// экземпляр: previous — прочитанное тогда значение last_digest_at.
+func (r *NotificationPreferenceRepository) ClaimDigest(ctx context.Context, userID uuid.UUID, previous *time.Time, at time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE notification_settings SET last_digest_at = $3
+		WHERE user_id = $1 AND last_digest_at IS NOT DISTINCT FROM $2::timestamptz
+	`, userID, previous, at)
+	if err != nil {
+		return false, fmt.Errorf("notification preference repository: claim digest %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("notification preference repository: claim digest %w", err)
+	}
+	return rows == 1, nil
+}
+
+// ReleaseDigest возвращает last_digest_at к previous, если сводку, отмеченную в at, не удалось
+// отправить: следующий проход попробует снова.
+func (r *NotificationPreferenceRepository) ReleaseDigest(ctx context.Context, userID uuid.UUID, previous *time.Time, at time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE notification_settings SET last_digest_at = $2::timestamptz
+		WHERE user_id = $1 AND last_digest_at = $3
+	`, userID, previous, at); err != nil {
+		return fmt.Errorf("notification preference repository: release digest %w", err)
+	}
+	return nil
+}
+
+// DeferDelivery откладывает отправку события по внешнему каналу до конца тихих часов.
+func (r *NotificationPreferenceRepository) DeferDelivery(ctx context.Context, userID uuid.UUID, eventType, channel string) error {
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO notification_deferred (user_id, event_type, channel) VALUES ($1, $2, $3)
+	`, userID, eventType, channel); err != nil {
+		return fmt.Errorf("notification preference repository: defer delivery %w", err)
+	}
+	return nil
+}
+
+// ListDeferredUsers возвращает до limit пользователей с отложенными уведомлениями, которые
+// сейчас никто не отправляет; первыми — те, кто ждёт дольше.
+func (r *NotificationPreferenceRepository) ListDeferredUsers(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	users := []uuid.UUID{}
+	if err := r.db.SelectContext(ctx, &users, `
+		SELECT user_id FROM notification_deferred
+		WHERE locked_until IS NULL OR locked_until < NOW()
+		GROUP BY user_id
+		ORDER BY MIN(id)
+		LIMIT $1
+	`, limit); err != nil {
+		return nil, fmt.Errorf("notification preference repository: list deferred users %w", err)
+	}
+	return users, nil
+}
+
+// ClaimDeferred забирает отложенные уведомления пользователя на время lease, чтобы другой
+// экземпляр не отправил их повторно. Уведомления старше maxAge удаляются: их так и не
+// удалось отправить.
+func (r *NotificationPreferenceRepository) ClaimDeferred(ctx context.Context, userID uuid.UUID, lease, maxAge time.Duration) ([]models.DeferredNotification, error) {
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM notification_deferred WHERE user_id = $1 AND created_at < NOW() - $2 * INTERVAL '1 second'
+	`, userID, maxAge.Seconds()); err != nil {
+		return nil, fmt.Errorf("notification preference repository: expire deferred %w", err)
+	}
+
+	deferred := []models.DeferredNotification{}
+	if err := r.db.SelectContext(ctx, &deferred, `
+		UPDATE notification_deferred SET locked_until = NOW() + $2 * INTERVAL '1 second'
+		WHERE user_id = $1 AND (locked_until IS NULL OR locked_until < NOW())
+		RETURNING id, event_type, channel, created_at
+	`, userID, lease.Seconds()); err != nil {
+		return nil, fmt.Errorf("notification preference repository: claim deferred %w", err)
+	}
+	return deferred, nil
+}
+
+// DeleteDeferred удаляет отправленные отложенные уведомления.
+func (r *NotificationPreferenceRepository) DeleteDeferred(ctx context.Context, ids []int64) error {
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM notification_deferred WHERE id = ANY($1)
+	`, pq.Array(ids)); err != nil {
+		return fmt.Errorf("notification preference repository: delete deferred %w", err)
+	}
+	return nil
+}
+
+// ListUnreadSince возвращает до limit последних непрочитанных уведомлений после since и их общее число.
+func (r *NotificationPreferenceRepository) ListUnreadSince(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]models.Notification, int, error) {
+	var total int
+	if err := r.db.GetContext(ctx, &total, `
+		SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND NOT is_read AND created_at > $2
+	`, userID, since); err != nil {
+		return nil, 0, fmt.Errorf("notification preference repository: count unread %w", err)
+	}
+
+	notifications := []models.Notification{}
+	if err := r.db.SelectContext(ctx, &notifications, `
+		SELECT * FROM notifications
+		WHERE user_id = $1 AND NOT is_read AND created_at > $2
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, userID, since, limit); err != nil {
+		return nil, 0, fmt.Errorf("notification preference repository: list unread %w", err)
+	}
+	return notifications, total, nil
+}
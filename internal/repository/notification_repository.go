@@ -25,12 +25,18 @@ func NewNotificationRepository(db *sqlx.DB) *NotificationRepository {
 	return &NotificationRepository{db: db}
 }
 
-// Create создаёт новое уведомление.
+// Create создаёт новое уведомление и выдаёт ему следующий номер события пользователя.
 func (r *NotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
 	query := `
-		INSERT INTO notifications (user_id, payload, is_read)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		WITH next AS (
+			INSERT INTO user_event_sequences (user_id, last_seq)
+			VALUES ($1, 1)
+			ON CONFLICT (user_id) DO UPDATE SET last_seq = user_event_sequences.last_seq + 1
+			RETURNING last_seq
+		)
+		INSERT INTO notifications (user_id, payload, is_read, seq)
+		SELECT $1, $2, $3, last_seq FROM next
+		RETURNING id, seq, created_at
 	`
 
 	if err := r.db.QueryRowxContext(
@@ -39,7 +45,7 @@ func (r *NotificationRepository) Create(ctx context.Context, notification *model
 		notification.UserID,
 		notification.Payload,
 		notification.IsRead,
-	).Scan(&notification.ID, &notification.CreatedAt); err != nil {
+	).Scan(&notification.ID, &notification.Seq, &notification.CreatedAt); err != nil {
 		return fmt.Errorf("notification repository: create %w", err)
 	}
 
@@ -151,3 +157,21 @@ func (r *NotificationRepository) CountUnread(ctx context.Context, userID uuid.UU
 	return count, nil
 }
 
+// ListLatestSince возвращает не больше limit последних уведомлений пользователя с номером
+// больше since, в порядке возрастания номера.
+func (r *NotificationRepository) ListLatestSince(ctx context.Context, userID uuid.UUID, since int64, limit int) ([]models.Notification, error) {
+	query := `
+		SELECT * FROM (
+			SELECT * FROM notifications
+			WHERE user_id = $1 AND seq > $2
+			ORDER BY seq DESC
+			LIMIT $3
+		) latest
+		ORDER BY seq ASC
+	`
+	var notifications []models.Notification
+	if err := r.db.SelectContext(ctx, &notifications, query, userID, since, limit); err != nil {
+		return nil, fmt.Errorf("notification repository: list since %w", err)
+	}
+	return notifications, nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 )
 
@@ -24,6 +25,7 @@ var (
 	ErrOrderNotFound        = errors.New("order not found")
 	ErrProposalNotFound     = errors.New("proposal not found")
 	ErrConversationNotFound = errors.New("conversation not found")
+	ErrMessageNotFound      = errors.New("message not found")
 )
 
 // NewOrderRepository создаёт новый экземпляр.
@@ -35,7 +37,7 @@ func NewOrderRepository(db *sqlx.DB) *OrderRepository {
 func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Order, error) {
 	var order models.Order
 	query := `
-		SELECT id, client_id, freelancer_id, category_id, title, description, budget_min, budget_max, final_amount, status, deadline_at, ai_summary,
+		SELECT id, client_id, freelancer_id, category_id, title, description, budget_min, budget_max, currency, final_amount, status, deadline_at, ai_summary,
 		       best_recommendation_proposal_id, best_recommendation_justification, ai_analysis_updated_at,
 		       created_at, updated_at
 		FROM orders
@@ -55,7 +57,7 @@ func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Or
 func (r *OrderRepository) GetByIDWithDetails(ctx context.Context, id uuid.UUID) (*models.Order, []models.OrderRequirement, []models.OrderAttachment, error) {
 	var order models.Order
 	orderQuery := `
-		SELECT id, client_id, freelancer_id, category_id, title, description, budget_min, budget_max, final_amount, status, deadline_at, ai_summary,
+		SELECT id, client_id, freelancer_id, category_id, title, description, budget_min, budget_max, currency, final_amount, status, deadline_at, ai_summary,
 		       best_recommendation_proposal_id, best_recommendation_justification, ai_analysis_updated_at,
 		       created_at, updated_at
 		FROM orders
@@ -144,8 +146,8 @@ func (r *OrderRepository) Create(ctx context.Context, order *models.Order, requi
 	}()
 
 	query := `
-		INSERT INTO orders (client_id, title, description, budget_min, budget_max, status, deadline_at, ai_summary)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO orders (client_id, title, description, budget_min, budget_max, currency, status, deadline_at, ai_summary)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -157,6 +159,7 @@ func (r *OrderRepository) Create(ctx context.Context, order *models.Order, requi
 		order.Description,
 		order.BudgetMin,
 		order.BudgetMax,
+		order.Currency,
 		order.Status,
 		order.DeadlineAt,
 		order.AISummary,
@@ -230,6 +233,7 @@ func (r *OrderRepository) Update(ctx context.Context, order *models.Order, requi
 		    deadline_at = $6,
 		    ai_summary = $7,
 		    freelancer_id = $8,
+		    currency = $11,
 		    updated_at = NOW()
 		WHERE id = $9 AND client_id = $10
 		RETURNING updated_at
@@ -249,6 +253,7 @@ func (r *OrderRepository) Update(ctx context.Context, order *models.Order, requi
 		order.FreelancerID,
 		order.ID,
 		order.ClientID,
+		order.Currency,
 	).Scan(&updatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -315,8 +320,8 @@ type ListFilterParams struct {
 	Status    string
 	Search    string
 	Skills    []string
-	BudgetMin *float64
-	BudgetMax *float64
+	BudgetMin *valueobject.Amount
+	BudgetMax *valueobject.Amount
 	SortBy    string // "date", "budget", "proposals"
 	SortOrder string // "asc", "desc"
 	Limit     int
@@ -491,8 +496,8 @@ func (r *OrderRepository) List(ctx context.Context, params ListFilterParams) (*L
 // CreateProposal добавляет отклик и возвращает его идентификатор.
 func (r *OrderRepository) CreateProposal(ctx context.Context, proposal *models.Proposal) error {
 	query := `
-		INSERT INTO proposals (order_id, freelancer_id, cover_letter, proposed_amount, status, ai_feedback)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO proposals (order_id, freelancer_id, cover_letter, proposed_amount, currency, status, ai_feedback)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -503,6 +508,7 @@ func (r *OrderRepository) CreateProposal(ctx context.Context, proposal *models.P
 		proposal.FreelancerID,
 		proposal.CoverLetter,
 		proposal.ProposedAmount,
+		proposal.Currency,
 		proposal.Status,
 		proposal.AIFeedback,
 	).Scan(&proposal.ID, &proposal.CreatedAt, &proposal.UpdatedAt)
@@ -527,7 +533,7 @@ func (r *OrderRepository) UpdateProposalStatus(ctx context.Context, id uuid.UUID
 		SET status = $2,
 		    updated_at = NOW()
 		WHERE id = $1
-		RETURNING id, order_id, freelancer_id, cover_letter, proposed_amount, status, ai_feedback, created_at, updated_at
+		RETURNING id, order_id, freelancer_id, cover_letter, proposed_amount, currency, status, ai_feedback, created_at, updated_at
 	`
 
 	var proposal models.Proposal
@@ -556,6 +562,15 @@ func (r *OrderRepository) ListProposals(ctx context.Context, orderID uuid.UUID)
 	return proposals, nil
 }
 
+// CountProposals возвращает число откликов на заказ.
+func (r *OrderRepository) CountProposals(ctx context.Context, orderID uuid.UUID) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM proposals WHERE order_id = $1`, orderID); err != nil {
+		return 0, fmt.Errorf("order repository: count proposals %w", err)
+	}
+	return count, nil
+}
+
 // CreateConversation создаёт чат для заказа.
 func (r *OrderRepository) CreateConversation(ctx context.Context, conv *models.Conversation) error {
 	query := `
@@ -629,7 +644,7 @@ func (r *OrderRepository) GetLastMessageForConversation(ctx context.Context, con
 	var message models.Message
 	query := `
 		SELECT * FROM messages
-		WHERE conversation_id = $1
+		WHERE conversation_id = $1 AND hidden_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT 1
 	`
@@ -680,7 +695,7 @@ func (r *OrderRepository) AddMessage(ctx context.Context, msg *models.Message) e
 func (r *OrderRepository) ListMessages(ctx context.Context, conversationID uuid.UUID, limit, offset int) ([]models.Message, error) {
 	query := `
 		SELECT * FROM messages
-		WHERE conversation_id = $1
+		WHERE conversation_id = $1 AND hidden_at IS NULL
 		ORDER BY created_at ASC
 	`
 	args := []interface{}{conversationID}
@@ -732,6 +747,16 @@ func (r *OrderRepository) ListMessages(ctx context.Context, conversationID uuid.
 				messages[i].Reactions = reactionsMap[messages[i].ID]
 			}
 		}
+
+		// Загружаем отметки о прочтении
+		readTimes, err := r.GetMessageReadTimes(ctx, messageIDs)
+		if err == nil {
+			for i := range messages {
+				if readAt, ok := readTimes[messages[i].ID]; ok {
+					messages[i].ReadAt = &readAt
+				}
+			}
+		}
 	}
 
 	return messages, nil
@@ -1383,9 +1408,125 @@ func (r *OrderRepository) GetMessageReactionsByMessageIDs(ctx context.Context, m
 }
 
 // SetOrderFreelancer устанавливает фрилансера и итоговую сумму для заказа.
-func (r *OrderRepository) SetOrderFreelancer(ctx context.Context, orderID, freelancerID uuid.UUID, finalAmount float64) error {
+func (r *OrderRepository) SetOrderFreelancer(ctx context.Context, orderID, freelancerID uuid.UUID, finalAmount valueobject.Amount) error {
 	_, err := r.db.ExecContext(ctx, `
 		UPDATE orders SET freelancer_id = $2, final_amount = $3, updated_at = NOW() WHERE id = $1
 	`, orderID, freelancerID, finalAmount)
 	return err
 }
+
+// MarkMessagesRead отмечает прочитанными для userID все сообщения собеседника в чате,
+// отправленные не позже upToMessageID. Возвращает только сообщения, отмеченные этим вызовом.
+func (r *OrderRepository) MarkMessagesRead(ctx context.Context, conversationID, userID, upToMessageID uuid.UUID) (*models.ReadReceipt, error) {
+	var upTo time.Time
+	err := r.db.GetContext(ctx, &upTo, `
+		SELECT created_at FROM messages WHERE id = $1 AND conversation_id = $2
+	`, upToMessageID, conversationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("order repository: mark messages read %w", err)
+	}
+
+	var rows []struct {
+		MessageID uuid.UUID `db:"message_id"`
+		ReadAt    time.Time `db:"read_at"`
+	}
+	query := `
+		INSERT INTO message_reads (message_id, user_id)
+		SELECT id, $2 FROM messages
+		WHERE conversation_id = $1
+		  AND created_at <= $3
+		  AND author_id IS DISTINCT FROM $2
+		  AND hidden_at IS NULL
+		ON CONFLICT DO NOTHING
+		RETURNING message_id, read_at
+	`
+	if err := r.db.SelectContext(ctx, &rows, query, conversationID, userID, upTo); err != nil {
+		return nil, fmt.Errorf("order repository: mark messages read %w", err)
+	}
+
+	receipt := &models.ReadReceipt{
+		ConversationID: conversationID,
+		ReaderID:       userID,
+		MessageIDs:     make([]uuid.UUID, 0, len(rows)),
+		ReadAt:         time.Now(),
+	}
+	for _, row := range rows {
+		receipt.MessageIDs = append(receipt.MessageIDs, row.MessageID)
+		receipt.ReadAt = row.ReadAt
+	}
+	return receipt, nil
+}
+
+// GetMessageReadTimes возвращает время прочтения сообщений собеседником автора.
+func (r *OrderRepository) GetMessageReadTimes(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID]time.Time, error) {
+	result := make(map[uuid.UUID]time.Time)
+	if len(messageIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		MessageID uuid.UUID `db:"message_id"`
+		ReadAt    time.Time `db:"read_at"`
+	}
+	query := `
+		SELECT mr.message_id, MIN(mr.read_at) AS read_at
+		FROM message_reads mr
+		JOIN messages m ON m.id = mr.message_id
+		WHERE mr.message_id = ANY($1) AND mr.user_id IS DISTINCT FROM m.author_id
+		GROUP BY mr.message_id
+	`
+	if err := r.db.SelectContext(ctx, &rows, query, pq.Array(messageIDs)); err != nil {
+		return nil, fmt.Errorf("order repository: get message read times %w", err)
+	}
+	for _, row := range rows {
+		result[row.MessageID] = row.ReadAt
+	}
+	return result, nil
+}
+
+// CountUnreadMessages возвращает число непрочитанных пользователем сообщений по каждому его чату.
+// Чаты без непрочитанных сообщений в результат не попадают.
+func (r *OrderRepository) CountUnreadMessages(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		ConversationID uuid.UUID `db:"conversation_id"`
+		Unread         int       `db:"unread"`
+	}
+	query := `
+		SELECT m.conversation_id, COUNT(*) AS unread
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE (c.client_id = $1 OR c.freelancer_id = $1)
+		  AND m.author_id IS DISTINCT FROM $1
+		  AND m.hidden_at IS NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM message_reads mr WHERE mr.message_id = m.id AND mr.user_id = $1
+		  )
+		GROUP BY m.conversation_id
+	`
+	if err := r.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		return nil, fmt.Errorf("order repository: count unread messages %w", err)
+	}
+
+	result := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		result[row.ConversationID] = row.Unread
+	}
+	return result, nil
+}
+
+// ListConversationContacts возвращает пользователей, с которыми у userID есть общий чат.
+func (r *OrderRepository) ListConversationContacts(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT DISTINCT CASE WHEN client_id = $1 THEN freelancer_id ELSE client_id END
+		FROM conversations
+		WHERE (client_id = $1 OR freelancer_id = $1) AND client_id <> freelancer_id
+	`
+	var contacts []uuid.UUID
+	if err := r.db.SelectContext(ctx, &contacts, query, userID); err != nil {
+		return nil, fmt.Errorf("order repository: list conversation contacts %w", err)
+	}
+	return contacts, nil
+}
@@ -10,12 +10,17 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 )
 
 var (
-	ErrInsufficientFunds = errors.New("insufficient funds")
-	ErrEscrowNotFound    = errors.New("escrow not found")
+	ErrInsufficientFunds     = errors.New("insufficient funds")
+	ErrEscrowNotFound        = errors.New("escrow not found")
+	ErrMilestoneNotFound     = errors.New("milestone not found")
+	ErrMilestoneInvalidState = errors.New("milestone is not in a valid state for this operation")
+	ErrInvalidEscrowSplit    = errors.New("escrow split does not match escrow amount")
+	ErrOrderEscrowExists     = errors.New("order is already paid with a whole-order escrow")
 )
 
 type PaymentRepository struct {
@@ -26,23 +31,36 @@ func NewPaymentRepository(db *sqlx.DB) *PaymentRepository {
 	return &PaymentRepository{db: db}
 }
 
-// GetBalance возвращает баланс пользователя, создаёт если не существует.
-func (r *PaymentRepository) GetBalance(ctx context.Context, userID uuid.UUID) (*models.UserBalance, error) {
+// GetBalance возвращает баланс пользователя в валюте, создаёт если не существует.
+func (r *PaymentRepository) GetBalance(ctx context.Context, userID uuid.UUID, currency string) (*models.UserBalance, error) {
 	var balance models.UserBalance
 	query := `
-		INSERT INTO user_balances (user_id, available, frozen)
-		VALUES ($1, 0, 0)
-		ON CONFLICT (user_id) DO UPDATE SET updated_at = NOW()
-		RETURNING user_id, available, frozen, updated_at
+		INSERT INTO user_balances (user_id, currency, available, frozen)
+		VALUES ($1, $2, 0, 0)
+		ON CONFLICT (user_id, currency) DO UPDATE SET updated_at = NOW()
+		RETURNING user_id, currency, available, frozen, updated_at
 	`
-	if err := r.db.GetContext(ctx, &balance, query, userID); err != nil {
+	if err := r.db.GetContext(ctx, &balance, query, userID, currency); err != nil {
 		return nil, fmt.Errorf("payment repository: get balance %w", err)
 	}
 	return &balance, nil
 }
 
-// Deposit пополняет баланс пользователя.
-func (r *PaymentRepository) Deposit(ctx context.Context, userID uuid.UUID, amount float64, description string) (*models.Transaction, error) {
+// ListBalances возвращает балансы пользователя во всех валютах, в которых они заведены.
+func (r *PaymentRepository) ListBalances(ctx context.Context, userID uuid.UUID) ([]models.UserBalance, error) {
+	balances := []models.UserBalance{}
+	err := r.db.SelectContext(ctx, &balances, `
+		SELECT user_id, currency, available, frozen, updated_at
+		FROM user_balances WHERE user_id = $1 ORDER BY currency
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("payment repository: list balances %w", err)
+	}
+	return balances, nil
+}
+
+// Deposit пополняет баланс пользователя в валюте.
+func (r *PaymentRepository) Deposit(ctx context.Context, userID uuid.UUID, amount valueobject.Amount, currency, description string) (*models.Transaction, error) {
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -51,10 +69,10 @@ func (r *PaymentRepository) Deposit(ctx context.Context, userID uuid.UUID, amoun
 
 	// Обновляем баланс
 	_, err = tx.ExecContext(ctx, `
-		INSERT INTO user_balances (user_id, available, frozen)
-		VALUES ($1, $2, 0)
-		ON CONFLICT (user_id) DO UPDATE SET available = user_balances.available + $2, updated_at = NOW()
-	`, userID, amount)
+		INSERT INTO user_balances (user_id, currency, available, frozen)
+		VALUES ($1, $3, $2, 0)
+		ON CONFLICT (user_id, currency) DO UPDATE SET available = user_balances.available + $2, updated_at = NOW()
+	`, userID, amount, currency)
 	if err != nil {
 		return nil, fmt.Errorf("payment repository: deposit update balance %w", err)
 	}
@@ -62,72 +80,210 @@ func (r *PaymentRepository) Deposit(ctx context.Context, userID uuid.UUID, amoun
 	// Создаём транзакцию
 	var transaction models.Transaction
 	err = tx.GetContext(ctx, &transaction, `
-		INSERT INTO transactions (user_id, type, amount, status, description, completed_at)
-		VALUES ($1, 'deposit', $2, 'completed', $3, NOW())
-		RETURNING id, user_id, order_id, type, amount, status, description, created_at, completed_at
-	`, userID, amount, description)
+		INSERT INTO transactions (user_id, type, amount, currency, status, description, completed_at)
+		VALUES ($1, 'deposit', $2, $3, 'completed', $4, NOW())
+		RETURNING id, user_id, order_id, milestone_id, type, amount, currency, status, description, created_at, completed_at
+	`, userID, amount, currency, description)
 	if err != nil {
 		return nil, fmt.Errorf("payment repository: deposit create transaction %w", err)
 	}
 
+	err = postLedger(ctx, tx, ledgerJournal{Kind: models.LedgerJournalDeposit, Currency: currency, Description: description},
+		platformLeg(models.LedgerAccountExternalCash, -amount),
+		userLeg(models.LedgerAccountUserAvailable, userID, amount),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return &transaction, tx.Commit()
 }
 
-// CreateEscrow создаёт escrow и замораживает средства клиента.
-func (r *PaymentRepository) CreateEscrow(ctx context.Context, orderID, clientID, freelancerID uuid.UUID, amount float64) (*models.Escrow, error) {
+// CreateEscrow создаёт escrow и замораживает средства клиента в валюте.
+func (r *PaymentRepository) CreateEscrow(ctx context.Context, orderID, clientID, freelancerID uuid.UUID, amount valueobject.Amount, currency string) (*models.Escrow, error) {
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	// Проверяем баланс клиента
-	var balance models.UserBalance
-	err = tx.GetContext(ctx, &balance, `SELECT user_id, available, frozen FROM user_balances WHERE user_id = $1 FOR UPDATE`, clientID)
+	if err := lockOrder(ctx, tx, orderID); err != nil {
+		return nil, err
+	}
+	escrow, err := r.holdEscrow(ctx, tx, orderID, nil, clientID, freelancerID, amount, currency, "Заморозка средств для заказа")
+	if err != nil {
+		return nil, err
+	}
+
+	return escrow, tx.Commit()
+}
+
+// ReleaseEscrow освобождает средства в пользу фрилансера.
+func (r *PaymentRepository) ReleaseEscrow(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var escrow models.Escrow
+	err = tx.GetContext(ctx, &escrow, `SELECT * FROM escrow WHERE order_id = $1 AND milestone_id IS NULL AND status = 'held' FOR UPDATE`, orderID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrInsufficientFunds
+			return nil, ErrEscrowNotFound
 		}
 		return nil, err
 	}
-	if balance.Available < amount {
-		return nil, ErrInsufficientFunds
+
+	if err := r.releaseHeldEscrow(ctx, tx, &escrow, "Получение оплаты за заказ"); err != nil {
+		return nil, err
 	}
 
-	// Замораживаем средства
-	_, err = tx.ExecContext(ctx, `
-		UPDATE user_balances SET available = available - $2, frozen = frozen + $2, updated_at = NOW()
-		WHERE user_id = $1
-	`, clientID, amount)
+	return &escrow, tx.Commit()
+}
+
+// RefundEscrow возвращает средства клиенту.
+func (r *PaymentRepository) RefundEscrow(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	// Создаём escrow
 	var escrow models.Escrow
-	err = tx.GetContext(ctx, &escrow, `
-		INSERT INTO escrow (order_id, client_id, freelancer_id, amount, status)
-		VALUES ($1, $2, $3, $4, 'held')
-		RETURNING id, order_id, client_id, freelancer_id, amount, status, created_at, released_at
-	`, orderID, clientID, freelancerID, amount)
+	err = tx.GetContext(ctx, &escrow, `SELECT * FROM escrow WHERE order_id = $1 AND milestone_id IS NULL AND status = 'held' FOR UPDATE`, orderID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrEscrowNotFound
+		}
 		return nil, err
 	}
 
-	// Транзакция заморозки
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO transactions (user_id, order_id, type, amount, status, description, completed_at)
-		VALUES ($1, $2, 'escrow_hold', $3, 'completed', 'Заморозка средств для заказа', NOW())
-	`, clientID, orderID, amount)
-	if err != nil {
+	if err := r.refundHeldEscrow(ctx, tx, &escrow, "Возврат средств за отменённый заказ"); err != nil {
 		return nil, err
 	}
 
 	return &escrow, tx.Commit()
 }
 
-// ReleaseEscrow освобождает средства в пользу фрилансера.
-func (r *PaymentRepository) ReleaseEscrow(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error) {
+// FundMilestone замораживает средства клиента под конкретный этап заказа в валюте принятого отклика.
+func (r *PaymentRepository) FundMilestone(ctx context.Context, milestoneID, clientID, freelancerID uuid.UUID) (*models.Escrow, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var milestone models.Milestone
+	err = tx.GetContext(ctx, &milestone, `SELECT * FROM order_milestones WHERE id = $1 FOR UPDATE`, milestoneID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMilestoneNotFound
+		}
+		return nil, err
+	}
+	if milestone.Status != models.MilestoneStatusPending {
+		return nil, ErrMilestoneInvalidState
+	}
+
+	// Заказ, уже оплаченный целиком при принятии отклика, поэтапно не оплачивается:
+	// иначе заказчик заплатил бы за ту же работу дважды
+	if err := lockOrder(ctx, tx, milestone.OrderID); err != nil {
+		return nil, err
+	}
+	var paid bool
+	if err := tx.GetContext(ctx, &paid, `
+		SELECT EXISTS (SELECT 1 FROM escrow WHERE order_id = $1 AND milestone_id IS NULL)
+	`, milestone.OrderID); err != nil {
+		return nil, fmt.Errorf("payment repository: order escrow %w", err)
+	}
+	if paid {
+		return nil, ErrOrderEscrowExists
+	}
+
+	// Цену фиксирует принятый отклик исполнителя: этап оплачивается в его валюте,
+	// а если сумма в отклике не указана — в валюте заказа, как и escrow всего заказа
+	var currency string
+	if err := tx.GetContext(ctx, &currency, `
+		SELECT COALESCE(
+			(SELECT p.currency FROM proposals p
+			 WHERE p.order_id = o.id AND p.freelancer_id = $2 AND p.status = 'accepted' AND p.proposed_amount > 0
+			 LIMIT 1),
+			o.currency
+		)
+		FROM orders o WHERE o.id = $1
+	`, milestone.OrderID, freelancerID); err != nil {
+		return nil, fmt.Errorf("payment repository: milestone currency %w", err)
+	}
+
+	escrow, err := r.holdEscrow(ctx, tx, milestone.OrderID, &milestone.ID, clientID, freelancerID, milestone.Amount, currency,
+		fmt.Sprintf("Заморозка средств для этапа «%s»", milestone.Title))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE order_milestones SET status = 'funded', funded_at = NOW() WHERE id = $1`, milestone.ID)
+	if err != nil {
+		return nil, fmt.Errorf("payment repository: fund milestone %w", err)
+	}
+
+	return escrow, tx.Commit()
+}
+
+// ReleaseMilestone переводит средства этапа фрилансеру после приёмки.
+func (r *PaymentRepository) ReleaseMilestone(ctx context.Context, milestoneID uuid.UUID) (*models.Escrow, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	escrow, milestone, err := r.lockMilestoneEscrow(ctx, tx, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.releaseHeldEscrow(ctx, tx, escrow, fmt.Sprintf("Оплата этапа «%s»", milestone.Title)); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE order_milestones SET status = 'released', released_at = $2 WHERE id = $1`, milestone.ID, escrow.ReleasedAt)
+	if err != nil {
+		return nil, fmt.Errorf("payment repository: release milestone %w", err)
+	}
+
+	return escrow, tx.Commit()
+}
+
+// RefundMilestone возвращает клиенту средства, замороженные под этап.
+func (r *PaymentRepository) RefundMilestone(ctx context.Context, milestoneID uuid.UUID) (*models.Escrow, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	escrow, milestone, err := r.lockMilestoneEscrow(ctx, tx, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.refundHeldEscrow(ctx, tx, escrow, fmt.Sprintf("Возврат средств за этап «%s»", milestone.Title)); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE order_milestones SET status = 'refunded' WHERE id = $1`, milestone.ID)
+	if err != nil {
+		return nil, fmt.Errorf("payment repository: refund milestone %w", err)
+	}
+
+	return escrow, tx.Commit()
+}
+
+// SplitEscrow делит удерживаемый или оспариваемый escrow между заказчиком и
+// фрилансером. Обе выплаты и функция settle (например, закрытие спора)
+// выполняются в одной транзакции.
+func (r *PaymentRepository) SplitEscrow(ctx context.Context, escrowID uuid.UUID, clientAmount, freelancerAmount valueobject.Amount, description string, settle func(ctx context.Context, tx *sqlx.Tx) error) (*models.Escrow, error) {
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -135,104 +291,311 @@ func (r *PaymentRepository) ReleaseEscrow(ctx context.Context, orderID uuid.UUID
 	defer tx.Rollback()
 
 	var escrow models.Escrow
-	err = tx.GetContext(ctx, &escrow, `SELECT * FROM escrow WHERE order_id = $1 AND status = 'held' FOR UPDATE`, orderID)
+	err = tx.GetContext(ctx, &escrow, `SELECT * FROM escrow WHERE id = $1 AND status IN ('held', 'disputed') FOR UPDATE`, escrowID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrEscrowNotFound
 		}
 		return nil, err
 	}
+	if clientAmount < 0 || freelancerAmount < 0 || clientAmount+freelancerAmount != escrow.Amount {
+		return nil, ErrInvalidEscrowSplit
+	}
 
-	// Снимаем заморозку у клиента
+	// Снимаем заморозку и возвращаем заказчику его долю
 	_, err = tx.ExecContext(ctx, `
-		UPDATE user_balances SET frozen = frozen - $2, updated_at = NOW()
-		WHERE user_id = $1
-	`, escrow.ClientID, escrow.Amount)
+		UPDATE user_balances SET available = available + $2, frozen = frozen - $3, updated_at = NOW()
+		WHERE user_id = $1 AND currency = $4
+	`, escrow.ClientID, clientAmount, escrow.Amount, escrow.Currency)
 	if err != nil {
 		return nil, err
 	}
+	if clientAmount > 0 {
+		if err := insertTransaction(ctx, tx, escrow.ClientID, &escrow.OrderID, escrow.MilestoneID, models.TransactionTypeEscrowRefund, clientAmount, escrow.Currency, description); err != nil {
+			return nil, err
+		}
+	}
 
-	// Начисляем фрилансеру
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO user_balances (user_id, available, frozen)
-		VALUES ($1, $2, 0)
-		ON CONFLICT (user_id) DO UPDATE SET available = user_balances.available + $2, updated_at = NOW()
-	`, escrow.FreelancerID, escrow.Amount)
+	// Начисляем долю фрилансера за вычетом комиссии
+	var fee valueobject.Amount
+	if freelancerAmount > 0 {
+		if fee, err = r.payFreelancer(ctx, tx, &escrow, freelancerAmount, description); err != nil {
+			return nil, err
+		}
+	}
+
+	err = postLedger(ctx, tx, escrowJournal(models.LedgerJournalEscrowSplit, &escrow, description),
+		platformLeg(models.LedgerAccountPlatformEscrow, -escrow.Amount),
+		userLeg(models.LedgerAccountUserAvailable, escrow.ClientID, clientAmount),
+		userLeg(models.LedgerAccountUserAvailable, escrow.FreelancerID, freelancerAmount-fee),
+		platformLeg(models.LedgerAccountPlatformFees, fee),
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	// Обновляем escrow
+	status := models.EscrowStatusRefunded
+	if freelancerAmount > 0 {
+		status = models.EscrowStatusReleased
+	}
 	now := time.Now()
-	_, err = tx.ExecContext(ctx, `UPDATE escrow SET status = 'released', released_at = $2 WHERE id = $1`, escrow.ID, now)
-	if err != nil {
+	if _, err := tx.ExecContext(ctx, `UPDATE escrow SET status = $2, released_at = $3 WHERE id = $1`, escrow.ID, status, now); err != nil {
 		return nil, err
 	}
-	escrow.Status = models.EscrowStatusReleased
+	escrow.Status = status
 	escrow.ReleasedAt = &now
 
-	// Транзакция освобождения
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO transactions (user_id, order_id, type, amount, status, description, completed_at)
-		VALUES ($1, $2, 'escrow_release', $3, 'completed', 'Получение оплаты за заказ', NOW())
-	`, escrow.FreelancerID, orderID, escrow.Amount)
-	if err != nil {
-		return nil, err
+	if escrow.MilestoneID != nil {
+		query := `UPDATE order_milestones SET status = 'refunded' WHERE id = $1`
+		args := []interface{}{escrow.MilestoneID}
+		if status == models.EscrowStatusReleased {
+			query = `UPDATE order_milestones SET status = 'released', released_at = $2 WHERE id = $1`
+			args = append(args, now)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return nil, fmt.Errorf("payment repository: split milestone %w", err)
+		}
+	}
+
+	if settle != nil {
+		if err := settle(ctx, tx); err != nil {
+			return nil, err
+		}
 	}
 
 	return &escrow, tx.Commit()
 }
 
-// RefundEscrow возвращает средства клиенту.
-func (r *PaymentRepository) RefundEscrow(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error) {
-	tx, err := r.db.BeginTxx(ctx, nil)
+// GetEscrowByID возвращает escrow по идентификатору.
+func (r *PaymentRepository) GetEscrowByID(ctx context.Context, id uuid.UUID) (*models.Escrow, error) {
+	var escrow models.Escrow
+	err := r.db.GetContext(ctx, &escrow, `SELECT * FROM escrow WHERE id = $1`, id)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrEscrowNotFound
+		}
 		return nil, err
 	}
-	defer tx.Rollback()
+	return &escrow, nil
+}
 
+// GetEscrowByMilestoneID возвращает escrow по ID этапа.
+func (r *PaymentRepository) GetEscrowByMilestoneID(ctx context.Context, milestoneID uuid.UUID) (*models.Escrow, error) {
 	var escrow models.Escrow
-	err = tx.GetContext(ctx, &escrow, `SELECT * FROM escrow WHERE order_id = $1 AND status = 'held' FOR UPDATE`, orderID)
+	err := r.db.GetContext(ctx, &escrow, `SELECT * FROM escrow WHERE milestone_id = $1`, milestoneID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrEscrowNotFound
 		}
 		return nil, err
 	}
+	return &escrow, nil
+}
+
+// lockMilestoneEscrow блокирует этап и его удерживаемый escrow в рамках транзакции.
+func (r *PaymentRepository) lockMilestoneEscrow(ctx context.Context, tx *sqlx.Tx, milestoneID uuid.UUID) (*models.Escrow, *models.Milestone, error) {
+	var milestone models.Milestone
+	err := tx.GetContext(ctx, &milestone, `SELECT * FROM order_milestones WHERE id = $1 FOR UPDATE`, milestoneID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrMilestoneNotFound
+		}
+		return nil, nil, err
+	}
+	if milestone.Status != models.MilestoneStatusFunded && milestone.Status != models.MilestoneStatusSubmitted {
+		return nil, nil, ErrMilestoneInvalidState
+	}
+
+	var escrow models.Escrow
+	err = tx.GetContext(ctx, &escrow, `SELECT * FROM escrow WHERE milestone_id = $1 AND status = 'held' FOR UPDATE`, milestoneID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrEscrowNotFound
+		}
+		return nil, nil, err
+	}
+
+	return &escrow, &milestone, nil
+}
+
+// holdEscrow замораживает средства клиента в валюте и создаёт escrow (для заказа или этапа).
+func (r *PaymentRepository) holdEscrow(ctx context.Context, tx *sqlx.Tx, orderID uuid.UUID, milestoneID *uuid.UUID, clientID, freelancerID uuid.UUID, amount valueobject.Amount, currency, description string) (*models.Escrow, error) {
+	// Проверяем баланс клиента
+	var balance models.UserBalance
+	err := tx.GetContext(ctx, &balance, `
+		SELECT user_id, currency, available, frozen FROM user_balances WHERE user_id = $1 AND currency = $2 FOR UPDATE
+	`, clientID, currency)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInsufficientFunds
+		}
+		return nil, err
+	}
+	if balance.Available < amount {
+		return nil, ErrInsufficientFunds
+	}
 
-	// Возвращаем средства клиенту
+	// Замораживаем средства
 	_, err = tx.ExecContext(ctx, `
-		UPDATE user_balances SET available = available + $2, frozen = frozen - $2, updated_at = NOW()
-		WHERE user_id = $1
-	`, escrow.ClientID, escrow.Amount)
+		UPDATE user_balances SET available = available - $2, frozen = frozen + $2, updated_at = NOW()
+		WHERE user_id = $1 AND currency = $3
+	`, clientID, amount, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Создаём escrow
+	var escrow models.Escrow
+	err = tx.GetContext(ctx, &escrow, `
+		INSERT INTO escrow (order_id, milestone_id, client_id, freelancer_id, amount, currency, status)
+		VALUES ($1, $2, $3, $4, $5, $6, 'held')
+		RETURNING id, order_id, milestone_id, client_id, freelancer_id, amount, currency, status, created_at, released_at
+	`, orderID, milestoneID, clientID, freelancerID, amount, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Транзакция заморозки
+	if err := insertTransaction(ctx, tx, clientID, &orderID, milestoneID, models.TransactionTypeEscrowHold, amount, currency, description); err != nil {
+		return nil, err
+	}
+
+	err = postLedger(ctx, tx, escrowJournal(models.LedgerJournalEscrowHold, &escrow, description),
+		userLeg(models.LedgerAccountUserAvailable, clientID, -amount),
+		platformLeg(models.LedgerAccountPlatformEscrow, amount),
+	)
 	if err != nil {
 		return nil, err
 	}
 
+	return &escrow, nil
+}
+
+// releaseHeldEscrow снимает заморозку у клиента и начисляет средства фрилансеру за вычетом комиссии.
+func (r *PaymentRepository) releaseHeldEscrow(ctx context.Context, tx *sqlx.Tx, escrow *models.Escrow, description string) error {
+	// Снимаем заморозку у клиента
+	_, err := tx.ExecContext(ctx, `
+		UPDATE user_balances SET frozen = frozen - $2, updated_at = NOW()
+		WHERE user_id = $1 AND currency = $3
+	`, escrow.ClientID, escrow.Amount, escrow.Currency)
+	if err != nil {
+		return err
+	}
+
+	// Начисляем фрилансеру
+	fee, err := r.payFreelancer(ctx, tx, escrow, escrow.Amount, description)
+	if err != nil {
+		return err
+	}
+
+	// Обновляем escrow
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `UPDATE escrow SET status = 'released', released_at = $2 WHERE id = $1`, escrow.ID, now)
+	if err != nil {
+		return err
+	}
+	escrow.Status = models.EscrowStatusReleased
+	escrow.ReleasedAt = &now
+
+	return postLedger(ctx, tx, escrowJournal(models.LedgerJournalEscrowRelease, escrow, description),
+		platformLeg(models.LedgerAccountPlatformEscrow, -escrow.Amount),
+		userLeg(models.LedgerAccountUserAvailable, escrow.FreelancerID, escrow.Amount-fee),
+		platformLeg(models.LedgerAccountPlatformFees, fee),
+	)
+}
+
+// payFreelancer начисляет фрилансеру выплату по escrow за вычетом комиссии платформы,
+// записывает транзакции escrow_release (вся сумма) и platform_fee (комиссия). Возвращает комиссию.
+func (r *PaymentRepository) payFreelancer(ctx context.Context, tx *sqlx.Tx, escrow *models.Escrow, amount valueobject.Amount, description string) (valueobject.Amount, error) {
+	fee, err := freelancerFee(ctx, tx, escrow, amount)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_balances (user_id, currency, available, frozen)
+		VALUES ($1, $3, $2, 0)
+		ON CONFLICT (user_id, currency) DO UPDATE SET available = user_balances.available + $2, updated_at = NOW()
+	`, escrow.FreelancerID, amount-fee, escrow.Currency)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := insertTransaction(ctx, tx, escrow.FreelancerID, &escrow.OrderID, escrow.MilestoneID, models.TransactionTypeEscrowRelease, amount, escrow.Currency, description); err != nil {
+		return 0, err
+	}
+	if fee > 0 {
+		if err := insertTransaction(ctx, tx, escrow.FreelancerID, &escrow.OrderID, escrow.MilestoneID, models.TransactionTypePlatformFee, fee, escrow.Currency, "Комиссия платформы"); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE escrow SET fee_amount = $2 WHERE id = $1`, escrow.ID, fee); err != nil {
+		return 0, fmt.Errorf("payment repository: escrow fee %w", err)
+	}
+	escrow.FeeAmount = fee
+	return fee, nil
+}
+
+// refundHeldEscrow возвращает замороженные средства клиенту.
+func (r *PaymentRepository) refundHeldEscrow(ctx context.Context, tx *sqlx.Tx, escrow *models.Escrow, description string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE user_balances SET available = available + $2, frozen = frozen - $2, updated_at = NOW()
+		WHERE user_id = $1 AND currency = $3
+	`, escrow.ClientID, escrow.Amount, escrow.Currency)
+	if err != nil {
+		return err
+	}
+
 	now := time.Now()
 	_, err = tx.ExecContext(ctx, `UPDATE escrow SET status = 'refunded', released_at = $2 WHERE id = $1`, escrow.ID, now)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	escrow.Status = models.EscrowStatusRefunded
 	escrow.ReleasedAt = &now
 
 	// Транзакция возврата
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO transactions (user_id, order_id, type, amount, status, description, completed_at)
-		VALUES ($1, $2, 'escrow_refund', $3, 'completed', 'Возврат средств за отменённый заказ', NOW())
-	`, escrow.ClientID, orderID, escrow.Amount)
+	if err := insertTransaction(ctx, tx, escrow.ClientID, &escrow.OrderID, escrow.MilestoneID, models.TransactionTypeEscrowRefund, escrow.Amount, escrow.Currency, description); err != nil {
+		return err
+	}
+
+	return postLedger(ctx, tx, escrowJournal(models.LedgerJournalEscrowRefund, escrow, description),
+		platformLeg(models.LedgerAccountPlatformEscrow, -escrow.Amount),
+		userLeg(models.LedgerAccountUserAvailable, escrow.ClientID, escrow.Amount),
+	)
+}
+
+// escrowJournal описывает операцию по escrow для журнала двойной записи.
+func escrowJournal(kind string, escrow *models.Escrow, description string) ledgerJournal {
+	return ledgerJournal{Kind: kind, Currency: escrow.Currency, OrderID: &escrow.OrderID, EscrowID: &escrow.ID, Description: description}
+}
+
+// insertTransaction записывает завершённую финансовую операцию.
+func insertTransaction(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, orderID, milestoneID *uuid.UUID, txType string, amount valueobject.Amount, currency, description string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO transactions (user_id, order_id, milestone_id, type, amount, currency, status, description, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 'completed', $7, NOW())
+	`, userID, orderID, milestoneID, txType, amount, currency, description)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("payment repository: insert transaction %w", err)
 	}
+	return nil
+}
 
-	return &escrow, tx.Commit()
+// lockOrder блокирует строку заказа до конца транзакции, чтобы общий escrow заказа
+// и escrow этапов не создавались одновременно.
+func lockOrder(ctx context.Context, tx *sqlx.Tx, orderID uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM orders WHERE id = $1 FOR UPDATE`, orderID); err != nil {
+		return fmt.Errorf("payment repository: lock order %w", err)
+	}
+	return nil
 }
 
 // GetEscrowByOrderID возвращает escrow по ID заказа.
 func (r *PaymentRepository) GetEscrowByOrderID(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error) {
 	var escrow models.Escrow
-	err := r.db.GetContext(ctx, &escrow, `SELECT * FROM escrow WHERE order_id = $1`, orderID)
+	err := r.db.GetContext(ctx, &escrow, `SELECT * FROM escrow WHERE order_id = $1 AND milestone_id IS NULL`, orderID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrEscrowNotFound
@@ -246,7 +609,7 @@ func (r *PaymentRepository) GetEscrowByOrderID(ctx context.Context, orderID uuid
 func (r *PaymentRepository) ListTransactions(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Transaction, error) {
 	var transactions []models.Transaction
 	err := r.db.SelectContext(ctx, &transactions, `
-		SELECT id, user_id, order_id, type, amount, status, description, created_at, completed_at
+		SELECT id, user_id, order_id, milestone_id, type, amount, currency, status, description, created_at, completed_at
 		FROM transactions WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3
 	`, userID, limit, offset)
 	return transactions, err
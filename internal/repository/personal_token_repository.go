@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+// ErrPersonalTokenNotFound возвращается для неизвестного, отозванного или истёкшего токена.
+var ErrPersonalTokenNotFound = errors.New("personal access token not found")
+
+const personalTokenColumns = `id, user_id, name, token_hash, token_prefix, scopes, expires_at, last_used_at, last_used_ip, revoked_at, created_at`
+
+// PersonalTokenRepository отвечает за таблицу personal_access_tokens.
+type PersonalTokenRepository struct {
+	db *sqlx.DB
+}
+
+// NewPersonalTokenRepository создаёт экземпляр репозитория.
+func NewPersonalTokenRepository(db *sqlx.DB) *PersonalTokenRepository {
+	return &PersonalTokenRepository{db: db}
+}
+
+// CreateToken сохраняет хеш нового токена.
+func (r *PersonalTokenRepository) CreateToken(ctx context.Context, token *models.PersonalAccessToken) error {
+	if err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+personalTokenColumns,
+		token.UserID, token.Name, token.TokenHash, token.TokenPrefix, token.Scopes, token.ExpiresAt,
+	).StructScan(token); err != nil {
+		return fmt.Errorf("personal token repository: create %w", err)
+	}
+	return nil
+}
+
+// CountActiveTokens возвращает число неотозванных и неистёкших токенов пользователя.
+func (r *PersonalTokenRepository) CountActiveTokens(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, `
+		SELECT COUNT(*) FROM personal_access_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	`, userID); err != nil {
+		return 0, fmt.Errorf("personal token repository: count active %w", err)
+	}
+	return count, nil
+}
+
+// ListTokens возвращает неотозванные токены пользователя, включая истёкшие.
+func (r *PersonalTokenRepository) ListTokens(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	tokens := []models.PersonalAccessToken{}
+	if err := r.db.SelectContext(ctx, &tokens, `
+		SELECT `+personalTokenColumns+` FROM personal_access_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`, userID); err != nil {
+		return nil, fmt.Errorf("personal token repository: list %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeToken отзывает токен пользователя.
+func (r *PersonalTokenRepository) RevokeToken(ctx context.Context, userID, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE personal_access_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return fmt.Errorf("personal token repository: revoke %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrPersonalTokenNotFound
+	}
+	return nil
+}
+
+// UseToken находит действующий токен по хешу, запоминает время и IP запроса и возвращает
+// токен вместе с текущей ролью владельца.
+func (r *PersonalTokenRepository) UseToken(ctx context.Context, tokenHash string, ip *string) (*models.PersonalAccessToken, string, error) {
+	var row struct {
+		models.PersonalAccessToken
+		Role string `db:"role"`
+	}
+	err := r.db.GetContext(ctx, &row, `
+		UPDATE personal_access_tokens t
+		SET last_used_at = NOW(), last_used_ip = COALESCE($2::inet, t.last_used_ip)
+		FROM users u
+		WHERE t.token_hash = $1 AND u.id = t.user_id
+			AND t.revoked_at IS NULL AND (t.expires_at IS NULL OR t.expires_at > NOW())
+		RETURNING t.id, t.user_id, t.name, t.token_hash, t.token_prefix, t.scopes, t.expires_at,
+			t.last_used_at, t.last_used_ip, t.revoked_at, t.created_at, u.role
+	`, tokenHash, ip)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrPersonalTokenNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("personal token repository: use %w", err)
+	}
+	return &row.PersonalAccessToken, row.Role, nil
+}
@@ -0,0 +1,416 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+var (
+	// ErrDataExportNotFound возвращается, если выгрузки нет или она принадлежит другому пользователю.
+	ErrDataExportNotFound = errors.New("data export not found")
+	// ErrDataExportInProgress возвращается при новой выгрузке, пока предыдущая ещё собирается.
+	ErrDataExportInProgress = errors.New("data export already in progress")
+	// ErrAccountDeletionNotScheduled возвращается при отмене удаления, которое не запрошено.
+	ErrAccountDeletionNotScheduled = errors.New("account deletion not scheduled")
+	// ErrAccountDeletionBlocked возвращается, если к моменту удаления у пользователя появились
+	// незавершённые сделки, споры, выводы или деньги на балансе.
+	ErrAccountDeletionBlocked = errors.New("account deletion blocked")
+)
+
+const dataExportColumns = `id, user_id, status, file_path, file_size, processing_started_at, completed_at, expires_at, created_at`
+
+// exportSections — содержимое архива: имя JSON файла и запрос, собирающий его по user_id ($1).
+var exportSections = map[string]string{
+	"profile": `
+		SELECT jsonb_build_object(
+			'user', (SELECT to_jsonb(u) - 'password_hash' FROM users u WHERE u.id = $1),
+			'profile', (SELECT to_jsonb(p) FROM profiles p WHERE p.user_id = $1),
+			'portfolio', (SELECT COALESCE(jsonb_agg(to_jsonb(i) ORDER BY i.created_at), '[]') FROM portfolio_items i WHERE i.user_id = $1)
+		)`,
+	"orders": `
+		SELECT COALESCE(jsonb_agg(to_jsonb(o) ORDER BY o.created_at), '[]')
+		FROM orders o WHERE o.client_id = $1 OR o.freelancer_id = $1`,
+	"proposals": `
+		SELECT COALESCE(jsonb_agg(to_jsonb(p) ORDER BY p.created_at), '[]')
+		FROM proposals p WHERE p.freelancer_id = $1`,
+	"messages": `
+		SELECT COALESCE(jsonb_agg(to_jsonb(m) ORDER BY m.created_at), '[]')
+		FROM messages m WHERE m.author_id = $1`,
+	"reviews": `
+		SELECT jsonb_build_object(
+			'written', (SELECT COALESCE(jsonb_agg(to_jsonb(r) ORDER BY r.created_at), '[]') FROM reviews r WHERE r.reviewer_id = $1),
+			'received', (SELECT COALESCE(jsonb_agg(to_jsonb(r) ORDER BY r.created_at), '[]') FROM reviews r WHERE r.reviewed_id = $1)
+		)`,
+	"transactions": `
+		SELECT jsonb_build_object(
+			'transactions', (SELECT COALESCE(jsonb_agg(to_jsonb(t) ORDER BY t.created_at), '[]') FROM transactions t WHERE t.user_id = $1),
+			'withdrawals', (SELECT COALESCE(jsonb_agg(to_jsonb(w) ORDER BY w.created_at), '[]') FROM withdrawals w WHERE w.user_id = $1),
+			'balances', (SELECT COALESCE(jsonb_agg(to_jsonb(b) ORDER BY b.currency), '[]') FROM user_balances b WHERE b.user_id = $1)
+		)`,
+	"notifications": `
+		SELECT COALESCE(jsonb_agg(to_jsonb(n) ORDER BY n.created_at), '[]')
+		FROM notifications n WHERE n.user_id = $1`,
+	"notification_settings": `
+		SELECT jsonb_build_object(
+			'settings', (SELECT to_jsonb(s) - 'user_id' FROM notification_settings s WHERE s.user_id = $1),
+			'preferences', (SELECT COALESCE(jsonb_agg(jsonb_build_object('event_type', p.event_type, 'channel', p.channel) ORDER BY p.event_type), '[]') FROM notification_preferences p WHERE p.user_id = $1)
+		)`,
+}
+
+// deletionBlockersQuery перечисляет причины, по которым аккаунт $1 пока нельзя удалить.
+const deletionBlockersQuery = `
+	SELECT blocker FROM (
+		SELECT 'escrow_held' AS blocker WHERE EXISTS (
+			SELECT 1 FROM escrow WHERE (client_id = $1 OR freelancer_id = $1) AND status IN ('held', 'disputed'))
+		UNION ALL
+		SELECT 'dispute_open' WHERE EXISTS (
+			SELECT 1 FROM disputes d JOIN escrow e ON e.id = d.escrow_id
+			WHERE (e.client_id = $1 OR e.freelancer_id = $1) AND d.status IN ('open', 'under_review'))
+		UNION ALL
+		SELECT 'withdrawal_pending' WHERE EXISTS (
+			SELECT 1 FROM withdrawals WHERE user_id = $1 AND status IN ('pending', 'processing'))
+		UNION ALL
+		SELECT 'balance_not_empty' WHERE EXISTS (
+			SELECT 1 FROM user_balances WHERE user_id = $1 AND (available <> 0 OR frozen <> 0))
+	) b`
+
+// AnonymizedAccount — то, что осталось убрать после обезличивания аккаунта в базе. Media — только
+// удалённые из базы файлы; вложения, которые видят другие участники, сохраняются.
+type AnonymizedAccount struct {
+	Email       string
+	Username    string
+	Media       []models.MediaFile
+	ExportPaths []string
+}
+
+// PrivacyRepository отвечает за выгрузку данных пользователя и удаление аккаунта.
+type PrivacyRepository struct {
+	db *sqlx.DB
+}
+
+// NewPrivacyRepository создаёт экземпляр репозитория.
+func NewPrivacyRepository(db *sqlx.DB) *PrivacyRepository {
+	return &PrivacyRepository{db: db}
+}
+
+// CreateExport ставит выгрузку в очередь.
+func (r *PrivacyRepository) CreateExport(ctx context.Context, userID uuid.UUID) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.GetContext(ctx, &export, `
+		INSERT INTO data_exports (user_id) VALUES ($1)
+		RETURNING `+dataExportColumns, userID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrDataExportInProgress
+		}
+		return nil, fmt.Errorf("privacy repository: create export %w", err)
+	}
+	return &export, nil
+}
+
+// ListExports возвращает выгрузки пользователя, новые первыми.
+func (r *PrivacyRepository) ListExports(ctx context.Context, userID uuid.UUID) ([]models.DataExport, error) {
+	exports := []models.DataExport{}
+	if err := r.db.SelectContext(ctx, &exports, `
+		SELECT `+dataExportColumns+` FROM data_exports
+		WHERE user_id = $1 ORDER BY created_at DESC LIMIT 20
+	`, userID); err != nil {
+		return nil, fmt.Errorf("privacy repository: list exports %w", err)
+	}
+	return exports, nil
+}
+
+// GetExport возвращает выгрузку пользователя.
+func (r *PrivacyRepository) GetExport(ctx context.Context, userID, id uuid.UUID) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.GetContext(ctx, &export, `
+		SELECT `+dataExportColumns+` FROM data_exports WHERE id = $1 AND user_id = $2
+	`, id, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDataExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("privacy repository: get export %w", err)
+	}
+	return &export, nil
+}
+
+// ClaimPendingExports переводит до limit выгрузок в processing. Выгрузки, зависшие
+// в processing дольше staleAfter, забираются повторно.
+func (r *PrivacyRepository) ClaimPendingExports(ctx context.Context, limit int, staleAfter time.Duration) ([]models.DataExport, error) {
+	exports := []models.DataExport{}
+	err := r.db.SelectContext(ctx, &exports, `
+		UPDATE data_exports SET status = 'processing', processing_started_at = NOW()
+		WHERE id IN (
+			SELECT id FROM data_exports
+			WHERE status = 'pending'
+				OR (status = 'processing' AND processing_started_at < NOW() - $2 * INTERVAL '1 second')
+			ORDER BY created_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+dataExportColumns, limit, staleAfter.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("privacy repository: claim exports %w", err)
+	}
+	return exports, nil
+}
+
+// CompleteExport отмечает архив готовым к скачиванию до expiresAt.
+func (r *PrivacyRepository) CompleteExport(ctx context.Context, id uuid.UUID, filePath string, fileSize int64, expiresAt time.Time) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.GetContext(ctx, &export, `
+		UPDATE data_exports
+		SET status = 'ready', file_path = $2, file_size = $3, completed_at = NOW(), expires_at = $4
+		WHERE id = $1 AND status = 'processing'
+		RETURNING `+dataExportColumns, id, filePath, fileSize, expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDataExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("privacy repository: complete export %w", err)
+	}
+	return &export, nil
+}
+
+// FailExport отмечает выгрузку неудавшейся; пользователь может запросить новую.
+func (r *PrivacyRepository) FailExport(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE data_exports SET status = 'failed', completed_at = NOW()
+		WHERE id = $1 AND status = 'processing'
+	`, id); err != nil {
+		return fmt.Errorf("privacy repository: fail export %w", err)
+	}
+	return nil
+}
+
+// ListExpiredExports возвращает готовые архивы с истёкшим сроком хранения.
+func (r *PrivacyRepository) ListExpiredExports(ctx context.Context, limit int) ([]models.DataExport, error) {
+	exports := []models.DataExport{}
+	if err := r.db.SelectContext(ctx, &exports, `
+		SELECT `+dataExportColumns+` FROM data_exports
+		WHERE status = 'ready' AND expires_at <= NOW()
+		ORDER BY expires_at LIMIT $1
+	`, limit); err != nil {
+		return nil, fmt.Errorf("privacy repository: list expired exports %w", err)
+	}
+	return exports, nil
+}
+
+// ExpireExport отмечает архив удалённым из хранилища.
+func (r *PrivacyRepository) ExpireExport(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE data_exports SET status = 'expired', file_path = NULL WHERE id = $1 AND status = 'ready'
+	`, id); err != nil {
+		return fmt.Errorf("privacy repository: expire export %w", err)
+	}
+	return nil
+}
+
+// ExportData собирает данные пользователя для архива: имя файла → JSON.
+func (r *PrivacyRepository) ExportData(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error) {
+	data := make(map[string]json.RawMessage, len(exportSections))
+	for name, query := range exportSections {
+		var section []byte
+		if err := r.db.GetContext(ctx, &section, query, userID); err != nil {
+			return nil, fmt.Errorf("privacy repository: export %s %w", name, err)
+		}
+		data[name] = section
+	}
+	return data, nil
+}
+
+// ListUserMedia возвращает файлы, загруженные пользователем.
+func (r *PrivacyRepository) ListUserMedia(ctx context.Context, userID uuid.UUID) ([]models.MediaFile, error) {
+	media := []models.MediaFile{}
+	if err := r.db.SelectContext(ctx, &media, `
+		SELECT id, user_id, file_path, file_type, file_size, is_public, created_at
+		FROM media_files WHERE user_id = $1 ORDER BY created_at
+	`, userID); err != nil {
+		return nil, fmt.Errorf("privacy repository: list media %w", err)
+	}
+	return media, nil
+}
+
+// DeletionBlockers возвращает причины, по которым аккаунт пока нельзя удалить.
+func (r *PrivacyRepository) DeletionBlockers(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	blockers := []string{}
+	if err := r.db.SelectContext(ctx, &blockers, deletionBlockersQuery, userID); err != nil {
+		return nil, fmt.Errorf("privacy repository: deletion blockers %w", err)
+	}
+	return blockers, nil
+}
+
+// GetDeletionSchedule возвращает запланированное время удаления аккаунта или nil.
+func (r *PrivacyRepository) GetDeletionSchedule(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	var scheduledAt *time.Time
+	err := r.db.QueryRowContext(ctx, `SELECT deletion_scheduled_at FROM users WHERE id = $1`, userID).Scan(&scheduledAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("privacy repository: get deletion schedule %w", err)
+	}
+	return scheduledAt, nil
+}
+
+// ScheduleDeletion планирует удаление аккаунта на at. Повторный запрос не переносит уже
+// назначенную дату. Возвращает дату удаления и email для уведомления.
+func (r *PrivacyRepository) ScheduleDeletion(ctx context.Context, userID uuid.UUID, at time.Time) (time.Time, string, error) {
+	var row struct {
+		ScheduledAt time.Time `db:"deletion_scheduled_at"`
+		Email       string    `db:"email"`
+	}
+	err := r.db.GetContext(ctx, &row, `
+		UPDATE users SET deletion_scheduled_at = COALESCE(deletion_scheduled_at, $2)
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING deletion_scheduled_at, email
+	`, userID, at)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, "", ErrUserNotFound
+	}
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("privacy repository: schedule deletion %w", err)
+	}
+	return row.ScheduledAt, row.Email, nil
+}
+
+// CancelDeletion отменяет запланированное удаление аккаунта.
+func (r *PrivacyRepository) CancelDeletion(ctx context.Context, userID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE users SET deletion_scheduled_at = NULL
+		WHERE id = $1 AND deletion_scheduled_at IS NOT NULL AND deleted_at IS NULL
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("privacy repository: cancel deletion %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrAccountDeletionNotScheduled
+	}
+	return nil
+}
+
+// ListDueDeletions возвращает до limit аккаунтов, у которых истёк период отмены удаления.
+func (r *PrivacyRepository) ListDueDeletions(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+	if err := r.db.SelectContext(ctx, &ids, `
+		SELECT id FROM users
+		WHERE deletion_scheduled_at <= NOW() AND deleted_at IS NULL
+		ORDER BY deletion_scheduled_at LIMIT $1
+	`, limit); err != nil {
+		return nil, fmt.Errorf("privacy repository: list due deletions %w", err)
+	}
+	return ids, nil
+}
+
+// AnonymizeUser обезличивает аккаунт, у которого истёк период отмены удаления.
+// Строка пользователя остаётся, чтобы заказы, чаты, отзывы и журнал проводок второй стороны
+// не потеряли связей, но email, имя, пароль и профиль стираются, а личные данные —
+// сессии, токены, уведомления, портфолио, файлы, заявки KYC — удаляются. Открытые заказы
+// отменяются, неразобранные отклики удаляются. Файлы из хранилища удаляет вызывающий код.
+func (r *PrivacyRepository) AnonymizeUser(ctx context.Context, userID uuid.UUID) (*AnonymizedAccount, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("privacy repository: begin anonymize %w", err)
+	}
+	defer tx.Rollback()
+
+	account := &AnonymizedAccount{}
+	err = tx.QueryRowxContext(ctx, `
+		SELECT email, username FROM users
+		WHERE id = $1 AND deletion_scheduled_at <= NOW() AND deleted_at IS NULL
+		FOR UPDATE
+	`, userID).Scan(&account.Email, &account.Username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAccountDeletionNotScheduled
+	}
+	if err != nil {
+		return nil, fmt.Errorf("privacy repository: lock user %w", err)
+	}
+
+	var blockers []string
+	if err := tx.SelectContext(ctx, &blockers, deletionBlockersQuery, userID); err != nil {
+		return nil, fmt.Errorf("privacy repository: deletion blockers %w", err)
+	}
+	if len(blockers) > 0 {
+		return nil, ErrAccountDeletionBlocked
+	}
+
+	if err := tx.SelectContext(ctx, &account.ExportPaths, `
+		SELECT file_path FROM data_exports WHERE user_id = $1 AND file_path IS NOT NULL
+	`, userID); err != nil {
+		return nil, fmt.Errorf("privacy repository: list exports %w", err)
+	}
+
+	statements := []string{
+		`UPDATE orders SET status = 'cancelled' WHERE client_id = $1 AND status IN ('draft', 'published')`,
+		`DELETE FROM proposals WHERE freelancer_id = $1 AND status IN ('pending', 'shortlisted')`,
+		`UPDATE order_history SET user_id = NULL WHERE user_id = $1`,
+		`DELETE FROM user_sessions WHERE user_id = $1`,
+		`DELETE FROM user_action_tokens WHERE user_id = $1`,
+		`DELETE FROM verification_codes WHERE user_id = $1`,
+		`DELETE FROM telegram_contacts WHERE chat_id::text = (SELECT TRIM(telegram) FROM profiles WHERE user_id = $1)`,
+		`DELETE FROM user_totp WHERE user_id = $1`,
+		`DELETE FROM user_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM user_identities WHERE user_id = $1`,
+		`DELETE FROM login_attempts WHERE user_id = $1`,
+		`DELETE FROM personal_access_tokens WHERE user_id = $1`,
+		`DELETE FROM kyc_submissions WHERE user_id = $1`,
+		`DELETE FROM notifications WHERE user_id = $1`,
+		`DELETE FROM notification_preferences WHERE user_id = $1`,
+		`DELETE FROM notification_settings WHERE user_id = $1`,
+		`DELETE FROM notification_deferred WHERE user_id = $1`,
+		`DELETE FROM favorites WHERE user_id = $1`,
+		`DELETE FROM proposal_templates WHERE user_id = $1`,
+		`DELETE FROM portfolio_items WHERE user_id = $1`,
+		`DELETE FROM ai_sessions WHERE user_id = $1`,
+		`DELETE FROM idempotency_keys WHERE user_id = $1`,
+		`DELETE FROM data_exports WHERE user_id = $1`,
+		`UPDATE profiles SET display_name = 'Удалённый пользователь', bio = NULL, hourly_rate = NULL,
+			skills = '{}', location = NULL, photo_id = NULL, ai_summary = NULL, phone = NULL,
+			telegram = NULL, website = NULL, company_name = NULL, inn = NULL, updated_at = NOW()
+		WHERE user_id = $1`,
+		`UPDATE users SET email = 'deleted-' || id || '@deleted.invalid', username = 'deleted-' || id,
+			password_hash = '', is_active = FALSE, email_verified = FALSE, phone_verified = FALSE,
+			identity_verified = FALSE, deletion_scheduled_at = NULL, deleted_at = NOW()
+		WHERE id = $1`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement, userID); err != nil {
+			return nil, fmt.Errorf("privacy repository: anonymize %w", err)
+		}
+	}
+
+	// Вложения в чатах, заказах и доказательства по спорам остаются у других участников:
+	// каскадное удаление убрало бы их из чужих переписок и заказов. Такие файлы только
+	// отвязываются от аккаунта, удаляются остальные
+	if err := tx.SelectContext(ctx, &account.Media, `
+		DELETE FROM media_files m
+		WHERE m.user_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM message_attachments a WHERE a.media_id = m.id)
+		  AND NOT EXISTS (SELECT 1 FROM order_attachments a WHERE a.media_id = m.id)
+		  AND NOT EXISTS (SELECT 1 FROM dispute_evidence e WHERE e.media_id = m.id)
+		RETURNING id, user_id, file_path, file_type, file_size, is_public, created_at
+	`, userID); err != nil {
+		return nil, fmt.Errorf("privacy repository: delete media %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE media_files SET user_id = NULL WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("privacy repository: detach media %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("privacy repository: commit anonymize %w", err)
+	}
+	return account, nil
+}
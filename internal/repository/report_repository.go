@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -11,7 +13,63 @@ import (
 	"github.com/ignatzorin/freelance-backend/internal/models"
 )
 
-var ErrReportNotFound = errors.New("report not found")
+var (
+	ErrReportNotFound          = errors.New("report not found")
+	ErrNoPendingReports        = errors.New("no pending reports for this target")
+	ErrReportTargetNotFound    = errors.New("report target not found")
+	ErrReportActionUnavailable = errors.New("moderation action is not applicable to the target")
+)
+
+// reportPreviewQueries выбирают краткое представление объекта жалобы.
+var reportPreviewQueries = map[string]string{
+	models.ReportTargetUser: `
+		SELECT row_to_json(t) FROM (
+			SELECT u.id, u.username, u.role, u.is_active, u.created_at, p.display_name
+			FROM users u LEFT JOIN profiles p ON p.user_id = u.id
+			WHERE u.id = $1
+		) t`,
+	models.ReportTargetOrder: `
+		SELECT row_to_json(t) FROM (
+			SELECT id, client_id, title, LEFT(description, 500) AS description, status, created_at
+			FROM orders WHERE id = $1
+		) t`,
+	models.ReportTargetMessage: `
+		SELECT row_to_json(t) FROM (
+			SELECT m.id, m.conversation_id, c.order_id, m.author_id, m.content, m.hidden_at, m.created_at
+			FROM messages m JOIN conversations c ON c.id = m.conversation_id
+			WHERE m.id = $1
+		) t`,
+	models.ReportTargetReview: `
+		SELECT row_to_json(t) FROM (
+			SELECT id, order_id, reviewer_id, reviewed_id, rating, comment, created_at
+			FROM reviews WHERE id = $1
+		) t`,
+}
+
+// reportActionQueries применяют меру модератора к объекту жалобы.
+var reportActionQueries = map[string]string{
+	models.ReportActionHideMessage: `
+		UPDATE messages SET hidden_at = NOW(), hidden_by = $2 WHERE id = $1 AND hidden_at IS NULL`,
+	models.ReportActionUnpublishOrder: `
+		UPDATE orders SET status = 'draft' WHERE id = $1 AND status = 'published'`,
+	models.ReportActionRemoveReview: `
+		DELETE FROM reviews WHERE id = $1`,
+	// Администратора заблокировать нельзя, модератора — только администратору
+	models.ReportActionSuspendUser: `
+		UPDATE users SET is_active = FALSE
+		WHERE id = $1 AND (
+			role NOT IN ('admin', 'moderator')
+			OR (role = 'moderator' AND EXISTS (SELECT 1 FROM users WHERE id = $2 AND role = 'admin'))
+		)`,
+}
+
+// reportTargetTables таблицы, в которых хранятся объекты жалоб.
+var reportTargetTables = map[string]string{
+	models.ReportTargetUser:    "users",
+	models.ReportTargetOrder:   "orders",
+	models.ReportTargetMessage: "messages",
+	models.ReportTargetReview:  "reviews",
+}
 
 type ReportRepository struct {
 	db *sqlx.DB
@@ -54,3 +112,121 @@ func (r *ReportRepository) ListPending(ctx context.Context, limit, offset int) (
 	`, limit, offset)
 	return reports, err
 }
+
+// ListPendingTargets возвращает объекты с необработанными жалобами:
+// сначала те, на которые жалуются чаще, затем самые давние.
+func (r *ReportRepository) ListPendingTargets(ctx context.Context, limit, offset int) ([]models.ReportTarget, error) {
+	targets := []models.ReportTarget{}
+	err := r.db.SelectContext(ctx, &targets, `
+		SELECT target_type, target_id, COUNT(*) AS report_count,
+			ARRAY_AGG(DISTINCT reason) AS reasons,
+			MIN(created_at) AS first_reported_at, MAX(created_at) AS last_reported_at
+		FROM reports
+		WHERE status = 'pending'
+		GROUP BY target_type, target_id
+		ORDER BY report_count DESC, first_reported_at ASC
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("report repository: list pending targets %w", err)
+	}
+	return targets, nil
+}
+
+// ListPendingByTarget возвращает необработанные жалобы на объект.
+func (r *ReportRepository) ListPendingByTarget(ctx context.Context, targetType string, targetID uuid.UUID) ([]models.Report, error) {
+	reports := []models.Report{}
+	err := r.db.SelectContext(ctx, &reports, `
+		SELECT * FROM reports
+		WHERE target_type = $1 AND target_id = $2 AND status = 'pending'
+		ORDER BY created_at ASC
+	`, targetType, targetID)
+	return reports, err
+}
+
+// TargetPreview возвращает JSON с кратким описанием объекта жалобы.
+// Если объект уже удалён, возвращается nil.
+func (r *ReportRepository) TargetPreview(ctx context.Context, targetType string, targetID uuid.UUID) (json.RawMessage, error) {
+	query, ok := reportPreviewQueries[targetType]
+	if !ok {
+		return nil, ErrReportTargetNotFound
+	}
+	var preview []byte
+	err := r.db.QueryRowContext(ctx, query, targetID).Scan(&preview)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("report repository: target preview %w", err)
+	}
+	return json.RawMessage(preview), nil
+}
+
+// ResolveTarget закрывает все необработанные жалобы на объект и, если указана мера,
+// применяет её в той же транзакции. Возвращает закрытые жалобы.
+func (r *ReportRepository) ResolveTarget(ctx context.Context, targetType string, targetID, moderatorID uuid.UUID, status string, action, note *string) ([]models.Report, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if action != nil {
+		if err := r.applyAction(ctx, tx, *action, targetType, targetID, moderatorID); err != nil {
+			return nil, err
+		}
+	}
+
+	reports := []models.Report{}
+	err = tx.SelectContext(ctx, &reports, `
+		UPDATE reports SET status = $3, action = $4, moderator_note = $5, reviewed_by = $6, reviewed_at = NOW()
+		WHERE target_type = $1 AND target_id = $2 AND status = 'pending'
+		RETURNING *
+	`, targetType, targetID, status, action, note, moderatorID)
+	if err != nil {
+		return nil, fmt.Errorf("report repository: resolve target %w", err)
+	}
+	if len(reports) == 0 {
+		return nil, ErrNoPendingReports
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (r *ReportRepository) applyAction(ctx context.Context, tx *sqlx.Tx, action, targetType string, targetID, moderatorID uuid.UUID) error {
+	query, ok := reportActionQueries[action]
+	if !ok || models.ReportActionTargets[action] != targetType {
+		return ErrReportActionUnavailable
+	}
+	args := []interface{}{targetID}
+	if action == models.ReportActionHideMessage || action == models.ReportActionSuspendUser {
+		args = append(args, moderatorID)
+	}
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("report repository: apply %s %w", action, err)
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		if action == models.ReportActionSuspendUser {
+			// Отзываем refresh-сессии, чтобы заблокированный пользователь не продлил доступ
+			if _, err := tx.ExecContext(ctx, `DELETE FROM user_sessions WHERE user_id = $1`, targetID); err != nil {
+				return fmt.Errorf("report repository: revoke sessions %w", err)
+			}
+		}
+		return nil
+	}
+
+	// Ничего не изменилось: объект удалён либо мера к нему неприменима
+	var exists bool
+	if err := tx.GetContext(ctx, &exists,
+		`SELECT EXISTS (SELECT 1 FROM `+reportTargetTables[targetType]+` WHERE id = $1)`, targetID); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrReportTargetNotFound
+	}
+	return ErrReportActionUnavailable
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+var (
+	// ErrTOTPNotFound возвращается, если пользователь не начинал подключение TOTP.
+	ErrTOTPNotFound = errors.New("totp not found")
+	// ErrTOTPAlreadyEnabled возвращается при попытке перезаписать секрет подключённого TOTP.
+	ErrTOTPAlreadyEnabled = errors.New("totp already enabled")
+)
+
+type TwoFactorRepository struct {
+	db *sqlx.DB
+}
+
+func NewTwoFactorRepository(db *sqlx.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// GetTOTP возвращает TOTP запись пользователя, в том числе неподтверждённую.
+func (r *TwoFactorRepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*models.UserTOTP, error) {
+	var totp models.UserTOTP
+	err := r.db.GetContext(ctx, &totp, `
+		SELECT user_id, secret, enabled_at, last_used_step, created_at FROM user_totp WHERE user_id = $1
+	`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTOTPNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("two factor repository: get totp %w", err)
+	}
+
+	return &totp, nil
+}
+
+// SaveTOTPSecret сохраняет новый неподтверждённый секрет вместо прежнего неподтверждённого.
+func (r *TwoFactorRepository) SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+		WHERE user_totp.enabled_at IS NULL
+	`, userID, secret)
+	if err != nil {
+		return fmt.Errorf("two factor repository: save totp secret %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+
+	return nil
+}
+
+// EnableTOTP подтверждает TOTP, запоминает использованный интервал и выдаёт новые коды восстановления.
+func (r *TwoFactorRepository) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("two factor repository: enable totp begin %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE user_totp SET enabled_at = NOW(), last_used_step = $2 WHERE user_id = $1 AND enabled_at IS NULL
+	`, userID, step)
+	if err != nil {
+		return fmt.Errorf("two factor repository: enable totp %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep помечает интервал step использованным. Возвращает false, если код этого
+// или более позднего интервала уже принимался: так один код нельзя предъявить дважды.
+func (r *TwoFactorRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE user_totp SET last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NOT NULL AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("two factor repository: use totp step %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("two factor repository: use totp step %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// DeleteTOTP отключает второй фактор и удаляет коды восстановления.
+func (r *TwoFactorRepository) DeleteTOTP(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("two factor repository: delete totp begin %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("two factor repository: delete recovery codes %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("two factor repository: delete totp %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes заменяет все коды восстановления пользователя новыми.
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("two factor repository: replace recovery codes begin %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseRecoveryCode гасит код восстановления. Возвращает false, если кода нет или он уже использован.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE user_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("two factor repository: use recovery code %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("two factor repository: use recovery code %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// CountRecoveryCodes возвращает число неиспользованных кодов восстановления.
+func (r *TwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, `
+		SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID); err != nil {
+		return 0, fmt.Errorf("two factor repository: count recovery codes %w", err)
+	}
+
+	return count, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("two factor repository: delete recovery codes %w", err)
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)
+		`, userID, hash); err != nil {
+			return fmt.Errorf("two factor repository: insert recovery code %w", err)
+		}
+	}
+
+	return nil
+}
@@ -16,7 +16,23 @@ import (
 // ErrUserNotFound возвращается, когда запись пользователя не найдена.
 var ErrUserNotFound = errors.New("user not found")
 
-// UserRepository отвечает за работу с таблицами users, profiles и user_sessions.
+// ErrEmailTaken возвращается, если email уже принадлежит другому пользователю.
+var ErrEmailTaken = errors.New("email already taken")
+
+// ErrUsernameTaken возвращается, если username уже принадлежит другому пользователю.
+var ErrUsernameTaken = errors.New("username already taken")
+
+// ErrActionTokenNotFound возвращается, если ссылка из письма уже использована, отменена или истекла.
+var ErrActionTokenNotFound = errors.New("action token not found")
+
+var (
+	// ErrRefreshTokenNotFound возвращается, если refresh токен не выпускался или его сессия удалена.
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	// ErrRefreshTokenRotated возвращается, если refresh токен уже обменян на новый.
+	ErrRefreshTokenRotated = errors.New("refresh token already rotated")
+)
+
+// UserRepository отвечает за работу с таблицами users, profiles, user_sessions и refresh_tokens.
 type UserRepository struct {
 	db *sqlx.DB
 }
@@ -38,6 +54,10 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 		ctx, query,
 		user.Email, user.Username, user.PasswordHash, user.Role,
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "users_username_key" {
+			return ErrUsernameTaken
+		}
 		return fmt.Errorf("user repository: create %w", err)
 	}
 
@@ -48,7 +68,7 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
 	query := `
-		SELECT id, email, username, password_hash, role, is_active, last_login_at, created_at, updated_at
+		SELECT id, email, username, password_hash, role, is_active, identity_verified, last_login_at, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
@@ -66,7 +86,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	var user models.User
 	query := `
-		SELECT id, email, username, password_hash, role, is_active, last_login_at, created_at, updated_at
+		SELECT id, email, username, password_hash, role, is_active, identity_verified, last_login_at, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -103,6 +123,15 @@ func (r *UserRepository) UpsertProfile(ctx context.Context, profile *models.Prof
 		RETURNING user_id, display_name, bio, hourly_rate, experience_level, skills, location, photo_id, ai_summary, phone, telegram, website, company_name, inn, updated_at
 	`
 
+	// Подтверждение относится к конкретному номеру: смена телефона его сбрасывает
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE users u SET phone_verified = FALSE
+		FROM profiles p
+		WHERE p.user_id = u.id AND u.id = $1 AND u.phone_verified AND p.phone IS DISTINCT FROM $2
+	`, profile.UserID, profile.Phone); err != nil {
+		return fmt.Errorf("user repository: reset phone verification %w", err)
+	}
+
 	var skills pq.StringArray
 	row := r.db.QueryRowxContext(
 		ctx,
@@ -183,19 +212,26 @@ func (r *UserRepository) GetProfile(ctx context.Context, userID uuid.UUID) (*mod
 	return &profile, nil
 }
 
-// CreateSession сохраняет новую сессию пользователя.
-func (r *UserRepository) CreateSession(ctx context.Context, session *models.Session) error {
+// CreateSession сохраняет новую сессию пользователя и её первый refresh токен.
+// Идентификатор сессии задаёт вызывающий: он уже записан в выпущенные токены.
+func (r *UserRepository) CreateSession(ctx context.Context, session *models.Session, refreshTokenHash string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("user repository: create session begin %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO user_sessions (user_id, refresh_token, user_agent, ip_address, expires_at)
+		INSERT INTO user_sessions (id, user_id, user_agent, ip_address, expires_at)
 		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at
 	`
 
-	if err := r.db.QueryRowxContext(
+	if err := tx.QueryRowxContext(
 		ctx,
 		query,
+		session.ID,
 		session.UserID,
-		session.RefreshToken,
 		session.UserAgent,
 		session.IPAddress,
 		session.ExpiresAt,
@@ -203,13 +239,93 @@ func (r *UserRepository) CreateSession(ctx context.Context, session *models.Sess
 		return fmt.Errorf("user repository: create session %w", err)
 	}
 
-	return nil
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (token_hash, session_id, expires_at)
+		VALUES ($1, $2, $3)
+	`, refreshTokenHash, session.ID, session.ExpiresAt); err != nil {
+		return fmt.Errorf("user repository: create refresh token %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetRefreshToken возвращает refresh токен по хешу вместе с владельцем сессии.
+func (r *UserRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.GetContext(ctx, &token, `
+		SELECT t.token_hash, t.session_id, s.user_id, t.expires_at, t.created_at, t.rotated_at
+		FROM refresh_tokens t
+		JOIN user_sessions s ON s.id = t.session_id
+		WHERE t.token_hash = $1
+	`, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("user repository: get refresh token %w", err)
+	}
+
+	return &token, nil
+}
+
+// RotateRefreshToken помечает refresh токен обменянным и выпускает в той же сессии новый.
+// Если токен уже обменян (в том числе параллельным запросом), возвращает ErrRefreshTokenRotated.
+func (r *UserRepository) RotateRefreshToken(ctx context.Context, session *models.Session, oldTokenHash, newTokenHash string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("user repository: rotate refresh token begin %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET rotated_at = NOW()
+		WHERE token_hash = $1 AND session_id = $2 AND rotated_at IS NULL
+	`, oldTokenHash, session.ID)
+	if err != nil {
+		return fmt.Errorf("user repository: rotate refresh token %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("user repository: rotate refresh token rows affected %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrRefreshTokenRotated
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (token_hash, session_id, expires_at)
+		VALUES ($1, $2, $3)
+	`, newTokenHash, session.ID, session.ExpiresAt); err != nil {
+		return fmt.Errorf("user repository: create refresh token %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE user_sessions SET user_agent = COALESCE($2, user_agent), ip_address = COALESCE($3, ip_address), expires_at = $4
+		WHERE id = $1
+	`, session.ID, session.UserAgent, session.IPAddress, session.ExpiresAt); err != nil {
+		return fmt.Errorf("user repository: update session %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// SessionExists проверяет, что сессия пользователя не удалена и не истекла.
+func (r *UserRepository) SessionExists(ctx context.Context, sessionID, userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, `
+		SELECT EXISTS (SELECT 1 FROM user_sessions WHERE id = $1 AND user_id = $2 AND expires_at > NOW())
+	`, sessionID, userID)
+	if err != nil {
+		return false, fmt.Errorf("user repository: session exists %w", err)
+	}
+
+	return exists, nil
 }
 
-// DeleteSession удаляет сессию по refresh токену.
-func (r *UserRepository) DeleteSession(ctx context.Context, refreshToken string) error {
-	if _, err := r.db.ExecContext(ctx, `DELETE FROM user_sessions WHERE refresh_token = $1`, refreshToken); err != nil {
-		return fmt.Errorf("user repository: delete session %w", err)
+// RevokeSession удаляет сессию вместе со всеми её refresh токенами.
+func (r *UserRepository) RevokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM user_sessions WHERE id = $1`, sessionID); err != nil {
+		return fmt.Errorf("user repository: revoke session %w", err)
 	}
 
 	return nil
@@ -227,7 +343,7 @@ func (r *UserRepository) UpdateLastLoginAt(ctx context.Context, userID uuid.UUID
 // ListSessions возвращает список всех активных сессий пользователя.
 func (r *UserRepository) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
 	query := `
-		SELECT id, user_id, refresh_token, user_agent, ip_address, expires_at, created_at
+		SELECT id, user_id, user_agent, ip_address, expires_at, created_at
 		FROM user_sessions
 		WHERE user_id = $1 AND expires_at > NOW()
 		ORDER BY created_at DESC
@@ -260,16 +376,147 @@ func (r *UserRepository) DeleteSessionByID(ctx context.Context, sessionID uuid.U
 	return nil
 }
 
-// DeleteAllSessionsExcept удаляет все сессии пользователя кроме указанной.
-func (r *UserRepository) DeleteAllSessionsExcept(ctx context.Context, userID uuid.UUID, exceptRefreshToken string) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM user_sessions WHERE user_id = $1 AND refresh_token != $2`, userID, exceptRefreshToken)
+// DeleteAllSessionsExcept удаляет все сессии пользователя кроме той, которой принадлежит refresh токен
+// с указанным хешем, и возвращает идентификаторы удалённых сессий.
+func (r *UserRepository) DeleteAllSessionsExcept(ctx context.Context, userID uuid.UUID, exceptRefreshTokenHash string) ([]uuid.UUID, error) {
+	var deleted []uuid.UUID
+	err := r.db.SelectContext(ctx, &deleted, `
+		DELETE FROM user_sessions
+		WHERE user_id = $1 AND id NOT IN (SELECT session_id FROM refresh_tokens WHERE token_hash = $2 AND rotated_at IS NULL)
+		RETURNING id
+	`, userID, exceptRefreshTokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("user repository: delete all sessions except %w", err)
+	}
+
+	return deleted, nil
+}
+
+// DeleteSessionsExcept удаляет все сессии пользователя кроме keepSessionID (uuid.Nil — все сессии)
+// и возвращает идентификаторы удалённых.
+func (r *UserRepository) DeleteSessionsExcept(ctx context.Context, userID, keepSessionID uuid.UUID) ([]uuid.UUID, error) {
+	var deleted []uuid.UUID
+	err := r.db.SelectContext(ctx, &deleted, `
+		DELETE FROM user_sessions WHERE user_id = $1 AND id <> $2 RETURNING id
+	`, userID, keepSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("user repository: delete sessions except %w", err)
+	}
+
+	return deleted, nil
+}
+
+// UpdatePassword заменяет хеш пароля пользователя.
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET password_hash = $2, updated_at = NOW() WHERE id = $1`, userID, passwordHash)
+	if err != nil {
+		return fmt.Errorf("user repository: update password %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// IsEmailVerified сообщает, подтверждён ли email пользователя.
+func (r *UserRepository) IsEmailVerified(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var verified bool
+	if err := r.db.GetContext(ctx, &verified, `SELECT email_verified FROM users WHERE id = $1`, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrUserNotFound
+		}
+		return false, fmt.Errorf("user repository: is email verified %w", err)
+	}
+
+	return verified, nil
+}
+
+// MarkEmailVerified отмечает текущий email пользователя подтверждённым.
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE users SET email_verified = TRUE, updated_at = NOW() WHERE id = $1
+	`, userID); err != nil {
+		return fmt.Errorf("user repository: mark email verified %w", err)
+	}
+
+	return nil
+}
+
+// UpdateEmail меняет email пользователя на подтверждённый адрес.
+func (r *UserRepository) UpdateEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE users SET email = $2, email_verified = TRUE, updated_at = NOW() WHERE id = $1
+	`, userID, email)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrEmailTaken
+		}
+		return fmt.Errorf("user repository: update email %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// CreateActionToken сохраняет одноразовую ссылку; прежние неиспользованные ссылки
+// того же назначения перестают действовать.
+func (r *UserRepository) CreateActionToken(ctx context.Context, token *models.UserActionToken) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("user repository: delete all sessions except %w", err)
+		return fmt.Errorf("user repository: create action token begin %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE user_action_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`, token.UserID, token.Purpose); err != nil {
+		return fmt.Errorf("user repository: expire action tokens %w", err)
+	}
+
+	if err := tx.QueryRowxContext(ctx, `
+		INSERT INTO user_action_tokens (id, user_id, purpose, new_email, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`, token.ID, token.UserID, token.Purpose, token.NewEmail, token.ExpiresAt).Scan(&token.CreatedAt); err != nil {
+		return fmt.Errorf("user repository: create action token %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ExpireActionTokens гасит все неиспользованные ссылки пользователя с назначением purpose.
+func (r *UserRepository) ExpireActionTokens(ctx context.Context, userID uuid.UUID, purpose string) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE user_action_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`, userID, purpose); err != nil {
+		return fmt.Errorf("user repository: expire action tokens %w", err)
 	}
 
 	return nil
 }
 
+// ConsumeActionToken помечает ссылку использованной и возвращает её; повторно ссылка не сработает.
+func (r *UserRepository) ConsumeActionToken(ctx context.Context, id uuid.UUID, purpose string) (*models.UserActionToken, error) {
+	var token models.UserActionToken
+	err := r.db.GetContext(ctx, &token, `
+		UPDATE user_action_tokens SET used_at = NOW()
+		WHERE id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, purpose, new_email, expires_at, used_at, created_at
+	`, id, purpose)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrActionTokenNotFound
+		}
+		return nil, fmt.Errorf("user repository: consume action token %w", err)
+	}
+
+	return &token, nil
+}
+
 // GetReviewsForUser возвращает все отзывы о пользователе.
 func (r *UserRepository) GetReviewsForUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Review, error) {
 	query := `
@@ -352,14 +599,30 @@ func (r *UserRepository) GetUserStats(ctx context.Context, userID uuid.UUID) (*m
 	// Округляем средний рейтинг до 2 знаков после запятой
 	stats.AverageRating = float64(int(stats.AverageRating*100)) / 100
 
-	// Подсчитываем общий заработок фрилансера из освобождённых escrow
+	// Подсчитываем заработок фрилансера по каждой валюте: выплаты escrow и заработок за вычетом комиссии платформы
 	earningsQuery := `
-		SELECT COALESCE(SUM(amount), 0)
-		FROM escrow
-		WHERE freelancer_id = $1 AND status = 'released'
+		SELECT
+			currency,
+			COALESCE(SUM(amount) FILTER (WHERE type = 'escrow_release'), 0) AS gross,
+			COALESCE(SUM(amount) FILTER (WHERE type = 'platform_fee'), 0) AS fees
+		FROM transactions
+		WHERE user_id = $1 AND status = 'completed' AND type IN ('escrow_release', 'platform_fee')
+		GROUP BY currency
+		ORDER BY currency
 	`
-	if err := r.db.GetContext(ctx, &stats.TotalEarnings, earningsQuery, userID); err != nil {
-		stats.TotalEarnings = 0
+	if err := r.db.SelectContext(ctx, &stats.Earnings, earningsQuery, userID); err != nil {
+		return nil, fmt.Errorf("user repository: get earnings %w", err)
+	}
+	for i := range stats.Earnings {
+		e := &stats.Earnings[i]
+		e.NetEarnings = e.TotalEarnings - e.PlatformFees
+		// Итоговые суммы профиля приводятся в валюте по умолчанию
+		if e.Currency == models.DefaultCurrency {
+			stats.TotalEarnings, stats.PlatformFees, stats.NetEarnings = e.TotalEarnings, e.PlatformFees, e.NetEarnings
+		}
+	}
+	if len(stats.Earnings) > 0 {
+		stats.EarningsCurrency = models.DefaultCurrency
 	}
 
 	return stats, nil
@@ -368,7 +631,7 @@ func (r *UserRepository) GetUserStats(ctx context.Context, userID uuid.UUID) (*m
 // ListFreelancers возвращает список всех активных фрилансеров с их профилями.
 func (r *UserRepository) ListFreelancers(ctx context.Context, limit, offset int) ([]*models.User, error) {
 	query := `
-		SELECT u.id, u.email, u.username, u.password_hash, u.role, u.is_active, u.last_login_at, u.created_at, u.updated_at
+		SELECT u.id, u.email, u.username, u.password_hash, u.role, u.is_active, u.identity_verified, u.last_login_at, u.created_at, u.updated_at
 		FROM users u
 		WHERE u.role = 'freelancer' AND u.is_active = TRUE
 		ORDER BY u.created_at DESC
@@ -418,12 +681,7 @@ func (r *UserRepository) GetCompletedOrdersForUser(ctx context.Context, userID u
 // UpdateRole обновляет роль пользователя.
 func (r *UserRepository) UpdateRole(ctx context.Context, userID uuid.UUID, role string) error {
 	// Валидация роли
-	validRoles := map[string]bool{
-		"client":     true,
-		"freelancer": true,
-		"admin":      true,
-	}
-	if !validRoles[role] {
+	if _, ok := models.ValidRoles[role]; !ok {
 		return fmt.Errorf("user repository: invalid role %s", role)
 	}
 
@@ -471,7 +729,7 @@ type FreelancerSearchParams struct {
 func (r *UserRepository) SearchFreelancers(ctx context.Context, params FreelancerSearchParams) ([]*models.FreelancerSearchResult, error) {
 	query := `
 		SELECT 
-			u.id, u.username, u.created_at,
+			u.id, u.username, u.identity_verified, u.created_at,
 			p.display_name, p.bio, p.hourly_rate, p.experience_level, p.skills, p.location, p.photo_id,
 			COALESCE(AVG(rv.rating), 0) as avg_rating,
 			COUNT(rv.id) as review_count
@@ -514,7 +772,7 @@ func (r *UserRepository) SearchFreelancers(ctx context.Context, params Freelance
 		argNum++
 	}
 
-	query += ` GROUP BY u.id, u.username, u.created_at, p.display_name, p.bio, p.hourly_rate, p.experience_level, p.skills, p.location, p.photo_id`
+	query += ` GROUP BY u.id, u.username, u.identity_verified, u.created_at, p.display_name, p.bio, p.hourly_rate, p.experience_level, p.skills, p.location, p.photo_id`
 
 	if params.MinRating != nil {
 		query += fmt.Sprintf(` HAVING COALESCE(AVG(rv.rating), 0) >= $%d`, argNum)
@@ -537,7 +795,7 @@ func (r *UserRepository) SearchFreelancers(ctx context.Context, params Freelance
 		var r models.FreelancerSearchResult
 		var skills pq.StringArray
 		if err := rows.Scan(
-			&r.ID, &r.Username, &r.CreatedAt,
+			&r.ID, &r.Username, &r.IdentityVerified, &r.CreatedAt,
 			&r.DisplayName, &r.Bio, &r.HourlyRate, &r.ExperienceLevel, &skills, &r.Location, &r.PhotoID,
 			&r.AvgRating, &r.ReviewCount,
 		); err != nil {
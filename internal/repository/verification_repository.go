@@ -22,43 +22,164 @@ func NewVerificationRepository(db *sqlx.DB) *VerificationRepository {
 	return &VerificationRepository{db: db}
 }
 
-func (r *VerificationRepository) CreateCode(ctx context.Context, userID uuid.UUID, codeType, code string, expiresAt time.Time) (*models.VerificationCode, error) {
+// CreateCode сохраняет новый код; прежние неиспользованные коды того же типа перестают действовать,
+// чтобы одновременно подбирался только один код.
+func (r *VerificationRepository) CreateCode(ctx context.Context, userID uuid.UUID, codeType, code, channel, destination string, expiresAt time.Time) (*models.VerificationCode, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE verification_codes SET used = true WHERE user_id = $1 AND type = $2 AND used = false
+	`, userID, codeType); err != nil {
+		return nil, err
+	}
+
 	var vc models.VerificationCode
-	err := r.db.GetContext(ctx, &vc, `
-		INSERT INTO verification_codes (user_id, type, code, expires_at)
-		VALUES ($1, $2, $3, $4)
+	err = tx.GetContext(ctx, &vc, `
+		INSERT INTO verification_codes (user_id, type, code, channel, destination, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING *
-	`, userID, codeType, code, expiresAt)
-	return &vc, err
+	`, userID, codeType, code, channel, destination, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vc, tx.Commit()
+}
+
+// CountRecentCodes возвращает число кодов типа codeType, отправленных пользователю после since,
+// и время последней отправки.
+func (r *VerificationRepository) CountRecentCodes(ctx context.Context, userID uuid.UUID, codeType string, since time.Time) (int, *time.Time, error) {
+	var row struct {
+		Count  int        `db:"count"`
+		LastAt *time.Time `db:"last_at"`
+	}
+	err := r.db.GetContext(ctx, &row, `
+		SELECT COUNT(*) AS count, MAX(created_at) AS last_at FROM verification_codes
+		WHERE user_id = $1 AND type = $2 AND created_at > $3
+	`, userID, codeType, since)
+	return row.Count, row.LastAt, err
 }
 
-func (r *VerificationRepository) VerifyCode(ctx context.Context, userID uuid.UUID, codeType, code string) (bool, error) {
+// CountRecentCodesTo возвращает число кодов, отправленных на destination после since любым пользователям.
+func (r *VerificationRepository) CountRecentCodesTo(ctx context.Context, destination string, since time.Time) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `
+		SELECT COUNT(*) FROM verification_codes WHERE destination = $1 AND created_at > $2
+	`, destination, since)
+	return count, err
+}
+
+// GetActiveCode возвращает последний неиспользованный и не истёкший код пользователя.
+func (r *VerificationRepository) GetActiveCode(ctx context.Context, userID uuid.UUID, codeType string) (*models.VerificationCode, error) {
 	var vc models.VerificationCode
 	err := r.db.GetContext(ctx, &vc, `
-		SELECT * FROM verification_codes 
-		WHERE user_id = $1 AND type = $2 AND code = $3 AND used = false AND expires_at > NOW()
+		SELECT * FROM verification_codes
+		WHERE user_id = $1 AND type = $2 AND used = false AND expires_at > NOW()
 		ORDER BY created_at DESC LIMIT 1
-	`, userID, codeType, code)
+	`, userID, codeType)
 	if errors.Is(err, sql.ErrNoRows) {
-		return false, nil
+		return nil, ErrVerificationCodeNotFound
 	}
 	if err != nil {
-		return false, err
+		return nil, err
 	}
+	return &vc, nil
+}
 
-	// Помечаем код как использованный
-	_, err = r.db.ExecContext(ctx, `UPDATE verification_codes SET used = true WHERE id = $1`, vc.ID)
+// RegisterAttempt засчитывает попытку ввода кода. Возвращает false, если лимит maxAttempts
+// уже исчерпан; счётчик увеличивается атомарно, поэтому параллельные запросы не обходят лимит.
+func (r *VerificationRepository) RegisterAttempt(ctx context.Context, codeID uuid.UUID, maxAttempts int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE verification_codes SET attempts = attempts + 1
+		WHERE id = $1 AND used = false AND attempts < $2
+	`, codeID, maxAttempts)
 	if err != nil {
 		return false, err
 	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// InvalidateCode гасит код, например после исчерпания попыток.
+func (r *VerificationRepository) InvalidateCode(ctx context.Context, codeID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE verification_codes SET used = true WHERE id = $1`, codeID)
+	return err
+}
+
+// ConfirmCode гасит код и отмечает email или телефон пользователя подтверждённым.
+func (r *VerificationRepository) ConfirmCode(ctx context.Context, vc *models.VerificationCode) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE verification_codes SET used = true WHERE id = $1 AND used = false`, vc.ID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return ErrVerificationCodeNotFound
+	}
 
-	// Обновляем статус верификации пользователя
 	field := "email_verified"
-	if codeType == models.VerificationTypePhone {
+	if vc.Type == models.VerificationTypePhone {
 		field = "phone_verified"
 	}
-	_, err = r.db.ExecContext(ctx, `UPDATE users SET `+field+` = true WHERE id = $1`, userID)
-	return err == nil, err
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET `+field+` = true WHERE id = $1`, vc.UserID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetUserEmail возвращает адрес, на который отправляется код подтверждения.
+func (r *VerificationRepository) GetUserEmail(ctx context.Context, userID uuid.UUID) (string, error) {
+	var email string
+	err := r.db.GetContext(ctx, &email, `SELECT email FROM users WHERE id = $1`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUserNotFound
+	}
+	return email, err
+}
+
+// GetUserContacts возвращает телефон и Telegram из профиля пользователя.
+func (r *VerificationRepository) GetUserContacts(ctx context.Context, userID uuid.UUID) (phone, telegram *string, err error) {
+	err = r.db.QueryRowContext(ctx, `
+		SELECT phone, telegram FROM profiles WHERE user_id = $1
+	`, userID).Scan(&phone, &telegram)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, nil
+	}
+	return phone, telegram, err
+}
+
+// SaveTelegramContact запоминает номер, которым владелец чата chatID поделился с ботом.
+func (r *VerificationRepository) SaveTelegramContact(ctx context.Context, chatID int64, phone string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO telegram_contacts (chat_id, phone) VALUES ($1, $2)
+		ON CONFLICT (chat_id) DO UPDATE SET phone = EXCLUDED.phone, shared_at = NOW()
+	`, chatID, phone)
+	return err
+}
+
+// GetTelegramPhone возвращает номер, которым владелец чата chatID поделился с ботом, или nil.
+func (r *VerificationRepository) GetTelegramPhone(ctx context.Context, chatID int64) (*string, error) {
+	var phone string
+	err := r.db.GetContext(ctx, &phone, `SELECT phone FROM telegram_contacts WHERE chat_id = $1`, chatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &phone, nil
 }
 
 func (r *VerificationRepository) GetUserVerificationStatus(ctx context.Context, userID uuid.UUID) (emailVerified, phoneVerified, identityVerified bool, err error) {
@@ -4,15 +4,20 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 )
 
-var ErrWithdrawalNotFound = errors.New("withdrawal not found")
+var (
+	ErrWithdrawalNotFound     = errors.New("withdrawal not found")
+	ErrWithdrawalInvalidState = errors.New("withdrawal is not in a valid state for this operation")
+)
 
 type WithdrawalRepository struct {
 	db *sqlx.DB
@@ -22,16 +27,42 @@ func NewWithdrawalRepository(db *sqlx.DB) *WithdrawalRepository {
 	return &WithdrawalRepository{db: db}
 }
 
-func (r *WithdrawalRepository) Create(ctx context.Context, userID uuid.UUID, amount float64, cardLast4, bankName string) (*models.Withdrawal, error) {
+// WithdrawalCheck проверяет новую заявку вместе с выводами пользователя, созданными с момента
+// Since (суммы по валютам, кроме отклонённых).
+type WithdrawalCheck struct {
+	Since time.Time
+	Allow func(ctx context.Context, recent map[string]valueobject.Amount) error
+}
+
+// Create замораживает сумму вывода на балансе пользователя в валюте и создаёт заявку
+// вместе с транзакцией withdrawal в статусе pending. Если задан check, он вызывается в той же
+// транзакции под блокировкой пользователя, поэтому параллельные заявки не проходят проверку
+// по одной и той же сумме прежних выводов.
+func (r *WithdrawalRepository) Create(ctx context.Context, userID uuid.UUID, amount valueobject.Amount, currency, cardLast4, bankName string, check *WithdrawalCheck) (*models.Withdrawal, error) {
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	// Проверяем и списываем баланс
-	var available float64
-	err = tx.GetContext(ctx, &available, `SELECT available FROM user_balances WHERE user_id = $1 FOR UPDATE`, userID)
+	if check != nil {
+		if _, err := tx.ExecContext(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+			return nil, fmt.Errorf("withdrawal repository: lock user %w", err)
+		}
+		recent, err := sumSince(ctx, tx, userID, check.Since)
+		if err != nil {
+			return nil, err
+		}
+		if err := check.Allow(ctx, recent); err != nil {
+			return nil, err
+		}
+	}
+
+	// Проверяем баланс и переносим сумму из доступных средств в замороженные
+	var available valueobject.Amount
+	err = tx.GetContext(ctx, &available, `
+		SELECT available FROM user_balances WHERE user_id = $1 AND currency = $2 FOR UPDATE
+	`, userID, currency)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrInsufficientFunds
@@ -42,17 +73,38 @@ func (r *WithdrawalRepository) Create(ctx context.Context, userID uuid.UUID, amo
 		return nil, ErrInsufficientFunds
 	}
 
-	_, err = tx.ExecContext(ctx, `UPDATE user_balances SET available = available - $2, updated_at = NOW() WHERE user_id = $1`, userID, amount)
+	_, err = tx.ExecContext(ctx, `
+		UPDATE user_balances SET available = available - $2, frozen = frozen + $2, updated_at = NOW()
+		WHERE user_id = $1 AND currency = $3
+	`, userID, amount, currency)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("withdrawal repository: hold balance %w", err)
+	}
+
+	var transactionID uuid.UUID
+	err = tx.GetContext(ctx, &transactionID, `
+		INSERT INTO transactions (user_id, type, amount, currency, status, description)
+		VALUES ($1, 'withdrawal', $2, $3, 'pending', $4)
+		RETURNING id
+	`, userID, amount, currency, "Вывод средств на карту *"+cardLast4)
+	if err != nil {
+		return nil, fmt.Errorf("withdrawal repository: insert transaction %w", err)
 	}
 
 	var w models.Withdrawal
 	err = tx.GetContext(ctx, &w, `
-		INSERT INTO withdrawals (user_id, amount, card_last4, bank_name)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO withdrawals (user_id, amount, currency, card_last4, bank_name, transaction_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING *
-	`, userID, amount, cardLast4, bankName)
+	`, userID, amount, currency, cardLast4, bankName, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = postLedger(ctx, tx, withdrawalJournal(models.LedgerJournalWithdrawalHold, &w),
+		userLeg(models.LedgerAccountUserAvailable, userID, -amount),
+		userLeg(models.LedgerAccountUserFrozen, userID, amount),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -77,10 +129,195 @@ func (r *WithdrawalRepository) ListByUser(ctx context.Context, userID uuid.UUID,
 	return withdrawals, err
 }
 
-func (r *WithdrawalRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string, rejectionReason *string) error {
-	now := time.Now()
+// sumSince возвращает по валютам сумму выводов пользователя, созданных с момента since, кроме отклонённых.
+func sumSince(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, since time.Time) (map[string]valueobject.Amount, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT currency, SUM(amount) FROM withdrawals
+		WHERE user_id = $1 AND created_at >= $2 AND status <> 'rejected'
+		GROUP BY currency
+	`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("withdrawal repository: sum since %w", err)
+	}
+	defer rows.Close()
+
+	sums := make(map[string]valueobject.Amount)
+	for rows.Next() {
+		var (
+			currency string
+			sum      valueobject.Amount
+		)
+		if err := rows.Scan(&currency, &sum); err != nil {
+			return nil, fmt.Errorf("withdrawal repository: sum since %w", err)
+		}
+		sums[currency] = sum
+	}
+	return sums, rows.Err()
+}
+
+// ClaimPending переводит до limit заявок в processing и возвращает их обработчику.
+// Заявки, зависшие в processing дольше staleAfter (например, после падения процесса),
+// забираются повторно, поэтому провайдер выплат должен быть идемпотентен по ID заявки.
+func (r *WithdrawalRepository) ClaimPending(ctx context.Context, limit int, staleAfter time.Duration) ([]models.Withdrawal, error) {
+	withdrawals := []models.Withdrawal{}
+	err := r.db.SelectContext(ctx, &withdrawals, `
+		UPDATE withdrawals SET status = 'processing', processing_started_at = NOW()
+		WHERE id IN (
+			SELECT id FROM withdrawals
+			WHERE status = 'pending'
+				OR (status = 'processing' AND processing_started_at < NOW() - $2 * INTERVAL '1 second')
+			ORDER BY created_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`, limit, staleAfter.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("withdrawal repository: claim pending %w", err)
+	}
+	return withdrawals, nil
+}
+
+// Requeue возвращает заявку в очередь после временной ошибки провайдера.
+func (r *WithdrawalRepository) Requeue(ctx context.Context, id uuid.UUID) error {
 	_, err := r.db.ExecContext(ctx, `
-		UPDATE withdrawals SET status = $2, rejection_reason = $3, processed_at = $4 WHERE id = $1
-	`, id, status, rejectionReason, now)
+		UPDATE withdrawals SET status = 'pending', processing_started_at = NULL
+		WHERE id = $1 AND status = 'processing'
+	`, id)
 	return err
 }
+
+// Complete списывает замороженную сумму и завершает заявку и её транзакцию.
+func (r *WithdrawalRepository) Complete(ctx context.Context, id uuid.UUID, payoutReference string) (*models.Withdrawal, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	w, err := r.lockForProcessing(ctx, tx, id, models.WithdrawalStatusProcessing)
+	if err != nil {
+		return nil, err
+	}
+
+	// Заявки без транзакции созданы до заморозки средств: сумма уже списана с available
+	if w.TransactionID != nil {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE user_balances SET frozen = frozen - $2, updated_at = NOW() WHERE user_id = $1 AND currency = $3
+		`, w.UserID, w.Amount, w.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("withdrawal repository: release frozen %w", err)
+		}
+		if err := r.finishTransaction(ctx, tx, *w.TransactionID, models.TransactionStatusCompleted); err != nil {
+			return nil, err
+		}
+		err = postLedger(ctx, tx, withdrawalJournal(models.LedgerJournalWithdrawalPayout, w),
+			userLeg(models.LedgerAccountUserFrozen, w.UserID, -w.Amount),
+			platformLeg(models.LedgerAccountExternalCash, w.Amount),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.GetContext(ctx, w, `
+		UPDATE withdrawals SET status = 'completed', payout_reference = $2, processed_at = NOW()
+		WHERE id = $1
+		RETURNING *
+	`, id, payoutReference)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, tx.Commit()
+}
+
+// Reject отклоняет заявку: возвращает замороженную сумму в доступные средства,
+// отменяет транзакцию и сохраняет причину отказа.
+func (r *WithdrawalRepository) Reject(ctx context.Context, id uuid.UUID, reason string) (*models.Withdrawal, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	w, err := r.lockForProcessing(ctx, tx, id, models.WithdrawalStatusPending, models.WithdrawalStatusProcessing)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.TransactionID != nil {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE user_balances SET available = available + $2, frozen = frozen - $2, updated_at = NOW()
+			WHERE user_id = $1 AND currency = $3
+		`, w.UserID, w.Amount, w.Currency)
+		if err == nil {
+			err = r.finishTransaction(ctx, tx, *w.TransactionID, models.TransactionStatusCancelled)
+		}
+		if err == nil {
+			err = postLedger(ctx, tx, withdrawalJournal(models.LedgerJournalWithdrawalReject, w),
+				userLeg(models.LedgerAccountUserFrozen, w.UserID, -w.Amount),
+				userLeg(models.LedgerAccountUserAvailable, w.UserID, w.Amount),
+			)
+		}
+	} else {
+		// Заявка создана до журнала и её сумма не вошла во входящие остатки: возврат проводим как поступление извне
+		_, err = tx.ExecContext(ctx, `
+			UPDATE user_balances SET available = available + $2, updated_at = NOW() WHERE user_id = $1 AND currency = $3
+		`, w.UserID, w.Amount, w.Currency)
+		if err == nil {
+			err = postLedger(ctx, tx, withdrawalJournal(models.LedgerJournalWithdrawalReject, w),
+				platformLeg(models.LedgerAccountExternalCash, -w.Amount),
+				userLeg(models.LedgerAccountUserAvailable, w.UserID, w.Amount),
+			)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("withdrawal repository: restore balance %w", err)
+	}
+
+	err = tx.GetContext(ctx, w, `
+		UPDATE withdrawals SET status = 'rejected', rejection_reason = $2, processed_at = NOW()
+		WHERE id = $1
+		RETURNING *
+	`, id, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, tx.Commit()
+}
+
+// lockForProcessing блокирует заявку и проверяет, что она в одном из допустимых статусов.
+func (r *WithdrawalRepository) lockForProcessing(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, statuses ...string) (*models.Withdrawal, error) {
+	var w models.Withdrawal
+	err := tx.GetContext(ctx, &w, `SELECT * FROM withdrawals WHERE id = $1 FOR UPDATE`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWithdrawalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		if w.Status == status {
+			return &w, nil
+		}
+	}
+	return nil, ErrWithdrawalInvalidState
+}
+
+// finishTransaction переводит транзакцию вывода в финальный статус.
+func (r *WithdrawalRepository) finishTransaction(ctx context.Context, tx *sqlx.Tx, transactionID uuid.UUID, status string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE transactions SET status = $2, completed_at = NOW() WHERE id = $1
+	`, transactionID, status)
+	if err != nil {
+		return fmt.Errorf("withdrawal repository: finish transaction %w", err)
+	}
+	return nil
+}
+
+// withdrawalJournal описывает операцию по выводу для журнала двойной записи.
+func withdrawalJournal(kind string, w *models.Withdrawal) ledgerJournal {
+	return ledgerJournal{Kind: kind, Currency: w.Currency, WithdrawalID: &w.ID, Description: "Вывод средств"}
+}
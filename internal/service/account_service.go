@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/ignatzorin/freelance-backend/internal/logger"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+const (
+	passwordResetTTL = time.Hour
+	emailChangeTTL   = 24 * time.Hour
+)
+
+var (
+	// ErrInvalidActionLink возвращается для поддельной, истёкшей или уже использованной ссылки из письма.
+	ErrInvalidActionLink = errors.New("auth service: ссылка недействительна или устарела")
+	// ErrWrongPassword возвращается, если текущий пароль указан неверно.
+	ErrWrongPassword = errors.New("auth service: неверный пароль")
+	// ErrEmailTaken возвращается, если новый email уже занят.
+	ErrEmailTaken = errors.New("auth service: email уже зарегистрирован")
+	// ErrSameEmail возвращается при попытке сменить email на текущий.
+	ErrSameEmail = errors.New("auth service: новый email совпадает с текущим")
+)
+
+// AccountRepository описывает зависимости AccountService от слоя хранилища.
+type AccountRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	UpdateEmail(ctx context.Context, userID uuid.UUID, email string) error
+	DeleteSessionsExcept(ctx context.Context, userID, keepSessionID uuid.UUID) ([]uuid.UUID, error)
+	CreateActionToken(ctx context.Context, token *models.UserActionToken) error
+	ConsumeActionToken(ctx context.Context, id uuid.UUID, purpose string) (*models.UserActionToken, error)
+	ExpireActionTokens(ctx context.Context, userID uuid.UUID, purpose string) error
+}
+
+// AccountService управляет паролем и email пользователя: сброс пароля по ссылке из письма,
+// смена пароля и смена email с подтверждением нового адреса.
+type AccountService struct {
+	repo         AccountRepository
+	tokenManager *TokenManager
+	mailer       Mailer
+	frontendURL  string
+	revoker      SessionRevoker
+}
+
+// NewAccountService создаёт сервис; frontendURL — адрес фронтенда, на страницы которого ведут ссылки из писем.
+func NewAccountService(repo AccountRepository, tokenManager *TokenManager, mailer Mailer, frontendURL string) *AccountService {
+	return &AccountService{
+		repo:         repo,
+		tokenManager: tokenManager,
+		mailer:       mailer,
+		frontendURL:  strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// SetSessionRevoker устанавливает получателя уведомлений о сессиях, завершённых сменой пароля.
+func (s *AccountService) SetSessionRevoker(revoker SessionRevoker) {
+	s.revoker = revoker
+}
+
+// RequestPasswordReset отправляет ссылку для сброса пароля. Для неизвестного или заблокированного
+// email ничего не делает и не возвращает ошибку, чтобы ответ не раскрывал, зарегистрирован ли адрес.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.repo.GetByEmail(ctx, email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	link, err := s.issueLink(ctx, user.ID, models.ActionPasswordReset, nil, passwordResetTTL, "/reset-password")
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, Email{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует %s и срабатывает один раз. Если вы не запрашивали сброс, просто проигнорируйте письмо.",
+			user.Username, link, formatTTL(passwordResetTTL)),
+	})
+}
+
+// ResetPassword задаёт новый пароль по ссылке из письма и завершает все сессии пользователя.
+func (s *AccountService) ResetPassword(ctx context.Context, rawToken, newPassword string) error {
+	token, err := s.consumeLink(ctx, rawToken, models.ActionPasswordReset)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetByID(ctx, token.UserID)
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return ErrAccountSuspended
+	}
+
+	return s.setPassword(ctx, user, newPassword, uuid.Nil)
+}
+
+// ChangePassword меняет пароль после проверки текущего; все сессии, кроме текущей, завершаются.
+func (s *AccountService) ChangePassword(ctx context.Context, userID, currentSessionID uuid.UUID, currentPassword, newPassword string) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		return ErrWrongPassword
+	}
+
+	return s.setPassword(ctx, user, newPassword, currentSessionID)
+}
+
+// RequestEmailChange отправляет ссылку подтверждения на новый адрес и предупреждение на текущий.
+// Email меняется только после перехода по ссылке.
+func (s *AccountService) RequestEmailChange(ctx context.Context, userID uuid.UUID, newEmail, password string) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return ErrWrongPassword
+	}
+
+	newEmail = strings.ToLower(strings.TrimSpace(newEmail))
+	if strings.EqualFold(newEmail, user.Email) {
+		return ErrSameEmail
+	}
+	if _, err := s.repo.GetByEmail(ctx, newEmail); err == nil {
+		return ErrEmailTaken
+	} else if !errors.Is(err, repository.ErrUserNotFound) {
+		return err
+	}
+
+	link, err := s.issueLink(ctx, user.ID, models.ActionEmailChange, &newEmail, emailChangeTTL, "/confirm-email")
+	if err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, Email{
+		To:      newEmail,
+		Subject: "Подтвердите новый email",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы использовать этот адрес для входа, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует %s и срабатывает один раз.",
+			user.Username, link, formatTTL(emailChangeTTL)),
+	}); err != nil {
+		return err
+	}
+
+	s.sendNotice(ctx, Email{
+		To:      user.Email,
+		Subject: "Запрошена смена email",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nДля вашего аккаунта запрошена смена email на %s. "+
+			"Адрес изменится, когда владелец нового адреса подтвердит его.\n\n"+
+			"Если это были не вы, смените пароль: после этого ссылка подтверждения перестанет действовать.",
+			user.Username, newEmail),
+	})
+	return nil
+}
+
+// ConfirmEmailChange меняет email по ссылке, отправленной на новый адрес, и сообщает об этом на прежний.
+func (s *AccountService) ConfirmEmailChange(ctx context.Context, rawToken string) error {
+	token, err := s.consumeLink(ctx, rawToken, models.ActionEmailChange)
+	if err != nil {
+		return err
+	}
+	if token.NewEmail == nil {
+		return ErrInvalidActionLink
+	}
+
+	user, err := s.repo.GetByID(ctx, token.UserID)
+	if err != nil {
+		return err
+	}
+
+	oldEmail := user.Email
+	if err := s.repo.UpdateEmail(ctx, user.ID, *token.NewEmail); err != nil {
+		if errors.Is(err, repository.ErrEmailTaken) {
+			return ErrEmailTaken
+		}
+		return err
+	}
+
+	s.sendNotice(ctx, Email{
+		To:      oldEmail,
+		Subject: "Email аккаунта изменён",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nEmail вашего аккаунта изменён на %s. "+
+			"Если это были не вы, срочно обратитесь в поддержку.",
+			user.Username, *token.NewEmail),
+	})
+	return nil
+}
+
+// setPassword сохраняет новый пароль, завершает сессии кроме keepSessionID и предупреждает пользователя.
+// Незавершённая смена email отменяется: её мог запросить тот, кто знал старый пароль.
+func (s *AccountService) setPassword(ctx context.Context, user *models.User, newPassword string, keepSessionID uuid.UUID) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("auth service: не удалось захешировать пароль: %w", err)
+	}
+	if err := s.repo.UpdatePassword(ctx, user.ID, string(hash)); err != nil {
+		return err
+	}
+
+	revoked, err := s.repo.DeleteSessionsExcept(ctx, user.ID, keepSessionID)
+	if err != nil {
+		return err
+	}
+	if s.revoker != nil && len(revoked) > 0 {
+		s.revoker.SessionsRevoked(user.ID, revoked...)
+	}
+
+	if err := s.repo.ExpireActionTokens(ctx, user.ID, models.ActionEmailChange); err != nil {
+		return err
+	}
+
+	s.sendNotice(ctx, Email{
+		To:      user.Email,
+		Subject: "Пароль изменён",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nПароль вашего аккаунта изменён, остальные устройства вышли из аккаунта. "+
+			"Если это были не вы, восстановите доступ через «Забыли пароль?».", user.Username),
+	})
+	return nil
+}
+
+// issueLink создаёт одноразовую запись и возвращает подписанную ссылку на страницу фронтенда.
+func (s *AccountService) issueLink(ctx context.Context, userID uuid.UUID, purpose string, newEmail *string, ttl time.Duration, path string) (string, error) {
+	token := &models.UserActionToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Purpose:   purpose,
+		NewEmail:  newEmail,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.repo.CreateActionToken(ctx, token); err != nil {
+		return "", err
+	}
+
+	signed, err := s.tokenManager.GenerateActionToken(token.ID, purpose, token.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+	return s.frontendURL + path + "?token=" + url.QueryEscape(signed), nil
+}
+
+// consumeLink проверяет подпись ссылки и гасит её запись.
+func (s *AccountService) consumeLink(ctx context.Context, rawToken, purpose string) (*models.UserActionToken, error) {
+	id, err := s.tokenManager.ParseActionToken(rawToken, purpose)
+	if err != nil {
+		return nil, ErrInvalidActionLink
+	}
+
+	token, err := s.repo.ConsumeActionToken(ctx, id, purpose)
+	if errors.Is(err, repository.ErrActionTokenNotFound) {
+		return nil, ErrInvalidActionLink
+	}
+	return token, err
+}
+
+// sendNotice отправляет письмо-уведомление; ошибка отправки не отменяет уже выполненное действие.
+func (s *AccountService) sendNotice(ctx context.Context, msg Email) {
+	if err := s.mailer.Send(ctx, msg); err != nil && logger.Log != nil {
+		logger.Log.WithFields(map[string]interface{}{
+			"subject": msg.Subject,
+			"error":   err.Error(),
+		}).Warn("account service: не удалось отправить письмо")
+	}
+}
+
+func formatTTL(ttl time.Duration) string {
+	if ttl >= time.Hour {
+		return fmt.Sprintf("%d ч", int(ttl.Hours()))
+	}
+	return fmt.Sprintf("%d мин", int(ttl.Minutes()))
+}
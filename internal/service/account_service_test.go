@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+// mockAccountRepository дополняет mockAuthRepository методами AccountRepository.
+type mockAccountRepository struct {
+	*mockAuthRepository
+	actionTokens map[uuid.UUID]*models.UserActionToken
+}
+
+func newMockAccountRepository() *mockAccountRepository {
+	return &mockAccountRepository{
+		mockAuthRepository: newMockAuthRepository(),
+		actionTokens:       make(map[uuid.UUID]*models.UserActionToken),
+	}
+}
+
+func (m *mockAccountRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	m.usersByID[userID].PasswordHash = passwordHash
+	return nil
+}
+
+func (m *mockAccountRepository) UpdateEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	if _, ok := m.usersByEmail[email]; ok {
+		return repository.ErrEmailTaken
+	}
+	user := m.usersByID[userID]
+	delete(m.usersByEmail, user.Email)
+	user.Email = email
+	m.usersByEmail[email] = user
+	return nil
+}
+
+func (m *mockAccountRepository) DeleteSessionsExcept(ctx context.Context, userID, keepSessionID uuid.UUID) ([]uuid.UUID, error) {
+	var deleted []uuid.UUID
+	for id, s := range m.sessions {
+		if s.UserID == userID && id != keepSessionID {
+			m.deleteSession(id)
+			deleted = append(deleted, id)
+		}
+	}
+	return deleted, nil
+}
+
+func (m *mockAccountRepository) CreateActionToken(ctx context.Context, token *models.UserActionToken) error {
+	_ = m.ExpireActionTokens(ctx, token.UserID, token.Purpose)
+	m.actionTokens[token.ID] = token
+	return nil
+}
+
+func (m *mockAccountRepository) ConsumeActionToken(ctx context.Context, id uuid.UUID, purpose string) (*models.UserActionToken, error) {
+	token, ok := m.actionTokens[id]
+	if !ok || token.Purpose != purpose || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, repository.ErrActionTokenNotFound
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	return token, nil
+}
+
+func (m *mockAccountRepository) ExpireActionTokens(ctx context.Context, userID uuid.UUID, purpose string) error {
+	for _, token := range m.actionTokens {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			token.ExpiresAt = time.Now().Add(-time.Second)
+		}
+	}
+	return nil
+}
+
+type recordingMailer struct {
+	sent []Email
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg Email) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var linkTokenPattern = regexp.MustCompile(`\?token=(\S+)`)
+
+// linkToken достаёт токен из ссылки в последнем письме на адрес to.
+func (m *recordingMailer) linkToken(t *testing.T, to string) string {
+	t.Helper()
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if m.sent[i].To != to {
+			continue
+		}
+		if match := linkTokenPattern.FindStringSubmatch(m.sent[i].Body); match != nil {
+			token, err := url.QueryUnescape(match[1])
+			assert.NoError(t, err)
+			return token
+		}
+	}
+	t.Fatalf("письмо со ссылкой на %s не найдено", to)
+	return ""
+}
+
+func setupAccountService(t *testing.T) (*AccountService, *AuthService, *mockAccountRepository, *recordingMailer) {
+	t.Helper()
+	repo := newMockAccountRepository()
+	tokenManager := NewTokenManager("access-secret", "refresh-secret", time.Minute, time.Hour)
+	mailer := &recordingMailer{}
+	return NewAccountService(repo, tokenManager, mailer, "https://app.example.com/"), NewAuthService(repo.mockAuthRepository, tokenManager), repo, mailer
+}
+
+func TestAccountService_PasswordResetLinkIsSingleUse(t *testing.T) {
+	account, auth, repo, mailer := setupAccountService(t)
+	ctx := context.Background()
+
+	res, err := auth.Register(ctx, RegisterInput{Email: "reset@example.com", Password: "password123"}, nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, account.RequestPasswordReset(ctx, "reset@example.com"))
+	assert.Len(t, mailer.sent, 1)
+	assert.Contains(t, mailer.sent[0].Body, "https://app.example.com/reset-password?token=")
+	token := mailer.linkToken(t, "reset@example.com")
+
+	assert.NoError(t, account.ResetPassword(ctx, token, "newpassword456"))
+	user := repo.usersByID[res.User.ID]
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("newpassword456")))
+	assert.Empty(t, repo.sessions, "сброс пароля завершает все сессии")
+
+	assert.ErrorIs(t, account.ResetPassword(ctx, token, "anotherpassword789"), ErrInvalidActionLink)
+}
+
+func TestAccountService_PasswordResetUnknownEmailIsSilent(t *testing.T) {
+	account, _, _, mailer := setupAccountService(t)
+
+	assert.NoError(t, account.RequestPasswordReset(context.Background(), "nobody@example.com"))
+	assert.Empty(t, mailer.sent)
+}
+
+func TestAccountService_PasswordResetRejectsForeignToken(t *testing.T) {
+	account, auth, _, mailer := setupAccountService(t)
+	ctx := context.Background()
+
+	_, err := auth.Register(ctx, RegisterInput{Email: "owner@example.com", Password: "password123"}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, account.RequestPasswordReset(ctx, "owner@example.com"))
+	token := mailer.linkToken(t, "owner@example.com")
+
+	// Ссылка сброса пароля не подходит для подтверждения email и наоборот
+	assert.ErrorIs(t, account.ConfirmEmailChange(ctx, token), ErrInvalidActionLink)
+	assert.ErrorIs(t, account.ResetPassword(ctx, token+"x", "newpassword456"), ErrInvalidActionLink)
+}
+
+func TestAccountService_ChangePasswordKeepsCurrentSession(t *testing.T) {
+	account, auth, repo, _ := setupAccountService(t)
+	ctx := context.Background()
+
+	first, err := auth.Register(ctx, RegisterInput{Email: "change@example.com", Password: "password123"}, nil)
+	assert.NoError(t, err)
+	_, err = auth.Login(ctx, LoginInput{Email: "change@example.com", Password: "password123"}, nil)
+	assert.NoError(t, err)
+	claims, err := auth.tokenManager.ParseAccess(first.TokenPair.AccessToken)
+	assert.NoError(t, err)
+
+	err = account.ChangePassword(ctx, first.User.ID, claims.SessionID, "wrongpassword", "newpassword456")
+	assert.ErrorIs(t, err, ErrWrongPassword)
+	assert.Len(t, repo.sessions, 2)
+
+	assert.NoError(t, account.ChangePassword(ctx, first.User.ID, claims.SessionID, "password123", "newpassword456"))
+	assert.Len(t, repo.sessions, 1)
+	assert.Contains(t, repo.sessions, claims.SessionID)
+}
+
+func TestAccountService_EmailChangeNotifiesBothAddresses(t *testing.T) {
+	account, auth, repo, mailer := setupAccountService(t)
+	ctx := context.Background()
+
+	res, err := auth.Register(ctx, RegisterInput{Email: "old@example.com", Password: "password123"}, nil)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, account.RequestEmailChange(ctx, res.User.ID, "new@example.com", "wrongpassword"), ErrWrongPassword)
+	assert.ErrorIs(t, account.RequestEmailChange(ctx, res.User.ID, "OLD@example.com", "password123"), ErrSameEmail)
+	assert.Empty(t, mailer.sent)
+
+	assert.NoError(t, account.RequestEmailChange(ctx, res.User.ID, " New@Example.com ", "password123"))
+	token := mailer.linkToken(t, "new@example.com")
+	assert.Equal(t, "old@example.com", mailer.sent[len(mailer.sent)-1].To)
+	assert.Equal(t, "old@example.com", repo.usersByID[res.User.ID].Email, "email меняется только после подтверждения")
+
+	assert.NoError(t, account.ConfirmEmailChange(ctx, token))
+	assert.Equal(t, "new@example.com", repo.usersByID[res.User.ID].Email)
+	last := mailer.sent[len(mailer.sent)-1]
+	assert.Equal(t, "old@example.com", last.To)
+	assert.True(t, strings.Contains(last.Body, "new@example.com"))
+}
+
+func TestAccountService_PasswordChangeCancelsPendingEmailChange(t *testing.T) {
+	account, auth, _, mailer := setupAccountService(t)
+	ctx := context.Background()
+
+	res, err := auth.Register(ctx, RegisterInput{Email: "victim@example.com", Password: "password123"}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, account.RequestEmailChange(ctx, res.User.ID, "attacker@example.com", "password123"))
+	token := mailer.linkToken(t, "attacker@example.com")
+
+	assert.NoError(t, account.ChangePassword(ctx, res.User.ID, uuid.Nil, "password123", "newpassword456"))
+	assert.ErrorIs(t, account.ConfirmEmailChange(ctx, token), ErrInvalidActionLink)
+}
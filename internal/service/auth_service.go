@@ -2,9 +2,12 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
@@ -23,18 +26,54 @@ type AuthRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
 	GetProfile(ctx context.Context, userID uuid.UUID) (*models.Profile, error)
 	UpsertProfile(ctx context.Context, profile *models.Profile) error
-	CreateSession(ctx context.Context, session *models.Session) error
-	DeleteSession(ctx context.Context, refreshToken string) error
+	CreateSession(ctx context.Context, session *models.Session, refreshTokenHash string) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, session *models.Session, oldTokenHash, newTokenHash string) error
+	RevokeSession(ctx context.Context, sessionID uuid.UUID) error
 	UpdateLastLoginAt(ctx context.Context, userID uuid.UUID) error
 	ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
 	DeleteSessionByID(ctx context.Context, sessionID uuid.UUID, userID uuid.UUID) error
-	DeleteAllSessionsExcept(ctx context.Context, userID uuid.UUID, exceptRefreshToken string) error
+	DeleteAllSessionsExcept(ctx context.Context, userID uuid.UUID, exceptRefreshTokenHash string) ([]uuid.UUID, error)
+}
+
+var (
+	// ErrSessionRevoked возвращается при обмене refresh токена завершённой или неизвестной сессии.
+	ErrSessionRevoked = errors.New("auth service: сессия завершена, войдите заново")
+	// ErrRefreshTokenReused возвращается при повторном предъявлении уже обменянного refresh токена.
+	ErrRefreshTokenReused = errors.New("auth service: refresh токен уже использован, сессия завершена")
+	// ErrTwoFactorChallengeInvalid возвращается для поддельного или истёкшего токена второго шага входа.
+	ErrTwoFactorChallengeInvalid = errors.New("auth service: время на ввод кода истекло, войдите заново")
+)
+
+const (
+	// twoFactorLoginPurpose — назначение токена второго шага входа; в jti передаётся id пользователя.
+	twoFactorLoginPurpose = "two_factor_login"
+	// twoFactorChallengeTTL — сколько действует токен второго шага входа.
+	twoFactorChallengeTTL = 5 * time.Minute
+)
+
+// TwoFactorVerifier проверяет второй фактор при входе.
+type TwoFactorVerifier interface {
+	Enabled(ctx context.Context, userID uuid.UUID) (bool, error)
+	Verify(ctx context.Context, userID uuid.UUID, code string) error
+}
+
+// LoginProtector ограничивает подбор пароля и предупреждает о входе с нового устройства.
+type LoginProtector interface {
+	Check(ctx context.Context, email, ip string) error
+	RecordFailure(ctx context.Context, email string, user *models.User, meta map[string]string)
+	RecordSuccess(ctx context.Context, user *models.User, meta map[string]string)
+	NoticeLogin(ctx context.Context, user *models.User, meta map[string]string)
 }
 
 // AuthService инкапсулирует бизнес-логику регистрации и аутентификации.
 type AuthService struct {
 	repo         AuthRepository
 	tokenManager *TokenManager
+	hub          WSNotifier
+	revoker      SessionRevoker
+	twoFactor    TwoFactorVerifier
+	loginGuard   LoginProtector
 }
 
 // RegisterInput содержит данные пользователя при регистрации.
@@ -52,11 +91,13 @@ type LoginInput struct {
 	Password string
 }
 
-// AuthResult возвращает итог регистрации или авторизации.
+// AuthResult возвращает итог регистрации или авторизации. Если у пользователя подключена
+// двухфакторная аутентификация, Login заполняет только TwoFactorChallenge.
 type AuthResult struct {
-	User      *models.User
-	Profile   *models.Profile
-	TokenPair *TokenPair
+	User               *models.User
+	Profile            *models.Profile
+	TokenPair          *TokenPair
+	TwoFactorChallenge string
 }
 
 // NewAuthService создаёт сервис аутентификации.
@@ -67,6 +108,26 @@ func NewAuthService(repo AuthRepository, tokenManager *TokenManager) *AuthServic
 	}
 }
 
+// SetHub устанавливает WebSocket hub для уведомлений о компрометации сессии.
+func (s *AuthService) SetHub(hub WSNotifier) {
+	s.hub = hub
+}
+
+// SetSessionRevoker устанавливает получателя уведомлений об отозванных сессиях.
+func (s *AuthService) SetSessionRevoker(revoker SessionRevoker) {
+	s.revoker = revoker
+}
+
+// SetTwoFactor включает второй шаг входа для пользователей с подключённым TOTP.
+func (s *AuthService) SetTwoFactor(twoFactor TwoFactorVerifier) {
+	s.twoFactor = twoFactor
+}
+
+// SetLoginGuard включает защиту входа по паролю от подбора.
+func (s *AuthService) SetLoginGuard(guard LoginProtector) {
+	s.loginGuard = guard
+}
+
 // Register создаёт нового пользователя и профиль.
 func (s *AuthService) Register(ctx context.Context, in RegisterInput, meta map[string]string) (*AuthResult, error) {
 	// Валидация email на уровне сервиса
@@ -87,7 +148,10 @@ func (s *AuthService) Register(ctx context.Context, in RegisterInput, meta map[s
 
 	role := in.Role
 	if role == "" {
-		role = "freelancer"
+		role = models.RoleFreelancer
+	}
+	if _, ok := models.SelfAssignableRoles[role]; !ok {
+		return nil, fmt.Errorf("auth service: роль %s нельзя выбрать при регистрации", role)
 	}
 
 	passHash, err := bcrypt.GenerateFromPassword([]byte(in.Password), bcrypt.DefaultCost)
@@ -102,55 +166,98 @@ func (s *AuthService) Register(ctx context.Context, in RegisterInput, meta map[s
 		Role:         role,
 	}
 
-	if err := s.repo.Create(ctx, user); err != nil {
+	profile, err := s.createUser(ctx, user, in.DisplayName)
+	if err != nil {
 		return nil, err
 	}
 
-	displayName := in.DisplayName
-	if displayName == "" {
-		displayName = username
+	sessionID := uuid.New()
+	tokenPair, _, refreshExp, err := s.tokenManager.GeneratePair(user, sessionID)
+	if err != nil {
+		return nil, err
 	}
 
-	profile := &models.Profile{
-		UserID:          user.ID,
-		DisplayName:     displayName,
-		ExperienceLevel: "junior",
-		Skills:          []string{},
+	session := &models.Session{
+		ID:        sessionID,
+		UserID:    user.ID,
+		ExpiresAt: refreshExp,
 	}
+	applySessionMeta(session, meta)
 
-	if err := s.repo.UpsertProfile(ctx, profile); err != nil {
+	if err := s.repo.CreateSession(ctx, session, HashRefreshToken(tokenPair.RefreshToken)); err != nil {
 		return nil, err
 	}
 
-	tokenPair, _, refreshExp, err := s.tokenManager.GeneratePair(user)
+	return &AuthResult{
+		User:      user,
+		Profile:   profile,
+		TokenPair: tokenPair,
+	}, nil
+}
+
+// RegisterExternal создаёт пользователя, чей email подтвердил внешний провайдер входа. Профиль
+// заводится так же, как при Register; паролем служит случайная строка, задать свой пароль
+// можно через сброс. Если username из email занят, к нему добавляется случайный суффикс.
+func (s *AuthService) RegisterExternal(ctx context.Context, email, displayName string) (*models.User, error) {
+	if err := validation.ValidateEmail(email); err != nil {
+		return nil, fmt.Errorf("auth service: %w", err)
+	}
+
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return nil, err
+	}
+	passHash, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(password)), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("auth service: не удалось захешировать пароль: %w", err)
+	}
+
+	if validation.ValidateDisplayName(displayName) != nil {
+		displayName = ""
+	}
+
+	username := deriveUsername(email)
+	user := &models.User{
+		Email:        strings.ToLower(email),
+		Username:     username,
+		PasswordHash: string(passHash),
+		Role:         models.RoleFreelancer,
+	}
+
+	_, err = s.createUser(ctx, user, displayName)
+	if errors.Is(err, repository.ErrUsernameTaken) {
+		user.Username = username + "_" + uuid.NewString()[:6]
+		_, err = s.createUser(ctx, user, displayName)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	session := &models.Session{
-		UserID:       user.ID,
-		RefreshToken: tokenPair.RefreshToken,
-		ExpiresAt:    refreshExp,
+	return user, nil
+}
+
+// createUser сохраняет пользователя и создаёт ему профиль; пустое displayName заменяется username.
+func (s *AuthService) createUser(ctx context.Context, user *models.User, displayName string) (*models.Profile, error) {
+	if err := s.repo.Create(ctx, user); err != nil {
+		return nil, err
 	}
 
-	if meta != nil {
-		if ua, ok := meta["user_agent"]; ok {
-			session.UserAgent = &ua
-		}
-		if ip, ok := meta["ip"]; ok {
-			session.IPAddress = &ip
-		}
+	if displayName == "" {
+		displayName = user.Username
+	}
+
+	profile := &models.Profile{
+		UserID:          user.ID,
+		DisplayName:     displayName,
+		ExperienceLevel: "junior",
+		Skills:          []string{},
 	}
 
-	if err := s.repo.CreateSession(ctx, session); err != nil {
+	if err := s.repo.UpsertProfile(ctx, profile); err != nil {
 		return nil, err
 	}
 
-	return &AuthResult{
-		User:      user,
-		Profile:   profile,
-		TokenPair: tokenPair,
-	}, nil
+	return profile, nil
 }
 
 // Login проверяет учётные данные и возвращает токены.
@@ -160,20 +267,94 @@ func (s *AuthService) Login(ctx context.Context, in LoginInput, meta map[string]
 		return nil, fmt.Errorf("auth service: %w", err)
 	}
 
+	if s.loginGuard != nil {
+		if err := s.loginGuard.Check(ctx, in.Email, meta["ip"]); err != nil {
+			return nil, err
+		}
+	}
+
 	user, err := s.repo.GetByEmail(ctx, in.Email)
 	if err != nil {
+		if s.loginGuard != nil && errors.Is(err, repository.ErrUserNotFound) {
+			s.loginGuard.RecordFailure(ctx, in.Email, nil, meta)
+		}
 		return nil, fmt.Errorf("auth service: неверный email или пароль")
 	}
 
 	// Проверка активности пользователя
 	if !user.IsActive {
-		return nil, fmt.Errorf("auth service: аккаунт заблокирован")
+		return nil, ErrAccountSuspended
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(in.Password)); err != nil {
+		if s.loginGuard != nil {
+			s.loginGuard.RecordFailure(ctx, in.Email, user, meta)
+		}
 		return nil, fmt.Errorf("auth service: неверный email или пароль")
 	}
+	if s.loginGuard != nil {
+		s.loginGuard.RecordSuccess(ctx, user, meta)
+	}
 
+	return s.loginVerified(ctx, user, meta)
+}
+
+// LoginExternal входит за пользователя, которого подтвердил внешний провайдер. Как и Login,
+// при подключённой двухфакторной аутентификации возвращает только TwoFactorChallenge.
+func (s *AuthService) LoginExternal(ctx context.Context, user *models.User, meta map[string]string) (*AuthResult, error) {
+	if !user.IsActive {
+		return nil, ErrAccountSuspended
+	}
+
+	return s.loginVerified(ctx, user, meta)
+}
+
+// loginVerified завершает вход после проверки первого фактора или выдаёт токен второго шага.
+func (s *AuthService) loginVerified(ctx context.Context, user *models.User, meta map[string]string) (*AuthResult, error) {
+	if s.twoFactor != nil {
+		enabled, err := s.twoFactor.Enabled(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if enabled {
+			challenge, err := s.tokenManager.GenerateActionToken(user.ID, twoFactorLoginPurpose, time.Now().Add(twoFactorChallengeTTL))
+			if err != nil {
+				return nil, err
+			}
+			return &AuthResult{TwoFactorChallenge: challenge}, nil
+		}
+	}
+
+	return s.completeLogin(ctx, user, meta)
+}
+
+// LoginTwoFactor завершает вход вторым фактором: кодом из приложения или кодом восстановления.
+func (s *AuthService) LoginTwoFactor(ctx context.Context, challenge, code string, meta map[string]string) (*AuthResult, error) {
+	if s.twoFactor == nil {
+		return nil, ErrTwoFactorChallengeInvalid
+	}
+	userID, err := s.tokenManager.ParseActionToken(challenge, twoFactorLoginPurpose)
+	if err != nil {
+		return nil, ErrTwoFactorChallengeInvalid
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrTwoFactorChallengeInvalid
+	}
+	if !user.IsActive {
+		return nil, ErrAccountSuspended
+	}
+
+	if err := s.twoFactor.Verify(ctx, user.ID, code); err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user, meta)
+}
+
+// completeLogin создаёт сессию после успешной проверки всех факторов.
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User, meta map[string]string) (*AuthResult, error) {
 	// Обновляем время последнего входа
 	if err := s.repo.UpdateLastLoginAt(ctx, user.ID); err != nil {
 		// Логируем ошибку, но не прерываем процесс логина
@@ -185,27 +366,25 @@ func (s *AuthService) Login(ctx context.Context, in LoginInput, meta map[string]
 		}
 	}
 
-	tokenPair, _, refreshExp, err := s.tokenManager.GeneratePair(user)
+	// Сравнение с прежними сессиями возможно только до создания новой
+	if s.loginGuard != nil {
+		s.loginGuard.NoticeLogin(ctx, user, meta)
+	}
+
+	sessionID := uuid.New()
+	tokenPair, _, refreshExp, err := s.tokenManager.GeneratePair(user, sessionID)
 	if err != nil {
 		return nil, err
 	}
 
 	session := &models.Session{
-		UserID:       user.ID,
-		RefreshToken: tokenPair.RefreshToken,
-		ExpiresAt:    refreshExp,
+		ID:        sessionID,
+		UserID:    user.ID,
+		ExpiresAt: refreshExp,
 	}
+	applySessionMeta(session, meta)
 
-	if meta != nil {
-		if ua, ok := meta["user_agent"]; ok {
-			session.UserAgent = &ua
-		}
-		if ip, ok := meta["ip"]; ok {
-			session.IPAddress = &ip
-		}
-	}
-
-	if err := s.repo.CreateSession(ctx, session); err != nil {
+	if err := s.repo.CreateSession(ctx, session, HashRefreshToken(tokenPair.RefreshToken)); err != nil {
 		return nil, err
 	}
 
@@ -232,7 +411,9 @@ func (s *AuthService) Login(ctx context.Context, in LoginInput, meta map[string]
 	}, nil
 }
 
-// Refresh выпускает новую пару токенов.
+// Refresh обменивает refresh токен на новую пару токенов той же сессии. Обменянный токен
+// больше не принимается: его повторное предъявление означает утечку, поэтому сессия
+// отзывается целиком, а пользователь получает уведомление.
 func (s *AuthService) Refresh(ctx context.Context, oldToken string, meta map[string]string) (*TokenPair, error) {
 	claims, err := s.tokenManager.ParseRefresh(oldToken)
 	if err != nil {
@@ -244,40 +425,91 @@ func (s *AuthService) Refresh(ctx context.Context, oldToken string, meta map[str
 		return nil, fmt.Errorf("auth service: некорректный subject: %w", err)
 	}
 
-	user, err := s.repo.GetByID(ctx, userID)
+	oldHash := HashRefreshToken(oldToken)
+	stored, err := s.repo.GetRefreshToken(ctx, oldHash)
 	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil, ErrSessionRevoked
+		}
 		return nil, err
 	}
+	if stored.UserID != userID {
+		return nil, ErrSessionRevoked
+	}
+	if stored.RotatedAt != nil {
+		s.revokeReusedSession(ctx, stored, meta)
+		return nil, ErrRefreshTokenReused
+	}
 
-	tokenPair, _, refreshExp, err := s.tokenManager.GeneratePair(user)
+	user, err := s.repo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
+	if !user.IsActive {
+		return nil, ErrAccountSuspended
+	}
 
-	if err := s.repo.DeleteSession(ctx, oldToken); err != nil {
+	tokenPair, _, refreshExp, err := s.tokenManager.GeneratePair(user, stored.SessionID)
+	if err != nil {
 		return nil, err
 	}
 
 	session := &models.Session{
-		UserID:       userID,
-		RefreshToken: tokenPair.RefreshToken,
-		ExpiresAt:    refreshExp,
+		ID:        stored.SessionID,
+		UserID:    userID,
+		ExpiresAt: refreshExp,
 	}
+	applySessionMeta(session, meta)
 
-	if meta != nil {
-		if ua, ok := meta["user_agent"]; ok {
-			session.UserAgent = &ua
+	if err := s.repo.RotateRefreshToken(ctx, session, oldHash, HashRefreshToken(tokenPair.RefreshToken)); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenRotated) {
+			// Токен обменяли между проверкой и ротацией — это тоже повторное использование
+			s.revokeReusedSession(ctx, stored, meta)
+			return nil, ErrRefreshTokenReused
 		}
-		if ip, ok := meta["ip"]; ok {
-			session.IPAddress = &ip
+		return nil, err
+	}
+
+	return tokenPair, nil
+}
+
+// revokeReusedSession отзывает сессию, в которой предъявлен уже обменянный refresh токен,
+// и сообщает пользователю, откуда пришёл запрос.
+func (s *AuthService) revokeReusedSession(ctx context.Context, stored *models.RefreshToken, meta map[string]string) {
+	if err := s.repo.RevokeSession(ctx, stored.SessionID); err != nil {
+		if logger.Log != nil {
+			logger.Log.WithFields(map[string]interface{}{
+				"session_id": stored.SessionID,
+				"error":      err.Error(),
+			}).Error("auth service: не удалось отозвать сессию после повторного использования refresh токена")
 		}
+	} else {
+		s.sessionsRevoked(stored.UserID, stored.SessionID)
 	}
 
-	if err := s.repo.CreateSession(ctx, session); err != nil {
-		return nil, err
+	if logger.Log != nil {
+		logger.Log.WithFields(map[string]interface{}{
+			"user_id":    stored.UserID,
+			"session_id": stored.SessionID,
+			"ip":         meta["ip"],
+		}).Warn("auth service: повторное использование refresh токена, сессия отозвана")
 	}
 
-	return tokenPair, nil
+	if s.hub == nil {
+		return
+	}
+	payload := map[string]interface{}{
+		"session_id": stored.SessionID,
+		"reason":     "refresh_token_reused",
+		"ip_address": meta["ip"],
+		"user_agent": meta["user_agent"],
+	}
+	if err := s.hub.BroadcastToUser(stored.UserID, "session_revoked", payload); err != nil && logger.Log != nil {
+		logger.Log.WithFields(map[string]interface{}{
+			"session_id": stored.SessionID,
+			"error":      err.Error(),
+		}).Warn("auth service: не удалось отправить уведомление об отзыве сессии")
+	}
 }
 
 // ListSessions возвращает список активных сессий пользователя.
@@ -285,14 +517,42 @@ func (s *AuthService) ListSessions(ctx context.Context, userID uuid.UUID) ([]mod
 	return s.repo.ListSessions(ctx, userID)
 }
 
-// DeleteSession удаляет сессию по идентификатору.
+// DeleteSession удаляет сессию по идентификатору; её access токены перестают приниматься сразу.
 func (s *AuthService) DeleteSession(ctx context.Context, sessionID uuid.UUID, userID uuid.UUID) error {
-	return s.repo.DeleteSessionByID(ctx, sessionID, userID)
+	if err := s.repo.DeleteSessionByID(ctx, sessionID, userID); err != nil {
+		return err
+	}
+	s.sessionsRevoked(userID, sessionID)
+	return nil
 }
 
 // DeleteAllSessionsExcept удаляет все сессии пользователя кроме текущей.
 func (s *AuthService) DeleteAllSessionsExcept(ctx context.Context, userID uuid.UUID, currentRefreshToken string) error {
-	return s.repo.DeleteAllSessionsExcept(ctx, userID, currentRefreshToken)
+	deleted, err := s.repo.DeleteAllSessionsExcept(ctx, userID, HashRefreshToken(currentRefreshToken))
+	if err != nil {
+		return err
+	}
+	s.sessionsRevoked(userID, deleted...)
+	return nil
+}
+
+func (s *AuthService) sessionsRevoked(userID uuid.UUID, sessionIDs ...uuid.UUID) {
+	if s.revoker != nil && len(sessionIDs) > 0 {
+		s.revoker.SessionsRevoked(userID, sessionIDs...)
+	}
+}
+
+// applySessionMeta переносит в сессию User-Agent и IP клиента.
+func applySessionMeta(session *models.Session, meta map[string]string) {
+	if meta == nil {
+		return
+	}
+	if ua, ok := meta["user_agent"]; ok {
+		session.UserAgent = &ua
+	}
+	if ip, ok := meta["ip"]; ok {
+		session.IPAddress = &ip
+	}
 }
 
 // deriveUsername формирует красивый username из email.
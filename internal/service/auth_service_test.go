@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -17,7 +18,8 @@ type mockAuthRepository struct {
 	usersByEmail map[string]*models.User
 	usersByID    map[uuid.UUID]*models.User
 	profiles     map[uuid.UUID]*models.Profile
-	sessions     map[string]*models.Session
+	sessions     map[uuid.UUID]*models.Session
+	tokens       map[string]*models.RefreshToken
 }
 
 func newMockAuthRepository() *mockAuthRepository {
@@ -25,7 +27,8 @@ func newMockAuthRepository() *mockAuthRepository {
 		usersByEmail: make(map[string]*models.User),
 		usersByID:    make(map[uuid.UUID]*models.User),
 		profiles:     make(map[uuid.UUID]*models.Profile),
-		sessions:     make(map[string]*models.Session),
+		sessions:     make(map[uuid.UUID]*models.Session),
+		tokens:       make(map[string]*models.RefreshToken),
 	}
 }
 
@@ -66,18 +69,51 @@ func (m *mockAuthRepository) UpsertProfile(ctx context.Context, profile *models.
 	return nil
 }
 
-func (m *mockAuthRepository) CreateSession(ctx context.Context, session *models.Session) error {
-	session.ID = uuid.New()
+func (m *mockAuthRepository) CreateSession(ctx context.Context, session *models.Session, refreshTokenHash string) error {
+	if session.ID == uuid.Nil {
+		session.ID = uuid.New()
+	}
 	session.CreatedAt = time.Now()
-	m.sessions[session.RefreshToken] = session
+	m.sessions[session.ID] = session
+	m.tokens[refreshTokenHash] = &models.RefreshToken{TokenHash: refreshTokenHash, SessionID: session.ID, UserID: session.UserID, ExpiresAt: session.ExpiresAt}
+	return nil
+}
+
+func (m *mockAuthRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	token, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, repository.ErrRefreshTokenNotFound
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (m *mockAuthRepository) RotateRefreshToken(ctx context.Context, session *models.Session, oldTokenHash, newTokenHash string) error {
+	old, ok := m.tokens[oldTokenHash]
+	if !ok || old.RotatedAt != nil {
+		return repository.ErrRefreshTokenRotated
+	}
+	now := time.Now()
+	old.RotatedAt = &now
+	m.tokens[newTokenHash] = &models.RefreshToken{TokenHash: newTokenHash, SessionID: session.ID, UserID: session.UserID, ExpiresAt: session.ExpiresAt}
+	m.sessions[session.ID].ExpiresAt = session.ExpiresAt
 	return nil
 }
 
-func (m *mockAuthRepository) DeleteSession(ctx context.Context, refreshToken string) error {
-	delete(m.sessions, refreshToken)
+func (m *mockAuthRepository) RevokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	m.deleteSession(sessionID)
 	return nil
 }
 
+func (m *mockAuthRepository) deleteSession(sessionID uuid.UUID) {
+	delete(m.sessions, sessionID)
+	for hash, token := range m.tokens {
+		if token.SessionID == sessionID {
+			delete(m.tokens, hash)
+		}
+	}
+}
+
 func (m *mockAuthRepository) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
 	var sessions []models.Session
 	for _, s := range m.sessions {
@@ -89,21 +125,43 @@ func (m *mockAuthRepository) ListSessions(ctx context.Context, userID uuid.UUID)
 }
 
 func (m *mockAuthRepository) DeleteSessionByID(ctx context.Context, sessionID uuid.UUID, userID uuid.UUID) error {
-	for token, s := range m.sessions {
-		if s.ID == sessionID && s.UserID == userID {
-			delete(m.sessions, token)
-			return nil
-		}
+	if s, ok := m.sessions[sessionID]; ok && s.UserID == userID {
+		m.deleteSession(sessionID)
 	}
 	return nil
 }
 
-func (m *mockAuthRepository) DeleteAllSessionsExcept(ctx context.Context, userID uuid.UUID, exceptRefreshToken string) error {
-	for token, s := range m.sessions {
-		if s.UserID == userID && token != exceptRefreshToken {
-			delete(m.sessions, token)
+func (m *mockAuthRepository) DeleteAllSessionsExcept(ctx context.Context, userID uuid.UUID, exceptRefreshTokenHash string) ([]uuid.UUID, error) {
+	var keep uuid.UUID
+	if token, ok := m.tokens[exceptRefreshTokenHash]; ok && token.RotatedAt == nil {
+		keep = token.SessionID
+	}
+	var deleted []uuid.UUID
+	for id, s := range m.sessions {
+		if s.UserID == userID && id != keep {
+			m.deleteSession(id)
+			deleted = append(deleted, id)
 		}
 	}
+	return deleted, nil
+}
+
+func (m *mockAuthRepository) SessionExists(ctx context.Context, sessionID, userID uuid.UUID) (bool, error) {
+	s, ok := m.sessions[sessionID]
+	return ok && s.UserID == userID, nil
+}
+
+type recordedBroadcast struct {
+	userID uuid.UUID
+	event  string
+}
+
+type mockWSNotifier struct {
+	events []recordedBroadcast
+}
+
+func (m *mockWSNotifier) BroadcastToUser(userID uuid.UUID, event string, data interface{}) error {
+	m.events = append(m.events, recordedBroadcast{userID: userID, event: event})
 	return nil
 }
 
@@ -166,11 +224,13 @@ func TestAuthService_Refresh(t *testing.T) {
 		Email:        "user@example.com",
 		PasswordHash: string(hash),
 		Role:         "freelancer",
+		IsActive:     true,
 	}
 	repo.usersByEmail[user.Email] = user
 	repo.usersByID[user.ID] = user
 
-	tokenPair, accessExp, refreshExp, err := tokenManager.GeneratePair(user)
+	sessionID := uuid.New()
+	tokenPair, accessExp, refreshExp, err := tokenManager.GeneratePair(user, sessionID)
 	if err != nil {
 		t.Fatalf("не удалось сгенерировать токены: %v", err)
 	}
@@ -178,11 +238,8 @@ func TestAuthService_Refresh(t *testing.T) {
 		t.Fatalf("access должен истекать раньше refresh")
 	}
 
-	repo.sessions[tokenPair.RefreshToken] = &models.Session{
-		ID:           uuid.New(),
-		UserID:       user.ID,
-		RefreshToken: tokenPair.RefreshToken,
-		ExpiresAt:    refreshExp,
+	if err := repo.CreateSession(ctx, &models.Session{ID: sessionID, UserID: user.ID, ExpiresAt: refreshExp}, HashRefreshToken(tokenPair.RefreshToken)); err != nil {
+		t.Fatalf("не удалось создать сессию: %v", err)
 	}
 
 	newPair, err := service.Refresh(ctx, tokenPair.RefreshToken, nil)
@@ -194,3 +251,81 @@ func TestAuthService_Refresh(t *testing.T) {
 		t.Fatalf("ожидался новый refresh токен")
 	}
 }
+
+func TestAuthService_Register_PrivilegedRoleRejected(t *testing.T) {
+	repo := newMockAuthRepository()
+	tokenManager := NewTokenManager("access", "refresh", time.Minute, time.Hour)
+	service := NewAuthService(repo, tokenManager)
+
+	for _, role := range []string{"admin", "moderator"} {
+		_, err := service.Register(context.Background(), RegisterInput{
+			Email:    role + "@example.com",
+			Password: "password123",
+			Role:     role,
+		}, nil)
+		if err == nil {
+			t.Fatalf("регистрация с ролью %s должна быть запрещена", role)
+		}
+	}
+
+	if len(repo.sessions) != 0 {
+		t.Fatalf("сессии не должны создаваться, получили %d", len(repo.sessions))
+	}
+}
+
+func TestAuthService_Refresh_ReuseRevokesSession(t *testing.T) {
+	repo := newMockAuthRepository()
+	tokenManager := NewTokenManager("access-secret", "refresh-secret", time.Minute, time.Hour)
+	service := NewAuthService(repo, tokenManager)
+	hub := &mockWSNotifier{}
+	service.SetHub(hub)
+
+	ctx := context.Background()
+	res, err := service.Register(ctx, RegisterInput{Email: "reuse@example.com", Password: "password123"}, nil)
+	if err != nil {
+		t.Fatalf("register returned error: %v", err)
+	}
+	stolen := res.TokenPair.RefreshToken
+
+	rotated, err := service.Refresh(ctx, stolen, nil)
+	if err != nil {
+		t.Fatalf("refresh вернул ошибку: %v", err)
+	}
+	if len(repo.sessions) != 1 {
+		t.Fatalf("ротация не должна создавать новую сессию, получили %d", len(repo.sessions))
+	}
+
+	if _, err := service.Refresh(ctx, stolen, map[string]string{"ip": "10.0.0.1"}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("ожидалась ErrRefreshTokenReused, получили %v", err)
+	}
+	if len(repo.sessions) != 0 {
+		t.Fatalf("сессия должна быть отозвана, осталось %d", len(repo.sessions))
+	}
+	if len(hub.events) != 1 || hub.events[0].event != "session_revoked" || hub.events[0].userID != res.User.ID {
+		t.Fatalf("ожидалось уведомление session_revoked, получили %+v", hub.events)
+	}
+
+	// Токен, выпущенный после ротации, принадлежит отозванной сессии
+	if _, err := service.Refresh(ctx, rotated.RefreshToken, nil); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("ожидалась ErrSessionRevoked, получили %v", err)
+	}
+}
+
+func TestAuthService_Refresh_UnknownTokenRejected(t *testing.T) {
+	repo := newMockAuthRepository()
+	tokenManager := NewTokenManager("access-secret", "refresh-secret", time.Minute, time.Hour)
+	service := NewAuthService(repo, tokenManager)
+
+	user := &models.User{ID: uuid.New(), Email: "ghost@example.com", Role: "freelancer"}
+	repo.usersByID[user.ID] = user
+
+	// Подпись верна, но токен не выпускался как часть сессии
+	tokenPair, _, _, err := tokenManager.GeneratePair(user, uuid.New())
+	if err != nil {
+		t.Fatalf("не удалось сгенерировать токены: %v", err)
+	}
+
+	if _, err := service.Refresh(context.Background(), tokenPair.RefreshToken, nil); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("ожидалась ErrSessionRevoked, получили %v", err)
+	}
+}
@@ -3,9 +3,14 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+	"github.com/ignatzorin/freelance-backend/internal/logger"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/repository"
 )
@@ -14,17 +19,69 @@ var (
 	ErrDisputeAlreadyExists = errors.New("dispute already exists for this order")
 	ErrNotParticipant       = errors.New("user is not a participant of this escrow")
 	ErrEscrowNotHeld        = errors.New("escrow is not in held status")
+	ErrDisputeNotAssigned   = errors.New("dispute must be assigned before it can be resolved")
+	ErrInvalidClientPercent = errors.New("client_percent must be between 0 and 100")
+	ErrEvidenceEmpty        = errors.New("evidence must contain a note, a message or an attachment")
+	ErrEvidenceMessage      = errors.New("message does not belong to the order chat")
+	ErrEvidenceMedia        = errors.New("attachment does not belong to the user")
 )
 
+// DisputeRepository описывает хранилище споров и доказательств.
+type DisputeRepository interface {
+	Create(ctx context.Context, d *models.Dispute) error
+	CreateForMilestone(ctx context.Context, d *models.Dispute) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Dispute, error)
+	GetByOrderID(ctx context.Context, orderID uuid.UUID) (*models.Dispute, error)
+	GetByMilestoneID(ctx context.Context, milestoneID uuid.UUID) (*models.Dispute, error)
+	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Dispute, error)
+	ListAll(ctx context.Context, status string, limit, offset int) ([]models.Dispute, error)
+	Assign(ctx context.Context, id, adminID uuid.UUID) (*models.Dispute, error)
+	MarkResolved(id uuid.UUID, status, resolution string, resolvedBy uuid.UUID, clientPercent float64, clientAmount, freelancerAmount valueobject.Amount) func(ctx context.Context, tx *sqlx.Tx) error
+	AddEvidence(ctx context.Context, e *models.DisputeEvidence) error
+	ListEvidence(ctx context.Context, disputeID uuid.UUID) ([]models.DisputeEvidence, error)
+	ListOrderMessages(ctx context.Context, orderID, clientID, freelancerID uuid.UUID) ([]models.Message, error)
+	MessageInOrderChat(ctx context.Context, messageID, orderID uuid.UUID) (bool, error)
+	MediaOwnedBy(ctx context.Context, mediaID, userID uuid.UUID) (bool, error)
+}
+
+// DisputePaymentRepository описывает операции с escrow, нужные для споров.
+type DisputePaymentRepository interface {
+	GetEscrowByID(ctx context.Context, id uuid.UUID) (*models.Escrow, error)
+	GetEscrowByOrderID(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error)
+	GetEscrowByMilestoneID(ctx context.Context, milestoneID uuid.UUID) (*models.Escrow, error)
+	SplitEscrow(ctx context.Context, escrowID uuid.UUID, clientAmount, freelancerAmount valueobject.Amount, description string, settle func(ctx context.Context, tx *sqlx.Tx) error) (*models.Escrow, error)
+}
+
 type DisputeService struct {
-	disputeRepo *repository.DisputeRepository
-	paymentRepo *repository.PaymentRepository
+	disputeRepo DisputeRepository
+	paymentRepo DisputePaymentRepository
+	hub         WSNotifier
+}
+
+// DisputeCase собирает всё, что нужно администратору для решения спора.
+type DisputeCase struct {
+	Dispute  *models.Dispute          `json:"dispute"`
+	Escrow   *models.Escrow           `json:"escrow"`
+	Evidence []models.DisputeEvidence `json:"evidence"`
+	Messages []models.Message         `json:"messages"`
+}
+
+// EvidenceInput описывает доказательство, прикладываемое к спору.
+type EvidenceInput struct {
+	Content   *string
+	MessageID *uuid.UUID
+	MediaID   *uuid.UUID
 }
 
-func NewDisputeService(dr *repository.DisputeRepository, pr *repository.PaymentRepository) *DisputeService {
+func NewDisputeService(dr DisputeRepository, pr DisputePaymentRepository) *DisputeService {
 	return &DisputeService{disputeRepo: dr, paymentRepo: pr}
 }
 
+// SetHub устанавливает WebSocket hub для уведомления участников спора.
+func (s *DisputeService) SetHub(hub WSNotifier) {
+	s.hub = hub
+}
+
 func (s *DisputeService) CreateDispute(ctx context.Context, orderID, initiatorID uuid.UUID, reason string) (*models.Dispute, error) {
 	// Проверяем escrow
 	escrow, err := s.paymentRepo.GetEscrowByOrderID(ctx, orderID)
@@ -57,6 +114,42 @@ func (s *DisputeService) CreateDispute(ctx context.Context, orderID, initiatorID
 	return d, nil
 }
 
+// CreateMilestoneDispute открывает спор по отдельному этапу заказа.
+// Замораживаются только средства этого этапа.
+func (s *DisputeService) CreateMilestoneDispute(ctx context.Context, orderID, milestoneID, initiatorID uuid.UUID, reason string) (*models.Dispute, error) {
+	escrow, err := s.paymentRepo.GetEscrowByMilestoneID(ctx, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if escrow.OrderID != orderID {
+		return nil, ErrMilestoneWrongOrder
+	}
+	if escrow.Status != models.EscrowStatusHeld {
+		return nil, ErrEscrowNotHeld
+	}
+	if escrow.ClientID != initiatorID && escrow.FreelancerID != initiatorID {
+		return nil, ErrNotParticipant
+	}
+
+	existing, err := s.disputeRepo.GetByMilestoneID(ctx, milestoneID)
+	if err == nil && existing != nil {
+		return nil, ErrDisputeAlreadyExists
+	}
+
+	d := &models.Dispute{
+		EscrowID:    escrow.ID,
+		OrderID:     orderID,
+		MilestoneID: &milestoneID,
+		InitiatorID: initiatorID,
+		Reason:      reason,
+		Status:      models.DisputeStatusOpen,
+	}
+	if err := s.disputeRepo.CreateForMilestone(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
 func (s *DisputeService) GetDispute(ctx context.Context, orderID uuid.UUID) (*models.Dispute, error) {
 	return s.disputeRepo.GetByOrderID(ctx, orderID)
 }
@@ -64,3 +157,193 @@ func (s *DisputeService) GetDispute(ctx context.Context, orderID uuid.UUID) (*mo
 func (s *DisputeService) ListUserDisputes(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Dispute, error) {
 	return s.disputeRepo.ListByUser(ctx, userID, limit, offset)
 }
+
+// ListDisputes возвращает очередь споров для администратора.
+func (s *DisputeService) ListDisputes(ctx context.Context, status string, limit, offset int) ([]models.Dispute, error) {
+	return s.disputeRepo.ListAll(ctx, status, limit, offset)
+}
+
+// GetDisputeCase возвращает спор вместе с escrow, доказательствами и перепиской сторон.
+func (s *DisputeService) GetDisputeCase(ctx context.Context, disputeID uuid.UUID) (*DisputeCase, error) {
+	d, err := s.disputeRepo.GetByID(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	escrow, err := s.paymentRepo.GetEscrowByID(ctx, d.EscrowID)
+	if err != nil {
+		return nil, err
+	}
+	evidence, err := s.disputeRepo.ListEvidence(ctx, d.ID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.disputeRepo.ListOrderMessages(ctx, d.OrderID, escrow.ClientID, escrow.FreelancerID)
+	if err != nil {
+		return nil, err
+	}
+	return &DisputeCase{Dispute: d, Escrow: escrow, Evidence: evidence, Messages: messages}, nil
+}
+
+// AssignDispute назначает администратора и переводит спор на рассмотрение.
+func (s *DisputeService) AssignDispute(ctx context.Context, disputeID, adminID uuid.UUID) (*models.Dispute, error) {
+	d, err := s.disputeRepo.Assign(ctx, disputeID, adminID)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyParties(ctx, d, "dispute_under_review")
+	return d, nil
+}
+
+// AddEvidence прикладывает к спору заметку, сообщение из чата заказа или файл.
+// Доказательства принимаются от сторон сделки и от администратора.
+func (s *DisputeService) AddEvidence(ctx context.Context, disputeID, authorID uuid.UUID, role string, in EvidenceInput) (*models.DisputeEvidence, error) {
+	d, escrow, err := s.disputeForUser(ctx, disputeID, authorID, role)
+	if err != nil {
+		return nil, err
+	}
+	if d.Status != models.DisputeStatusOpen && d.Status != models.DisputeStatusUnderReview {
+		return nil, repository.ErrDisputeClosed
+	}
+
+	if in.Content != nil {
+		trimmed := strings.TrimSpace(*in.Content)
+		in.Content = &trimmed
+		if trimmed == "" {
+			in.Content = nil
+		}
+	}
+
+	e := &models.DisputeEvidence{
+		DisputeID: d.ID,
+		AuthorID:  authorID,
+		Content:   in.Content,
+		MessageID: in.MessageID,
+		MediaID:   in.MediaID,
+	}
+	switch {
+	case in.MessageID != nil:
+		ok, err := s.disputeRepo.MessageInOrderChat(ctx, *in.MessageID, escrow.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrEvidenceMessage
+		}
+		e.Kind = models.DisputeEvidenceMessage
+	case in.MediaID != nil:
+		ok, err := s.disputeRepo.MediaOwnedBy(ctx, *in.MediaID, authorID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrEvidenceMedia
+		}
+		e.Kind = models.DisputeEvidenceAttachment
+	case in.Content != nil:
+		e.Kind = models.DisputeEvidenceNote
+	default:
+		return nil, ErrEvidenceEmpty
+	}
+
+	if err := s.disputeRepo.AddEvidence(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ListEvidence возвращает доказательства по спору сторонам сделки и администратору.
+func (s *DisputeService) ListEvidence(ctx context.Context, disputeID, userID uuid.UUID, role string) ([]models.DisputeEvidence, error) {
+	d, _, err := s.disputeForUser(ctx, disputeID, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	return s.disputeRepo.ListEvidence(ctx, d.ID)
+}
+
+// ResolveDispute закрывает спор и делит escrow между сторонами. clientPercent —
+// доля суммы в процентах, которая возвращается заказчику, остаток получает фрилансер.
+func (s *DisputeService) ResolveDispute(ctx context.Context, disputeID, adminID uuid.UUID, clientPercent float64, resolution string) (*models.Dispute, error) {
+	if clientPercent < 0 || clientPercent > 100 {
+		return nil, ErrInvalidClientPercent
+	}
+
+	d, err := s.disputeRepo.GetByID(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	switch d.Status {
+	case models.DisputeStatusUnderReview:
+	case models.DisputeStatusOpen:
+		return nil, ErrDisputeNotAssigned
+	default:
+		return nil, repository.ErrDisputeClosed
+	}
+
+	escrow, err := s.paymentRepo.GetEscrowByID(ctx, d.EscrowID)
+	if err != nil {
+		return nil, err
+	}
+
+	clientAmount, freelancerAmount := splitAmount(escrow.Amount, clientPercent)
+	status := models.DisputeStatusResolvedSplit
+	switch clientPercent {
+	case 100:
+		status = models.DisputeStatusResolvedClient
+	case 0:
+		status = models.DisputeStatusResolvedFreelancer
+	}
+
+	description := fmt.Sprintf("Решение по спору: заказчику %.0f%%, исполнителю %.0f%%", clientPercent, 100-clientPercent)
+	settle := s.disputeRepo.MarkResolved(d.ID, status, resolution, adminID, clientPercent, clientAmount, freelancerAmount)
+	if _, err := s.paymentRepo.SplitEscrow(ctx, escrow.ID, clientAmount, freelancerAmount, description, settle); err != nil {
+		return nil, err
+	}
+
+	resolved, err := s.disputeRepo.GetByID(ctx, d.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyParties(ctx, resolved, "dispute_resolved")
+	return resolved, nil
+}
+
+// disputeForUser загружает спор и проверяет доступ: администратор или сторона сделки.
+func (s *DisputeService) disputeForUser(ctx context.Context, disputeID, userID uuid.UUID, role string) (*models.Dispute, *models.Escrow, error) {
+	d, err := s.disputeRepo.GetByID(ctx, disputeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	escrow, err := s.paymentRepo.GetEscrowByID(ctx, d.EscrowID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if role != "admin" && escrow.ClientID != userID && escrow.FreelancerID != userID {
+		return nil, nil, ErrNotParticipant
+	}
+	return d, escrow, nil
+}
+
+// notifyParties отправляет событие по спору обеим сторонам сделки.
+func (s *DisputeService) notifyParties(ctx context.Context, d *models.Dispute, event string) {
+	if s.hub == nil {
+		return
+	}
+	escrow, err := s.paymentRepo.GetEscrowByID(ctx, d.EscrowID)
+	if err != nil {
+		return
+	}
+	for _, userID := range []uuid.UUID{escrow.ClientID, escrow.FreelancerID} {
+		if err := s.hub.BroadcastToUser(userID, event, d); err != nil && logger.Log != nil {
+			logger.Log.WithFields(map[string]interface{}{
+				"dispute_id": d.ID,
+				"error":      err.Error(),
+			}).Warn("dispute service: не удалось отправить уведомление")
+		}
+	}
+}
+
+// splitAmount делит сумму по процентам с точностью до копейки.
+func splitAmount(amount valueobject.Amount, clientPercent float64) (clientAmount, freelancerAmount valueobject.Amount) {
+	clientAmount = amount.Percent(clientPercent)
+	return clientAmount, amount - clientAmount
+}
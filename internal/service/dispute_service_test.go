@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+func TestSplitAmount(t *testing.T) {
+	rub := valueobject.AmountFromFloat
+
+	clientAmount, freelancerAmount := splitAmount(rub(1000), 30)
+	assert.Equal(t, rub(300), clientAmount)
+	assert.Equal(t, rub(700), freelancerAmount)
+
+	// Остаток от округления достаётся фрилансеру, сумма не теряется
+	clientAmount, freelancerAmount = splitAmount(rub(100.01), 50)
+	assert.Equal(t, rub(50.01), clientAmount)
+	assert.Equal(t, rub(50), freelancerAmount)
+	assert.Equal(t, rub(100.01), clientAmount+freelancerAmount)
+
+	clientAmount, freelancerAmount = splitAmount(rub(250), 100)
+	assert.Equal(t, rub(250), clientAmount)
+	assert.Equal(t, valueobject.Amount(0), freelancerAmount)
+}
+
+func TestDisputeService_ResolveDispute_InvalidPercent(t *testing.T) {
+	svc := NewDisputeService(nil, nil)
+
+	_, err := svc.ResolveDispute(context.Background(), uuid.New(), uuid.New(), 120, "решение")
+	assert.ErrorIs(t, err, ErrInvalidClientPercent)
+
+	_, err = svc.ResolveDispute(context.Background(), uuid.New(), uuid.New(), -1, "решение")
+	assert.ErrorIs(t, err, ErrInvalidClientPercent)
+}
+
+type fakeDisputeRepo struct {
+	DisputeRepository
+	disputes     map[uuid.UUID]*models.Dispute
+	evidence     []models.DisputeEvidence
+	chatMessages map[uuid.UUID]bool
+	ownedMedia   map[uuid.UUID]bool
+}
+
+func (r *fakeDisputeRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Dispute, error) {
+	d, ok := r.disputes[id]
+	if !ok {
+		return nil, repository.ErrDisputeNotFound
+	}
+	return d, nil
+}
+
+func (r *fakeDisputeRepo) AddEvidence(ctx context.Context, e *models.DisputeEvidence) error {
+	r.evidence = append(r.evidence, *e)
+	return nil
+}
+
+func (r *fakeDisputeRepo) ListEvidence(ctx context.Context, disputeID uuid.UUID) ([]models.DisputeEvidence, error) {
+	return r.evidence, nil
+}
+
+func (r *fakeDisputeRepo) MessageInOrderChat(ctx context.Context, messageID, orderID uuid.UUID) (bool, error) {
+	return r.chatMessages[messageID], nil
+}
+
+func (r *fakeDisputeRepo) MediaOwnedBy(ctx context.Context, mediaID, userID uuid.UUID) (bool, error) {
+	return r.ownedMedia[mediaID], nil
+}
+
+func (r *fakeDisputeRepo) MarkResolved(id uuid.UUID, status, resolution string, resolvedBy uuid.UUID, clientPercent float64, clientAmount, freelancerAmount valueobject.Amount) func(ctx context.Context, tx *sqlx.Tx) error {
+	return func(ctx context.Context, tx *sqlx.Tx) error {
+		d := r.disputes[id]
+		d.Status = status
+		d.Resolution = &resolution
+		d.ResolvedBy = &resolvedBy
+		return nil
+	}
+}
+
+type fakeDisputePayments struct {
+	DisputePaymentRepository
+	escrow *models.Escrow
+	splits [][2]valueobject.Amount
+}
+
+func (p *fakeDisputePayments) GetEscrowByID(ctx context.Context, id uuid.UUID) (*models.Escrow, error) {
+	if p.escrow == nil || p.escrow.ID != id {
+		return nil, repository.ErrEscrowNotFound
+	}
+	return p.escrow, nil
+}
+
+func (p *fakeDisputePayments) SplitEscrow(ctx context.Context, escrowID uuid.UUID, clientAmount, freelancerAmount valueobject.Amount, description string, settle func(ctx context.Context, tx *sqlx.Tx) error) (*models.Escrow, error) {
+	p.splits = append(p.splits, [2]valueobject.Amount{clientAmount, freelancerAmount})
+	return p.escrow, settle(ctx, nil)
+}
+
+type disputeFixture struct {
+	svc        *DisputeService
+	repo       *fakeDisputeRepo
+	payments   *fakeDisputePayments
+	dispute    *models.Dispute
+	client     uuid.UUID
+	freelancer uuid.UUID
+}
+
+func newDisputeFixture(status string) *disputeFixture {
+	escrow := &models.Escrow{
+		ID:           uuid.New(),
+		OrderID:      uuid.New(),
+		ClientID:     uuid.New(),
+		FreelancerID: uuid.New(),
+		Amount:       valueobject.AmountFromFloat(1000),
+		Status:       models.EscrowStatusDisputed,
+	}
+	dispute := &models.Dispute{ID: uuid.New(), EscrowID: escrow.ID, OrderID: escrow.OrderID, Status: status}
+	repo := &fakeDisputeRepo{
+		disputes:     map[uuid.UUID]*models.Dispute{dispute.ID: dispute},
+		chatMessages: map[uuid.UUID]bool{},
+		ownedMedia:   map[uuid.UUID]bool{},
+	}
+	payments := &fakeDisputePayments{escrow: escrow}
+	return &disputeFixture{
+		svc:        NewDisputeService(repo, payments),
+		repo:       repo,
+		payments:   payments,
+		dispute:    dispute,
+		client:     escrow.ClientID,
+		freelancer: escrow.FreelancerID,
+	}
+}
+
+func TestDisputeService_AddEvidence_Access(t *testing.T) {
+	f := newDisputeFixture(models.DisputeStatusUnderReview)
+	ctx := context.Background()
+	note := "Макет сдан 1 марта"
+
+	_, err := f.svc.AddEvidence(ctx, f.dispute.ID, uuid.New(), "freelancer", EvidenceInput{Content: &note})
+	assert.ErrorIs(t, err, ErrNotParticipant)
+
+	_, err = f.svc.ListEvidence(ctx, f.dispute.ID, uuid.New(), "moderator")
+	assert.ErrorIs(t, err, ErrNotParticipant)
+
+	_, err = f.svc.AddEvidence(ctx, uuid.New(), f.client, "client", EvidenceInput{Content: &note})
+	assert.ErrorIs(t, err, repository.ErrDisputeNotFound)
+
+	// Стороны сделки и администратор могут прикладывать доказательства
+	for _, author := range []struct {
+		id   uuid.UUID
+		role string
+	}{{f.client, "client"}, {f.freelancer, "freelancer"}, {uuid.New(), "admin"}} {
+		evidence, err := f.svc.AddEvidence(ctx, f.dispute.ID, author.id, author.role, EvidenceInput{Content: &note})
+		if assert.NoError(t, err, author.role) {
+			assert.Equal(t, models.DisputeEvidenceNote, evidence.Kind)
+		}
+	}
+	assert.Len(t, f.repo.evidence, 3)
+}
+
+func TestDisputeService_AddEvidence_Validation(t *testing.T) {
+	f := newDisputeFixture(models.DisputeStatusOpen)
+	ctx := context.Background()
+	blank := "   "
+	foreignMessage, foreignMedia := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name  string
+		input EvidenceInput
+		want  error
+	}{
+		{"empty", EvidenceInput{}, ErrEvidenceEmpty},
+		{"blank note", EvidenceInput{Content: &blank}, ErrEvidenceEmpty},
+		{"message from another chat", EvidenceInput{MessageID: &foreignMessage}, ErrEvidenceMessage},
+		{"someone else's file", EvidenceInput{MediaID: &foreignMedia}, ErrEvidenceMedia},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.svc.AddEvidence(ctx, f.dispute.ID, f.client, "client", tt.input)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+	assert.Empty(t, f.repo.evidence)
+
+	// После решения спора доказательства не принимаются
+	f.dispute.Status = models.DisputeStatusResolvedClient
+	note := "Поздно"
+	_, err := f.svc.AddEvidence(ctx, f.dispute.ID, f.client, "client", EvidenceInput{Content: &note})
+	assert.ErrorIs(t, err, repository.ErrDisputeClosed)
+}
+
+func TestDisputeService_ResolveDispute_RequiresReview(t *testing.T) {
+	ctx := context.Background()
+
+	f := newDisputeFixture(models.DisputeStatusOpen)
+	_, err := f.svc.ResolveDispute(ctx, f.dispute.ID, uuid.New(), 50, "решение")
+	assert.ErrorIs(t, err, ErrDisputeNotAssigned)
+
+	f = newDisputeFixture(models.DisputeStatusResolvedFreelancer)
+	_, err = f.svc.ResolveDispute(ctx, f.dispute.ID, uuid.New(), 50, "решение")
+	assert.ErrorIs(t, err, repository.ErrDisputeClosed)
+	assert.Empty(t, f.payments.splits)
+
+	_, err = f.svc.ResolveDispute(ctx, uuid.New(), uuid.New(), 50, "решение")
+	assert.ErrorIs(t, err, repository.ErrDisputeNotFound)
+}
+
+func TestDisputeService_ResolveDispute_SplitsEscrow(t *testing.T) {
+	f := newDisputeFixture(models.DisputeStatusUnderReview)
+	adminID := uuid.New()
+
+	resolved, err := f.svc.ResolveDispute(context.Background(), f.dispute.ID, adminID, 30, "Работа выполнена частично")
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]valueobject.Amount{{valueobject.AmountFromFloat(300), valueobject.AmountFromFloat(700)}}, f.payments.splits)
+	assert.Equal(t, models.DisputeStatusResolvedSplit, resolved.Status)
+	assert.Equal(t, &adminID, resolved.ResolvedBy)
+}
+
+func TestDisputeService_ResolveDispute_Status(t *testing.T) {
+	tests := []struct {
+		clientPercent float64
+		want          string
+	}{
+		{100, models.DisputeStatusResolvedClient},
+		{0, models.DisputeStatusResolvedFreelancer},
+		{50, models.DisputeStatusResolvedSplit},
+		{99.5, models.DisputeStatusResolvedSplit},
+	}
+	for _, tt := range tests {
+		f := newDisputeFixture(models.DisputeStatusUnderReview)
+		resolved, err := f.svc.ResolveDispute(context.Background(), f.dispute.ID, uuid.New(), tt.clientPercent, "решение")
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, resolved.Status, tt.clientPercent)
+	}
+}
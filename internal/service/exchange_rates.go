@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+var ErrExchangeRateUnavailable = errors.New("exchange rate unavailable")
+
+// ExchangeRateProvider возвращает курс пересчёта: сколько единиц валюты to стоит одна единица from.
+type ExchangeRateProvider interface {
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// StaticExchangeRates курсы, заданные относительно базовой валюты:
+// Prices[code] — стоимость одной единицы валюты code в базовой валюте.
+type StaticExchangeRates struct {
+	Base   string             `json:"base"`
+	Prices map[string]float64 `json:"rates"`
+}
+
+// NewStaticExchangeRates создаёт провайдер с фиксированными курсами.
+func NewStaticExchangeRates(base string, prices map[string]float64) *StaticExchangeRates {
+	normalized := make(map[string]float64, len(prices))
+	for code, price := range prices {
+		normalized[strings.ToUpper(code)] = price
+	}
+	return &StaticExchangeRates{Base: strings.ToUpper(base), Prices: normalized}
+}
+
+// DefaultExchangeRates встроенные курсы для локальной разработки.
+func DefaultExchangeRates() *StaticExchangeRates {
+	return NewStaticExchangeRates(models.CurrencyRUB, map[string]float64{
+		models.CurrencyUSD: 90,
+		models.CurrencyEUR: 98,
+	})
+}
+
+// LoadExchangeRatesFile читает курсы из JSON-файла вида
+// {"base": "RUB", "rates": {"USD": 90, "EUR": 98}}.
+func LoadExchangeRatesFile(path string) (*StaticExchangeRates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("exchange rates: read %s: %w", path, err)
+	}
+	var file StaticExchangeRates
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("exchange rates: parse %s: %w", path, err)
+	}
+	if file.Base == "" {
+		return nil, fmt.Errorf("exchange rates: %s: base currency is required", path)
+	}
+	for code, price := range file.Prices {
+		if price <= 0 {
+			return nil, fmt.Errorf("exchange rates: %s: rate for %s must be positive", path, code)
+		}
+	}
+	return NewStaticExchangeRates(file.Base, file.Prices), nil
+}
+
+func (r *StaticExchangeRates) Rate(ctx context.Context, from, to string) (float64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1, nil
+	}
+	fromPrice, ok := r.price(from)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrExchangeRateUnavailable, from)
+	}
+	toPrice, ok := r.price(to)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrExchangeRateUnavailable, to)
+	}
+	return fromPrice / toPrice, nil
+}
+
+// price возвращает стоимость единицы валюты в базовой валюте.
+func (r *StaticExchangeRates) price(code string) (float64, bool) {
+	if code == r.Base {
+		return 1, true
+	}
+	price, ok := r.Prices[code]
+	return price, ok && price > 0
+}
+
+// convertAmount пересчитывает сумму в валюту to и округляет её до копеек.
+func convertAmount(ctx context.Context, rates ExchangeRateProvider, amount valueobject.Amount, from, to string) (*models.ConvertedAmount, error) {
+	rate, err := rates.Rate(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ConvertedAmount{
+		Amount:   amount.Mul(rate),
+		Currency: to,
+		Rate:     rate,
+	}, nil
+}
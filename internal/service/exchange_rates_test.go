@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+func TestStaticExchangeRates_Rate(t *testing.T) {
+	rates := NewStaticExchangeRates("rub", map[string]float64{"usd": 90, "EUR": 99})
+	ctx := context.Background()
+
+	rate, err := rates.Rate(ctx, models.CurrencyUSD, models.CurrencyRUB)
+	assert.NoError(t, err)
+	assert.Equal(t, 90.0, rate)
+
+	rate, err = rates.Rate(ctx, models.CurrencyRUB, models.CurrencyUSD)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.0/90, rate, 1e-9)
+
+	rate, err = rates.Rate(ctx, models.CurrencyEUR, models.CurrencyUSD)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.1, rate, 1e-9)
+
+	rate, err = rates.Rate(ctx, models.CurrencyEUR, models.CurrencyEUR)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, rate)
+
+	_, err = rates.Rate(ctx, "GBP", models.CurrencyRUB)
+	assert.ErrorIs(t, err, ErrExchangeRateUnavailable)
+}
+
+func TestLoadExchangeRatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"base": "USD", "rates": {"RUB": 0.011}}`), 0o600))
+
+	rates, err := LoadExchangeRatesFile(path)
+	assert.NoError(t, err)
+
+	rate, err := rates.Rate(context.Background(), models.CurrencyRUB, models.CurrencyUSD)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.011, rate, 1e-9)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"base": "USD", "rates": {"RUB": 0}}`), 0o600))
+	_, err = LoadExchangeRatesFile(path)
+	assert.Error(t, err)
+}
+
+func TestOrderService_ConvertProposalAmounts(t *testing.T) {
+	svc := &OrderService{rates: NewStaticExchangeRates(models.CurrencyRUB, map[string]float64{models.CurrencyUSD: 90})}
+	usd, rub := valueobject.AmountFromFloat(150), valueobject.AmountFromFloat(9000)
+	proposals := []models.Proposal{
+		{ProposedAmount: &usd, Currency: models.CurrencyUSD},
+		{ProposedAmount: &rub, Currency: models.CurrencyRUB},
+		{Currency: models.CurrencyUSD},
+	}
+
+	svc.convertProposalAmounts(context.Background(), &models.Order{Currency: models.CurrencyRUB}, proposals)
+
+	assert.Equal(t, &models.ConvertedAmount{Amount: valueobject.AmountFromFloat(13500), Currency: models.CurrencyRUB, Rate: 90}, proposals[0].ConvertedAmount)
+	assert.Nil(t, proposals[1].ConvertedAmount)
+	assert.Nil(t, proposals[2].ConvertedAmount)
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/logger"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+var (
+	// ErrKYCDocumentType возвращается для неизвестного типа документа.
+	ErrKYCDocumentType = errors.New("verification: тип документа должен быть passport, id_card или driver_license")
+	// ErrKYCDocumentsIncomplete возвращается, если не хватает обязательных фотографий.
+	ErrKYCDocumentsIncomplete = errors.New("verification: приложите лицевую сторону документа, фото с документом в руках и, кроме паспорта, оборот")
+	// ErrKYCDocumentInvalid возвращается для чужого, публичного или повторно приложенного файла.
+	ErrKYCDocumentInvalid = errors.New("verification: фотографии документа загружаются через POST /media/documents")
+	// ErrKYCAlreadyVerified возвращается при новой заявке от пользователя, уже прошедшего проверку.
+	ErrKYCAlreadyVerified = errors.New("verification: личность уже подтверждена")
+	// ErrKYCRejectReason возвращается при отклонении заявки без причины.
+	ErrKYCRejectReason = errors.New("verification: укажите причину отклонения")
+	// ErrKYCSelfReview возвращается модератору, который рассматривает свою заявку.
+	ErrKYCSelfReview = errors.New("verification: нельзя рассматривать собственную заявку")
+	// ErrKYCStatusInvalid возвращается для неизвестного статуса в фильтре очереди.
+	ErrKYCStatusInvalid = errors.New("verification: статус должен быть pending, approved или rejected")
+)
+
+// KYCRepository описывает зависимости KYCService от слоя хранилища.
+type KYCRepository interface {
+	CreateSubmission(ctx context.Context, submission *models.KYCSubmission) error
+	GetSubmission(ctx context.Context, id uuid.UUID) (*models.KYCSubmission, error)
+	GetLatestSubmission(ctx context.Context, userID uuid.UUID) (*models.KYCSubmission, error)
+	ListSubmissions(ctx context.Context, status string, limit, offset int) ([]models.KYCSubmission, error)
+	ReviewSubmission(ctx context.Context, id, reviewerID uuid.UUID, status string, reason *string) (*models.KYCSubmission, error)
+	IsIdentityVerified(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+// KYCMediaRepository описывает доступ KYCService к загруженным файлам.
+type KYCMediaRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.MediaFile, error)
+}
+
+// KYCStatus — состояние проверки личности пользователя и его последняя заявка.
+type KYCStatus struct {
+	IdentityVerified bool                  `json:"identity_verified"`
+	Submission       *models.KYCSubmission `json:"submission"`
+}
+
+// KYCService реализует проверку личности: пользователь прикладывает фотографии документа
+// из приватного хранилища, модератор одобряет или отклоняет заявку с указанием причины.
+type KYCService struct {
+	repo  KYCRepository
+	media KYCMediaRepository
+	hub   WSNotifier
+}
+
+// NewKYCService создаёт сервис.
+func NewKYCService(repo KYCRepository, media KYCMediaRepository) *KYCService {
+	return &KYCService{repo: repo, media: media}
+}
+
+// SetHub устанавливает WebSocket hub для уведомления о решении по заявке.
+func (s *KYCService) SetHub(hub WSNotifier) {
+	s.hub = hub
+}
+
+// Submit создаёт заявку на проверку личности. Все фотографии должны быть приватными файлами
+// пользователя; обязательны лицевая сторона и фото с документом, для всех документов,
+// кроме паспорта, — ещё и оборот.
+func (s *KYCService) Submit(ctx context.Context, userID uuid.UUID, documentType string, documents []models.KYCDocument) (*models.KYCSubmission, error) {
+	if _, ok := models.KYCDocumentTypes[documentType]; !ok {
+		return nil, ErrKYCDocumentType
+	}
+
+	kinds := make(map[string]bool, len(documents))
+	mediaIDs := make(map[uuid.UUID]bool, len(documents))
+	for _, doc := range documents {
+		if _, ok := models.KYCDocumentKinds[doc.Kind]; !ok || kinds[doc.Kind] || mediaIDs[doc.MediaID] {
+			return nil, ErrKYCDocumentInvalid
+		}
+		kinds[doc.Kind], mediaIDs[doc.MediaID] = true, true
+
+		media, err := s.media.GetByID(ctx, doc.MediaID)
+		if errors.Is(err, repository.ErrMediaNotFound) {
+			return nil, ErrKYCDocumentInvalid
+		}
+		if err != nil {
+			return nil, err
+		}
+		if media.IsPublic || media.UserID == nil || *media.UserID != userID {
+			return nil, ErrKYCDocumentInvalid
+		}
+	}
+	if !kinds[models.KYCDocumentFront] || !kinds[models.KYCDocumentSelfie] ||
+		(documentType != models.KYCDocumentPassport && !kinds[models.KYCDocumentBack]) {
+		return nil, ErrKYCDocumentsIncomplete
+	}
+
+	verified, err := s.repo.IsIdentityVerified(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if verified {
+		return nil, ErrKYCAlreadyVerified
+	}
+
+	submission := &models.KYCSubmission{
+		UserID:       userID,
+		DocumentType: documentType,
+		Documents:    documents,
+	}
+	if err := s.repo.CreateSubmission(ctx, submission); err != nil {
+		return nil, err
+	}
+	return submission, nil
+}
+
+// GetStatus возвращает, подтверждена ли личность пользователя, и его последнюю заявку.
+func (s *KYCService) GetStatus(ctx context.Context, userID uuid.UUID) (*KYCStatus, error) {
+	verified, err := s.repo.IsIdentityVerified(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &KYCStatus{IdentityVerified: verified}
+	submission, err := s.repo.GetLatestSubmission(ctx, userID)
+	switch {
+	case err == nil:
+		status.Submission = submission
+	case !errors.Is(err, repository.ErrKYCSubmissionNotFound):
+		return nil, err
+	}
+	return status, nil
+}
+
+// ListSubmissions возвращает очередь заявок для модераторов, старые первыми.
+func (s *KYCService) ListSubmissions(ctx context.Context, status string, limit, offset int) ([]models.KYCSubmission, error) {
+	switch status {
+	case "", models.KYCStatusPending, models.KYCStatusApproved, models.KYCStatusRejected:
+	default:
+		return nil, ErrKYCStatusInvalid
+	}
+	return s.repo.ListSubmissions(ctx, status, limit, offset)
+}
+
+// GetSubmission возвращает заявку с фотографиями для модератора.
+func (s *KYCService) GetSubmission(ctx context.Context, id uuid.UUID) (*models.KYCSubmission, error) {
+	return s.repo.GetSubmission(ctx, id)
+}
+
+// Approve одобряет заявку; пользователь отмечается как прошедший проверку личности.
+func (s *KYCService) Approve(ctx context.Context, id, reviewerID uuid.UUID) (*models.KYCSubmission, error) {
+	return s.review(ctx, id, reviewerID, models.KYCStatusApproved, nil)
+}
+
+// Reject отклоняет заявку; причину увидит пользователь и сможет подать новую заявку.
+func (s *KYCService) Reject(ctx context.Context, id, reviewerID uuid.UUID, reason string) (*models.KYCSubmission, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrKYCRejectReason
+	}
+	return s.review(ctx, id, reviewerID, models.KYCStatusRejected, &reason)
+}
+
+func (s *KYCService) review(ctx context.Context, id, reviewerID uuid.UUID, status string, reason *string) (*models.KYCSubmission, error) {
+	current, err := s.repo.GetSubmission(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.UserID == reviewerID {
+		return nil, ErrKYCSelfReview
+	}
+
+	submission, err := s.repo.ReviewSubmission(ctx, id, reviewerID, status, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.hub != nil {
+		payload := map[string]interface{}{
+			"submission_id":    submission.ID,
+			"status":           submission.Status,
+			"rejection_reason": submission.RejectionReason,
+		}
+		if err := s.hub.BroadcastToUser(submission.UserID, "identity_verification_reviewed", payload); err != nil && logger.Log != nil {
+			logger.Log.WithFields(map[string]interface{}{
+				"submission_id": submission.ID,
+				"error":         err.Error(),
+			}).Warn("verification: не удалось отправить уведомление о решении по заявке")
+		}
+	}
+	return submission, nil
+}
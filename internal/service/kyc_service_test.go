@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+// mockKYCRepository хранит заявки и отметки о проверке личности в памяти.
+type mockKYCRepository struct {
+	submissions []*models.KYCSubmission
+	verified    map[uuid.UUID]bool
+}
+
+func newMockKYCRepository() *mockKYCRepository {
+	return &mockKYCRepository{verified: make(map[uuid.UUID]bool)}
+}
+
+func (m *mockKYCRepository) CreateSubmission(ctx context.Context, submission *models.KYCSubmission) error {
+	for _, existing := range m.submissions {
+		if existing.UserID == submission.UserID && existing.Status == models.KYCStatusPending {
+			return repository.ErrKYCSubmissionPending
+		}
+	}
+	submission.ID = uuid.New()
+	submission.Status = models.KYCStatusPending
+	submission.CreatedAt = time.Now()
+	m.submissions = append(m.submissions, submission)
+	return nil
+}
+
+func (m *mockKYCRepository) GetSubmission(ctx context.Context, id uuid.UUID) (*models.KYCSubmission, error) {
+	for _, submission := range m.submissions {
+		if submission.ID == id {
+			copied := *submission
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrKYCSubmissionNotFound
+}
+
+func (m *mockKYCRepository) GetLatestSubmission(ctx context.Context, userID uuid.UUID) (*models.KYCSubmission, error) {
+	for i := len(m.submissions) - 1; i >= 0; i-- {
+		if m.submissions[i].UserID == userID {
+			copied := *m.submissions[i]
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrKYCSubmissionNotFound
+}
+
+func (m *mockKYCRepository) ListSubmissions(ctx context.Context, status string, limit, offset int) ([]models.KYCSubmission, error) {
+	var result []models.KYCSubmission
+	for _, submission := range m.submissions {
+		if status == "" || submission.Status == status {
+			result = append(result, *submission)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockKYCRepository) ReviewSubmission(ctx context.Context, id, reviewerID uuid.UUID, status string, reason *string) (*models.KYCSubmission, error) {
+	for _, submission := range m.submissions {
+		if submission.ID != id {
+			continue
+		}
+		if submission.Status != models.KYCStatusPending {
+			return nil, repository.ErrKYCSubmissionReviewed
+		}
+		now := time.Now()
+		submission.Status, submission.RejectionReason = status, reason
+		submission.ReviewedBy, submission.ReviewedAt = &reviewerID, &now
+		if status == models.KYCStatusApproved {
+			m.verified[submission.UserID] = true
+		}
+		copied := *submission
+		return &copied, nil
+	}
+	return nil, repository.ErrKYCSubmissionNotFound
+}
+
+func (m *mockKYCRepository) IsIdentityVerified(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return m.verified[userID], nil
+}
+
+type mockKYCMedia map[uuid.UUID]*models.MediaFile
+
+func (m mockKYCMedia) GetByID(ctx context.Context, id uuid.UUID) (*models.MediaFile, error) {
+	if media, ok := m[id]; ok {
+		return media, nil
+	}
+	return nil, repository.ErrMediaNotFound
+}
+
+func (m mockKYCMedia) add(userID uuid.UUID, public bool) uuid.UUID {
+	id := uuid.New()
+	m[id] = &models.MediaFile{ID: id, UserID: &userID, IsPublic: public}
+	return id
+}
+
+type kycFixture struct {
+	svc   *KYCService
+	repo  *mockKYCRepository
+	media mockKYCMedia
+	hub   *mockWSNotifier
+}
+
+func setupKYC() *kycFixture {
+	repo := newMockKYCRepository()
+	media := mockKYCMedia{}
+	hub := &mockWSNotifier{}
+	svc := NewKYCService(repo, media)
+	svc.SetHub(hub)
+	return &kycFixture{svc: svc, repo: repo, media: media, hub: hub}
+}
+
+// passport возвращает полный комплект фотографий паспорта, загруженных пользователем в приватное хранилище.
+func (f *kycFixture) passport(userID uuid.UUID) []models.KYCDocument {
+	return []models.KYCDocument{
+		{Kind: models.KYCDocumentFront, MediaID: f.media.add(userID, false)},
+		{Kind: models.KYCDocumentSelfie, MediaID: f.media.add(userID, false)},
+	}
+}
+
+func TestKYCService_SubmitValidatesDocuments(t *testing.T) {
+	f := setupKYC()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	_, err := f.svc.Submit(ctx, userID, "diploma", f.passport(userID))
+	assert.ErrorIs(t, err, ErrKYCDocumentType)
+
+	// Для ID-карты нужен и оборот
+	_, err = f.svc.Submit(ctx, userID, models.KYCDocumentIDCard, f.passport(userID))
+	assert.ErrorIs(t, err, ErrKYCDocumentsIncomplete)
+
+	// Публичный файл, раздаваемый по /media, в заявку не принимается
+	docs := f.passport(userID)
+	docs[0].MediaID = f.media.add(userID, true)
+	_, err = f.svc.Submit(ctx, userID, models.KYCDocumentPassport, docs)
+	assert.ErrorIs(t, err, ErrKYCDocumentInvalid)
+
+	// Как и чужой приватный файл
+	docs = f.passport(userID)
+	docs[1].MediaID = f.media.add(uuid.New(), false)
+	_, err = f.svc.Submit(ctx, userID, models.KYCDocumentPassport, docs)
+	assert.ErrorIs(t, err, ErrKYCDocumentInvalid)
+
+	// Один файл под двумя видами фотографий
+	docs = f.passport(userID)
+	docs[1].MediaID = docs[0].MediaID
+	_, err = f.svc.Submit(ctx, userID, models.KYCDocumentPassport, docs)
+	assert.ErrorIs(t, err, ErrKYCDocumentInvalid)
+
+	submission, err := f.svc.Submit(ctx, userID, models.KYCDocumentPassport, f.passport(userID))
+	assert.NoError(t, err)
+	assert.Equal(t, models.KYCStatusPending, submission.Status)
+
+	_, err = f.svc.Submit(ctx, userID, models.KYCDocumentPassport, f.passport(userID))
+	assert.ErrorIs(t, err, repository.ErrKYCSubmissionPending)
+}
+
+func TestKYCService_ApproveVerifiesIdentity(t *testing.T) {
+	f := setupKYC()
+	ctx := context.Background()
+	userID, moderatorID := uuid.New(), uuid.New()
+
+	submission, err := f.svc.Submit(ctx, userID, models.KYCDocumentPassport, f.passport(userID))
+	assert.NoError(t, err)
+
+	queue, err := f.svc.ListSubmissions(ctx, models.KYCStatusPending, 20, 0)
+	assert.NoError(t, err)
+	assert.Len(t, queue, 1)
+
+	// Модератор не рассматривает собственную заявку
+	_, err = f.svc.Approve(ctx, submission.ID, userID)
+	assert.ErrorIs(t, err, ErrKYCSelfReview)
+
+	approved, err := f.svc.Approve(ctx, submission.ID, moderatorID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.KYCStatusApproved, approved.Status)
+
+	status, err := f.svc.GetStatus(ctx, userID)
+	assert.NoError(t, err)
+	assert.True(t, status.IdentityVerified)
+	assert.Equal(t, submission.ID, status.Submission.ID)
+
+	assert.Equal(t, "identity_verification_reviewed", f.hub.events[len(f.hub.events)-1].event)
+	assert.Equal(t, userID, f.hub.events[len(f.hub.events)-1].userID)
+
+	_, err = f.svc.Reject(ctx, submission.ID, moderatorID, "поздно")
+	assert.ErrorIs(t, err, repository.ErrKYCSubmissionReviewed)
+
+	_, err = f.svc.Submit(ctx, userID, models.KYCDocumentPassport, f.passport(userID))
+	assert.ErrorIs(t, err, ErrKYCAlreadyVerified)
+}
+
+func TestKYCService_RejectAllowsResubmission(t *testing.T) {
+	f := setupKYC()
+	ctx := context.Background()
+	userID, moderatorID := uuid.New(), uuid.New()
+
+	submission, err := f.svc.Submit(ctx, userID, models.KYCDocumentPassport, f.passport(userID))
+	assert.NoError(t, err)
+
+	_, err = f.svc.Reject(ctx, submission.ID, moderatorID, "  ")
+	assert.ErrorIs(t, err, ErrKYCRejectReason)
+
+	rejected, err := f.svc.Reject(ctx, submission.ID, moderatorID, "фото размыто")
+	assert.NoError(t, err)
+	assert.Equal(t, models.KYCStatusRejected, rejected.Status)
+	assert.Equal(t, "фото размыто", *rejected.RejectionReason)
+
+	status, err := f.svc.GetStatus(ctx, userID)
+	assert.NoError(t, err)
+	assert.False(t, status.IdentityVerified)
+	assert.Equal(t, models.KYCStatusRejected, status.Submission.Status)
+
+	_, err = f.svc.Submit(ctx, userID, models.KYCDocumentPassport, f.passport(userID))
+	assert.NoError(t, err)
+}
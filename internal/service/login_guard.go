@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/logger"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+// Ограничения на подбор пароля. Неудачные попытки считаются за loginFailureWindow отдельно
+// для email и для IP: после порога вход задерживается, и задержка удваивается с каждой новой
+// ошибкой; после порога блокировки вход закрыт на loginFailureWindow с последней ошибки.
+// Для IP пороги выше, потому что за одним адресом бывает целая сеть; порог блокировки
+// достижим с учётом задержек в пределах окна.
+const (
+	loginFailureWindow      = 15 * time.Minute
+	accountFailureDelayFrom = 3
+	accountFailureLockAt    = 10
+	ipFailureDelayFrom      = 20
+	ipFailureLockAt         = 40
+	maxLoginDelay           = 30 * time.Second
+)
+
+// LoginThrottledError — вход для email или IP временно приостановлен после неудачных попыток.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	// Locked — достигнут порог блокировки, а не очередная задержка
+	Locked bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "auth service: слишком много неудачных попыток входа, вход временно заблокирован"
+	}
+	return "auth service: слишком много неудачных попыток входа, повторите попытку позже"
+}
+
+// LoginAttemptRepository описывает зависимости LoginGuard от слоя хранилища.
+type LoginAttemptRepository interface {
+	RecordLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error
+	CountAccountFailures(ctx context.Context, email string, since time.Time) (int, *time.Time, error)
+	CountIPFailures(ctx context.Context, ip string, since time.Time) (int, *time.Time, error)
+	HasSessionFrom(ctx context.Context, userID uuid.UUID, userAgent, ip string) (bool, error)
+}
+
+// LoginGuard защищает вход по паролю от подбора и предупреждает владельца аккаунта
+// о подозрительных попытках и о входе с нового устройства.
+type LoginGuard struct {
+	repo        LoginAttemptRepository
+	mailer      Mailer
+	hub         WSNotifier
+	frontendURL string
+}
+
+// NewLoginGuard создаёт защиту входа; frontendURL — адрес фронтенда для ссылок в письмах.
+func NewLoginGuard(repo LoginAttemptRepository, mailer Mailer, frontendURL string) *LoginGuard {
+	return &LoginGuard{
+		repo:        repo,
+		mailer:      mailer,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// SetHub устанавливает WebSocket hub для уведомлений владельцу аккаунта.
+func (g *LoginGuard) SetHub(hub WSNotifier) {
+	g.hub = hub
+}
+
+// Check возвращает *LoginThrottledError, если вход в email или с IP сейчас приостановлен.
+// Вызывается до проверки пароля: во время блокировки не принимается и верный пароль.
+func (g *LoginGuard) Check(ctx context.Context, email, ip string) error {
+	since := time.Now().Add(-loginFailureWindow)
+
+	count, last, err := g.repo.CountAccountFailures(ctx, strings.ToLower(email), since)
+	if err != nil {
+		return err
+	}
+	if err := throttleFor(count, last, accountFailureDelayFrom, accountFailureLockAt); err != nil {
+		return err
+	}
+
+	if ip == "" {
+		return nil
+	}
+	count, last, err = g.repo.CountIPFailures(ctx, ip, since)
+	if err != nil {
+		return err
+	}
+	return throttleFor(count, last, ipFailureDelayFrom, ipFailureLockAt)
+}
+
+// RecordFailure запоминает неудачную попытку; user пуст для незарегистрированного email.
+// Когда попытки в аккаунт достигают порога блокировки, владелец получает предупреждение.
+func (g *LoginGuard) RecordFailure(ctx context.Context, email string, user *models.User, meta map[string]string) {
+	attempt := newLoginAttempt(email, user, meta, false)
+	if err := g.repo.RecordLoginAttempt(ctx, attempt); err != nil {
+		g.logError(err, "auth service: не удалось сохранить попытку входа")
+		return
+	}
+	if user == nil {
+		return
+	}
+
+	count, _, err := g.repo.CountAccountFailures(ctx, attempt.Email, time.Now().Add(-loginFailureWindow))
+	if err != nil {
+		g.logError(err, "auth service: не удалось посчитать неудачные попытки входа")
+		return
+	}
+	if count != accountFailureLockAt {
+		return
+	}
+
+	g.alert(ctx, user, "suspicious_login", meta, Email{
+		To:      user.Email,
+		Subject: "Подозрительные попытки входа",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЗа последние %s в ваш аккаунт %d раз пытались войти с неверным паролем, "+
+			"последний раз — с IP %s (%s). Вход временно заблокирован.\n\n"+
+			"Если это были не вы, смените пароль: %s",
+			user.Username, formatTTL(loginFailureWindow), count, metaOrUnknown(meta, "ip"), metaOrUnknown(meta, "user_agent"),
+			g.frontendURL+"/forgot-password"),
+	})
+}
+
+// RecordSuccess запоминает верный пароль; счётчик неудачных попыток аккаунта обнуляется.
+func (g *LoginGuard) RecordSuccess(ctx context.Context, user *models.User, meta map[string]string) {
+	if err := g.repo.RecordLoginAttempt(ctx, newLoginAttempt(user.Email, user, meta, true)); err != nil {
+		g.logError(err, "auth service: не удалось сохранить попытку входа")
+	}
+}
+
+// NoticeLogin предупреждает владельца о входе с устройства и IP, с которых у него нет сессий.
+// Вызывается до создания новой сессии.
+func (g *LoginGuard) NoticeLogin(ctx context.Context, user *models.User, meta map[string]string) {
+	userAgent, ip := meta["user_agent"], meta["ip"]
+	if userAgent == "" || ip == "" {
+		return
+	}
+
+	known, err := g.repo.HasSessionFrom(ctx, user.ID, userAgent, ip)
+	if err != nil {
+		g.logError(err, "auth service: не удалось проверить устройство входа")
+		return
+	}
+	if known {
+		return
+	}
+
+	g.alert(ctx, user, "new_device_login", meta, Email{
+		To:      user.Email,
+		Subject: "Вход с нового устройства",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nВ ваш аккаунт вошли с нового устройства или адреса:\n"+
+			"IP: %s\nУстройство: %s\nВремя: %s\n\n"+
+			"Если это были не вы, завершите сессию в настройках безопасности и смените пароль: %s",
+			user.Username, ip, userAgent, time.Now().UTC().Format("02.01.2006 15:04 UTC"),
+			g.frontendURL+"/forgot-password"),
+	})
+}
+
+// alert отправляет владельцу письмо и событие в WebSocket; ошибки доставки не мешают входу.
+func (g *LoginGuard) alert(ctx context.Context, user *models.User, event string, meta map[string]string, msg Email) {
+	if err := g.mailer.Send(ctx, msg); err != nil {
+		g.logError(err, "auth service: не удалось отправить предупреждение о входе")
+	}
+
+	if g.hub == nil {
+		return
+	}
+	payload := map[string]interface{}{
+		"ip_address": meta["ip"],
+		"user_agent": meta["user_agent"],
+	}
+	if err := g.hub.BroadcastToUser(user.ID, event, payload); err != nil {
+		g.logError(err, "auth service: не удалось отправить уведомление о входе")
+	}
+}
+
+func (g *LoginGuard) logError(err error, message string) {
+	if logger.Log != nil {
+		logger.Log.WithFields(map[string]interface{}{
+			"error": err.Error(),
+		}).Warn(message)
+	}
+}
+
+// throttleFor переводит число неудачных попыток и время последней в задержку или блокировку.
+func throttleFor(count int, last *time.Time, delayFrom, lockAt int) error {
+	if last == nil || count < delayFrom {
+		return nil
+	}
+
+	locked := count >= lockAt
+	wait := loginFailureWindow
+	if !locked {
+		wait = maxLoginDelay
+		if shift := count - delayFrom; shift < 5 {
+			wait = time.Second << shift
+		}
+	}
+
+	retryAfter := time.Until(last.Add(wait))
+	if retryAfter <= 0 {
+		return nil
+	}
+	return &LoginThrottledError{RetryAfter: retryAfter, Locked: locked}
+}
+
+func newLoginAttempt(email string, user *models.User, meta map[string]string, success bool) *models.LoginAttempt {
+	attempt := &models.LoginAttempt{
+		Email:   strings.ToLower(email),
+		Success: success,
+	}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+	if ip := meta["ip"]; ip != "" {
+		attempt.IPAddress = &ip
+	}
+	if userAgent := meta["user_agent"]; userAgent != "" {
+		attempt.UserAgent = &userAgent
+	}
+	return attempt
+}
+
+func metaOrUnknown(meta map[string]string, key string) string {
+	if value := meta[key]; value != "" {
+		return value
+	}
+	return "неизвестно"
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+// mockLoginAttemptRepository хранит попытки в памяти, а сессии берёт из mockAuthRepository.
+type mockLoginAttemptRepository struct {
+	attempts []models.LoginAttempt
+	users    *mockAuthRepository
+}
+
+func (m *mockLoginAttemptRepository) RecordLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	attempt.ID = uuid.New()
+	attempt.CreatedAt = time.Now()
+	m.attempts = append(m.attempts, *attempt)
+	return nil
+}
+
+func (m *mockLoginAttemptRepository) CountAccountFailures(ctx context.Context, email string, since time.Time) (int, *time.Time, error) {
+	return m.count(since, func(a models.LoginAttempt) bool { return a.Email == email }, true)
+}
+
+func (m *mockLoginAttemptRepository) CountIPFailures(ctx context.Context, ip string, since time.Time) (int, *time.Time, error) {
+	return m.count(since, func(a models.LoginAttempt) bool { return a.IPAddress != nil && *a.IPAddress == ip }, false)
+}
+
+func (m *mockLoginAttemptRepository) count(since time.Time, match func(models.LoginAttempt) bool, resetOnSuccess bool) (int, *time.Time, error) {
+	var (
+		count int
+		last  *time.Time
+	)
+	for _, a := range m.attempts {
+		if !match(a) || !a.CreatedAt.After(since) {
+			continue
+		}
+		if a.Success {
+			if resetOnSuccess {
+				count, last = 0, nil
+			}
+			continue
+		}
+		createdAt := a.CreatedAt
+		count, last = count+1, &createdAt
+	}
+	return count, last, nil
+}
+
+// age сдвигает все попытки в прошлое на d.
+func (m *mockLoginAttemptRepository) age(d time.Duration) {
+	for i := range m.attempts {
+		m.attempts[i].CreatedAt = m.attempts[i].CreatedAt.Add(-d)
+	}
+}
+
+func (m *mockLoginAttemptRepository) HasSessionFrom(ctx context.Context, userID uuid.UUID, userAgent, ip string) (bool, error) {
+	for _, session := range m.users.sessions {
+		if session.UserID == userID && session.UserAgent != nil && *session.UserAgent == userAgent &&
+			session.IPAddress != nil && *session.IPAddress == ip {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type loginGuardFixture struct {
+	auth   *AuthService
+	repo   *mockLoginAttemptRepository
+	mailer *recordingMailer
+	hub    *mockWSNotifier
+	userID uuid.UUID
+}
+
+func setupLoginGuard(t *testing.T) *loginGuardFixture {
+	t.Helper()
+	users := newMockAuthRepository()
+	auth := NewAuthService(users, NewTokenManager("access-secret", "refresh-secret", time.Minute, time.Hour))
+	res, err := auth.Register(context.Background(), RegisterInput{Email: "owner@example.com", Password: "password123"},
+		map[string]string{"user_agent": "Firefox", "ip": "10.0.0.1"})
+	assert.NoError(t, err)
+
+	repo := &mockLoginAttemptRepository{users: users}
+	mailer := &recordingMailer{}
+	hub := &mockWSNotifier{}
+	guard := NewLoginGuard(repo, mailer, "https://app.example.com")
+	guard.SetHub(hub)
+	auth.SetLoginGuard(guard)
+
+	return &loginGuardFixture{auth: auth, repo: repo, mailer: mailer, hub: hub, userID: res.User.ID}
+}
+
+func (f *loginGuardFixture) login(password, ip string) error {
+	_, err := f.auth.Login(context.Background(), LoginInput{Email: "owner@example.com", Password: password},
+		map[string]string{"user_agent": "Firefox", "ip": ip})
+	return err
+}
+
+func TestLoginGuard_ProgressiveDelay(t *testing.T) {
+	f := setupLoginGuard(t)
+
+	for i := 0; i < accountFailureDelayFrom; i++ {
+		assert.NoError(t, ignoreWrongPassword(f.login("wrong-password", "10.0.0.2")))
+	}
+
+	var throttled *LoginThrottledError
+	err := f.login("password123", "10.0.0.2")
+	assert.True(t, errors.As(err, &throttled), "после порога даже верный пароль ждёт задержку")
+	assert.False(t, throttled.Locked)
+	assert.LessOrEqual(t, throttled.RetryAfter, time.Second)
+
+	// Задержка удваивается с каждой следующей ошибкой
+	f.repo.age(time.Second)
+	assert.NoError(t, ignoreWrongPassword(f.login("wrong-password", "10.0.0.2")))
+	err = f.login("password123", "10.0.0.2")
+	assert.True(t, errors.As(err, &throttled))
+	assert.Greater(t, throttled.RetryAfter, time.Second)
+
+	// После задержки верный пароль принимается и обнуляет счётчик
+	f.repo.age(2 * time.Second)
+	assert.NoError(t, f.login("password123", "10.0.0.2"))
+	assert.NoError(t, ignoreWrongPassword(f.login("wrong-password", "10.0.0.2")))
+	assert.NoError(t, ignoreWrongPassword(f.login("wrong-password", "10.0.0.2")))
+}
+
+func TestLoginGuard_LockoutAlertsOwnerOnce(t *testing.T) {
+	f := setupLoginGuard(t)
+
+	f.failTimes(t, accountFailureLockAt, func() error { return f.login("wrong-password", "203.0.113.7") })
+
+	var throttled *LoginThrottledError
+	assert.True(t, errors.As(f.login("password123", "10.0.0.1"), &throttled), "блокировка действует для любого IP")
+	assert.True(t, throttled.Locked)
+	assert.True(t, errors.As(f.login("wrong-password", "203.0.113.7"), &throttled))
+
+	var alerts int
+	for _, msg := range f.mailer.sent {
+		if msg.Subject == "Подозрительные попытки входа" {
+			alerts++
+			assert.Equal(t, "owner@example.com", msg.To)
+			assert.Contains(t, msg.Body, "203.0.113.7")
+		}
+	}
+	assert.Equal(t, 1, alerts)
+	assert.Equal(t, "suspicious_login", f.hub.events[len(f.hub.events)-1].event)
+
+	// Блокировка снимается через loginFailureWindow после последней ошибки
+	f.repo.age(loginFailureWindow)
+	assert.NoError(t, f.login("password123", "10.0.0.1"))
+}
+
+func TestLoginGuard_IPLimitSpansAccounts(t *testing.T) {
+	f := setupLoginGuard(t)
+	ctx := context.Background()
+
+	f.failTimes(t, ipFailureLockAt, func() error {
+		_, err := f.auth.Login(ctx, LoginInput{Email: uuid.NewString()[:8] + "@example.com", Password: "password123"},
+			map[string]string{"ip": "198.51.100.1"})
+		return err
+	})
+
+	var throttled *LoginThrottledError
+	assert.True(t, errors.As(f.login("password123", "198.51.100.1"), &throttled))
+	assert.True(t, throttled.Locked)
+	assert.NoError(t, f.login("password123", "10.0.0.1"), "с другого IP вход открыт")
+}
+
+// failTimes делает n неудачных попыток, выжидая каждую задержку; все они должны уложиться в окно подсчёта.
+func (f *loginGuardFixture) failTimes(t *testing.T, n int, attempt func() error) {
+	t.Helper()
+	var waited time.Duration
+	for failed := 0; failed < n; {
+		var throttled *LoginThrottledError
+		err := attempt()
+		if errors.As(err, &throttled) {
+			assert.False(t, throttled.Locked, "блокировка раньше порога")
+			f.repo.age(throttled.RetryAfter)
+			waited += throttled.RetryAfter
+			continue
+		}
+		assert.Error(t, err)
+		failed++
+	}
+	assert.Less(t, waited, loginFailureWindow)
+}
+
+func TestLoginGuard_NewDeviceAlert(t *testing.T) {
+	f := setupLoginGuard(t)
+
+	// Устройство и IP из регистрации уже известны
+	assert.NoError(t, f.login("password123", "10.0.0.1"))
+	assert.Empty(t, f.mailer.sent)
+
+	assert.NoError(t, f.login("password123", "192.0.2.50"))
+	assert.Len(t, f.mailer.sent, 1)
+	assert.Equal(t, "Вход с нового устройства", f.mailer.sent[0].Subject)
+	assert.Contains(t, f.mailer.sent[0].Body, "192.0.2.50")
+	assert.Equal(t, "new_device_login", f.hub.events[len(f.hub.events)-1].event)
+
+	// Следующий вход с того же устройства уже не новый
+	assert.NoError(t, f.login("password123", "192.0.2.50"))
+	assert.Len(t, f.mailer.sent, 1)
+}
+
+// ignoreWrongPassword пропускает ожидаемую ошибку неверного пароля, но не задержку.
+func ignoreWrongPassword(err error) error {
+	var throttled *LoginThrottledError
+	if errors.As(err, &throttled) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/logger"
+)
+
+// Email — текстовое письмо одному получателю.
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer отправляет письма пользователям.
+type Mailer interface {
+	Send(ctx context.Context, msg Email) error
+}
+
+// Драйверы Mailer, выбираемые через MAIL_DRIVER.
+const (
+	MailDriverSMTP = "smtp"
+	MailDriverFile = "file"
+	MailDriverLog  = "log"
+)
+
+// SMTPMailer отправляет письма через SMTP сервер с STARTTLS и PLAIN аутентификацией.
+type SMTPMailer struct {
+	addr     string
+	host     string
+	from     string
+	username string
+	password string
+}
+
+// NewSMTPMailer создаёт SMTP отправителя; username пустой, если сервер не требует аутентификации.
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(host, fmt.Sprint(port)),
+		host:     host,
+		from:     from,
+		username: username,
+		password: password,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Email) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+	if err := smtp.SendMail(m.addr, auth, m.from, []string{msg.To}, buildMessage(m.from, msg)); err != nil {
+		return fmt.Errorf("smtp mailer: %w", err)
+	}
+	return nil
+}
+
+// FileMailer сохраняет письма в каталог в формате .eml; для локальной разработки и тестов.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer создаёт отправителя, складывающего письма в dir.
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("file mailer: %w", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Email) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString()[:8])
+	if err := os.WriteFile(filepath.Join(m.dir, name), buildMessage(m.from, msg), 0o640); err != nil {
+		return fmt.Errorf("file mailer: %w", err)
+	}
+	return nil
+}
+
+// LogMailer пишет письма в лог приложения вместо отправки.
+// Письма содержат одноразовые ссылки и коды, поэтому только для разработки.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Email) error {
+	if logger.Log != nil {
+		logger.Log.WithFields(map[string]interface{}{
+			"to":      msg.To,
+			"subject": msg.Subject,
+			"body":    msg.Body,
+		}).Info("mailer: письмо не отправлено, MAIL_DRIVER=log")
+	}
+	return nil
+}
+
+// buildMessage формирует письмо в формате RFC 5322 с телом в UTF-8.
+func buildMessage(from string, msg Email) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", sanitizeHeader(from))
+	fmt.Fprintf(&b, "To: %s\r\n", sanitizeHeader(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.Bytes()
+}
+
+// sanitizeHeader убирает переводы строк, чтобы адрес не мог добавить свои заголовки.
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+	"github.com/ignatzorin/freelance-backend/internal/logger"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+var (
+	ErrMilestoneForbidden    = errors.New("milestone: action is not allowed for this user")
+	ErrMilestoneOrderClosed  = errors.New("milestone: order is completed or cancelled")
+	ErrMilestoneNoFreelancer = errors.New("milestone: freelancer is not assigned to the order")
+	ErrMilestoneInvalidInput = errors.New("milestone: title is required and amount must be positive")
+	ErrMilestoneDueInPast    = errors.New("milestone: due date cannot be in the past")
+	ErrMilestoneWrongOrder   = errors.New("milestone: milestone does not belong to this order")
+	ErrMilestoneRefundDenied = errors.New("milestone: client can refund only overdue unsubmitted milestones or milestones of a cancelled order")
+)
+
+// MilestoneRepository описывает хранилище этапов заказа.
+type MilestoneRepository interface {
+	Create(ctx context.Context, m *models.Milestone) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Milestone, error)
+	ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.Milestone, error)
+	Update(ctx context.Context, m *models.Milestone) error
+	MarkSubmitted(ctx context.Context, id uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// MilestonePaymentRepository описывает движение средств по этапам.
+type MilestonePaymentRepository interface {
+	FundMilestone(ctx context.Context, milestoneID, clientID, freelancerID uuid.UUID) (*models.Escrow, error)
+	ReleaseMilestone(ctx context.Context, milestoneID uuid.UUID) (*models.Escrow, error)
+	RefundMilestone(ctx context.Context, milestoneID uuid.UUID) (*models.Escrow, error)
+	GetEscrowByOrderID(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error)
+}
+
+// MilestoneInput содержит данные этапа при создании и изменении.
+type MilestoneInput struct {
+	Title       string
+	Description *string
+	Amount      valueobject.Amount
+	DueAt       *time.Time
+}
+
+// MilestoneService управляет этапами заказа и их поэтапной оплатой.
+type MilestoneService struct {
+	repo     MilestoneRepository
+	payments MilestonePaymentRepository
+	orders   OrderRepoForReview
+	hub      WSNotifier
+}
+
+// NewMilestoneService создаёт сервис этапов.
+func NewMilestoneService(repo MilestoneRepository, payments MilestonePaymentRepository, orders OrderRepoForReview) *MilestoneService {
+	return &MilestoneService{repo: repo, payments: payments, orders: orders}
+}
+
+// SetHub устанавливает WebSocket hub для уведомлений участников.
+func (s *MilestoneService) SetHub(hub WSNotifier) {
+	s.hub = hub
+}
+
+// ListMilestones возвращает этапы заказа. Опубликованный заказ виден всем,
+// остальные — только его участникам.
+func (s *MilestoneService) ListMilestones(ctx context.Context, orderID, userID uuid.UUID) ([]models.Milestone, error) {
+	order, err := s.orders.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderStatusPublished && !isOrderParticipant(order, userID) {
+		return nil, ErrMilestoneForbidden
+	}
+	return s.repo.ListByOrder(ctx, orderID)
+}
+
+// CreateMilestone добавляет этап в заказ. Доступно только заказчику и только пока заказ
+// не оплачен целиком при принятии отклика.
+func (s *MilestoneService) CreateMilestone(ctx context.Context, orderID, clientID uuid.UUID, in MilestoneInput) (*models.Milestone, error) {
+	order, err := s.clientOrder(ctx, orderID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateMilestoneInput(in); err != nil {
+		return nil, err
+	}
+	if _, err := s.payments.GetEscrowByOrderID(ctx, order.ID); err == nil {
+		return nil, repository.ErrOrderEscrowExists
+	} else if !errors.Is(err, repository.ErrEscrowNotFound) {
+		return nil, err
+	}
+
+	m := &models.Milestone{
+		OrderID:     order.ID,
+		Title:       strings.TrimSpace(in.Title),
+		Description: in.Description,
+		Amount:      in.Amount,
+		DueAt:       in.DueAt,
+	}
+	if err := s.repo.Create(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// UpdateMilestone изменяет неоплаченный этап.
+func (s *MilestoneService) UpdateMilestone(ctx context.Context, orderID, milestoneID, clientID uuid.UUID, in MilestoneInput) (*models.Milestone, error) {
+	if _, err := s.clientOrder(ctx, orderID, clientID); err != nil {
+		return nil, err
+	}
+	if err := validateMilestoneInput(in); err != nil {
+		return nil, err
+	}
+
+	m, err := s.orderMilestone(ctx, orderID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status != models.MilestoneStatusPending {
+		return nil, repository.ErrMilestoneInvalidState
+	}
+
+	m.Title = strings.TrimSpace(in.Title)
+	m.Description = in.Description
+	m.Amount = in.Amount
+	m.DueAt = in.DueAt
+	if err := s.repo.Update(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DeleteMilestone удаляет неоплаченный этап.
+func (s *MilestoneService) DeleteMilestone(ctx context.Context, orderID, milestoneID, clientID uuid.UUID) error {
+	if _, err := s.clientOrder(ctx, orderID, clientID); err != nil {
+		return err
+	}
+	if _, err := s.orderMilestone(ctx, orderID, milestoneID); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, milestoneID)
+}
+
+// FundMilestone замораживает средства заказчика под этап.
+func (s *MilestoneService) FundMilestone(ctx context.Context, orderID, milestoneID, clientID uuid.UUID) (*models.Milestone, error) {
+	order, err := s.clientOrder(ctx, orderID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if order.FreelancerID == nil {
+		return nil, ErrMilestoneNoFreelancer
+	}
+	if _, err := s.orderMilestone(ctx, orderID, milestoneID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.payments.FundMilestone(ctx, milestoneID, order.ClientID, *order.FreelancerID); err != nil {
+		return nil, err
+	}
+	return s.afterTransition(ctx, milestoneID, *order.FreelancerID, "milestone_funded")
+}
+
+// SubmitMilestone отмечает этап как сданный фрилансером на приёмку.
+func (s *MilestoneService) SubmitMilestone(ctx context.Context, orderID, milestoneID, freelancerID uuid.UUID) (*models.Milestone, error) {
+	order, err := s.orders.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.FreelancerID == nil || *order.FreelancerID != freelancerID {
+		return nil, ErrMilestoneForbidden
+	}
+	if _, err := s.orderMilestone(ctx, orderID, milestoneID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.MarkSubmitted(ctx, milestoneID); err != nil {
+		return nil, err
+	}
+	return s.afterTransition(ctx, milestoneID, order.ClientID, "milestone_submitted")
+}
+
+// AcceptMilestone принимает работу по этапу и переводит оплату фрилансеру.
+func (s *MilestoneService) AcceptMilestone(ctx context.Context, orderID, milestoneID, clientID uuid.UUID) (*models.Milestone, error) {
+	order, err := s.orders.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.ClientID != clientID {
+		return nil, ErrMilestoneForbidden
+	}
+	if _, err := s.orderMilestone(ctx, orderID, milestoneID); err != nil {
+		return nil, err
+	}
+
+	escrow, err := s.payments.ReleaseMilestone(ctx, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	return s.afterTransition(ctx, milestoneID, escrow.FreelancerID, "milestone_released")
+}
+
+// RefundMilestone возвращает средства этапа заказчику. Фрилансер может вернуть
+// средства в любой момент, заказчик — только по отменённому заказу или если
+// срок этапа истёк, а работа так и не была сдана.
+func (s *MilestoneService) RefundMilestone(ctx context.Context, orderID, milestoneID, userID uuid.UUID) (*models.Milestone, error) {
+	order, err := s.orders.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !isOrderParticipant(order, userID) {
+		return nil, ErrMilestoneForbidden
+	}
+	m, err := s.orderMilestone(ctx, orderID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	notifyID := order.ClientID
+	if userID == order.ClientID {
+		overdue := m.Status == models.MilestoneStatusFunded && m.DueAt != nil && m.DueAt.Before(time.Now())
+		if order.Status != models.OrderStatusCancelled && !overdue {
+			return nil, ErrMilestoneRefundDenied
+		}
+		if order.FreelancerID != nil {
+			notifyID = *order.FreelancerID
+		}
+	}
+
+	if _, err := s.payments.RefundMilestone(ctx, milestoneID); err != nil {
+		return nil, err
+	}
+	return s.afterTransition(ctx, milestoneID, notifyID, "milestone_refunded")
+}
+
+// clientOrder загружает заказ и проверяет, что действие выполняет его заказчик.
+func (s *MilestoneService) clientOrder(ctx context.Context, orderID, clientID uuid.UUID) (*models.Order, error) {
+	order, err := s.orders.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.ClientID != clientID {
+		return nil, ErrMilestoneForbidden
+	}
+	if order.Status == models.OrderStatusCompleted || order.Status == models.OrderStatusCancelled {
+		return nil, ErrMilestoneOrderClosed
+	}
+	return order, nil
+}
+
+// orderMilestone загружает этап и проверяет принадлежность заказу.
+func (s *MilestoneService) orderMilestone(ctx context.Context, orderID, milestoneID uuid.UUID) (*models.Milestone, error) {
+	m, err := s.repo.GetByID(ctx, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if m.OrderID != orderID {
+		return nil, ErrMilestoneWrongOrder
+	}
+	return m, nil
+}
+
+// afterTransition перечитывает этап и уведомляет второго участника сделки.
+func (s *MilestoneService) afterTransition(ctx context.Context, milestoneID, notifyUserID uuid.UUID, event string) (*models.Milestone, error) {
+	m, err := s.repo.GetByID(ctx, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.hub != nil {
+		if err := s.hub.BroadcastToUser(notifyUserID, event, m); err != nil && logger.Log != nil {
+			logger.Log.WithFields(map[string]interface{}{
+				"milestone_id": milestoneID,
+				"error":        err.Error(),
+			}).Warn("milestone service: не удалось отправить уведомление")
+		}
+	}
+
+	return m, nil
+}
+
+func validateMilestoneInput(in MilestoneInput) error {
+	if strings.TrimSpace(in.Title) == "" || in.Amount <= 0 {
+		return ErrMilestoneInvalidInput
+	}
+	if in.DueAt != nil && in.DueAt.Before(time.Now()) {
+		return ErrMilestoneDueInPast
+	}
+	return nil
+}
+
+func isOrderParticipant(order *models.Order, userID uuid.UUID) bool {
+	return order.ClientID == userID || (order.FreelancerID != nil && *order.FreelancerID == userID)
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+type mockMilestoneRepo struct {
+	mock.Mock
+}
+
+func (m *mockMilestoneRepo) Create(ctx context.Context, milestone *models.Milestone) error {
+	args := m.Called(ctx, milestone)
+	return args.Error(0)
+}
+
+func (m *mockMilestoneRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Milestone, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Milestone), args.Error(1)
+}
+
+func (m *mockMilestoneRepo) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.Milestone, error) {
+	args := m.Called(ctx, orderID)
+	return args.Get(0).([]models.Milestone), args.Error(1)
+}
+
+func (m *mockMilestoneRepo) Update(ctx context.Context, milestone *models.Milestone) error {
+	args := m.Called(ctx, milestone)
+	return args.Error(0)
+}
+
+func (m *mockMilestoneRepo) MarkSubmitted(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockMilestoneRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+type mockMilestonePaymentRepo struct {
+	mock.Mock
+}
+
+func (m *mockMilestonePaymentRepo) FundMilestone(ctx context.Context, milestoneID, clientID, freelancerID uuid.UUID) (*models.Escrow, error) {
+	args := m.Called(ctx, milestoneID, clientID, freelancerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Escrow), args.Error(1)
+}
+
+func (m *mockMilestonePaymentRepo) ReleaseMilestone(ctx context.Context, milestoneID uuid.UUID) (*models.Escrow, error) {
+	args := m.Called(ctx, milestoneID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Escrow), args.Error(1)
+}
+
+func (m *mockMilestonePaymentRepo) RefundMilestone(ctx context.Context, milestoneID uuid.UUID) (*models.Escrow, error) {
+	args := m.Called(ctx, milestoneID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Escrow), args.Error(1)
+}
+
+func (m *mockMilestonePaymentRepo) GetEscrowByOrderID(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Escrow), args.Error(1)
+}
+
+func newMilestoneTestService() (*MilestoneService, *mockMilestoneRepo, *mockMilestonePaymentRepo, *mockOrderRepoForReview) {
+	repo := new(mockMilestoneRepo)
+	payments := new(mockMilestonePaymentRepo)
+	orders := new(mockOrderRepoForReview)
+	return NewMilestoneService(repo, payments, orders), repo, payments, orders
+}
+
+func TestMilestoneService_CreateMilestone_OnlyClient(t *testing.T) {
+	svc, _, _, orders := newMilestoneTestService()
+	ctx := context.Background()
+
+	orderID := uuid.New()
+	orders.On("GetByID", ctx, orderID).Return(&models.Order{ID: orderID, ClientID: uuid.New(), Status: models.OrderStatusPublished}, nil)
+
+	_, err := svc.CreateMilestone(ctx, orderID, uuid.New(), MilestoneInput{Title: "Дизайн", Amount: 100})
+	assert.ErrorIs(t, err, ErrMilestoneForbidden)
+}
+
+func TestMilestoneService_CreateMilestone_InvalidInput(t *testing.T) {
+	svc, _, _, orders := newMilestoneTestService()
+	ctx := context.Background()
+
+	clientID := uuid.New()
+	orderID := uuid.New()
+	orders.On("GetByID", ctx, orderID).Return(&models.Order{ID: orderID, ClientID: clientID, Status: models.OrderStatusPublished}, nil)
+
+	_, err := svc.CreateMilestone(ctx, orderID, clientID, MilestoneInput{Title: " ", Amount: 100})
+	assert.ErrorIs(t, err, ErrMilestoneInvalidInput)
+
+	past := time.Now().Add(-time.Hour)
+	_, err = svc.CreateMilestone(ctx, orderID, clientID, MilestoneInput{Title: "Дизайн", Amount: 100, DueAt: &past})
+	assert.ErrorIs(t, err, ErrMilestoneDueInPast)
+}
+
+func TestMilestoneService_FundMilestone_Success(t *testing.T) {
+	svc, repo, payments, orders := newMilestoneTestService()
+	ctx := context.Background()
+
+	clientID := uuid.New()
+	freelancerID := uuid.New()
+	orderID := uuid.New()
+	milestoneID := uuid.New()
+
+	orders.On("GetByID", ctx, orderID).Return(&models.Order{
+		ID: orderID, ClientID: clientID, FreelancerID: &freelancerID, Status: models.OrderStatusInProgress,
+	}, nil)
+	repo.On("GetByID", ctx, milestoneID).Return(&models.Milestone{
+		ID: milestoneID, OrderID: orderID, Amount: 300, Status: models.MilestoneStatusPending,
+	}, nil).Once()
+	payments.On("FundMilestone", ctx, milestoneID, clientID, freelancerID).Return(&models.Escrow{ID: uuid.New()}, nil)
+	repo.On("GetByID", ctx, milestoneID).Return(&models.Milestone{
+		ID: milestoneID, OrderID: orderID, Amount: 300, Status: models.MilestoneStatusFunded,
+	}, nil).Once()
+
+	milestone, err := svc.FundMilestone(ctx, orderID, milestoneID, clientID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.MilestoneStatusFunded, milestone.Status)
+	payments.AssertExpectations(t)
+}
+
+// Заказчик принял отклик, и заказ оплачен целиком; этапы, добавленные до и после
+// принятия, оплатить повторно нельзя.
+func TestMilestoneService_AcceptedOrderEscrowBlocksMilestones(t *testing.T) {
+	svc, repo, payments, orders := newMilestoneTestService()
+	ctx := context.Background()
+
+	clientID := uuid.New()
+	freelancerID := uuid.New()
+	orderID := uuid.New()
+	milestoneID := uuid.New()
+
+	orders.On("GetByID", ctx, orderID).Return(&models.Order{
+		ID: orderID, ClientID: clientID, FreelancerID: &freelancerID, Status: models.OrderStatusInProgress,
+	}, nil)
+	payments.On("GetEscrowByOrderID", ctx, orderID).Return(&models.Escrow{ID: uuid.New(), OrderID: orderID, Amount: 1000}, nil)
+
+	_, err := svc.CreateMilestone(ctx, orderID, clientID, MilestoneInput{Title: "Дизайн", Amount: 300})
+	assert.ErrorIs(t, err, repository.ErrOrderEscrowExists)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	repo.On("GetByID", ctx, milestoneID).Return(&models.Milestone{
+		ID: milestoneID, OrderID: orderID, Amount: 300, Status: models.MilestoneStatusPending,
+	}, nil)
+	payments.On("FundMilestone", ctx, milestoneID, clientID, freelancerID).Return(nil, repository.ErrOrderEscrowExists)
+
+	_, err = svc.FundMilestone(ctx, orderID, milestoneID, clientID)
+	assert.ErrorIs(t, err, repository.ErrOrderEscrowExists)
+}
+
+func TestMilestoneService_FundMilestone_WrongOrder(t *testing.T) {
+	svc, repo, payments, orders := newMilestoneTestService()
+	ctx := context.Background()
+
+	clientID := uuid.New()
+	freelancerID := uuid.New()
+	orderID := uuid.New()
+	milestoneID := uuid.New()
+
+	orders.On("GetByID", ctx, orderID).Return(&models.Order{
+		ID: orderID, ClientID: clientID, FreelancerID: &freelancerID, Status: models.OrderStatusInProgress,
+	}, nil)
+	repo.On("GetByID", ctx, milestoneID).Return(&models.Milestone{ID: milestoneID, OrderID: uuid.New()}, nil)
+
+	_, err := svc.FundMilestone(ctx, orderID, milestoneID, clientID)
+
+	assert.ErrorIs(t, err, ErrMilestoneWrongOrder)
+	payments.AssertNotCalled(t, "FundMilestone", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMilestoneService_AcceptMilestone_NotClient(t *testing.T) {
+	svc, _, payments, orders := newMilestoneTestService()
+	ctx := context.Background()
+
+	freelancerID := uuid.New()
+	orderID := uuid.New()
+	orders.On("GetByID", ctx, orderID).Return(&models.Order{
+		ID: orderID, ClientID: uuid.New(), FreelancerID: &freelancerID, Status: models.OrderStatusInProgress,
+	}, nil)
+
+	_, err := svc.AcceptMilestone(ctx, orderID, uuid.New(), freelancerID)
+
+	assert.ErrorIs(t, err, ErrMilestoneForbidden)
+	payments.AssertNotCalled(t, "ReleaseMilestone", mock.Anything, mock.Anything)
+}
+
+func TestMilestoneService_RefundMilestone_ClientBeforeDeadline(t *testing.T) {
+	svc, repo, payments, orders := newMilestoneTestService()
+	ctx := context.Background()
+
+	clientID := uuid.New()
+	freelancerID := uuid.New()
+	orderID := uuid.New()
+	milestoneID := uuid.New()
+	due := time.Now().Add(24 * time.Hour)
+
+	orders.On("GetByID", ctx, orderID).Return(&models.Order{
+		ID: orderID, ClientID: clientID, FreelancerID: &freelancerID, Status: models.OrderStatusInProgress,
+	}, nil)
+	repo.On("GetByID", ctx, milestoneID).Return(&models.Milestone{
+		ID: milestoneID, OrderID: orderID, Status: models.MilestoneStatusFunded, DueAt: &due,
+	}, nil)
+
+	_, err := svc.RefundMilestone(ctx, orderID, milestoneID, clientID)
+
+	assert.ErrorIs(t, err, ErrMilestoneRefundDenied)
+	payments.AssertNotCalled(t, "RefundMilestone", mock.Anything, mock.Anything)
+}
+
+func TestMilestoneService_RefundMilestone_ClientOverdue(t *testing.T) {
+	svc, repo, payments, orders := newMilestoneTestService()
+	ctx := context.Background()
+
+	clientID := uuid.New()
+	freelancerID := uuid.New()
+	orderID := uuid.New()
+	milestoneID := uuid.New()
+	due := time.Now().Add(-time.Hour)
+
+	orders.On("GetByID", ctx, orderID).Return(&models.Order{
+		ID: orderID, ClientID: clientID, FreelancerID: &freelancerID, Status: models.OrderStatusInProgress,
+	}, nil)
+	repo.On("GetByID", ctx, milestoneID).Return(&models.Milestone{
+		ID: milestoneID, OrderID: orderID, Status: models.MilestoneStatusFunded, DueAt: &due,
+	}, nil)
+	payments.On("RefundMilestone", ctx, milestoneID).Return(&models.Escrow{ID: uuid.New()}, nil)
+
+	_, err := svc.RefundMilestone(ctx, orderID, milestoneID, clientID)
+
+	assert.NoError(t, err)
+	payments.AssertExpectations(t)
+}
+
+func TestMilestoneService_UpdateMilestone_AlreadyFunded(t *testing.T) {
+	svc, repo, _, orders := newMilestoneTestService()
+	ctx := context.Background()
+
+	clientID := uuid.New()
+	orderID := uuid.New()
+	milestoneID := uuid.New()
+
+	orders.On("GetByID", ctx, orderID).Return(&models.Order{ID: orderID, ClientID: clientID, Status: models.OrderStatusInProgress}, nil)
+	repo.On("GetByID", ctx, milestoneID).Return(&models.Milestone{
+		ID: milestoneID, OrderID: orderID, Status: models.MilestoneStatusFunded,
+	}, nil)
+
+	_, err := svc.UpdateMilestone(ctx, orderID, milestoneID, clientID, MilestoneInput{Title: "Новый", Amount: 50})
+
+	assert.ErrorIs(t, err, repository.ErrMilestoneInvalidState)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+type fakeOrderMilestones []models.Milestone
+
+func (f fakeOrderMilestones) CountByOrder(ctx context.Context, orderID uuid.UUID) (int, error) {
+	return len(f), nil
+}
+
+func (f fakeOrderMilestones) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.Milestone, error) {
+	return f, nil
+}
+
+type refundRecorder struct {
+	PaymentRepositoryForOrders
+	refunded []uuid.UUID
+}
+
+func (r *refundRecorder) RefundMilestone(ctx context.Context, milestoneID uuid.UUID) (*models.Escrow, error) {
+	r.refunded = append(r.refunded, milestoneID)
+	return &models.Escrow{}, nil
+}
+
+func TestOrderService_SettleMilestones(t *testing.T) {
+	ctx := context.Background()
+	funded := models.Milestone{ID: uuid.New(), Title: "Дизайн", Status: models.MilestoneStatusFunded}
+	submitted := models.Milestone{ID: uuid.New(), Title: "Вёрстка", Status: models.MilestoneStatusSubmitted}
+	released := models.Milestone{ID: uuid.New(), Title: "Бриф", Status: models.MilestoneStatusReleased}
+
+	payments := &refundRecorder{}
+	svc := &OrderService{payment: payments, milestones: fakeOrderMilestones{released, funded, submitted}}
+
+	// Пока есть оплаченные, но не принятые этапы, заказ не завершить
+	assert.ErrorIs(t, svc.settleMilestones(ctx, uuid.New(), models.OrderStatusCompleted), ErrOrderMilestonesOpen)
+	assert.Empty(t, payments.refunded)
+
+	// При отмене возвращаются только оплаченные и не сданные этапы
+	assert.NoError(t, svc.settleMilestones(ctx, uuid.New(), models.OrderStatusCancelled))
+	assert.Equal(t, []uuid.UUID{funded.ID}, payments.refunded)
+
+	svc.milestones = fakeOrderMilestones{released}
+	assert.NoError(t, svc.settleMilestones(ctx, uuid.New(), models.OrderStatusCompleted))
+}
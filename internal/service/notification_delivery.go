@@ -0,0 +1,556 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/logger"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+const (
+	// digestPeriod — как часто пользователь получает сводку непрочитанных уведомлений.
+	digestPeriod = 24 * time.Hour
+	// digestBatchSize — сколько сводок отправляется за один проход.
+	digestBatchSize = 100
+	// digestMaxItems — сколько уведомлений перечисляется в письме; об остальных пишется число.
+	digestMaxItems = 20
+	// externalDeliveryTimeout ограничивает отправку письма или сообщения в Telegram.
+	externalDeliveryTimeout = 15 * time.Second
+	// deferredBatchSize — скольким пользователям за проход отправляются отложенные уведомления.
+	deferredBatchSize = 100
+	// deferredLease — через сколько неотправленные отложенные уведомления заберёт следующий проход.
+	deferredLease = 10 * time.Minute
+	// deferredMaxAge — сколько отложенное уведомление пытаются отправить, прежде чем удалить.
+	deferredMaxAge = 24 * time.Hour
+	// maxNotificationPreferences — сколько типов событий можно настроить отдельно.
+	maxNotificationPreferences = 100
+)
+
+var (
+	// ErrNotificationChannel возвращается для неизвестного канала доставки.
+	ErrNotificationChannel = errors.New("notification service: канал должен быть одним из in_app, email, telegram, none")
+	// ErrNotificationEventType возвращается для пустого или некорректного типа события.
+	ErrNotificationEventType = errors.New("notification service: тип события — латиница, цифры, точка и подчёркивание до 64 символов или *")
+	// ErrNotificationMandatory возвращается при попытке отключить уведомления безопасности.
+	ErrNotificationMandatory = errors.New("notification service: уведомления безопасности нельзя отключить")
+	// ErrNotificationQuietHours возвращается для некорректных тихих часов или часового пояса.
+	ErrNotificationQuietHours = errors.New("notification service: тихие часы задаются как ЧЧ:ММ вместе с началом и концом, часовой пояс — в формате IANA")
+	// ErrNotificationPreferencesLimit возвращается, если настроено слишком много типов событий.
+	ErrNotificationPreferencesLimit = errors.New("notification service: слишком много настроек по типам событий")
+	// ErrNotificationTelegramNotLinked возвращается при выборе канала telegram, пока чат из профиля
+	// не привязан к подтверждённому телефону.
+	ErrNotificationTelegramNotLinked = errors.New("notification service: укажите в профиле chat id Telegram и поделитесь с ботом подтверждённым номером телефона")
+)
+
+var notificationEventTypePattern = regexp.MustCompile(`^[a-z0-9_.]{1,64}$`)
+
+// mandatoryNotificationEvents сохраняются всегда, даже если пользователь выбрал канал none.
+var mandatoryNotificationEvents = map[string]struct{}{
+	"session_revoked":  {},
+	"suspicious_login": {},
+	"new_device_login": {},
+}
+
+// liveNotificationEvents при канале none всё равно уходят открытым вкладкам, но без номера:
+// без них открытая страница показывала бы устаревшие данные. Остальные события с каналом
+// none не отправляются совсем.
+var liveNotificationEvents = map[string]struct{}{
+	"chat.message":    {},
+	"profile.updated": {},
+}
+
+// notificationTitles — заголовки писем и сообщений в Telegram для известных событий.
+var notificationTitles = map[string]string{
+	"chat.message":                   "Новое сообщение в чате",
+	"proposals.new":                  "Новый отклик на заказ",
+	"proposals.updated":              "Статус отклика изменён",
+	"orders.updated":                 "Заказ обновлён",
+	"order_status_changed":           "Статус заказа изменён",
+	"proposal_status_changed":        "Статус отклика изменён",
+	"session_revoked":                "Сессия отозвана",
+	"suspicious_login":               "Вход в аккаунт заблокирован",
+	"new_device_login":               "Вход с нового устройства",
+	"identity_verification_reviewed": "Проверка личности завершена",
+	"data_export_ready":              "Архив с данными готов",
+	"report_resolved":                "Жалоба рассмотрена",
+}
+
+// NotificationPreferenceRepository описывает хранилище настроек уведомлений.
+type NotificationPreferenceRepository interface {
+	GetSettings(ctx context.Context, userID uuid.UUID) (*models.NotificationSettings, error)
+	ListPreferences(ctx context.Context, userID uuid.UUID) ([]models.NotificationPreference, error)
+	SaveSettings(ctx context.Context, settings *models.NotificationSettings, preferences []models.NotificationPreference) error
+	GetDelivery(ctx context.Context, userID uuid.UUID, eventType string) (*models.NotificationDelivery, error)
+	ListDigestRecipients(ctx context.Context, period time.Duration, limit int) ([]models.DigestRecipient, error)
+	ClaimDigest(ctx context.Context, userID uuid.UUID, previous *time.Time, at time.Time) (bool, error)
+	ReleaseDigest(ctx context.Context, userID uuid.UUID, previous *time.Time, at time.Time) error
+	DeferDelivery(ctx context.Context, userID uuid.UUID, eventType, channel string) error
+	ListDeferredUsers(ctx context.Context, limit int) ([]uuid.UUID, error)
+	ClaimDeferred(ctx context.Context, userID uuid.UUID, lease, maxAge time.Duration) ([]models.DeferredNotification, error)
+	DeleteDeferred(ctx context.Context, ids []int64) error
+	ListUnreadSince(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]models.Notification, int, error)
+}
+
+// NotificationSettingsInput — новые настройки уведомлений. QuietHoursStart и QuietHoursEnd
+// задаются как "ЧЧ:ММ" или оба пустые.
+type NotificationSettingsInput struct {
+	Preferences     []models.NotificationPreference
+	QuietHoursStart string
+	QuietHoursEnd   string
+	Timezone        string
+	DigestEnabled   bool
+}
+
+// NotificationSettingsView — настройки уведомлений в ответе API.
+type NotificationSettingsView struct {
+	Preferences     []models.NotificationPreference `json:"preferences"`
+	QuietHoursStart *string                         `json:"quiet_hours_start"`
+	QuietHoursEnd   *string                         `json:"quiet_hours_end"`
+	Timezone        string                          `json:"timezone"`
+	DigestEnabled   bool                            `json:"digest_enabled"`
+	LastDigestAt    *time.Time                      `json:"last_digest_at,omitempty"`
+}
+
+// SetPreferences подключает настройки уведомлений; без них все события сохраняются и
+// никуда, кроме WebSocket, не отправляются.
+func (s *NotificationService) SetPreferences(prefs NotificationPreferenceRepository) {
+	s.prefs = prefs
+}
+
+// SetChannels подключает внешние каналы. mailer нужен для канала email и сводок, telegram —
+// для канала telegram; любой из них может быть nil. frontendURL подставляется в ссылки.
+func (s *NotificationService) SetChannels(mailer Mailer, telegram SMSSender, frontendURL string) {
+	s.mailer = mailer
+	s.telegram = telegram
+	s.frontendURL = strings.TrimRight(frontendURL, "/")
+}
+
+// GetSettings возвращает настройки уведомлений пользователя.
+func (s *NotificationService) GetSettings(ctx context.Context, userID uuid.UUID) (*NotificationSettingsView, error) {
+	if s.prefs == nil {
+		return nil, fmt.Errorf("notification service: настройки уведомлений недоступны")
+	}
+	settings, err := s.prefs.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	preferences, err := s.prefs.ListPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return newNotificationSettingsView(settings, preferences), nil
+}
+
+// UpdateSettings проверяет и сохраняет настройки уведомлений, заменяя прежние целиком.
+func (s *NotificationService) UpdateSettings(ctx context.Context, userID uuid.UUID, input NotificationSettingsInput) (*NotificationSettingsView, error) {
+	if s.prefs == nil {
+		return nil, fmt.Errorf("notification service: настройки уведомлений недоступны")
+	}
+	if len(input.Preferences) > maxNotificationPreferences {
+		return nil, ErrNotificationPreferencesLimit
+	}
+
+	seen := make(map[string]int, len(input.Preferences))
+	preferences := make([]models.NotificationPreference, 0, len(input.Preferences))
+	for _, preference := range input.Preferences {
+		eventType := strings.TrimSpace(preference.EventType)
+		if eventType != models.NotificationEventDefault && !notificationEventTypePattern.MatchString(eventType) {
+			return nil, ErrNotificationEventType
+		}
+		switch preference.Channel {
+		case models.NotificationChannelInApp, models.NotificationChannelEmail, models.NotificationChannelTelegram:
+		case models.NotificationChannelNone:
+			if _, ok := mandatoryNotificationEvents[eventType]; ok {
+				return nil, ErrNotificationMandatory
+			}
+		default:
+			return nil, ErrNotificationChannel
+		}
+		// Повтор типа события заменяет предыдущее значение
+		if i, ok := seen[eventType]; ok {
+			preferences[i].Channel = preference.Channel
+			continue
+		}
+		seen[eventType] = len(preferences)
+		preferences = append(preferences, models.NotificationPreference{EventType: eventType, Channel: preference.Channel})
+	}
+	for _, preference := range preferences {
+		if preference.Channel != models.NotificationChannelTelegram {
+			continue
+		}
+		// Контакты не зависят от типа события
+		delivery, err := s.prefs.GetDelivery(ctx, userID, models.NotificationEventDefault)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := linkedTelegramChat(delivery); !ok {
+			return nil, ErrNotificationTelegramNotLinked
+		}
+		break
+	}
+
+	settings := &models.NotificationSettings{
+		UserID:        userID,
+		Timezone:      strings.TrimSpace(input.Timezone),
+		DigestEnabled: input.DigestEnabled,
+	}
+	if settings.Timezone == "" {
+		settings.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(settings.Timezone); err != nil {
+		return nil, ErrNotificationQuietHours
+	}
+	if input.QuietHoursStart != "" || input.QuietHoursEnd != "" {
+		start, err := parseClock(input.QuietHoursStart)
+		if err != nil {
+			return nil, ErrNotificationQuietHours
+		}
+		end, err := parseClock(input.QuietHoursEnd)
+		if err != nil {
+			return nil, ErrNotificationQuietHours
+		}
+		settings.QuietHoursStart, settings.QuietHoursEnd = &start, &end
+	}
+
+	if err := s.prefs.SaveSettings(ctx, settings, preferences); err != nil {
+		return nil, err
+	}
+	return s.GetSettings(ctx, userID)
+}
+
+// deliveryFor возвращает, как доставить событие. Если настройки недоступны, событие
+// сохраняется как обычно: пропустить уведомление хуже, чем показать лишнее.
+func (s *NotificationService) deliveryFor(ctx context.Context, userID uuid.UUID, event string) *models.NotificationDelivery {
+	fallback := &models.NotificationDelivery{Channel: models.NotificationChannelInApp}
+	if s.prefs == nil {
+		return fallback
+	}
+	delivery, err := s.prefs.GetDelivery(ctx, userID, event)
+	if err != nil {
+		s.logDeliveryError(err, userID, event, "notification: не удалось получить настройки доставки")
+		return fallback
+	}
+	if _, ok := mandatoryNotificationEvents[event]; ok && delivery.Channel == models.NotificationChannelNone {
+		delivery.Channel = models.NotificationChannelInApp
+	}
+	return delivery
+}
+
+// deliverExternal дублирует уведомление письмом или сообщением в Telegram. В тихие часы
+// отправка откладывается: после их окончания отложенное уйдёт через SendDeferred.
+func (s *NotificationService) deliverExternal(userID uuid.UUID, event string, delivery *models.NotificationDelivery) {
+	ctx, cancel := context.WithTimeout(context.Background(), externalDeliveryTimeout)
+	defer cancel()
+
+	if delivery.Settings.InQuietHours(s.now()) {
+		if err := s.prefs.DeferDelivery(ctx, userID, event, delivery.Channel); err != nil {
+			s.logDeliveryError(err, userID, event, "notification: не удалось отложить уведомление до конца тихих часов")
+		}
+		return
+	}
+	if err := s.sendExternal(ctx, delivery.Channel, delivery, []string{event}); err != nil {
+		s.logDeliveryError(err, userID, event, "notification: не удалось отправить уведомление по каналу "+delivery.Channel)
+	}
+}
+
+// sendExternal отправляет по каналу channel одно сообщение о событиях events. Если канал
+// не подключён или у пользователя нет контакта для него, ничего не отправляется.
+func (s *NotificationService) sendExternal(ctx context.Context, channel string, delivery *models.NotificationDelivery, events []string) error {
+	subject, text := externalSummary(events)
+	switch channel {
+	case models.NotificationChannelEmail:
+		if s.mailer == nil || delivery.Email == "" {
+			return nil
+		}
+		return s.mailer.Send(ctx, Email{
+			To:      delivery.Email,
+			Subject: subject,
+			Body:    fmt.Sprintf("%s\n\nПодробности в уведомлениях: %s/notifications\n", text, s.frontendURL),
+		})
+	case models.NotificationChannelTelegram:
+		chatID, ok := linkedTelegramChat(delivery)
+		if s.telegram == nil || !ok {
+			return nil
+		}
+		return s.telegram.SendSMS(ctx, PhoneMessage{
+			Telegram: chatID,
+			Text:     fmt.Sprintf("%s\n%s/notifications", text, s.frontendURL),
+		})
+	default:
+		return nil
+	}
+}
+
+// linkedTelegramChat возвращает chat id из профиля, если чат принадлежит пользователю: его владелец
+// поделился с ботом подтверждённым телефоном профиля, как и при отправке кода в Telegram.
+// Поле Telegram заполняет сам пользователь, и без этой проверки туда можно вписать чужой чат.
+func linkedTelegramChat(delivery *models.NotificationDelivery) (string, bool) {
+	if delivery.Telegram == nil || !delivery.PhoneVerified || delivery.TelegramPhone == nil {
+		return "", false
+	}
+	chatID := strings.TrimSpace(*delivery.Telegram)
+	if !isTelegramChatID(chatID) {
+		return "", false
+	}
+	phone, ok := normalizePhone(delivery.Phone)
+	if !ok || phone != *delivery.TelegramPhone {
+		return "", false
+	}
+	return chatID, true
+}
+
+// RunDigests периодически отправляет уведомления, отложенные в тихие часы, и сводки, пока
+// ctx не отменён.
+func (s *NotificationService) RunDigests(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.SendDeferred(ctx); err != nil && logger.Log != nil {
+			logger.Log.WithField("error", err.Error()).Error("notification: не удалось отправить отложенные уведомления")
+		}
+		if _, err := s.SendDigests(ctx); err != nil && logger.Log != nil {
+			logger.Log.WithField("error", err.Error()).Error("notification: не удалось отправить сводки")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDeferred отправляет уведомления, отложенные в тихие часы, пользователям, у которых тихие
+// часы закончились: одно сообщение на канал. Возвращает число отправленных сообщений.
+func (s *NotificationService) SendDeferred(ctx context.Context) (int, error) {
+	if s.prefs == nil {
+		return 0, nil
+	}
+	users, err := s.prefs.ListDeferredUsers(ctx, deferredBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.now()
+	sent := 0
+	for _, userID := range users {
+		// Канал события здесь не нужен, только настройки и контакты
+		delivery, err := s.prefs.GetDelivery(ctx, userID, models.NotificationEventDefault)
+		if err != nil {
+			return sent, err
+		}
+		if delivery.Settings.InQuietHours(now) {
+			continue
+		}
+		deferred, err := s.prefs.ClaimDeferred(ctx, userID, deferredLease, deferredMaxAge)
+		if err != nil {
+			return sent, err
+		}
+
+		events := make(map[string][]string)
+		ids := make(map[string][]int64)
+		for _, d := range deferred {
+			events[d.Channel] = append(events[d.Channel], d.EventType)
+			ids[d.Channel] = append(ids[d.Channel], d.ID)
+		}
+		for channel := range events {
+			// Если отправить не удалось, строки останутся и уйдут после истечения deferredLease
+			if err := s.sendExternal(ctx, channel, delivery, events[channel]); err != nil {
+				s.logDeliveryError(err, userID, "deferred", "notification: не удалось отправить отложенные уведомления по каналу "+channel)
+				continue
+			}
+			if err := s.prefs.DeleteDeferred(ctx, ids[channel]); err != nil {
+				return sent, err
+			}
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// SendDigests отправляет пачку сводок непрочитанных уведомлений. Возвращает число отправленных писем.
+func (s *NotificationService) SendDigests(ctx context.Context) (int, error) {
+	if s.prefs == nil || s.mailer == nil {
+		return 0, nil
+	}
+	recipients, err := s.prefs.ListDigestRecipients(ctx, digestPeriod, digestBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.now()
+	sent := 0
+	for _, recipient := range recipients {
+		// В тихие часы сводка откладывается до следующего прохода
+		if recipient.InQuietHours(now) {
+			continue
+		}
+		// Отметка не даёт другому экземпляру отправить ту же сводку; если письмо не ушло,
+		// она снимается, и сводка будет отправлена при следующем проходе
+		claimed, err := s.prefs.ClaimDigest(ctx, recipient.UserID, recipient.LastDigestAt, now)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		since := now.Add(-digestPeriod)
+		if recipient.LastDigestAt != nil {
+			since = *recipient.LastDigestAt
+		}
+		notifications, total, err := s.prefs.ListUnreadSince(ctx, recipient.UserID, since, digestMaxItems)
+		if err != nil {
+			s.releaseDigest(ctx, recipient, now)
+			return sent, err
+		}
+		if total == 0 {
+			continue
+		}
+
+		if err := s.mailer.Send(ctx, Email{
+			To:      recipient.Email,
+			Subject: fmt.Sprintf("Непрочитанные уведомления: %d", total),
+			Body:    s.digestBody(notifications, total),
+		}); err != nil {
+			s.logDeliveryError(err, recipient.UserID, "digest", "notification: не удалось отправить сводку")
+			s.releaseDigest(ctx, recipient, now)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// releaseDigest снимает отметку о сводке, которую не удалось отправить.
+func (s *NotificationService) releaseDigest(ctx context.Context, recipient models.DigestRecipient, at time.Time) {
+	if err := s.prefs.ReleaseDigest(ctx, recipient.UserID, recipient.LastDigestAt, at); err != nil {
+		s.logDeliveryError(err, recipient.UserID, "digest", "notification: не удалось снять отметку о сводке")
+	}
+}
+
+func (s *NotificationService) digestBody(notifications []models.Notification, total int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "За последние сутки у вас %d непрочитанных уведомлений:\n\n", total)
+	for _, n := range notifications {
+		fmt.Fprintf(&b, "- %s — %s\n", n.CreatedAt.UTC().Format("02.01 15:04 UTC"), notificationTitle(notificationEvent(n)))
+	}
+	if rest := total - len(notifications); rest > 0 {
+		fmt.Fprintf(&b, "- и ещё %d\n", rest)
+	}
+	fmt.Fprintf(&b, "\nВсе уведомления: %s/notifications\n", s.frontendURL)
+	b.WriteString("Отключить сводку можно в настройках уведомлений.\n")
+	return b.String()
+}
+
+func (s *NotificationService) now() time.Time {
+	if s.clock != nil {
+		return s.clock()
+	}
+	return time.Now()
+}
+
+func (s *NotificationService) logDeliveryError(err error, userID uuid.UUID, event, msg string) {
+	if logger.Log == nil {
+		return
+	}
+	logger.Log.WithFields(map[string]interface{}{
+		"error":   err.Error(),
+		"user_id": userID.String(),
+		"event":   event,
+	}).Error(msg)
+}
+
+func newNotificationSettingsView(settings *models.NotificationSettings, preferences []models.NotificationPreference) *NotificationSettingsView {
+	view := &NotificationSettingsView{
+		Preferences:   preferences,
+		Timezone:      settings.Timezone,
+		DigestEnabled: settings.DigestEnabled,
+		LastDigestAt:  settings.LastDigestAt,
+	}
+	if settings.QuietHoursStart != nil && settings.QuietHoursEnd != nil {
+		start, end := formatClock(*settings.QuietHoursStart), formatClock(*settings.QuietHoursEnd)
+		view.QuietHoursStart, view.QuietHoursEnd = &start, &end
+	}
+	return view
+}
+
+// externalSummary возвращает тему и текст сообщения о событиях: для одного события — его
+// заголовок, для нескольких — список заголовков с числом повторов.
+func externalSummary(events []string) (string, string) {
+	if len(events) == 1 {
+		title := notificationTitle(events[0])
+		return title, title + "."
+	}
+
+	var order []string
+	counts := make(map[string]int)
+	for _, event := range events {
+		if counts[event] == 0 {
+			order = append(order, event)
+		}
+		counts[event]++
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Пока действовали тихие часы, пришло уведомлений: %d", len(events))
+	for i, event := range order {
+		if i == digestMaxItems {
+			b.WriteString("\n- и другие")
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s", notificationTitle(event))
+		if counts[event] > 1 {
+			fmt.Fprintf(&b, " (%d)", counts[event])
+		}
+	}
+	return fmt.Sprintf("Уведомления за тихие часы: %d", len(events)), b.String()
+}
+
+func notificationTitle(event string) string {
+	if title, ok := notificationTitles[event]; ok {
+		return title
+	}
+	return "Новое уведомление: " + event
+}
+
+// notificationEvent достаёт тип события из сохранённого уведомления {"event", "data"}.
+func notificationEvent(n models.Notification) string {
+	var payload struct {
+		Event string `json:"event"`
+	}
+	if err := json.Unmarshal(n.Payload, &payload); err != nil {
+		return ""
+	}
+	return payload.Event
+}
+
+// parseClock разбирает "ЧЧ:ММ" в минуты от полуночи.
+func parseClock(value string) (int, error) {
+	hours, minutes, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok || len(hours) != 2 || len(minutes) != 2 {
+		return 0, fmt.Errorf("некорректное время %q", value)
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("некорректное время %q", value)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 || m > 59 {
+		return 0, fmt.Errorf("некорректное время %q", value)
+	}
+	return h*60 + m, nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -19,11 +20,17 @@ type NotificationRepository interface {
 	MarkAllAsRead(ctx context.Context, userID uuid.UUID) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	CountUnread(ctx context.Context, userID uuid.UUID) (int, error)
+	ListLatestSince(ctx context.Context, userID uuid.UUID, since int64, limit int) ([]models.Notification, error)
 }
 
 // NotificationService содержит бизнес-логику работы с уведомлениями.
 type NotificationService struct {
-	repo NotificationRepository
+	repo        NotificationRepository
+	prefs       NotificationPreferenceRepository
+	mailer      Mailer
+	telegram    SMSSender
+	frontendURL string
+	clock       func() time.Time
 }
 
 // NewNotificationService создаёт новый сервис уведомлений.
@@ -111,9 +118,31 @@ func (s *NotificationService) CountUnread(ctx context.Context, userID uuid.UUID)
 	return s.repo.CountUnread(ctx, userID)
 }
 
-// CreateNotificationForWS создаёт уведомление (для использования в WebSocket hub).
-func (s *NotificationService) CreateNotificationForWS(ctx context.Context, userID uuid.UUID, event string, data interface{}) error {
-	_, err := s.CreateNotification(ctx, userID, event, data)
-	return err
+// CreateNotificationForWS сохраняет событие WebSocket hub как уведомление с учётом настроек
+// пользователя и возвращает его номер события и нужно ли отправить событие открытым соединениям.
+// Для канала none уведомление не сохраняется; отправляются без номера только события из
+// liveNotificationEvents.
+func (s *NotificationService) CreateNotificationForWS(ctx context.Context, userID uuid.UUID, event string, data interface{}) (int64, bool, error) {
+	delivery := s.deliveryFor(ctx, userID, event)
+	if delivery.Channel == models.NotificationChannelNone {
+		_, live := liveNotificationEvents[event]
+		return 0, live, nil
+	}
+
+	notification, err := s.CreateNotification(ctx, userID, event, data)
+	if err != nil {
+		return 0, true, err
+	}
+	if delivery.Channel == models.NotificationChannelEmail || delivery.Channel == models.NotificationChannelTelegram {
+		go s.deliverExternal(userID, event, delivery)
+	}
+	if notification.Seq == nil {
+		return 0, true, nil
+	}
+	return *notification.Seq, true, nil
 }
 
+// ListEventsSince возвращает не больше limit последних событий пользователя с номером больше since.
+func (s *NotificationService) ListEventsSince(ctx context.Context, userID uuid.UUID, since int64, limit int) ([]models.Notification, error) {
+	return s.repo.ListLatestSince(ctx, userID, since, limit)
+}
@@ -0,0 +1,361 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+type memoryNotificationRepo struct {
+	NotificationRepository
+	created []*models.Notification
+}
+
+func (r *memoryNotificationRepo) Create(ctx context.Context, notification *models.Notification) error {
+	seq := int64(len(r.created) + 1)
+	notification.ID = uuid.New()
+	notification.Seq = &seq
+	r.created = append(r.created, notification)
+	return nil
+}
+
+type fakeNotificationPreferences struct {
+	NotificationPreferenceRepository
+	delivery   map[string]*models.NotificationDelivery
+	saved      []models.NotificationPreference
+	recipients []models.DigestRecipient
+	claimed    map[uuid.UUID]bool
+	unread     []models.Notification
+	deferred   []models.DeferredNotification
+}
+
+func (p *fakeNotificationPreferences) GetDelivery(ctx context.Context, userID uuid.UUID, eventType string) (*models.NotificationDelivery, error) {
+	if delivery, ok := p.delivery[eventType]; ok {
+		copied := *delivery
+		return &copied, nil
+	}
+	return &models.NotificationDelivery{Channel: models.NotificationChannelInApp}, nil
+}
+
+func (p *fakeNotificationPreferences) SaveSettings(ctx context.Context, settings *models.NotificationSettings, preferences []models.NotificationPreference) error {
+	p.saved = preferences
+	return nil
+}
+
+func (p *fakeNotificationPreferences) GetSettings(ctx context.Context, userID uuid.UUID) (*models.NotificationSettings, error) {
+	return &models.NotificationSettings{UserID: userID, Timezone: "UTC"}, nil
+}
+
+func (p *fakeNotificationPreferences) ListPreferences(ctx context.Context, userID uuid.UUID) ([]models.NotificationPreference, error) {
+	return p.saved, nil
+}
+
+func (p *fakeNotificationPreferences) ListDigestRecipients(ctx context.Context, period time.Duration, limit int) ([]models.DigestRecipient, error) {
+	return p.recipients, nil
+}
+
+func (p *fakeNotificationPreferences) ClaimDigest(ctx context.Context, userID uuid.UUID, previous *time.Time, at time.Time) (bool, error) {
+	if p.claimed[userID] {
+		return false, nil
+	}
+	p.claimed[userID] = true
+	return true, nil
+}
+
+func (p *fakeNotificationPreferences) ReleaseDigest(ctx context.Context, userID uuid.UUID, previous *time.Time, at time.Time) error {
+	delete(p.claimed, userID)
+	return nil
+}
+
+func (p *fakeNotificationPreferences) DeferDelivery(ctx context.Context, userID uuid.UUID, eventType, channel string) error {
+	p.deferred = append(p.deferred, models.DeferredNotification{ID: int64(len(p.deferred) + 1), EventType: eventType, Channel: channel})
+	return nil
+}
+
+func (p *fakeNotificationPreferences) ListDeferredUsers(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	if len(p.deferred) == 0 {
+		return nil, nil
+	}
+	return []uuid.UUID{uuid.New()}, nil
+}
+
+func (p *fakeNotificationPreferences) ClaimDeferred(ctx context.Context, userID uuid.UUID, lease, maxAge time.Duration) ([]models.DeferredNotification, error) {
+	return p.deferred, nil
+}
+
+func (p *fakeNotificationPreferences) DeleteDeferred(ctx context.Context, ids []int64) error {
+	remaining := p.deferred[:0]
+	for _, d := range p.deferred {
+		if !slices.Contains(ids, d.ID) {
+			remaining = append(remaining, d)
+		}
+	}
+	p.deferred = remaining
+	return nil
+}
+
+func (p *fakeNotificationPreferences) ListUnreadSince(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]models.Notification, int, error) {
+	return p.unread, len(p.unread), nil
+}
+
+// linkedTelegram возвращает контакты пользователя, чей чат Telegram поделился с ботом подтверждённым телефоном профиля.
+func linkedTelegram(delivery models.NotificationDelivery) *models.NotificationDelivery {
+	chatID, phone, shared := "123456789", "+7 999 123-45-67", "+79991234567"
+	delivery.Telegram, delivery.Phone, delivery.PhoneVerified, delivery.TelegramPhone = &chatID, &phone, true, &shared
+	return &delivery
+}
+
+func TestNotificationService_UpdateSettings_Validation(t *testing.T) {
+	svc := NewNotificationService(nil)
+	svc.SetPreferences(&fakeNotificationPreferences{})
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		input NotificationSettingsInput
+		want  error
+	}{
+		{"unknown channel", NotificationSettingsInput{Preferences: []models.NotificationPreference{{EventType: "chat.message", Channel: "sms"}}}, ErrNotificationChannel},
+		{"bad event type", NotificationSettingsInput{Preferences: []models.NotificationPreference{{EventType: "Chat Message", Channel: "email"}}}, ErrNotificationEventType},
+		{"mandatory event", NotificationSettingsInput{Preferences: []models.NotificationPreference{{EventType: "suspicious_login", Channel: "none"}}}, ErrNotificationMandatory},
+		{"only start", NotificationSettingsInput{QuietHoursStart: "23:00"}, ErrNotificationQuietHours},
+		{"bad clock", NotificationSettingsInput{QuietHoursStart: "24:00", QuietHoursEnd: "07:00"}, ErrNotificationQuietHours},
+		{"bad timezone", NotificationSettingsInput{Timezone: "Mars/Olympus"}, ErrNotificationQuietHours},
+		{"telegram not linked", NotificationSettingsInput{Preferences: []models.NotificationPreference{{EventType: "*", Channel: "telegram"}}}, ErrNotificationTelegramNotLinked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.UpdateSettings(ctx, uuid.New(), tt.input)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestNotificationService_UpdateSettings_LastDuplicateWins(t *testing.T) {
+	prefs := &fakeNotificationPreferences{delivery: map[string]*models.NotificationDelivery{
+		models.NotificationEventDefault: linkedTelegram(models.NotificationDelivery{}),
+	}}
+	svc := NewNotificationService(nil)
+	svc.SetPreferences(prefs)
+
+	view, err := svc.UpdateSettings(context.Background(), uuid.New(), NotificationSettingsInput{
+		Preferences: []models.NotificationPreference{
+			{EventType: "chat.message", Channel: "email"},
+			{EventType: "*", Channel: "none"},
+			{EventType: "chat.message", Channel: "telegram"},
+		},
+		QuietHoursStart: "23:00",
+		QuietHoursEnd:   "07:30",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.NotificationPreference{
+		{EventType: "chat.message", Channel: "telegram"},
+		{EventType: "*", Channel: "none"},
+	}, view.Preferences)
+	assert.Equal(t, "UTC", view.Timezone)
+}
+
+func TestNotificationService_CreateNotificationForWS_Channels(t *testing.T) {
+	repo := &memoryNotificationRepo{}
+	sender := NewFakeSMSSender()
+	svc := NewNotificationService(repo)
+	svc.SetPreferences(&fakeNotificationPreferences{delivery: map[string]*models.NotificationDelivery{
+		"chat.message":     {Channel: models.NotificationChannelNone},
+		"orders.updated":   {Channel: models.NotificationChannelNone},
+		"suspicious_login": {Channel: models.NotificationChannelNone},
+		"proposals.new":    linkedTelegram(models.NotificationDelivery{Channel: models.NotificationChannelTelegram}),
+	}})
+	svc.SetChannels(nil, sender, "https://example.com/")
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// Канал none: уведомление не сохраняется и не получает номер, открытым вкладкам
+	// уходят только события из liveNotificationEvents
+	seq, live, err := svc.CreateNotificationForWS(ctx, userID, "chat.message", nil)
+	assert.NoError(t, err)
+	assert.Zero(t, seq)
+	assert.True(t, live)
+	seq, live, err = svc.CreateNotificationForWS(ctx, userID, "orders.updated", nil)
+	assert.NoError(t, err)
+	assert.Zero(t, seq)
+	assert.False(t, live)
+	assert.Empty(t, repo.created)
+
+	// Уведомления безопасности сохраняются всегда
+	seq, live, err = svc.CreateNotificationForWS(ctx, userID, "suspicious_login", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), seq)
+	assert.True(t, live)
+
+	// Telegram дублирует сохранённое уведомление
+	seq, _, err = svc.CreateNotificationForWS(ctx, userID, "proposals.new", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), seq)
+	assert.Eventually(t, func() bool { return len(sender.Sent()) == 1 }, time.Second, 5*time.Millisecond)
+	sent := sender.Sent()[0]
+	assert.Equal(t, "123456789", sent.Telegram)
+	assert.Contains(t, sent.Text, "https://example.com/notifications")
+}
+
+func TestNotificationService_DeliverExternal_DefersQuietHours(t *testing.T) {
+	start, end := 22*60, 8*60
+	settings := models.NotificationSettings{QuietHoursStart: &start, QuietHoursEnd: &end, Timezone: "UTC"}
+	prefs := &fakeNotificationPreferences{delivery: map[string]*models.NotificationDelivery{
+		models.NotificationEventDefault: linkedTelegram(models.NotificationDelivery{Email: "client@example.com", Settings: settings}),
+	}}
+	mailer := &recordingMailer{}
+	sender := NewFakeSMSSender()
+	svc := NewNotificationService(nil)
+	svc.SetPreferences(prefs)
+	svc.SetChannels(mailer, sender, "https://example.com")
+	svc.clock = func() time.Time { return time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC) }
+
+	email := &models.NotificationDelivery{Channel: models.NotificationChannelEmail, Email: "client@example.com", Settings: settings}
+	userID := uuid.New()
+	svc.deliverExternal(userID, "chat.message", email)
+	svc.deliverExternal(userID, "chat.message", email)
+	svc.deliverExternal(userID, "proposals.new", email)
+	svc.deliverExternal(userID, "proposals.new", linkedTelegram(models.NotificationDelivery{Channel: models.NotificationChannelTelegram, Settings: settings}))
+	assert.Empty(t, mailer.sent)
+	assert.Len(t, prefs.deferred, 4)
+
+	// Пока тихие часы не кончились, отложенное не отправляется
+	sent, err := svc.SendDeferred(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, sent)
+
+	svc.clock = func() time.Time { return time.Date(2026, 3, 11, 8, 10, 0, 0, time.UTC) }
+	sent, err = svc.SendDeferred(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	assert.Empty(t, prefs.deferred)
+	if assert.Len(t, mailer.sent, 1) {
+		assert.Equal(t, "Уведомления за тихие часы: 3", mailer.sent[0].Subject)
+		assert.Contains(t, mailer.sent[0].Body, "- Новое сообщение в чате (2)\n- Новый отклик на заказ\n")
+	}
+	if assert.Len(t, sender.Sent(), 1) {
+		assert.Contains(t, sender.Sent()[0].Text, "Новый отклик на заказ.")
+	}
+
+	// Вне тихих часов уведомление отправляется сразу
+	svc.deliverExternal(userID, "proposals.new", email)
+	if assert.Len(t, mailer.sent, 2) {
+		assert.Equal(t, "Новый отклик на заказ", mailer.sent[1].Subject)
+	}
+}
+
+func TestNotificationService_TelegramRequiresLinkedChat(t *testing.T) {
+	sender := NewFakeSMSSender()
+	svc := NewNotificationService(nil)
+	svc.SetChannels(nil, sender, "https://example.com")
+	handle, other := "@client", "+79990000000"
+
+	unlinked := map[string]func(d *models.NotificationDelivery){
+		"handle instead of chat id": func(d *models.NotificationDelivery) { d.Telegram = &handle },
+		"phone not verified":        func(d *models.NotificationDelivery) { d.PhoneVerified = false },
+		"chat never shared a phone": func(d *models.NotificationDelivery) { d.TelegramPhone = nil },
+		"chat shared another phone": func(d *models.NotificationDelivery) { d.TelegramPhone = &other },
+	}
+	for name, unlink := range unlinked {
+		t.Run(name, func(t *testing.T) {
+			delivery := linkedTelegram(models.NotificationDelivery{})
+			unlink(delivery)
+			assert.NoError(t, svc.sendExternal(context.Background(), models.NotificationChannelTelegram, delivery, []string{"proposals.new"}))
+		})
+	}
+	assert.Empty(t, sender.Sent())
+
+	assert.NoError(t, svc.sendExternal(context.Background(), models.NotificationChannelTelegram, linkedTelegram(models.NotificationDelivery{}), []string{"proposals.new"}))
+	if assert.Len(t, sender.Sent(), 1) {
+		assert.Equal(t, "123456789", sender.Sent()[0].Telegram)
+	}
+}
+
+func TestNotificationService_SendDigests(t *testing.T) {
+	start, end := 22*60, 8*60
+	now := time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC)
+	sleeping := models.DigestRecipient{
+		NotificationSettings: models.NotificationSettings{UserID: uuid.New(), QuietHoursStart: &start, QuietHoursEnd: &end, Timezone: "UTC"},
+		Email:                "sleeping@example.com",
+	}
+	awake := models.DigestRecipient{
+		NotificationSettings: models.NotificationSettings{UserID: uuid.New(), Timezone: "UTC"},
+		Email:                "awake@example.com",
+	}
+	prefs := &fakeNotificationPreferences{
+		recipients: []models.DigestRecipient{sleeping, awake},
+		claimed:    map[uuid.UUID]bool{},
+		unread: []models.Notification{
+			{Payload: []byte(`{"event":"chat.message","data":{}}`), CreatedAt: now.Add(-time.Hour)},
+			{Payload: []byte(`{"event":"custom.event","data":{}}`), CreatedAt: now.Add(-2 * time.Hour)},
+		},
+	}
+	mailer := &recordingMailer{}
+	svc := NewNotificationService(nil)
+	svc.SetPreferences(prefs)
+	svc.SetChannels(mailer, nil, "https://example.com")
+	svc.clock = func() time.Time { return now }
+
+	sent, err := svc.SendDigests(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	if assert.Len(t, mailer.sent, 1) {
+		assert.Equal(t, "awake@example.com", mailer.sent[0].To)
+		assert.Equal(t, "Непрочитанные уведомления: 2", mailer.sent[0].Subject)
+		assert.Contains(t, mailer.sent[0].Body, "Новое сообщение в чате")
+		assert.Contains(t, mailer.sent[0].Body, "Новое уведомление: custom.event")
+	}
+
+	// Сводку уже забрал другой проход
+	sent, err = svc.SendDigests(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, sent)
+}
+
+// flakyMailer не отправляет первые fail писем.
+type flakyMailer struct {
+	recordingMailer
+	fail int
+}
+
+func (m *flakyMailer) Send(ctx context.Context, msg Email) error {
+	if m.fail > 0 {
+		m.fail--
+		return errors.New("smtp недоступен")
+	}
+	return m.recordingMailer.Send(ctx, msg)
+}
+
+func TestNotificationService_SendDigests_RetriesFailedSend(t *testing.T) {
+	recipient := models.DigestRecipient{
+		NotificationSettings: models.NotificationSettings{UserID: uuid.New(), Timezone: "UTC"},
+		Email:                "client@example.com",
+	}
+	prefs := &fakeNotificationPreferences{
+		recipients: []models.DigestRecipient{recipient},
+		claimed:    map[uuid.UUID]bool{},
+		unread:     []models.Notification{{Payload: []byte(`{"event":"chat.message","data":{}}`), CreatedAt: time.Now()}},
+	}
+	mailer := &flakyMailer{fail: 1}
+	svc := NewNotificationService(nil)
+	svc.SetPreferences(prefs)
+	svc.SetChannels(mailer, nil, "https://example.com")
+
+	sent, err := svc.SendDigests(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.False(t, prefs.claimed[recipient.UserID])
+
+	sent, err = svc.SendDigests(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Len(t, mailer.sent, 1)
+}
@@ -0,0 +1,432 @@
+package service
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCProviderConfig описывает внешнего провайдера входа.
+type OIDCProviderConfig struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	// Issuer включает OpenID Connect: адреса берутся из discovery, пользователя подтверждает подписанный id_token
+	Issuer string
+	// Адреса провайдеров без OpenID Connect; данные пользователя берутся из UserInfoURL
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	// EmailsURL отдаёт список адресов пользователя с признаком подтверждения (GitHub)
+	EmailsURL string
+	Scopes    []string
+	// TrustEmail — провайдер сообщает только подтверждённые адреса, но не отмечает это явно
+	TrustEmail bool
+}
+
+const (
+	oidcResponseLimit = 1 << 20
+	// oidcKeysRefreshInterval — не чаще этого ключи перечитываются ради неизвестного kid
+	oidcKeysRefreshInterval = time.Minute
+)
+
+// externalIdentity — пользователь, которого подтвердил провайдер.
+type externalIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type oidcEndpoints struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// flexBool принимает и true, и "true": часть провайдеров отдаёт email_verified строкой.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	*b = flexBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	Name          string   `json:"name"`
+}
+
+// userInfoResponse покрывает ответы OIDC userinfo, GitHub /user и Yandex /info.
+type userInfoResponse struct {
+	Sub           string          `json:"sub"`
+	ID            json.RawMessage `json:"id"`
+	Email         string          `json:"email"`
+	DefaultEmail  string          `json:"default_email"`
+	EmailVerified flexBool        `json:"email_verified"`
+	Name          string          `json:"name"`
+	RealName      string          `json:"real_name"`
+	Login         string          `json:"login"`
+}
+
+// oidcProvider выполняет authorization code flow с PKCE у одного провайдера.
+type oidcProvider struct {
+	cfg    OIDCProviderConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	endpoints     *oidcEndpoints
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+}
+
+func newOIDCProvider(cfg OIDCProviderConfig, client *http.Client) *oidcProvider {
+	return &oidcProvider{cfg: cfg, client: client}
+}
+
+// discover возвращает адреса провайдера; для OpenID Connect читает и кэширует discovery документ.
+func (p *oidcProvider) discover(ctx context.Context) (*oidcEndpoints, error) {
+	if p.cfg.Issuer == "" {
+		return &oidcEndpoints{
+			AuthorizationEndpoint: p.cfg.AuthURL,
+			TokenEndpoint:         p.cfg.TokenURL,
+			UserInfoEndpoint:      p.cfg.UserInfoURL,
+		}, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.endpoints != nil {
+		return p.endpoints, nil
+	}
+
+	var doc oidcEndpoints
+	if err := p.getJSON(ctx, strings.TrimRight(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", "", &doc); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	if doc.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("discovery: issuer %q не совпадает с настроенным %q", doc.Issuer, p.cfg.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("discovery: в документе нет обязательных адресов")
+	}
+	p.endpoints = &doc
+
+	return p.endpoints, nil
+}
+
+// authCodeURL формирует адрес страницы входа провайдера.
+func (p *oidcProvider) authCodeURL(ctx context.Context, state, nonce, verifier, redirectURL string) (string, error) {
+	endpoints, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(endpoints.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("authorization endpoint: %w", err)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", redirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("code_challenge", pkceChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	if p.cfg.Issuer != "" {
+		query.Set("nonce", nonce)
+	}
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// exchange обменивает код авторизации на токены, предъявляя PKCE verifier.
+func (p *oidcProvider) exchange(ctx context.Context, code, verifier, redirectURL string) (*oidcTokenResponse, error) {
+	endpoints, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("client_secret", p.cfg.ClientSecret)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoints.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokens oidcTokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcResponseLimit)).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("token: статус %d: %w", resp.StatusCode, err)
+	}
+	// GitHub сообщает об ошибке обмена со статусом 200
+	if resp.StatusCode != http.StatusOK || tokens.Error != "" {
+		return nil, fmt.Errorf("token: статус %d: %s %s", resp.StatusCode, tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.AccessToken == "" {
+		return nil, errors.New("token: в ответе нет access_token")
+	}
+
+	return &tokens, nil
+}
+
+// identity определяет пользователя по токенам: у OpenID Connect провайдеров — по проверенному
+// id_token, у остальных — по userinfo.
+func (p *oidcProvider) identity(ctx context.Context, tokens *oidcTokenResponse, nonce string) (*externalIdentity, error) {
+	endpoints, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var identity *externalIdentity
+	if p.cfg.Issuer != "" {
+		identity, err = p.verifyIDToken(ctx, endpoints, tokens.IDToken, nonce)
+		if err != nil {
+			return nil, err
+		}
+		if identity.Email == "" && endpoints.UserInfoEndpoint != "" {
+			info, err := p.userInfo(ctx, endpoints.UserInfoEndpoint, tokens.AccessToken)
+			if err != nil {
+				return nil, err
+			}
+			// Данные userinfo принимаются только для того же пользователя, что в id_token
+			if info.Subject == identity.Subject {
+				identity.Email, identity.EmailVerified = info.Email, info.EmailVerified
+				if identity.Name == "" {
+					identity.Name = info.Name
+				}
+			}
+		}
+	} else {
+		identity, err = p.userInfo(ctx, endpoints.UserInfoEndpoint, tokens.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		if p.cfg.EmailsURL != "" {
+			if err := p.primaryEmail(ctx, tokens.AccessToken, identity); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if identity.Subject == "" {
+		return nil, errors.New("провайдер не сообщил идентификатор пользователя")
+	}
+	identity.Email = strings.ToLower(strings.TrimSpace(identity.Email))
+	if p.cfg.TrustEmail && identity.Email != "" {
+		identity.EmailVerified = true
+	}
+
+	return identity, nil
+}
+
+// verifyIDToken проверяет подпись id_token ключом провайдера, издателя, получателя, срок и nonce.
+func (p *oidcProvider) verifyIDToken(ctx context.Context, endpoints *oidcEndpoints, raw, nonce string) (*externalIdentity, error) {
+	if raw == "" {
+		return nil, errors.New("id_token: отсутствует в ответе")
+	}
+
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, endpoints.JWKSURI, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(endpoints.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("id_token: %w", err)
+	}
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		return nil, errors.New("id_token: nonce не совпадает")
+	}
+
+	return &externalIdentity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
+
+// publicKey возвращает ключ подписи по kid, при необходимости перечитывая JWKS провайдера.
+func (p *oidcProvider) publicKey(ctx context.Context, jwksURI, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < oidcKeysRefreshInterval {
+		return nil, fmt.Errorf("неизвестный ключ подписи %q", kid)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURI, "", &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil || len(e) > 4 {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("неизвестный ключ подписи %q", kid)
+}
+
+// lookupKey ищет ключ в кэше; токен без kid подходит, только если ключ один.
+func (p *oidcProvider) lookupKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return p.keys[kid]
+}
+
+// userInfo запрашивает данные пользователя по access токену.
+func (p *oidcProvider) userInfo(ctx context.Context, endpoint, accessToken string) (*externalIdentity, error) {
+	var info userInfoResponse
+	if err := p.getJSON(ctx, endpoint, accessToken, &info); err != nil {
+		return nil, fmt.Errorf("userinfo: %w", err)
+	}
+
+	identity := &externalIdentity{
+		Subject:       info.Sub,
+		Email:         info.Email,
+		EmailVerified: bool(info.EmailVerified),
+		Name:          info.Name,
+	}
+	if identity.Subject == "" && len(info.ID) > 0 {
+		// GitHub отдаёт id числом, Yandex — строкой
+		identity.Subject = strings.Trim(string(info.ID), `"`)
+	}
+	if identity.Email == "" {
+		identity.Email = info.DefaultEmail
+	}
+	if identity.Name == "" {
+		identity.Name = info.RealName
+	}
+	if identity.Name == "" {
+		identity.Name = info.Login
+	}
+
+	return identity, nil
+}
+
+// primaryEmail берёт основной подтверждённый адрес из списка адресов пользователя.
+func (p *oidcProvider) primaryEmail(ctx context.Context, accessToken string, identity *externalIdentity) error {
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.getJSON(ctx, p.cfg.EmailsURL, accessToken, &emails); err != nil {
+		return fmt.Errorf("emails: %w", err)
+	}
+
+	identity.EmailVerified = false
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email, identity.EmailVerified = email.Email, email.Verified
+			break
+		}
+	}
+
+	return nil
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, endpoint, accessToken string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	// GitHub API отклоняет запросы без User-Agent
+	req.Header.Set("User-Agent", "freelance-backend")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("статус %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcResponseLimit)).Decode(dst)
+}
+
+// pkceChallenge вычисляет code_challenge метода S256 (RFC 7636).
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/logger"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+// oidcLoginTTL — сколько ждём возврата пользователя со страницы провайдера.
+const oidcLoginTTL = 10 * time.Minute
+
+var (
+	// ErrOIDCProviderUnknown возвращается для провайдера, который не настроен.
+	ErrOIDCProviderUnknown = errors.New("auth service: вход через этого провайдера не настроен")
+	// ErrOIDCStateInvalid возвращается для неизвестного, истёкшего или уже использованного state.
+	ErrOIDCStateInvalid = errors.New("auth service: время на вход истекло, начните вход заново")
+	// ErrOIDCLoginFailed возвращается, если провайдер не подтвердил вход.
+	ErrOIDCLoginFailed = errors.New("auth service: не удалось подтвердить вход через провайдера")
+	// ErrOIDCEmailUnverified возвращается, если провайдер не сообщил подтверждённый email.
+	ErrOIDCEmailUnverified = errors.New("auth service: провайдер не подтвердил email, войдите по паролю")
+	// ErrOIDCAccountUnverified возвращается, если email принадлежит аккаунту, который его не подтвердил:
+	// такой аккаунт мог зарегистрировать кто угодно, поэтому привязывать к нему вход нельзя.
+	ErrOIDCAccountUnverified = errors.New("auth service: аккаунт с этим email не подтверждён, войдите по паролю или восстановите доступ")
+)
+
+// OIDCRepository описывает зависимости OIDCService от слоя хранилища.
+type OIDCRepository interface {
+	CreateLoginState(ctx context.Context, state *models.OIDCLoginState) error
+	ConsumeLoginState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error)
+	GetIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	LinkIdentity(ctx context.Context, identity *models.UserIdentity) error
+	TouchIdentity(ctx context.Context, id uuid.UUID, email *string) error
+	ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error)
+}
+
+// OIDCUserRepository описывает доступ OIDCService к пользователям.
+type OIDCUserRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	IsEmailVerified(ctx context.Context, userID uuid.UUID) (bool, error)
+	MarkEmailVerified(ctx context.Context, userID uuid.UUID) error
+}
+
+// OIDCLoginStart — адрес страницы входа провайдера и state, который фронтенд сверит при возврате.
+type OIDCLoginStart struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+// OIDCService реализует вход через внешних провайдеров по authorization code flow с PKCE.
+// Учётная запись провайдера привязывается к пользователю с тем же подтверждённым email,
+// а при его отсутствии создаётся новый пользователь.
+type OIDCService struct {
+	repo        OIDCRepository
+	users       OIDCUserRepository
+	auth        *AuthService
+	providers   map[string]*oidcProvider
+	redirectURL string
+}
+
+// NewOIDCService создаёт сервис; redirectURL — страница фронтенда, на которую провайдер вернёт пользователя.
+func NewOIDCService(repo OIDCRepository, users OIDCUserRepository, auth *AuthService, providers []OIDCProviderConfig, redirectURL string) *OIDCService {
+	client := &http.Client{Timeout: 10 * time.Second}
+	registry := make(map[string]*oidcProvider, len(providers))
+	for _, cfg := range providers {
+		registry[cfg.Name] = newOIDCProvider(cfg, client)
+	}
+
+	return &OIDCService{
+		repo:        repo,
+		users:       users,
+		auth:        auth,
+		providers:   registry,
+		redirectURL: redirectURL,
+	}
+}
+
+// Providers возвращает имена настроенных провайдеров.
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start начинает вход: запоминает state, nonce и PKCE verifier и возвращает адрес страницы провайдера.
+func (s *OIDCService) Start(ctx context.Context, providerName string) (*OIDCLoginStart, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrOIDCProviderUnknown
+	}
+
+	state, err := randomURLToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomURLToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomURLToken()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := provider.authCodeURL(ctx, state, nonce, verifier, s.redirectURL)
+	if err != nil {
+		s.logProviderError(providerName, err)
+		return nil, ErrOIDCLoginFailed
+	}
+
+	if err := s.repo.CreateLoginState(ctx, &models.OIDCLoginState{
+		StateHash:    HashRefreshToken(state),
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(oidcLoginTTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &OIDCLoginStart{AuthorizationURL: authURL, State: state}, nil
+}
+
+// Callback завершает вход по коду, с которым провайдер вернул пользователя. Результат такой же,
+// как у Login: при подключённой двухфакторной аутентификации — только TwoFactorChallenge.
+func (s *OIDCService) Callback(ctx context.Context, state, code string, meta map[string]string) (*AuthResult, error) {
+	login, err := s.repo.ConsumeLoginState(ctx, HashRefreshToken(state))
+	if errors.Is(err, repository.ErrLoginStateNotFound) {
+		return nil, ErrOIDCStateInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	provider, ok := s.providers[login.Provider]
+	if !ok {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	tokens, err := provider.exchange(ctx, code, login.CodeVerifier, s.redirectURL)
+	if err != nil {
+		s.logProviderError(login.Provider, err)
+		return nil, ErrOIDCLoginFailed
+	}
+	identity, err := provider.identity(ctx, tokens, login.Nonce)
+	if err != nil {
+		s.logProviderError(login.Provider, err)
+		return nil, ErrOIDCLoginFailed
+	}
+
+	user, err := s.resolveUser(ctx, login.Provider, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.auth.LoginExternal(ctx, user, meta)
+}
+
+// ListIdentities возвращает привязанные к пользователю учётные записи провайдеров.
+func (s *OIDCService) ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	return s.repo.ListIdentities(ctx, userID)
+}
+
+// resolveUser находит пользователя по привязанной учётной записи провайдера, а при первом входе
+// привязывает её к пользователю с тем же email или создаёт нового.
+func (s *OIDCService) resolveUser(ctx context.Context, providerName string, identity *externalIdentity) (*models.User, error) {
+	var email *string
+	if identity.Email != "" && identity.EmailVerified {
+		email = &identity.Email
+	}
+
+	linked, err := s.repo.GetIdentity(ctx, providerName, identity.Subject)
+	if err == nil {
+		if err := s.repo.TouchIdentity(ctx, linked.ID, email); err != nil && logger.Log != nil {
+			logger.Log.WithFields(map[string]interface{}{
+				"identity_id": linked.ID,
+				"error":       err.Error(),
+			}).Warn("auth service: не удалось обновить время входа через провайдера")
+		}
+		return s.users.GetByID(ctx, linked.UserID)
+	}
+	if !errors.Is(err, repository.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	if email == nil {
+		return nil, ErrOIDCEmailUnverified
+	}
+
+	user, err := s.users.GetByEmail(ctx, *email)
+	switch {
+	case err == nil:
+		verified, err := s.users.IsEmailVerified(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !verified {
+			return nil, ErrOIDCAccountUnverified
+		}
+	case errors.Is(err, repository.ErrUserNotFound):
+		user, err = s.auth.RegisterExternal(ctx, *email, identity.Name)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.users.MarkEmailVerified(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if err := s.repo.LinkIdentity(ctx, &models.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  identity.Subject,
+		Email:    email,
+	}); err != nil {
+		if !errors.Is(err, repository.ErrIdentityLinked) {
+			return nil, err
+		}
+		// Параллельный вход уже привязал эту учётную запись
+		linked, err := s.repo.GetIdentity(ctx, providerName, identity.Subject)
+		if err != nil {
+			return nil, err
+		}
+		return s.users.GetByID(ctx, linked.UserID)
+	}
+
+	return user, nil
+}
+
+func (s *OIDCService) logProviderError(providerName string, err error) {
+	if logger.Log == nil {
+		return
+	}
+	logger.Log.WithFields(map[string]interface{}{
+		"provider": providerName,
+		"error":    err.Error(),
+	}).Warn("auth service: ошибка входа через внешнего провайдера")
+}
+
+// randomURLToken возвращает 256 случайных бит в base64url без выравнивания.
+func randomURLToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("auth service: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,361 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+type mockOIDCRepository struct {
+	states     map[string]*models.OIDCLoginState
+	identities map[string]*models.UserIdentity
+}
+
+func newMockOIDCRepository() *mockOIDCRepository {
+	return &mockOIDCRepository{
+		states:     make(map[string]*models.OIDCLoginState),
+		identities: make(map[string]*models.UserIdentity),
+	}
+}
+
+func (m *mockOIDCRepository) CreateLoginState(ctx context.Context, state *models.OIDCLoginState) error {
+	m.states[state.StateHash] = state
+	return nil
+}
+
+func (m *mockOIDCRepository) ConsumeLoginState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	state, ok := m.states[stateHash]
+	if !ok || time.Now().After(state.ExpiresAt) {
+		return nil, repository.ErrLoginStateNotFound
+	}
+	delete(m.states, stateHash)
+	return state, nil
+}
+
+func (m *mockOIDCRepository) GetIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	identity, ok := m.identities[provider+"/"+subject]
+	if !ok {
+		return nil, repository.ErrIdentityNotFound
+	}
+	return identity, nil
+}
+
+func (m *mockOIDCRepository) LinkIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	key := identity.Provider + "/" + identity.Subject
+	if _, ok := m.identities[key]; ok {
+		return repository.ErrIdentityLinked
+	}
+	identity.ID = uuid.New()
+	m.identities[key] = identity
+	return nil
+}
+
+func (m *mockOIDCRepository) TouchIdentity(ctx context.Context, id uuid.UUID, email *string) error {
+	return nil
+}
+
+func (m *mockOIDCRepository) ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	for _, identity := range m.identities {
+		if identity.UserID == userID {
+			identities = append(identities, *identity)
+		}
+	}
+	return identities, nil
+}
+
+// mockOIDCUsers дополняет mockAuthRepository признаком подтверждённого email.
+type mockOIDCUsers struct {
+	*mockAuthRepository
+	verified map[uuid.UUID]bool
+}
+
+func (m *mockOIDCUsers) IsEmailVerified(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return m.verified[userID], nil
+}
+
+func (m *mockOIDCUsers) MarkEmailVerified(ctx context.Context, userID uuid.UUID) error {
+	m.verified[userID] = true
+	return nil
+}
+
+// stubAccount — пользователь, за которого заглушка провайдера выдаёт код.
+type stubAccount struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// Nonce подменяет nonce из запроса авторизации
+	Nonce string
+}
+
+type stubGrant struct {
+	account   stubAccount
+	challenge string
+	nonce     string
+}
+
+// stubOIDCServer — локальный OpenID Connect провайдер: discovery, JWKS и token endpoint,
+// проверяющий PKCE. Страницу входа заменяет authorize.
+type stubOIDCServer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]stubGrant
+}
+
+func newStubOIDCServer(t *testing.T) *stubOIDCServer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	stub := &stubOIDCServer{key: key, grants: make(map[string]stubGrant)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 stub.URL,
+			"authorization_endpoint": stub.URL + "/authorize",
+			"token_endpoint":         stub.URL + "/token",
+			"jwks_uri":               stub.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "stub-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", stub.token)
+	stub.Server = httptest.NewServer(mux)
+	t.Cleanup(stub.Close)
+
+	return stub
+}
+
+// authorize имитирует вход пользователя на странице провайдера и возвращает код авторизации.
+func (s *stubOIDCServer) authorize(t *testing.T, authorizationURL string, account stubAccount) string {
+	t.Helper()
+	parsed, err := url.Parse(authorizationURL)
+	assert.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, "stub-client", query.Get("client_id"))
+
+	code := uuid.NewString()
+	s.mu.Lock()
+	s.grants[code] = stubGrant{account: account, challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	s.mu.Unlock()
+	return code
+}
+
+func (s *stubOIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	grant, ok := s.grants[r.FormValue("code")]
+	delete(s.grants, r.FormValue("code"))
+	s.mu.Unlock()
+
+	if !ok || r.FormValue("client_id") != "stub-client" || r.FormValue("client_secret") != "stub-secret" ||
+		pkceChallenge(r.FormValue("code_verifier")) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	nonce := grant.nonce
+	if grant.account.Nonce != "" {
+		nonce = grant.account.Nonce
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            s.URL,
+		"aud":            "stub-client",
+		"sub":            grant.account.Subject,
+		"email":          grant.account.Email,
+		"email_verified": grant.account.EmailVerified,
+		"nonce":          nonce,
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	})
+	idToken.Header["kid"] = "stub-key"
+	signed, _ := idToken.SignedString(s.key)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "stub-access-token",
+		"token_type":   "Bearer",
+		"id_token":     signed,
+	})
+}
+
+type oidcFixture struct {
+	stub  *stubOIDCServer
+	oidc  *OIDCService
+	auth  *AuthService
+	users *mockOIDCUsers
+	repo  *mockOIDCRepository
+}
+
+func setupOIDC(t *testing.T) *oidcFixture {
+	t.Helper()
+	stub := newStubOIDCServer(t)
+	users := &mockOIDCUsers{mockAuthRepository: newMockAuthRepository(), verified: make(map[uuid.UUID]bool)}
+	auth := NewAuthService(users, NewTokenManager("access-secret", "refresh-secret", time.Minute, time.Hour))
+	repo := newMockOIDCRepository()
+	oidc := NewOIDCService(repo, users, auth, []OIDCProviderConfig{{
+		Name:         "stub",
+		ClientID:     "stub-client",
+		ClientSecret: "stub-secret",
+		Issuer:       stub.URL,
+		Scopes:       []string{"openid", "email"},
+	}}, "http://localhost:3000/auth/oidc/callback")
+
+	return &oidcFixture{stub: stub, oidc: oidc, auth: auth, users: users, repo: repo}
+}
+
+// login проходит вход целиком: начало, страница провайдера и возврат с кодом.
+func (f *oidcFixture) login(t *testing.T, account stubAccount) (*AuthResult, error) {
+	t.Helper()
+	start, err := f.oidc.Start(context.Background(), "stub")
+	assert.NoError(t, err)
+	code := f.stub.authorize(t, start.AuthorizationURL, account)
+	return f.oidc.Callback(context.Background(), start.State, code, nil)
+}
+
+func TestOIDC_FirstLoginCreatesUser(t *testing.T) {
+	f := setupOIDC(t)
+
+	res, err := f.login(t, stubAccount{Subject: "1001", Email: "New.User@example.com", EmailVerified: true})
+	assert.NoError(t, err)
+	assert.NotNil(t, res.TokenPair)
+	assert.Equal(t, "new.user@example.com", res.User.Email)
+	assert.Equal(t, models.RoleFreelancer, res.User.Role)
+	assert.True(t, f.users.verified[res.User.ID])
+	assert.Equal(t, "new_user", f.users.profiles[res.User.ID].DisplayName)
+
+	// Повторный вход находит пользователя по subject, даже если email у провайдера сменился
+	again, err := f.login(t, stubAccount{Subject: "1001", Email: "other@example.com", EmailVerified: true})
+	assert.NoError(t, err)
+	assert.Equal(t, res.User.ID, again.User.ID)
+	assert.Len(t, f.users.usersByID, 1)
+}
+
+func TestOIDC_LinksExistingVerifiedAccount(t *testing.T) {
+	f := setupOIDC(t)
+	ctx := context.Background()
+
+	registered, err := f.auth.Register(ctx, RegisterInput{Email: "client@example.com", Password: "password123", Role: models.RoleClient}, nil)
+	assert.NoError(t, err)
+	f.users.verified[registered.User.ID] = true
+
+	res, err := f.login(t, stubAccount{Subject: "2002", Email: "client@example.com", EmailVerified: true})
+	assert.NoError(t, err)
+	assert.Equal(t, registered.User.ID, res.User.ID)
+
+	identities, err := f.oidc.ListIdentities(ctx, registered.User.ID)
+	assert.NoError(t, err)
+	assert.Len(t, identities, 1)
+	assert.Equal(t, "stub", identities[0].Provider)
+}
+
+func TestOIDC_DoesNotLinkUnverifiedEmails(t *testing.T) {
+	f := setupOIDC(t)
+
+	_, err := f.auth.Register(context.Background(), RegisterInput{Email: "victim@example.com", Password: "password123"}, nil)
+	assert.NoError(t, err)
+
+	// Чужой аккаунт с неподтверждённым email не захватывается входом через провайдера
+	_, err = f.login(t, stubAccount{Subject: "3003", Email: "victim@example.com", EmailVerified: true})
+	assert.ErrorIs(t, err, ErrOIDCAccountUnverified)
+
+	// Неподтверждённый у провайдера email не годится ни для привязки, ни для регистрации
+	_, err = f.login(t, stubAccount{Subject: "3004", Email: "fresh@example.com", EmailVerified: false})
+	assert.ErrorIs(t, err, ErrOIDCEmailUnverified)
+	assert.Empty(t, f.repo.identities)
+}
+
+func TestOIDC_StateIsSingleUse(t *testing.T) {
+	f := setupOIDC(t)
+	ctx := context.Background()
+
+	start, err := f.oidc.Start(ctx, "stub")
+	assert.NoError(t, err)
+	code := f.stub.authorize(t, start.AuthorizationURL, stubAccount{Subject: "4004", Email: "state@example.com", EmailVerified: true})
+
+	_, err = f.oidc.Callback(ctx, "forged-state", code, nil)
+	assert.ErrorIs(t, err, ErrOIDCStateInvalid)
+
+	_, err = f.oidc.Callback(ctx, start.State, code, nil)
+	assert.NoError(t, err)
+	_, err = f.oidc.Callback(ctx, start.State, code, nil)
+	assert.ErrorIs(t, err, ErrOIDCStateInvalid)
+
+	_, err = f.oidc.Start(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrOIDCProviderUnknown)
+}
+
+func TestOIDC_RejectsReplayedIDToken(t *testing.T) {
+	f := setupOIDC(t)
+
+	_, err := f.login(t, stubAccount{Subject: "5005", Email: "nonce@example.com", EmailVerified: true, Nonce: "nonce-from-another-login"})
+	assert.ErrorIs(t, err, ErrOIDCLoginFailed)
+	assert.Empty(t, f.users.usersByID)
+}
+
+func TestOIDC_UserInfoProviderUsesPrimaryVerifiedEmail(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "gh-token", "token_type": "bearer"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer gh-token", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"id": 6006, "login": "octocat", "email": null}`))
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"email":"old@example.com","primary":false,"verified":true},{"email":"octo@example.com","primary":true,"verified":true}]`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	users := &mockOIDCUsers{mockAuthRepository: newMockAuthRepository(), verified: make(map[uuid.UUID]bool)}
+	auth := NewAuthService(users, NewTokenManager("access-secret", "refresh-secret", time.Minute, time.Hour))
+	repo := newMockOIDCRepository()
+	oidc := NewOIDCService(repo, users, auth, []OIDCProviderConfig{{
+		Name:         "github",
+		ClientID:     "gh-client",
+		ClientSecret: "gh-secret",
+		AuthURL:      server.URL + "/authorize",
+		TokenURL:     server.URL + "/token",
+		UserInfoURL:  server.URL + "/user",
+		EmailsURL:    server.URL + "/user/emails",
+	}}, "http://localhost:3000/auth/oidc/callback")
+
+	ctx := context.Background()
+	start, err := oidc.Start(ctx, "github")
+	assert.NoError(t, err)
+	assert.NotContains(t, start.AuthorizationURL, "nonce=")
+
+	res, err := oidc.Callback(ctx, start.State, "gh-code", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "octo@example.com", res.User.Email)
+	assert.Equal(t, "octocat", users.profiles[res.User.ID].DisplayName)
+	_, linked := repo.identities["github/6006"]
+	assert.True(t, linked)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/logger"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/repository"
@@ -30,6 +31,7 @@ type OrderRepository interface {
 	UpdateProposalStatus(ctx context.Context, id uuid.UUID, status string) (*models.Proposal, error)
 	CreateProposal(ctx context.Context, proposal *models.Proposal) error
 	ListProposals(ctx context.Context, orderID uuid.UUID) ([]models.Proposal, error)
+	CountProposals(ctx context.Context, orderID uuid.UUID) (int, error)
 	GetMyProposalForOrder(ctx context.Context, orderID, freelancerID uuid.UUID) (*models.Proposal, error)
 	ListMyProposals(ctx context.Context, freelancerID uuid.UUID) ([]models.Proposal, error)
 	CreateConversation(ctx context.Context, conv *models.Conversation) error
@@ -37,6 +39,7 @@ type OrderRepository interface {
 	GetConversationByParticipants(ctx context.Context, orderID uuid.UUID, clientID, freelancerID uuid.UUID) (*models.Conversation, error)
 	GetConversationByID(ctx context.Context, id uuid.UUID) (*models.Conversation, error)
 	ListMyConversations(ctx context.Context, userID uuid.UUID) ([]models.Conversation, error)
+	CountUnreadMessages(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]int, error)
 	GetLastMessageForConversation(ctx context.Context, conversationID uuid.UUID) (*models.Message, error)
 	ListMessages(ctx context.Context, conversationID uuid.UUID, limit, offset int) ([]models.Message, error)
 	GetMessageByID(ctx context.Context, messageID uuid.UUID) (*models.Message, error)
@@ -112,6 +115,10 @@ type AIHelper interface {
 	StreamGenerateWelcomeMessage(ctx context.Context, userRole string, onDelta func(chunk string) error) error
 }
 
+// ErrOrderMilestonesOpen возвращается при завершении заказа, у которого остались оплаченные,
+// но не принятые этапы.
+var ErrOrderMilestonesOpen = errors.New("order service: сначала примите или верните оплаченные этапы заказа")
+
 // WSNotifier интерфейс для отправки WebSocket уведомлений.
 type WSNotifier interface {
 	BroadcastToUser(userID uuid.UUID, event string, data interface{}) error
@@ -119,22 +126,31 @@ type WSNotifier interface {
 
 // PaymentRepositoryForOrders описывает контракт для работы с платежами в заказах.
 type PaymentRepositoryForOrders interface {
-	GetBalance(ctx context.Context, userID uuid.UUID) (*models.UserBalance, error)
-	CreateEscrow(ctx context.Context, orderID, clientID, freelancerID uuid.UUID, amount float64) (*models.Escrow, error)
+	GetBalance(ctx context.Context, userID uuid.UUID, currency string) (*models.UserBalance, error)
+	CreateEscrow(ctx context.Context, orderID, clientID, freelancerID uuid.UUID, amount valueobject.Amount, currency string) (*models.Escrow, error)
 	ReleaseEscrow(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error)
 	RefundEscrow(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error)
 	GetEscrowByOrderID(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error)
+	RefundMilestone(ctx context.Context, milestoneID uuid.UUID) (*models.Escrow, error)
+}
+
+// OrderMilestones даёт доступ к этапам заказа: разбит ли он на этапы и в каком они состоянии.
+type OrderMilestones interface {
+	CountByOrder(ctx context.Context, orderID uuid.UUID) (int, error)
+	ListByOrder(ctx context.Context, orderID uuid.UUID) ([]models.Milestone, error)
 }
 
 // OrderService содержит бизнес-логику работы с заказами.
 type OrderService struct {
-	repo      OrderRepository
-	profile   ProfileRepository
-	portfolio PortfolioRepositoryForAI
-	users     UserRepositoryForAI
-	ai        AIHelper
-	hub       WSNotifier
-	payment   PaymentRepositoryForOrders
+	repo       OrderRepository
+	profile    ProfileRepository
+	portfolio  PortfolioRepositoryForAI
+	users      UserRepositoryForAI
+	ai         AIHelper
+	hub        WSNotifier
+	payment    PaymentRepositoryForOrders
+	milestones OrderMilestones
+	rates      ExchangeRateProvider
 }
 
 // NewOrderService создаёт новый сервис заказов.
@@ -158,13 +174,36 @@ func (s *OrderService) SetHub(hub WSNotifier) {
 	s.hub = hub
 }
 
+// SetMilestoneRepository устанавливает репозиторий этапов заказа.
+func (s *OrderService) SetMilestoneRepository(milestones OrderMilestones) {
+	s.milestones = milestones
+}
+
+// SetExchangeRates устанавливает провайдер курсов для пересчёта откликов в валюту заказа.
+func (s *OrderService) SetExchangeRates(rates ExchangeRateProvider) {
+	s.rates = rates
+}
+
+// hasMilestones сообщает, оплачивается ли заказ поэтапно.
+func (s *OrderService) hasMilestones(ctx context.Context, orderID uuid.UUID) (bool, error) {
+	if s.milestones == nil {
+		return false, nil
+	}
+	count, err := s.milestones.CountByOrder(ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 // CreateOrderInput описывает входные данные.
 type CreateOrderInput struct {
 	ClientID      uuid.UUID
 	Title         string
 	Description   string
-	BudgetMin     *float64
-	BudgetMax     *float64
+	BudgetMin     *valueobject.Amount
+	BudgetMax     *valueobject.Amount
+	Currency      string
 	DeadlineAt    *time.Time
 	Requirements  []models.OrderRequirement
 	AttachmentIDs []uuid.UUID
@@ -176,8 +215,9 @@ type UpdateOrderInput struct {
 	ClientID      uuid.UUID
 	Title         string
 	Description   string
-	BudgetMin     *float64
-	BudgetMax     *float64
+	BudgetMin     *valueobject.Amount
+	BudgetMax     *valueobject.Amount
+	Currency      string // пустая строка оставляет валюту без изменений
 	Status        string
 	DeadlineAt    *time.Time
 	Requirements  []models.OrderRequirement
@@ -189,7 +229,8 @@ type ProposalInput struct {
 	OrderID      uuid.UUID
 	FreelancerID uuid.UUID
 	CoverLetter  string
-	Amount       *float64
+	Amount       *valueobject.Amount
+	Currency     string // по умолчанию — валюта заказа
 }
 
 // CreateOrder создаёт заказ и возвращает его.
@@ -207,6 +248,10 @@ func (s *OrderService) CreateOrder(ctx context.Context, in CreateOrderInput) (*m
 	if in.DeadlineAt != nil && in.DeadlineAt.Before(time.Now()) {
 		return nil, fmt.Errorf("order service: дедлайн не может быть в прошлом")
 	}
+	currency, ok := models.NormalizeCurrency(in.Currency)
+	if !ok {
+		return nil, fmt.Errorf("order service: некорректная валюта заказа")
+	}
 
 	order := &models.Order{
 		ClientID:    in.ClientID,
@@ -215,6 +260,7 @@ func (s *OrderService) CreateOrder(ctx context.Context, in CreateOrderInput) (*m
 		Status:      models.OrderStatusPublished,
 		BudgetMin:   in.BudgetMin,
 		BudgetMax:   in.BudgetMax,
+		Currency:    currency,
 		DeadlineAt:  in.DeadlineAt,
 	}
 
@@ -286,6 +332,18 @@ func (s *OrderService) UpdateOrder(ctx context.Context, in UpdateOrderInput) (*m
 		return nil, fmt.Errorf("order service: описание заказа не может быть пустым")
 	}
 
+	// Валюта меняется только до выбора исполнителя: escrow уже создан в прежней валюте
+	if in.Currency != "" {
+		currency, ok := models.NormalizeCurrency(in.Currency)
+		if !ok {
+			return nil, fmt.Errorf("order service: некорректная валюта заказа")
+		}
+		if currency != existing.Currency && existing.FreelancerID != nil {
+			return nil, fmt.Errorf("order service: нельзя изменить валюту заказа после выбора исполнителя")
+		}
+		existing.Currency = currency
+	}
+
 	needsResummary := existing.Title != in.Title || existing.Description != in.Description
 
 	existing.Title = in.Title
@@ -297,6 +355,15 @@ func (s *OrderService) UpdateOrder(ctx context.Context, in UpdateOrderInput) (*m
 	}
 	existing.DeadlineAt = in.DeadlineAt
 
+	// Этапы закрываются вместе с заказом: завершить заказ можно, только когда оплаченные этапы
+	// приняты или возвращены, а при отмене оплаченные и не сданные этапы возвращаются заказчику
+	if in.Status != "" && in.Status != existing.Status && s.milestones != nil &&
+		(in.Status == models.OrderStatusCompleted || in.Status == models.OrderStatusCancelled) {
+		if err := s.settleMilestones(ctx, existing.ID, in.Status); err != nil {
+			return nil, err
+		}
+	}
+
 	// Обработка escrow при изменении статуса
 	if in.Status != "" && s.payment != nil {
 		if in.Status == models.OrderStatusCompleted {
@@ -337,6 +404,29 @@ func (s *OrderService) UpdateOrder(ctx context.Context, in UpdateOrderInput) (*m
 	return existing, nil
 }
 
+// settleMilestones проверяет этапы перед завершением заказа или возвращает их средства при отмене.
+func (s *OrderService) settleMilestones(ctx context.Context, orderID uuid.UUID, status string) error {
+	milestones, err := s.milestones.ListByOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	for _, m := range milestones {
+		switch {
+		case status == models.OrderStatusCompleted &&
+			(m.Status == models.MilestoneStatusFunded || m.Status == models.MilestoneStatusSubmitted || m.Status == models.MilestoneStatusDisputed):
+			return ErrOrderMilestonesOpen
+		case status == models.OrderStatusCancelled && m.Status == models.MilestoneStatusFunded:
+			if s.payment == nil {
+				return fmt.Errorf("order service: платёжная система недоступна")
+			}
+			if _, err := s.payment.RefundMilestone(ctx, m.ID); err != nil {
+				return fmt.Errorf("order service: не удалось вернуть средства этапа «%s»: %w", m.Title, err)
+			}
+		}
+	}
+	return nil
+}
+
 // CreateProposal создаёт отклик и может сформировать чат.
 func (s *OrderService) CreateProposal(ctx context.Context, in ProposalInput) (*models.Proposal, error) {
 	// Валидация входных данных
@@ -369,11 +459,20 @@ func (s *OrderService) CreateProposal(ctx context.Context, in ProposalInput) (*m
 		}
 	}
 
+	currency := order.Currency
+	if in.Currency != "" {
+		var ok bool
+		if currency, ok = models.NormalizeCurrency(in.Currency); !ok {
+			return nil, fmt.Errorf("order service: некорректная валюта предложения")
+		}
+	}
+
 	proposal := &models.Proposal{
 		OrderID:        in.OrderID,
 		FreelancerID:   in.FreelancerID,
 		CoverLetter:    in.CoverLetter,
 		ProposedAmount: in.Amount,
+		Currency:       currency,
 		Status:         models.ProposalStatusPending,
 	}
 
@@ -428,36 +527,67 @@ func (s *OrderService) ListProposals(ctx context.Context, orderID uuid.UUID, cli
 	result := &ListProposalsResult{
 		Proposals: proposals,
 	}
+	if len(proposals) == 0 {
+		return result, nil
+	}
+
+	order, err := s.repo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	s.convertProposalAmounts(ctx, order, proposals)
 
 	// Если вызывается заказчиком и есть AI сервис
-	if clientID != nil && s.ai != nil && s.profile != nil && len(proposals) > 0 {
-		order, err := s.repo.GetByID(ctx, orderID)
-		if err == nil && order != nil && order.ClientID == *clientID {
-			// Используем кэшированные данные если они есть
-			if order.BestRecommendationProposalID != nil && order.BestRecommendationJustification != nil {
-				result.BestRecommendation = &BestRecommendation{
-					ProposalID:    order.BestRecommendationProposalID,
-					Justification: *order.BestRecommendationJustification,
-				}
+	if clientID != nil && s.ai != nil && s.profile != nil && order.ClientID == *clientID {
+		// Используем кэшированные данные если они есть
+		if order.BestRecommendationProposalID != nil && order.BestRecommendationJustification != nil {
+			result.BestRecommendation = &BestRecommendation{
+				ProposalID:    order.BestRecommendationProposalID,
+				Justification: *order.BestRecommendationJustification,
 			}
+		}
 
-			// Проверяем, нужно ли регенерировать анализ
-			needsRegeneration := s.needsAIRegeneration(ctx, orderID, order)
+		// Проверяем, нужно ли регенерировать анализ
+		needsRegeneration := s.needsAIRegeneration(ctx, orderID, order)
 
-			if needsRegeneration {
-				// Запускаем асинхронную генерацию в фоне
-				go func() {
-					bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-					defer cancel()
-					s.generateAIAnalysisAsync(bgCtx, orderID, *clientID, order, proposals)
-				}()
-			}
+		if needsRegeneration {
+			// Запускаем асинхронную генерацию в фоне
+			go func() {
+				bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+				defer cancel()
+				s.generateAIAnalysisAsync(bgCtx, orderID, *clientID, order, proposals)
+			}()
 		}
 	}
 
 	return result, nil
 }
 
+// convertProposalAmounts пересчитывает суммы откликов в другой валюте в валюту заказа.
+// Если курс недоступен, отклик отдаётся без пересчёта.
+func (s *OrderService) convertProposalAmounts(ctx context.Context, order *models.Order, proposals []models.Proposal) {
+	if s.rates == nil {
+		return
+	}
+	for i := range proposals {
+		p := &proposals[i]
+		if p.ProposedAmount == nil || p.Currency == order.Currency {
+			continue
+		}
+		converted, err := convertAmount(ctx, s.rates, *p.ProposedAmount, p.Currency, order.Currency)
+		if err != nil {
+			if logger.Log != nil {
+				logger.Log.WithFields(map[string]interface{}{
+					"proposal_id": p.ID,
+					"error":       err.Error(),
+				}).Warn("order service: не удалось пересчитать сумму отклика")
+			}
+			continue
+		}
+		p.ConvertedAmount = converted
+	}
+}
+
 // needsAIRegeneration проверяет, нужно ли регенерировать AI анализ.
 func (s *OrderService) needsAIRegeneration(ctx context.Context, orderID uuid.UUID, order *models.Order) bool {
 	// Если анализа еще не было, нужно сгенерировать
@@ -638,6 +768,11 @@ func (s *OrderService) GetOrder(ctx context.Context, id uuid.UUID) (*models.Orde
 	return s.repo.GetByID(ctx, id)
 }
 
+// CountProposals возвращает число откликов на заказ.
+func (s *OrderService) CountProposals(ctx context.Context, orderID uuid.UUID) (int, error) {
+	return s.repo.CountProposals(ctx, orderID)
+}
+
 // GetOrderWithDetails возвращает заказ со всеми связанными данными (требования и вложения).
 func (s *OrderService) GetOrderWithDetails(ctx context.Context, id uuid.UUID) (*models.Order, []models.OrderRequirement, []models.OrderAttachment, error) {
 	return s.repo.GetByIDWithDetails(ctx, id)
@@ -684,16 +819,27 @@ func (s *OrderService) UpdateProposalStatus(ctx context.Context, actorID uuid.UU
 		return nil, nil, fmt.Errorf("order service: нельзя изменить статус предложения для завершённого или отменённого заказа")
 	}
 
-	// При принятии предложения проверяем баланс и создаём escrow
+	// Заказ с этапами оплачивается поэтапно, общий escrow для него не создаётся
+	milestoneBased := false
 	if status == models.ProposalStatusAccepted {
+		milestoneBased, err = s.hasMilestones(ctx, order.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// При принятии предложения проверяем баланс и создаём escrow
+	if status == models.ProposalStatusAccepted && !milestoneBased {
 		if s.payment == nil {
 			return nil, nil, fmt.Errorf("order service: платёжная система недоступна")
 		}
 
-		// Определяем сумму для резервирования
-		var escrowAmount float64
+		// Определяем сумму и валюту для резервирования: сумма отклика в его валюте либо бюджет заказа
+		var escrowAmount valueobject.Amount
+		escrowCurrency := order.Currency
 		if proposal.ProposedAmount != nil && *proposal.ProposedAmount > 0 {
 			escrowAmount = *proposal.ProposedAmount
+			escrowCurrency = proposal.Currency
 		} else if order.BudgetMax != nil && *order.BudgetMax > 0 {
 			escrowAmount = *order.BudgetMax
 		} else if order.BudgetMin != nil && *order.BudgetMin > 0 {
@@ -702,17 +848,17 @@ func (s *OrderService) UpdateProposalStatus(ctx context.Context, actorID uuid.UU
 			return nil, nil, fmt.Errorf("order service: не указана сумма заказа")
 		}
 
-		// Проверяем баланс заказчика
-		balance, err := s.payment.GetBalance(ctx, order.ClientID)
+		// Проверяем баланс заказчика в валюте escrow
+		balance, err := s.payment.GetBalance(ctx, order.ClientID, escrowCurrency)
 		if err != nil {
 			return nil, nil, fmt.Errorf("order service: не удалось получить баланс: %w", err)
 		}
 		if balance.Available < escrowAmount {
-			return nil, nil, fmt.Errorf("order service: недостаточно средств на балансе (доступно: %.2f, требуется: %.2f)", balance.Available, escrowAmount)
+			return nil, nil, fmt.Errorf("order service: недостаточно средств на балансе (доступно: %s %s, требуется: %s %s)", balance.Available, escrowCurrency, escrowAmount, escrowCurrency)
 		}
 
 		// Создаём escrow (резервируем средства)
-		_, err = s.payment.CreateEscrow(ctx, order.ID, order.ClientID, proposal.FreelancerID, escrowAmount)
+		_, err = s.payment.CreateEscrow(ctx, order.ID, order.ClientID, proposal.FreelancerID, escrowAmount, escrowCurrency)
 		if err != nil {
 			return nil, nil, fmt.Errorf("order service: не удалось зарезервировать средства: %w", err)
 		}
@@ -832,6 +978,11 @@ func (s *OrderService) ListMyConversations(ctx context.Context, userID uuid.UUID
 	return s.repo.ListMyConversations(ctx, userID)
 }
 
+// CountUnreadMessages возвращает число непрочитанных сообщений по чатам пользователя.
+func (s *OrderService) CountUnreadMessages(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]int, error) {
+	return s.repo.CountUnreadMessages(ctx, userID)
+}
+
 // GetLastMessageForConversation возвращает последнее сообщение в чате.
 func (s *OrderService) GetLastMessageForConversation(ctx context.Context, conversationID uuid.UUID) (*models.Message, error) {
 	return s.repo.GetLastMessageForConversation(ctx, conversationID)
@@ -2175,7 +2326,7 @@ func (s *OrderService) FindSuitableFreelancers(ctx context.Context, orderID uuid
 
 	// Проверка прав: только владелец заказа или admin могут искать исполнителей
 	// Нормализуем роль для сравнения (на случай разных регистров)
-	isAdmin := strings.ToLower(userRole) == "admin"
+	isAdmin := strings.ToLower(userRole) == models.RoleAdmin
 	isOwner := order.ClientID == userID
 
 	if !isAdmin && !isOwner {
@@ -2278,7 +2429,7 @@ func (s *OrderService) StreamFindSuitableFreelancers(
 
 	// Проверка прав: только владелец заказа или admin могут искать исполнителей
 	// Нормализуем роль для сравнения (на случай разных регистров)
-	isAdmin := strings.ToLower(userRole) == "admin"
+	isAdmin := strings.ToLower(userRole) == models.RoleAdmin
 	isOwner := order.ClientID == userID
 
 	if !isAdmin && !isOwner {
@@ -2,18 +2,23 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/repository"
 )
 
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
 type PaymentRepository interface {
-	GetBalance(ctx context.Context, userID uuid.UUID) (*models.UserBalance, error)
-	Deposit(ctx context.Context, userID uuid.UUID, amount float64, description string) (*models.Transaction, error)
-	CreateEscrow(ctx context.Context, orderID, clientID, freelancerID uuid.UUID, amount float64) (*models.Escrow, error)
+	GetBalance(ctx context.Context, userID uuid.UUID, currency string) (*models.UserBalance, error)
+	ListBalances(ctx context.Context, userID uuid.UUID) ([]models.UserBalance, error)
+	Deposit(ctx context.Context, userID uuid.UUID, amount valueobject.Amount, currency, description string) (*models.Transaction, error)
+	CreateEscrow(ctx context.Context, orderID, clientID, freelancerID uuid.UUID, amount valueobject.Amount, currency string) (*models.Escrow, error)
 	ReleaseEscrow(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error)
 	RefundEscrow(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error)
 	GetEscrowByOrderID(ctx context.Context, orderID uuid.UUID) (*models.Escrow, error)
@@ -28,25 +33,42 @@ func NewPaymentService(repo PaymentRepository) *PaymentService {
 	return &PaymentService{repo: repo}
 }
 
-// GetBalance возвращает баланс пользователя.
-func (s *PaymentService) GetBalance(ctx context.Context, userID uuid.UUID) (*models.UserBalance, error) {
-	return s.repo.GetBalance(ctx, userID)
+// GetBalance возвращает баланс пользователя в валюте (по умолчанию — в основной).
+func (s *PaymentService) GetBalance(ctx context.Context, userID uuid.UUID, currency string) (*models.UserBalance, error) {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetBalance(ctx, userID, currency)
+}
+
+// ListBalances возвращает балансы пользователя во всех валютах.
+func (s *PaymentService) ListBalances(ctx context.Context, userID uuid.UUID) ([]models.UserBalance, error) {
+	return s.repo.ListBalances(ctx, userID)
 }
 
-// Deposit пополняет баланс.
-func (s *PaymentService) Deposit(ctx context.Context, userID uuid.UUID, amount float64) (*models.Transaction, error) {
+// Deposit пополняет баланс в валюте.
+func (s *PaymentService) Deposit(ctx context.Context, userID uuid.UUID, amount valueobject.Amount, currency string) (*models.Transaction, error) {
 	if amount <= 0 {
 		return nil, fmt.Errorf("сумма должна быть положительной")
 	}
-	return s.repo.Deposit(ctx, userID, amount, "Пополнение баланса")
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Deposit(ctx, userID, amount, currency, "Пополнение баланса")
 }
 
-// CreateEscrow создаёт защищённую сделку.
-func (s *PaymentService) CreateEscrow(ctx context.Context, orderID, clientID, freelancerID uuid.UUID, amount float64) (*models.Escrow, error) {
+// CreateEscrow создаёт защищённую сделку в валюте.
+func (s *PaymentService) CreateEscrow(ctx context.Context, orderID, clientID, freelancerID uuid.UUID, amount valueobject.Amount, currency string) (*models.Escrow, error) {
 	if amount <= 0 {
 		return nil, fmt.Errorf("сумма должна быть положительной")
 	}
-	escrow, err := s.repo.CreateEscrow(ctx, orderID, clientID, freelancerID, amount)
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	escrow, err := s.repo.CreateEscrow(ctx, orderID, clientID, freelancerID, amount, currency)
 	if err != nil {
 		if err == repository.ErrInsufficientFunds {
 			return nil, fmt.Errorf("недостаточно средств на балансе")
@@ -78,3 +100,12 @@ func (s *PaymentService) ListTransactions(ctx context.Context, userID uuid.UUID,
 	}
 	return s.repo.ListTransactions(ctx, userID, limit, offset)
 }
+
+// normalizeCurrency проверяет код валюты; пустой код означает основную валюту платформы.
+func normalizeCurrency(code string) (string, error) {
+	currency, ok := models.NormalizeCurrency(code)
+	if !ok {
+		return "", ErrUnsupportedCurrency
+	}
+	return currency, nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/repository"
 )
@@ -17,24 +18,29 @@ type mockPaymentRepo struct {
 	mock.Mock
 }
 
-func (m *mockPaymentRepo) GetBalance(ctx context.Context, userID uuid.UUID) (*models.UserBalance, error) {
-	args := m.Called(ctx, userID)
+func (m *mockPaymentRepo) GetBalance(ctx context.Context, userID uuid.UUID, currency string) (*models.UserBalance, error) {
+	args := m.Called(ctx, userID, currency)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.UserBalance), args.Error(1)
 }
 
-func (m *mockPaymentRepo) Deposit(ctx context.Context, userID uuid.UUID, amount float64, description string) (*models.Transaction, error) {
-	args := m.Called(ctx, userID, amount, description)
+func (m *mockPaymentRepo) ListBalances(ctx context.Context, userID uuid.UUID) ([]models.UserBalance, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.UserBalance), args.Error(1)
+}
+
+func (m *mockPaymentRepo) Deposit(ctx context.Context, userID uuid.UUID, amount valueobject.Amount, currency, description string) (*models.Transaction, error) {
+	args := m.Called(ctx, userID, amount, currency, description)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Transaction), args.Error(1)
 }
 
-func (m *mockPaymentRepo) CreateEscrow(ctx context.Context, orderID, clientID, freelancerID uuid.UUID, amount float64) (*models.Escrow, error) {
-	args := m.Called(ctx, orderID, clientID, freelancerID, amount)
+func (m *mockPaymentRepo) CreateEscrow(ctx context.Context, orderID, clientID, freelancerID uuid.UUID, amount valueobject.Amount, currency string) (*models.Escrow, error) {
+	args := m.Called(ctx, orderID, clientID, freelancerID, amount, currency)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -76,15 +82,33 @@ func TestPaymentService_GetBalance(t *testing.T) {
 	ctx := context.Background()
 	userID := uuid.New()
 
-	expected := &models.UserBalance{UserID: userID, Available: 1000, Frozen: 500}
-	repo.On("GetBalance", ctx, userID).Return(expected, nil)
+	expected := &models.UserBalance{UserID: userID, Currency: models.CurrencyRUB, Available: 1000, Frozen: 500}
+	repo.On("GetBalance", ctx, userID, models.CurrencyRUB).Return(expected, nil)
 
-	balance, err := svc.GetBalance(ctx, userID)
+	balance, err := svc.GetBalance(ctx, userID, "")
 	assert.NoError(t, err)
 	assert.Equal(t, expected, balance)
 	repo.AssertExpectations(t)
 }
 
+func TestPaymentService_GetBalance_Currency(t *testing.T) {
+	repo := new(mockPaymentRepo)
+	svc := NewPaymentService(repo)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	expected := &models.UserBalance{UserID: userID, Currency: models.CurrencyUSD, Available: 20}
+	repo.On("GetBalance", ctx, userID, models.CurrencyUSD).Return(expected, nil)
+
+	balance, err := svc.GetBalance(ctx, userID, "usd")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, balance)
+
+	_, err = svc.GetBalance(ctx, userID, "XYZ")
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+	repo.AssertExpectations(t)
+}
+
 func TestPaymentService_Deposit_Success(t *testing.T) {
 	repo := new(mockPaymentRepo)
 	svc := NewPaymentService(repo)
@@ -92,9 +116,9 @@ func TestPaymentService_Deposit_Success(t *testing.T) {
 	userID := uuid.New()
 
 	expected := &models.Transaction{ID: uuid.New(), Amount: 1000}
-	repo.On("Deposit", ctx, userID, float64(1000), "Пополнение баланса").Return(expected, nil)
+	repo.On("Deposit", ctx, userID, valueobject.Amount(1000), models.CurrencyEUR, "Пополнение баланса").Return(expected, nil)
 
-	tx, err := svc.Deposit(ctx, userID, 1000)
+	tx, err := svc.Deposit(ctx, userID, 1000, models.CurrencyEUR)
 	assert.NoError(t, err)
 	assert.Equal(t, expected, tx)
 }
@@ -105,14 +129,23 @@ func TestPaymentService_Deposit_InvalidAmount(t *testing.T) {
 	ctx := context.Background()
 	userID := uuid.New()
 
-	_, err := svc.Deposit(ctx, userID, 0)
+	_, err := svc.Deposit(ctx, userID, 0, "")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "положительной")
 
-	_, err = svc.Deposit(ctx, userID, -100)
+	_, err = svc.Deposit(ctx, userID, -100, "")
 	assert.Error(t, err)
 }
 
+func TestPaymentService_Deposit_UnsupportedCurrency(t *testing.T) {
+	repo := new(mockPaymentRepo)
+	svc := NewPaymentService(repo)
+
+	_, err := svc.Deposit(context.Background(), uuid.New(), 1000, "GBP")
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+	repo.AssertNotCalled(t, "Deposit")
+}
+
 func TestPaymentService_CreateEscrow_Success(t *testing.T) {
 	repo := new(mockPaymentRepo)
 	svc := NewPaymentService(repo)
@@ -123,9 +156,9 @@ func TestPaymentService_CreateEscrow_Success(t *testing.T) {
 	freelancerID := uuid.New()
 
 	expected := &models.Escrow{ID: uuid.New(), Amount: 5000, Status: models.EscrowStatusHeld}
-	repo.On("CreateEscrow", ctx, orderID, clientID, freelancerID, float64(5000)).Return(expected, nil)
+	repo.On("CreateEscrow", ctx, orderID, clientID, freelancerID, valueobject.Amount(5000), models.CurrencyRUB).Return(expected, nil)
 
-	escrow, err := svc.CreateEscrow(ctx, orderID, clientID, freelancerID, 5000)
+	escrow, err := svc.CreateEscrow(ctx, orderID, clientID, freelancerID, 5000, "")
 	assert.NoError(t, err)
 	assert.Equal(t, expected, escrow)
 }
@@ -139,9 +172,9 @@ func TestPaymentService_CreateEscrow_InsufficientFunds(t *testing.T) {
 	clientID := uuid.New()
 	freelancerID := uuid.New()
 
-	repo.On("CreateEscrow", ctx, orderID, clientID, freelancerID, float64(5000)).Return(nil, repository.ErrInsufficientFunds)
+	repo.On("CreateEscrow", ctx, orderID, clientID, freelancerID, valueobject.Amount(5000), models.CurrencyUSD).Return(nil, repository.ErrInsufficientFunds)
 
-	_, err := svc.CreateEscrow(ctx, orderID, clientID, freelancerID, 5000)
+	_, err := svc.CreateEscrow(ctx, orderID, clientID, freelancerID, 5000, models.CurrencyUSD)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "недостаточно средств")
 }
@@ -151,7 +184,7 @@ func TestPaymentService_CreateEscrow_InvalidAmount(t *testing.T) {
 	svc := NewPaymentService(repo)
 	ctx := context.Background()
 
-	_, err := svc.CreateEscrow(ctx, uuid.New(), uuid.New(), uuid.New(), 0)
+	_, err := svc.CreateEscrow(ctx, uuid.New(), uuid.New(), uuid.New(), 0, "")
 	assert.Error(t, err)
 }
 
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+// PayoutProvider отправляет выплату по заявке на вывод во внешнюю платёжную систему.
+// Повторный вызов для той же заявки не должен приводить к повторной выплате.
+type PayoutProvider interface {
+	// Payout возвращает идентификатор выплаты у провайдера.
+	// Окончательный отказ возвращается как *PayoutRejectedError, остальные ошибки считаются временными.
+	Payout(ctx context.Context, w *models.Withdrawal) (string, error)
+}
+
+// PayoutRejectedError — окончательный отказ провайдера в выплате.
+type PayoutRejectedError struct {
+	Reason string
+}
+
+func (e *PayoutRejectedError) Error() string {
+	return "payout rejected: " + e.Reason
+}
+
+// FakeCardDeclined номер карты, выплаты на которую локальный провайдер всегда отклоняет.
+const FakeCardDeclined = "0000"
+
+// FakePayoutProvider локальный провайдер для разработки: одобряет все выплаты,
+// кроме выплат на карту FakeCardDeclined и сумм больше MaxAmount (если задан).
+type FakePayoutProvider struct {
+	MaxAmount valueobject.Amount
+}
+
+// NewFakePayoutProvider создаёт локальный провайдер выплат.
+func NewFakePayoutProvider() *FakePayoutProvider {
+	return &FakePayoutProvider{}
+}
+
+func (p *FakePayoutProvider) Payout(ctx context.Context, w *models.Withdrawal) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if w.CardLast4 != nil && *w.CardLast4 == FakeCardDeclined {
+		return "", &PayoutRejectedError{Reason: "карта отклонена банком"}
+	}
+	if p.MaxAmount > 0 && w.Amount > p.MaxAmount {
+		return "", &PayoutRejectedError{Reason: fmt.Sprintf("сумма превышает лимит выплаты %s", p.MaxAmount)}
+	}
+	return "fake-" + w.ID.String(), nil
+}
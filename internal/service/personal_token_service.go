@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+// PersonalTokenPrefix отличает персональные токены доступа от JWT в заголовке Authorization.
+const PersonalTokenPrefix = "fbp_"
+
+const (
+	// maxPersonalTokens — сколько действующих токенов может быть у пользователя одновременно.
+	maxPersonalTokens = 20
+	// maxPersonalTokenTTL — наибольший срок действия токена, если он задан.
+	maxPersonalTokenTTL = 365 * 24 * time.Hour
+	// personalTokenPrefixLen — сколько первых символов токена показывается в списке.
+	personalTokenPrefixLen = len(PersonalTokenPrefix) + 6
+)
+
+var (
+	// ErrPersonalTokenInvalid возвращается для неизвестного, отозванного или истёкшего токена.
+	ErrPersonalTokenInvalid = errors.New("auth service: персональный токен недействителен")
+	// ErrPersonalTokenName возвращается для пустого или слишком длинного названия.
+	ErrPersonalTokenName = errors.New("auth service: укажите название токена до 100 символов")
+	// ErrPersonalTokenScopes возвращается для пустого набора или неизвестного права.
+	ErrPersonalTokenScopes = errors.New("auth service: укажите права токена: orders:read, orders:write, proposals:read, proposals:write")
+	// ErrPersonalTokenTTL возвращается для срока действия вне допустимого диапазона.
+	ErrPersonalTokenTTL = errors.New("auth service: срок действия токена — от 1 до 365 дней")
+	// ErrPersonalTokenLimit возвращается при попытке создать токен сверх maxPersonalTokens.
+	ErrPersonalTokenLimit = errors.New("auth service: слишком много действующих токенов, отзовите ненужные")
+)
+
+// PersonalTokenRepository описывает зависимости PersonalTokenService от слоя хранилища.
+type PersonalTokenRepository interface {
+	CreateToken(ctx context.Context, token *models.PersonalAccessToken) error
+	CountActiveTokens(ctx context.Context, userID uuid.UUID) (int, error)
+	ListTokens(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error)
+	RevokeToken(ctx context.Context, userID, id uuid.UUID) error
+	UseToken(ctx context.Context, tokenHash string, ip *string) (*models.PersonalAccessToken, string, error)
+}
+
+// CreatedPersonalToken — новый токен; значение Token показывается только один раз.
+type CreatedPersonalToken struct {
+	*models.PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// PersonalTokenService выпускает долгоживущие токены с ограниченными правами для скриптов
+// и интеграций. Хранится только SHA-256 токена, каждое использование запоминается.
+type PersonalTokenService struct {
+	repo PersonalTokenRepository
+}
+
+// NewPersonalTokenService создаёт сервис.
+func NewPersonalTokenService(repo PersonalTokenRepository) *PersonalTokenService {
+	return &PersonalTokenService{repo: repo}
+}
+
+// Create выпускает токен с правами scopes; ttl равный нулю означает бессрочный токен.
+func (s *PersonalTokenService) Create(ctx context.Context, userID uuid.UUID, name string, scopes []string, ttl time.Duration) (*CreatedPersonalToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 100 {
+		return nil, ErrPersonalTokenName
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+	if ttl < 0 || ttl > maxPersonalTokenTTL || (ttl > 0 && ttl < 24*time.Hour) {
+		return nil, ErrPersonalTokenTTL
+	}
+
+	active, err := s.repo.CountActiveTokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if active >= maxPersonalTokens {
+		return nil, ErrPersonalTokenLimit
+	}
+
+	secret, err := randomURLToken()
+	if err != nil {
+		return nil, err
+	}
+	raw := PersonalTokenPrefix + secret
+
+	token := &models.PersonalAccessToken{
+		UserID:      userID,
+		Name:        name,
+		TokenHash:   HashRefreshToken(raw),
+		TokenPrefix: raw[:personalTokenPrefixLen],
+		Scopes:      scopes,
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := s.repo.CreateToken(ctx, token); err != nil {
+		return nil, err
+	}
+
+	return &CreatedPersonalToken{PersonalAccessToken: token, Token: raw}, nil
+}
+
+// List возвращает неотозванные токены пользователя.
+func (s *PersonalTokenService) List(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	return s.repo.ListTokens(ctx, userID)
+}
+
+// Revoke отзывает токен; следующий запрос с ним получит 401.
+func (s *PersonalTokenService) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	return s.repo.RevokeToken(ctx, userID, id)
+}
+
+// Authenticate проверяет персональный токен и запоминает его использование с адреса ip.
+// Возвращает владельца с текущей ролью и права токена.
+func (s *PersonalTokenService) Authenticate(ctx context.Context, raw, ip string) (*AccessClaims, []string, error) {
+	if !strings.HasPrefix(raw, PersonalTokenPrefix) {
+		return nil, nil, ErrPersonalTokenInvalid
+	}
+
+	var lastIP *string
+	if ip != "" {
+		lastIP = &ip
+	}
+	token, role, err := s.repo.UseToken(ctx, HashRefreshToken(raw), lastIP)
+	if errors.Is(err, repository.ErrPersonalTokenNotFound) {
+		return nil, nil, ErrPersonalTokenInvalid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &AccessClaims{UserID: token.UserID, Role: role}, token.Scopes, nil
+}
+
+// normalizeScopes проверяет права и убирает повторы.
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool, len(scopes))
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if _, ok := models.TokenScopes[scope]; !ok {
+			return nil, ErrPersonalTokenScopes
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, ErrPersonalTokenScopes
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
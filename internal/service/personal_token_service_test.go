@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+// mockPersonalTokenRepository хранит токены в памяти; роль владельца у всех одна.
+type mockPersonalTokenRepository struct {
+	tokens []*models.PersonalAccessToken
+	role   string
+}
+
+func (m *mockPersonalTokenRepository) CreateToken(ctx context.Context, token *models.PersonalAccessToken) error {
+	token.ID = uuid.New()
+	token.CreatedAt = time.Now()
+	m.tokens = append(m.tokens, token)
+	return nil
+}
+
+func (m *mockPersonalTokenRepository) CountActiveTokens(ctx context.Context, userID uuid.UUID) (int, error) {
+	count := 0
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockPersonalTokenRepository) ListTokens(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	var result []models.PersonalAccessToken
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			result = append(result, *token)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockPersonalTokenRepository) RevokeToken(ctx context.Context, userID, id uuid.UUID) error {
+	for _, token := range m.tokens {
+		if token.ID == id && token.UserID == userID && token.RevokedAt == nil {
+			now := time.Now()
+			token.RevokedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrPersonalTokenNotFound
+}
+
+func (m *mockPersonalTokenRepository) UseToken(ctx context.Context, tokenHash string, ip *string) (*models.PersonalAccessToken, string, error) {
+	for _, token := range m.tokens {
+		if token.TokenHash != tokenHash || token.RevokedAt != nil {
+			continue
+		}
+		if token.ExpiresAt != nil && !token.ExpiresAt.After(time.Now()) {
+			continue
+		}
+		now := time.Now()
+		token.LastUsedAt, token.LastUsedIP = &now, ip
+		copied := *token
+		return &copied, m.role, nil
+	}
+	return nil, "", repository.ErrPersonalTokenNotFound
+}
+
+func TestPersonalTokenService_CreateValidates(t *testing.T) {
+	svc := NewPersonalTokenService(&mockPersonalTokenRepository{})
+	ctx := context.Background()
+	userID := uuid.New()
+
+	_, err := svc.Create(ctx, userID, "  ", []string{models.ScopeOrdersRead}, 0)
+	assert.ErrorIs(t, err, ErrPersonalTokenName)
+
+	_, err = svc.Create(ctx, userID, "CI", nil, 0)
+	assert.ErrorIs(t, err, ErrPersonalTokenScopes)
+
+	_, err = svc.Create(ctx, userID, "CI", []string{"payments:write"}, 0)
+	assert.ErrorIs(t, err, ErrPersonalTokenScopes)
+
+	_, err = svc.Create(ctx, userID, "CI", []string{models.ScopeOrdersRead}, 400*24*time.Hour)
+	assert.ErrorIs(t, err, ErrPersonalTokenTTL)
+
+	for i := 0; i < maxPersonalTokens; i++ {
+		_, err = svc.Create(ctx, userID, "CI", []string{models.ScopeOrdersRead}, 0)
+		assert.NoError(t, err)
+	}
+	_, err = svc.Create(ctx, userID, "CI", []string{models.ScopeOrdersRead}, 0)
+	assert.ErrorIs(t, err, ErrPersonalTokenLimit)
+}
+
+func TestPersonalTokenService_AuthenticateRecordsUse(t *testing.T) {
+	repo := &mockPersonalTokenRepository{role: models.RoleFreelancer}
+	svc := NewPersonalTokenService(repo)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	created, err := svc.Create(ctx, userID, "CRM", []string{" Proposals:Write", models.ScopeOrdersRead, models.ScopeOrdersRead}, 30*24*time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Token, PersonalTokenPrefix))
+	assert.True(t, strings.HasPrefix(created.Token, created.TokenPrefix))
+	assert.Equal(t, []string{models.ScopeOrdersRead, models.ScopeProposalsWrite}, []string(created.Scopes))
+	// В хранилище попадает только хеш
+	assert.NotContains(t, repo.tokens[0].TokenHash, created.Token)
+
+	claims, scopes, err := svc.Authenticate(ctx, created.Token, "203.0.113.7")
+	assert.NoError(t, err)
+	assert.Equal(t, userID, claims.UserID)
+	assert.Equal(t, models.RoleFreelancer, claims.Role)
+	assert.Equal(t, uuid.Nil, claims.SessionID)
+	assert.Len(t, scopes, 2)
+	assert.NotNil(t, repo.tokens[0].LastUsedAt)
+	assert.Equal(t, "203.0.113.7", *repo.tokens[0].LastUsedIP)
+
+	_, _, err = svc.Authenticate(ctx, created.Token+"x", "")
+	assert.ErrorIs(t, err, ErrPersonalTokenInvalid)
+
+	assert.NoError(t, svc.Revoke(ctx, userID, created.ID))
+	_, _, err = svc.Authenticate(ctx, created.Token, "")
+	assert.ErrorIs(t, err, ErrPersonalTokenInvalid)
+	assert.ErrorIs(t, svc.Revoke(ctx, userID, created.ID), repository.ErrPersonalTokenNotFound)
+}
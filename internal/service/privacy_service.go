@@ -0,0 +1,434 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/logger"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+const (
+	privacyBatchSize   = 10
+	exportStaleAfter   = 30 * time.Minute
+	deletedAccountName = "Удалённый пользователь"
+)
+
+var (
+	// ErrDataExportNotReady возвращается при скачивании архива, который ещё собирается или уже удалён.
+	ErrDataExportNotReady = errors.New("privacy: архив ещё не готов или срок его хранения истёк")
+)
+
+// AccountDeletionBlockedError возвращается, пока у пользователя есть незавершённые сделки,
+// споры, выводы или деньги на балансе.
+type AccountDeletionBlockedError struct {
+	Blockers []string
+}
+
+func (e *AccountDeletionBlockedError) Error() string {
+	return "privacy: аккаунт нельзя удалить, пока не завершены: " + strings.Join(e.Blockers, ", ")
+}
+
+// PrivacyRepository описывает зависимости PrivacyService от слоя хранилища.
+type PrivacyRepository interface {
+	CreateExport(ctx context.Context, userID uuid.UUID) (*models.DataExport, error)
+	ListExports(ctx context.Context, userID uuid.UUID) ([]models.DataExport, error)
+	GetExport(ctx context.Context, userID, id uuid.UUID) (*models.DataExport, error)
+	ClaimPendingExports(ctx context.Context, limit int, staleAfter time.Duration) ([]models.DataExport, error)
+	CompleteExport(ctx context.Context, id uuid.UUID, filePath string, fileSize int64, expiresAt time.Time) (*models.DataExport, error)
+	FailExport(ctx context.Context, id uuid.UUID) error
+	ListExpiredExports(ctx context.Context, limit int) ([]models.DataExport, error)
+	ExpireExport(ctx context.Context, id uuid.UUID) error
+	ExportData(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error)
+	ListUserMedia(ctx context.Context, userID uuid.UUID) ([]models.MediaFile, error)
+	DeletionBlockers(ctx context.Context, userID uuid.UUID) ([]string, error)
+	GetDeletionSchedule(ctx context.Context, userID uuid.UUID) (*time.Time, error)
+	ScheduleDeletion(ctx context.Context, userID uuid.UUID, at time.Time) (time.Time, string, error)
+	CancelDeletion(ctx context.Context, userID uuid.UUID) error
+	ListDueDeletions(ctx context.Context, limit int) ([]uuid.UUID, error)
+	AnonymizeUser(ctx context.Context, userID uuid.UUID) (*repository.AnonymizedAccount, error)
+}
+
+// PrivacyFileStore — файловое хранилище, из которого читаются файлы пользователя
+// и в которое складываются архивы.
+type PrivacyFileStore interface {
+	Open(ctx context.Context, relativePath string) (*os.File, error)
+	Create(ctx context.Context, relativePath string) (*os.File, error)
+	Delete(ctx context.Context, relativePath string) error
+}
+
+// AccountDeletionStatus — запланированное удаление аккаунта и то, что ему мешает.
+type AccountDeletionStatus struct {
+	ScheduledAt *time.Time `json:"scheduled_at"`
+	Blockers    []string   `json:"blockers"`
+}
+
+// PrivacyService выгружает данные пользователя в ZIP-архив и удаляет аккаунт
+// по истечении периода, в течение которого удаление можно отменить.
+type PrivacyService struct {
+	repo        PrivacyRepository
+	public      PrivacyFileStore
+	private     PrivacyFileStore
+	mailer      Mailer
+	hub         WSNotifier
+	revoker     SessionRevoker
+	gracePeriod time.Duration
+	exportTTL   time.Duration
+}
+
+// NewPrivacyService создаёт сервис. public и private — хранилища публичных и приватных
+// файлов; архивы складываются в private.
+func NewPrivacyService(repo PrivacyRepository, public, private PrivacyFileStore, mailer Mailer, gracePeriod, exportTTL time.Duration) *PrivacyService {
+	return &PrivacyService{
+		repo:        repo,
+		public:      public,
+		private:     private,
+		mailer:      mailer,
+		gracePeriod: gracePeriod,
+		exportTTL:   exportTTL,
+	}
+}
+
+// SetHub устанавливает WebSocket hub для уведомления о готовности архива.
+func (s *PrivacyService) SetHub(hub WSNotifier) {
+	s.hub = hub
+}
+
+// SetSessionRevoker устанавливает получателя уведомлений о блокировке удалённых аккаунтов.
+func (s *PrivacyService) SetSessionRevoker(revoker SessionRevoker) {
+	s.revoker = revoker
+}
+
+// RequestExport ставит в очередь сборку архива с данными пользователя.
+func (s *PrivacyService) RequestExport(ctx context.Context, userID uuid.UUID) (*models.DataExport, error) {
+	return s.repo.CreateExport(ctx, userID)
+}
+
+// ListExports возвращает последние выгрузки пользователя.
+func (s *PrivacyService) ListExports(ctx context.Context, userID uuid.UUID) ([]models.DataExport, error) {
+	return s.repo.ListExports(ctx, userID)
+}
+
+// OpenExport открывает готовый архив пользователя для скачивания.
+func (s *PrivacyService) OpenExport(ctx context.Context, userID, id uuid.UUID) (*models.DataExport, *os.File, error) {
+	export, err := s.repo.GetExport(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.Status != models.DataExportReady || export.FilePath == nil ||
+		(export.ExpiresAt != nil && !export.ExpiresAt.After(time.Now())) {
+		return nil, nil, ErrDataExportNotReady
+	}
+
+	f, err := s.private.Open(ctx, *export.FilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrDataExportNotReady
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return export, f, nil
+}
+
+// DeletionStatus возвращает дату запланированного удаления и причины, которые ему мешают.
+func (s *PrivacyService) DeletionStatus(ctx context.Context, userID uuid.UUID) (*AccountDeletionStatus, error) {
+	scheduledAt, err := s.repo.GetDeletionSchedule(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	blockers, err := s.repo.DeletionBlockers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountDeletionStatus{ScheduledAt: scheduledAt, Blockers: blockers}, nil
+}
+
+// RequestDeletion планирует удаление аккаунта через gracePeriod. Пока идут сделки в escrow,
+// споры, выводы или на балансе есть деньги, удаление не планируется.
+func (s *PrivacyService) RequestDeletion(ctx context.Context, userID uuid.UUID) (*AccountDeletionStatus, error) {
+	blockers, err := s.repo.DeletionBlockers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(blockers) > 0 {
+		return nil, &AccountDeletionBlockedError{Blockers: blockers}
+	}
+
+	scheduledAt, email, err := s.repo.ScheduleDeletion(ctx, userID, time.Now().Add(s.gracePeriod))
+	if err != nil {
+		return nil, err
+	}
+
+	s.sendMail(ctx, Email{
+		To:      email,
+		Subject: "Запрос на удаление аккаунта",
+		Body: fmt.Sprintf("Здравствуйте!\n\nВаш аккаунт будет удалён %s. "+
+			"До этого момента удаление можно отменить в настройках аккаунта.\n\n"+
+			"Если вы не запрашивали удаление, отмените его и смените пароль.",
+			scheduledAt.UTC().Format("02.01.2006 15:04 UTC")),
+	})
+
+	return &AccountDeletionStatus{ScheduledAt: &scheduledAt, Blockers: []string{}}, nil
+}
+
+// CancelDeletion отменяет запланированное удаление аккаунта.
+func (s *PrivacyService) CancelDeletion(ctx context.Context, userID uuid.UUID) error {
+	return s.repo.CancelDeletion(ctx, userID)
+}
+
+// Run раз в interval собирает архивы, удаляет устаревшие и обезличивает аккаунты,
+// у которых истёк период отмены удаления, до отмены контекста.
+func (s *PrivacyService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ProcessExports(ctx); err != nil {
+			s.logError(err, "privacy: не удалось собрать архивы")
+		}
+		if err := s.CleanupExports(ctx); err != nil {
+			s.logError(err, "privacy: не удалось удалить устаревшие архивы")
+		}
+		if _, err := s.ProcessDeletions(ctx); err != nil {
+			s.logError(err, "privacy: не удалось удалить аккаунты")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessExports собирает пачку архивов из очереди. Возвращает число готовых архивов.
+func (s *PrivacyService) ProcessExports(ctx context.Context) (int, error) {
+	exports, err := s.repo.ClaimPendingExports(ctx, privacyBatchSize, exportStaleAfter)
+	if err != nil {
+		return 0, err
+	}
+
+	ready := 0
+	for i := range exports {
+		export := &exports[i]
+		filePath := path.Join("exports", export.UserID.String(), export.ID.String()+".zip")
+		size, err := s.writeExport(ctx, export.UserID, filePath)
+		if err != nil {
+			s.logError(err, "privacy: не удалось собрать архив")
+			_ = s.private.Delete(ctx, filePath)
+			if err := s.repo.FailExport(ctx, export.ID); err != nil {
+				s.logError(err, "privacy: не удалось отметить архив неудавшимся")
+			}
+			continue
+		}
+
+		completed, err := s.repo.CompleteExport(ctx, export.ID, filePath, size, time.Now().Add(s.exportTTL))
+		if err != nil {
+			s.logError(err, "privacy: не удалось отметить архив готовым")
+			continue
+		}
+		ready++
+
+		if s.hub != nil {
+			if err := s.hub.BroadcastToUser(completed.UserID, "data_export_ready", completed); err != nil {
+				s.logError(err, "privacy: не удалось отправить уведомление о готовности архива")
+			}
+		}
+	}
+	return ready, nil
+}
+
+// writeExport складывает JSON файлы с данными пользователя и его загруженные файлы в ZIP.
+func (s *PrivacyService) writeExport(ctx context.Context, userID uuid.UUID, filePath string) (int64, error) {
+	data, err := s.repo.ExportData(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	media, err := s.repo.ListUserMedia(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := s.private.Create(ctx, filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, data[name], "", "  "); err != nil {
+			return 0, fmt.Errorf("privacy: %s.json: %w", name, err)
+		}
+		w, err := zw.Create(name + ".json")
+		if err != nil {
+			return 0, err
+		}
+		if _, err := pretty.WriteTo(w); err != nil {
+			return 0, err
+		}
+	}
+
+	mediaJSON, err := json.MarshalIndent(media, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	w, err := zw.Create("media.json")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(mediaJSON); err != nil {
+		return 0, err
+	}
+
+	for _, file := range media {
+		if err := s.addMediaFile(ctx, zw, file); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// addMediaFile копирует файл пользователя в каталог media/ архива. Файлы, пропавшие
+// из хранилища, пропускаются: их описание остаётся в media.json.
+func (s *PrivacyService) addMediaFile(ctx context.Context, zw *zip.Writer, file models.MediaFile) error {
+	src, err := s.storeFor(file).Open(ctx, file.FilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	w, err := zw.Create("media/" + file.ID.String() + filepath.Ext(file.FilePath))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, src)
+	return err
+}
+
+// CleanupExports удаляет архивы с истёкшим сроком хранения.
+func (s *PrivacyService) CleanupExports(ctx context.Context) error {
+	exports, err := s.repo.ListExpiredExports(ctx, privacyBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if export.FilePath != nil {
+			if err := s.private.Delete(ctx, *export.FilePath); err != nil {
+				s.logError(err, "privacy: не удалось удалить архив")
+				continue
+			}
+		}
+		if err := s.repo.ExpireExport(ctx, export.ID); err != nil {
+			s.logError(err, "privacy: не удалось отметить архив удалённым")
+		}
+	}
+	return nil
+}
+
+// ProcessDeletions обезличивает аккаунты, у которых истёк период отмены удаления, и удаляет
+// их файлы. Аккаунт, у которого за это время появились сделки или деньги на балансе,
+// остаётся в очереди до их завершения. Возвращает число удалённых аккаунтов.
+func (s *PrivacyService) ProcessDeletions(ctx context.Context) (int, error) {
+	ids, err := s.repo.ListDueDeletions(ctx, privacyBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, userID := range ids {
+		account, err := s.repo.AnonymizeUser(ctx, userID)
+		if errors.Is(err, repository.ErrAccountDeletionBlocked) || errors.Is(err, repository.ErrAccountDeletionNotScheduled) {
+			continue
+		}
+		if err != nil {
+			s.logError(err, "privacy: не удалось удалить аккаунт")
+			continue
+		}
+		deleted++
+		// Сессии удалены вместе с аккаунтом; сбрасываем кэш проверки токенов и закрываем соединения
+		if s.revoker != nil {
+			s.revoker.UserSuspended(userID)
+		}
+
+		for _, file := range account.Media {
+			if err := s.storeFor(file).Delete(ctx, file.FilePath); err != nil {
+				s.logError(err, "privacy: не удалось удалить файл пользователя")
+			}
+		}
+		for _, exportPath := range account.ExportPaths {
+			if err := s.private.Delete(ctx, exportPath); err != nil {
+				s.logError(err, "privacy: не удалось удалить архив пользователя")
+			}
+		}
+
+		s.sendMail(ctx, Email{
+			To:      account.Email,
+			Subject: "Аккаунт удалён",
+			Body: fmt.Sprintf("Здравствуйте, %s!\n\nВаш аккаунт удалён. В заказах, чатах и отзывах "+
+				"других пользователей вы теперь отображаетесь как «%s».",
+				account.Username, deletedAccountName),
+		})
+	}
+	return deleted, nil
+}
+
+func (s *PrivacyService) storeFor(file models.MediaFile) PrivacyFileStore {
+	if file.IsPublic {
+		return s.public
+	}
+	return s.private
+}
+
+func (s *PrivacyService) sendMail(ctx context.Context, msg Email) {
+	if s.mailer == nil {
+		return
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		s.logError(err, "privacy: не удалось отправить письмо")
+	}
+}
+
+func (s *PrivacyService) logError(err error, msg string) {
+	if logger.Log == nil {
+		return
+	}
+	logger.Log.WithFields(map[string]interface{}{
+		"error": err.Error(),
+	}).Warn(msg)
+}
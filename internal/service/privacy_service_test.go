@@ -0,0 +1,346 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+	"github.com/ignatzorin/freelance-backend/internal/storage"
+)
+
+// mockPrivacyRepository хранит выгрузки, расписание удалений и файлы пользователей в памяти.
+type mockPrivacyRepository struct {
+	exports    []*models.DataExport
+	media      map[uuid.UUID][]models.MediaFile
+	blockers   map[uuid.UUID][]string
+	scheduled  map[uuid.UUID]time.Time
+	anonymized map[uuid.UUID]bool
+}
+
+func newMockPrivacyRepository() *mockPrivacyRepository {
+	return &mockPrivacyRepository{
+		media:      make(map[uuid.UUID][]models.MediaFile),
+		blockers:   make(map[uuid.UUID][]string),
+		scheduled:  make(map[uuid.UUID]time.Time),
+		anonymized: make(map[uuid.UUID]bool),
+	}
+}
+
+func (m *mockPrivacyRepository) CreateExport(ctx context.Context, userID uuid.UUID) (*models.DataExport, error) {
+	for _, export := range m.exports {
+		if export.UserID == userID && (export.Status == models.DataExportPending || export.Status == models.DataExportProcessing) {
+			return nil, repository.ErrDataExportInProgress
+		}
+	}
+	export := &models.DataExport{ID: uuid.New(), UserID: userID, Status: models.DataExportPending, CreatedAt: time.Now()}
+	m.exports = append(m.exports, export)
+	copied := *export
+	return &copied, nil
+}
+
+func (m *mockPrivacyRepository) ListExports(ctx context.Context, userID uuid.UUID) ([]models.DataExport, error) {
+	var result []models.DataExport
+	for _, export := range m.exports {
+		if export.UserID == userID {
+			result = append(result, *export)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockPrivacyRepository) GetExport(ctx context.Context, userID, id uuid.UUID) (*models.DataExport, error) {
+	for _, export := range m.exports {
+		if export.ID == id && export.UserID == userID {
+			copied := *export
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrDataExportNotFound
+}
+
+func (m *mockPrivacyRepository) ClaimPendingExports(ctx context.Context, limit int, staleAfter time.Duration) ([]models.DataExport, error) {
+	var result []models.DataExport
+	for _, export := range m.exports {
+		if export.Status == models.DataExportPending {
+			export.Status = models.DataExportProcessing
+			result = append(result, *export)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockPrivacyRepository) CompleteExport(ctx context.Context, id uuid.UUID, filePath string, fileSize int64, expiresAt time.Time) (*models.DataExport, error) {
+	for _, export := range m.exports {
+		if export.ID == id {
+			now := time.Now()
+			export.Status, export.FilePath, export.FileSize = models.DataExportReady, &filePath, &fileSize
+			export.CompletedAt, export.ExpiresAt = &now, &expiresAt
+			copied := *export
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrDataExportNotFound
+}
+
+func (m *mockPrivacyRepository) FailExport(ctx context.Context, id uuid.UUID) error {
+	for _, export := range m.exports {
+		if export.ID == id {
+			export.Status = models.DataExportFailed
+		}
+	}
+	return nil
+}
+
+func (m *mockPrivacyRepository) ListExpiredExports(ctx context.Context, limit int) ([]models.DataExport, error) {
+	var result []models.DataExport
+	for _, export := range m.exports {
+		if export.Status == models.DataExportReady && export.ExpiresAt != nil && !export.ExpiresAt.After(time.Now()) {
+			result = append(result, *export)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockPrivacyRepository) ExpireExport(ctx context.Context, id uuid.UUID) error {
+	for _, export := range m.exports {
+		if export.ID == id {
+			export.Status, export.FilePath = models.DataExportExpired, nil
+		}
+	}
+	return nil
+}
+
+func (m *mockPrivacyRepository) ExportData(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error) {
+	return map[string]json.RawMessage{
+		"profile": json.RawMessage(`{"user":{"id":"` + userID.String() + `"}}`),
+		"orders":  json.RawMessage(`[]`),
+	}, nil
+}
+
+func (m *mockPrivacyRepository) ListUserMedia(ctx context.Context, userID uuid.UUID) ([]models.MediaFile, error) {
+	return m.media[userID], nil
+}
+
+func (m *mockPrivacyRepository) DeletionBlockers(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return m.blockers[userID], nil
+}
+
+func (m *mockPrivacyRepository) GetDeletionSchedule(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	if at, ok := m.scheduled[userID]; ok {
+		return &at, nil
+	}
+	return nil, nil
+}
+
+func (m *mockPrivacyRepository) ScheduleDeletion(ctx context.Context, userID uuid.UUID, at time.Time) (time.Time, string, error) {
+	if existing, ok := m.scheduled[userID]; ok {
+		return existing, "user@example.com", nil
+	}
+	m.scheduled[userID] = at
+	return at, "user@example.com", nil
+}
+
+func (m *mockPrivacyRepository) CancelDeletion(ctx context.Context, userID uuid.UUID) error {
+	if _, ok := m.scheduled[userID]; !ok {
+		return repository.ErrAccountDeletionNotScheduled
+	}
+	delete(m.scheduled, userID)
+	return nil
+}
+
+func (m *mockPrivacyRepository) ListDueDeletions(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for userID, at := range m.scheduled {
+		if !at.After(time.Now()) {
+			ids = append(ids, userID)
+		}
+	}
+	return ids, nil
+}
+
+func (m *mockPrivacyRepository) AnonymizeUser(ctx context.Context, userID uuid.UUID) (*repository.AnonymizedAccount, error) {
+	if len(m.blockers[userID]) > 0 {
+		return nil, repository.ErrAccountDeletionBlocked
+	}
+	account := &repository.AnonymizedAccount{Email: "user@example.com", Username: "user", Media: m.media[userID]}
+	for _, export := range m.exports {
+		if export.UserID == userID && export.FilePath != nil {
+			account.ExportPaths = append(account.ExportPaths, *export.FilePath)
+		}
+	}
+	delete(m.scheduled, userID)
+	delete(m.media, userID)
+	m.anonymized[userID] = true
+	return account, nil
+}
+
+type privacyFixture struct {
+	svc     *PrivacyService
+	repo    *mockPrivacyRepository
+	public  *storage.PhotoStorage
+	private *storage.PhotoStorage
+	mailer  *recordingMailer
+	hub     *mockWSNotifier
+}
+
+func setupPrivacy(t *testing.T) *privacyFixture {
+	t.Helper()
+	public, err := storage.NewPhotoStorage(t.TempDir(), 1)
+	assert.NoError(t, err)
+	private, err := storage.NewPhotoStorage(t.TempDir(), 1)
+	assert.NoError(t, err)
+
+	repo := newMockPrivacyRepository()
+	mailer := &recordingMailer{}
+	hub := &mockWSNotifier{}
+	svc := NewPrivacyService(repo, public, private, mailer, 14*24*time.Hour, 7*24*time.Hour)
+	svc.SetHub(hub)
+	return &privacyFixture{svc: svc, repo: repo, public: public, private: private, mailer: mailer, hub: hub}
+}
+
+// upload сохраняет файл пользователя в публичное или приватное хранилище.
+func (f *privacyFixture) upload(t *testing.T, userID uuid.UUID, public bool, content string) models.MediaFile {
+	t.Helper()
+	store := f.private
+	if public {
+		store = f.public
+	}
+	relative, size, err := store.Save(context.Background(), userID, "photo.jpg", strings.NewReader(content))
+	assert.NoError(t, err)
+	file := models.MediaFile{ID: uuid.New(), UserID: &userID, FilePath: relative, FileType: "image/jpeg", FileSize: size, IsPublic: public}
+	f.repo.media[userID] = append(f.repo.media[userID], file)
+	return file
+}
+
+func TestPrivacyService_ExportBuildsArchive(t *testing.T) {
+	f := setupPrivacy(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	avatar := f.upload(t, userID, true, "avatar")
+	passport := f.upload(t, userID, false, "passport")
+
+	export, err := f.svc.RequestExport(ctx, userID)
+	assert.NoError(t, err)
+	_, err = f.svc.RequestExport(ctx, userID)
+	assert.ErrorIs(t, err, repository.ErrDataExportInProgress)
+
+	_, _, err = f.svc.OpenExport(ctx, userID, export.ID)
+	assert.ErrorIs(t, err, ErrDataExportNotReady)
+
+	ready, err := f.svc.ProcessExports(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ready)
+	assert.Equal(t, "data_export_ready", f.hub.events[len(f.hub.events)-1].event)
+
+	// Чужой архив не отдаётся
+	_, _, err = f.svc.OpenExport(ctx, uuid.New(), export.ID)
+	assert.ErrorIs(t, err, repository.ErrDataExportNotFound)
+
+	completed, file, err := f.svc.OpenExport(ctx, userID, export.ID)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	archive, err := zip.NewReader(file, *completed.FileSize)
+	assert.NoError(t, err)
+	contents := make(map[string]string)
+	for _, entry := range archive.File {
+		rc, err := entry.Open()
+		assert.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		assert.NoError(t, err)
+		rc.Close()
+		contents[entry.Name] = string(data)
+	}
+	assert.Contains(t, contents["profile.json"], userID.String())
+	assert.Contains(t, contents, "orders.json")
+	assert.Contains(t, contents["media.json"], avatar.ID.String())
+	assert.Equal(t, "avatar", contents["media/"+avatar.ID.String()+".jpg"])
+	assert.Equal(t, "passport", contents["media/"+passport.ID.String()+".jpg"])
+
+	// После истечения срока хранения архив удаляется из хранилища
+	expired := time.Now().Add(-time.Minute)
+	f.repo.exports[0].ExpiresAt = &expired
+	assert.NoError(t, f.svc.CleanupExports(ctx))
+	_, err = f.private.Open(ctx, *completed.FilePath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, _, err = f.svc.OpenExport(ctx, userID, export.ID)
+	assert.ErrorIs(t, err, ErrDataExportNotReady)
+}
+
+func TestPrivacyService_DeletionBlockedByOpenDeals(t *testing.T) {
+	f := setupPrivacy(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	f.repo.blockers[userID] = []string{models.DeletionBlockerEscrowHeld, models.DeletionBlockerDisputeOpen}
+
+	_, err := f.svc.RequestDeletion(ctx, userID)
+	var blocked *AccountDeletionBlockedError
+	assert.ErrorAs(t, err, &blocked)
+	assert.Equal(t, []string{models.DeletionBlockerEscrowHeld, models.DeletionBlockerDisputeOpen}, blocked.Blockers)
+	assert.Empty(t, f.repo.scheduled)
+
+	f.repo.blockers[userID] = nil
+	status, err := f.svc.RequestDeletion(ctx, userID)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(14*24*time.Hour), *status.ScheduledAt, time.Minute)
+	assert.Equal(t, "Запрос на удаление аккаунта", f.mailer.sent[len(f.mailer.sent)-1].Subject)
+
+	// До истечения периода отмены аккаунт не трогается
+	deleted, err := f.svc.ProcessDeletions(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	assert.NoError(t, f.svc.CancelDeletion(ctx, userID))
+	assert.ErrorIs(t, f.svc.CancelDeletion(ctx, userID), repository.ErrAccountDeletionNotScheduled)
+}
+
+type recordingRevoker struct {
+	suspended []uuid.UUID
+}
+
+func (r *recordingRevoker) SessionsRevoked(userID uuid.UUID, sessionIDs ...uuid.UUID) {}
+
+func (r *recordingRevoker) UserSuspended(userID uuid.UUID) {
+	r.suspended = append(r.suspended, userID)
+}
+
+func TestPrivacyService_ProcessDeletionsRemovesFiles(t *testing.T) {
+	f := setupPrivacy(t)
+	ctx := context.Background()
+	userID, blockedID := uuid.New(), uuid.New()
+	avatar := f.upload(t, userID, true, "avatar")
+	passport := f.upload(t, userID, false, "passport")
+
+	past := time.Now().Add(-time.Minute)
+	f.repo.scheduled[userID] = past
+	f.repo.scheduled[blockedID] = past
+	// За период отмены у второго пользователя появилась сделка в escrow
+	f.repo.blockers[blockedID] = []string{models.DeletionBlockerEscrowHeld}
+
+	revoker := &recordingRevoker{}
+	f.svc.SetSessionRevoker(revoker)
+
+	deleted, err := f.svc.ProcessDeletions(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.True(t, f.repo.anonymized[userID])
+	assert.Equal(t, []uuid.UUID{userID}, revoker.suspended)
+	assert.False(t, f.repo.anonymized[blockedID])
+	assert.Contains(t, f.repo.scheduled, blockedID)
+
+	_, err = f.public.Open(ctx, avatar.FilePath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = f.private.Open(ctx, passport.FilePath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, "Аккаунт удалён", f.mailer.sent[len(f.mailer.sent)-1].Subject)
+}
@@ -6,28 +6,53 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/ignatzorin/freelance-backend/internal/logger"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/repository"
 )
 
-var ErrInvalidReportTarget = errors.New("invalid target type")
+var (
+	ErrInvalidReportTarget   = errors.New("invalid target type")
+	ErrInvalidReportDecision = errors.New("status must be reviewed, action_taken or dismissed")
+	ErrReportActionRequired  = errors.New("action is required when status is action_taken")
+	ErrReportActionMismatch  = errors.New("action does not match the target type")
+)
 
 type ReportService struct {
-	repo *repository.ReportRepository
+	repo    *repository.ReportRepository
+	hub     WSNotifier
+	revoker SessionRevoker
+}
+
+// ReportTargetCase — объект жалоб вместе с необработанными жалобами на него.
+type ReportTargetCase struct {
+	models.ReportTarget
+	Reports []models.Report `json:"reports"`
+}
+
+// ReportDecision — решение модератора по объекту жалоб.
+type ReportDecision struct {
+	Status string
+	Action *string
+	Note   *string
 }
 
 func NewReportService(r *repository.ReportRepository) *ReportService {
 	return &ReportService{repo: r}
 }
 
+// SetHub устанавливает WebSocket hub для уведомления авторов жалоб.
+func (s *ReportService) SetHub(hub WSNotifier) {
+	s.hub = hub
+}
+
+// SetSessionRevoker устанавливает получателя уведомлений о блокировке пользователей.
+func (s *ReportService) SetSessionRevoker(revoker SessionRevoker) {
+	s.revoker = revoker
+}
+
 func (s *ReportService) CreateReport(ctx context.Context, reporterID uuid.UUID, targetType string, targetID uuid.UUID, reason string, description *string) (*models.Report, error) {
-	validTypes := map[string]bool{
-		models.ReportTargetUser:    true,
-		models.ReportTargetOrder:   true,
-		models.ReportTargetMessage: true,
-		models.ReportTargetReview:  true,
-	}
-	if !validTypes[targetType] {
+	if !isReportTarget(targetType) {
 		return nil, ErrInvalidReportTarget
 	}
 
@@ -51,3 +76,127 @@ func (s *ReportService) GetReport(ctx context.Context, id uuid.UUID) (*models.Re
 func (s *ReportService) ListMyReports(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Report, error) {
 	return s.repo.ListByReporter(ctx, userID, limit, offset)
 }
+
+// ModerationQueue возвращает очередь модерации: объекты с необработанными жалобами и их превью.
+func (s *ReportService) ModerationQueue(ctx context.Context, limit, offset int) ([]models.ReportTarget, error) {
+	targets, err := s.repo.ListPendingTargets(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i := range targets {
+		preview, err := s.repo.TargetPreview(ctx, targets[i].TargetType, targets[i].TargetID)
+		if err != nil {
+			return nil, err
+		}
+		targets[i].Preview = preview
+	}
+	return targets, nil
+}
+
+// GetTargetCase возвращает объект с превью и все необработанные жалобы на него.
+func (s *ReportService) GetTargetCase(ctx context.Context, targetType string, targetID uuid.UUID) (*ReportTargetCase, error) {
+	if !isReportTarget(targetType) {
+		return nil, ErrInvalidReportTarget
+	}
+	reports, err := s.repo.ListPendingByTarget(ctx, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, repository.ErrNoPendingReports
+	}
+	preview, err := s.repo.TargetPreview(ctx, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &ReportTargetCase{
+		ReportTarget: models.ReportTarget{
+			TargetType:      targetType,
+			TargetID:        targetID,
+			ReportCount:     len(reports),
+			FirstReportedAt: reports[0].CreatedAt,
+			LastReportedAt:  reports[len(reports)-1].CreatedAt,
+			Preview:         preview,
+		},
+		Reports: reports,
+	}
+	seen := make(map[string]struct{}, len(reports))
+	for _, r := range reports {
+		if _, ok := seen[r.Reason]; !ok {
+			seen[r.Reason] = struct{}{}
+			tc.Reasons = append(tc.Reasons, r.Reason)
+		}
+	}
+	return tc, nil
+}
+
+// ResolveTarget закрывает жалобы на объект решением модератора и уведомляет авторов жалоб.
+func (s *ReportService) ResolveTarget(ctx context.Context, moderatorID uuid.UUID, targetType string, targetID uuid.UUID, d ReportDecision) ([]models.Report, error) {
+	if err := validateReportDecision(targetType, d); err != nil {
+		return nil, err
+	}
+
+	reports, err := s.repo.ResolveTarget(ctx, targetType, targetID, moderatorID, d.Status, d.Action, d.Note)
+	if err != nil {
+		return nil, err
+	}
+	if d.Action != nil && *d.Action == models.ReportActionSuspendUser && s.revoker != nil {
+		s.revoker.UserSuspended(targetID)
+	}
+	s.notifyReporters(reports)
+	return reports, nil
+}
+
+// validateReportDecision проверяет, что статус допустим, а мера указана и подходит к типу объекта.
+func validateReportDecision(targetType string, d ReportDecision) error {
+	if !isReportTarget(targetType) {
+		return ErrInvalidReportTarget
+	}
+	switch d.Status {
+	case models.ReportStatusActionTaken:
+		if d.Action == nil {
+			return ErrReportActionRequired
+		}
+		if models.ReportActionTargets[*d.Action] != targetType {
+			return ErrReportActionMismatch
+		}
+	case models.ReportStatusReviewed, models.ReportStatusDismissed:
+		if d.Action != nil {
+			return ErrReportActionMismatch
+		}
+	default:
+		return ErrInvalidReportDecision
+	}
+	return nil
+}
+
+func isReportTarget(targetType string) bool {
+	switch targetType {
+	case models.ReportTargetUser, models.ReportTargetOrder, models.ReportTargetMessage, models.ReportTargetReview:
+		return true
+	}
+	return false
+}
+
+// notifyReporters сообщает каждому автору жалобы о принятом решении.
+func (s *ReportService) notifyReporters(reports []models.Report) {
+	if s.hub == nil {
+		return
+	}
+	for _, r := range reports {
+		payload := map[string]interface{}{
+			"report_id":   r.ID,
+			"target_type": r.TargetType,
+			"target_id":   r.TargetID,
+			"status":      r.Status,
+			"action":      r.Action,
+		}
+		if err := s.hub.BroadcastToUser(r.ReporterID, "report_resolved", payload); err != nil && logger.Log != nil {
+			logger.Log.WithFields(map[string]interface{}{
+				"report_id": r.ID,
+				"error":     err.Error(),
+			}).Warn("report service: не удалось отправить уведомление")
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+func TestValidateReportDecision(t *testing.T) {
+	hide := models.ReportActionHideMessage
+	suspend := models.ReportActionSuspendUser
+
+	tests := []struct {
+		name       string
+		targetType string
+		decision   ReportDecision
+		want       error
+	}{
+		{"dismiss", models.ReportTargetMessage, ReportDecision{Status: models.ReportStatusDismissed}, nil},
+		{"reviewed without action", models.ReportTargetOrder, ReportDecision{Status: models.ReportStatusReviewed}, nil},
+		{"hide message", models.ReportTargetMessage, ReportDecision{Status: models.ReportStatusActionTaken, Action: &hide}, nil},
+		{"action required", models.ReportTargetMessage, ReportDecision{Status: models.ReportStatusActionTaken}, ErrReportActionRequired},
+		{"action for other target", models.ReportTargetMessage, ReportDecision{Status: models.ReportStatusActionTaken, Action: &suspend}, ErrReportActionMismatch},
+		{"action with dismiss", models.ReportTargetUser, ReportDecision{Status: models.ReportStatusDismissed, Action: &suspend}, ErrReportActionMismatch},
+		{"pending is not a decision", models.ReportTargetUser, ReportDecision{Status: models.ReportStatusPending}, ErrInvalidReportDecision},
+		{"unknown target", "comment", ReportDecision{Status: models.ReportStatusDismissed}, ErrInvalidReportTarget},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validateReportDecision(tt.targetType, tt.decision))
+		})
+	}
+}
+
+func TestReportService_ResolveTarget_ValidatesBeforeRepository(t *testing.T) {
+	svc := NewReportService(nil)
+
+	_, err := svc.ResolveTarget(context.Background(), uuid.New(), models.ReportTargetReview, uuid.New(), ReportDecision{Status: models.ReportStatusActionTaken})
+
+	assert.ErrorIs(t, err, ErrReportActionRequired)
+}
@@ -8,6 +8,7 @@ import (
 
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/repository"
 )
@@ -299,10 +300,10 @@ func (s *SeedService) generateOrders(ctx context.Context, clients []*models.User
 		title := titles[rand.Intn(len(titles))]
 		description := descriptions[rand.Intn(len(descriptions))]
 
-		var budgetMin, budgetMax *float64
+		var budgetMin, budgetMax *valueobject.Amount
 		if rand.Float32() > 0.2 { // 80% заказов с бюджетом
-			min := float64(rand.Intn(50000)+10000) / 100.0      // 100-600 USD
-			max := min + float64(rand.Intn(100000)+20000)/100.0 // +200-1200 USD
+			min := valueobject.Amount(rand.Intn(50000) + 10000)      // 100-600 USD
+			max := min + valueobject.Amount(rand.Intn(100000)+20000) // +200-1200 USD
 			budgetMin = &min
 			budgetMax = &max
 		}
@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/repository"
 )
@@ -190,8 +191,8 @@ func (s *ExtendedSeedService) SeedRealisticData(ctx context.Context) (*ExtendedS
 
 	// 2. Пополняем балансы клиентов
 	for _, client := range clients {
-		amount := float64(rand.Intn(100000) + 50000)
-		s.paymentRepo.Deposit(ctx, client.ID, amount, "Пополнение баланса")
+		amount := valueobject.AmountFromFloat(float64(rand.Intn(100000) + 50000))
+		s.paymentRepo.Deposit(ctx, client.ID, amount, models.DefaultCurrency, "Пополнение баланса")
 	}
 
 	// 3. Создаём шаблоны откликов для фрилансеров
@@ -317,8 +318,8 @@ func (s *ExtendedSeedService) createOrders(ctx context.Context, clients []*model
 		title := orderTitles[rand.Intn(len(orderTitles))]
 		desc := orderDescriptions[rand.Intn(len(orderDescriptions))]
 
-		budgetMin := float64(10000 + rand.Intn(40000))
-		budgetMax := budgetMin + float64(rand.Intn(30000))
+		budgetMin := valueobject.AmountFromFloat(float64(10000 + rand.Intn(40000)))
+		budgetMax := budgetMin + valueobject.AmountFromFloat(float64(rand.Intn(30000)))
 		deadline := time.Now().Add(time.Duration(7+rand.Intn(30)) * 24 * time.Hour)
 
 		order := &models.Order{
@@ -362,7 +363,7 @@ func (s *ExtendedSeedService) createProposalsAndAccept(ctx context.Context, orde
 			}
 			usedFreelancers[f.ID] = true
 
-			price := *order.BudgetMin + float64(rand.Intn(int(*order.BudgetMax-*order.BudgetMin)))
+			price := *order.BudgetMin + valueobject.Amount(rand.Int63n(int64(*order.BudgetMax-*order.BudgetMin)))
 			text := proposalTexts[rand.Intn(len(proposalTexts))]
 
 			proposal := &models.Proposal{
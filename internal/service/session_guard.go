@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+// ErrAccountSuspended возвращается, если аккаунт пользователя заблокирован.
+var ErrAccountSuspended = errors.New("auth service: аккаунт заблокирован")
+
+// SessionStatusRepository описывает зависимости SessionGuard от слоя хранилища.
+type SessionStatusRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	SessionExists(ctx context.Context, sessionID, userID uuid.UUID) (bool, error)
+}
+
+// SessionRevoker получает уведомления об отзыве сессий и блокировке пользователей.
+type SessionRevoker interface {
+	SessionsRevoked(userID uuid.UUID, sessionIDs ...uuid.UUID)
+	UserSuspended(userID uuid.UUID)
+}
+
+// WSDisconnector закрывает WebSocket соединения пользователя.
+type WSDisconnector interface {
+	DisconnectSession(userID, sessionID uuid.UUID)
+	DisconnectUser(userID uuid.UUID)
+}
+
+// SessionGuard проверяет, что сессия access токена не отозвана и пользователь не заблокирован.
+// Результаты кэшируются на ttl; изменения, сделанные этим процессом, сбрасывают кэш сразу,
+// изменения с других реплик вступают в силу не позже чем через ttl.
+type SessionGuard struct {
+	repo  SessionStatusRepository
+	cache *CacheService
+	ttl   time.Duration
+	hub   WSDisconnector
+}
+
+// NewSessionGuard создаёт проверку сессий.
+func NewSessionGuard(repo SessionStatusRepository, cache *CacheService, ttl time.Duration) *SessionGuard {
+	return &SessionGuard{repo: repo, cache: cache, ttl: ttl}
+}
+
+// SetHub устанавливает WebSocket hub, соединения которого закрываются при отзыве сессии.
+func (g *SessionGuard) SetHub(hub WSDisconnector) {
+	g.hub = hub
+}
+
+// Check возвращает ErrAccountSuspended или ErrSessionRevoked, если токен больше не должен приниматься.
+func (g *SessionGuard) Check(ctx context.Context, claims *AccessClaims) error {
+	active, err := g.cached(userStatusKey(claims.UserID), func() (bool, error) {
+		user, err := g.repo.GetByID(ctx, claims.UserID)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return user.IsActive, nil
+	})
+	if err != nil {
+		return err
+	}
+	if !active {
+		return ErrAccountSuspended
+	}
+
+	// Токены без sid выпущены до появления клейма и доживают свой короткий срок
+	if claims.SessionID == uuid.Nil {
+		return nil
+	}
+	exists, err := g.cached(sessionStatusKey(claims.SessionID), func() (bool, error) {
+		return g.repo.SessionExists(ctx, claims.SessionID, claims.UserID)
+	})
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// SessionsRevoked сбрасывает кэш отозванных сессий и закрывает их WebSocket соединения.
+func (g *SessionGuard) SessionsRevoked(userID uuid.UUID, sessionIDs ...uuid.UUID) {
+	for _, sessionID := range sessionIDs {
+		g.cache.Delete(sessionStatusKey(sessionID))
+		if g.hub != nil {
+			g.hub.DisconnectSession(userID, sessionID)
+		}
+	}
+}
+
+// UserSuspended сбрасывает кэш заблокированного пользователя и закрывает все его соединения.
+func (g *SessionGuard) UserSuspended(userID uuid.UUID) {
+	g.cache.Delete(userStatusKey(userID))
+	if g.hub != nil {
+		g.hub.DisconnectUser(userID)
+	}
+}
+
+func (g *SessionGuard) cached(key string, load func() (bool, error)) (bool, error) {
+	if value, ok := g.cache.Get(key); ok {
+		if result, ok := value.(bool); ok {
+			return result, nil
+		}
+	}
+	result, err := load()
+	if err != nil {
+		return false, fmt.Errorf("session guard: %w", err)
+	}
+	g.cache.Set(key, result, g.ttl)
+	return result, nil
+}
+
+func userStatusKey(userID uuid.UUID) string {
+	return "auth:user_active:" + userID.String()
+}
+
+func sessionStatusKey(sessionID uuid.UUID) string {
+	return "auth:session_active:" + sessionID.String()
+}
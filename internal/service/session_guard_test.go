@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedDisconnects struct {
+	sessions []uuid.UUID
+	users    []uuid.UUID
+}
+
+func (d *recordedDisconnects) DisconnectSession(userID, sessionID uuid.UUID) {
+	d.sessions = append(d.sessions, sessionID)
+}
+
+func (d *recordedDisconnects) DisconnectUser(userID uuid.UUID) {
+	d.users = append(d.users, userID)
+}
+
+func TestSessionGuard_DeletedSessionRejectedImmediately(t *testing.T) {
+	repo := newMockAuthRepository()
+	tokenManager := NewTokenManager("access-secret", "refresh-secret", time.Minute, time.Hour)
+	auth := NewAuthService(repo, tokenManager)
+	guard := NewSessionGuard(repo, NewCacheService(), time.Hour)
+	disconnects := &recordedDisconnects{}
+	guard.SetHub(disconnects)
+	auth.SetSessionRevoker(guard)
+
+	ctx := context.Background()
+	res, err := auth.Register(ctx, RegisterInput{Email: "guard@example.com", Password: "password123"}, nil)
+	assert.NoError(t, err)
+
+	claims, err := tokenManager.ParseAccess(res.TokenPair.AccessToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, claims.SessionID)
+	assert.NoError(t, guard.Check(ctx, claims))
+
+	// Результат закэширован на час, но удаление сессии сбрасывает кэш
+	assert.NoError(t, auth.DeleteSession(ctx, claims.SessionID, claims.UserID))
+	assert.ErrorIs(t, guard.Check(ctx, claims), ErrSessionRevoked)
+	assert.Equal(t, []uuid.UUID{claims.SessionID}, disconnects.sessions)
+}
+
+func TestSessionGuard_SuspendedUserRejected(t *testing.T) {
+	repo := newMockAuthRepository()
+	tokenManager := NewTokenManager("access-secret", "refresh-secret", time.Minute, time.Hour)
+	auth := NewAuthService(repo, tokenManager)
+	guard := NewSessionGuard(repo, NewCacheService(), time.Hour)
+	disconnects := &recordedDisconnects{}
+	guard.SetHub(disconnects)
+
+	ctx := context.Background()
+	res, err := auth.Register(ctx, RegisterInput{Email: "suspended@example.com", Password: "password123"}, nil)
+	assert.NoError(t, err)
+	claims, err := tokenManager.ParseAccess(res.TokenPair.AccessToken)
+	assert.NoError(t, err)
+	assert.NoError(t, guard.Check(ctx, claims))
+
+	repo.usersByID[res.User.ID].IsActive = false
+	assert.NoError(t, guard.Check(ctx, claims), "до уведомления действует кэш")
+
+	guard.UserSuspended(res.User.ID)
+	assert.ErrorIs(t, guard.Check(ctx, claims), ErrAccountSuspended)
+	assert.Equal(t, []uuid.UUID{res.User.ID}, disconnects.users)
+
+	_, err = auth.Refresh(ctx, res.TokenPair.RefreshToken, nil)
+	assert.ErrorIs(t, err, ErrAccountSuspended)
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ignatzorin/freelance-backend/internal/logger"
+)
+
+// ErrTelegramNotLinked возвращается, если в профиле нет chat id Telegram, по которому бот может написать.
+var ErrTelegramNotLinked = errors.New("verification: в профиле не указан Telegram chat id")
+
+// PhoneMessage — сообщение с кодом подтверждения телефона.
+type PhoneMessage struct {
+	// Phone — подтверждаемый номер в формате E.164.
+	Phone string
+	// Telegram — значение profiles.telegram; используется провайдером Telegram.
+	Telegram string
+	Text     string
+}
+
+// SMSSender доставляет коды подтверждения телефона.
+type SMSSender interface {
+	SendSMS(ctx context.Context, msg PhoneMessage) error
+}
+
+// HTTPSMSSender отправляет SMS через HTTP шлюз: POST {"to", "text", "sender"} с Bearer ключом.
+type HTTPSMSSender struct {
+	url    string
+	apiKey string
+	sender string
+	client *http.Client
+}
+
+// NewHTTPSMSSender создаёт отправителя через SMS шлюз; sender — имя отправителя, может быть пустым.
+func NewHTTPSMSSender(url, apiKey, sender string) *HTTPSMSSender {
+	return &HTTPSMSSender{
+		url:    url,
+		apiKey: apiKey,
+		sender: sender,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *HTTPSMSSender) SendSMS(ctx context.Context, msg PhoneMessage) error {
+	payload, err := json.Marshal(map[string]string{
+		"to":     msg.Phone,
+		"text":   msg.Text,
+		"sender": s.sender,
+	})
+	if err != nil {
+		return fmt.Errorf("sms gateway: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("sms gateway: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sms gateway: status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// TelegramSMSSender отправляет код сообщением от Telegram бота. Бот может написать только тем,
+// кто начал с ним диалог, и только по числовому chat id, поэтому в profiles.telegram должен быть chat id.
+// Номер телефона бот узнаёт, только когда владелец чата сам делится контактом (см. RequestContact).
+type TelegramSMSSender struct {
+	apiURL string
+	client *http.Client
+}
+
+// NewTelegramSMSSender создаёт отправителя через Telegram Bot API.
+func NewTelegramSMSSender(botToken string) *TelegramSMSSender {
+	return &TelegramSMSSender{
+		apiURL: "https://api.telegram.org/bot" + botToken + "/sendMessage",
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *TelegramSMSSender) SendSMS(ctx context.Context, msg PhoneMessage) error {
+	if !isTelegramChatID(msg.Telegram) {
+		return ErrTelegramNotLinked
+	}
+	return s.sendMessage(ctx, map[string]interface{}{
+		"chat_id": msg.Telegram,
+		"text":    msg.Text,
+	})
+}
+
+// RequestContact отправляет в чат text с кнопкой, которая делится с ботом номером телефона
+// владельца чата.
+func (s *TelegramSMSSender) RequestContact(ctx context.Context, chatID int64, text string) error {
+	return s.sendMessage(ctx, map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+		"reply_markup": map[string]interface{}{
+			"keyboard":          [][]map[string]interface{}{{{"text": "Отправить номер", "request_contact": true}}},
+			"one_time_keyboard": true,
+			"resize_keyboard":   true,
+		},
+	})
+}
+
+// Reply отправляет в чат text и убирает клавиатуру с кнопкой контакта.
+func (s *TelegramSMSSender) Reply(ctx context.Context, chatID int64, text string) error {
+	return s.sendMessage(ctx, map[string]interface{}{
+		"chat_id":      chatID,
+		"text":         text,
+		"reply_markup": map[string]bool{"remove_keyboard": true},
+	})
+}
+
+func (s *TelegramSMSSender) sendMessage(ctx context.Context, message map[string]interface{}) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("telegram: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("telegram: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Ошибка транспорта содержит URL с токеном бота
+		return errors.New("telegram: запрос к Bot API не выполнен")
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&result); err != nil {
+		return fmt.Errorf("telegram: status %d: %w", resp.StatusCode, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram: %s", result.Description)
+	}
+	return nil
+}
+
+// FakeSMSSender локальный отправитель для разработки и тестов: ничего не отправляет,
+// а запоминает сообщения и пишет их в лог.
+type FakeSMSSender struct {
+	mu   sync.Mutex
+	sent []PhoneMessage
+}
+
+// NewFakeSMSSender создаёт локального отправителя.
+func NewFakeSMSSender() *FakeSMSSender {
+	return &FakeSMSSender{}
+}
+
+func (s *FakeSMSSender) SendSMS(ctx context.Context, msg PhoneMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.sent = append(s.sent, msg)
+	s.mu.Unlock()
+
+	if logger.Log != nil {
+		logger.Log.WithFields(map[string]interface{}{
+			"phone": msg.Phone,
+			"text":  msg.Text,
+		}).Info("sms: сообщение не отправлено, используется локальный отправитель")
+	}
+	return nil
+}
+
+// Sent возвращает копию отправленных сообщений.
+func (s *FakeSMSSender) Sent() []PhoneMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]PhoneMessage(nil), s.sent...)
+}
+
+func isTelegramChatID(value string) bool {
+	_, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	return err == nil
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -16,10 +19,19 @@ type TokenPair struct {
 	ExpiresIn    time.Duration `json:"expires_in"`
 }
 
+// AccessClaims содержит данные, извлечённые из access токена.
+type AccessClaims struct {
+	UserID uuid.UUID
+	Role   string
+	// SessionID пуст у токенов, выпущенных до появления клейма sid.
+	SessionID uuid.UUID
+}
+
 // TokenManager отвечает за выпуск и проверку JWT.
 type TokenManager struct {
 	accessSecret  []byte
 	refreshSecret []byte
+	actionSecret  []byte
 	accessTTL     time.Duration
 	refreshTTL    time.Duration
 }
@@ -29,18 +41,19 @@ func NewTokenManager(accessSecret, refreshSecret string, accessTTL, refreshTTL t
 	return &TokenManager{
 		accessSecret:  []byte(accessSecret),
 		refreshSecret: []byte(refreshSecret),
+		actionSecret:  deriveSecret(accessSecret, "action-token"),
 		accessTTL:     accessTTL,
 		refreshTTL:    refreshTTL,
 	}
 }
 
-// GeneratePair выпускает новую пару токенов.
-func (m *TokenManager) GeneratePair(user *models.User) (*TokenPair, time.Time, time.Time, error) {
+// GeneratePair выпускает новую пару токенов для сессии sessionID.
+func (m *TokenManager) GeneratePair(user *models.User, sessionID uuid.UUID) (*TokenPair, time.Time, time.Time, error) {
 	now := time.Now()
 	accessExp := now.Add(m.accessTTL)
 	refreshExp := now.Add(m.refreshTTL)
 
-	accessToken, err := m.createToken(user, accessExp, m.accessSecret)
+	accessToken, err := m.createToken(user, sessionID, accessExp, m.accessSecret)
 	if err != nil {
 		return nil, time.Time{}, time.Time{}, err
 	}
@@ -57,6 +70,11 @@ func (m *TokenManager) GeneratePair(user *models.User) (*TokenPair, time.Time, t
 	}, accessExp, refreshExp, nil
 }
 
+// GenerateAccess выпускает только access токен, например после смены роли.
+func (m *TokenManager) GenerateAccess(user *models.User, sessionID uuid.UUID) (string, error) {
+	return m.createToken(user, sessionID, time.Now().Add(m.accessTTL), m.accessSecret)
+}
+
 // ParseRefresh проверяет refresh токен и возвращает клеймы.
 func (m *TokenManager) ParseRefresh(token string) (*jwt.RegisteredClaims, error) {
 	parsed, err := jwt.ParseWithClaims(token, &jwt.RegisteredClaims{}, func(t *jwt.Token) (interface{}, error) {
@@ -73,39 +91,78 @@ func (m *TokenManager) ParseRefresh(token string) (*jwt.RegisteredClaims, error)
 	return nil, jwt.ErrTokenInvalidClaims
 }
 
-// ParseAccess извлекает userID и роль из access токена.
-func (m *TokenManager) ParseAccess(token string) (uuid.UUID, string, error) {
+// ParseAccess извлекает userID, роль и сессию из access токена.
+func (m *TokenManager) ParseAccess(token string) (*AccessClaims, error) {
 	parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
 		return m.accessSecret, nil
 	})
-	if err != nil || !parsed.Valid {
-		return uuid.Nil, "", err
+	if err != nil {
+		return nil, err
+	}
+	if !parsed.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
 	}
 
 	claims, ok := parsed.Claims.(jwt.MapClaims)
 	if !ok {
-		return uuid.Nil, "", jwt.ErrTokenInvalidClaims
+		return nil, jwt.ErrTokenInvalidClaims
 	}
 
 	sub, ok := claims["sub"].(string)
 	if !ok {
-		return uuid.Nil, "", jwt.ErrTokenInvalidClaims
+		return nil, jwt.ErrTokenInvalidClaims
 	}
 
 	role, _ := claims["role"].(string)
 
 	userID, err := uuid.Parse(sub)
 	if err != nil {
-		return uuid.Nil, "", err
+		return nil, err
+	}
+
+	result := &AccessClaims{UserID: userID, Role: role}
+	if sid, ok := claims["sid"].(string); ok {
+		if result.SessionID, err = uuid.Parse(sid); err != nil {
+			return nil, jwt.ErrTokenInvalidClaims
+		}
 	}
 
-	return userID, role, nil
+	return result, nil
+}
+
+// GenerateActionToken подписывает короткоживущий токен действия: идентификатор (запись
+// user_action_tokens для ссылок из писем, пользователь для второго шага входа) и назначение.
+// Подпись отдельным ключом не даёт выдать его за access токен, назначение — за токен другого действия.
+func (m *TokenManager) GenerateActionToken(id uuid.UUID, purpose string, exp time.Time) (string, error) {
+	claims := jwt.RegisteredClaims{
+		ID:        id.String(),
+		Audience:  jwt.ClaimStrings{purpose},
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(exp),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(m.actionSecret)
+}
+
+// ParseActionToken проверяет подпись, срок и назначение ссылки и возвращает идентификатор записи.
+func (m *TokenManager) ParseActionToken(token, purpose string) (uuid.UUID, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return m.actionSecret, nil
+	}, jwt.WithAudience(purpose), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return uuid.Parse(claims.ID)
 }
 
 // createToken формирует access токен.
-func (m *TokenManager) createToken(user *models.User, exp time.Time, secret []byte) (string, error) {
+func (m *TokenManager) createToken(user *models.User, sessionID uuid.UUID, exp time.Time, secret []byte) (string, error) {
 	claims := jwt.MapClaims{
 		"sub":  user.ID.String(),
+		"sid":  sessionID.String(),
 		"role": user.Role,
 		"iat":  time.Now().Unix(),
 		"exp":  exp.Unix(),
@@ -127,3 +184,16 @@ func (m *TokenManager) createRefreshToken(user *models.User, exp time.Time) (str
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(m.refreshSecret)
 }
+
+// deriveSecret выводит из секрета отдельный ключ для другого вида токенов.
+func deriveSecret(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// HashRefreshToken возвращает SHA-256 refresh токена в hex: в базе хранится только хеш.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+const (
+	recoveryCodeCount = 10
+	// После maxTwoFactorFailures неверных кодов подряд проверка блокируется на twoFactorLockout
+	maxTwoFactorFailures = 5
+	twoFactorLockout     = 15 * time.Minute
+)
+
+var (
+	// ErrTwoFactorRequired возвращается, если действие требует кода из приложения-аутентификатора.
+	ErrTwoFactorRequired = errors.New("auth service: требуется код двухфакторной аутентификации")
+	// ErrTwoFactorInvalidCode возвращается для неверного, устаревшего или уже использованного кода.
+	ErrTwoFactorInvalidCode = errors.New("auth service: неверный код двухфакторной аутентификации")
+	// ErrTwoFactorLocked возвращается после серии неверных кодов.
+	ErrTwoFactorLocked = errors.New("auth service: слишком много неверных кодов, попробуйте через 15 минут")
+	// ErrTwoFactorNotEnabled возвращается, если двухфакторная аутентификация не подключена.
+	ErrTwoFactorNotEnabled = errors.New("auth service: двухфакторная аутентификация не подключена")
+	// ErrTwoFactorAlreadyEnabled возвращается при повторном подключении.
+	ErrTwoFactorAlreadyEnabled = errors.New("auth service: двухфакторная аутентификация уже подключена")
+)
+
+// TwoFactorRepository описывает зависимости TwoFactorService от слоя хранилища.
+type TwoFactorRepository interface {
+	GetTOTP(ctx context.Context, userID uuid.UUID) (*models.UserTOTP, error)
+	SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error
+	EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, userID uuid.UUID) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+// UserRepositoryForTwoFactor загружает пользователя для проверки пароля и подписи в приложении.
+type UserRepositoryForTwoFactor interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+// TwoFactorSetup — данные для подключения приложения-аутентификатора.
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// TwoFactorStatus — состояние двухфакторной аутентификации пользователя.
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// TwoFactorService управляет TOTP: подключение, проверка кодов при входе и перед чувствительными
+// действиями, коды восстановления.
+type TwoFactorService struct {
+	repo     TwoFactorRepository
+	users    UserRepositoryForTwoFactor
+	box      *secretBox
+	issuer   string
+	cache    *CacheService
+	failures sync.Mutex
+}
+
+// NewTwoFactorService создаёт сервис; encryptionKey шифрует TOTP секреты в базе, issuer
+// отображается в приложении-аутентификаторе.
+func NewTwoFactorService(repo TwoFactorRepository, users UserRepositoryForTwoFactor, cache *CacheService, encryptionKey, issuer string) (*TwoFactorService, error) {
+	box, err := newSecretBox(encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("two factor service: %w", err)
+	}
+	return &TwoFactorService{repo: repo, users: users, box: box, issuer: issuer, cache: cache}, nil
+}
+
+// Status возвращает состояние двухфакторной аутентификации.
+func (s *TwoFactorService) Status(ctx context.Context, userID uuid.UUID) (*TwoFactorStatus, error) {
+	totp, err := s.repo.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrTOTPNotFound) || (err == nil && totp.EnabledAt == nil) {
+		return &TwoFactorStatus{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	left, err := s.repo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorStatus{Enabled: true, EnabledAt: totp.EnabledAt, RecoveryCodesLeft: left}, nil
+}
+
+// Enabled сообщает, требуется ли пользователю второй фактор.
+func (s *TwoFactorService) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	totp, err := s.repo.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrTOTPNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return totp.EnabledAt != nil, nil
+}
+
+// Setup создаёт новый секрет. Второй фактор начинает действовать только после Enable.
+func (s *TwoFactorService) Setup(ctx context.Context, userID uuid.UUID) (*TwoFactorSetup, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("two factor service: %w", err)
+	}
+	sealed, err := s.box.seal(secret)
+	if err != nil {
+		return nil, fmt.Errorf("two factor service: %w", err)
+	}
+	if err := s.repo.SaveTOTPSecret(ctx, userID, sealed); err != nil {
+		if errors.Is(err, repository.ErrTOTPAlreadyEnabled) {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return &TwoFactorSetup{Secret: secret, OTPAuthURI: totpURI(s.issuer, user.Email, secret)}, nil
+}
+
+// Enable подтверждает подключение первым кодом из приложения и возвращает коды восстановления.
+// Коды показываются один раз и в базе хранятся только их хеши.
+func (s *TwoFactorService) Enable(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	totp, err := s.repo.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrTOTPNotFound) {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if totp.EnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, err := s.matchCode(ctx, totp, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, repository.ErrTOTPAlreadyEnabled) {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable отключает второй фактор; нужны пароль и код из приложения или код восстановления.
+func (s *TwoFactorService) Disable(ctx context.Context, userID uuid.UUID, password, code string) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return ErrWrongPassword
+	}
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.repo.DeleteTOTP(ctx, userID)
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления; прежние перестают действовать.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	totp, err := s.enabledTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.useCode(ctx, totp, strings.TrimSpace(code)); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify проверяет второй фактор при входе: код из приложения или код восстановления.
+func (s *TwoFactorService) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	totp, err := s.enabledTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totpDigits {
+		return s.useCode(ctx, totp, code)
+	}
+
+	if err := s.checkLockout(userID); err != nil {
+		return err
+	}
+	used, err := s.repo.UseRecoveryCode(ctx, userID, HashRefreshToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return s.registerFailure(userID)
+	}
+	s.resetFailures(userID)
+	return nil
+}
+
+// RequireFresh проверяет код из приложения перед чувствительным действием. Для пользователей
+// без двухфакторной аутентификации ничего не требует. Коды восстановления не принимаются.
+func (s *TwoFactorService) RequireFresh(ctx context.Context, userID uuid.UUID, code string) error {
+	totp, err := s.enabledTOTP(ctx, userID)
+	if errors.Is(err, ErrTwoFactorNotEnabled) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(code) == "" {
+		return ErrTwoFactorRequired
+	}
+	return s.useCode(ctx, totp, strings.TrimSpace(code))
+}
+
+func (s *TwoFactorService) enabledTOTP(ctx context.Context, userID uuid.UUID) (*models.UserTOTP, error) {
+	totp, err := s.repo.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrTOTPNotFound) || (err == nil && totp.EnabledAt == nil) {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return totp, err
+}
+
+// useCode принимает код из приложения один раз: интервал кода запоминается.
+func (s *TwoFactorService) useCode(ctx context.Context, totp *models.UserTOTP, code string) error {
+	step, err := s.matchCode(ctx, totp, code)
+	if err != nil {
+		return err
+	}
+	fresh, err := s.repo.UseTOTPStep(ctx, totp.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return s.registerFailure(totp.UserID)
+	}
+	return nil
+}
+
+// matchCode сверяет код с секретом с учётом блокировки после серии ошибок.
+func (s *TwoFactorService) matchCode(ctx context.Context, totp *models.UserTOTP, code string) (int64, error) {
+	if err := s.checkLockout(totp.UserID); err != nil {
+		return 0, err
+	}
+	secret, err := s.box.open(totp.Secret)
+	if err != nil {
+		return 0, fmt.Errorf("two factor service: не удалось расшифровать секрет: %w", err)
+	}
+	step, ok := matchTOTP(secret, code, time.Now())
+	if !ok || step <= totp.LastUsedStep {
+		return 0, s.registerFailure(totp.UserID)
+	}
+	s.resetFailures(totp.UserID)
+	return step, nil
+}
+
+func (s *TwoFactorService) checkLockout(userID uuid.UUID) error {
+	if failures, ok := s.cache.Get(twoFactorFailuresKey(userID)); ok && failures.(int) >= maxTwoFactorFailures {
+		return ErrTwoFactorLocked
+	}
+	return nil
+}
+
+func (s *TwoFactorService) registerFailure(userID uuid.UUID) error {
+	s.failures.Lock()
+	defer s.failures.Unlock()
+	failures := 0
+	if value, ok := s.cache.Get(twoFactorFailuresKey(userID)); ok {
+		failures = value.(int)
+	}
+	s.cache.Set(twoFactorFailuresKey(userID), failures+1, twoFactorLockout)
+	return ErrTwoFactorInvalidCode
+}
+
+func (s *TwoFactorService) resetFailures(userID uuid.UUID) {
+	s.cache.Delete(twoFactorFailuresKey(userID))
+}
+
+func twoFactorFailuresKey(userID uuid.UUID) string {
+	return "auth:2fa_failures:" + userID.String()
+}
+
+// newRecoveryCodes возвращает коды восстановления вида xxxxx-xxxxx и их хеши.
+func newRecoveryCodes() ([]string, []string, error) {
+	// 32 символа: каждый байт даёт ровно 5 случайных бит без смещения
+	const alphabet = "abcdefghijklmnopqrstuvwxyz234567"
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	raw := make([]byte, 10)
+	for i := range codes {
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("two factor service: %w", err)
+		}
+		var b strings.Builder
+		for j, v := range raw {
+			if j == 5 {
+				b.WriteByte('-')
+			}
+			b.WriteByte(alphabet[v&31])
+		}
+		codes[i] = b.String()
+		hashes[i] = HashRefreshToken(normalizeRecoveryCode(codes[i]))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode убирает дефисы, пробелы и регистр, чтобы код можно было ввести как удобно.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+type mockTwoFactorRepository struct {
+	totp     map[uuid.UUID]*models.UserTOTP
+	recovery map[uuid.UUID]map[string]bool
+}
+
+func newMockTwoFactorRepository() *mockTwoFactorRepository {
+	return &mockTwoFactorRepository{
+		totp:     make(map[uuid.UUID]*models.UserTOTP),
+		recovery: make(map[uuid.UUID]map[string]bool),
+	}
+}
+
+func (m *mockTwoFactorRepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*models.UserTOTP, error) {
+	totp, ok := m.totp[userID]
+	if !ok {
+		return nil, repository.ErrTOTPNotFound
+	}
+	copied := *totp
+	return &copied, nil
+}
+
+func (m *mockTwoFactorRepository) SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	if totp, ok := m.totp[userID]; ok && totp.EnabledAt != nil {
+		return repository.ErrTOTPAlreadyEnabled
+	}
+	m.totp[userID] = &models.UserTOTP{UserID: userID, Secret: secret, CreatedAt: time.Now()}
+	return nil
+}
+
+func (m *mockTwoFactorRepository) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, hashes []string) error {
+	now := time.Now()
+	m.totp[userID].EnabledAt = &now
+	m.totp[userID].LastUsedStep = step
+	return m.ReplaceRecoveryCodes(ctx, userID, hashes)
+}
+
+func (m *mockTwoFactorRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	totp := m.totp[userID]
+	if totp == nil || totp.EnabledAt == nil || totp.LastUsedStep >= step {
+		return false, nil
+	}
+	totp.LastUsedStep = step
+	return true, nil
+}
+
+func (m *mockTwoFactorRepository) DeleteTOTP(ctx context.Context, userID uuid.UUID) error {
+	delete(m.totp, userID)
+	delete(m.recovery, userID)
+	return nil
+}
+
+func (m *mockTwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	m.recovery[userID] = make(map[string]bool)
+	for _, hash := range hashes {
+		m.recovery[userID][hash] = false
+	}
+	return nil
+}
+
+func (m *mockTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error) {
+	used, ok := m.recovery[userID][hash]
+	if !ok || used {
+		return false, nil
+	}
+	m.recovery[userID][hash] = true
+	return true, nil
+}
+
+func (m *mockTwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	var left int
+	for _, used := range m.recovery[userID] {
+		if !used {
+			left++
+		}
+	}
+	return left, nil
+}
+
+func TestTOTPCode_RFC6238Vector(t *testing.T) {
+	// Секрет "12345678901234567890" из приложения B RFC 6238; T = 59 с — интервал 1
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	code, err := totpCode(secret, totpStep(time.Unix(59, 0)))
+	assert.NoError(t, err)
+	assert.Equal(t, "287082", code)
+}
+
+type twoFactorFixture struct {
+	auth      *AuthService
+	twoFactor *TwoFactorService
+	repo      *mockTwoFactorRepository
+	userID    uuid.UUID
+	secret    string
+	recovery  []string
+}
+
+// setupTwoFactor регистрирует пользователя и подключает ему TOTP.
+func setupTwoFactor(t *testing.T) *twoFactorFixture {
+	t.Helper()
+	users := newMockAuthRepository()
+	tokenManager := NewTokenManager("access-secret", "refresh-secret", time.Minute, time.Hour)
+	auth := NewAuthService(users, tokenManager)
+	repo := newMockTwoFactorRepository()
+	twoFactor, err := NewTwoFactorService(repo, users, NewCacheService(), "test-encryption-key", "Freelance")
+	assert.NoError(t, err)
+	auth.SetTwoFactor(twoFactor)
+
+	ctx := context.Background()
+	res, err := auth.Register(ctx, RegisterInput{Email: "totp@example.com", Password: "password123"}, nil)
+	assert.NoError(t, err)
+
+	setup, err := twoFactor.Setup(ctx, res.User.ID)
+	assert.NoError(t, err)
+	assert.Contains(t, setup.OTPAuthURI, "otpauth://totp/Freelance:totp@example.com?")
+	assert.Contains(t, setup.OTPAuthURI, "secret="+setup.Secret)
+	assert.NotContains(t, repo.totp[res.User.ID].Secret, setup.Secret, "секрет хранится зашифрованным")
+
+	code, err := totpCode(setup.Secret, totpStep(time.Now())-1)
+	assert.NoError(t, err)
+	recovery, err := twoFactor.Enable(ctx, res.User.ID, code)
+	assert.NoError(t, err)
+	assert.Len(t, recovery, recoveryCodeCount)
+
+	return &twoFactorFixture{auth: auth, twoFactor: twoFactor, repo: repo, userID: res.User.ID, secret: setup.Secret, recovery: recovery}
+}
+
+func TestTwoFactor_LoginRequiresSecondStep(t *testing.T) {
+	f := setupTwoFactor(t)
+	ctx := context.Background()
+
+	res, err := f.auth.Login(ctx, LoginInput{Email: "totp@example.com", Password: "password123"}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, res.TokenPair)
+	assert.NotEmpty(t, res.TwoFactorChallenge)
+
+	code, err := totpCode(f.secret, totpStep(time.Now()))
+	assert.NoError(t, err)
+	res, err = f.auth.LoginTwoFactor(ctx, res.TwoFactorChallenge, code, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, res.TokenPair)
+
+	// Тот же код второй раз не принимается
+	second, err := f.auth.Login(ctx, LoginInput{Email: "totp@example.com", Password: "password123"}, nil)
+	assert.NoError(t, err)
+	_, err = f.auth.LoginTwoFactor(ctx, second.TwoFactorChallenge, code, nil)
+	assert.ErrorIs(t, err, ErrTwoFactorInvalidCode)
+}
+
+func TestTwoFactor_ChallengeCannotBeForged(t *testing.T) {
+	f := setupTwoFactor(t)
+
+	// Токен другого назначения, подписанный тем же ключом, не подходит
+	forged, err := f.auth.tokenManager.GenerateActionToken(f.userID, models.ActionPasswordReset, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	_, err = f.auth.LoginTwoFactor(context.Background(), forged, "000000", nil)
+	assert.ErrorIs(t, err, ErrTwoFactorChallengeInvalid)
+}
+
+func TestTwoFactor_RecoveryCodeIsSingleUse(t *testing.T) {
+	f := setupTwoFactor(t)
+	ctx := context.Background()
+
+	assert.NoError(t, f.twoFactor.Verify(ctx, f.userID, f.recovery[0]))
+	assert.ErrorIs(t, f.twoFactor.Verify(ctx, f.userID, f.recovery[0]), ErrTwoFactorInvalidCode)
+
+	status, err := f.twoFactor.Status(ctx, f.userID)
+	assert.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, recoveryCodeCount-1, status.RecoveryCodesLeft)
+}
+
+func TestTwoFactor_LocksAfterFailures(t *testing.T) {
+	f := setupTwoFactor(t)
+	ctx := context.Background()
+
+	for i := 0; i < maxTwoFactorFailures; i++ {
+		assert.ErrorIs(t, f.twoFactor.Verify(ctx, f.userID, "000000"), ErrTwoFactorInvalidCode)
+	}
+	code, err := totpCode(f.secret, totpStep(time.Now())+1)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, f.twoFactor.Verify(ctx, f.userID, code), ErrTwoFactorLocked)
+}
+
+func TestTwoFactor_RequireFresh(t *testing.T) {
+	f := setupTwoFactor(t)
+	ctx := context.Background()
+
+	assert.NoError(t, f.twoFactor.RequireFresh(ctx, uuid.New(), ""), "без 2FA код не нужен")
+	assert.ErrorIs(t, f.twoFactor.RequireFresh(ctx, f.userID, ""), ErrTwoFactorRequired)
+	assert.ErrorIs(t, f.twoFactor.RequireFresh(ctx, f.userID, f.recovery[0]), ErrTwoFactorInvalidCode)
+
+	code, err := totpCode(f.secret, totpStep(time.Now()))
+	assert.NoError(t, err)
+	assert.NoError(t, f.twoFactor.RequireFresh(ctx, f.userID, code))
+}
+
+func TestTwoFactor_DisableRequiresPassword(t *testing.T) {
+	f := setupTwoFactor(t)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, f.twoFactor.Disable(ctx, f.userID, "wrongpassword", f.recovery[0]), ErrWrongPassword)
+	assert.NoError(t, f.twoFactor.Disable(ctx, f.userID, "password123", f.recovery[0]))
+
+	res, err := f.auth.Login(ctx, LoginInput{Email: "totp@example.com", Password: "password123"}, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, res.TokenPair)
+}
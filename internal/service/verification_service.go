@@ -3,7 +3,12 @@ package service
 import (
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,38 +17,272 @@ import (
 	"github.com/ignatzorin/freelance-backend/internal/repository"
 )
 
+const (
+	emailCodeTTL = 15 * time.Minute
+	phoneCodeTTL = 5 * time.Minute
+
+	// Повторная отправка кода того же типа не чаще раза в codeResendInterval
+	codeResendInterval = time.Minute
+	// Не больше maxCodesPerHour кодов в час одному пользователю и на один номер
+	maxCodesPerHour = 5
+	// После maxCodeAttempts неверных вводов код перестаёт действовать
+	maxCodeAttempts = 5
+)
+
+var (
+	// ErrPhoneNotSet возвращается, если в профиле нет телефона в международном формате.
+	ErrPhoneNotSet = errors.New("verification: укажите в профиле телефон в формате +79991234567")
+	// ErrPhoneChannelUnavailable возвращается для канала доставки, который не настроен на сервере.
+	ErrPhoneChannelUnavailable = errors.New("verification: канал доставки кода недоступен")
+	// ErrVerificationLocked возвращается после исчерпания попыток ввода кода.
+	ErrVerificationLocked = errors.New("verification: слишком много неверных попыток, запросите новый код")
+	// ErrTelegramPhoneNotShared возвращается, если владелец Telegram чата не поделился с ботом
+	// номером из профиля: иначе код в Telegram не доказывал бы владение телефоном.
+	ErrTelegramPhoneNotShared = errors.New("verification: поделитесь с Telegram ботом номером телефона из профиля кнопкой «Отправить номер»")
+)
+
+// VerificationThrottledError — коды отправляются слишком часто.
+type VerificationThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *VerificationThrottledError) Error() string {
+	return "verification: код уже отправлен, повторите попытку позже"
+}
+
+// VerificationRepository описывает зависимости VerificationService от слоя хранилища.
+type VerificationRepository interface {
+	CreateCode(ctx context.Context, userID uuid.UUID, codeType, code, channel, destination string, expiresAt time.Time) (*models.VerificationCode, error)
+	CountRecentCodes(ctx context.Context, userID uuid.UUID, codeType string, since time.Time) (int, *time.Time, error)
+	CountRecentCodesTo(ctx context.Context, destination string, since time.Time) (int, error)
+	GetActiveCode(ctx context.Context, userID uuid.UUID, codeType string) (*models.VerificationCode, error)
+	RegisterAttempt(ctx context.Context, codeID uuid.UUID, maxAttempts int) (bool, error)
+	InvalidateCode(ctx context.Context, codeID uuid.UUID) error
+	ConfirmCode(ctx context.Context, vc *models.VerificationCode) error
+	GetUserEmail(ctx context.Context, userID uuid.UUID) (string, error)
+	GetUserContacts(ctx context.Context, userID uuid.UUID) (phone, telegram *string, err error)
+	GetUserVerificationStatus(ctx context.Context, userID uuid.UUID) (emailVerified, phoneVerified, identityVerified bool, err error)
+	SaveTelegramContact(ctx context.Context, chatID int64, phone string) error
+	GetTelegramPhone(ctx context.Context, chatID int64) (*string, error)
+}
+
+// TelegramBot отвечает пользователю в чате с ботом при привязке номера.
+type TelegramBot interface {
+	RequestContact(ctx context.Context, chatID int64, text string) error
+	Reply(ctx context.Context, chatID int64, text string) error
+}
+
+// TelegramUpdate — часть обновления Telegram Bot API, нужная для привязки номера.
+type TelegramUpdate struct {
+	Message *TelegramMessage `json:"message"`
+}
+
+// TelegramMessage — входящее сообщение боту.
+type TelegramMessage struct {
+	Chat    TelegramChat     `json:"chat"`
+	From    *TelegramUser    `json:"from"`
+	Text    string           `json:"text"`
+	Contact *TelegramContact `json:"contact"`
+}
+
+// TelegramChat — чат, из которого пришло сообщение.
+type TelegramChat struct {
+	ID int64 `json:"id"`
+}
+
+// TelegramUser — отправитель сообщения.
+type TelegramUser struct {
+	ID int64 `json:"id"`
+}
+
+// TelegramContact — контакт, которым поделились в чате. UserID совпадает с отправителем,
+// только если это его собственный контакт.
+type TelegramContact struct {
+	PhoneNumber string `json:"phone_number"`
+	UserID      int64  `json:"user_id"`
+}
+
 type VerificationService struct {
-	repo *repository.VerificationRepository
+	repo         VerificationRepository
+	mailer       Mailer
+	phoneSenders map[string]SMSSender
 }
 
-func NewVerificationService(r *repository.VerificationRepository) *VerificationService {
-	return &VerificationService{repo: r}
+func NewVerificationService(r VerificationRepository, mailer Mailer) *VerificationService {
+	return &VerificationService{repo: r, mailer: mailer, phoneSenders: make(map[string]SMSSender)}
 }
 
-func (s *VerificationService) SendEmailCode(ctx context.Context, userID uuid.UUID) (string, error) {
-	code := generateCode()
-	expiresAt := time.Now().Add(15 * time.Minute)
-	_, err := s.repo.CreateCode(ctx, userID, models.VerificationTypeEmail, code, expiresAt)
+// SetPhoneSender подключает отправителя кодов для канала models.PhoneChannelSMS или models.PhoneChannelTelegram.
+func (s *VerificationService) SetPhoneSender(channel string, sender SMSSender) {
+	s.phoneSenders[channel] = sender
+}
+
+// PhoneChannels возвращает настроенные каналы доставки кода на телефон.
+func (s *VerificationService) PhoneChannels() []string {
+	var channels []string
+	for _, channel := range []string{models.PhoneChannelSMS, models.PhoneChannelTelegram} {
+		if _, ok := s.phoneSenders[channel]; ok {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// SendEmailCode отправляет код подтверждения на email пользователя. Код в ответе API не возвращается.
+func (s *VerificationService) SendEmailCode(ctx context.Context, userID uuid.UUID) error {
+	email, err := s.repo.GetUserEmail(ctx, userID)
 	if err != nil {
-		return "", err
+		return err
 	}
-	// TODO: отправить email с кодом
-	return code, nil
+
+	code, err := s.issueCode(ctx, userID, models.VerificationTypeEmail, models.VerificationTypeEmail, email, emailCodeTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, Email{
+		To:      email,
+		Subject: "Код подтверждения email",
+		Body:    fmt.Sprintf("Ваш код подтверждения: %s\n\nКод действует 15 минут. Никому его не сообщайте.", code),
+	})
 }
 
-func (s *VerificationService) SendPhoneCode(ctx context.Context, userID uuid.UUID) (string, error) {
-	code := generateCode()
-	expiresAt := time.Now().Add(5 * time.Minute)
-	_, err := s.repo.CreateCode(ctx, userID, models.VerificationTypePhone, code, expiresAt)
+// SendPhoneCode отправляет код подтверждения телефона из профиля по каналу channel;
+// пустой channel — первый настроенный канал.
+func (s *VerificationService) SendPhoneCode(ctx context.Context, userID uuid.UUID, channel string) error {
+	if channel == "" {
+		if channels := s.PhoneChannels(); len(channels) > 0 {
+			channel = channels[0]
+		}
+	}
+	sender, ok := s.phoneSenders[channel]
+	if !ok {
+		return ErrPhoneChannelUnavailable
+	}
+
+	phone, telegram, err := s.repo.GetUserContacts(ctx, userID)
 	if err != nil {
-		return "", err
+		return err
 	}
-	// TODO: отправить SMS с кодом
-	return code, nil
+	normalized, ok := normalizePhone(phone)
+	if !ok {
+		return ErrPhoneNotSet
+	}
+	if channel == models.PhoneChannelTelegram {
+		if err := s.checkTelegramPhone(ctx, telegram, normalized); err != nil {
+			return err
+		}
+	}
+
+	// Общий лимит на номер не даёт использовать сервис для рассылки на чужой телефон с разных аккаунтов
+	sent, err := s.repo.CountRecentCodesTo(ctx, normalized, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if sent >= maxCodesPerHour {
+		return &VerificationThrottledError{RetryAfter: time.Hour}
+	}
+
+	code, err := s.issueCode(ctx, userID, models.VerificationTypePhone, channel, normalized, phoneCodeTTL)
+	if err != nil {
+		return err
+	}
+
+	msg := PhoneMessage{
+		Phone: normalized,
+		Text:  fmt.Sprintf("Код подтверждения номера %s: %s. Никому его не сообщайте.", normalized, code),
+	}
+	if telegram != nil {
+		msg.Telegram = strings.TrimSpace(*telegram)
+	}
+	return sender.SendSMS(ctx, msg)
 }
 
+// VerifyCode проверяет последний отправленный код. Возвращает false для неверного или истёкшего кода
+// и ErrVerificationLocked, когда попытки исчерпаны.
 func (s *VerificationService) VerifyCode(ctx context.Context, userID uuid.UUID, codeType, code string) (bool, error) {
-	return s.repo.VerifyCode(ctx, userID, codeType, code)
+	vc, err := s.repo.GetActiveCode(ctx, userID, codeType)
+	if errors.Is(err, repository.ErrVerificationCodeNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	allowed, err := s.repo.RegisterAttempt(ctx, vc.ID, maxCodeAttempts)
+	if err != nil {
+		return false, err
+	}
+	if !allowed {
+		return false, ErrVerificationLocked
+	}
+
+	if subtle.ConstantTimeCompare([]byte(vc.Code), []byte(code)) != 1 {
+		if vc.Attempts+1 >= maxCodeAttempts {
+			if err := s.repo.InvalidateCode(ctx, vc.ID); err != nil {
+				return false, err
+			}
+			return false, ErrVerificationLocked
+		}
+		return false, nil
+	}
+
+	// Код подтверждает тот номер, на который был отправлен: если телефон в профиле успели сменить, код не засчитывается
+	if codeType == models.VerificationTypePhone {
+		phone, _, err := s.repo.GetUserContacts(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		if current, ok := normalizePhone(phone); !ok || vc.Destination == nil || current != *vc.Destination {
+			return false, s.repo.InvalidateCode(ctx, vc.ID)
+		}
+	}
+
+	if err := s.repo.ConfirmCode(ctx, vc); err != nil {
+		if errors.Is(err, repository.ErrVerificationCodeNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// HandleTelegramUpdate обрабатывает сообщение боту: собственный контакт владельца чата
+// запоминается как подтверждённый Telegram номер, на остальное бот предлагает поделиться номером.
+func (s *VerificationService) HandleTelegramUpdate(ctx context.Context, update TelegramUpdate) error {
+	bot, ok := s.phoneSenders[models.PhoneChannelTelegram].(TelegramBot)
+	if !ok {
+		return ErrPhoneChannelUnavailable
+	}
+	msg := update.Message
+	if msg == nil {
+		return nil
+	}
+
+	const prompt = "Чтобы получать коды подтверждения телефона, поделитесь номером кнопкой ниже. " +
+		"Номер должен совпадать с телефоном в профиле."
+	contact := msg.Contact
+	if contact == nil {
+		return bot.RequestContact(ctx, msg.Chat.ID, prompt)
+	}
+	// Чужой контакт Telegram не подтверждает: номер должен принадлежать отправителю
+	if msg.From == nil || contact.UserID != msg.From.ID {
+		return bot.RequestContact(ctx, msg.Chat.ID, "Это не ваш контакт. "+prompt)
+	}
+
+	phone := strings.TrimSpace(contact.PhoneNumber)
+	if !strings.HasPrefix(phone, "+") {
+		phone = "+" + phone
+	}
+	normalized, ok := normalizePhone(&phone)
+	if !ok {
+		return bot.Reply(ctx, msg.Chat.ID, "Не удалось распознать номер телефона.")
+	}
+	if err := s.repo.SaveTelegramContact(ctx, msg.Chat.ID, normalized); err != nil {
+		return err
+	}
+	return bot.Reply(ctx, msg.Chat.ID, fmt.Sprintf(
+		"Номер %s привязан. Ваш chat id: %d — укажите его в поле Telegram профиля и запросите код.", normalized, msg.Chat.ID))
 }
 
 func (s *VerificationService) GetStatus(ctx context.Context, userID uuid.UUID) (map[string]bool, error) {
@@ -58,8 +297,77 @@ func (s *VerificationService) GetStatus(ctx context.Context, userID uuid.UUID) (
 	}, nil
 }
 
+// checkTelegramPhone проверяет, что в профиле указан chat id и его владелец поделился с ботом
+// именно этим номером.
+func (s *VerificationService) checkTelegramPhone(ctx context.Context, telegram *string, phone string) error {
+	if telegram == nil || !isTelegramChatID(*telegram) {
+		return ErrTelegramNotLinked
+	}
+	chatID, _ := strconv.ParseInt(strings.TrimSpace(*telegram), 10, 64)
+	shared, err := s.repo.GetTelegramPhone(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	if shared == nil || *shared != phone {
+		return ErrTelegramPhoneNotShared
+	}
+	return nil
+}
+
+// issueCode проверяет частоту отправки пользователю и сохраняет новый код.
+func (s *VerificationService) issueCode(ctx context.Context, userID uuid.UUID, codeType, channel, destination string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	sent, lastAt, err := s.repo.CountRecentCodes(ctx, userID, codeType, now.Add(-time.Hour))
+	if err != nil {
+		return "", err
+	}
+	if lastAt != nil && now.Sub(*lastAt) < codeResendInterval {
+		return "", &VerificationThrottledError{RetryAfter: codeResendInterval - now.Sub(*lastAt)}
+	}
+	if sent >= maxCodesPerHour {
+		return "", &VerificationThrottledError{RetryAfter: time.Hour}
+	}
+
+	code := generateCode()
+	if _, err := s.repo.CreateCode(ctx, userID, codeType, code, channel, destination, now.Add(ttl)); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// normalizePhone приводит номер к формату E.164, убирая пробелы, дефисы и скобки.
+func normalizePhone(phone *string) (string, bool) {
+	if phone == nil {
+		return "", false
+	}
+	normalized := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(*phone))
+
+	if !strings.HasPrefix(normalized, "+") {
+		return "", false
+	}
+	digits := normalized[1:]
+	if len(digits) < 10 || len(digits) > 15 {
+		return "", false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+	return normalized, true
+}
+
+// generateCode возвращает равномерно распределённый шестизначный код.
 func generateCode() string {
-	b := make([]byte, 3)
-	rand.Read(b)
-	return fmt.Sprintf("%06d", int(b[0])*10000+int(b[1])*100+int(b[2])%100)[:6]
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		panic(fmt.Sprintf("verification: crypto/rand недоступен: %v", err))
+	}
+	return fmt.Sprintf("%06d", n.Int64())
 }
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+type mockVerificationRepository struct {
+	codes          []*models.VerificationCode
+	phones         map[uuid.UUID]*string
+	telegrams      map[uuid.UUID]*string
+	verified       map[uuid.UUID]map[string]bool
+	telegramPhones map[int64]string
+}
+
+func newMockVerificationRepository() *mockVerificationRepository {
+	return &mockVerificationRepository{
+		phones:         make(map[uuid.UUID]*string),
+		telegrams:      make(map[uuid.UUID]*string),
+		verified:       make(map[uuid.UUID]map[string]bool),
+		telegramPhones: make(map[int64]string),
+	}
+}
+
+func (m *mockVerificationRepository) CreateCode(ctx context.Context, userID uuid.UUID, codeType, code, channel, destination string, expiresAt time.Time) (*models.VerificationCode, error) {
+	for _, vc := range m.codes {
+		if vc.UserID == userID && vc.Type == codeType {
+			vc.Used = true
+		}
+	}
+	vc := &models.VerificationCode{
+		ID: uuid.New(), UserID: userID, Type: codeType, Code: code,
+		Channel: &channel, Destination: &destination, ExpiresAt: expiresAt, CreatedAt: time.Now(),
+	}
+	m.codes = append(m.codes, vc)
+	return vc, nil
+}
+
+func (m *mockVerificationRepository) CountRecentCodes(ctx context.Context, userID uuid.UUID, codeType string, since time.Time) (int, *time.Time, error) {
+	var count int
+	var lastAt *time.Time
+	for _, vc := range m.codes {
+		if vc.UserID == userID && vc.Type == codeType && vc.CreatedAt.After(since) {
+			count++
+			createdAt := vc.CreatedAt
+			lastAt = &createdAt
+		}
+	}
+	return count, lastAt, nil
+}
+
+func (m *mockVerificationRepository) CountRecentCodesTo(ctx context.Context, destination string, since time.Time) (int, error) {
+	var count int
+	for _, vc := range m.codes {
+		if vc.Destination != nil && *vc.Destination == destination && vc.CreatedAt.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockVerificationRepository) GetActiveCode(ctx context.Context, userID uuid.UUID, codeType string) (*models.VerificationCode, error) {
+	for i := len(m.codes) - 1; i >= 0; i-- {
+		vc := m.codes[i]
+		if vc.UserID == userID && vc.Type == codeType && !vc.Used && vc.ExpiresAt.After(time.Now()) {
+			copied := *vc
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrVerificationCodeNotFound
+}
+
+func (m *mockVerificationRepository) find(id uuid.UUID) *models.VerificationCode {
+	for _, vc := range m.codes {
+		if vc.ID == id {
+			return vc
+		}
+	}
+	return nil
+}
+
+func (m *mockVerificationRepository) RegisterAttempt(ctx context.Context, codeID uuid.UUID, maxAttempts int) (bool, error) {
+	vc := m.find(codeID)
+	if vc == nil || vc.Used || vc.Attempts >= maxAttempts {
+		return false, nil
+	}
+	vc.Attempts++
+	return true, nil
+}
+
+func (m *mockVerificationRepository) InvalidateCode(ctx context.Context, codeID uuid.UUID) error {
+	if vc := m.find(codeID); vc != nil {
+		vc.Used = true
+	}
+	return nil
+}
+
+func (m *mockVerificationRepository) ConfirmCode(ctx context.Context, code *models.VerificationCode) error {
+	vc := m.find(code.ID)
+	if vc == nil || vc.Used {
+		return repository.ErrVerificationCodeNotFound
+	}
+	vc.Used = true
+	if m.verified[vc.UserID] == nil {
+		m.verified[vc.UserID] = make(map[string]bool)
+	}
+	m.verified[vc.UserID][vc.Type] = true
+	return nil
+}
+
+func (m *mockVerificationRepository) GetUserEmail(ctx context.Context, userID uuid.UUID) (string, error) {
+	return userID.String() + "@example.com", nil
+}
+
+func (m *mockVerificationRepository) GetUserContacts(ctx context.Context, userID uuid.UUID) (*string, *string, error) {
+	return m.phones[userID], m.telegrams[userID], nil
+}
+
+func (m *mockVerificationRepository) GetUserVerificationStatus(ctx context.Context, userID uuid.UUID) (bool, bool, bool, error) {
+	return m.verified[userID]["email"], m.verified[userID]["phone"], false, nil
+}
+
+func (m *mockVerificationRepository) SaveTelegramContact(ctx context.Context, chatID int64, phone string) error {
+	m.telegramPhones[chatID] = phone
+	return nil
+}
+
+func (m *mockVerificationRepository) GetTelegramPhone(ctx context.Context, chatID int64) (*string, error) {
+	phone, ok := m.telegramPhones[chatID]
+	if !ok {
+		return nil, nil
+	}
+	return &phone, nil
+}
+
+// fakeTelegramBot запоминает коды и ответы бота при привязке номера.
+type fakeTelegramBot struct {
+	*FakeSMSSender
+	prompts []string
+	replies []string
+}
+
+func (b *fakeTelegramBot) RequestContact(ctx context.Context, chatID int64, text string) error {
+	b.prompts = append(b.prompts, text)
+	return nil
+}
+
+func (b *fakeTelegramBot) Reply(ctx context.Context, chatID int64, text string) error {
+	b.replies = append(b.replies, text)
+	return nil
+}
+
+var smsCodePattern = regexp.MustCompile(`: (\d{6})\.`)
+
+func lastSMSCode(t *testing.T, sender *FakeSMSSender) string {
+	t.Helper()
+	sent := sender.Sent()
+	if len(sent) == 0 {
+		t.Fatalf("сообщение с кодом не отправлено")
+	}
+	match := smsCodePattern.FindStringSubmatch(sent[len(sent)-1].Text)
+	if match == nil {
+		t.Fatalf("код не найден в сообщении %q", sent[len(sent)-1].Text)
+	}
+	return match[1]
+}
+
+func setupVerificationService() (*VerificationService, *mockVerificationRepository, *FakeSMSSender) {
+	repo := newMockVerificationRepository()
+	sms := NewFakeSMSSender()
+	svc := NewVerificationService(repo, &recordingMailer{})
+	svc.SetPhoneSender(models.PhoneChannelSMS, sms)
+	return svc, repo, sms
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestVerificationService_PhoneCodeVerifiesPhone(t *testing.T) {
+	svc, repo, sms := setupVerificationService()
+	ctx := context.Background()
+	userID := uuid.New()
+	repo.phones[userID] = strPtr("+7 (999) 123-45-67")
+
+	assert.NoError(t, svc.SendPhoneCode(ctx, userID, ""))
+	assert.Equal(t, "+79991234567", sms.Sent()[0].Phone)
+
+	ok, err := svc.VerifyCode(ctx, userID, models.VerificationTypePhone, lastSMSCode(t, sms))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	status, err := svc.GetStatus(ctx, userID)
+	assert.NoError(t, err)
+	assert.True(t, status["phone_verified"])
+}
+
+func TestVerificationService_PhoneCodeRequiresPhone(t *testing.T) {
+	svc, repo, _ := setupVerificationService()
+	userID := uuid.New()
+	repo.phones[userID] = strPtr("89991234567")
+
+	assert.ErrorIs(t, svc.SendPhoneCode(context.Background(), userID, ""), ErrPhoneNotSet)
+	assert.ErrorIs(t, svc.SendPhoneCode(context.Background(), userID, models.PhoneChannelTelegram), ErrPhoneChannelUnavailable)
+}
+
+func TestVerificationService_ResendThrottled(t *testing.T) {
+	svc, repo, _ := setupVerificationService()
+	ctx := context.Background()
+	userID := uuid.New()
+	repo.phones[userID] = strPtr("+79991234567")
+
+	assert.NoError(t, svc.SendPhoneCode(ctx, userID, ""))
+	err := svc.SendPhoneCode(ctx, userID, "")
+	var throttled *VerificationThrottledError
+	assert.ErrorAs(t, err, &throttled)
+	assert.True(t, throttled.RetryAfter > 0 && throttled.RetryAfter <= codeResendInterval)
+}
+
+func TestVerificationService_NumberThrottledAcrossUsers(t *testing.T) {
+	svc, repo, sms := setupVerificationService()
+	ctx := context.Background()
+
+	for i := 0; i < maxCodesPerHour; i++ {
+		userID := uuid.New()
+		repo.phones[userID] = strPtr("+79991234567")
+		assert.NoError(t, svc.SendPhoneCode(ctx, userID, ""))
+	}
+
+	userID := uuid.New()
+	repo.phones[userID] = strPtr("+79991234567")
+	var throttled *VerificationThrottledError
+	assert.ErrorAs(t, svc.SendPhoneCode(ctx, userID, ""), &throttled)
+	assert.Len(t, sms.Sent(), maxCodesPerHour)
+}
+
+func TestVerificationService_LocksAfterWrongAttempts(t *testing.T) {
+	svc, repo, sms := setupVerificationService()
+	ctx := context.Background()
+	userID := uuid.New()
+	repo.phones[userID] = strPtr("+79991234567")
+
+	assert.NoError(t, svc.SendPhoneCode(ctx, userID, ""))
+	code := lastSMSCode(t, sms)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for i := 0; i < maxCodeAttempts-1; i++ {
+		ok, err := svc.VerifyCode(ctx, userID, models.VerificationTypePhone, wrong)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	}
+	_, err := svc.VerifyCode(ctx, userID, models.VerificationTypePhone, wrong)
+	assert.ErrorIs(t, err, ErrVerificationLocked)
+
+	// После блокировки верный код уже не принимается
+	ok, err := svc.VerifyCode(ctx, userID, models.VerificationTypePhone, code)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, repo.verified[userID]["phone"])
+}
+
+func TestVerificationService_PhoneChangedAfterSend(t *testing.T) {
+	svc, repo, sms := setupVerificationService()
+	ctx := context.Background()
+	userID := uuid.New()
+	repo.phones[userID] = strPtr("+79991234567")
+
+	assert.NoError(t, svc.SendPhoneCode(ctx, userID, ""))
+	repo.phones[userID] = strPtr("+79990000000")
+
+	ok, err := svc.VerifyCode(ctx, userID, models.VerificationTypePhone, lastSMSCode(t, sms))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerificationService_TelegramRequiresChatID(t *testing.T) {
+	svc, repo, _ := setupVerificationService()
+	svc.SetPhoneSender(models.PhoneChannelTelegram, NewTelegramSMSSender("test-token"))
+	userID := uuid.New()
+	repo.phones[userID] = strPtr("+79991234567")
+
+	assert.ErrorIs(t, svc.SendPhoneCode(context.Background(), userID, models.PhoneChannelTelegram), ErrTelegramNotLinked)
+
+	repo.telegrams[userID] = strPtr("@username")
+	assert.ErrorIs(t, svc.SendPhoneCode(context.Background(), userID, models.PhoneChannelTelegram), ErrTelegramNotLinked)
+}
+
+func TestVerificationService_TelegramRequiresSharedPhone(t *testing.T) {
+	svc, repo, _ := setupVerificationService()
+	bot := &fakeTelegramBot{FakeSMSSender: NewFakeSMSSender()}
+	svc.SetPhoneSender(models.PhoneChannelTelegram, bot)
+	ctx := context.Background()
+	userID := uuid.New()
+	repo.phones[userID] = strPtr("+79991234567")
+	repo.telegrams[userID] = strPtr("100500")
+
+	// Chat id из профиля сам по себе не подтверждает номер
+	assert.ErrorIs(t, svc.SendPhoneCode(ctx, userID, models.PhoneChannelTelegram), ErrTelegramPhoneNotShared)
+
+	repo.telegramPhones[100500] = "+79990000000"
+	assert.ErrorIs(t, svc.SendPhoneCode(ctx, userID, models.PhoneChannelTelegram), ErrTelegramPhoneNotShared)
+	assert.Empty(t, bot.Sent())
+
+	repo.telegramPhones[100500] = "+79991234567"
+	assert.NoError(t, svc.SendPhoneCode(ctx, userID, models.PhoneChannelTelegram))
+	ok, err := svc.VerifyCode(ctx, userID, models.VerificationTypePhone, lastSMSCode(t, bot.FakeSMSSender))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestVerificationService_HandleTelegramUpdate(t *testing.T) {
+	svc, repo, _ := setupVerificationService()
+	ctx := context.Background()
+
+	assert.ErrorIs(t, svc.HandleTelegramUpdate(ctx, TelegramUpdate{}), ErrPhoneChannelUnavailable)
+
+	bot := &fakeTelegramBot{FakeSMSSender: NewFakeSMSSender()}
+	svc.SetPhoneSender(models.PhoneChannelTelegram, bot)
+	message := func(fromID int64, contact *TelegramContact) TelegramUpdate {
+		return TelegramUpdate{Message: &TelegramMessage{
+			Chat:    TelegramChat{ID: 100500},
+			From:    &TelegramUser{ID: fromID},
+			Contact: contact,
+		}}
+	}
+
+	// На /start бот просит поделиться номером
+	assert.NoError(t, svc.HandleTelegramUpdate(ctx, message(42, nil)))
+	assert.Len(t, bot.prompts, 1)
+
+	// Чужой контакт не привязывается
+	assert.NoError(t, svc.HandleTelegramUpdate(ctx, message(42, &TelegramContact{PhoneNumber: "79991234567", UserID: 7})))
+	assert.Empty(t, repo.telegramPhones)
+	assert.Len(t, bot.prompts, 2)
+
+	assert.NoError(t, svc.HandleTelegramUpdate(ctx, message(42, &TelegramContact{PhoneNumber: "79991234567", UserID: 42})))
+	assert.Equal(t, "+79991234567", repo.telegramPhones[100500])
+	assert.Len(t, bot.replies, 1)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/logger"
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+const (
+	withdrawalBatchSize  = 20
+	withdrawalStaleAfter = 15 * time.Minute
+)
+
+// WithdrawalQueue — хранилище заявок на вывод, с которым работает обработчик.
+type WithdrawalQueue interface {
+	ClaimPending(ctx context.Context, limit int, staleAfter time.Duration) ([]models.Withdrawal, error)
+	Requeue(ctx context.Context, id uuid.UUID) error
+	Complete(ctx context.Context, id uuid.UUID, payoutReference string) (*models.Withdrawal, error)
+	Reject(ctx context.Context, id uuid.UUID, reason string) (*models.Withdrawal, error)
+}
+
+// WithdrawalProcessor фоново переводит заявки на вывод pending → processing → completed/rejected.
+type WithdrawalProcessor struct {
+	queue    WithdrawalQueue
+	provider PayoutProvider
+	hub      WSNotifier
+	interval time.Duration
+}
+
+// NewWithdrawalProcessor создаёт обработчик заявок на вывод.
+func NewWithdrawalProcessor(queue WithdrawalQueue, provider PayoutProvider, interval time.Duration) *WithdrawalProcessor {
+	return &WithdrawalProcessor{queue: queue, provider: provider, interval: interval}
+}
+
+// SetHub устанавливает WebSocket hub для уведомления пользователей о выплатах.
+func (p *WithdrawalProcessor) SetHub(hub WSNotifier) {
+	p.hub = hub
+}
+
+// Run обрабатывает очередь раз в interval до отмены контекста.
+func (p *WithdrawalProcessor) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.ProcessBatch(ctx); err != nil && logger.Log != nil {
+			logger.Log.WithFields(map[string]interface{}{
+				"error": err.Error(),
+			}).Error("withdrawal processor: не удалось получить заявки")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch забирает пачку заявок и отправляет их провайдеру. Возвращает число обработанных заявок.
+func (p *WithdrawalProcessor) ProcessBatch(ctx context.Context) (int, error) {
+	withdrawals, err := p.queue.ClaimPending(ctx, withdrawalBatchSize, withdrawalStaleAfter)
+	if err != nil {
+		return 0, err
+	}
+	for i := range withdrawals {
+		p.process(ctx, &withdrawals[i])
+	}
+	return len(withdrawals), nil
+}
+
+func (p *WithdrawalProcessor) process(ctx context.Context, w *models.Withdrawal) {
+	reference, err := p.provider.Payout(ctx, w)
+
+	var rejected *PayoutRejectedError
+	switch {
+	case errors.As(err, &rejected):
+		updated, rejectErr := p.queue.Reject(ctx, w.ID, rejected.Reason)
+		if rejectErr != nil {
+			p.logFailure(w, "не удалось отклонить заявку", rejectErr)
+			return
+		}
+		p.notify(updated, "withdrawal_rejected")
+	case err != nil:
+		// Временная ошибка: возвращаем заявку в очередь, средства остаются замороженными
+		p.logFailure(w, "ошибка провайдера выплат", err)
+		if requeueErr := p.queue.Requeue(ctx, w.ID); requeueErr != nil {
+			p.logFailure(w, "не удалось вернуть заявку в очередь", requeueErr)
+		}
+	default:
+		updated, completeErr := p.queue.Complete(ctx, w.ID, reference)
+		if completeErr != nil {
+			p.logFailure(w, "не удалось завершить заявку", completeErr)
+			return
+		}
+		p.notify(updated, "withdrawal_completed")
+	}
+}
+
+func (p *WithdrawalProcessor) notify(w *models.Withdrawal, event string) {
+	if p.hub == nil {
+		return
+	}
+	if err := p.hub.BroadcastToUser(w.UserID, event, w); err != nil {
+		p.logFailure(w, "не удалось отправить уведомление", err)
+	}
+}
+
+func (p *WithdrawalProcessor) logFailure(w *models.Withdrawal, msg string, err error) {
+	if logger.Log == nil {
+		return
+	}
+	logger.Log.WithFields(map[string]interface{}{
+		"withdrawal_id": w.ID,
+		"error":         err.Error(),
+	}).Warn("withdrawal processor: " + msg)
+}
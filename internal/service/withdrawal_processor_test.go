@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+type mockWithdrawalQueue struct {
+	mock.Mock
+}
+
+func (m *mockWithdrawalQueue) ClaimPending(ctx context.Context, limit int, staleAfter time.Duration) ([]models.Withdrawal, error) {
+	args := m.Called(ctx, limit, staleAfter)
+	return args.Get(0).([]models.Withdrawal), args.Error(1)
+}
+
+func (m *mockWithdrawalQueue) Requeue(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockWithdrawalQueue) Complete(ctx context.Context, id uuid.UUID, payoutReference string) (*models.Withdrawal, error) {
+	args := m.Called(ctx, id, payoutReference)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Withdrawal), args.Error(1)
+}
+
+func (m *mockWithdrawalQueue) Reject(ctx context.Context, id uuid.UUID, reason string) (*models.Withdrawal, error) {
+	args := m.Called(ctx, id, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Withdrawal), args.Error(1)
+}
+
+type stubPayoutProvider struct {
+	err error
+}
+
+func (p *stubPayoutProvider) Payout(ctx context.Context, w *models.Withdrawal) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	return "ref-" + w.ID.String(), nil
+}
+
+func newTestWithdrawal() models.Withdrawal {
+	card := "1234"
+	return models.Withdrawal{ID: uuid.New(), UserID: uuid.New(), Amount: 500, Status: models.WithdrawalStatusProcessing, CardLast4: &card}
+}
+
+func TestWithdrawalProcessor_ProcessBatch_Completes(t *testing.T) {
+	w := newTestWithdrawal()
+	queue := new(mockWithdrawalQueue)
+	queue.On("ClaimPending", mock.Anything, withdrawalBatchSize, withdrawalStaleAfter).Return([]models.Withdrawal{w}, nil)
+	queue.On("Complete", mock.Anything, w.ID, "ref-"+w.ID.String()).Return(&w, nil)
+
+	processor := NewWithdrawalProcessor(queue, &stubPayoutProvider{}, time.Minute)
+	n, err := processor.ProcessBatch(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	queue.AssertExpectations(t)
+}
+
+func TestWithdrawalProcessor_ProcessBatch_RejectsWithReason(t *testing.T) {
+	w := newTestWithdrawal()
+	queue := new(mockWithdrawalQueue)
+	queue.On("ClaimPending", mock.Anything, withdrawalBatchSize, withdrawalStaleAfter).Return([]models.Withdrawal{w}, nil)
+	queue.On("Reject", mock.Anything, w.ID, "карта заблокирована").Return(&w, nil)
+
+	provider := &stubPayoutProvider{err: &PayoutRejectedError{Reason: "карта заблокирована"}}
+	processor := NewWithdrawalProcessor(queue, provider, time.Minute)
+	_, err := processor.ProcessBatch(context.Background())
+
+	assert.NoError(t, err)
+	queue.AssertExpectations(t)
+	queue.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWithdrawalProcessor_ProcessBatch_RequeuesOnTemporaryError(t *testing.T) {
+	w := newTestWithdrawal()
+	queue := new(mockWithdrawalQueue)
+	queue.On("ClaimPending", mock.Anything, withdrawalBatchSize, withdrawalStaleAfter).Return([]models.Withdrawal{w}, nil)
+	queue.On("Requeue", mock.Anything, w.ID).Return(nil)
+
+	processor := NewWithdrawalProcessor(queue, &stubPayoutProvider{err: errors.New("timeout")}, time.Minute)
+	_, err := processor.ProcessBatch(context.Background())
+
+	assert.NoError(t, err)
+	queue.AssertExpectations(t)
+	queue.AssertNotCalled(t, "Reject", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFakePayoutProvider_DeclinedCard(t *testing.T) {
+	w := newTestWithdrawal()
+	declined := FakeCardDeclined
+	w.CardLast4 = &declined
+
+	_, err := NewFakePayoutProvider().Payout(context.Background(), &w)
+
+	var rejected *PayoutRejectedError
+	assert.ErrorAs(t, err, &rejected)
+}
@@ -3,28 +3,64 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/models"
 	"github.com/ignatzorin/freelance-backend/internal/repository"
 )
 
-var ErrMinWithdrawalAmount = errors.New("minimum withdrawal amount is 100 RUB")
+var (
+	ErrMinWithdrawalAmount = errors.New("amount is below the minimum withdrawal")
+	// ErrIdentityVerificationRequired возвращается, если выводы без проверки личности превысили порог.
+	ErrIdentityVerificationRequired = errors.New("identity verification is required for this withdrawal")
+)
+
+// kycWithdrawalWindow — за какой период суммируются выводы при сравнении с порогом проверки личности,
+// чтобы порог нельзя было обойти, разбив вывод на несколько заявок.
+const kycWithdrawalWindow = 30 * 24 * time.Hour
+
+// IdentityChecker сообщает, прошёл ли пользователь проверку личности.
+type IdentityChecker interface {
+	IsIdentityVerified(ctx context.Context, userID uuid.UUID) (bool, error)
+}
 
 type WithdrawalService struct {
 	repo *repository.WithdrawalRepository
+
+	identity     IdentityChecker
+	kycThreshold valueobject.Amount
+	rates        ExchangeRateProvider
 }
 
 func NewWithdrawalService(r *repository.WithdrawalRepository) *WithdrawalService {
 	return &WithdrawalService{repo: r}
 }
 
-func (s *WithdrawalService) CreateWithdrawal(ctx context.Context, userID uuid.UUID, amount float64, cardLast4, bankName string) (*models.Withdrawal, error) {
-	if amount < 100 {
-		return nil, ErrMinWithdrawalAmount
+// SetIdentityRequirement требует проверки личности, если выводы пользователя за kycWithdrawalWindow
+// вместе с новым превысят threshold в валюте по умолчанию; rates пересчитывают выводы в другой валюте.
+func (s *WithdrawalService) SetIdentityRequirement(identity IdentityChecker, threshold valueobject.Amount, rates ExchangeRateProvider) {
+	s.identity = identity
+	s.kycThreshold = threshold
+	s.rates = rates
+}
+
+func (s *WithdrawalService) CreateWithdrawal(ctx context.Context, userID uuid.UUID, amount valueobject.Amount, currency, cardLast4, bankName string) (*models.Withdrawal, error) {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	if minAmount := models.MinWithdrawalAmounts[currency]; amount < minAmount {
+		return nil, fmt.Errorf("%w of %s %s", ErrMinWithdrawalAmount, minAmount, currency)
 	}
-	return s.repo.Create(ctx, userID, amount, cardLast4, bankName)
+	check, err := s.identityCheck(ctx, userID, amount, currency)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, userID, amount, currency, cardLast4, bankName, check)
 }
 
 func (s *WithdrawalService) GetWithdrawal(ctx context.Context, id uuid.UUID) (*models.Withdrawal, error) {
@@ -34,3 +70,36 @@ func (s *WithdrawalService) GetWithdrawal(ctx context.Context, id uuid.UUID) (*m
 func (s *WithdrawalService) ListUserWithdrawals(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Withdrawal, error) {
 	return s.repo.ListByUser(ctx, userID, limit, offset)
 }
+
+// identityCheck возвращает проверку, которая в транзакции создания заявки отклоняет её
+// с ErrIdentityVerificationRequired, если пользователь без проверки личности превысит порог
+// выводов за kycWithdrawalWindow. Для проверенных пользователей и без порога возвращает nil.
+func (s *WithdrawalService) identityCheck(ctx context.Context, userID uuid.UUID, amount valueobject.Amount, currency string) (*repository.WithdrawalCheck, error) {
+	if s.identity == nil || s.kycThreshold <= 0 {
+		return nil, nil
+	}
+	verified, err := s.identity.IsIdentityVerified(ctx, userID)
+	if err != nil || verified {
+		return nil, err
+	}
+
+	return &repository.WithdrawalCheck{
+		Since: time.Now().Add(-kycWithdrawalWindow),
+		Allow: func(ctx context.Context, recent map[string]valueobject.Amount) error {
+			recent[currency] += amount
+
+			var total valueobject.Amount
+			for code, sum := range recent {
+				rate, err := s.rates.Rate(ctx, code, models.DefaultCurrency)
+				if err != nil {
+					return err
+				}
+				total += sum.Mul(rate)
+			}
+			if total > s.kycThreshold {
+				return fmt.Errorf("%w: withdrawals above %s %s within 30 days", ErrIdentityVerificationRequired, s.kycThreshold, models.DefaultCurrency)
+			}
+			return nil
+		},
+	}, nil
+}
@@ -90,6 +90,46 @@ func (s *PhotoStorage) Delete(ctx context.Context, relativePath string) error {
 	return nil
 }
 
+// Open открывает сохранённый файл для чтения.
+func (s *PhotoStorage) Open(ctx context.Context, relativePath string) (*os.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	target := filepath.Join(s.rootPath, relativePath)
+	if rel, err := filepath.Rel(s.rootPath, target); err != nil || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("storage: путь %s вне хранилища", relativePath)
+	}
+
+	f, err := os.Open(target)
+	if err != nil {
+		return nil, fmt.Errorf("storage: не удалось открыть файл: %w", err)
+	}
+	return f, nil
+}
+
+// Create создаёт файл для записи по относительному пути без ограничения размера;
+// используется для файлов, которые собирает сам сервер, а не загружает пользователь.
+func (s *PhotoStorage) Create(ctx context.Context, relativePath string) (*os.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	target := filepath.Join(s.rootPath, relativePath)
+	if rel, err := filepath.Rel(s.rootPath, target); err != nil || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("storage: путь %s вне хранилища", relativePath)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return nil, fmt.Errorf("storage: не удалось создать каталог: %w", err)
+	}
+
+	f, err := os.Create(target)
+	if err != nil {
+		return nil, fmt.Errorf("storage: не удалось создать файл: %w", err)
+	}
+	return f, nil
+}
+
 // sanitizeFilename удаляет потенциально опасные символы.
 func sanitizeFilename(name string) string {
 	name = filepath.Base(name)
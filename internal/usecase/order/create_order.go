@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/ignatzorin/freelance-backend/internal/domain/entity"
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/domain/repository"
 	"github.com/ignatzorin/freelance-backend/internal/pkg/apperror"
 )
@@ -14,8 +15,8 @@ type CreateOrderInput struct {
 	ClientID      uuid.UUID
 	Title         string
 	Description   string
-	BudgetMin     float64
-	BudgetMax     float64
+	BudgetMin     valueobject.Amount
+	BudgetMax     valueobject.Amount
 	DeadlineAt    *time.Time
 	Requirements  []RequirementInput
 	AttachmentIDs []uuid.UUID
@@ -117,7 +117,7 @@ func TestCreateOrderUseCase_Success(t *testing.T) {
 	}
 
 	if result.Budget.Min.Amount != input.BudgetMin {
-		t.Errorf("expected budget min %f, got %f", input.BudgetMin, result.Budget.Min.Amount)
+		t.Errorf("expected budget min %s, got %s", input.BudgetMin, result.Budget.Min.Amount)
 	}
 }
 
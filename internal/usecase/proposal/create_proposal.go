@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/ignatzorin/freelance-backend/internal/domain/entity"
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 	"github.com/ignatzorin/freelance-backend/internal/domain/repository"
 	"github.com/ignatzorin/freelance-backend/internal/pkg/apperror"
 )
@@ -14,7 +15,7 @@ type CreateProposalInput struct {
 	OrderID          uuid.UUID
 	FreelancerID     uuid.UUID
 	CoverLetter      string
-	ProposedBudget   float64
+	ProposedBudget   valueobject.Amount
 	ProposedDeadline *time.Time
 }
 
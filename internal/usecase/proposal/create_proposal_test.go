@@ -181,7 +181,7 @@ func TestCreateProposalUseCase_Success(t *testing.T) {
 	}
 
 	if result.ProposedBudget != input.ProposedBudget {
-		t.Errorf("expected budget %f, got %f", input.ProposedBudget, result.ProposedBudget)
+		t.Errorf("expected budget %s, got %s", input.ProposedBudget, result.ProposedBudget)
 	}
 }
 
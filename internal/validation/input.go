@@ -7,6 +7,8 @@ import (
 	"strings"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/ignatzorin/freelance-backend/internal/domain/valueobject"
 )
 
 // Константы валидации
@@ -28,8 +30,8 @@ const (
 	MaxLocationLength = 100
 	MaxSkillLength = 50
 	MaxSkillsCount = 50
-	MinBudget = 0.0
-	MaxBudget = 100000000.0 // 100 миллионов
+	MinBudget = valueobject.Amount(0)
+	MaxBudget = valueobject.Amount(100000000 * 100) // 100 миллионов, в копейках
 	MinHourlyRate = 0.0
 	MaxHourlyRate = 100000.0
 	MinMessageLength = 1
@@ -201,13 +203,13 @@ func ValidateProposalCoverLetter(coverLetter string) error {
 }
 
 // ValidateBudget проверяет бюджет.
-func ValidateBudget(budgetMin, budgetMax *float64) error {
+func ValidateBudget(budgetMin, budgetMax *valueobject.Amount) error {
 	if budgetMin != nil {
 		if *budgetMin < MinBudget {
 			return fmt.Errorf("минимальный бюджет не может быть отрицательным")
 		}
 		if *budgetMin > MaxBudget {
-			return fmt.Errorf("минимальный бюджет не может превышать %.0f", MaxBudget)
+			return fmt.Errorf("минимальный бюджет не может превышать %.0f", MaxBudget.Float64())
 		}
 	}
 
@@ -216,7 +218,7 @@ func ValidateBudget(budgetMin, budgetMax *float64) error {
 			return fmt.Errorf("максимальный бюджет не может быть отрицательным")
 		}
 		if *budgetMax > MaxBudget {
-			return fmt.Errorf("максимальный бюджет не может превышать %.0f", MaxBudget)
+			return fmt.Errorf("максимальный бюджет не может превышать %.0f", MaxBudget.Float64())
 		}
 	}
 
@@ -0,0 +1,285 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Виды конвертов, которыми обмениваются экземпляры хаба.
+const (
+	EnvelopeEvent             = "event"
+	EnvelopeTopic             = "topic"
+	EnvelopeDisconnectSession = "disconnect_session"
+	EnvelopeDisconnectUser    = "disconnect_user"
+	EnvelopePresence          = "presence"
+	// EnvelopeHello отправляет запустившийся экземпляр; остальные отвечают EnvelopeSnapshot
+	// со списком своих подключённых пользователей.
+	EnvelopeHello    = "hello"
+	EnvelopeSnapshot = "snapshot"
+	// EnvelopeBye отправляет экземпляр при остановке, чтобы остальные забыли его подключения.
+	EnvelopeBye = "bye"
+	// EnvelopeSubscribed backplane передаёт своему хабу, когда подписка установлена или
+	// восстановлена после разрыва: хаб запрашивает состояние остальных экземпляров заново.
+	EnvelopeSubscribed = "subscribed"
+)
+
+const (
+	backplanePublishTimeout = 5 * time.Second
+	// Экземпляр периодически рассылает список своих пользователей; экземпляр, от которого
+	// давно ничего не было, считается остановленным, и его пользователи — не в сети.
+	backplaneSnapshotInterval = 30 * time.Second
+	backplaneNodeTimeout      = 3 * backplaneSnapshotInterval
+)
+
+// Envelope — сообщение между экземплярами хаба. Origin — идентификатор экземпляра-отправителя,
+// свои конверты экземпляр пропускает.
+type Envelope struct {
+	Origin    string          `json:"origin"`
+	Kind      string          `json:"kind"`
+	UserID    uuid.UUID       `json:"user_id,omitempty"`
+	SessionID uuid.UUID       `json:"session_id,omitempty"`
+	Seq       int64           `json:"seq,omitempty"`
+	Topic     string          `json:"topic,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Online    bool            `json:"online,omitempty"`
+	UserIDs   []uuid.UUID     `json:"user_ids,omitempty"`
+}
+
+// Backplane пересылает события хаба между экземплярами сервера, чтобы событие,
+// опубликованное на любом из них, дошло до соединений пользователя на всех.
+type Backplane interface {
+	// Publish отправляет конверт всем экземплярам, включая отправителя.
+	Publish(ctx context.Context, env Envelope) error
+	// Subscribe вызывает deliver для каждого опубликованного конверта, пока ctx не отменён.
+	Subscribe(ctx context.Context, deliver func(Envelope)) error
+}
+
+// MemoryBackplane связывает хабы внутри одного процесса. Подходит для единственного
+// экземпляра сервера и для тестов.
+type MemoryBackplane struct {
+	mu          sync.RWMutex
+	subscribers map[int]func(Envelope)
+	nextID      int
+}
+
+// NewMemoryBackplane создаёт backplane в памяти.
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{subscribers: make(map[int]func(Envelope))}
+}
+
+// Publish синхронно передаёт конверт всем подписчикам.
+func (b *MemoryBackplane) Publish(ctx context.Context, env Envelope) error {
+	b.mu.RLock()
+	subscribers := make([]func(Envelope), 0, len(b.subscribers))
+	for _, deliver := range b.subscribers {
+		subscribers = append(subscribers, deliver)
+	}
+	b.mu.RUnlock()
+
+	for _, deliver := range subscribers {
+		deliver(env)
+	}
+	return nil
+}
+
+// Subscribe регистрирует подписчика до отмены ctx.
+func (b *MemoryBackplane) Subscribe(ctx context.Context, deliver func(Envelope)) error {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = deliver
+	b.mu.Unlock()
+
+	deliver(Envelope{Kind: EnvelopeSubscribed})
+	<-ctx.Done()
+
+	b.mu.Lock()
+	delete(b.subscribers, id)
+	b.mu.Unlock()
+	return nil
+}
+
+// publish ставит конверт в очередь отправки в backplane. Без backplane ничего не делает.
+// h.backplane задаётся до Run и дальше не меняется, поэтому читается без блокировки:
+// publish вызывается в том числе под h.mu.
+func (h *Hub) publish(env Envelope) {
+	if h.backplane == nil {
+		return
+	}
+	env.Origin = h.nodeID
+	select {
+	case h.outbound <- env:
+	case <-h.ctx.Done():
+	}
+}
+
+// runBackplane отправляет очередь конвертов и держит подписку, переподключаясь после ошибок.
+func (h *Hub) runBackplane(backplane Backplane) {
+	go h.publishLoop(backplane)
+
+	for {
+		err := backplane.Subscribe(h.ctx, h.receive)
+		if h.ctx.Err() != nil {
+			return
+		}
+		fmt.Printf("ws: подписка backplane прервана, переподключение: %v\n", err)
+		select {
+		case <-time.After(5 * time.Second):
+		case <-h.ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *Hub) publishLoop(backplane Backplane) {
+	ticker := time.NewTicker(backplaneSnapshotInterval)
+	defer ticker.Stop()
+
+	send := func(ctx context.Context, env Envelope) {
+		ctx, cancel := context.WithTimeout(ctx, backplanePublishTimeout)
+		defer cancel()
+		if err := backplane.Publish(ctx, env); err != nil {
+			fmt.Printf("ws: не удалось опубликовать %s в backplane: %v\n", env.Kind, err)
+		}
+	}
+
+	for {
+		select {
+		case env := <-h.outbound:
+			send(h.ctx, env)
+		case <-ticker.C:
+			send(h.ctx, h.snapshot())
+		case <-h.ctx.Done():
+			// Остальные экземпляры сразу перестают считать наших пользователей подключёнными
+			send(context.Background(), Envelope{Origin: h.nodeID, Kind: EnvelopeBye})
+			return
+		}
+	}
+}
+
+// receive обрабатывает конверт, пришедший из backplane.
+func (h *Hub) receive(env Envelope) {
+	if env.Origin == h.nodeID {
+		return
+	}
+
+	switch env.Kind {
+	case EnvelopeSubscribed:
+		h.mu.Lock()
+		h.remote = make(map[uuid.UUID]map[string]struct{})
+		h.mu.Unlock()
+		h.publish(Envelope{Kind: EnvelopeHello})
+	case EnvelopeEvent:
+		h.broadcast <- message{userID: env.UserID, seq: env.Seq, payload: env.Payload}
+	case EnvelopeTopic:
+		h.broadcast <- message{topic: env.Topic, payload: env.Payload}
+	case EnvelopeDisconnectSession:
+		h.disconnectSession(env.UserID, env.SessionID)
+	case EnvelopeDisconnectUser:
+		h.disconnectUser(env.UserID)
+	case EnvelopePresence:
+		h.setRemotePresence(env.Origin, env.UserID, env.Online)
+	case EnvelopeHello:
+		h.markNodeSeen(env.Origin)
+		snapshot := h.snapshot()
+		h.publish(snapshot)
+	case EnvelopeSnapshot:
+		h.replaceNode(env.Origin, env.UserIDs)
+	case EnvelopeBye:
+		h.replaceNode(env.Origin, nil)
+	}
+}
+
+// snapshot возвращает конверт со всеми пользователями, подключёнными к этому экземпляру.
+func (h *Hub) snapshot() Envelope {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	users := make([]uuid.UUID, 0, len(h.clients))
+	for userID := range h.clients {
+		users = append(users, userID)
+	}
+	return Envelope{Origin: h.nodeID, Kind: EnvelopeSnapshot, UserIDs: users}
+}
+
+func (h *Hub) setRemotePresence(node string, userID uuid.UUID, online bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nodeSeen[node] = time.Now()
+	if online {
+		if h.remote[userID] == nil {
+			h.remote[userID] = make(map[string]struct{})
+		}
+		h.remote[userID][node] = struct{}{}
+		delete(h.lastSeen, userID)
+		return
+	}
+	h.dropRemote(userID, node)
+}
+
+// replaceNode заменяет известный список пользователей экземпляра node на users.
+func (h *Hub) replaceNode(node string, users []uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	current := make(map[uuid.UUID]struct{}, len(users))
+	for _, userID := range users {
+		current[userID] = struct{}{}
+		if h.remote[userID] == nil {
+			h.remote[userID] = make(map[string]struct{})
+		}
+		h.remote[userID][node] = struct{}{}
+	}
+	for userID, nodes := range h.remote {
+		if _, ok := nodes[node]; !ok {
+			continue
+		}
+		if _, ok := current[userID]; !ok {
+			h.dropRemote(userID, node)
+		}
+	}
+
+	if users == nil {
+		delete(h.nodeSeen, node)
+	} else {
+		h.nodeSeen[node] = time.Now()
+	}
+}
+
+func (h *Hub) markNodeSeen(node string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nodeSeen[node] = time.Now()
+}
+
+// dropRemote убирает соединение пользователя на экземпляре node. Вызывается под h.mu.
+func (h *Hub) dropRemote(userID uuid.UUID, node string) {
+	nodes, ok := h.remote[userID]
+	if !ok {
+		return
+	}
+	delete(nodes, node)
+	if len(nodes) == 0 {
+		delete(h.remote, userID)
+		if len(h.clients[userID]) == 0 {
+			h.lastSeen[userID] = time.Now()
+		}
+	}
+}
+
+// onlineRemotely сообщает, есть ли у пользователя соединения на экземплярах, от которых
+// недавно приходили конверты. Вызывается под h.mu.
+func (h *Hub) onlineRemotely(userID uuid.UUID) bool {
+	for node := range h.remote[userID] {
+		if time.Since(h.nodeSeen[node]) < backplaneNodeTimeout {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// startNode запускает хаб, подключённый к общему backplane, как отдельный экземпляр сервера.
+func startNode(t *testing.T, backplane Backplane) *Hub {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	hub := NewHub(ctx)
+	hub.SetBackplane(backplane)
+	go hub.Run()
+	return hub
+}
+
+func TestBackplane_DeliversEventsAcrossNodes(t *testing.T) {
+	backplane := NewMemoryBackplane()
+	nodeA := startNode(t, backplane)
+	nodeB := startNode(t, backplane)
+	userID := uuid.New()
+
+	onA := connectTo(nodeA, userID)
+	onB := connectTo(nodeB, userID)
+
+	assert.NoError(t, nodeA.BroadcastToUser(userID, "order_status_changed", map[string]string{"status": "completed"}))
+	expectEvent(t, onA, "order_status_changed", nil)
+	expectEvent(t, onB, "order_status_changed", nil)
+
+	// Экземпляр не получает собственное событие второй раз
+	select {
+	case raw := <-onA.send:
+		var event receivedEvent
+		_ = json.Unmarshal(raw, &event)
+		assert.NotEqual(t, "order_status_changed", event.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBackplane_PresenceAcrossNodes(t *testing.T) {
+	backplane := NewMemoryBackplane()
+	nodeA := startNode(t, backplane)
+	nodeB := startNode(t, backplane)
+	userID := uuid.New()
+
+	onB := connectTo(nodeB, userID)
+	assert.Eventually(t, func() bool {
+		online, _ := nodeA.Presence(userID)
+		return online
+	}, time.Second, 5*time.Millisecond)
+
+	// Экземпляр, запущенный позже, узнаёт о подключениях из снимков остальных
+	nodeC := startNode(t, backplane)
+	assert.Eventually(t, func() bool {
+		online, _ := nodeC.Presence(userID)
+		return online
+	}, time.Second, 5*time.Millisecond)
+
+	nodeB.Unregister(onB)
+	assert.Eventually(t, func() bool {
+		online, lastSeen := nodeA.Presence(userID)
+		return !online && lastSeen != nil
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestBackplane_ForgetsStoppedNode(t *testing.T) {
+	backplane := NewMemoryBackplane()
+	nodeA := startNode(t, backplane)
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	nodeB := NewHub(ctx)
+	nodeB.SetBackplane(backplane)
+	go nodeB.Run()
+
+	userID := uuid.New()
+	connectTo(nodeB, userID)
+	assert.Eventually(t, func() bool {
+		online, _ := nodeA.Presence(userID)
+		return online
+	}, time.Second, 5*time.Millisecond)
+
+	stop()
+	assert.Eventually(t, func() bool {
+		online, _ := nodeA.Presence(userID)
+		return !online
+	}, time.Second, 5*time.Millisecond)
+}
@@ -14,23 +14,35 @@ const (
 	writeWait  = 10 * time.Second
 	pongWait   = 60 * time.Second
 	pingPeriod = (pongWait * 9) / 10
+
+	// CloseSessionRevoked код закрытия соединения, сессия которого отозвана или пользователь заблокирован.
+	CloseSessionRevoked = 4001
 )
 
 // Client представляет одно подключение WebSocket.
 type Client struct {
-	conn   *websocket.Conn
-	hub    *Hub
-	userID uuid.UUID
-	send   chan []byte
+	conn      *websocket.Conn
+	hub       *Hub
+	userID    uuid.UUID
+	sessionID uuid.UUID
+	send      chan []byte
+	// peers кеширует собеседника по чату; используется только из readPump
+	peers map[uuid.UUID]uuid.UUID
+	// topics — темы, на которые подписан клиент; защищено h.mu
+	topics map[string]struct{}
+	// replay задан, если клиент подключился с ?since= и ему досылаются пропущенные события
+	replay *replayState
 }
 
-// NewClient создаёт нового клиента.
-func NewClient(conn *websocket.Conn, hub *Hub, userID uuid.UUID) *Client {
+// NewClient создаёт нового клиента; sessionID — сессия access токена, по которому открыто соединение.
+func NewClient(conn *websocket.Conn, hub *Hub, userID, sessionID uuid.UUID) *Client {
 	return &Client{
-		conn:   conn,
-		hub:    hub,
-		userID: userID,
-		send:   make(chan []byte, 16),
+		conn:      conn,
+		hub:       hub,
+		userID:    userID,
+		sessionID: sessionID,
+		send:      make(chan []byte, 16),
+		peers:     make(map[uuid.UUID]uuid.UUID),
 	}
 }
 
@@ -57,6 +69,13 @@ func (c *Client) Close() {
 	c.conn.Close()
 }
 
+// Disconnect отправляет клиенту close frame с кодом и причиной и закрывает соединение.
+func (c *Client) Disconnect(code int, reason string) {
+	// WriteControl безопасно вызывать параллельно с writePump
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
+	c.Close()
+}
+
 func (c *Client) readPump(ctx context.Context) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -78,8 +97,7 @@ func (c *Client) readPump(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		default:
-			// Читаем сообщения, но не обрабатываем их (клиент только получает сообщения от сервера)
-			_, _, err := c.conn.ReadMessage()
+			messageType, raw, err := c.conn.ReadMessage()
 			if err != nil {
 				// Логируем ошибку только если это не нормальное закрытие
 				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
@@ -87,6 +105,11 @@ func (c *Client) readPump(ctx context.Context) {
 				}
 				return
 			}
+			// Любой кадр от клиента, в том числе команда ping, продлевает соединение
+			_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+			if messageType == websocket.TextMessage {
+				c.handleFrame(ctx, raw)
+			}
 		}
 	}
 }
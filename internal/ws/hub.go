@@ -3,42 +3,102 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"runtime/debug"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
 )
 
-// NotificationSaver интерфейс для сохранения уведомлений в БД.
+// ErrEventMuted возвращает NotificationSaver, если пользователь отключил событие и его
+// не нужно отправлять даже открытым соединениям.
+var ErrEventMuted = errors.New("ws: пользователь отключил это событие")
+
+// NotificationSaver интерфейс для сохранения уведомлений в БД. Сохранённое событие получает
+// номер seq, по которому клиент после переподключения запрашивает пропущенные события.
 type NotificationSaver interface {
-	CreateNotification(ctx context.Context, userID uuid.UUID, event string, data interface{}) error
+	CreateNotification(ctx context.Context, userID uuid.UUID, event string, data interface{}) (int64, error)
+	EventsSince(ctx context.Context, userID uuid.UUID, since int64, limit int) ([]StoredEvent, error)
+}
+
+// StoredEvent — сохранённое событие хаба.
+type StoredEvent struct {
+	Seq  int64
+	Type string
+	Data json.RawMessage
+}
+
+// ConversationStore даёт хабу доступ к чатам для команд клиента: индикатора набора,
+// отметок о прочтении и присутствия.
+type ConversationStore interface {
+	GetConversationByID(ctx context.Context, id uuid.UUID) (*models.Conversation, error)
+	MarkMessagesRead(ctx context.Context, conversationID, userID, upToMessageID uuid.UUID) (*models.ReadReceipt, error)
+	ListConversationContacts(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
 }
 
 // Hub управляет всеми WebSocket клиентами.
 type Hub struct {
 	mu                sync.RWMutex
 	clients           map[uuid.UUID]map[*Client]struct{}
+	lastSeen          map[uuid.UUID]time.Time
 	register          chan *Client
 	unregister        chan *Client
 	broadcast         chan message
+	persist           []chan pendingEvent
 	notificationSaver NotificationSaver
-	ctx               context.Context
+	conversations     ConversationStore
+	orders            OrderStore
+	// topics — подписчики тем на этом экземпляре
+	topics map[string]map[*Client]struct{}
+	ctx    context.Context
+
+	// nodeID отличает этот экземпляр в backplane; remote — на каких других экземплярах
+	// у пользователя есть соединения
+	nodeID    string
+	backplane Backplane
+	outbound  chan Envelope
+	remote    map[uuid.UUID]map[string]struct{}
+	nodeSeen  map[string]time.Time
 }
 
+// message адресовано соединениям пользователя userID или, если задан topic, подписчикам темы.
 type message struct {
 	userID  uuid.UUID
+	topic   string
+	seq     int64
 	payload []byte
 }
 
+// pendingEvent ждёт сохранения и номера перед отправкой.
+type pendingEvent struct {
+	userID uuid.UUID
+	event  string
+	data   any
+}
+
 // NewHub создаёт новый хаб.
 func NewHub(ctx context.Context) *Hub {
+	persist := make([]chan pendingEvent, persistWorkers)
+	for i := range persist {
+		persist[i] = make(chan pendingEvent, 64)
+	}
 	return &Hub{
+		persist:    persist,
 		clients:    make(map[uuid.UUID]map[*Client]struct{}),
+		lastSeen:   make(map[uuid.UUID]time.Time),
+		topics:     make(map[string]map[*Client]struct{}),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan message, 32),
 		ctx:        ctx,
+		nodeID:     uuid.NewString(),
+		outbound:   make(chan Envelope, 256),
+		remote:     make(map[uuid.UUID]map[string]struct{}),
+		nodeSeen:   make(map[string]time.Time),
 	}
 }
 
@@ -49,8 +109,34 @@ func (h *Hub) SetNotificationSaver(saver NotificationSaver) {
 	h.notificationSaver = saver
 }
 
+// SetConversationStore подключает доступ к чатам; без него команды typing, message.read
+// и presence отклоняются.
+func (h *Hub) SetConversationStore(store ConversationStore) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conversations = store
+}
+
+// SetBackplane подключает доставку событий через другие экземпляры сервера.
+// Вызывается до Run.
+func (h *Hub) SetBackplane(backplane Backplane) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.backplane = backplane
+}
+
 // Run запускает главный цикл хаба.
 func (h *Hub) Run() {
+	h.mu.RLock()
+	backplane := h.backplane
+	h.mu.RUnlock()
+	if backplane != nil {
+		go h.runBackplane(backplane)
+	}
+	for _, queue := range h.persist {
+		go h.persistLoop(queue)
+	}
+
 	for {
 		select {
 		case client := <-h.register:
@@ -58,7 +144,7 @@ func (h *Hub) Run() {
 		case client := <-h.unregister:
 			h.removeClient(client)
 		case msg := <-h.broadcast:
-			h.send(msg.userID, msg.payload)
+			h.send(msg)
 		}
 	}
 }
@@ -73,42 +159,164 @@ func (h *Hub) Unregister(client *Client) {
 	h.unregister <- client
 }
 
-// BroadcastToUser отправляет сообщение конкретному пользователю и сохраняет уведомление в БД.
+// BroadcastToUser сохраняет уведомление в БД и отправляет его пользователю с номером seq.
+// Сохранение выполняется в фоне, не задерживая вызывающего; события одного пользователя
+// обрабатывает один обработчик, поэтому они уходят в порядке номеров.
 func (h *Hub) BroadcastToUser(userID uuid.UUID, event string, data any) error {
-	// Сообщение для клиента строго следует контракту WebSocket API:
-	// поле "type" содержит имя события, "data" — полезную нагрузку.
+	h.mu.RLock()
+	saver := h.notificationSaver
+	h.mu.RUnlock()
+
+	if saver == nil {
+		raw, err := encodeEvent(event, data, 0)
+		if err != nil {
+			return err
+		}
+		h.deliver(userID, raw, 0)
+		return nil
+	}
+
+	// Проверяем сериализацию сразу, чтобы вызывающий получил ошибку
+	if _, err := json.Marshal(data); err != nil {
+		return fmt.Errorf("ws: не удалось сериализовать сообщение: %w", err)
+	}
+	h.persist[persistShard(userID)] <- pendingEvent{userID: userID, event: event, data: data}
+	return nil
+}
+
+// persistLoop сохраняет события очереди и отправляет их с выданным номером.
+func (h *Hub) persistLoop(queue chan pendingEvent) {
+	for {
+		select {
+		case ev := <-queue:
+			h.persistAndDeliver(ev)
+		case <-h.ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *Hub) persistAndDeliver(ev pendingEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("WebSocket notification save panic recovered: %v\nStack trace:\n%s\n", r, debug.Stack())
+		}
+	}()
+
+	h.mu.RLock()
+	saver := h.notificationSaver
+	h.mu.RUnlock()
+
+	seq, err := saver.CreateNotification(h.ctx, ev.userID, ev.event, ev.data)
+	if errors.Is(err, ErrEventMuted) {
+		return
+	}
+	if err != nil {
+		// Логируем ошибку, но не прерываем отправку через WebSocket: событие уйдёт без номера
+		fmt.Printf("ws: не удалось сохранить уведомление: %v\n", err)
+		seq = 0
+	}
+
+	raw, err := encodeEvent(ev.event, ev.data, seq)
+	if err != nil {
+		fmt.Printf("ws: %v\n", err)
+		return
+	}
+	h.deliver(ev.userID, raw, seq)
+}
+
+// persistShard выбирает очередь сохранения по пользователю.
+func persistShard(userID uuid.UUID) int {
+	return int(userID[len(userID)-1]) % persistWorkers
+}
+
+// SendToUser отправляет событие подключениям пользователя, не сохраняя его в уведомления.
+// Используется для событий, которые имеют смысл только в реальном времени: набор текста,
+// присутствие, отметки о прочтении.
+func (h *Hub) SendToUser(userID uuid.UUID, event string, data any) error {
+	raw, err := encodeEvent(event, data, 0)
+	if err != nil {
+		return err
+	}
+	h.deliver(userID, raw, 0)
+	return nil
+}
+
+// deliver отправляет событие соединениям пользователя на этом экземпляре и публикует его
+// для остальных.
+func (h *Hub) deliver(userID uuid.UUID, raw []byte, seq int64) {
+	h.broadcast <- message{userID: userID, seq: seq, payload: raw}
+	h.publish(Envelope{Kind: EnvelopeEvent, UserID: userID, Seq: seq, Payload: raw})
+}
+
+// Presence сообщает, есть ли у пользователя открытые соединения, и когда закрылось последнее.
+// lastSeen равен nil, если пользователь в сети или не подключался с момента запуска сервера.
+func (h *Hub) Presence(userID uuid.UUID) (online bool, lastSeen *time.Time) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.clients[userID]) > 0 || h.onlineRemotely(userID) {
+		return true, nil
+	}
+	if at, ok := h.lastSeen[userID]; ok {
+		return false, &at
+	}
+	return false, nil
+}
+
+// encodeEvent сериализует событие строго по контракту WebSocket API:
+// поле "type" содержит имя события, "data" — полезную нагрузку, "seq" — номер
+// сохранённого события (у событий без сохранения его нет).
+func encodeEvent(event string, data any, seq int64) ([]byte, error) {
 	payload := map[string]any{
 		"type": event,
 		"data": data,
 	}
+	if seq > 0 {
+		payload["seq"] = seq
+	}
 	raw, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("ws: не удалось сериализовать сообщение: %w", err)
+		return nil, fmt.Errorf("ws: не удалось сериализовать сообщение: %w", err)
 	}
+	return raw, nil
+}
 
-	// Сохраняем уведомление в БД, если установлен notification saver
+// DisconnectSession закрывает соединения пользователя, открытые с токенами сессии sessionID,
+// на всех экземплярах.
+func (h *Hub) DisconnectSession(userID, sessionID uuid.UUID) {
+	h.disconnectSession(userID, sessionID)
+	h.publish(Envelope{Kind: EnvelopeDisconnectSession, UserID: userID, SessionID: sessionID})
+}
+
+// DisconnectUser закрывает все соединения пользователя на всех экземплярах.
+func (h *Hub) DisconnectUser(userID uuid.UUID) {
+	h.disconnectUser(userID)
+	h.publish(Envelope{Kind: EnvelopeDisconnectUser, UserID: userID})
+}
+
+func (h *Hub) disconnectSession(userID, sessionID uuid.UUID) {
+	h.disconnect(userID, func(c *Client) bool { return c.sessionID == sessionID }, "session revoked")
+}
+
+func (h *Hub) disconnectUser(userID uuid.UUID) {
+	h.disconnect(userID, func(*Client) bool { return true }, "account suspended")
+}
+
+func (h *Hub) disconnect(userID uuid.UUID, match func(*Client) bool, reason string) {
 	h.mu.RLock()
-	saver := h.notificationSaver
-	ctx := h.ctx
+	var targets []*Client
+	for client := range h.clients[userID] {
+		if match(client) {
+			targets = append(targets, client)
+		}
+	}
 	h.mu.RUnlock()
 
-	if saver != nil {
-		// Сохраняем асинхронно, чтобы не блокировать отправку (с panic recovery)
-		go func() {
-			defer func() {
-				if r := recover(); r != nil {
-					fmt.Printf("WebSocket notification save panic recovered: %v\nStack trace:\n%s\n", r, debug.Stack())
-				}
-			}()
-			if err := saver.CreateNotification(ctx, userID, event, data); err != nil {
-				// Логируем ошибку, но не прерываем отправку через WebSocket
-				fmt.Printf("ws: не удалось сохранить уведомление: %v\n", err)
-			}
-		}()
-	}
-
-	h.broadcast <- message{userID: userID, payload: raw}
-	return nil
+	// Close снимает клиента с регистрации через главный цикл, поэтому вызывается без блокировки
+	for _, client := range targets {
+		go client.Disconnect(CloseSessionRevoked, reason)
+	}
 }
 
 func (h *Hub) addClient(client *Client) {
@@ -117,29 +325,90 @@ func (h *Hub) addClient(client *Client) {
 
 	if _, ok := h.clients[client.userID]; !ok {
 		h.clients[client.userID] = make(map[*Client]struct{})
+		delete(h.lastSeen, client.userID)
+		// Публикуется под блокировкой, чтобы подключение и отключение дошли до других
+		// экземпляров в том порядке, в котором произошли
+		h.publish(Envelope{Kind: EnvelopePresence, UserID: client.userID, Online: true})
+		go h.announcePresence(client.userID)
 	}
 	h.clients[client.userID][client] = struct{}{}
+	// Досылка начинается после регистрации: всё, что придёт дальше, будет отложено до её конца
+	if client.replay != nil {
+		go client.runReplay()
+	}
 }
 
 func (h *Hub) removeClient(client *Client) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if clients, ok := h.clients[client.userID]; ok {
-		delete(clients, client)
-		if len(clients) == 0 {
-			delete(h.clients, client.userID)
+	clients, ok := h.clients[client.userID]
+	if !ok {
+		return
+	}
+	if _, registered := clients[client]; !registered {
+		return
+	}
+	delete(clients, client)
+	for topic := range client.topics {
+		h.dropSubscription(client, topic)
+	}
+	if len(clients) == 0 {
+		delete(h.clients, client.userID)
+		h.lastSeen[client.userID] = time.Now()
+		h.publish(Envelope{Kind: EnvelopePresence, UserID: client.userID, Online: false})
+		go h.announcePresence(client.userID)
+	}
+}
+
+// announcePresence сообщает собеседникам пользователя, которые сейчас в сети,
+// о его подключении или отключении. Отправляется текущее состояние, а не то, что было
+// при вызове, поэтому быстрые переподключения не оставляют у собеседников устаревший статус.
+func (h *Hub) announcePresence(userID uuid.UUID) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("WebSocket presence panic recovered: %v\nStack trace:\n%s\n", r, debug.Stack())
+		}
+	}()
+
+	h.mu.RLock()
+	store := h.conversations
+	h.mu.RUnlock()
+	if store == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, commandTimeout)
+	defer cancel()
+	contacts, err := store.ListConversationContacts(ctx, userID)
+	if err != nil {
+		fmt.Printf("ws: не удалось получить собеседников для присутствия: %v\n", err)
+		return
+	}
+
+	state := h.presenceState(userID)
+	for _, contact := range contacts {
+		if contactOnline, _ := h.Presence(contact); contactOnline {
+			_ = h.SendToUser(contact, EventPresence, state)
 		}
 	}
 }
 
-func (h *Hub) send(userID uuid.UUID, payload []byte) {
+func (h *Hub) send(msg message) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	for client := range h.clients[userID] {
+	recipients := h.clients[msg.userID]
+	if msg.topic != "" {
+		recipients = h.topics[msg.topic]
+	}
+	for client := range recipients {
+		// Пока клиенту досылаются пропущенные события, новые откладываются
+		if client.holdDuringReplay(msg) {
+			continue
+		}
 		select {
-		case client.send <- payload:
+		case client.send <- msg.payload:
 		default:
 			// Закрываем клиент асинхронно с panic recovery
 			go func(c *Client) {
@@ -0,0 +1,218 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+// fakeConversationStore хранит чаты в памяти и запоминает отметки о прочтении.
+type fakeConversationStore struct {
+	conversations map[uuid.UUID]*models.Conversation
+	reads         []models.ReadReceipt
+}
+
+func (s *fakeConversationStore) GetConversationByID(ctx context.Context, id uuid.UUID) (*models.Conversation, error) {
+	conv, ok := s.conversations[id]
+	if !ok {
+		return nil, repository.ErrConversationNotFound
+	}
+	return conv, nil
+}
+
+func (s *fakeConversationStore) MarkMessagesRead(ctx context.Context, conversationID, userID, upToMessageID uuid.UUID) (*models.ReadReceipt, error) {
+	receipt := models.ReadReceipt{
+		ConversationID: conversationID,
+		ReaderID:       userID,
+		MessageIDs:     []uuid.UUID{upToMessageID},
+		ReadAt:         time.Now(),
+	}
+	s.reads = append(s.reads, receipt)
+	return &receipt, nil
+}
+
+func (s *fakeConversationStore) ListConversationContacts(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	var contacts []uuid.UUID
+	for _, conv := range s.conversations {
+		switch userID {
+		case conv.ClientID:
+			contacts = append(contacts, conv.FreelancerID)
+		case conv.FreelancerID:
+			contacts = append(contacts, conv.ClientID)
+		}
+	}
+	return contacts, nil
+}
+
+type receivedEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type hubFixture struct {
+	hub        *Hub
+	store      *fakeConversationStore
+	conv       *models.Conversation
+	client     uuid.UUID
+	freelancer uuid.UUID
+}
+
+func setupHub(t *testing.T) *hubFixture {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	conv := &models.Conversation{ID: uuid.New(), ClientID: uuid.New(), FreelancerID: uuid.New()}
+	store := &fakeConversationStore{conversations: map[uuid.UUID]*models.Conversation{conv.ID: conv}}
+	hub := NewHub(ctx)
+	hub.SetConversationStore(store)
+	go hub.Run()
+
+	return &hubFixture{hub: hub, store: store, conv: conv, client: conv.ClientID, freelancer: conv.FreelancerID}
+}
+
+func (f *hubFixture) connect(userID uuid.UUID) *Client {
+	return connectTo(f.hub, userID)
+}
+
+// connectTo регистрирует клиента без сетевого соединения: команды подаются напрямую в handleFrame.
+func connectTo(hub *Hub, userID uuid.UUID) *Client {
+	c := &Client{hub: hub, userID: userID, send: make(chan []byte, 16), peers: make(map[uuid.UUID]uuid.UUID)}
+	hub.Register(c)
+	return c
+}
+
+func (c *Client) command(t *testing.T, frame string) {
+	t.Helper()
+	c.handleFrame(context.Background(), []byte(frame))
+}
+
+// expectEvent ждёт событие нужного типа, пропуская остальные.
+func expectEvent(t *testing.T, c *Client, eventType string, out any) {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case raw := <-c.send:
+			var event receivedEvent
+			assert.NoError(t, json.Unmarshal(raw, &event))
+			if event.Type != eventType {
+				continue
+			}
+			if out != nil {
+				assert.NoError(t, json.Unmarshal(event.Data, out))
+			}
+			return
+		case <-timeout:
+			t.Fatalf("не дождались события %s", eventType)
+		}
+	}
+}
+
+func TestClient_ProtocolBasics(t *testing.T) {
+	f := setupHub(t)
+	c := f.connect(f.client)
+
+	c.command(t, `{"v":1,"id":"p1","type":"ping"}`)
+	var pong pongPayload
+	expectEvent(t, c, EventPong, &pong)
+	assert.Equal(t, "p1", pong.ID)
+
+	c.command(t, `{"v":2,"id":"v2","type":"ping"}`)
+	var failure errorPayload
+	expectEvent(t, c, EventError, &failure)
+	assert.Equal(t, ErrorCodeUnsupportedVersion, failure.Code)
+
+	c.command(t, `{"v":1,"id":"x","type":"call.start"}`)
+	expectEvent(t, c, EventError, &failure)
+	assert.Equal(t, ErrorCodeUnknownCommand, failure.Code)
+
+	c.command(t, `not json`)
+	expectEvent(t, c, EventError, &failure)
+	assert.Equal(t, ErrorCodeBadRequest, failure.Code)
+}
+
+func TestClient_TypingReachesPeerOnly(t *testing.T) {
+	f := setupHub(t)
+	client := f.connect(f.client)
+	freelancer := f.connect(f.freelancer)
+	stranger := f.connect(uuid.New())
+
+	client.command(t, `{"v":1,"type":"typing","data":{"conversation_id":"`+f.conv.ID.String()+`","is_typing":true}}`)
+	var typing typingEvent
+	expectEvent(t, freelancer, EventTyping, &typing)
+	assert.Equal(t, f.conv.ID, typing.ConversationID)
+	assert.Equal(t, f.client, typing.UserID)
+	assert.True(t, typing.IsTyping)
+
+	stranger.command(t, `{"v":1,"id":"t1","type":"typing","data":{"conversation_id":"`+f.conv.ID.String()+`","is_typing":true}}`)
+	var failure errorPayload
+	expectEvent(t, stranger, EventError, &failure)
+	assert.Equal(t, "t1", failure.ID)
+	assert.Equal(t, ErrorCodeForbidden, failure.Code)
+
+	client.command(t, `{"v":1,"type":"typing","data":{"conversation_id":"`+uuid.NewString()+`"}}`)
+	expectEvent(t, client, EventError, &failure)
+	assert.Equal(t, ErrorCodeNotFound, failure.Code)
+}
+
+func TestClient_MessageReadNotifiesPeer(t *testing.T) {
+	f := setupHub(t)
+	client := f.connect(f.client)
+	freelancer := f.connect(f.freelancer)
+	messageID := uuid.New()
+
+	freelancer.command(t, `{"v":1,"id":"r1","type":"message.read","data":{"conversation_id":"`+f.conv.ID.String()+`","message_id":"`+messageID.String()+`"}}`)
+
+	var ack struct {
+		ID     string             `json:"id"`
+		Type   string             `json:"type"`
+		Result models.ReadReceipt `json:"result"`
+	}
+	expectEvent(t, freelancer, EventAck, &ack)
+	assert.Equal(t, "r1", ack.ID)
+	assert.Equal(t, []uuid.UUID{messageID}, ack.Result.MessageIDs)
+
+	var receipt models.ReadReceipt
+	expectEvent(t, client, EventMessageRead, &receipt)
+	assert.Equal(t, f.freelancer, receipt.ReaderID)
+	assert.Equal(t, []uuid.UUID{messageID}, receipt.MessageIDs)
+	assert.Len(t, f.store.reads, 1)
+}
+
+func TestHub_PresenceVisibleToContacts(t *testing.T) {
+	f := setupHub(t)
+	client := f.connect(f.client)
+
+	freelancer := f.connect(f.freelancer)
+	var state presenceState
+	expectEvent(t, client, EventPresence, &state)
+	assert.Equal(t, f.freelancer, state.UserID)
+	assert.True(t, state.Online)
+
+	stranger := uuid.New()
+	client.command(t, `{"v":1,"id":"q1","type":"presence","data":{"user_ids":["`+f.freelancer.String()+`","`+stranger.String()+`"]}}`)
+	var ack struct {
+		Result []presenceState `json:"result"`
+	}
+	expectEvent(t, client, EventAck, &ack)
+	assert.Len(t, ack.Result, 1)
+	assert.Equal(t, f.freelancer, ack.Result[0].UserID)
+	assert.True(t, ack.Result[0].Online)
+
+	f.hub.Unregister(freelancer)
+	expectEvent(t, client, EventPresence, &state)
+	assert.False(t, state.Online)
+	assert.NotNil(t, state.LastSeenAt)
+
+	online, lastSeen := f.hub.Presence(f.freelancer)
+	assert.False(t, online)
+	assert.NotNil(t, lastSeen)
+}
@@ -2,26 +2,56 @@ package ws
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
 )
 
 // NotificationServiceAdapter адаптирует NotificationService для использования в Hub.
 type NotificationServiceAdapter struct {
 	service interface {
-		CreateNotificationForWS(ctx context.Context, userID uuid.UUID, event string, data interface{}) error
+		CreateNotificationForWS(ctx context.Context, userID uuid.UUID, event string, data interface{}) (int64, bool, error)
+		ListEventsSince(ctx context.Context, userID uuid.UUID, since int64, limit int) ([]models.Notification, error)
 	}
 }
 
 // NewNotificationServiceAdapter создаёт новый адаптер.
 func NewNotificationServiceAdapter(service interface {
-	CreateNotificationForWS(ctx context.Context, userID uuid.UUID, event string, data interface{}) error
+	CreateNotificationForWS(ctx context.Context, userID uuid.UUID, event string, data interface{}) (int64, bool, error)
+	ListEventsSince(ctx context.Context, userID uuid.UUID, since int64, limit int) ([]models.Notification, error)
 }) *NotificationServiceAdapter {
 	return &NotificationServiceAdapter{service: service}
 }
 
 // CreateNotification реализует интерфейс NotificationSaver.
-func (a *NotificationServiceAdapter) CreateNotification(ctx context.Context, userID uuid.UUID, event string, data interface{}) error {
-	return a.service.CreateNotificationForWS(ctx, userID, event, data)
+func (a *NotificationServiceAdapter) CreateNotification(ctx context.Context, userID uuid.UUID, event string, data interface{}) (int64, error) {
+	seq, live, err := a.service.CreateNotificationForWS(ctx, userID, event, data)
+	if err == nil && !live {
+		return 0, ErrEventMuted
+	}
+	return seq, err
 }
 
+// EventsSince реализует интерфейс NotificationSaver. Уведомление хранится как {"event", "data"},
+// клиенту оно уходит в форме события хаба {"type", "data", "seq"}.
+func (a *NotificationServiceAdapter) EventsSince(ctx context.Context, userID uuid.UUID, since int64, limit int) ([]StoredEvent, error) {
+	notifications, err := a.service.ListEventsSince(ctx, userID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]StoredEvent, 0, len(notifications))
+	for _, n := range notifications {
+		var payload struct {
+			Event string          `json:"event"`
+			Data  json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(n.Payload, &payload); err != nil || n.Seq == nil {
+			continue
+		}
+		events = append(events, StoredEvent{Seq: *n.Seq, Type: payload.Event, Data: payload.Data})
+	}
+	return events, nil
+}
@@ -0,0 +1,130 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	// backplaneChannel — канал LISTEN/NOTIFY, общий для всех экземпляров.
+	backplaneChannel = "ws_backplane"
+	// maxNotifyPayload — NOTIFY принимает до 8000 байт; конверты крупнее сохраняются
+	// в ws_backplane_events, а в канал уходит только ссылка на строку.
+	maxNotifyPayload   = 7900
+	backplaneRefPrefix = "ref:"
+	// backplaneEventsRetention — сколько хранятся крупные конверты; к этому времени их
+	// давно прочитали все экземпляры.
+	backplaneEventsRetention = 5 * time.Minute
+)
+
+// PostgresBackplane пересылает конверты между экземплярами через LISTEN/NOTIFY.
+type PostgresBackplane struct {
+	db  *sqlx.DB
+	dsn string
+}
+
+// NewPostgresBackplane создаёт backplane; dsn нужен для отдельного соединения LISTEN,
+// которое не берётся из пула db.
+func NewPostgresBackplane(db *sqlx.DB, dsn string) *PostgresBackplane {
+	return &PostgresBackplane{db: db, dsn: dsn}
+}
+
+// Publish отправляет конверт через NOTIFY.
+func (b *PostgresBackplane) Publish(ctx context.Context, env Envelope) error {
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("ws backplane: marshal %w", err)
+	}
+
+	payload := string(raw)
+	if len(raw) > maxNotifyPayload {
+		var id int64
+		if err := b.db.QueryRowContext(ctx, `
+			INSERT INTO ws_backplane_events (payload) VALUES ($1) RETURNING id
+		`, string(raw)).Scan(&id); err != nil {
+			return fmt.Errorf("ws backplane: store large envelope %w", err)
+		}
+		payload = backplaneRefPrefix + strconv.FormatInt(id, 10)
+	}
+
+	if _, err := b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, backplaneChannel, payload); err != nil {
+		return fmt.Errorf("ws backplane: notify %w", err)
+	}
+	return nil
+}
+
+// Subscribe слушает канал до отмены ctx. EnvelopeSubscribed передаётся после LISTEN и после
+// каждого восстановления соединения: конверты, отправленные за время разрыва, потеряны.
+func (b *PostgresBackplane) Subscribe(ctx context.Context, deliver func(Envelope)) error {
+	listener := pq.NewListener(b.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			fmt.Printf("ws backplane: ошибка соединения LISTEN: %v\n", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(backplaneChannel); err != nil {
+		return fmt.Errorf("ws backplane: listen %w", err)
+	}
+	deliver(Envelope{Kind: EnvelopeSubscribed})
+
+	ping := time.NewTicker(time.Minute)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			if n == nil {
+				deliver(Envelope{Kind: EnvelopeSubscribed})
+				continue
+			}
+			env, err := b.decode(ctx, n.Extra)
+			if err != nil {
+				fmt.Printf("ws backplane: не удалось разобрать конверт: %v\n", err)
+				continue
+			}
+			deliver(env)
+		case <-ping.C:
+			go func() { _ = listener.Ping() }()
+			b.cleanup(ctx)
+		}
+	}
+}
+
+func (b *PostgresBackplane) decode(ctx context.Context, payload string) (Envelope, error) {
+	raw := []byte(payload)
+	if ref, ok := strings.CutPrefix(payload, backplaneRefPrefix); ok {
+		id, err := strconv.ParseInt(ref, 10, 64)
+		if err != nil {
+			return Envelope{}, err
+		}
+		if err := b.db.GetContext(ctx, &raw, `SELECT payload FROM ws_backplane_events WHERE id = $1`, id); err != nil {
+			return Envelope{}, fmt.Errorf("ws backplane: load large envelope %w", err)
+		}
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return Envelope{}, err
+	}
+	return env, nil
+}
+
+// cleanup удаляет прочитанные крупные конверты. Выполняется всеми экземплярами, это безопасно.
+func (b *PostgresBackplane) cleanup(ctx context.Context) {
+	_, err := b.db.ExecContext(ctx, `
+		DELETE FROM ws_backplane_events WHERE created_at < NOW() - $1 * INTERVAL '1 second'
+	`, backplaneEventsRetention.Seconds())
+	if err != nil {
+		fmt.Printf("ws backplane: не удалось очистить ws_backplane_events: %v\n", err)
+	}
+}
@@ -0,0 +1,316 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+// ProtocolVersion версия протокола команд, которые клиент отправляет по WebSocket.
+const ProtocolVersion = 1
+
+// Команды клиента.
+const (
+	CommandPing        = "ping"
+	CommandTyping      = "typing"
+	CommandMessageRead = "message.read"
+	CommandPresence    = "presence"
+)
+
+// События, которые сервер отправляет в ответ на команды или по их результату.
+const (
+	EventPong        = "pong"
+	EventAck         = "ack"
+	EventError       = "error"
+	EventTyping      = "typing"
+	EventMessageRead = "message.read"
+	EventPresence    = "presence"
+)
+
+// Коды ошибок команд.
+const (
+	ErrorCodeUnsupportedVersion = "unsupported_version"
+	ErrorCodeBadRequest         = "bad_request"
+	ErrorCodeUnknownCommand     = "unknown_command"
+	ErrorCodeForbidden          = "forbidden"
+	ErrorCodeNotFound           = "not_found"
+	ErrorCodeUnavailable        = "unavailable"
+	ErrorCodeInternal           = "internal"
+)
+
+const (
+	commandTimeout = 5 * time.Second
+	// maxPresenceUsers ограничивает число пользователей в одном запросе присутствия.
+	maxPresenceUsers = 100
+)
+
+var (
+	errNotParticipant = errors.New("ws: нет доступа к этому чату")
+	errStoreMissing   = errors.New("ws: чаты недоступны")
+)
+
+// inboundFrame — команда клиента. ID необязателен; если он указан, сервер повторит его в ack или error.
+type inboundFrame struct {
+	V    int             `json:"v"`
+	ID   string          `json:"id,omitempty"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+type ackPayload struct {
+	ID     string `json:"id,omitempty"`
+	Type   string `json:"type"`
+	Result any    `json:"result,omitempty"`
+}
+
+type errorPayload struct {
+	ID    string `json:"id,omitempty"`
+	Type  string `json:"type,omitempty"`
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+type pongPayload struct {
+	ID         string    `json:"id,omitempty"`
+	ServerTime time.Time `json:"server_time"`
+}
+
+type typingCommand struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	IsTyping       bool      `json:"is_typing"`
+}
+
+type typingEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	IsTyping       bool      `json:"is_typing"`
+}
+
+type messageReadCommand struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	MessageID      uuid.UUID `json:"message_id"`
+}
+
+type presenceCommand struct {
+	UserIDs []uuid.UUID `json:"user_ids"`
+}
+
+type presenceState struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	Online     bool       `json:"online"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+func (h *Hub) presenceState(userID uuid.UUID) presenceState {
+	online, lastSeen := h.Presence(userID)
+	return presenceState{UserID: userID, Online: online, LastSeenAt: lastSeen}
+}
+
+func (h *Hub) conversationStore() ConversationStore {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.conversations
+}
+
+// handleFrame разбирает и выполняет команду клиента.
+func (c *Client) handleFrame(ctx context.Context, raw []byte) {
+	var frame inboundFrame
+	if err := json.Unmarshal(raw, &frame); err != nil {
+		c.replyError(frame, ErrorCodeBadRequest, "некорректный JSON")
+		return
+	}
+	if frame.V != ProtocolVersion {
+		c.replyError(frame, ErrorCodeUnsupportedVersion, fmt.Sprintf("поддерживается версия протокола %d", ProtocolVersion))
+		return
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	var (
+		result any
+		err    error
+	)
+	switch frame.Type {
+	case CommandPing:
+		c.reply(EventPong, pongPayload{ID: frame.ID, ServerTime: time.Now().UTC()})
+		return
+	case CommandTyping:
+		err = c.handleTyping(cmdCtx, frame.Data)
+	case CommandMessageRead:
+		result, err = c.handleMessageRead(cmdCtx, frame.Data)
+	case CommandPresence:
+		result, err = c.handlePresence(cmdCtx, frame.Data)
+	case CommandSubscribe:
+		result, err = c.handleSubscribe(cmdCtx, frame.Data)
+	case CommandUnsubscribe:
+		result, err = c.handleUnsubscribe(frame.Data)
+	default:
+		c.replyError(frame, ErrorCodeUnknownCommand, fmt.Sprintf("неизвестная команда %q", frame.Type))
+		return
+	}
+
+	if err != nil {
+		code, msg := commandErrorCode(err)
+		c.replyError(frame, code, msg)
+		return
+	}
+	if frame.ID != "" {
+		c.reply(EventAck, ackPayload{ID: frame.ID, Type: frame.Type, Result: result})
+	}
+}
+
+func (c *Client) handleTyping(ctx context.Context, data json.RawMessage) error {
+	var cmd typingCommand
+	if err := json.Unmarshal(data, &cmd); err != nil || cmd.ConversationID == uuid.Nil {
+		return errBadRequest("conversation_id обязателен")
+	}
+
+	peer, err := c.conversationPeer(ctx, cmd.ConversationID)
+	if err != nil {
+		return err
+	}
+	return c.hub.SendToUser(peer, EventTyping, typingEvent{
+		ConversationID: cmd.ConversationID,
+		UserID:         c.userID,
+		IsTyping:       cmd.IsTyping,
+	})
+}
+
+func (c *Client) handleMessageRead(ctx context.Context, data json.RawMessage) (any, error) {
+	var cmd messageReadCommand
+	if err := json.Unmarshal(data, &cmd); err != nil || cmd.ConversationID == uuid.Nil || cmd.MessageID == uuid.Nil {
+		return nil, errBadRequest("conversation_id и message_id обязательны")
+	}
+
+	peer, err := c.conversationPeer(ctx, cmd.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	receipt, err := c.hub.conversationStore().MarkMessagesRead(ctx, cmd.ConversationID, c.userID, cmd.MessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Собеседник видит отметки о прочтении, остальные устройства читателя — обновлённый счётчик
+	if len(receipt.MessageIDs) > 0 {
+		_ = c.hub.SendToUser(peer, EventMessageRead, receipt)
+		_ = c.hub.SendToUser(c.userID, EventMessageRead, receipt)
+	}
+	return receipt, nil
+}
+
+func (c *Client) handlePresence(ctx context.Context, data json.RawMessage) (any, error) {
+	var cmd presenceCommand
+	if err := json.Unmarshal(data, &cmd); err != nil || len(cmd.UserIDs) == 0 {
+		return nil, errBadRequest("user_ids обязателен")
+	}
+	if len(cmd.UserIDs) > maxPresenceUsers {
+		return nil, errBadRequest(fmt.Sprintf("не больше %d пользователей за запрос", maxPresenceUsers))
+	}
+
+	store := c.hub.conversationStore()
+	if store == nil {
+		return nil, errStoreMissing
+	}
+	contacts, err := store.ListConversationContacts(ctx, c.userID)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make(map[uuid.UUID]struct{}, len(contacts))
+	for _, contact := range contacts {
+		allowed[contact] = struct{}{}
+	}
+
+	// Присутствие видно только собеседникам, остальные идентификаторы пропускаются
+	states := make([]presenceState, 0, len(cmd.UserIDs))
+	for _, userID := range cmd.UserIDs {
+		if _, ok := allowed[userID]; ok {
+			states = append(states, c.hub.presenceState(userID))
+		}
+	}
+	return states, nil
+}
+
+// conversationPeer возвращает собеседника в чате, проверяя, что клиент в нём участвует.
+// Участники чата не меняются, поэтому результат кешируется на время соединения.
+func (c *Client) conversationPeer(ctx context.Context, conversationID uuid.UUID) (uuid.UUID, error) {
+	if peer, ok := c.peers[conversationID]; ok {
+		return peer, nil
+	}
+
+	store := c.hub.conversationStore()
+	if store == nil {
+		return uuid.Nil, errStoreMissing
+	}
+	conv, err := store.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var peer uuid.UUID
+	switch c.userID {
+	case conv.ClientID:
+		peer = conv.FreelancerID
+	case conv.FreelancerID:
+		peer = conv.ClientID
+	default:
+		return uuid.Nil, errNotParticipant
+	}
+	c.peers[conversationID] = peer
+	return peer, nil
+}
+
+type badRequestError struct{ msg string }
+
+func (e badRequestError) Error() string { return e.msg }
+
+func errBadRequest(msg string) error { return badRequestError{msg: msg} }
+
+func commandErrorCode(err error) (string, string) {
+	var badRequest badRequestError
+	switch {
+	case errors.As(err, &badRequest):
+		return ErrorCodeBadRequest, badRequest.msg
+	case errors.Is(err, errNotParticipant):
+		return ErrorCodeForbidden, "нет доступа к этому чату"
+	case errors.Is(err, repository.ErrConversationNotFound):
+		return ErrorCodeNotFound, "чат не найден"
+	case errors.Is(err, repository.ErrMessageNotFound):
+		return ErrorCodeNotFound, "сообщение не найдено в этом чате"
+	case errors.Is(err, errTopicForbidden):
+		return ErrorCodeForbidden, "нет доступа к этой теме"
+	case errors.Is(err, repository.ErrOrderNotFound):
+		return ErrorCodeNotFound, "заказ не найден"
+	case errors.Is(err, errStoreMissing):
+		return ErrorCodeUnavailable, "команда временно недоступна"
+	case errors.Is(err, errClientClosed):
+		return ErrorCodeUnavailable, "соединение закрыто"
+	default:
+		fmt.Printf("ws: ошибка выполнения команды: %v\n", err)
+		return ErrorCodeInternal, "внутренняя ошибка"
+	}
+}
+
+// reply ставит ответ клиенту в очередь отправки. Если очередь переполнена, ответ
+// отбрасывается: медленный клиент всё равно будет отключён хабом при следующей рассылке.
+func (c *Client) reply(event string, data any) {
+	raw, err := encodeEvent(event, data, 0)
+	if err != nil {
+		return
+	}
+	select {
+	case c.send <- raw:
+	default:
+	}
+}
+
+func (c *Client) replyError(frame inboundFrame, code, msg string) {
+	c.reply(EventError, errorPayload{ID: frame.ID, Type: frame.Type, Code: code, Error: msg})
+}
@@ -0,0 +1,170 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventReplayCompleted сервер отправляет после досылки пропущенных событий.
+const EventReplayCompleted = "replay.completed"
+
+const (
+	// persistWorkers — число очередей сохранения событий; события одного пользователя
+	// всегда попадают в одну очередь.
+	persistWorkers = 8
+	// replayLimit — сколько пропущенных событий досылается при переподключении. Если пропущено
+	// больше, клиент получает truncated и загружает состояние через REST.
+	replayLimit = 200
+	// maxHeldDuringReplay — сколько новых событий откладывается, пока идёт досылка. Клиент,
+	// не успевающий их принять, отключается, как и при переполнении очереди отправки.
+	maxHeldDuringReplay = 256
+	replayTimeout       = 10 * time.Second
+)
+
+// replayState — досылка пропущенных событий клиенту, подключившемуся с ?since=.
+type replayState struct {
+	mu     sync.Mutex
+	active bool
+	since  int64
+	held   []message
+}
+
+type replayCompleted struct {
+	Since     int64 `json:"since"`
+	Seq       int64 `json:"seq"`
+	Count     int   `json:"count"`
+	Truncated bool  `json:"truncated"`
+}
+
+// ReplayFrom просит дослать клиенту события с номером больше since. Вызывается до Register:
+// события, пришедшие после регистрации, откладываются и отправляются после досылки.
+func (c *Client) ReplayFrom(since int64) {
+	c.replay = &replayState{active: true, since: since}
+}
+
+// holdDuringReplay откладывает сообщение, если клиенту ещё досылаются пропущенные события.
+// Вызывается хабом под h.mu.
+func (c *Client) holdDuringReplay(msg message) bool {
+	if c.replay == nil {
+		return false
+	}
+	c.replay.mu.Lock()
+	defer c.replay.mu.Unlock()
+
+	if !c.replay.active {
+		return false
+	}
+	if len(c.replay.held) >= maxHeldDuringReplay {
+		c.replay.active = false
+		c.replay.held = nil
+		go c.Close()
+		return true
+	}
+	c.replay.held = append(c.replay.held, msg)
+	return true
+}
+
+// runReplay досылает пропущенные события, затем отложенные за это время. Запускается хабом
+// сразу после регистрации клиента.
+func (c *Client) runReplay() {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("WebSocket replay panic recovered: %v\nStack trace:\n%s\n", r, debug.Stack())
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(c.hub.ctx, replayTimeout)
+	defer cancel()
+
+	result := replayCompleted{Since: c.replay.since, Seq: c.replay.since}
+	events, err := c.hub.eventsSince(ctx, c.userID, c.replay.since, replayLimit+1)
+	switch {
+	case err != nil:
+		// Пропущенные события неизвестны: клиент должен загрузить состояние заново
+		fmt.Printf("ws: не удалось загрузить пропущенные события: %v\n", err)
+		result.Truncated = true
+	case len(events) > replayLimit:
+		result.Truncated = true
+		result.Seq = events[len(events)-1].Seq
+	default:
+		for _, event := range events {
+			raw, err := encodeEvent(event.Type, event.Data, event.Seq)
+			if err != nil {
+				continue
+			}
+			select {
+			case c.send <- raw:
+			case <-ctx.Done():
+				c.finishReplay(ctx, result.Seq, false)
+				return
+			}
+			result.Seq = event.Seq
+			result.Count++
+		}
+	}
+
+	raw, err := encodeEvent(EventReplayCompleted, result, 0)
+	if err == nil {
+		select {
+		case c.send <- raw:
+		case <-ctx.Done():
+			c.finishReplay(ctx, result.Seq, false)
+			return
+		}
+	}
+	c.finishReplay(ctx, result.Seq, true)
+}
+
+// finishReplay отправляет отложенные сообщения, которых не было среди досланных. Пока они
+// отправляются, новые продолжают откладываться, поэтому порядок сохраняется. Если отправить
+// не удалось, клиент отключается: иначе он молча пропустил бы события.
+func (c *Client) finishReplay(ctx context.Context, lastSeq int64, ok bool) {
+	for {
+		c.replay.mu.Lock()
+		if !c.replay.active {
+			c.replay.mu.Unlock()
+			return
+		}
+		held := c.replay.held
+		c.replay.held = nil
+		if !ok || len(held) == 0 {
+			c.replay.active = false
+			c.replay.mu.Unlock()
+			if !ok {
+				go c.Close()
+			}
+			return
+		}
+		c.replay.mu.Unlock()
+
+		for _, msg := range held {
+			if msg.seq > 0 && msg.seq <= lastSeq {
+				continue
+			}
+			select {
+			case c.send <- msg.payload:
+			case <-ctx.Done():
+				ok = false
+			}
+			if !ok {
+				break
+			}
+		}
+	}
+}
+
+// eventsSince возвращает сохранённые события пользователя с номером больше since.
+func (h *Hub) eventsSince(ctx context.Context, userID uuid.UUID, since int64, limit int) ([]StoredEvent, error) {
+	h.mu.RLock()
+	saver := h.notificationSaver
+	h.mu.RUnlock()
+	if saver == nil {
+		return nil, nil
+	}
+	return saver.EventsSince(ctx, userID, since, limit)
+}
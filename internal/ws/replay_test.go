@@ -0,0 +1,185 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSaver хранит события в памяти; gate, если задан, задерживает EventsSince,
+// события из muted не сохраняются и не отправляются.
+type fakeSaver struct {
+	mu     sync.Mutex
+	seq    map[uuid.UUID]int64
+	events map[uuid.UUID][]StoredEvent
+	gate   chan struct{}
+	muted  map[string]bool
+}
+
+func newFakeSaver() *fakeSaver {
+	return &fakeSaver{seq: make(map[uuid.UUID]int64), events: make(map[uuid.UUID][]StoredEvent)}
+}
+
+func (s *fakeSaver) CreateNotification(ctx context.Context, userID uuid.UUID, event string, data interface{}) (int64, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.muted[event] {
+		return 0, ErrEventMuted
+	}
+	s.seq[userID]++
+	s.events[userID] = append(s.events[userID], StoredEvent{Seq: s.seq[userID], Type: event, Data: raw})
+	return s.seq[userID], nil
+}
+
+func (s *fakeSaver) EventsSince(ctx context.Context, userID uuid.UUID, since int64, limit int) ([]StoredEvent, error) {
+	if s.gate != nil {
+		<-s.gate
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []StoredEvent
+	for _, event := range s.events[userID] {
+		if event.Seq > since {
+			result = append(result, event)
+		}
+	}
+	if len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	return result, nil
+}
+
+func startReplayHub(t *testing.T, saver *fakeSaver) *Hub {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	hub := NewHub(ctx)
+	hub.SetNotificationSaver(saver)
+	go hub.Run()
+	return hub
+}
+
+func connectSince(hub *Hub, userID uuid.UUID, since int64) *Client {
+	c := &Client{hub: hub, userID: userID, send: make(chan []byte, 16), peers: make(map[uuid.UUID]uuid.UUID)}
+	c.ReplayFrom(since)
+	hub.Register(c)
+	return c
+}
+
+type sequencedEvent struct {
+	Type string          `json:"type"`
+	Seq  int64           `json:"seq"`
+	Data json.RawMessage `json:"data"`
+}
+
+func nextEvent(t *testing.T, c *Client) sequencedEvent {
+	t.Helper()
+	select {
+	case raw := <-c.send:
+		var event sequencedEvent
+		assert.NoError(t, json.Unmarshal(raw, &event))
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("не дождались события")
+		return sequencedEvent{}
+	}
+}
+
+func TestReplay_SendsMissedEventsBeforeLive(t *testing.T) {
+	saver := newFakeSaver()
+	hub := startReplayHub(t, saver)
+	userID := uuid.New()
+	for _, event := range []string{"order_created", "proposal_created", "order_status_changed"} {
+		_, _ = saver.CreateNotification(context.Background(), userID, event, map[string]string{})
+	}
+
+	c := connectSince(hub, userID, 1)
+	first := nextEvent(t, c)
+	assert.Equal(t, "proposal_created", first.Type)
+	assert.Equal(t, int64(2), first.Seq)
+	second := nextEvent(t, c)
+	assert.Equal(t, "order_status_changed", second.Type)
+	assert.Equal(t, int64(3), second.Seq)
+
+	completed := nextEvent(t, c)
+	assert.Equal(t, EventReplayCompleted, completed.Type)
+	var result replayCompleted
+	assert.NoError(t, json.Unmarshal(completed.Data, &result))
+	assert.Equal(t, replayCompleted{Since: 1, Seq: 3, Count: 2}, result)
+
+	assert.NoError(t, hub.BroadcastToUser(userID, "new_message", map[string]string{}))
+	live := nextEvent(t, c)
+	assert.Equal(t, "new_message", live.Type)
+	assert.Equal(t, int64(4), live.Seq)
+}
+
+func TestReplay_HoldsLiveEventsWithoutDuplicates(t *testing.T) {
+	saver := newFakeSaver()
+	saver.gate = make(chan struct{})
+	hub := startReplayHub(t, saver)
+	userID := uuid.New()
+	_, _ = saver.CreateNotification(context.Background(), userID, "order_created", map[string]string{})
+
+	c := connectSince(hub, userID, 0)
+	// Событие сохранено и доставлено, пока досылка ещё не прочитала хранилище
+	assert.NoError(t, hub.BroadcastToUser(userID, "new_message", map[string]string{}))
+	assert.Eventually(t, func() bool {
+		c.replay.mu.Lock()
+		defer c.replay.mu.Unlock()
+		return len(c.replay.held) == 1
+	}, time.Second, 5*time.Millisecond)
+	close(saver.gate)
+
+	assert.Equal(t, int64(1), nextEvent(t, c).Seq)
+	assert.Equal(t, int64(2), nextEvent(t, c).Seq)
+	assert.Equal(t, EventReplayCompleted, nextEvent(t, c).Type)
+	select {
+	case raw := <-c.send:
+		t.Fatalf("лишнее событие: %s", raw)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReplay_TruncatesLongGap(t *testing.T) {
+	saver := newFakeSaver()
+	hub := startReplayHub(t, saver)
+	userID := uuid.New()
+	for range replayLimit + 5 {
+		_, _ = saver.CreateNotification(context.Background(), userID, "proposal_created", map[string]string{})
+	}
+
+	c := connectSince(hub, userID, 0)
+	completed := nextEvent(t, c)
+	assert.Equal(t, EventReplayCompleted, completed.Type)
+	var result replayCompleted
+	assert.NoError(t, json.Unmarshal(completed.Data, &result))
+	assert.True(t, result.Truncated)
+	assert.Equal(t, int64(replayLimit+5), result.Seq)
+	assert.Zero(t, result.Count)
+}
+
+func TestHub_SkipsMutedEvents(t *testing.T) {
+	saver := newFakeSaver()
+	saver.muted = map[string]bool{"orders.updated": true}
+	hub := startReplayHub(t, saver)
+	userID := uuid.New()
+
+	c := connectSince(hub, userID, 0)
+	assert.Equal(t, EventReplayCompleted, nextEvent(t, c).Type)
+
+	assert.NoError(t, hub.BroadcastToUser(userID, "orders.updated", map[string]string{}))
+	assert.NoError(t, hub.BroadcastToUser(userID, "new_message", map[string]string{}))
+	live := nextEvent(t, c)
+	assert.Equal(t, "new_message", live.Type)
+	assert.Equal(t, int64(1), live.Seq)
+}
@@ -0,0 +1,199 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+)
+
+// Команды подписки на темы.
+const (
+	CommandSubscribe   = "subscribe"
+	CommandUnsubscribe = "unsubscribe"
+)
+
+// Виды тем. Тема записывается как "<вид>:<uuid>", например "order:6f1c...".
+const (
+	TopicConversation = "conversation"
+	TopicOrder        = "order"
+	TopicDashboard    = "dashboard"
+)
+
+// maxTopicsPerClient ограничивает число подписок одного соединения.
+const maxTopicsPerClient = 50
+
+var (
+	errTopicForbidden = errors.New("ws: нет доступа к этой теме")
+	errClientClosed   = errors.New("ws: соединение уже закрыто")
+)
+
+// OrderStore даёт хабу доступ к заказам для проверки подписки на тему заказа.
+type OrderStore interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Order, error)
+}
+
+// ConversationTopic возвращает тему событий чата.
+func ConversationTopic(conversationID uuid.UUID) string {
+	return TopicConversation + ":" + conversationID.String()
+}
+
+// OrderTopic возвращает тему событий заказа.
+func OrderTopic(orderID uuid.UUID) string {
+	return TopicOrder + ":" + orderID.String()
+}
+
+// DashboardTopic возвращает тему обновлений дашборда пользователя.
+func DashboardTopic(userID uuid.UUID) string {
+	return TopicDashboard + ":" + userID.String()
+}
+
+type subscriptionCommand struct {
+	Topic string `json:"topic"`
+}
+
+type subscriptionResult struct {
+	Topic string `json:"topic"`
+}
+
+// SetOrderStore подключает доступ к заказам; без него подписка на темы заказов отклоняется.
+func (h *Hub) SetOrderStore(store OrderStore) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.orders = store
+}
+
+// PublishToTopic отправляет событие всем соединениям, подписанным на тему, на всех экземплярах.
+// Событие не сохраняется в уведомлениях и не досылается после переподключения.
+func (h *Hub) PublishToTopic(topic, event string, data any) error {
+	raw, err := json.Marshal(map[string]any{
+		"type":  event,
+		"topic": topic,
+		"data":  data,
+	})
+	if err != nil {
+		return fmt.Errorf("ws: не удалось сериализовать сообщение: %w", err)
+	}
+	h.broadcast <- message{topic: topic, payload: raw}
+	h.publish(Envelope{Kind: EnvelopeTopic, Topic: topic, Payload: raw})
+	return nil
+}
+
+func (c *Client) handleSubscribe(ctx context.Context, data json.RawMessage) (any, error) {
+	var cmd subscriptionCommand
+	if err := json.Unmarshal(data, &cmd); err != nil || cmd.Topic == "" {
+		return nil, errBadRequest("topic обязателен")
+	}
+	if err := c.authorizeTopic(ctx, cmd.Topic); err != nil {
+		return nil, err
+	}
+	if err := c.hub.subscribe(c, cmd.Topic); err != nil {
+		return nil, err
+	}
+	return subscriptionResult{Topic: cmd.Topic}, nil
+}
+
+func (c *Client) handleUnsubscribe(data json.RawMessage) (any, error) {
+	var cmd subscriptionCommand
+	if err := json.Unmarshal(data, &cmd); err != nil || cmd.Topic == "" {
+		return nil, errBadRequest("topic обязателен")
+	}
+	c.hub.unsubscribe(c, cmd.Topic)
+	return subscriptionResult{Topic: cmd.Topic}, nil
+}
+
+// authorizeTopic проверяет, что клиент может получать события темы: участвует в чате,
+// является заказчиком или исполнителем заказа, или это его собственный дашборд.
+func (c *Client) authorizeTopic(ctx context.Context, topic string) error {
+	kind, rawID, ok := strings.Cut(topic, ":")
+	if !ok {
+		return errBadRequest("тема должна иметь вид <вид>:<id>")
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return errBadRequest("некорректный идентификатор в теме")
+	}
+	// Тема сравнивается как строка, поэтому принимается только каноническая запись
+	if rawID != id.String() {
+		return errBadRequest("идентификатор в теме должен быть в нижнем регистре")
+	}
+
+	switch kind {
+	case TopicConversation:
+		_, err := c.conversationPeer(ctx, id)
+		return err
+	case TopicOrder:
+		h := c.hub
+		h.mu.RLock()
+		store := h.orders
+		h.mu.RUnlock()
+		if store == nil {
+			return errStoreMissing
+		}
+		order, err := store.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if order.ClientID != c.userID && (order.FreelancerID == nil || *order.FreelancerID != c.userID) {
+			return errTopicForbidden
+		}
+		return nil
+	case TopicDashboard:
+		if id != c.userID {
+			return errTopicForbidden
+		}
+		return nil
+	default:
+		return errBadRequest(fmt.Sprintf("неизвестный вид темы %q", kind))
+	}
+}
+
+// subscribe подписывает клиента на тему. Клиент, которого хаб уже отключил, не подписывается:
+// removeClient успел снять его подписки, и новая осталась бы в h.topics навсегда.
+func (h *Hub) subscribe(c *Client, topic string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, registered := h.clients[c.userID][c]; !registered {
+		return errClientClosed
+	}
+	if _, ok := c.topics[topic]; ok {
+		return nil
+	}
+	if len(c.topics) >= maxTopicsPerClient {
+		return errBadRequest(fmt.Sprintf("не больше %d подписок на соединение", maxTopicsPerClient))
+	}
+	if c.topics == nil {
+		c.topics = make(map[string]struct{})
+	}
+	c.topics[topic] = struct{}{}
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*Client]struct{})
+	}
+	h.topics[topic][c] = struct{}{}
+	return nil
+}
+
+func (h *Hub) unsubscribe(c *Client, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dropSubscription(c, topic)
+}
+
+// dropSubscription убирает подписку клиента. Вызывается под h.mu.
+func (h *Hub) dropSubscription(c *Client, topic string) {
+	delete(c.topics, topic)
+	subscribers, ok := h.topics[topic]
+	if !ok {
+		return
+	}
+	delete(subscribers, c)
+	if len(subscribers) == 0 {
+		delete(h.topics, topic)
+	}
+}
@@ -0,0 +1,198 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ignatzorin/freelance-backend/internal/models"
+	"github.com/ignatzorin/freelance-backend/internal/repository"
+)
+
+type fakeOrderStore map[uuid.UUID]*models.Order
+
+func (s fakeOrderStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Order, error) {
+	order, ok := s[id]
+	if !ok {
+		return nil, repository.ErrOrderNotFound
+	}
+	return order, nil
+}
+
+type topicEvent struct {
+	Type  string          `json:"type"`
+	Topic string          `json:"topic"`
+	Data  json.RawMessage `json:"data"`
+}
+
+func subscribeFrame(topic string) string {
+	return `{"v":1,"id":"s","type":"subscribe","data":{"topic":"` + topic + `"}}`
+}
+
+// expectNoEvent проверяет, что клиенту не пришло событие eventType.
+func expectNoEvent(t *testing.T, c *Client, eventType string) {
+	t.Helper()
+	timeout := time.After(50 * time.Millisecond)
+	for {
+		select {
+		case raw := <-c.send:
+			var event receivedEvent
+			_ = json.Unmarshal(raw, &event)
+			assert.NotEqual(t, eventType, event.Type)
+		case <-timeout:
+			return
+		}
+	}
+}
+
+func TestTopics_SubscriptionAuthorization(t *testing.T) {
+	f := setupHub(t)
+	order := &models.Order{ID: uuid.New(), ClientID: f.client}
+	f.hub.SetOrderStore(fakeOrderStore{order.ID: order})
+	client := f.connect(f.client)
+	freelancer := f.connect(f.freelancer)
+
+	var failure errorPayload
+	cases := []struct {
+		c     *Client
+		topic string
+		code  string
+	}{
+		{client, ConversationTopic(f.conv.ID), ""},
+		{client, OrderTopic(order.ID), ""},
+		{client, DashboardTopic(f.client), ""},
+		{freelancer, OrderTopic(order.ID), ErrorCodeForbidden},
+		{freelancer, DashboardTopic(f.client), ErrorCodeForbidden},
+		{freelancer, OrderTopic(uuid.New()), ErrorCodeNotFound},
+		{freelancer, "profile:" + f.client.String(), ErrorCodeBadRequest},
+		{freelancer, "order:" + order.ID.String()[:8], ErrorCodeBadRequest},
+	}
+	for _, tc := range cases {
+		tc.c.command(t, subscribeFrame(tc.topic))
+		if tc.code == "" {
+			var ack struct {
+				Result subscriptionResult `json:"result"`
+			}
+			expectEvent(t, tc.c, EventAck, &ack)
+			assert.Equal(t, tc.topic, ack.Result.Topic)
+			continue
+		}
+		expectEvent(t, tc.c, EventError, &failure)
+		assert.Equal(t, tc.code, failure.Code, tc.topic)
+	}
+}
+
+func TestTopics_PublishReachesSubscribersOnly(t *testing.T) {
+	f := setupHub(t)
+	client := f.connect(f.client)
+	freelancer := f.connect(f.freelancer)
+	topic := ConversationTopic(f.conv.ID)
+
+	client.command(t, subscribeFrame(topic))
+	expectEvent(t, client, EventAck, nil)
+
+	assert.NoError(t, f.hub.PublishToTopic(topic, "message.reaction.added", map[string]string{"emoji": "👍"}))
+	var event topicEvent
+	for event.Type != "message.reaction.added" {
+		select {
+		case raw := <-client.send:
+			assert.NoError(t, json.Unmarshal(raw, &event))
+		case <-time.After(time.Second):
+			t.Fatal("не дождались события темы")
+		}
+	}
+	assert.Equal(t, topic, event.Topic)
+	expectNoEvent(t, freelancer, "message.reaction.added")
+
+	client.command(t, `{"v":1,"id":"u","type":"unsubscribe","data":{"topic":"`+topic+`"}}`)
+	expectEvent(t, client, EventAck, nil)
+	assert.NoError(t, f.hub.PublishToTopic(topic, "message.reaction.removed", map[string]string{}))
+	expectNoEvent(t, client, "message.reaction.removed")
+}
+
+func TestTopics_ReactionReachesSubscribedParticipantOnce(t *testing.T) {
+	f := setupHub(t)
+	client := f.connect(f.client)
+	freelancer := f.connect(f.freelancer)
+	topic := ConversationTopic(f.conv.ID)
+	// Регистрация асинхронная: подписка до неё отклоняется
+	assert.Eventually(t, func() bool {
+		f.hub.mu.RLock()
+		defer f.hub.mu.RUnlock()
+		return len(f.hub.clients[f.client]) == 1 && len(f.hub.clients[f.freelancer]) == 1
+	}, time.Second, 5*time.Millisecond)
+	for _, c := range []*Client{client, freelancer} {
+		c.command(t, subscribeFrame(topic))
+		expectEvent(t, c, EventAck, nil)
+	}
+
+	// Так реакцию рассылает обработчик чата: каждому участнику, без публикации в тему
+	payload := map[string]string{"conversation_id": f.conv.ID.String(), "emoji": "👍"}
+	assert.NoError(t, f.hub.BroadcastToUser(f.client, "message.reaction.added", payload))
+	assert.NoError(t, f.hub.BroadcastToUser(f.freelancer, "message.reaction.added", payload))
+
+	for _, c := range []*Client{client, freelancer} {
+		var frames []topicEvent
+		timeout := time.After(100 * time.Millisecond)
+	collect:
+		for {
+			select {
+			case raw := <-c.send:
+				var event topicEvent
+				assert.NoError(t, json.Unmarshal(raw, &event))
+				if event.Type == "message.reaction.added" {
+					frames = append(frames, event)
+				}
+			case <-timeout:
+				break collect
+			}
+		}
+		if assert.Len(t, frames, 1) {
+			assert.Empty(t, frames[0].Topic)
+		}
+	}
+}
+
+func TestTopics_PublishAcrossNodes(t *testing.T) {
+	backplane := NewMemoryBackplane()
+	nodeA := startNode(t, backplane)
+	nodeB := startNode(t, backplane)
+	userID := uuid.New()
+	topic := DashboardTopic(userID)
+
+	onB := connectTo(nodeB, userID)
+	onB.command(t, subscribeFrame(topic))
+	expectEvent(t, onB, EventAck, nil)
+
+	assert.NoError(t, nodeA.PublishToTopic(topic, "dashboard.updated", map[string]string{"reason": "orders.new"}))
+	expectEvent(t, onB, "dashboard.updated", nil)
+
+	// Подписки снимаются вместе с соединением
+	nodeB.Unregister(onB)
+	assert.Eventually(t, func() bool {
+		nodeB.mu.RLock()
+		defer nodeB.mu.RUnlock()
+		return len(nodeB.topics) == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestTopics_SubscribeAfterDisconnect(t *testing.T) {
+	f := setupHub(t)
+	client := f.connect(f.client)
+	f.hub.Unregister(client)
+	assert.Eventually(t, func() bool { online, _ := f.hub.Presence(f.client); return !online }, time.Second, 5*time.Millisecond)
+
+	// Команда, начатая до отключения, не должна оставить закрытое соединение в подписчиках
+	client.command(t, subscribeFrame(DashboardTopic(f.client)))
+	var failure errorPayload
+	expectEvent(t, client, EventError, &failure)
+	assert.Equal(t, ErrorCodeUnavailable, failure.Code)
+
+	f.hub.mu.RLock()
+	defer f.hub.mu.RUnlock()
+	assert.Empty(t, f.hub.topics)
+}
@@ -0,0 +1,48 @@
+-- Этапы (milestones) заказа с поэтапной оплатой через escrow
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'milestone_status') THEN
+        CREATE TYPE milestone_status AS ENUM ('pending', 'funded', 'submitted', 'released', 'refunded', 'disputed');
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS order_milestones (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id        UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    title           TEXT NOT NULL,
+    description     TEXT,
+    amount          NUMERIC(12,2) NOT NULL CHECK (amount > 0),
+    position        INT NOT NULL DEFAULT 0,
+    status          milestone_status NOT NULL DEFAULT 'pending',
+    due_at          TIMESTAMPTZ,
+    funded_at       TIMESTAMPTZ,
+    submitted_at    TIMESTAMPTZ,
+    released_at     TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_milestones_order ON order_milestones(order_id, position);
+
+DROP TRIGGER IF EXISTS order_milestones_set_updated_at ON order_milestones;
+CREATE TRIGGER order_milestones_set_updated_at
+BEFORE UPDATE ON order_milestones
+FOR EACH ROW
+EXECUTE FUNCTION set_updated_at();
+
+-- Escrow теперь может быть привязан к этапу: один escrow на заказ без этапов
+-- либо по одному escrow на каждый этап.
+ALTER TABLE escrow ADD COLUMN IF NOT EXISTS milestone_id UUID REFERENCES order_milestones(id) ON DELETE CASCADE;
+ALTER TABLE escrow DROP CONSTRAINT IF EXISTS escrow_order_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_escrow_order_lump ON escrow(order_id) WHERE milestone_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_escrow_milestone ON escrow(milestone_id) WHERE milestone_id IS NOT NULL;
+
+-- Транзакции и споры по конкретному этапу
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS milestone_id UUID REFERENCES order_milestones(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_milestone_id ON transactions(milestone_id) WHERE milestone_id IS NOT NULL;
+
+ALTER TABLE disputes ADD COLUMN IF NOT EXISTS milestone_id UUID REFERENCES order_milestones(id) ON DELETE CASCADE;
+
+COMMENT ON TABLE order_milestones IS 'Этапы заказа с отдельной суммой, сроком и приёмкой';
+COMMENT ON COLUMN escrow.milestone_id IS 'Этап заказа, за который заморожены средства (NULL - весь заказ)';
@@ -0,0 +1,32 @@
+-- Разбор споров администратором: назначение, доказательства и раздел средств
+
+ALTER TABLE disputes ADD COLUMN IF NOT EXISTS assigned_to UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE disputes ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMPTZ;
+ALTER TABLE disputes ADD COLUMN IF NOT EXISTS client_percent NUMERIC(5,2) CHECK (client_percent BETWEEN 0 AND 100);
+ALTER TABLE disputes ADD COLUMN IF NOT EXISTS client_amount NUMERIC(12,2);
+ALTER TABLE disputes ADD COLUMN IF NOT EXISTS freelancer_amount NUMERIC(12,2);
+
+CREATE INDEX IF NOT EXISTS idx_disputes_assigned_to ON disputes(assigned_to) WHERE assigned_to IS NOT NULL;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'dispute_evidence_kind') THEN
+        CREATE TYPE dispute_evidence_kind AS ENUM ('note', 'message', 'attachment');
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS dispute_evidence (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    dispute_id      UUID NOT NULL REFERENCES disputes(id) ON DELETE CASCADE,
+    author_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind            dispute_evidence_kind NOT NULL,
+    content         TEXT,
+    message_id      UUID REFERENCES messages(id) ON DELETE SET NULL,
+    media_id        UUID REFERENCES media_files(id) ON DELETE SET NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispute_evidence_dispute ON dispute_evidence(dispute_id, created_at);
+
+COMMENT ON TABLE dispute_evidence IS 'Доказательства по спору: заметки, ссылки на сообщения чата и файлы';
+COMMENT ON COLUMN disputes.client_percent IS 'Доля escrow (в процентах), возвращённая заказчику при решении спора';
@@ -0,0 +1,12 @@
+-- Модерация жалоб: решение модератора и скрытие сообщений
+
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS action TEXT;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS moderator_note TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_reports_pending ON reports(target_type, target_id, created_at) WHERE status = 'pending';
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMPTZ;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS hidden_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+COMMENT ON COLUMN reports.action IS 'Мера, применённая модератором: hide_message, unpublish_order, remove_review, suspend_user';
+COMMENT ON COLUMN messages.hidden_at IS 'Время скрытия сообщения модератором';
@@ -0,0 +1,11 @@
+-- Обработка выводов средств: заморозка баланса и выплата через провайдера
+
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS transaction_id UUID REFERENCES transactions(id) ON DELETE SET NULL;
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS payout_reference TEXT;
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS processing_started_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_withdrawals_queue ON withdrawals(created_at) WHERE status IN ('pending', 'processing');
+
+COMMENT ON COLUMN withdrawals.transaction_id IS 'Транзакция withdrawal, созданная при заморозке средств';
+COMMENT ON COLUMN withdrawals.payout_reference IS 'Идентификатор выплаты у платёжного провайдера';
+COMMENT ON COLUMN user_balances.frozen IS 'Замороженные средства (escrow и выводы в обработке)';
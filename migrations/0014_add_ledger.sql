@@ -0,0 +1,119 @@
+-- Двойная запись: счета и проводки, из которых выводятся балансы пользователей
+
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id         UUID REFERENCES users(id),
+    kind            TEXT NOT NULL CHECK (kind IN ('user_available', 'user_frozen', 'platform_fees', 'platform_escrow', 'external_cash')),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((user_id IS NOT NULL) = (kind IN ('user_available', 'user_frozen')))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_accounts_user ON ledger_accounts(user_id, kind) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_accounts_platform ON ledger_accounts(kind) WHERE user_id IS NULL;
+
+INSERT INTO ledger_accounts (kind)
+VALUES ('platform_fees'), ('platform_escrow'), ('external_cash')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS ledger_journals (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind            TEXT NOT NULL,
+    order_id        UUID REFERENCES orders(id) ON DELETE SET NULL,
+    escrow_id       UUID REFERENCES escrow(id) ON DELETE SET NULL,
+    withdrawal_id   UUID REFERENCES withdrawals(id) ON DELETE SET NULL,
+    description     TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    journal_id      UUID NOT NULL REFERENCES ledger_journals(id),
+    account_id      UUID NOT NULL REFERENCES ledger_accounts(id),
+    amount          NUMERIC(14,2) NOT NULL CHECK (amount <> 0),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account ON ledger_entries(account_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_journal ON ledger_entries(journal_id);
+
+-- Журнал только дополняется: изменять и удалять проводки нельзя
+CREATE OR REPLACE FUNCTION ledger_forbid_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_entries_append_only ON ledger_entries;
+CREATE TRIGGER ledger_entries_append_only
+BEFORE UPDATE OR DELETE ON ledger_entries
+FOR EACH ROW EXECUTE FUNCTION ledger_forbid_change();
+
+DROP TRIGGER IF EXISTS ledger_journals_append_only ON ledger_journals;
+CREATE TRIGGER ledger_journals_append_only
+BEFORE UPDATE OR DELETE ON ledger_journals
+FOR EACH ROW EXECUTE FUNCTION ledger_forbid_change();
+
+-- Сумма проводок журнала должна быть нулевой; проверяется при коммите
+CREATE OR REPLACE FUNCTION ledger_check_balanced()
+RETURNS TRIGGER AS $$
+DECLARE
+    total NUMERIC;
+BEGIN
+    SELECT COALESCE(SUM(amount), 0) INTO total FROM ledger_entries WHERE journal_id = NEW.journal_id;
+    IF total <> 0 THEN
+        RAISE EXCEPTION 'ledger journal % is unbalanced by %', NEW.journal_id, total;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_entries_balanced ON ledger_entries;
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced
+AFTER INSERT ON ledger_entries
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION ledger_check_balanced();
+
+-- Входящие остатки: переносим текущие балансы в журнал одной проводкой
+DO $$
+DECLARE
+    opening_id UUID;
+BEGIN
+    IF EXISTS (SELECT 1 FROM ledger_journals) THEN
+        RETURN;
+    END IF;
+
+    INSERT INTO ledger_accounts (user_id, kind)
+    SELECT user_id, k.kind FROM user_balances, (VALUES ('user_available'), ('user_frozen')) AS k(kind)
+    ON CONFLICT DO NOTHING;
+
+    INSERT INTO ledger_journals (kind, description)
+    VALUES ('opening_balance', 'Входящие остатки на момент перехода на двойную запись')
+    RETURNING id INTO opening_id;
+
+    CREATE TEMP TABLE opening_entries ON COMMIT DROP AS
+    SELECT a.id AS account_id, b.available AS amount
+    FROM user_balances b JOIN ledger_accounts a ON a.user_id = b.user_id AND a.kind = 'user_available'
+    UNION ALL
+    SELECT a.id, b.frozen - COALESCE(e.held, 0)
+    FROM user_balances b
+    JOIN ledger_accounts a ON a.user_id = b.user_id AND a.kind = 'user_frozen'
+    LEFT JOIN (
+        SELECT client_id, SUM(amount) AS held FROM escrow WHERE status IN ('held', 'disputed') GROUP BY client_id
+    ) e ON e.client_id = b.user_id
+    UNION ALL
+    SELECT a.id, COALESCE((SELECT SUM(amount) FROM escrow WHERE status IN ('held', 'disputed')), 0)
+    FROM ledger_accounts a WHERE a.kind = 'platform_escrow' AND a.user_id IS NULL;
+
+    INSERT INTO ledger_entries (journal_id, account_id, amount)
+    SELECT opening_id, account_id, amount FROM opening_entries WHERE amount <> 0;
+
+    INSERT INTO ledger_entries (journal_id, account_id, amount)
+    SELECT opening_id, a.id, -t.total
+    FROM ledger_accounts a, (SELECT SUM(amount) AS total FROM opening_entries) t
+    WHERE a.kind = 'external_cash' AND a.user_id IS NULL AND t.total <> 0;
+END $$;
+
+COMMENT ON TABLE ledger_accounts IS 'Счета двойной записи: доступные и замороженные средства пользователей, комиссии, escrow и внешние деньги';
+COMMENT ON TABLE ledger_entries IS 'Проводки; сумма по каждому журналу равна нулю. Положительная сумма увеличивает обязательство платформы по счёту';
+COMMENT ON COLUMN user_balances.frozen IS 'Замороженные средства: escrow заказчика (счёт platform_escrow) и выводы в обработке (счёт user_frozen)';
@@ -0,0 +1,31 @@
+-- Комиссия платформы, удерживаемая при выплате escrow фрилансеру
+
+ALTER TYPE transaction_type ADD VALUE IF NOT EXISTS 'platform_fee';
+
+CREATE TABLE IF NOT EXISTS platform_fee_settings (
+    id              BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    default_percent NUMERIC(5,2) NOT NULL CHECK (default_percent BETWEEN 0 AND 100),
+    min_fee         NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (min_fee >= 0),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO platform_fee_settings (default_percent, min_fee) VALUES (10, 50) ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS platform_fee_categories (
+    category_id     UUID PRIMARY KEY REFERENCES categories(id) ON DELETE CASCADE,
+    percent         NUMERIC(5,2) NOT NULL CHECK (percent BETWEEN 0 AND 100)
+);
+
+CREATE TABLE IF NOT EXISTS platform_fee_tiers (
+    min_earnings    NUMERIC(12,2) PRIMARY KEY CHECK (min_earnings >= 0),
+    percent         NUMERIC(5,2) NOT NULL CHECK (percent BETWEEN 0 AND 100)
+);
+
+INSERT INTO platform_fee_tiers (min_earnings, percent) VALUES (100000, 8), (500000, 5) ON CONFLICT DO NOTHING;
+
+ALTER TABLE escrow ADD COLUMN IF NOT EXISTS fee_amount NUMERIC(12,2) NOT NULL DEFAULT 0;
+
+COMMENT ON TABLE platform_fee_settings IS 'Базовая ставка комиссии (в процентах) и минимальная комиссия с выплаты';
+COMMENT ON TABLE platform_fee_categories IS 'Ставка комиссии для категории заказа вместо базовой';
+COMMENT ON TABLE platform_fee_tiers IS 'Ставка для фрилансеров, заработавших не меньше min_earnings; применяется, если она ниже ставки категории';
+COMMENT ON COLUMN escrow.fee_amount IS 'Комиссия платформы, удержанная при выплате фрилансеру';
@@ -0,0 +1,65 @@
+-- Мультивалютность: валюта заказов, откликов, escrow, транзакций и выводов; отдельный баланс в каждой валюте
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB' CHECK (currency ~ '^[A-Z]{3}$');
+ALTER TABLE proposals ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB' CHECK (currency ~ '^[A-Z]{3}$');
+ALTER TABLE escrow ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB' CHECK (currency ~ '^[A-Z]{3}$');
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB' CHECK (currency ~ '^[A-Z]{3}$');
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB' CHECK (currency ~ '^[A-Z]{3}$');
+
+-- Баланс: одна строка на пользователя и валюту
+ALTER TABLE user_balances ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB' CHECK (currency ~ '^[A-Z]{3}$');
+ALTER TABLE user_balances DROP CONSTRAINT IF EXISTS user_balances_pkey;
+ALTER TABLE user_balances ADD PRIMARY KEY (user_id, currency);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_currency ON transactions(user_id, currency, type);
+
+-- Счета журнала ведутся в одной валюте; счета платформы заводятся для каждой валюты при первой проводке
+ALTER TABLE ledger_accounts ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB' CHECK (currency ~ '^[A-Z]{3}$');
+
+DROP INDEX IF EXISTS idx_ledger_accounts_user;
+DROP INDEX IF EXISTS idx_ledger_accounts_platform;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_accounts_user ON ledger_accounts(user_id, kind, currency) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_accounts_platform ON ledger_accounts(kind, currency) WHERE user_id IS NULL;
+
+-- Сумма проводок журнала должна быть нулевой в каждой валюте
+CREATE OR REPLACE FUNCTION ledger_check_balanced()
+RETURNS TRIGGER AS $$
+DECLARE
+    unbalanced_currency CHAR(3);
+    total NUMERIC;
+BEGIN
+    SELECT a.currency, SUM(e.amount) INTO unbalanced_currency, total
+    FROM ledger_entries e JOIN ledger_accounts a ON a.id = e.account_id
+    WHERE e.journal_id = NEW.journal_id
+    GROUP BY a.currency
+    HAVING SUM(e.amount) <> 0
+    LIMIT 1;
+    IF FOUND THEN
+        RAISE EXCEPTION 'ledger journal % is unbalanced by % %', NEW.journal_id, total, unbalanced_currency;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Комиссия: минимальная комиссия и пороги заработка задаются в валюте выплаты
+CREATE TABLE IF NOT EXISTS platform_fee_minimums (
+    currency        CHAR(3) PRIMARY KEY CHECK (currency ~ '^[A-Z]{3}$'),
+    min_fee         NUMERIC(12,2) NOT NULL CHECK (min_fee >= 0)
+);
+
+INSERT INTO platform_fee_minimums (currency, min_fee)
+SELECT 'RUB', min_fee FROM platform_fee_settings
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE platform_fee_settings DROP COLUMN IF EXISTS min_fee;
+
+ALTER TABLE platform_fee_tiers ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB' CHECK (currency ~ '^[A-Z]{3}$');
+ALTER TABLE platform_fee_tiers DROP CONSTRAINT IF EXISTS platform_fee_tiers_pkey;
+ALTER TABLE platform_fee_tiers ADD PRIMARY KEY (currency, min_earnings);
+
+COMMENT ON TABLE user_balances IS 'Баланс пользователя; отдельная строка для каждой валюты';
+COMMENT ON COLUMN orders.currency IS 'Валюта бюджета заказа (ISO 4217)';
+COMMENT ON COLUMN proposals.currency IS 'Валюта предложенной суммы; escrow по принятому отклику создаётся в этой валюте';
+COMMENT ON COLUMN escrow.currency IS 'Валюта escrow: средства замораживаются и выплачиваются с баланса в этой валюте';
+COMMENT ON TABLE platform_fee_minimums IS 'Минимальная комиссия с выплаты в каждой валюте; для валюты без строки минимум не применяется';
+COMMENT ON COLUMN platform_fee_tiers.currency IS 'Валюта порога: учитывается заработок фрилансера только в этой валюте';
@@ -0,0 +1,19 @@
+-- Ключи идемпотентности: повтор запроса с тем же Idempotency-Key возвращает сохранённый ответ
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key             VARCHAR(255) NOT NULL,
+    request_hash    CHAR(64) NOT NULL,
+    status_code     INTEGER,
+    content_type    VARCHAR(255),
+    response_body   BYTEA,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at    TIMESTAMPTZ,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(created_at);
+
+COMMENT ON TABLE idempotency_keys IS 'Ключи идемпотентности мутирующих запросов; ключ уникален в пределах пользователя';
+COMMENT ON COLUMN idempotency_keys.request_hash IS 'SHA-256 метода, пути и тела запроса: другой запрос с тем же ключом отклоняется';
+COMMENT ON COLUMN idempotency_keys.status_code IS 'Код сохранённого ответа; NULL — запрос ещё выполняется';
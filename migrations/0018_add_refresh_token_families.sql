@@ -0,0 +1,23 @@
+-- Ротация refresh токенов: сессия — семейство токенов, токены хранятся только в виде хешей
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash      CHAR(64) PRIMARY KEY,
+    session_id      UUID NOT NULL REFERENCES user_sessions(id) ON DELETE CASCADE,
+    expires_at      TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    rotated_at      TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens(session_id);
+
+-- Действующие токены существующих сессий переносятся в виде хешей, открытые значения удаляются
+INSERT INTO refresh_tokens (token_hash, session_id, expires_at, created_at)
+SELECT encode(sha256(convert_to(refresh_token, 'UTF8')), 'hex'), id, expires_at, created_at
+FROM user_sessions
+ON CONFLICT (token_hash) DO NOTHING;
+
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS refresh_token;
+
+COMMENT ON TABLE refresh_tokens IS 'Refresh токены сессии (семейства): каждый обмен выпускает новый токен и помечает предыдущий';
+COMMENT ON COLUMN refresh_tokens.token_hash IS 'SHA-256 refresh токена в hex; сам токен не хранится';
+COMMENT ON COLUMN refresh_tokens.rotated_at IS 'Время обмена на новый токен; повторное предъявление такого токена отзывает всю сессию';
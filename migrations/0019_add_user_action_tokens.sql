@@ -0,0 +1,17 @@
+-- Одноразовые ссылки из писем: сброс пароля и подтверждение смены email
+
+CREATE TABLE IF NOT EXISTS user_action_tokens (
+    id              UUID PRIMARY KEY,
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose         VARCHAR(32) NOT NULL CHECK (purpose IN ('password_reset', 'email_change')),
+    new_email       CITEXT,
+    expires_at      TIMESTAMPTZ NOT NULL,
+    used_at         TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_action_tokens_user ON user_action_tokens(user_id, purpose) WHERE used_at IS NULL;
+
+COMMENT ON TABLE user_action_tokens IS 'Одноразовые подписанные ссылки из писем; в ссылке передаётся подписанный id записи';
+COMMENT ON COLUMN user_action_tokens.new_email IS 'Новый адрес для email_change; ссылка отправляется на него';
+COMMENT ON COLUMN user_action_tokens.used_at IS 'Время использования или отмены более новой ссылкой того же назначения';
@@ -0,0 +1,13 @@
+-- Доставка кодов подтверждения телефона: получатель кода и счётчик неверных попыток
+
+ALTER TABLE verification_codes ADD COLUMN IF NOT EXISTS destination TEXT;
+ALTER TABLE verification_codes ADD COLUMN IF NOT EXISTS channel VARCHAR(16);
+ALTER TABLE verification_codes ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+
+-- Ограничение частоты отправки на один номер независимо от аккаунта
+CREATE INDEX IF NOT EXISTS idx_verification_codes_destination ON verification_codes(destination, created_at)
+    WHERE destination IS NOT NULL;
+
+COMMENT ON COLUMN verification_codes.destination IS 'Email или номер телефона в формате E.164, на который отправлен код';
+COMMENT ON COLUMN verification_codes.channel IS 'Канал доставки: email, sms или telegram';
+COMMENT ON COLUMN verification_codes.attempts IS 'Число попыток ввода; после лимита код перестаёт действовать';
@@ -0,0 +1,25 @@
+-- Двухфакторная аутентификация: TOTP секрет и одноразовые коды восстановления
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id         UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret          TEXT NOT NULL,
+    enabled_at      TIMESTAMPTZ,
+    last_used_step  BIGINT NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash       CHAR(64) NOT NULL,
+    used_at         TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_recovery_codes_hash ON user_recovery_codes(user_id, code_hash);
+
+COMMENT ON TABLE user_totp IS 'TOTP секрет пользователя; до подтверждения первым кодом enabled_at пуст и вход не требует второго фактора';
+COMMENT ON COLUMN user_totp.secret IS 'Секрет, зашифрованный AES-GCM ключом TOTP_ENCRYPTION_KEY';
+COMMENT ON COLUMN user_totp.last_used_step IS 'Номер 30-секундного интервала последнего принятого кода; коды этого и более ранних интервалов не принимаются повторно';
+COMMENT ON TABLE user_recovery_codes IS 'Одноразовые коды восстановления доступа при потере устройства с TOTP';
+COMMENT ON COLUMN user_recovery_codes.code_hash IS 'SHA-256 кода в hex; сам код показывается пользователю один раз';
@@ -0,0 +1,32 @@
+-- Вход через внешних провайдеров (OAuth2 / OpenID Connect): привязанные учётные записи и незавершённые входы
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider        TEXT NOT NULL,
+    subject         TEXT NOT NULL,
+    email           CITEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_login_at   TIMESTAMPTZ,
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
+
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state_hash      CHAR(64) PRIMARY KEY,
+    provider        TEXT NOT NULL,
+    code_verifier   TEXT NOT NULL,
+    nonce           TEXT NOT NULL,
+    expires_at      TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_login_states_expires ON oidc_login_states(expires_at);
+
+COMMENT ON TABLE user_identities IS 'Учётные записи внешних провайдеров входа, привязанные к пользователю';
+COMMENT ON COLUMN user_identities.subject IS 'Неизменный идентификатор пользователя у провайдера (sub или id)';
+COMMENT ON COLUMN user_identities.email IS 'Email, который провайдер сообщил при последнем входе';
+COMMENT ON TABLE oidc_login_states IS 'Начатые входы через провайдера; запись удаляется при возврате пользователя';
+COMMENT ON COLUMN oidc_login_states.state_hash IS 'SHA-256 параметра state в hex';
+COMMENT ON COLUMN oidc_login_states.code_verifier IS 'PKCE code_verifier; провайдеру при авторизации передаётся только его хеш';
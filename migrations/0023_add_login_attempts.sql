@@ -0,0 +1,19 @@
+-- Журнал попыток входа: задержки и временная блокировка при подборе пароля
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email           CITEXT NOT NULL,
+    user_id         UUID REFERENCES users(id) ON DELETE CASCADE,
+    ip_address      INET,
+    user_agent      TEXT,
+    success         BOOLEAN NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email ON login_attempts(email, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip ON login_attempts(ip_address, created_at DESC) WHERE NOT success;
+CREATE INDEX IF NOT EXISTS idx_login_attempts_created ON login_attempts(created_at);
+
+COMMENT ON TABLE login_attempts IS 'Попытки входа по паролю; хранятся 30 дней';
+COMMENT ON COLUMN login_attempts.email IS 'Email из запроса, в том числе незарегистрированный';
+COMMENT ON COLUMN login_attempts.user_id IS 'Пользователь с этим email, если он существует';
@@ -0,0 +1,32 @@
+-- Проверка личности (KYC): заявки с фотографиями документов и решения модераторов
+
+CREATE TABLE IF NOT EXISTS kyc_submissions (
+    id                  UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id             UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    document_type       TEXT NOT NULL CHECK (document_type IN ('passport', 'id_card', 'driver_license')),
+    status              TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    rejection_reason    TEXT,
+    reviewed_by         UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at         TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- У пользователя не больше одной заявки на рассмотрении
+CREATE UNIQUE INDEX IF NOT EXISTS idx_kyc_submissions_pending_user ON kyc_submissions(user_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_kyc_submissions_user ON kyc_submissions(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_kyc_submissions_queue ON kyc_submissions(status, created_at);
+
+-- Файл документа нельзя удалить, пока он приложен к заявке
+CREATE TABLE IF NOT EXISTS kyc_documents (
+    submission_id       UUID NOT NULL REFERENCES kyc_submissions(id) ON DELETE CASCADE,
+    kind                TEXT NOT NULL CHECK (kind IN ('front', 'back', 'selfie')),
+    media_id            UUID NOT NULL REFERENCES media_files(id),
+    PRIMARY KEY (submission_id, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_kyc_documents_media ON kyc_documents(media_id);
+
+COMMENT ON TABLE kyc_submissions IS 'Заявки на проверку личности; одобрение выставляет users.identity_verified';
+COMMENT ON COLUMN kyc_submissions.rejection_reason IS 'Причина отклонения, которую видит пользователь';
+COMMENT ON TABLE kyc_documents IS 'Фотографии документа из приватного хранилища медиа-файлов';
+COMMENT ON COLUMN kyc_documents.kind IS 'front — лицевая сторона, back — оборот, selfie — фото с документом в руках';
@@ -0,0 +1,23 @@
+-- Персональные токены доступа для скриптов и интеграций
+
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name            TEXT NOT NULL,
+    token_hash      CHAR(64) NOT NULL UNIQUE,
+    token_prefix    TEXT NOT NULL,
+    scopes          TEXT[] NOT NULL,
+    expires_at      TIMESTAMPTZ,
+    last_used_at    TIMESTAMPTZ,
+    last_used_ip    INET,
+    revoked_at      TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user ON personal_access_tokens(user_id, created_at DESC) WHERE revoked_at IS NULL;
+
+COMMENT ON TABLE personal_access_tokens IS 'Долгоживущие токены с ограниченным набором прав; сам токен не хранится';
+COMMENT ON COLUMN personal_access_tokens.token_hash IS 'SHA-256 токена в hex';
+COMMENT ON COLUMN personal_access_tokens.token_prefix IS 'Начало токена, по которому пользователь узнаёт его в списке';
+COMMENT ON COLUMN personal_access_tokens.expires_at IS 'NULL — бессрочный токен';
+COMMENT ON COLUMN personal_access_tokens.last_used_at IS 'Время последнего запроса с токеном, обновляется при каждом использовании';
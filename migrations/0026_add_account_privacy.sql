@@ -0,0 +1,27 @@
+-- Выгрузка данных пользователя и удаление аккаунта с периодом отмены
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled ON users(deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS data_exports (
+    id                      UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id                 UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status                  TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'ready', 'failed', 'expired')),
+    file_path               TEXT,
+    file_size               BIGINT,
+    processing_started_at   TIMESTAMPTZ,
+    completed_at            TIMESTAMPTZ,
+    expires_at              TIMESTAMPTZ,
+    created_at              TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- У пользователя не больше одной выгрузки в работе
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_exports_active_user ON data_exports(user_id) WHERE status IN ('pending', 'processing');
+CREATE INDEX IF NOT EXISTS idx_data_exports_user ON data_exports(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_data_exports_queue ON data_exports(status, created_at);
+
+COMMENT ON COLUMN users.deletion_scheduled_at IS 'Когда аккаунт будет обезличен; до этого момента удаление можно отменить';
+COMMENT ON COLUMN users.deleted_at IS 'Когда аккаунт обезличен: строка остаётся, чтобы заказы, чаты, отзывы и журнал проводок не теряли связей';
+COMMENT ON TABLE data_exports IS 'ZIP-архивы с данными пользователя; файл лежит в приватном хранилище до expires_at';
@@ -0,0 +1,13 @@
+-- Отметки о прочтении сообщений участниками чата
+
+CREATE TABLE IF NOT EXISTS message_reads (
+    message_id      UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    read_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (message_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_reads_user ON message_reads(user_id);
+
+COMMENT ON TABLE message_reads IS 'Какие сообщения чата прочитал каждый участник; собственные сообщения автора не отмечаются';
+COMMENT ON COLUMN message_reads.read_at IS 'Когда участник подтвердил прочтение через WebSocket';
@@ -0,0 +1,11 @@
+-- Крупные события WebSocket, которые не помещаются в NOTIFY
+
+CREATE TABLE IF NOT EXISTS ws_backplane_events (
+    id              BIGSERIAL PRIMARY KEY,
+    payload         JSONB NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ws_backplane_events_created ON ws_backplane_events(created_at);
+
+COMMENT ON TABLE ws_backplane_events IS 'Конверты хаба больше лимита NOTIFY; в канал ws_backplane уходит ссылка ref:<id>, строки удаляются через несколько минут';
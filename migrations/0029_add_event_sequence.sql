@@ -0,0 +1,13 @@
+-- Порядковые номера событий WebSocket для повторной доставки после переподключения
+
+CREATE TABLE IF NOT EXISTS user_event_sequences (
+    user_id         UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    last_seq        BIGINT NOT NULL DEFAULT 0
+);
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS seq BIGINT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_user_seq ON notifications(user_id, seq) WHERE seq IS NOT NULL;
+
+COMMENT ON TABLE user_event_sequences IS 'Последний выданный пользователю номер события; строка блокируется при выдаче, поэтому номера растут в порядке сохранения';
+COMMENT ON COLUMN notifications.seq IS 'Номер события у пользователя; клиент переподключается с ?since=<seq> и получает пропущенные события. NULL у уведомлений до появления нумерации';
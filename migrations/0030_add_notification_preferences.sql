@@ -0,0 +1,28 @@
+-- Настройки доставки уведомлений: канал для каждого типа события, тихие часы и ежедневная сводка
+
+CREATE TABLE IF NOT EXISTS notification_settings (
+    user_id             UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    quiet_hours_start   SMALLINT CHECK (quiet_hours_start BETWEEN 0 AND 1439),
+    quiet_hours_end     SMALLINT CHECK (quiet_hours_end BETWEEN 0 AND 1439),
+    timezone            TEXT NOT NULL DEFAULT 'UTC',
+    digest_enabled      BOOLEAN NOT NULL DEFAULT FALSE,
+    last_digest_at      TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((quiet_hours_start IS NULL) = (quiet_hours_end IS NULL))
+);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id             UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_type          TEXT NOT NULL,
+    channel             TEXT NOT NULL CHECK (channel IN ('in_app', 'email', 'telegram', 'none')),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, event_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_settings_digest ON notification_settings(last_digest_at) WHERE digest_enabled;
+CREATE INDEX IF NOT EXISTS idx_notifications_user_unread ON notifications(user_id, created_at) WHERE NOT is_read;
+
+COMMENT ON TABLE notification_settings IS 'Тихие часы и ежедневная сводка; без строки действуют значения по умолчанию';
+COMMENT ON COLUMN notification_settings.quiet_hours_start IS 'Начало тихих часов в минутах от полуночи по timezone; в тихие часы письма и сообщения в Telegram не отправляются';
+COMMENT ON COLUMN notification_settings.last_digest_at IS 'Когда отправлена последняя сводка; следующая включает непрочитанные уведомления после этого момента';
+COMMENT ON TABLE notification_preferences IS 'Канал доставки по типу события; event_type = ''*'' задаёт канал для остальных типов';
@@ -0,0 +1,10 @@
+-- Номера телефонов, которыми пользователи Telegram поделились с ботом
+
+CREATE TABLE IF NOT EXISTS telegram_contacts (
+    chat_id         BIGINT PRIMARY KEY,
+    phone           VARCHAR(20) NOT NULL,
+    shared_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE telegram_contacts IS 'Собственный контакт, отправленный боту кнопкой «Отправить номер»: Telegram подтверждает, что номер принадлежит владельцу чата. Код подтверждения телефона уходит в Telegram, только если номер здесь совпадает с телефоном в профиле';
+COMMENT ON COLUMN telegram_contacts.phone IS 'Номер в формате E.164';
@@ -0,0 +1,16 @@
+-- Письма и сообщения в Telegram, отложенные до конца тихих часов
+
+CREATE TABLE IF NOT EXISTS notification_deferred (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_type      TEXT NOT NULL,
+    channel         TEXT NOT NULL CHECK (channel IN ('email', 'telegram')),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_deferred_user ON notification_deferred(user_id, id);
+
+COMMENT ON TABLE notification_deferred IS 'Внешние уведомления, пришедшие в тихие часы; после их окончания отправляются одним сообщением на канал и удаляются';
+COMMENT ON COLUMN notification_deferred.locked_until IS 'До какого момента строку отправляет один из экземпляров; если отправка не удалась, после него строку заберёт следующий проход';
+COMMENT ON COLUMN notification_settings.quiet_hours_start IS 'Начало тихих часов в минутах от полуночи по timezone; письма и сообщения в Telegram, пришедшие в тихие часы, откладываются до их окончания';
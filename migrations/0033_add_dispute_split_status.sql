@@ -0,0 +1,5 @@
+-- Отдельный статус для спора, решённого разделом escrow между сторонами
+
+ALTER TYPE dispute_status ADD VALUE IF NOT EXISTS 'resolved_split';
+
+COMMENT ON COLUMN disputes.status IS 'resolved_client и resolved_freelancer — вся сумма escrow одной стороне, resolved_split — сумма разделена по client_percent';